@@ -3,7 +3,7 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	liberr "github.com/konveyor/controller/pkg/error"
@@ -110,6 +110,10 @@ func (h *EventHandler) Error(w *web.Watch, err error) {
 	fmt.Printf("[%d] Event (error): %v\n", h.wid, err)
 }
 
+func (h *EventHandler) Reset(reason string) {
+	fmt.Printf("[%d] Event (reset): %s\n", h.wid, reason)
+}
+
 func (h *EventHandler) End() {
 	h.done = true
 
@@ -118,28 +122,29 @@ func (h *EventHandler) End() {
 
 type Endpoint struct {
 	web.Watched
+	web.Streamed
 	db model.DB
 }
 
 func (h Endpoint) Get(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Query("id"))
 	m := &Model{ID: id}
-	err := h.db.Get(m)
+	err := h.db.GetContext(ctx.Request.Context(), m)
 	if err != nil {
-		if errors.Is(err, model.NotFound) {
-			ctx.Status(http.StatusNotFound)
-		} else {
-			ctx.Status(http.StatusInternalServerError)
-		}
+		web.Fail(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, m)
+	web.Render(ctx, http.StatusOK, m)
 }
 
 func (h Endpoint) List(ctx *gin.Context) {
+	err := h.Watched.PrepareErr(ctx)
+	if err != nil {
+		web.Fail(ctx, err)
+		return
+	}
 	// Watch request.
-	h.Watched.Prepare(ctx)
 	if h.WatchRequest {
 		err := h.Watch(
 			ctx,
@@ -150,24 +155,82 @@ func (h Endpoint) List(ctx *gin.Context) {
 				return
 			})
 		if err != nil {
-			ctx.Status(http.StatusInternalServerError)
+			web.Fail(ctx, err)
+		}
+		return
+	}
+	// List request.
+	err = h.Stream(
+		ctx,
+		h.db,
+		&Model{},
+		model.ListOptions{Detail: model.MaxDetail},
+		func(in model.Model) (r interface{}) {
+			r = in
+			return
+		})
+	if err != nil {
+		web.Fail(ctx, err)
+	}
+}
+
+func (h *Endpoint) AddRoutes(e *gin.Engine) {
+	e.GET("/models", h.List)
+	e.GET("/models/:id", h.Get)
+}
+
+func (h *Endpoint) Models() []model.Model {
+	return []model.Model{&Model{}}
+}
+
+//
+// Tenant (age) scoped endpoint.
+type TenantEndpoint struct {
+	web.Tenanted
+	db model.DB
+}
+
+func (h TenantEndpoint) List(ctx *gin.Context) {
+	h.OwnerField = "age"
+	err := h.PrepareErr(ctx)
+	if err != nil {
+		web.Fail(ctx, err)
+		return
+	}
+	// Watch request.
+	if h.WatchRequest {
+		err := h.Watch(
+			ctx,
+			h.db,
+			&Model{},
+			func(in model.Model) (r interface{}) {
+				r = in
+				return
+			})
+		if err != nil {
+			web.Fail(ctx, err)
 		}
 		return
 	}
 	// List request.
 	list := []Model{}
-	err := h.db.List(&list, model.ListOptions{Detail: model.MaxDetail})
+	options := h.ListOptions()
+	options.Detail = model.MaxDetail
+	err = h.db.List(&list, options)
 	if err != nil {
-		ctx.Status(http.StatusInternalServerError)
+		web.Fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, list)
 }
 
-func (h *Endpoint) AddRoutes(e *gin.Engine) {
-	e.GET("/models", h.List)
-	e.GET("/models/:id", h.Get)
+func (h *TenantEndpoint) AddRoutes(e *gin.Engine) {
+	e.GET(web.Scoped("/ages", "/models"), h.List)
+}
+
+func (h *TenantEndpoint) Models() []model.Model {
+	return []model.Model{&Model{}}
 }
 
 //
@@ -241,9 +304,23 @@ func setup() (db model.DB, webSrv *web.WebServer) {
 
 	//
 	// Launch web server.
-	webSrv = web.New(cnt, &Endpoint{db: db})
+	webSrv = web.New(
+		cnt,
+		&web.SchemaHandler{},
+		&web.HealthHandler{Container: cnt},
+		&web.MetricsHandler{Container: cnt},
+		&Endpoint{db: db},
+		&TenantEndpoint{db: db},
+		&web.WriteHandler{
+			DB:    db,
+			Model: &Model{},
+			Path:  "/models",
+		})
 	webSrv.Port = 7001
-	webSrv.Start()
+	err = webSrv.Start()
+	if err != nil {
+		panic(err)
+	}
 	return
 }
 
@@ -370,10 +447,215 @@ func testE(db model.DB, client *web.Client) {
 	_ = db.Close(false)
 }
 
+//
+// Test write endpoints.
+func testF(client *web.Client) {
+	m := &Model{ID: 100, Name: "created", Age: 1}
+	status, err := client.Post("http://localhost:7001/models", m, m)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusCreated {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	status, err = client.Post("http://localhost:7001/models", m, m)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusConflict {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	m.Name = "updated"
+	status, err = client.Put("http://localhost:7001/models/100", m, m)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusOK {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	status, err = client.Delete("http://localhost:7001/models/100")
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusNoContent {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	status, err = client.Delete("http://localhost:7001/models/100")
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusNotFound {
+		panic(liberr.New(http.StatusText(status)))
+	}
+
+	fmt.Printf("\nWrite: %v\n", m)
+}
+
+//
+// Test watch using the event-stream (SSE) transport.
+func testI(db model.DB) {
+	client := &web.Client{
+		Transport:      http.DefaultTransport,
+		WatchTransport: web.WatchEventStream,
+	}
+	handler := &EventHandler{
+		options: web.WatchOptions{Snapshot: true},
+	}
+	status, w, err := client.Watch(
+		"http://localhost:7001/models",
+		&Model{},
+		handler)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusOK {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	wait(100)
+	err = db.Insert(&Model{ID: 200, Name: "streamed"})
+	if err != nil {
+		panic(err)
+	}
+	wait(500)
+	if !handler.started || !handler.parity {
+		panic(liberr.New("event-stream not started."))
+	}
+	found := false
+	for _, id := range handler.created {
+		if id == 200 {
+			found = true
+		}
+	}
+	if !found {
+		panic(liberr.New("event-stream event not delivered."))
+	}
+	endWatch(w)
+	if !handler.done {
+		panic(liberr.New("event-stream not ended."))
+	}
+
+	fmt.Printf("\nEvent-stream: %v\n", handler.created)
+}
+
+//
+// Test tenant (owner) scoped list and watch.
+func testJ(db model.DB, client *web.Client) {
+	list := []Model{}
+	status, err := client.Get("http://localhost:7001/ages/15/models", &list)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusOK {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	if len(list) != 1 || list[0].ID != 5 {
+		panic(liberr.New("owner predicate not applied."))
+	}
+	handler := &EventHandler{
+		options: web.WatchOptions{Snapshot: true},
+	}
+	status, w, err := client.Watch(
+		"http://localhost:7001/ages/15/models",
+		&Model{},
+		handler)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusOK {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	wait(100)
+	err = db.Insert(&Model{ID: 300, Name: "other", Age: 16})
+	if err != nil {
+		panic(err)
+	}
+	err = db.Insert(&Model{ID: 301, Name: "owned", Age: 15})
+	if err != nil {
+		panic(err)
+	}
+	wait(500)
+	endWatch(w)
+	if len(handler.created) != 2 ||
+		handler.created[0] != 5 ||
+		handler.created[1] != 301 {
+		panic(liberr.New("owner filter not applied."))
+	}
+
+	fmt.Printf("\nTenant: %v\n", handler.created)
+}
+
+//
+// Test health and readiness probes.
+func testH(client *web.Client) {
+	for _, path := range []string{web.HealthRoot, web.ReadyRoot} {
+		report := &web.HealthReport{}
+		status, err := client.Get("http://localhost:7001"+path, report)
+		if err != nil {
+			panic(err)
+		}
+		if status != http.StatusOK {
+			panic(liberr.New(http.StatusText(status)))
+		}
+		fmt.Printf("\nProbe: %s %+v\n", path, *report)
+	}
+}
+
+//
+// Test stop (graceful) the web server.
+func testG(webSrv *web.WebServer, client *web.Client) {
+	handler := &EventHandler{}
+	status, _, err := client.Watch(
+		"http://localhost:7001/models",
+		&Model{},
+		handler)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusOK {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	streamClient := &web.Client{
+		Transport:      http.DefaultTransport,
+		WatchTransport: web.WatchEventStream,
+	}
+	streamHandler := &EventHandler{}
+	status, _, err = streamClient.Watch(
+		"http://localhost:7001/models",
+		&Model{},
+		streamHandler)
+	if err != nil {
+		panic(err)
+	}
+	if status != http.StatusOK {
+		panic(liberr.New(http.StatusText(status)))
+	}
+	wait(100)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err = webSrv.Stop(ctx)
+	if err != nil {
+		panic(err)
+	}
+	wait(3500)
+	for _, h := range []*EventHandler{handler, streamHandler} {
+		goingAway := false
+		for _, err := range h.err {
+			if _, cast := err.(*web.GoingAway); cast {
+				goingAway = true
+			}
+		}
+		if !goingAway {
+			panic(liberr.New("going-away not reported."))
+		}
+	}
+
+	fmt.Printf("\nStopped: %v\n", handler.err[0])
+}
+
 //
 // Main.
 func main() {
-	db, _ := setup()
+	db, webSrv := setup()
 	fmt.Println(db)
 	client := &web.Client{
 		Transport: http.DefaultTransport,
@@ -387,6 +669,11 @@ func main() {
 	testB(client, n)
 	testC(client, n)
 	testD(db, client)
+	testF(client)
+	testH(client)
+	testI(db)
+	testJ(db, client)
 	testE(db, client)
+	testG(webSrv, client)
 	wait(500)
 }
@@ -10,6 +10,7 @@ import (
 const (
 	ReconcileFailed = "ReconcileFailed"
 	Ready           = "Ready"
+	Degraded        = "Degraded"
 )
 
 // Status
@@ -18,6 +19,23 @@ const (
 	False = "False"
 )
 
+// Reasons
+const (
+	Expired        = "Expired"
+	RequiredNotMet = "RequiredNotMet"
+	AllMet         = "AllMet"
+)
+
+// Expire actions.
+type ExpireAction int
+
+const (
+	// Expired conditions are removed.
+	Remove ExpireAction = iota
+	// Expired conditions are downgraded.
+	Downgrade
+)
+
 // Category
 const (
 	// Errors that block Reconcile() and the `Ready` condition.
@@ -50,6 +68,18 @@ type Condition struct {
 	Durable bool `json:"durable,omitempty"`
 	// A list of items referenced in the `Message`.
 	Items []string `json:"items,omitempty"`
+	// Message template (text/template). When specified, the
+	// `Message` is rendered using the (localized) template
+	// and the params.
+	Template string `json:"template,omitempty"`
+	// Message template parameters.
+	Params map[string]string `json:"params,omitempty"`
+	// When the condition was last set (refreshed).
+	// Only tracked when a TTL is specified.
+	LastHeartbeatTime v1.Time `json:"lastHeartbeatTime,omitempty"`
+	// Time-to-live. The condition is expired by Expire()
+	// when not refreshed within the TTL. Zero=never.
+	TTL v1.Duration `json:"ttl,omitempty"`
 	// The condition has been explicitly set/updated.
 	staged bool `json:"-"`
 }
@@ -68,11 +98,43 @@ func (r *Condition) Update(other Condition) (updated bool) {
 	r.Message = other.Message
 	r.Durable = other.Durable
 	r.Items = other.Items
+	r.Template = other.Template
+	r.Params = other.Params
+	r.TTL = other.TTL
 	r.LastTransitionTime = v1.NewTime(time.Now())
 	updated = true
 	return
 }
 
+//
+// The condition has not been refreshed within the TTL.
+func (r *Condition) Expired(now time.Time) bool {
+	if r.TTL.Duration <= 0 {
+		return false
+	}
+	heartbeat := r.LastHeartbeatTime.Time
+	if heartbeat.IsZero() {
+		heartbeat = r.LastTransitionTime.Time
+	}
+
+	return now.Sub(heartbeat) > r.TTL.Duration
+}
+
+//
+// The condition has been downgraded by Expire().
+func (r *Condition) downgraded() bool {
+	return r.Category == Advisory && r.Reason == Expired
+}
+
+//
+// Record the heartbeat.
+// Only conditions with a TTL are tracked.
+func (r *Condition) heartbeat() {
+	if r.TTL.Duration > 0 {
+		r.LastHeartbeatTime = v1.NewTime(time.Now())
+	}
+}
+
 //
 // Get whether the conditions are equal.
 func (r *Condition) Equal(other Condition) bool {
@@ -82,6 +144,7 @@ func (r *Condition) Equal(other Condition) bool {
 		r.Reason == other.Reason &&
 		r.Message == other.Message &&
 		r.Durable == other.Durable &&
+		r.TTL == other.TTL &&
 		reflect.DeepEqual(r.Items, other.Items)
 }
 
@@ -105,10 +168,50 @@ type Conditions struct {
 	List []Condition `json:"conditions,omitempty"`
 	// Staging conditions.
 	staging bool `json:"-"`
+	// Transition history by condition type.
+	// Only recorded when enabled. See: RecordHistory().
+	History map[string][]Transition `json:"history,omitempty"`
+	// Max transitions kept (per type). Zero=disabled.
+	historyLimit int `json:"-"`
+	// The CR kind. Conditions are validated using the
+	// schema registered for the kind.
+	Kind string `json:"-"`
 	// Explain report.
 	explain Explain `json:"-"`
 }
 
+//
+// Condition history (spec).
+// Intended to be included in the resource Spec so that
+// the limit is persisted.
+type HistorySpec struct {
+	// Max transitions kept (per type). Zero=disabled.
+	HistoryLimit int `json:"historyLimit,omitempty"`
+}
+
+//
+// Condition (status) transition.
+type Transition struct {
+	// The prior status. Empty when added.
+	From string `json:"from,omitempty"`
+	// The new status. Empty when deleted.
+	To string `json:"to,omitempty"`
+	// The reason for the transition.
+	Reason string `json:"reason,omitempty"`
+	// When the transition occurred.
+	Time v1.Time `json:"time"`
+}
+
+//
+// Record the transition history as specified.
+// Expected to be called (using the resource Spec) on each
+// reconcile before conditions are set.
+// Example:
+//   thing.Status.RecordHistory(thing.Spec.History)
+func (r *Conditions) RecordHistory(spec HistorySpec) {
+	r.historyLimit = spec.HistoryLimit
+}
+
 //
 // Begin staging conditions.
 func (r *Conditions) BeginStagingConditions() {
@@ -137,6 +240,7 @@ func (r *Conditions) EndStagingConditions() {
 			continue
 		} else {
 			r.explain.deleted(condition)
+			r.transition(condition.Type, condition.Status, "", condition.Reason)
 		}
 	}
 	r.List = kept
@@ -184,15 +288,26 @@ func (r *Conditions) SetCondition(conditions ...Condition) {
 	}
 	for _, condition := range conditions {
 		condition.staged = true
+		condition.render()
+		if !r.validate(condition) {
+			continue
+		}
 		found := r.find(condition.Type)
 		if found == nil {
 			r.explain.added(condition)
+			r.transition(condition.Type, "", condition.Status, condition.Reason)
 			condition.LastTransitionTime = v1.NewTime(time.Now())
+			condition.heartbeat()
 			r.List = append(r.List, condition)
 		} else {
+			status := found.Status
 			if found.Update(condition) {
 				r.explain.updated(condition)
+				if status != condition.Status {
+					r.transition(condition.Type, status, condition.Status, condition.Reason)
+				}
 			}
+			found.heartbeat()
 		}
 	}
 }
@@ -240,6 +355,7 @@ func (r *Conditions) DeleteCondition(types ...string) {
 			continue
 		}
 		r.explain.deleted(condition)
+		r.transition(condition.Type, condition.Status, "", condition.Reason)
 		if r.staging {
 			condition.staged = false
 			kept = append(kept, condition)
@@ -341,6 +457,127 @@ func (r *Conditions) IsReady() bool {
 	return true
 }
 
+//
+// Expire conditions not refreshed (set) within their TTL.
+// Expired conditions are either removed or downgraded to the
+// `Advisory` category with reason `Expired` so they no longer
+// block the `Ready` condition. Conditions already downgraded
+// are not downgraded (expired) again.
+// Returns: the (newly) expired conditions.
+func (r *Conditions) Expire(action ExpireAction) (expired []Condition) {
+	if r.List == nil {
+		return
+	}
+	now := time.Now()
+	kept := []Condition{}
+	for i := range r.List {
+		condition := r.List[i]
+		if !condition.Expired(now) || (action == Downgrade && condition.downgraded()) {
+			kept = append(kept, condition)
+			continue
+		}
+		expired = append(expired, condition)
+		switch action {
+		case Downgrade:
+			condition.Category = Advisory
+			condition.Reason = Expired
+			condition.LastTransitionTime = v1.NewTime(now)
+			r.explain.updated(condition)
+			kept = append(kept, condition)
+		default:
+			r.explain.deleted(condition)
+		}
+	}
+	r.List = kept
+
+	return
+}
+
+//
+// Record a transition.
+// The history is bounded by the (spec) HistoryLimit.
+func (r *Conditions) transition(cndType, from, to, reason string) {
+	if r.historyLimit < 1 {
+		return
+	}
+	if r.History == nil {
+		r.History = map[string][]Transition{}
+	}
+	history := append(
+		r.History[cndType],
+		Transition{
+			From:   from,
+			To:     to,
+			Reason: reason,
+			Time:   v1.NewTime(time.Now()),
+		})
+	if len(history) > r.historyLimit {
+		history = history[len(history)-r.historyLimit:]
+	}
+
+	r.History[cndType] = history
+}
+
+//
+// Snapshot (copy) of the conditions.
+// Intended to be compared using Changed().
+func (r *Conditions) Snapshot() (snapshot Conditions) {
+	r.DeepCopyInto(&snapshot)
+	snapshot.explain = Explain{}
+	return
+}
+
+//
+// Conditions compatible with the prior status schema.
+// The status fields added by later versions (template, params,
+// heartbeat, TTL and the transition history) are omitted and the
+// rendered message is kept. Intended for CRD (version) conversion
+// when a served version does not define the fields.
+func (r *Conditions) Compatible() (compatible Conditions) {
+	compatible = r.Snapshot()
+	compatible.History = nil
+	for i := range compatible.List {
+		condition := &compatible.List[i]
+		condition.Template = ""
+		condition.Params = nil
+		condition.LastHeartbeatTime = v1.Time{}
+		condition.TTL = v1.Duration{}
+	}
+
+	return
+}
+
+//
+// Changes since a previous snapshot.
+// Conditions are compared by type using Equal(). The
+// report is empty when nothing has actually changed.
+func (r *Conditions) Changed(previous Conditions) (changed Explain) {
+	changed.build()
+	before := map[string]Condition{}
+	for _, condition := range previous.List {
+		before[condition.Type] = condition
+	}
+	for _, condition := range r.List {
+		if r.staging && !condition.staged {
+			continue
+		}
+		prior, found := before[condition.Type]
+		delete(before, condition.Type)
+		if !found {
+			changed.Added[condition.Type] = condition
+			continue
+		}
+		if !condition.Equal(prior) {
+			changed.Updated[condition.Type] = condition
+		}
+	}
+	for cndType, condition := range before {
+		changed.Deleted[cndType] = condition
+	}
+
+	return
+}
+
 //
 // Get Explain report.
 func (r *Conditions) Explain() Explain {
@@ -363,7 +600,7 @@ type Explain struct {
 // Total number of changes.
 func (r *Explain) Len() int {
 	r.build()
-	return len(r.Updated) + len(r.Updated) + len(r.Deleted)
+	return len(r.Added) + len(r.Updated) + len(r.Deleted)
 }
 
 //
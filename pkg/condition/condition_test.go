@@ -1,11 +1,20 @@
 package condition
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/onsi/gomega"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestCondition_Equal(t *testing.T) {
@@ -414,3 +423,506 @@ func TestConditions_ChangeSet(t *testing.T) {
 	g.Expect(len(explain.Deleted)).To(gomega.Equal(1))
 	g.Expect(explain.Deleted["D"].Type).To(gomega.Equal("D"))
 }
+
+func TestConditions_SetStatusConditions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	conditions := Conditions{}
+	conditions.SetCondition(
+		Condition{
+			Type:     "A",
+			Status:   True,
+			Category: Error,
+			Message:  "A is true.",
+		},
+		Condition{
+			Type:     "B",
+			Status:   True,
+			Reason:   "Found",
+			Category: Warn,
+		})
+	status := []MetaCondition{
+		{Type: "C", Status: True},
+	}
+	conditions.SetStatusConditions(&status, 2)
+	g.Expect(len(status)).To(gomega.Equal(2))
+	g.Expect(status[0].Type).To(gomega.Equal("A"))
+	g.Expect(status[0].Reason).To(gomega.Equal("A"))
+	g.Expect(status[0].ObservedGeneration).To(gomega.Equal(int64(2)))
+	g.Expect(IsStatusConditionTrue(status, "B")).To(gomega.BeTrue())
+	g.Expect(FindStatusCondition(status, "C")).To(gomega.BeNil())
+	// Status unchanged.
+	transition := metav1.NewTime(time.Now().Add(time.Hour))
+	SetStatusCondition(&status, MetaCondition{
+		Type:               "A",
+		Status:             True,
+		Reason:             "Changed",
+		LastTransitionTime: transition,
+	})
+	g.Expect(status[0].Reason).To(gomega.Equal("Changed"))
+	g.Expect(status[0].LastTransitionTime).ToNot(gomega.Equal(transition))
+	// Status changed.
+	SetStatusCondition(&status, MetaCondition{
+		Type:               "A",
+		Status:             False,
+		Reason:             "Changed",
+		LastTransitionTime: transition,
+	})
+	g.Expect(status[0].LastTransitionTime).To(gomega.Equal(transition))
+	// Remove.
+	RemoveStatusCondition(&status, "A")
+	g.Expect(len(status)).To(gomega.Equal(1))
+	// From.
+	condition := FromMeta(status[0], Warn)
+	g.Expect(condition.Type).To(gomega.Equal("B"))
+	g.Expect(condition.Category).To(gomega.Equal(Warn))
+}
+
+func TestConditions_Expire(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	conditions := Conditions{}
+	conditions.SetCondition(
+		Condition{
+			Type:     "A",
+			Status:   True,
+			Category: Error,
+			TTL:      metav1.Duration{Duration: time.Minute},
+		},
+		Condition{
+			Type:     "B",
+			Status:   True,
+			Category: Error,
+			TTL:      metav1.Duration{Duration: time.Minute},
+		},
+		Condition{
+			Type:     "C",
+			Status:   True,
+			Category: Error,
+		})
+	stale := metav1.NewTime(time.Now().Add(-time.Hour))
+	conditions.List[0].LastHeartbeatTime = stale
+	conditions.List[2].LastHeartbeatTime = stale
+	// Downgrade.
+	expired := conditions.Expire(Downgrade)
+	g.Expect(len(expired)).To(gomega.Equal(1))
+	g.Expect(len(conditions.List)).To(gomega.Equal(3))
+	a := conditions.FindCondition("A")
+	g.Expect(a.Category).To(gomega.Equal(Advisory))
+	g.Expect(a.Reason).To(gomega.Equal(Expired))
+	transitioned := a.LastTransitionTime
+	g.Expect(conditions.Expire(Downgrade)).To(gomega.BeEmpty())
+	g.Expect(conditions.FindCondition("A").LastTransitionTime).To(gomega.Equal(transitioned))
+	// Refreshed.
+	conditions.SetCondition(Condition{
+		Type:     "A",
+		Status:   True,
+		Category: Error,
+		TTL:      metav1.Duration{Duration: time.Minute},
+	})
+	g.Expect(conditions.Expire(Remove)).To(gomega.BeEmpty())
+	g.Expect(conditions.FindCondition("A").Category).To(gomega.Equal(Error))
+	// Remove.
+	conditions.List[1].LastHeartbeatTime = stale
+	expired = conditions.Expire(Remove)
+	g.Expect(len(expired)).To(gomega.Equal(1))
+	g.Expect(conditions.FindCondition("B")).To(gomega.BeNil())
+	g.Expect(len(conditions.List)).To(gomega.Equal(2))
+}
+
+func TestConditions_Rollup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	conditions := Conditions{}
+	conditions.SetCondition(
+		Condition{Type: "A", Status: True, Category: Advisory},
+		Condition{Type: "B", Status: True, Category: Required})
+	// Ready.
+	rollup := conditions.Rollup("B")
+	g.Expect(rollup.Type).To(gomega.Equal(Ready))
+	g.Expect(conditions.IsReady()).To(gomega.BeTrue())
+	// Required not met.
+	rollup = conditions.Rollup("B", "C")
+	g.Expect(rollup.Reason).To(gomega.Equal(RequiredNotMet))
+	g.Expect(rollup.Items).To(gomega.Equal([]string{"C"}))
+	g.Expect(conditions.IsReady()).To(gomega.BeFalse())
+	// Degraded.
+	conditions.SetCondition(Condition{
+		Type:     "W",
+		Status:   True,
+		Category: Warn,
+		Message:  "Warning.",
+	})
+	rollup = conditions.Rollup("B")
+	g.Expect(rollup.Type).To(gomega.Equal(Degraded))
+	g.Expect(rollup.Reason).To(gomega.Equal("W"))
+	g.Expect(conditions.IsReady()).To(gomega.BeTrue())
+	g.Expect(conditions.FindCondition(Degraded)).ToNot(gomega.BeNil())
+	// Blocked.
+	conditions.SetCondition(Condition{
+		Type:     "E",
+		Status:   True,
+		Category: Error,
+		Message:  "Error.",
+	})
+	rollup = conditions.Rollup("B")
+	g.Expect(rollup.Type).To(gomega.Equal(Ready))
+	g.Expect(rollup.Status).To(gomega.Equal(False))
+	g.Expect(rollup.Reason).To(gomega.Equal("E"))
+	g.Expect(conditions.FindCondition(Degraded)).To(gomega.BeNil())
+	// Ordered.
+	list := conditions.BySeverity()
+	g.Expect(list[0].Type).To(gomega.Equal("E"))
+	g.Expect(list[1].Type).To(gomega.Equal("W"))
+}
+
+func TestConditions_History(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	conditions := Conditions{}
+	conditions.RecordHistory(HistorySpec{HistoryLimit: 2})
+	conditions.SetCondition(Condition{Type: "A", Status: True, Category: Warn})
+	snapshot := conditions.Snapshot()
+	// Unchanged.
+	conditions.SetCondition(Condition{Type: "A", Status: True, Category: Warn})
+	changed := conditions.Changed(snapshot)
+	g.Expect(changed.Empty()).To(gomega.BeTrue())
+	g.Expect(len(conditions.History["A"])).To(gomega.Equal(1))
+	// Changed.
+	conditions.SetCondition(Condition{Type: "A", Status: False, Category: Warn})
+	conditions.SetCondition(Condition{Type: "B", Status: True, Category: Warn})
+	changed = conditions.Changed(snapshot)
+	g.Expect(changed.Len()).To(gomega.Equal(2))
+	g.Expect(changed.Updated["A"].Status).To(gomega.Equal(False))
+	g.Expect(changed.Added["B"].Type).To(gomega.Equal("B"))
+	// Bounded.
+	conditions.DeleteCondition("A")
+	history := conditions.History["A"]
+	g.Expect(len(history)).To(gomega.Equal(2))
+	g.Expect(history[0].From).To(gomega.Equal(True))
+	g.Expect(history[0].To).To(gomega.Equal(False))
+	g.Expect(history[1].To).To(gomega.BeEmpty())
+	g.Expect(conditions.Changed(snapshot).Deleted["A"].Type).To(gomega.Equal("A"))
+}
+
+func TestConditions_Compatible(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	conditions := Conditions{}
+	conditions.RecordHistory(HistorySpec{HistoryLimit: 2})
+	conditions.SetCondition(
+		Condition{
+			Type:     "A",
+			Status:   True,
+			Category: Warn,
+			Template: "Found {{.n}}.",
+			Params:   map[string]string{"n": "2"},
+			TTL:      metav1.Duration{Duration: time.Minute},
+		})
+	compatible := conditions.Compatible()
+	g.Expect(compatible.History).To(gomega.BeNil())
+	a := compatible.FindCondition("A")
+	g.Expect(a.Message).To(gomega.Equal("Found 2."))
+	g.Expect(a.Template).To(gomega.BeEmpty())
+	g.Expect(a.Params).To(gomega.BeNil())
+	g.Expect(a.TTL.Duration).To(gomega.BeZero())
+	g.Expect(a.LastHeartbeatTime.IsZero()).To(gomega.BeTrue())
+	g.Expect(conditions.FindCondition("A").Template).ToNot(gomega.BeEmpty())
+}
+
+func TestCondition_Render(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer func() {
+		Localize = nil
+	}()
+	conditions := Conditions{}
+	conditions.SetCondition(Condition{
+		Type:     "NotFound",
+		Status:   True,
+		Reason:   "Missing",
+		Category: Error,
+		Template: "The {{.kind}} `{{.name}}` not found.",
+		Params: map[string]string{
+			"kind": "secret",
+			"name": "test",
+		},
+	})
+	condition := conditions.FindCondition("NotFound")
+	g.Expect(condition.Message).To(gomega.Equal("The secret `test` not found."))
+	// Localized.
+	Localize = Catalog{
+		"NotFound.Missing": "{{.kind}} {{.name}}: introuvable.",
+	}
+	message, err := condition.Render()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(message).To(gomega.Equal("secret test: introuvable."))
+	g.Expect(condition.Reason).To(gomega.Equal("Missing"))
+	// Not valid.
+	Localize = nil
+	condition.Template = "{{.name"
+	_, err = condition.Render()
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestConditions_Schema(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	Schemas.Register(Schema{
+		Kind: "Thing",
+		Types: map[string]TypeSchema{
+			Ready: {},
+			"A": {
+				Categories: []string{Error},
+				Reasons:    []string{"NotFound"},
+			},
+			"B": {},
+		},
+		Exclusions: [][]string{
+			{"A", "B"},
+		},
+		Strict: true,
+	})
+	conditions := Conditions{Kind: "Thing"}
+	// Declared.
+	conditions.SetCondition(Condition{
+		Type:     "A",
+		Status:   True,
+		Reason:   "NotFound",
+		Category: Error,
+	})
+	g.Expect(conditions.FindCondition("A")).ToNot(gomega.BeNil())
+	// Not declared.
+	conditions.SetCondition(
+		Condition{Type: "C", Status: True, Category: Warn},
+		Condition{Type: "A", Status: True, Reason: "Typo", Category: Error})
+	g.Expect(conditions.FindCondition("C")).To(gomega.BeNil())
+	g.Expect(conditions.FindCondition("A").Reason).To(gomega.Equal("NotFound"))
+	// Exclusive.
+	conditions.SetCondition(Condition{Type: "B", Status: True, Category: Warn})
+	g.Expect(conditions.FindCondition("B")).To(gomega.BeNil())
+	conditions.SetCondition(Condition{Type: "B", Status: False, Category: Warn})
+	g.Expect(conditions.FindCondition("B")).ToNot(gomega.BeNil())
+	// Exclusive (staged only).
+	conditions.BeginStagingConditions()
+	conditions.SetCondition(Condition{Type: "B", Status: True, Category: Warn})
+	conditions.EndStagingConditions()
+	g.Expect(conditions.FindCondition("A")).To(gomega.BeNil())
+	g.Expect(conditions.FindCondition("B").Status).To(gomega.Equal(True))
+	// Not strict.
+	schema, found := Schemas.Find("Thing")
+	g.Expect(found).To(gomega.BeTrue())
+	schema.Strict = false
+	conditions.SetCondition(Condition{Type: "C", Status: True, Category: Warn})
+	g.Expect(conditions.FindCondition("C")).ToNot(gomega.BeNil())
+}
+
+type TestResource struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+	Status struct {
+		Conditions
+	}
+}
+
+func (r *TestResource) GetConditions() *Conditions {
+	return &r.Status.Conditions
+}
+
+func (r *TestResource) DeepCopyObject() runtime.Object {
+	out := &TestResource{}
+	out.TypeMeta = r.TypeMeta
+	r.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	r.Status.Conditions.DeepCopyInto(&out.Status.Conditions)
+	return out
+}
+
+type TestClient struct {
+	client.Client
+	// Stored resource.
+	stored *TestResource
+	// Number of conflicts to be returned.
+	conflicts int
+	// Number of (status) updates.
+	updates int
+}
+
+func (r *TestClient) Get(ctx context.Context, key client.ObjectKey, object runtime.Object) error {
+	stored := r.stored.DeepCopyObject().(*TestResource)
+	stored.GetConditions().staging = false
+	*object.(*TestResource) = *stored
+	return nil
+}
+
+func (r *TestClient) Status() client.StatusWriter {
+	return r
+}
+
+func (r *TestClient) Update(ctx context.Context, object runtime.Object) error {
+	if r.conflicts > 0 {
+		r.conflicts--
+		return k8serr.NewConflict(schema.GroupResource{}, "test", errors.New("conflict"))
+	}
+	r.updates++
+	object.(*TestResource).ResourceVersion = strconv.Itoa(r.updates)
+	r.stored = object.DeepCopyObject().(*TestResource)
+	return nil
+}
+
+func TestStatusUpdater(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	stored := &TestResource{}
+	stored.Name = "test"
+	kube := &TestClient{stored: stored}
+	updater := StatusUpdater{Client: kube}
+	ready := Condition{
+		Type:     Ready,
+		Status:   True,
+		Category: Required,
+		Message:  "Ready.",
+	}
+	degraded := Condition{
+		Type:     Degraded,
+		Status:   True,
+		Category: Warn,
+		Message:  "Degraded.",
+	}
+	// added.
+	object := stored.DeepCopyObject().(*TestResource)
+	object.Status.SetCondition(ready, degraded)
+	updated, err := updater.Update(context.TODO(), object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updated).To(gomega.BeTrue())
+	g.Expect(kube.updates).To(gomega.Equal(1))
+	g.Expect(object.ResourceVersion).To(gomega.Equal("1"))
+	g.Expect(len(kube.stored.Status.List)).To(gomega.Equal(2))
+	readyTime := kube.stored.Status.FindCondition(Ready).LastTransitionTime
+	// unchanged (timestamps ignored).
+	object = kube.stored.DeepCopyObject().(*TestResource)
+	object.Status.BeginStagingConditions()
+	object.Status.SetCondition(ready, degraded)
+	object.Status.FindCondition(Ready).LastTransitionTime = metav1.NewTime(time.Now().Add(time.Hour))
+	object.Status.EndStagingConditions()
+	updated, err = updater.Update(context.TODO(), object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updated).To(gomega.BeFalse())
+	g.Expect(kube.updates).To(gomega.Equal(1))
+	// changed with conflicts (retried).
+	kube.conflicts = 2
+	object = kube.stored.DeepCopyObject().(*TestResource)
+	object.Status.BeginStagingConditions()
+	object.Status.SetCondition(ready)
+	degraded.Message = "Still degraded."
+	object.Status.SetCondition(degraded)
+	object.Status.FindCondition(Ready).LastTransitionTime = metav1.NewTime(time.Now().Add(time.Hour))
+	updated, err = updater.Update(context.TODO(), object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updated).To(gomega.BeTrue())
+	g.Expect(kube.updates).To(gomega.Equal(2))
+	g.Expect(kube.conflicts).To(gomega.Equal(0))
+	g.Expect(kube.stored.Status.FindCondition(Degraded).Message).To(gomega.Equal("Still degraded."))
+	// stored transition time kept (unchanged).
+	g.Expect(kube.stored.Status.FindCondition(Ready).LastTransitionTime).To(gomega.Equal(readyTime))
+	// deleted (un-staged).
+	object = kube.stored.DeepCopyObject().(*TestResource)
+	object.Status.BeginStagingConditions()
+	object.Status.SetCondition(ready)
+	updated, err = updater.Update(context.TODO(), object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updated).To(gomega.BeTrue())
+	g.Expect(kube.stored.Status.List).To(gomega.HaveLen(1))
+	g.Expect(kube.stored.Status.HasCondition(Degraded)).To(gomega.BeFalse())
+}
+
+type TestPatchClient struct {
+	TestClient
+	// Applied patches.
+	patches []map[string]interface{}
+}
+
+func (r *TestPatchClient) Status() client.StatusWriter {
+	return r
+}
+
+func (r *TestPatchClient) Patch(ctx context.Context, object runtime.Object, patch []byte) (err error) {
+	mp := map[string]interface{}{}
+	err = json.Unmarshal(patch, &mp)
+	if err != nil {
+		return
+	}
+	version := mp["metadata"].(map[string]interface{})["resourceVersion"]
+	if r.conflicts > 0 {
+		r.conflicts--
+		return k8serr.NewConflict(schema.GroupResource{}, "test", errors.New("conflict"))
+	}
+	if version != r.stored.ResourceVersion {
+		return k8serr.NewConflict(schema.GroupResource{}, "test", errors.New("conflict"))
+	}
+	r.patches = append(r.patches, mp)
+	original, err := json.Marshal(r.stored)
+	if err != nil {
+		return
+	}
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return
+	}
+	stored := &TestResource{}
+	err = json.Unmarshal(patched, stored)
+	if err != nil {
+		return
+	}
+	r.updates++
+	stored.ResourceVersion = strconv.Itoa(r.updates)
+	r.stored = stored
+	*object.(*TestResource) = *stored.DeepCopyObject().(*TestResource)
+	return
+}
+
+func TestStatusPatcher(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	stored := &TestResource{}
+	stored.Name = "test"
+	stored.Labels = map[string]string{"A": "1"}
+	kube := &TestPatchClient{TestClient: TestClient{stored: stored}}
+	updater := StatusUpdater{Client: kube}
+	ready := Condition{
+		Type:     Ready,
+		Status:   True,
+		Category: Required,
+		Message:  "Ready.",
+	}
+	degraded := Condition{
+		Type:     Degraded,
+		Status:   True,
+		Category: Warn,
+		Message:  "Degraded.",
+	}
+	// added (patched).
+	object := stored.DeepCopyObject().(*TestResource)
+	object.Status.SetCondition(ready, degraded)
+	object.Labels = map[string]string{"B": "2"}
+	updated, err := updater.Update(context.TODO(), object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updated).To(gomega.BeTrue())
+	g.Expect(kube.patches).To(gomega.HaveLen(1))
+	g.Expect(kube.patches[0]).To(gomega.HaveKey("Status"))
+	g.Expect(kube.patches[0]).To(gomega.HaveKey("metadata"))
+	g.Expect(kube.patches[0]).ToNot(gomega.HaveKey("labels"))
+	g.Expect(object.ResourceVersion).To(gomega.Equal("1"))
+	g.Expect(kube.stored.Labels).To(gomega.Equal(map[string]string{"A": "1"}))
+	g.Expect(kube.stored.Status.List).To(gomega.HaveLen(2))
+	// unchanged (not patched).
+	object = kube.stored.DeepCopyObject().(*TestResource)
+	object.Status.SetCondition(ready, degraded)
+	updated, err = updater.Update(context.TODO(), object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updated).To(gomega.BeFalse())
+	g.Expect(kube.patches).To(gomega.HaveLen(1))
+	// deleted with conflicts (retried).
+	kube.conflicts = 2
+	object = kube.stored.DeepCopyObject().(*TestResource)
+	object.Status.BeginStagingConditions()
+	object.Status.SetCondition(ready)
+	updated, err = updater.Update(context.TODO(), object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updated).To(gomega.BeTrue())
+	g.Expect(kube.conflicts).To(gomega.Equal(0))
+	g.Expect(kube.patches).To(gomega.HaveLen(2))
+	g.Expect(kube.stored.Status.List).To(gomega.HaveLen(1))
+	g.Expect(kube.stored.Status.HasCondition(Degraded)).To(gomega.BeFalse())
+	g.Expect(object.ResourceVersion).To(gomega.Equal("2"))
+}
@@ -1,3 +1,19 @@
+/*
+Resource (CR) status conditions.
+
+Status schema:
+The following (status) fields were added to the Condition and
+Conditions and must be defined in the CRD status schema (or the
+status must preserve unknown fields). Otherwise, they are pruned
+by the API server when the status is updated:
+  - template, params (templated messages).
+  - lastHeartbeatTime, ttl (expiry).
+  - history (transition history).
+
+The rendered message is always stored so that pruned conditions
+remain meaningful. When the CRD serves a version that does not
+define the fields, the conversion should use Compatible().
+*/
 package condition
 
 // +k8s:deepcopy-gen=package
@@ -0,0 +1,82 @@
+package condition
+
+import (
+	"bytes"
+	"text/template"
+)
+
+//
+// Message localizer.
+// Translates or reformats condition message templates.
+// Reason codes are not affected.
+type Localizer interface {
+	// Localize a message template.
+	// Returns the template unchanged when not localized.
+	Localize(cndType, reason, template string) string
+}
+
+//
+// The (pluggable) localizer.
+// Nil=none.
+var Localize Localizer
+
+//
+// Catalog localizer.
+// Templates keyed by `type.reason` or `type`.
+type Catalog map[string]string
+
+//
+// Localize a message template.
+func (r Catalog) Localize(cndType, reason, template string) string {
+	if found, matched := r[cndType+"."+reason]; matched {
+		return found
+	}
+	if found, matched := r[cndType]; matched {
+		return found
+	}
+
+	return template
+}
+
+//
+// Render the message.
+// The (localized) template is executed using the params.
+// Returns the message unchanged when no template specified.
+func (r *Condition) Render() (message string, err error) {
+	message = r.Message
+	if r.Template == "" {
+		return
+	}
+	text := r.Template
+	if Localize != nil {
+		text = Localize.Localize(r.Type, r.Reason, text)
+	}
+	tmpl, err := template.New(r.Type).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return
+	}
+	buffer := bytes.Buffer{}
+	err = tmpl.Execute(&buffer, r.Params)
+	if err != nil {
+		return
+	}
+
+	message = buffer.String()
+
+	return
+}
+
+//
+// Render the message.
+// The template is used as the message when not valid.
+func (r *Condition) render() {
+	if r.Template == "" {
+		return
+	}
+	message, err := r.Render()
+	if err != nil {
+		message = r.Template
+	}
+
+	r.Message = message
+}
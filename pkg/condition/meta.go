@@ -0,0 +1,159 @@
+package condition
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+//
+// Kubernetes (metav1) condition.
+// Mirrors metav1.Condition which is not provided by the
+// apimachinery version used here. The fields and JSON tags
+// are identical so CR status fields declared using either
+// type are populated (serialized) the same.
+type MetaCondition struct {
+	// The condition type (CamelCase).
+	Type string `json:"type"`
+	// The condition status [True,False,Unknown].
+	Status string `json:"status"`
+	// The resource generation on which the condition is based.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// When the last status transition occurred.
+	LastTransitionTime v1.Time `json:"lastTransitionTime"`
+	// The reason for the last transition (CamelCase).
+	Reason string `json:"reason"`
+	// The human readable description of the transition.
+	Message string `json:"message"`
+}
+
+//
+// Convert to a metav1 condition.
+// The `Type` is used as the reason when not specified because
+// metav1 requires the reason. The category and items are not
+// represented.
+func (r *Condition) Meta(generation int64) (m MetaCondition) {
+	m = MetaCondition{
+		Type:               r.Type,
+		Status:             r.Status,
+		ObservedGeneration: generation,
+		LastTransitionTime: r.LastTransitionTime,
+		Reason:             r.Reason,
+		Message:            r.Message,
+	}
+	if m.Reason == "" {
+		m.Reason = r.Type
+	}
+
+	return
+}
+
+//
+// Build a condition from a metav1 condition.
+// The category is not represented in metav1 and must be specified.
+func FromMeta(m MetaCondition, category string) Condition {
+	return Condition{
+		Type:               m.Type,
+		Status:             m.Status,
+		Reason:             m.Reason,
+		Category:           category,
+		Message:            m.Message,
+		LastTransitionTime: m.LastTransitionTime,
+	}
+}
+
+//
+// Convert to metav1 conditions.
+// Un-staged conditions are omitted while staging.
+func (r *Conditions) Meta(generation int64) (list []MetaCondition) {
+	list = []MetaCondition{}
+	for i := range r.List {
+		condition := &r.List[i]
+		if r.staging && !condition.staged {
+			continue
+		}
+		list = append(list, condition.Meta(generation))
+	}
+
+	return
+}
+
+//
+// Populate a (CR status) list of metav1 conditions using
+// SetStatusCondition() semantics. Conditions not contained
+// in the collection are removed.
+func (r *Conditions) SetStatusConditions(conditions *[]MetaCondition, generation int64) {
+	wanted := map[string]bool{}
+	for _, m := range r.Meta(generation) {
+		wanted[m.Type] = true
+		SetStatusCondition(conditions, m)
+	}
+	kept := []MetaCondition{}
+	for _, m := range *conditions {
+		if wanted[m.Type] {
+			kept = append(kept, m)
+		}
+	}
+
+	*conditions = kept
+}
+
+//
+// Set (add/update) a condition in a list of metav1 conditions.
+// The LastTransitionTime is only updated when the status changes
+// and defaults to now when not specified.
+func SetStatusCondition(conditions *[]MetaCondition, new MetaCondition) {
+	if conditions == nil {
+		return
+	}
+	if new.LastTransitionTime.IsZero() {
+		new.LastTransitionTime = v1.NewTime(time.Now())
+	}
+	existing := FindStatusCondition(*conditions, new.Type)
+	if existing == nil {
+		*conditions = append(*conditions, new)
+		return
+	}
+	if existing.Status != new.Status {
+		existing.Status = new.Status
+		existing.LastTransitionTime = new.LastTransitionTime
+	}
+
+	existing.Reason = new.Reason
+	existing.Message = new.Message
+	existing.ObservedGeneration = new.ObservedGeneration
+}
+
+//
+// Remove a condition by type from a list of metav1 conditions.
+func RemoveStatusCondition(conditions *[]MetaCondition, cndType string) {
+	if conditions == nil {
+		return
+	}
+	kept := []MetaCondition{}
+	for _, m := range *conditions {
+		if m.Type != cndType {
+			kept = append(kept, m)
+		}
+	}
+
+	*conditions = kept
+}
+
+//
+// Find a condition by type in a list of metav1 conditions.
+func FindStatusCondition(conditions []MetaCondition, cndType string) *MetaCondition {
+	for i := range conditions {
+		if conditions[i].Type == cndType {
+			return &conditions[i]
+		}
+	}
+
+	return nil
+}
+
+//
+// The condition (type) is `True` in a list of metav1 conditions.
+func IsStatusConditionTrue(conditions []MetaCondition, cndType string) bool {
+	m := FindStatusCondition(conditions, cndType)
+	return m != nil && m.Status == True
+}
@@ -0,0 +1,113 @@
+package condition
+
+import (
+	"sort"
+)
+
+//
+// Severity by category.
+// Higher is more severe.
+var Severity = map[string]int{
+	Advisory: 0,
+	Required: 1,
+	Warn:     2,
+	Error:    3,
+	Critical: 4,
+}
+
+//
+// The severity of a category.
+// Unknown categories are advisory.
+func SeverityOf(category string) int {
+	return Severity[category]
+}
+
+//
+// Conditions ordered by severity (most severe first).
+// Order is otherwise preserved.
+func (r *Conditions) BySeverity() (list []Condition) {
+	list = []Condition{}
+	for i := range r.List {
+		condition := r.List[i]
+		if r.staging && !condition.staged {
+			continue
+		}
+		list = append(list, condition)
+	}
+	sort.SliceStable(
+		list,
+		func(i, j int) bool {
+			return SeverityOf(list[i].Category) > SeverityOf(list[j].Category)
+		})
+
+	return
+}
+
+//
+// Roll up the collection into the top-level `Ready`
+// and `Degraded` conditions.
+//   Ready=False: a `Critical` or `Error` condition or a required
+//     condition (type) is not `True`.
+//   Degraded=True: ready with `Warn` conditions.
+// The `Degraded` condition is deleted when not degraded.
+// Returns: the top-level condition (Degraded when degraded,
+// otherwise Ready).
+func (r *Conditions) Rollup(required ...string) (rollup Condition) {
+	var worst *Condition
+	warnings := []string{}
+	list := r.BySeverity()
+	for i := range list {
+		condition := &list[i]
+		if condition.Type == Ready || condition.Type == Degraded {
+			continue
+		}
+		if condition.Status != True {
+			continue
+		}
+		if condition.Category == Warn {
+			warnings = append(warnings, condition.Type)
+		}
+		if worst == nil {
+			worst = condition
+		}
+	}
+	ready := Condition{
+		Type:     Ready,
+		Status:   True,
+		Reason:   AllMet,
+		Category: Required,
+		Message:  "The resource is ready.",
+	}
+	for _, cndType := range required {
+		condition := r.FindCondition(cndType)
+		if condition == nil || condition.Status != True {
+			ready.Status = False
+			ready.Reason = RequiredNotMet
+			ready.Message = "The required condition is not met."
+			ready.Items = append(ready.Items, cndType)
+		}
+	}
+	if worst != nil && SeverityOf(worst.Category) >= SeverityOf(Error) {
+		ready.Status = False
+		ready.Reason = worst.Type
+		ready.Message = worst.Message
+		ready.Items = nil
+	}
+	r.SetCondition(ready)
+	rollup = ready
+	if ready.Status == True && len(warnings) > 0 {
+		rollup = Condition{
+			Type:     Degraded,
+			Status:   True,
+			Reason:   worst.Type,
+			Category: Warn,
+			Message:  worst.Message,
+			Items:    warnings,
+		}
+		r.SetCondition(rollup)
+	} else {
+		r.DeleteCondition(Degraded)
+	}
+
+	return
+}
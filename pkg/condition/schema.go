@@ -0,0 +1,187 @@
+package condition
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/logging"
+	"sync"
+)
+
+//
+// Logger.
+var log = logging.WithName("condition")
+
+//
+// Schema registry.
+// Controllers declare the allowed conditions by CR kind.
+var Schemas = SchemaRegistry{}
+
+//
+// Condition (type) schema.
+type TypeSchema struct {
+	// Allowed categories. Empty=any.
+	Categories []string
+	// Allowed reasons. Empty=any.
+	Reasons []string
+}
+
+//
+// Allowed conditions for a CR kind.
+type Schema struct {
+	// The CR kind.
+	Kind string
+	// Allowed condition types.
+	Types map[string]TypeSchema
+	// Sets of mutually exclusive types. At most one
+	// type in each set may be `True`.
+	Exclusions [][]string
+	// Undeclared conditions are rejected (not set).
+	// Otherwise, a warning is logged.
+	Strict bool
+}
+
+//
+// Validate a condition.
+// The existing conditions are used to check exclusions.
+func (r *Schema) Validate(condition Condition, existing []Condition) (err error) {
+	declared, found := r.Types[condition.Type]
+	if !found {
+		err = liberr.New(
+			"condition type not declared.",
+			"kind",
+			r.Kind,
+			"type",
+			condition.Type)
+		return
+	}
+	if !r.allowed(declared.Categories, condition.Category) {
+		err = liberr.New(
+			"condition category not declared.",
+			"kind",
+			r.Kind,
+			"type",
+			condition.Type,
+			"category",
+			condition.Category)
+		return
+	}
+	if !r.allowed(declared.Reasons, condition.Reason) {
+		err = liberr.New(
+			"condition reason not declared.",
+			"kind",
+			r.Kind,
+			"type",
+			condition.Type,
+			"reason",
+			condition.Reason)
+		return
+	}
+	if condition.Status != True {
+		return
+	}
+	for _, exclusion := range r.Exclusions {
+		if len(exclusion) == 0 || !r.allowed(exclusion, condition.Type) {
+			continue
+		}
+		for _, other := range existing {
+			if other.Type == condition.Type || other.Status != True {
+				continue
+			}
+			if r.allowed(exclusion, other.Type) {
+				err = liberr.New(
+					"conditions mutually exclusive.",
+					"kind",
+					r.Kind,
+					"type",
+					condition.Type,
+					"other",
+					other.Type)
+				return
+			}
+		}
+	}
+
+	return
+}
+
+//
+// Value allowed.
+// An empty list allows any value.
+func (r *Schema) allowed(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Schema registry.
+type SchemaRegistry struct {
+	// Schemas by kind.
+	content map[string]*Schema
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Register (declare) a schema.
+func (r *SchemaRegistry) Register(schema Schema) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[string]*Schema{}
+	}
+
+	r.content[schema.Kind] = &schema
+}
+
+//
+// Find a schema by kind.
+func (r *SchemaRegistry) Find(kind string) (schema *Schema, found bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	schema, found = r.content[kind]
+	return
+}
+
+//
+// Validate a condition against the schema for the kind.
+// While staging, exclusions are checked against the
+// staged conditions only.
+// Returns: true when the condition may be set.
+func (r *Conditions) validate(condition Condition) bool {
+	if r.Kind == "" {
+		return true
+	}
+	schema, found := Schemas.Find(r.Kind)
+	if !found {
+		return true
+	}
+	existing := []Condition{}
+	for _, other := range r.List {
+		if r.staging && !other.staged {
+			continue
+		}
+		existing = append(existing, other)
+	}
+	err := schema.Validate(condition, existing)
+	if err == nil {
+		return true
+	}
+	if schema.Strict {
+		log.Trace(err)
+		return false
+	}
+
+	log.Info(
+		"Warning: condition not valid.",
+		"error",
+		err.Error())
+
+	return true
+}
@@ -0,0 +1,247 @@
+package condition
+
+import (
+	"context"
+	"encoding/json"
+	jsonpatch "github.com/evanphx/json-patch"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+//
+// Resource (CR) with conditions.
+type Conditioned interface {
+	runtime.Object
+	v1.Object
+	// The (status) conditions.
+	GetConditions() *Conditions
+}
+
+//
+// Condition-driven status updater.
+// The resource status is updated only when the desired
+// conditions differ materially from the stored conditions.
+// Timestamps (transition and heartbeat) are ignored when
+// compared and the stored transition time is kept for
+// conditions that have not changed. The status is written
+// using a (JSON) merge patch of the changed status fields when
+// a patcher is available; otherwise, Status().Update() is used.
+// The patch includes the resource version so that concurrent
+// writes are detected. On conflict, the resource is fetched and
+// the desired conditions applied again.
+// Example:
+//   updater := condition.StatusUpdater{
+//       Client: r.Client,
+//       Patcher: &condition.DynamicPatcher{
+//           Client: dynamicClient,
+//           Mapper: mgr.GetRESTMapper(),
+//           Scheme: mgr.GetScheme(),
+//       },
+//   }
+//   updated, err := updater.Update(ctx, plan)
+// +k8s:deepcopy-gen=false
+type StatusUpdater struct {
+	// k8s client.
+	Client client.Client
+	// Status patcher.
+	// Default: the client status writer when it
+	// implements StatusPatcher.
+	Patcher StatusPatcher
+	// Conflict retry backoff.
+	// Default: retry.DefaultRetry.
+	Backoff *wait.Backoff
+}
+
+//
+// Update the resource status conditions.
+// Returns true when the status has been updated. The resource
+// version is refreshed (from the stored resource) either way.
+func (r *StatusUpdater) Update(ctx context.Context, object Conditioned) (updated bool, err error) {
+	desired := object.GetConditions().Snapshot()
+	backoff := retry.DefaultRetry
+	if r.Backoff != nil {
+		backoff = *r.Backoff
+	}
+	key := client.ObjectKey{
+		Namespace: object.GetNamespace(),
+		Name:      object.GetName(),
+	}
+	var stored Conditioned
+	err = retry.RetryOnConflict(backoff, func() (err error) {
+		stored = object.DeepCopyObject().(Conditioned)
+		err = r.Client.Get(ctx, key, stored)
+		if err != nil {
+			return
+		}
+		current := stored.GetConditions()
+		changed := desired.Changed(*current)
+		if changed.Empty() {
+			updated = false
+			return
+		}
+		patcher, found := r.patcher()
+		if !found {
+			current.List = desired.merge(current)
+			current.History = desired.History
+			err = r.Client.Status().Update(ctx, stored)
+			updated = err == nil
+			return
+		}
+		original, err := json.Marshal(stored)
+		if err != nil {
+			return
+		}
+		current.List = desired.merge(current)
+		current.History = desired.History
+		modified, err := json.Marshal(stored)
+		if err != nil {
+			return
+		}
+		patch, err := mergePatch(original, modified, stored.GetResourceVersion())
+		if err != nil {
+			return
+		}
+		err = patcher.Patch(ctx, stored, patch)
+		updated = err == nil
+		return
+	})
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"namespace",
+			key.Namespace,
+			"name",
+			key.Name)
+		return
+	}
+	object.SetResourceVersion(stored.GetResourceVersion())
+
+	log.V(4).Info(
+		"status conditions reconciled.",
+		"namespace",
+		key.Namespace,
+		"name",
+		key.Name,
+		"updated",
+		updated)
+
+	return
+}
+
+//
+// The status patcher.
+func (r *StatusUpdater) patcher() (patcher StatusPatcher, found bool) {
+	if r.Patcher != nil {
+		patcher = r.Patcher
+		found = true
+		return
+	}
+	patcher, found = r.Client.Status().(StatusPatcher)
+	return
+}
+
+//
+// Build the (JSON) merge patch.
+// The resource version is included so the patch is
+// rejected (conflict) when the resource has changed.
+func mergePatch(original, modified []byte, version string) (patch []byte, err error) {
+	patch, err = jsonpatch.CreateMergePatch(original, modified)
+	if err != nil {
+		return
+	}
+	mp := map[string]interface{}{}
+	err = json.Unmarshal(patch, &mp)
+	if err != nil {
+		return
+	}
+	mp["metadata"] = map[string]interface{}{
+		"resourceVersion": version,
+	}
+	patch, err = json.Marshal(mp)
+	return
+}
+
+//
+// Status (merge) patcher.
+// Applies a (JSON) merge patch to the status subresource and
+// updates the object with the patched resource.
+type StatusPatcher interface {
+	Patch(ctx context.Context, object runtime.Object, patch []byte) error
+}
+
+//
+// Status patcher using the dynamic client.
+// The resource is mapped using the scheme and REST mapper.
+// +k8s:deepcopy-gen=false
+type DynamicPatcher struct {
+	// Dynamic client.
+	Client dynamic.Interface
+	// REST mapper.
+	Mapper meta.RESTMapper
+	// Scheme.
+	Scheme *runtime.Scheme
+}
+
+//
+// Patch the status subresource.
+func (r *DynamicPatcher) Patch(ctx context.Context, object runtime.Object, patch []byte) (err error) {
+	mObject, err := meta.Accessor(object)
+	if err != nil {
+		return
+	}
+	gvk, err := apiutil.GVKForObject(object, r.Scheme)
+	if err != nil {
+		return
+	}
+	mapping, err := r.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return
+	}
+	var resource dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = r.Client.Resource(mapping.Resource).Namespace(mObject.GetNamespace())
+	} else {
+		resource = r.Client.Resource(mapping.Resource)
+	}
+	patched, err := resource.Patch(
+		mObject.GetName(),
+		types.MergePatchType,
+		patch,
+		v1.UpdateOptions{},
+		"status")
+	if err != nil {
+		return
+	}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(patched.Object, object)
+	return
+}
+
+//
+// Merge with the stored conditions.
+// Returns the (desired) conditions to be stored. Un-staged
+// conditions are omitted while staging. The stored timestamps
+// are kept for conditions that have not changed.
+func (r *Conditions) merge(stored *Conditions) (list []Condition) {
+	list = []Condition{}
+	for _, condition := range r.List {
+		if r.staging && !condition.staged {
+			continue
+		}
+		prior := stored.find(condition.Type)
+		if prior != nil && prior.Equal(condition) {
+			condition.LastTransitionTime = prior.LastTransitionTime
+			condition.LastHeartbeatTime = prior.LastHeartbeatTime
+		}
+		list = append(list, condition)
+	}
+
+	return
+}
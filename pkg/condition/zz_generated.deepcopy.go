@@ -32,6 +32,15 @@ func (in *Condition) DeepCopyInto(out *Condition) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.LastHeartbeatTime.DeepCopyInto(&out.LastHeartbeatTime)
+	out.TTL = in.TTL
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
@@ -54,6 +63,23 @@ func (in *Conditions) DeepCopyInto(out *Conditions) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make(map[string][]Transition, len(*in))
+		for key, val := range *in {
+			var outVal []Transition
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]Transition, len(*in))
+				for i := range *in {
+					(*in)[i].DeepCopyInto(&(*out)[i])
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
 	in.explain.DeepCopyInto(&out.explain)
 }
 
@@ -102,3 +128,50 @@ func (in *Explain) DeepCopy() *Explain {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HistorySpec) DeepCopyInto(out *HistorySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HistorySpec.
+func (in *HistorySpec) DeepCopy() *HistorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HistorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetaCondition) DeepCopyInto(out *MetaCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetaCondition.
+func (in *MetaCondition) DeepCopy() *MetaCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(MetaCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Transition) DeepCopyInto(out *Transition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Transition.
+func (in *Transition) DeepCopy() *Transition {
+	if in == nil {
+		return nil
+	}
+	out := new(Transition)
+	in.DeepCopyInto(out)
+	return out
+}
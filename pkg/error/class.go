@@ -0,0 +1,102 @@
+package error
+
+import (
+	"errors"
+)
+
+//
+// Error classification.
+type Class int
+
+const (
+	// Not classified. Treated as retryable.
+	Unclassified Class = iota
+	// Transient; the operation may be retried (backoff).
+	RetryableClass
+	// Permanent; retries will not succeed.
+	TerminalClass
+)
+
+//
+// Errors may (optionally) classify themselves.
+type Classifier interface {
+	// The error is terminal.
+	Terminal() bool
+}
+
+//
+// Wrap an error classified as retryable.
+func WrapRetryable(err error, kvpair ...interface{}) error {
+	return classify(err, RetryableClass, kvpair)
+}
+
+//
+// Wrap an error classified as terminal.
+func WrapTerminal(err error, kvpair ...interface{}) error {
+	return classify(err, TerminalClass, kvpair)
+}
+
+//
+// The error is retryable.
+// Unclassified errors are retryable.
+func Retryable(err error) bool {
+	return err != nil && ClassOf(err) != TerminalClass
+}
+
+//
+// The error is terminal.
+func Terminal(err error) bool {
+	return err != nil && ClassOf(err) == TerminalClass
+}
+
+//
+// Get the classification of an error.
+// The chain is searched for the (outermost) classification.
+func ClassOf(err error) (class Class) {
+	for err != nil {
+		if le, cast := err.(*Error); cast {
+			if le.class != Unclassified {
+				class = le.class
+				return
+			}
+			err = le.wrapped
+			continue
+		}
+		if classifier, cast := err.(Classifier); cast {
+			if classifier.Terminal() {
+				class = TerminalClass
+			} else {
+				class = RetryableClass
+			}
+			return
+		}
+		err = errors.Unwrap(err)
+	}
+
+	return
+}
+
+//
+// Wrap and classify.
+// A (wrapped) *Error is copied; the caller's error
+// is not modified.
+func classify(err error, class Class, kvpair []interface{}) error {
+	if err == nil {
+		return err
+	}
+	le := &Error{}
+	if wrapped, cast := err.(*Error); cast {
+		copied := *wrapped
+		copied.context = append(
+			[]interface{}{},
+			wrapped.context...)
+		le = &copied
+		le.append(kvpair)
+	} else {
+		le = Wrap(err, kvpair...).(*Error)
+	}
+
+	le.class = class
+
+	return le
+}
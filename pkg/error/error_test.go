@@ -1,9 +1,12 @@
 package error
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/onsi/gomega"
 	errors2 "github.com/pkg/errors"
+	"net/http"
 	"testing"
 )
 
@@ -77,3 +80,113 @@ func TestUnwrap(t *testing.T) {
 	g.Expect(Unwrap(errors2.Wrap(err, ""))).To(gomega.Equal(err))
 	g.Expect(Unwrap(errors2.Wrap(errors2.Wrap(err, ""), ""))).To(gomega.Equal(err))
 }
+
+type typedError struct {
+	reason string
+}
+
+func (e *typedError) Error() string {
+	return e.reason
+}
+
+func TestReport(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	typed := &typedError{reason: "denied"}
+	err := Wrap(
+		errors2.Wrap(typed, "help"),
+		"Get failed.",
+		Kind, "Plan",
+		PK, "1")
+	// Is/As.
+	g.Expect(errors.Is(err, typed)).To(gomega.BeTrue())
+	found := &typedError{}
+	g.Expect(errors.As(err, &found)).To(gomega.BeTrue())
+	g.Expect(found.reason).To(gomega.Equal("denied"))
+	// Context.
+	kind, hasKind := err.(*Error).Get(Kind)
+	g.Expect(hasKind).To(gomega.BeTrue())
+	g.Expect(kind).To(gomega.Equal("Plan"))
+	// Report.
+	report := ToReport(err, false)
+	g.Expect(report.Error).To(gomega.Equal(err.Error()))
+	g.Expect(report.Context).To(gomega.Equal(
+		map[string]interface{}{
+			Kind: "Plan",
+			PK:   "1",
+		}))
+	g.Expect(report.Stack).To(gomega.BeNil())
+	g.Expect(ToReport(err, true).Stack).ToNot(gomega.BeEmpty())
+	g.Expect(ToReport(typed, true).Error).To(gomega.Equal("denied"))
+	// JSON.
+	b, jErr := json.Marshal(err)
+	g.Expect(jErr).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.ContainSubstring(`"pk":"1"`))
+	// Format.
+	g.Expect(fmt.Sprintf("%v", err)).To(gomega.Equal(err.Error()))
+	g.Expect(fmt.Sprintf("%+v", err)).To(gomega.ContainSubstring("kind: Plan"))
+}
+
+func (e *typedError) Terminal() bool {
+	return e.reason == "denied"
+}
+
+func TestClass(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	err := errors.New("timeout")
+	g.Expect(Retryable(err)).To(gomega.BeTrue())
+	g.Expect(Terminal(err)).To(gomega.BeFalse())
+	g.Expect(Retryable(nil)).To(gomega.BeFalse())
+	// Wrapped.
+	terminal := WrapTerminal(err, "Get failed.")
+	g.Expect(Terminal(terminal)).To(gomega.BeTrue())
+	g.Expect(terminal.Error()).To(gomega.Equal("Get failed. caused by: 'timeout'"))
+	g.Expect(Terminal(Wrap(terminal, "Reconcile failed."))).To(gomega.BeTrue())
+	g.Expect(Terminal(errors2.Wrap(terminal, "help"))).To(gomega.BeTrue())
+	g.Expect(Retryable(WrapRetryable(terminal))).To(gomega.BeTrue())
+	g.Expect(Terminal(terminal)).To(gomega.BeTrue())
+	// Classifier.
+	g.Expect(Terminal(Wrap(&typedError{reason: "denied"}))).To(gomega.BeTrue())
+	g.Expect(ClassOf(&typedError{reason: "other"})).To(gomega.Equal(RetryableClass))
+}
+
+func TestStatus(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	notFound := errors.New("not found")
+	registry := &StatusRegistry{}
+	registry.Register(
+		StatusMapping{
+			Target: notFound,
+			Status: http.StatusNotFound,
+		},
+		StatusMapping{
+			Match: func(err error) bool {
+				typed := &typedError{}
+				return errors.As(err, &typed)
+			},
+			Status: http.StatusForbidden,
+			Title:  "Policy Denied",
+		})
+	// errors.Is().
+	problem := registry.Problem(Wrap(notFound, "kind", "VM"), "/vms/1")
+	g.Expect(problem.Status).To(gomega.Equal(http.StatusNotFound))
+	g.Expect(problem.Title).To(gomega.Equal("Not Found"))
+	g.Expect(problem.Type).To(gomega.Equal("about:blank"))
+	g.Expect(problem.Instance).To(gomega.Equal("/vms/1"))
+	g.Expect(problem.Detail).To(gomega.Equal("not found"))
+	g.Expect(problem.Context["kind"]).To(gomega.Equal("VM"))
+	// errors.As().
+	problem = registry.Problem(Wrap(&typedError{reason: "denied"}), "")
+	g.Expect(problem.Status).To(gomega.Equal(http.StatusForbidden))
+	g.Expect(problem.Title).To(gomega.Equal("Policy Denied"))
+	// retryable.
+	g.Expect(registry.Find(WrapRetryable(errors.New("busy"))).Status).To(
+		gomega.Equal(http.StatusServiceUnavailable))
+	// not disclosed.
+	problem = registry.Problem(New("failed", "path", "/tmp"), "")
+	g.Expect(problem.Status).To(gomega.Equal(http.StatusInternalServerError))
+	g.Expect(problem.Detail).To(gomega.BeEmpty())
+	g.Expect(problem.Context).To(gomega.BeNil())
+	b, err := json.Marshal(problem)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).ToNot(gomega.ContainSubstring("detail"))
+}
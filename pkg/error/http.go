@@ -0,0 +1,157 @@
+package error
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+//
+// Problem details (RFC 7807) content type.
+const ProblemType = "application/problem+json"
+
+//
+// Problem details (RFC 7807).
+// Rendered (JSON) by web error responses.
+type Problem struct {
+	// Problem type (URI).
+	Type string `json:"type"`
+	// Short (status) description.
+	Title string `json:"title"`
+	// HTTP status.
+	Status int `json:"status"`
+	// Error description.
+	// Omitted for server (5xx) errors.
+	Detail string `json:"detail,omitempty"`
+	// The request (URI).
+	Instance string `json:"instance,omitempty"`
+	// Error context key/value pairs.
+	// Omitted for server (5xx) errors.
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+//
+// HTTP status mapping.
+type StatusMapping struct {
+	// Target error matched using errors.Is().
+	Target error
+	// Match (optional) used instead of the target.
+	// Used to match typed errors using errors.As().
+	Match func(err error) bool
+	// HTTP status.
+	Status int
+	// Title.
+	// Default: status text.
+	Title string
+	// Problem type (URI).
+	// Default: about:blank.
+	Type string
+}
+
+//
+// The error is matched.
+func (r *StatusMapping) matched(err error) bool {
+	if r.Match != nil {
+		return r.Match(err)
+	}
+
+	return r.Target != nil && errors.Is(err, r.Target)
+}
+
+//
+// HTTP status mapping registry.
+// Errors are mapped to an HTTP status using the first mapping
+// matched (in the order registered). Errors not matched by any
+// mapping are mapped by classification: retryable (503); else
+// internal server error (500).
+type StatusRegistry struct {
+	// Mappings.
+	mappings []StatusMapping
+	// Protect the mappings.
+	mutex sync.RWMutex
+}
+
+//
+// Register status mappings.
+func (r *StatusRegistry) Register(mappings ...StatusMapping) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.mappings = append(r.mappings, mappings...)
+}
+
+//
+// Find the mapping for the error.
+func (r *StatusRegistry) Find(err error) (mapping StatusMapping) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, m := range r.mappings {
+		if m.matched(err) {
+			mapping = m
+			break
+		}
+	}
+	if mapping.Status == 0 {
+		mapping.Status = http.StatusInternalServerError
+		if ClassOf(err) == RetryableClass {
+			mapping.Status = http.StatusServiceUnavailable
+		}
+	}
+	if mapping.Title == "" {
+		mapping.Title = http.StatusText(mapping.Status)
+	}
+	if mapping.Type == "" {
+		mapping.Type = "about:blank"
+	}
+
+	return
+}
+
+//
+// Build the problem details for the error.
+// The detail and context of server (5xx) errors are not
+// disclosed; expected to be logged instead.
+func (r *StatusRegistry) Problem(err error, instance string) (problem Problem) {
+	mapping := r.Find(err)
+	problem = Problem{
+		Type:     mapping.Type,
+		Title:    mapping.Title,
+		Status:   mapping.Status,
+		Instance: instance,
+	}
+	if err == nil || problem.Status >= http.StatusInternalServerError {
+		return
+	}
+	report := ToReport(err, false)
+	problem.Detail = report.Error
+	problem.Context = report.Context
+
+	return
+}
+
+//
+// The (global) status registry.
+var Statuses = &StatusRegistry{}
+
+//
+// Register a status mapping with the global registry.
+// The error is matched using errors.Is().
+func RegisterStatus(target error, status int, title string) {
+	Statuses.Register(
+		StatusMapping{
+			Target: target,
+			Status: status,
+			Title:  title,
+		})
+}
+
+//
+// Get the HTTP status for the error.
+func StatusOf(err error) int {
+	return Statuses.Find(err).Status
+}
+
+//
+// Build the problem details for the error.
+func ToProblem(err error, instance string) Problem {
+	return Statuses.Problem(err, instance)
+}
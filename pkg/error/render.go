@@ -0,0 +1,111 @@
+package error
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//
+// Standard context keys.
+const (
+	Kind      = "kind"
+	PK        = "pk"
+	Operation = "operation"
+)
+
+//
+// Error report.
+// Rendered (JSON) by the logger and web error responses.
+type Report struct {
+	// Error description.
+	Error string `json:"error"`
+	// Context key/value pairs.
+	Context map[string]interface{} `json:"context,omitempty"`
+	// Stack trace.
+	Stack []string `json:"stack,omitempty"`
+}
+
+//
+// Build an error report.
+// The stack is included as specified.
+func ToReport(err error, stack bool) (report Report) {
+	if err == nil {
+		return
+	}
+	report.Error = err.Error()
+	le := &Error{}
+	if !errors.As(err, &le) {
+		return
+	}
+	context := le.Context()
+	if len(context) > 0 {
+		report.Context = map[string]interface{}{}
+		for i := 0; i+1 < len(context); i += 2 {
+			report.Context[fmt.Sprint(context[i])] = context[i+1]
+		}
+	}
+	if stack {
+		for _, frame := range le.stack {
+			if frame != "" {
+				report.Stack = append(report.Stack, frame)
+			}
+		}
+	}
+
+	return
+}
+
+//
+// Get a context value by key.
+func (e Error) Get(key string) (value interface{}, found bool) {
+	for i := 0; i+1 < len(e.context); i += 2 {
+		if fmt.Sprint(e.context[i]) == key {
+			value = e.context[i+1]
+			found = true
+		}
+	}
+
+	return
+}
+
+//
+// Supports errors.Is() through the wrapped chain.
+func (e Error) Is(target error) bool {
+	return errors.Is(e.wrapped, target)
+}
+
+//
+// Supports errors.As() through the wrapped chain.
+func (e Error) As(target interface{}) bool {
+	return errors.As(e.wrapped, target)
+}
+
+//
+// Render JSON.
+// Includes the context and stack.
+func (e Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ToReport(&e, true))
+}
+
+//
+// Render (human) formatted.
+//   %v  - The error description.
+//   %+v - The error description, context and stack.
+func (e Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		_, _ = io.WriteString(s, e.Error())
+		if s.Flag('+') {
+			for i := 0; i+1 < len(e.context); i += 2 {
+				_, _ = fmt.Fprintf(s, "\n%v: %v", e.context[i], e.context[i+1])
+			}
+			_, _ = io.WriteString(s, e.Stack())
+		}
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	}
+}
@@ -85,6 +85,8 @@ type Error struct {
 	context []interface{}
 	// Stack.
 	stack []string
+	// Classification.
+	class Class
 }
 
 //
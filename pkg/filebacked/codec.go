@@ -0,0 +1,105 @@
+package filebacked
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"io"
+)
+
+//
+// Entry header size (kind + size).
+const headerSize = 10
+
+//
+// Max (encoded) object size.
+// Larger entries are reported as corrupt.
+var MaxObjectSize int64 = 256 * 1024 * 1024
+
+//
+// Errors.
+var (
+	// Entry (encoded object) is corrupt.
+	CorruptErr = errors.New("corrupt entry")
+	// Object kind not found in the catalog.
+	KindErr = errors.New("kind not found in catalog")
+	// Index out of range.
+	IndexErr = errors.New("index out of range")
+)
+
+//
+// Read an entry.
+// The size is validated before the object is read.
+func readEntry(reader io.Reader) (kind uint16, b []byte, err error) {
+	header := make([]byte, headerSize)
+	_, err = io.ReadFull(reader, header)
+	if err != nil {
+		err = liberr.Wrap(CorruptErr, "reason", err.Error())
+		return
+	}
+	kind = binary.LittleEndian.Uint16(header[:2])
+	n := binary.LittleEndian.Uint64(header[2:])
+	if n > uint64(MaxObjectSize) {
+		err = liberr.Wrap(
+			CorruptErr,
+			"reason",
+			"size limit exceeded.",
+			"size",
+			n)
+		return
+	}
+	b = make([]byte, n)
+	_, err = io.ReadFull(reader, b)
+	if err != nil {
+		err = liberr.Wrap(CorruptErr, "reason", err.Error())
+		return
+	}
+
+	return
+}
+
+//
+// Decode (gob) an object.
+// Malformed content is reported as corrupt.
+func decode(b []byte, object interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = liberr.Wrap(
+				CorruptErr,
+				"reason",
+				fmt.Sprint(p))
+		}
+	}()
+	decoder := gob.NewDecoder(bytes.NewReader(b))
+	err = decoder.Decode(object)
+	if err != nil {
+		err = liberr.Wrap(CorruptErr, "reason", err.Error())
+	}
+
+	return
+}
+
+//
+// Decode an (encoded) entry.
+// The object kind must be in the catalog.
+// Format:
+//   | kind: 2 (uint16)
+//   | size: 8 (uint64)
+//   | object: n (gob encoded)
+func Decode(entry []byte) (object interface{}, err error) {
+	kind, b, err := readEntry(bytes.NewReader(entry))
+	if err != nil {
+		return
+	}
+	object, found := catalog.build(kind)
+	if !found {
+		err = liberr.Wrap(KindErr, "kind", kind)
+		return
+	}
+	err = decode(b, object)
+
+	return
+}
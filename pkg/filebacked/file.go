@@ -21,7 +21,7 @@ import (
 	"runtime"
 )
 
-var log = logging.WithName("filebacked")
+var log = logging.WithName(logging.FileBacked)
 
 //
 // File extension.
@@ -31,11 +31,20 @@ const (
 
 //
 // Working Directory.
+// See: UseMemory().
 var WorkingDir = "/tmp"
 
+//
+// Bytes written between (free) space checks.
+// See: MinFreeSpace.
+var SpaceCheckInterval int64 = 1 << 20
+
 //
 // Writer.
 type Writer struct {
+	// Working directory.
+	// Default: WorkingDir.
+	dir string
 	// File path.
 	path string
 	// File.
@@ -44,17 +53,31 @@ type Writer struct {
 	index []int64
 	// Dirty (needs flush).
 	dirty bool
+	// Size (bytes) written.
+	size int64
+	// Size (bytes) at the last space check.
+	checked int64
 }
 
 //
 // Append (write) object.
-func (w *Writer) Append(object interface{}) {
+// Returns SpaceErr when the directory has insufficient
+// (free) space. See: MinFreeSpace.
+func (w *Writer) Append(object interface{}) (err error) {
 	// Lazy open.
-	w.open()
+	err = w.open()
+	if err != nil {
+		return
+	}
+	err = w.checkSpace()
+	if err != nil {
+		return
+	}
 	// Seek end.
-	_, err := w.file.Seek(0, io.SeekEnd)
+	_, err = w.file.Seek(0, io.SeekEnd)
 	if err != nil {
-		panic(err)
+		err = liberr.Wrap(err, "path", w.path)
+		return
 	}
 	// Update catalog.
 	kind := catalog.add(object)
@@ -63,11 +86,17 @@ func (w *Writer) Append(object interface{}) {
 	encoder := gob.NewEncoder(&bfr)
 	err = encoder.Encode(object)
 	if err != nil {
-		panic(err)
+		err = liberr.Wrap(err, "path", w.path)
+		return
 	}
 	// Write entry.
-	offset := w.writeEntry(kind, bfr)
+	n := int64(bfr.Len())
+	offset, err := w.writeEntry(kind, bfr)
+	if err != nil {
+		return
+	}
 	w.index = append(w.index, offset)
+	w.size += n + headerSize
 	w.dirty = true
 
 	log.V(6).Info(
@@ -82,15 +111,33 @@ func (w *Writer) Append(object interface{}) {
 
 //
 // Build a reader.
+// Errors opening the writer (or linking the file) are
+// reported by the reader.
 func (w *Writer) Reader(shared bool) (reader *Reader) {
-	w.open()
-	w.flush()
+	err := w.open()
+	if err == nil {
+		err = w.flush()
+	}
+	if err != nil {
+		reader = &Reader{
+			index: w.index[:],
+			path:  w.path,
+			err:   err,
+		}
+		return
+	}
 	if !shared {
-		path := w.newPath()
+		path := w.newPath(pathlib.Dir(w.path))
 		err := os.Link(w.path, path)
 		if err != nil {
-			panic(err)
+			reader = &Reader{
+				index: w.index[:],
+				path:  path,
+				err:   liberr.Wrap(err, "path", w.path),
+			}
+			return
 		}
+		owned.add(path)
 		reader = &Reader{
 			index: w.index[:],
 			path:  path,
@@ -122,6 +169,7 @@ func (w *Writer) Reader(shared bool) (reader *Reader) {
 func (w *Writer) Close() {
 	defer func() {
 		_ = os.Remove(w.path)
+		owned.delete(w.path)
 	}()
 	if w.file == nil {
 		return
@@ -135,30 +183,41 @@ func (w *Writer) Close() {
 
 //
 // Flush.
-func (w *Writer) flush() {
+func (w *Writer) flush() (err error) {
 	if !w.dirty {
 		return
 	}
-	err := w.file.Sync()
+	err = w.file.Sync()
 	if err == nil {
 		w.dirty = false
 	} else {
-		panic(err)
+		err = liberr.Wrap(err, "path", w.path)
 	}
+
+	return
 }
 
 //
 // Open the writer.
-func (w *Writer) open() {
+func (w *Writer) open() (err error) {
 	if w.file != nil {
 		return
 	}
-	var err error
-	w.path = w.newPath()
+	dir := w.dir
+	if dir == "" {
+		dir = workingDir()
+	}
+	dir, err = selectDir(dir)
+	if err != nil {
+		return
+	}
+	w.path = w.newPath(dir)
 	w.file, err = os.Create(w.path)
 	if err != nil {
-		panic(err)
+		err = liberr.Wrap(err, "path", w.path)
+		return
 	}
+	owned.add(w.path)
 	log.V(5).Info(
 		"writer: opened.",
 		"path",
@@ -167,20 +226,51 @@ func (w *Writer) open() {
 	return
 }
 
+//
+// Check the (free) space in the directory.
+// Checked (at most) once per SpaceCheckInterval bytes written.
+func (w *Writer) checkSpace() (err error) {
+	if MinFreeSpace < 1 || w.size-w.checked < SpaceCheckInterval {
+		return
+	}
+	dir := pathlib.Dir(w.path)
+	if !hasSpace(dir) {
+		err = liberr.Wrap(
+			SpaceErr,
+			"dir",
+			dir,
+			"min",
+			MinFreeSpace)
+		return
+	}
+
+	w.checked = w.size
+
+	return
+}
+
 //
 // Write entry.
-func (w *Writer) writeEntry(kind uint16, bfr bytes.Buffer) (offset int64) {
+// A partially written entry is truncated.
+func (w *Writer) writeEntry(kind uint16, bfr bytes.Buffer) (offset int64, err error) {
 	file := w.file
-	offset, err := file.Seek(0, io.SeekCurrent)
+	offset, err = file.Seek(0, io.SeekCurrent)
 	if err != nil {
-		panic(err)
+		err = liberr.Wrap(err, "path", w.path)
+		return
 	}
+	defer func() {
+		if err != nil {
+			_ = file.Truncate(offset)
+			err = liberr.Wrap(err, "path", w.path)
+		}
+	}()
 	// Write object kind.
 	b := make([]byte, 2)
 	binary.LittleEndian.PutUint16(b, kind)
 	_, err = file.Write(b)
 	if err != nil {
-		panic(err)
+		return
 	}
 	// Write object encoded length.
 	n := bfr.Len()
@@ -188,15 +278,16 @@ func (w *Writer) writeEntry(kind uint16, bfr bytes.Buffer) (offset int64) {
 	binary.LittleEndian.PutUint64(b, uint64(n))
 	_, err = file.Write(b)
 	if err != nil {
-		panic(err)
+		return
 	}
 	// Write encoded object.
 	nWrite, err := file.Write(bfr.Bytes())
 	if err != nil {
-		panic(err)
+		return
 	}
 	if n != nWrite {
 		err = liberr.New("Write failed.")
+		return
 	}
 	log.V(6).Info(
 		"writer: write entry.",
@@ -211,11 +302,11 @@ func (w *Writer) writeEntry(kind uint16, bfr bytes.Buffer) (offset int64) {
 }
 
 //
-// New path.
-func (w *Writer) newPath() string {
+// New path (in the directory).
+func (w *Writer) newPath(dir string) string {
 	uid, _ := uuid.NewUUID()
 	name := uid.String() + Extension
-	return pathlib.Join(WorkingDir, name)
+	return pathlib.Join(dir, name)
 }
 
 //
@@ -229,6 +320,8 @@ type Reader struct {
 	index []int64
 	// shared
 	shared bool
+	// Error building the reader.
+	err error
 }
 
 //
@@ -240,27 +333,44 @@ func (r *Reader) Len() (length int) {
 
 //
 // Get the object at index.
+// Panics when the entry cannot be read. See: Get().
 func (r *Reader) At(index int) (object interface{}) {
-	// Lazy open.
-	r.open()
-	// Seek.
-	offset := r.index[index]
-	_, err := r.file.Seek(offset, io.SeekStart)
+	object, err := r.Get(index)
 	if err != nil {
 		panic(err)
 	}
-	// Read entry.
-	kind, b := r.readEntry()
-	// Decode object.
-	bfr := bytes.NewBuffer(b)
-	decoder := gob.NewDecoder(bfr)
+
+	return
+}
+
+//
+// Get the object at index.
+// Panics when the entry cannot be read. See: GetWith().
+func (r *Reader) AtWith(index int, object interface{}) {
+	err := r.GetWith(index, object)
+	if err != nil {
+		panic(err)
+	}
+}
+
+//
+// Get the object at index.
+// Returns CorruptErr, KindErr or IndexErr when the
+// entry cannot be read.
+func (r *Reader) Get(index int) (object interface{}, err error) {
+	kind, b, err := r.readAt(index)
+	if err != nil {
+		return
+	}
 	object, found := catalog.build(kind)
 	if !found {
-		panic(liberr.New("object not found in catalog."))
+		err = liberr.Wrap(KindErr, "path", r.path, "kind", kind)
+		return
 	}
-	err = decoder.Decode(object)
+	err = decode(b, object)
 	if err != nil {
-		panic(err)
+		err = liberr.Wrap(err, "path", r.path, "index", index)
+		return
 	}
 
 	log.V(6).Info(
@@ -274,24 +384,18 @@ func (r *Reader) At(index int) (object interface{}) {
 }
 
 //
-// Get the object at index.
-func (r *Reader) AtWith(index int, object interface{}) {
-	// Lazy open.
-	r.open()
-	// Seek.
-	offset := r.index[index]
-	_, err := r.file.Seek(offset, io.SeekStart)
+// Get the object at index (with) object.
+// Returns CorruptErr or IndexErr when the entry
+// cannot be read.
+func (r *Reader) GetWith(index int, object interface{}) (err error) {
+	_, b, err := r.readAt(index)
 	if err != nil {
-		panic(err)
+		return
 	}
-	// Read entry.
-	_, b := r.readEntry()
-	// Decode object.
-	bfr := bytes.NewBuffer(b)
-	decoder := gob.NewDecoder(bfr)
-	err = decoder.Decode(object)
+	err = decode(b, object)
 	if err != nil {
-		panic(err)
+		err = liberr.Wrap(err, "path", r.path, "index", index)
+		return
 	}
 
 	log.V(6).Info(
@@ -312,6 +416,7 @@ func (r *Reader) Close() {
 	}
 	defer func() {
 		_ = os.Remove(r.path)
+		owned.delete(r.path)
 	}()
 	if r.file == nil {
 		return
@@ -324,55 +429,55 @@ func (r *Reader) Close() {
 }
 
 //
-// Read next entry.
-func (r *Reader) readEntry() (kind uint16, bfr []byte) {
-	file := r.file
-	// Read object kind.
-	b := make([]byte, 2)
-	_, err := file.Read(b)
-	if err != nil {
-		if err != io.EOF {
-			panic(err)
-		}
+// Read the entry at index.
+func (r *Reader) readAt(index int) (kind uint16, b []byte, err error) {
+	if r.err != nil {
+		err = r.err
 		return
 	}
-	kind = binary.LittleEndian.Uint16(b)
-	// Read object encoded length.
-	b = make([]byte, 8)
-	_, err = file.Read(b)
+	if index < 0 || index >= len(r.index) {
+		err = liberr.Wrap(
+			IndexErr,
+			"path",
+			r.path,
+			"index",
+			index,
+			"length",
+			len(r.index))
+		return
+	}
+	// Lazy open.
+	err = r.open()
 	if err != nil {
-		if err != io.EOF {
-			panic(err)
-		}
 		return
 	}
-	n := int64(binary.LittleEndian.Uint64(b))
-	// Read encoded object.
-	b = make([]byte, n)
-	_, err = file.Read(b)
+	// Seek.
+	offset := r.index[index]
+	_, err = r.file.Seek(offset, io.SeekStart)
 	if err != nil {
-		if err != io.EOF {
-			panic(err)
-		}
+		err = liberr.Wrap(err)
 		return
 	}
-
-	bfr = b
+	// Read entry.
+	kind, b, err = readEntry(r.file)
+	if err != nil {
+		err = liberr.Wrap(err, "path", r.path, "index", index)
+	}
 
 	return
 }
 
 //
 // Open the reader.
-func (r *Reader) open() {
+func (r *Reader) open() (err error) {
 	if r.shared || r.file != nil {
 		return
 	}
 	// Open.
-	var err error
 	r.file, err = os.Open(r.path)
 	if err != nil {
-		panic(err)
+		err = liberr.Wrap(err)
+		return
 	}
 
 	log.V(5).Info(
@@ -0,0 +1,14 @@
+// +build gofuzz
+
+package filebacked
+
+//
+// Fuzz (go-fuzz) entry point.
+func Fuzz(data []byte) int {
+	_, err := Decode(data)
+	if err != nil {
+		return 0
+	}
+
+	return 1
+}
@@ -0,0 +1,126 @@
+package filebacked
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"os"
+	pathlib "path"
+)
+
+//
+// List (file) ownership handoff.
+// Transfers the backing file of a list built by a producer
+// (for example: a collector) to a consumer without re-encoding
+// the objects. Example:
+//   handoff := list.Handoff()
+//   ...
+//   adopted, err := fb.Adopt(handoff)
+type Handoff struct {
+	// File path.
+	Path string
+	// Direct access index.
+	Index []int64
+	// Size (bytes) written.
+	Size int64
+}
+
+//
+// Number of objects.
+func (h *Handoff) Len() int {
+	return len(h.Index)
+}
+
+//
+// Discard (delete) the file.
+// Used when the handoff will not be adopted.
+func (h *Handoff) Discard() {
+	if h.Path == "" {
+		return
+	}
+	_ = os.Remove(h.Path)
+	owned.delete(h.Path)
+	h.Path = ""
+	h.Index = nil
+}
+
+//
+// Hand off ownership of the backing file.
+// The list is emptied and may continue to be used. Readers
+// (iterators) built before the handoff are not affected.
+func (l *List) Handoff() (h *Handoff) {
+	w := &l.writer
+	h = &Handoff{
+		Index: w.index,
+		Size:  w.size,
+	}
+	if w.file != nil {
+		_ = w.flush()
+		_ = w.file.Close()
+		h.Path = w.path
+	}
+	l.closed()
+	l.writer = Writer{dir: w.dir}
+
+	log.V(5).Info(
+		"list: handoff.",
+		"path",
+		h.Path,
+		"length",
+		len(h.Index))
+
+	return
+}
+
+//
+// Adopt (take ownership of) the file.
+// Returns a list backed by the file. The handoff is consumed
+// and may be adopted only once. Returns CorruptErr when the
+// index does not match the file.
+func Adopt(h *Handoff) (list *List, err error) {
+	if h.Path == "" {
+		list = NewList()
+		return
+	}
+	file, err := os.OpenFile(h.Path, os.O_RDWR, 0)
+	if err != nil {
+		err = liberr.Wrap(err, "path", h.Path)
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		err = liberr.Wrap(err, "path", h.Path)
+		return
+	}
+	for _, offset := range h.Index {
+		if offset < 0 || offset+headerSize > info.Size() {
+			_ = file.Close()
+			err = liberr.Wrap(
+				CorruptErr,
+				"path",
+				h.Path,
+				"offset",
+				offset)
+			return
+		}
+	}
+	list = NewList()
+	list.writer = Writer{
+		dir:   pathlib.Dir(h.Path),
+		path:  h.Path,
+		file:  file,
+		index: h.Index,
+		size:  h.Size,
+	}
+	owned.add(h.Path)
+	h.Path = ""
+	h.Index = nil
+
+	log.V(5).Info(
+		"list: adopted.",
+		"path",
+		list.writer.path,
+		"length",
+		list.Len())
+
+	return
+}
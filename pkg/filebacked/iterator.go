@@ -3,12 +3,14 @@ package filebacked
 //
 // Iterator.
 // Read-only collection with stateful iteration.
+// Supports random access (At).
 type Iterator interface {
 	// Number of items.
 	Len() int
 	// Reverse.
 	Reverse()
 	// Object at index.
+	// Panics when the object cannot be read. See: Get().
 	At(index int) interface{}
 	// Object at index (with).
 	AtWith(int, interface{})
@@ -20,6 +22,60 @@ type Iterator interface {
 	Close()
 }
 
+//
+// Iterator (optional) error reporting.
+type Getter interface {
+	// Object at index.
+	Get(index int) (interface{}, error)
+	// Object at index (with).
+	GetWith(int, interface{}) error
+	// The error that ended the iteration.
+	Err() error
+}
+
+//
+// Iterator (optional) multiple passes.
+type Resetter interface {
+	// Reset (rewind) the iteration.
+	// The next object is the first.
+	Reset()
+}
+
+//
+// Reset (rewind) the iteration.
+// Returns false when not supported by the iterator.
+func Reset(itr Iterator) (reset bool) {
+	if resetter, cast := itr.(Resetter); cast {
+		resetter.Reset()
+		reset = true
+	}
+
+	return
+}
+
+//
+// Get the object at index.
+// Returns the read error when supported by the iterator.
+func Get(itr Iterator, index int) (object interface{}, err error) {
+	if getter, cast := itr.(Getter); cast {
+		object, err = getter.Get(index)
+	} else {
+		object = itr.At(index)
+	}
+
+	return
+}
+
+//
+// Get the error that ended the iteration (if any).
+func ErrOf(itr Iterator) (err error) {
+	if getter, cast := itr.(Getter); cast {
+		err = getter.Err()
+	}
+
+	return
+}
+
 //
 // Iterator.
 type FbIterator struct {
@@ -27,13 +83,21 @@ type FbIterator struct {
 	*Reader
 	// Current position.
 	current int
+	// Read error.
+	err error
 }
 
 //
 // Next object.
+// The iteration ends when the object cannot be read. See: Err().
 func (r *FbIterator) Next() (object interface{}, hasNext bool) {
-	if r.current < r.Len() {
-		object = r.At(r.current)
+	if r.err == nil && r.current < r.Len() {
+		object, r.err = r.Get(r.current)
+		if r.err != nil {
+			r.failed()
+			object = nil
+			return
+		}
 		r.current++
 		hasNext = true
 	}
@@ -43,9 +107,14 @@ func (r *FbIterator) Next() (object interface{}, hasNext bool) {
 
 //
 // Next object.
+// The iteration ends when the object cannot be read. See: Err().
 func (r *FbIterator) NextWith(object interface{}) (hasNext bool) {
-	if r.current < r.Len() {
-		r.AtWith(r.current, object)
+	if r.err == nil && r.current < r.Len() {
+		r.err = r.GetWith(r.current, object)
+		if r.err != nil {
+			r.failed()
+			return
+		}
 		r.current++
 		hasNext = true
 	}
@@ -53,6 +122,31 @@ func (r *FbIterator) NextWith(object interface{}) (hasNext bool) {
 	return
 }
 
+//
+// The error that ended the iteration.
+func (r *FbIterator) Err() error {
+	return r.err
+}
+
+//
+// Reset (rewind) the iteration.
+func (r *FbIterator) Reset() {
+	r.current = 0
+	r.err = nil
+}
+
+//
+// Log the read error.
+func (r *FbIterator) failed() {
+	log.Error(
+		r.err,
+		"iterator: read failed.",
+		"path",
+		r.path,
+		"index",
+		r.current)
+}
+
 //
 // Reverse the list.
 func (r *FbIterator) Reverse() {
@@ -80,6 +174,11 @@ type EmptyIterator struct {
 func (*EmptyIterator) Reverse() {
 }
 
+//
+// Reset.
+func (*EmptyIterator) Reset() {
+}
+
 //
 // Length.
 func (*EmptyIterator) Len() int {
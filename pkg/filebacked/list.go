@@ -55,6 +55,12 @@ for {
     }
     ...
 }
+
+//
+// Hand off (the file) to a consumer.
+handoff := list.Handoff()
+...
+adopted, err := fb.Adopt(handoff)
 */
 package filebacked
 
@@ -74,30 +80,60 @@ func NewList() (list *List) {
 	return
 }
 
+//
+// List factory.
+// The backing file is created in the specified
+// directory rather than the WorkingDir.
+func NewListIn(dir string) (list *List) {
+	list = NewList()
+	list.writer.dir = dir
+	return
+}
+
 //
 // File-backed list.
 type List struct {
 	// File writer.
 	writer Writer
+	// Close hooks by key.
+	hooks map[interface{}]*closeHook
+}
+
+//
+// Close hook.
+type closeHook struct {
+	// Called with the bytes written since registered.
+	fn func(int64)
+	// Size (bytes) when registered.
+	size int64
 }
 
 //
 // Append an object.
-func (l *List) Append(object interface{}) {
+// Objects (appended) before an error are retained.
+// Returns SpaceErr when the directory has insufficient
+// (free) space. See: MinFreeSpace.
+func (l *List) Append(object interface{}) (err error) {
 	switch object.(type) {
 	case Iterator:
 		itr := object.(Iterator)
 		for {
 			object, hasNext := itr.Next()
 			if hasNext {
-				l.writer.Append(object)
+				err = l.writer.Append(object)
+				if err != nil {
+					return
+				}
 			} else {
+				err = ErrOf(itr)
 				break
 			}
 		}
 	default:
-		l.writer.Append(object)
+		err = l.writer.Append(object)
 	}
+
+	return
 }
 
 //
@@ -107,7 +143,15 @@ func (l *List) Len() int {
 	return len(l.writer.index)
 }
 
+//
+// Size.
+// Number of bytes written (encoded).
+func (l *List) Size() int64 {
+	return l.writer.size
+}
+
 // Object at index.
+// Panics when the object cannot be read. See: Get().
 func (l *List) At(index int) (object interface{}) {
 	reader := l.writer.Reader(true)
 	object = reader.At(index)
@@ -115,12 +159,29 @@ func (l *List) At(index int) (object interface{}) {
 }
 
 // Object at index.
+// Panics when the object cannot be read. See: GetWith().
 func (l *List) AtWith(index int, object interface{}) {
 	reader := l.writer.Reader(true)
 	reader.AtWith(index, object)
 	return
 }
 
+//
+// Get the object at index.
+func (l *List) Get(index int) (object interface{}, err error) {
+	reader := l.writer.Reader(true)
+	object, err = reader.Get(index)
+	return
+}
+
+//
+// Get the object at index (with).
+func (l *List) GetWith(index int, object interface{}) (err error) {
+	reader := l.writer.Reader(true)
+	err = reader.GetWith(index, object)
+	return
+}
+
 //
 // Get an iterator.
 func (l *List) Iter() (itr Iterator) {
@@ -135,8 +196,38 @@ func (l *List) Iter() (itr Iterator) {
 	return
 }
 
+//
+// Register a function called when the list is closed (or
+// handed off) with the number of bytes written since registered.
+// Functions are registered by key and registering a key
+// already registered has no effect.
+func (l *List) OnClose(key interface{}, fn func(int64)) {
+	if l.hooks == nil {
+		l.hooks = map[interface{}]*closeHook{}
+	}
+	if _, found := l.hooks[key]; found {
+		return
+	}
+	l.hooks[key] = &closeHook{
+		fn:   fn,
+		size: l.Size(),
+	}
+}
+
 //
 // Close (delete) the list.
+// Close hooks are called (once).
 func (l *List) Close() {
 	l.writer.Close()
+	l.closed()
+}
+
+//
+// Call the close hooks.
+func (l *List) closed() {
+	hooks := l.hooks
+	l.hooks = nil
+	for _, hook := range hooks {
+		hook.fn(l.Size() - hook.size)
+	}
 }
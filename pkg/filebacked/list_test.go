@@ -1,8 +1,12 @@
 package filebacked
 
 import (
+	"errors"
 	"fmt"
 	"github.com/onsi/gomega"
+	"io/ioutil"
+	"math"
+	"os"
 	"testing"
 	"time"
 )
@@ -242,3 +246,321 @@ func __TestListPerf(t *testing.T) {
 	duration = time.Since(mark)
 	fmt.Printf("AtWith() total=%s per:%s\n", duration, duration/time.Duration(N))
 }
+
+func TestIteratorReset(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	list := NewList()
+	for i := 0; i < 3; i++ {
+		list.Append(User{ID: i})
+	}
+	itr := list.Iter()
+	defer itr.Close()
+	for pass := 0; pass < 2; pass++ {
+		n := 0
+		for {
+			object, hasNext := itr.Next()
+			if !hasNext {
+				break
+			}
+			g.Expect(object.(*User).ID).To(gomega.Equal(n))
+			n++
+		}
+		g.Expect(n).To(gomega.Equal(itr.Len()))
+		g.Expect(Reset(itr)).To(gomega.BeTrue())
+	}
+	empty := &EmptyIterator{}
+	g.Expect(Reset(empty)).To(gomega.BeTrue())
+	_, hasNext := empty.Next()
+	g.Expect(hasNext).To(gomega.BeFalse())
+}
+
+func TestDiskUsage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "filebacked")
+	g.Expect(err).To(gomega.BeNil())
+	defer os.RemoveAll(dir)
+	saved := WorkingDir
+	WorkingDir = dir
+	defer func() {
+		WorkingDir = saved
+	}()
+	usage, err := DiskUsage()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(usage.Dir).To(gomega.Equal(dir))
+	g.Expect(usage.Files).To(gomega.Equal(0))
+	list := NewList()
+	list.Append(1)
+	list.Append(2)
+	usage, err = DiskUsage()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(usage.Files).To(gomega.Equal(1))
+	g.Expect(usage.Bytes > 0).To(gomega.BeTrue())
+	g.Expect(list.Size()).To(gomega.Equal(usage.Bytes))
+	list.Close()
+	usage, err = DiskUsage()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(usage.Files).To(gomega.Equal(0))
+}
+
+func TestCorrupt(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	type Person struct {
+		ID   int
+		Name string
+	}
+
+	writer := &Writer{}
+	defer writer.Close()
+	writer.Append(&Person{ID: 1, Name: "Elmer"})
+	writer.Append(&Person{ID: 2, Name: "Fudd"})
+	reader := writer.Reader(false)
+	defer reader.Close()
+	// valid.
+	object, err := reader.Get(1)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.(*Person).ID).To(gomega.Equal(2))
+	// index out of range.
+	_, err = reader.Get(2)
+	g.Expect(errors.Is(err, IndexErr)).To(gomega.BeTrue())
+	_, err = reader.Get(-1)
+	g.Expect(errors.Is(err, IndexErr)).To(gomega.BeTrue())
+	// truncated.
+	err = os.Truncate(reader.path, reader.index[1]+headerSize+2)
+	g.Expect(err).To(gomega.BeNil())
+	_, err = reader.Get(1)
+	g.Expect(errors.Is(err, CorruptErr)).To(gomega.BeTrue())
+	g.Expect(func() { reader.At(1) }).To(gomega.Panic())
+	// iterator.
+	itr := &FbIterator{Reader: reader}
+	_, hasNext := itr.Next()
+	g.Expect(hasNext).To(gomega.BeTrue())
+	_, hasNext = itr.Next()
+	g.Expect(hasNext).To(gomega.BeFalse())
+	g.Expect(errors.Is(ErrOf(itr), CorruptErr)).To(gomega.BeTrue())
+	_, err = Get(itr, 1)
+	g.Expect(errors.Is(err, CorruptErr)).To(gomega.BeTrue())
+	// malformed object.
+	file, err := os.OpenFile(reader.path, os.O_WRONLY, 0)
+	g.Expect(err).To(gomega.BeNil())
+	_, err = file.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, headerSize)
+	g.Expect(err).To(gomega.BeNil())
+	_ = file.Close()
+	person := &Person{}
+	err = reader.GetWith(0, person)
+	g.Expect(errors.Is(err, CorruptErr)).To(gomega.BeTrue())
+	// decode.
+	_, err = Decode([]byte{})
+	g.Expect(errors.Is(err, CorruptErr)).To(gomega.BeTrue())
+	_, err = Decode([]byte{1, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	g.Expect(errors.Is(err, CorruptErr)).To(gomega.BeTrue())
+	_, err = Decode([]byte{0xff, 0xff, 1, 0, 0, 0, 0, 0, 0, 0, 0})
+	g.Expect(errors.Is(err, KindErr)).To(gomega.BeTrue())
+}
+
+func TestHandoff(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	list := NewList()
+	for i := 0; i < 3; i++ {
+		list.Append(User{ID: i})
+	}
+	itr := list.Iter()
+	defer itr.Close()
+	handoff := list.Handoff()
+	g.Expect(handoff.Len()).To(gomega.Equal(3))
+	g.Expect(list.Len()).To(gomega.Equal(0))
+	path := handoff.Path
+	// existing reader not affected.
+	g.Expect(itr.At(2).(*User).ID).To(gomega.Equal(2))
+	// producer list closed.
+	list.Close()
+	_, err := os.Stat(path)
+	g.Expect(err).To(gomega.BeNil())
+	// adopted.
+	adopted, err := Adopt(handoff)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(handoff.Path).To(gomega.BeEmpty())
+	g.Expect(adopted.Len()).To(gomega.Equal(3))
+	adopted.Append(User{ID: 3})
+	g.Expect(adopted.Len()).To(gomega.Equal(4))
+	for i := 0; i < adopted.Len(); i++ {
+		g.Expect(adopted.At(i).(*User).ID).To(gomega.Equal(i))
+	}
+	adopted.Close()
+	_, err = os.Stat(path)
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+	// empty.
+	adopted, err = Adopt(NewList().Handoff())
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(adopted.Len()).To(gomega.Equal(0))
+	// corrupt.
+	list = NewList()
+	list.Append(User{ID: 0})
+	handoff = list.Handoff()
+	handoff.Index = append(handoff.Index, handoff.Size+100)
+	_, err = Adopt(handoff)
+	g.Expect(errors.Is(err, CorruptErr)).To(gomega.BeTrue())
+	// discarded.
+	path = handoff.Path
+	handoff.Discard()
+	_, err = os.Stat(path)
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+}
+
+func TestWorkingDir(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	type User struct {
+		ID int
+	}
+
+	workingDir := WorkingDir
+	defer func() {
+		WorkingDir = workingDir
+		FallbackDir = ""
+		MinFreeSpace = 0
+		SpaceCheckInterval = 1 << 20
+		UseDisk()
+	}()
+	dir, err := ioutil.TempDir("", "fb-dir")
+	g.Expect(err).To(gomega.BeNil())
+	defer os.RemoveAll(dir)
+	fallback, err := ioutil.TempDir("", "fb-fallback")
+	g.Expect(err).To(gomega.BeNil())
+	defer os.RemoveAll(fallback)
+	count := func(dir string) int {
+		entries, _ := ioutil.ReadDir(dir)
+		return len(entries)
+	}
+	// per-list.
+	list := NewListIn(dir)
+	list.Append(User{ID: 1})
+	g.Expect(count(dir)).To(gomega.Equal(1))
+	itr := list.Iter()
+	g.Expect(count(dir)).To(gomega.Equal(2))
+	itr.Close()
+	list.Close()
+	g.Expect(count(dir)).To(gomega.Equal(0))
+	// insufficient (including fallback).
+	free, err := FreeSpace(dir)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(free > 0).To(gomega.BeTrue())
+	MinFreeSpace = math.MaxInt64
+	FallbackDir = fallback
+	list = NewListIn(dir)
+	err = list.Append(User{ID: 1})
+	g.Expect(errors.Is(err, SpaceErr)).To(gomega.BeTrue())
+	g.Expect(list.Len()).To(gomega.Equal(0))
+	_, err = list.Get(0)
+	g.Expect(errors.Is(err, SpaceErr)).To(gomega.BeTrue())
+	itr = list.Iter()
+	g.Expect(itr.Len()).To(gomega.Equal(0))
+	list.Close()
+	// sufficient.
+	MinFreeSpace = free / 2
+	list = NewListIn(dir)
+	err = list.Append(User{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count(dir)).To(gomega.Equal(1))
+	// insufficient (checked on write).
+	SpaceCheckInterval = 1
+	MinFreeSpace = math.MaxInt64
+	err = list.Append(User{ID: 2})
+	g.Expect(errors.Is(err, SpaceErr)).To(gomega.BeTrue())
+	g.Expect(list.Len()).To(gomega.Equal(1))
+	list.Close()
+	// memory.
+	WorkingDir = fallback
+	FallbackDir = ""
+	MinFreeSpace = 0
+	selected := UseMemory(0)
+	g.Expect(WorkingDir).To(gomega.Equal(fallback))
+	if Memory(selected) {
+		g.Expect(fallbackDir()).To(gomega.Equal(fallback))
+	} else {
+		g.Expect(selected).To(gomega.Equal(fallback))
+	}
+	usage, err := DiskUsage()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(usage.Dir).To(gomega.Equal(selected))
+	g.Expect(usage.Memory).To(gomega.Equal(Memory(selected)))
+}
+
+func TestRelease(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	Release()
+	// closed.
+	closed := NewList()
+	closed.Append(User{ID: 0})
+	closed.Close()
+	// not closed.
+	list := NewList()
+	list.Append(User{ID: 1})
+	itr := list.Iter().(*FbIterator)
+	g.Expect(itr.Len()).To(gomega.Equal(1))
+	paths := []string{list.writer.path, itr.path}
+	for _, path := range paths {
+		_, err := os.Stat(path)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	g.Expect(Release()).To(gomega.Equal(2))
+	for _, path := range paths {
+		_, err := os.Stat(path)
+		g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+	}
+	g.Expect(Release()).To(gomega.Equal(0))
+}
+
+func TestOnClose(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	released := int64(0)
+	hook := func(n int64) {
+		released += n
+	}
+	// closed.
+	list := NewList()
+	list.Append(User{ID: 0})
+	list.OnClose("A", hook)
+	list.OnClose("A", hook)
+	before := list.Size()
+	list.Append(User{ID: 1})
+	list.Close()
+	g.Expect(released).To(gomega.Equal(list.Size() - before))
+	list.Close()
+	g.Expect(released).To(gomega.Equal(list.Size() - before))
+	// handoff.
+	released = 0
+	list = NewList()
+	list.OnClose("A", hook)
+	list.Append(User{ID: 2})
+	size := list.Size()
+	h := list.Handoff()
+	defer h.Discard()
+	g.Expect(released).To(gomega.Equal(size))
+	list.Close()
+	g.Expect(released).To(gomega.Equal(size))
+}
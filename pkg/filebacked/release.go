@@ -0,0 +1,72 @@
+package filebacked
+
+import (
+	"os"
+	"sync"
+)
+
+//
+// Backing files owned by the process.
+var owned = fileSet{}
+
+//
+// Set of (backing) file paths.
+type fileSet struct {
+	// Paths.
+	content map[string]bool
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Add a path.
+func (r *fileSet) add(path string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[string]bool{}
+	}
+	r.content[path] = true
+}
+
+//
+// Delete a path.
+func (r *fileSet) delete(path string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.content, path)
+}
+
+//
+// Take (and clear) the paths.
+func (r *fileSet) take() (paths []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for path := range r.content {
+		paths = append(paths, path)
+	}
+	r.content = nil
+	return
+}
+
+//
+// Release (delete) the backing files owned by the process.
+// Intended to be called on shutdown so that (stale) files of
+// lists and iterators not closed are not left in the working
+// directory. Lists and iterators must not be used after
+// released. Returns the number of files deleted.
+func Release() (n int) {
+	for _, path := range owned.take() {
+		err := os.Remove(path)
+		if err == nil {
+			n++
+		}
+	}
+
+	log.V(3).Info(
+		"backing files released.",
+		"deleted",
+		n)
+
+	return
+}
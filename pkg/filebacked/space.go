@@ -0,0 +1,178 @@
+package filebacked
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"os"
+	"sync"
+)
+
+//
+// Memory-backed (tmpfs) directories (in order preferred).
+var MemoryDirs = []string{"/dev/shm"}
+
+//
+// Fallback (disk) directory used when the working
+// directory has insufficient space. "" = none, unless
+// a memory-backed directory is used in which case the
+// WorkingDir is the fallback.
+var FallbackDir = ""
+
+//
+// Min free space (bytes) required in the working
+// directory when a backing file is created and (again)
+// as it is written. See: SpaceCheckInterval.
+// 0 = not checked.
+var MinFreeSpace int64 = 0
+
+//
+// The selected memory-backed directory.
+// See: UseMemory().
+var memory struct {
+	// Directory. "" = not selected.
+	dir string
+	// Protect fields.
+	mutex sync.RWMutex
+}
+
+//
+// Errors.
+var (
+	// Insufficient (free) space.
+	SpaceErr = errors.New("insufficient space")
+)
+
+//
+// Use a memory-backed (tmpfs) working directory.
+// The first memory-backed directory (see: MemoryDirs) with
+// at least `minFree` bytes available is selected and the
+// (disk) working directory becomes the fallback. The working
+// directory is not changed when none is available. Memory
+// directories (e.g. /dev/shm) are commonly small so setting
+// the MinFreeSpace is recommended.
+// Returns the working directory.
+func UseMemory(minFree int64) (dir string) {
+	selected := ""
+	for _, memDir := range MemoryDirs {
+		if !Memory(memDir) {
+			continue
+		}
+		free, err := FreeSpace(memDir)
+		if err != nil || free < minFree {
+			continue
+		}
+		selected = memDir
+		break
+	}
+	memory.mutex.Lock()
+	memory.dir = selected
+	memory.mutex.Unlock()
+
+	dir = workingDir()
+
+	log.V(3).Info(
+		"working directory selected.",
+		"dir",
+		dir,
+		"memory",
+		selected != "",
+		"fallback",
+		fallbackDir())
+
+	return
+}
+
+//
+// Use the (disk) WorkingDir.
+// Reverts UseMemory().
+func UseDisk() {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+	memory.dir = ""
+}
+
+//
+// The working directory.
+// The selected memory-backed directory or the WorkingDir.
+func workingDir() (dir string) {
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+	dir = memory.dir
+	if dir == "" {
+		dir = WorkingDir
+	}
+
+	return
+}
+
+//
+// The fallback directory.
+// The FallbackDir or the WorkingDir when a
+// memory-backed directory is selected.
+func fallbackDir() (dir string) {
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+	dir = FallbackDir
+	if dir == "" && memory.dir != "" {
+		dir = WorkingDir
+	}
+
+	return
+}
+
+//
+// Select the directory for a (new) backing file.
+// The fallback directory is selected when the
+// preferred directory has insufficient space.
+// Returns SpaceErr when neither has sufficient space.
+func selectDir(preferred string) (dir string, err error) {
+	dir = preferred
+	if hasSpace(dir) {
+		return
+	}
+	fallback := fallbackDir()
+	if fallback != "" && fallback != dir && hasSpace(fallback) {
+		log.V(3).Info(
+			"insufficient space: using fallback.",
+			"dir",
+			dir,
+			"fallback",
+			fallback)
+		dir = fallback
+		return
+	}
+
+	err = liberr.Wrap(
+		SpaceErr,
+		"dir",
+		dir,
+		"min",
+		MinFreeSpace)
+
+	return
+}
+
+//
+// The directory has (at least) the min free space.
+func hasSpace(dir string) bool {
+	if MinFreeSpace < 1 {
+		return true
+	}
+	free, err := FreeSpace(dir)
+	if err != nil {
+		return false
+	}
+
+	return free >= MinFreeSpace
+}
+
+//
+// The directory exists and is writable.
+func writable(dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+
+	return info.Mode().Perm()&0222 != 0
+}
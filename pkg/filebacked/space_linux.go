@@ -0,0 +1,40 @@
+package filebacked
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"syscall"
+)
+
+//
+// tmpfs (statfs) magic.
+const tmpfsMagic = 0x01021994
+
+//
+// Free space (bytes) available in the directory.
+func FreeSpace(dir string) (free int64, err error) {
+	stat := syscall.Statfs_t{}
+	err = syscall.Statfs(dir, &stat)
+	if err != nil {
+		err = liberr.Wrap(err, "dir", dir)
+		return
+	}
+
+	free = int64(stat.Bavail) * int64(stat.Bsize)
+
+	return
+}
+
+//
+// The directory is memory-backed (tmpfs) and writable.
+func Memory(dir string) bool {
+	if !writable(dir) {
+		return false
+	}
+	stat := syscall.Statfs_t{}
+	err := syscall.Statfs(dir, &stat)
+	if err != nil {
+		return false
+	}
+
+	return int64(stat.Type) == tmpfsMagic
+}
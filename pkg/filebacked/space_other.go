@@ -0,0 +1,22 @@
+// +build !linux
+
+package filebacked
+
+import (
+	"math"
+)
+
+//
+// Free space (bytes) available in the directory.
+// Not supported (unlimited).
+func FreeSpace(dir string) (free int64, err error) {
+	free = math.MaxInt64
+	return
+}
+
+//
+// The directory is memory-backed (tmpfs) and writable.
+// Not supported.
+func Memory(dir string) bool {
+	return false
+}
@@ -0,0 +1,44 @@
+package filebacked
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"io/ioutil"
+	"strings"
+)
+
+//
+// Disk usage.
+type Usage struct {
+	// Working directory.
+	Dir string `json:"dir"`
+	// Number of (backing) files.
+	Files int `json:"files"`
+	// Total size (bytes).
+	Bytes int64 `json:"bytes"`
+	// Free space (bytes).
+	Free int64 `json:"free"`
+	// Memory-backed (tmpfs).
+	Memory bool `json:"memory"`
+}
+
+//
+// Disk usage by backing files in the working directory.
+func DiskUsage() (usage Usage, err error) {
+	usage.Dir = workingDir()
+	entries, err := ioutil.ReadDir(usage.Dir)
+	if err != nil {
+		err = liberr.Wrap(err, "dir", usage.Dir)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), Extension) {
+			continue
+		}
+		usage.Files++
+		usage.Bytes += entry.Size()
+	}
+	usage.Memory = Memory(usage.Dir)
+	usage.Free, err = FreeSpace(usage.Dir)
+
+	return
+}
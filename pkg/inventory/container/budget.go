@@ -0,0 +1,404 @@
+package container
+
+import (
+	"context"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// Budget resources.
+const (
+	// Filebacked buffers (bytes).
+	BudgetBuffers = "buffers"
+	// Concurrent provider API calls.
+	BudgetCalls = "calls"
+	// Reconcile interval.
+	BudgetInterval = "interval"
+)
+
+//
+// Errors.
+var (
+	// The (buffer) budget has been exceeded.
+	BudgetErr = errors.New("budget exceeded")
+)
+
+//
+// Budget metrics.
+// Registered with the (web) metrics registry.
+var (
+	// Budget usage by collector and resource.
+	BudgetGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "inventory_budget_usage",
+			Help: "Collector resource usage (buffer bytes, calls in flight).",
+		},
+		[]string{"collector", "resource"})
+	// Budget enforced (rejected or delayed) by collector and resource.
+	BudgetCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_budget_enforced_total",
+			Help: "Number of requests rejected or delayed by collector budgets.",
+		},
+		[]string{"collector", "resource"})
+)
+
+//
+// Collector resource budget.
+// Prevents one (noisy) source from degrading the rest of
+// the inventory. The zero value is not limited.
+type Budget struct {
+	// Max (total) size of filebacked buffers (bytes).
+	// 0 = not limited.
+	MaxBufferBytes int64 `json:"maxBufferBytes,omitempty"`
+	// Max number of concurrent provider API calls.
+	// 0 = not limited.
+	MaxCalls int `json:"maxCalls,omitempty"`
+	// Min interval between (the start of) reconciles.
+	// 0 = not limited.
+	MinInterval time.Duration `json:"minInterval,omitempty"`
+}
+
+//
+// Budget usage.
+type BudgetUsage struct {
+	// Collector name.
+	Collector string `json:"collector"`
+	// Budget.
+	Budget Budget `json:"budget"`
+	// Size of filebacked buffers (bytes).
+	BufferBytes int64 `json:"bufferBytes"`
+	// Number of calls in flight.
+	Calls int `json:"calls"`
+	// Last reconcile (started).
+	Reconciled *time.Time `json:"reconciled,omitempty"`
+}
+
+//
+// Collector (optional) budget.
+// The container provides the meter for the collector
+// when added. See: Budgets.
+type Budgeted interface {
+	// Use the (budget) meter.
+	UseMeter(meter *Meter)
+}
+
+//
+// Budgets by collector.
+var Budgets = BudgetSet{}
+
+//
+// Budgets (meters) by collector name.
+// The zero value is ready to use.
+type BudgetSet struct {
+	// Meters by collector name.
+	content map[string]*Meter
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Set the budget for a collector.
+// Applied to the meter already in use.
+func (r *BudgetSet) Set(collector string, budget Budget) {
+	r.For(collector).setBudget(budget)
+
+	log.V(3).Info(
+		"budget set.",
+		"collector",
+		collector,
+		"budget",
+		budget)
+}
+
+//
+// Get the meter for a collector.
+// Created (not limited) as needed.
+func (r *BudgetSet) For(collector string) (meter *Meter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[string]*Meter{}
+	}
+	meter, found := r.content[collector]
+	if !found {
+		meter = &Meter{
+			collector: collector,
+			released:  make(chan struct{}),
+		}
+		r.content[collector] = meter
+	}
+
+	return
+}
+
+//
+// Delete the meter for a collector.
+func (r *BudgetSet) Delete(collector string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.content, collector)
+	for _, resource := range []string{BudgetBuffers, BudgetCalls} {
+		BudgetGauge.DeleteLabelValues(collector, resource)
+	}
+}
+
+//
+// List usage.
+// Ordered by collector.
+func (r *BudgetSet) List() (list []BudgetUsage) {
+	r.mutex.Lock()
+	meters := []*Meter{}
+	for _, meter := range r.content {
+		meters = append(meters, meter)
+	}
+	r.mutex.Unlock()
+	list = []BudgetUsage{}
+	for _, meter := range meters {
+		list = append(list, meter.Usage())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Collector < list[j].Collector
+	})
+
+	return
+}
+
+//
+// Collector budget meter.
+// Enforces the budget for a collector. Collectors are
+// expected to make provider API calls using Call(), append
+// to filebacked buffers using Append() and to begin the
+// (reconcile) transactions using Begin(). See: Budgeted.
+type Meter struct {
+	// Collector name.
+	collector string
+	// Budget.
+	budget Budget
+	// Size of filebacked buffers (bytes).
+	buffers int64
+	// Calls in flight.
+	calls int
+	// Closed (and replaced) when a call slot
+	// is released or the budget changed.
+	released chan struct{}
+	// Last reconcile (started).
+	reconciled time.Time
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Usage.
+func (r *Meter) Usage() (usage BudgetUsage) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	usage = BudgetUsage{
+		Collector:   r.collector,
+		Budget:      r.budget,
+		BufferBytes: r.buffers,
+		Calls:       r.calls,
+	}
+	if !r.reconciled.IsZero() {
+		reconciled := r.reconciled
+		usage.Reconciled = &reconciled
+	}
+
+	return
+}
+
+//
+// Make a provider API call.
+// Blocks until the number of calls in flight is within the
+// budget. Returns the context error when the context is done
+// before the call is made.
+func (r *Meter) Call(ctx context.Context, fn func() error) (err error) {
+	delayed := false
+	for {
+		r.mutex.Lock()
+		if r.budget.MaxCalls < 1 || r.calls < r.budget.MaxCalls {
+			r.calls++
+			r.gauge(BudgetCalls, float64(r.calls))
+			r.mutex.Unlock()
+			break
+		}
+		released := r.released
+		r.mutex.Unlock()
+		if !delayed {
+			delayed = true
+			BudgetCounter.WithLabelValues(r.collector, BudgetCalls).Inc()
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			err = liberr.Wrap(ctx.Err(), "collector", r.collector)
+			return
+		}
+	}
+	defer func() {
+		r.mutex.Lock()
+		r.calls--
+		r.gauge(BudgetCalls, float64(r.calls))
+		r.release()
+		r.mutex.Unlock()
+	}()
+	err = fn()
+	return
+}
+
+//
+// Reserve (filebacked) buffer bytes.
+// Returns BudgetErr when the budget would be exceeded.
+func (r *Meter) Reserve(n int64) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	max := r.budget.MaxBufferBytes
+	if max > 0 && r.buffers+n > max {
+		BudgetCounter.WithLabelValues(r.collector, BudgetBuffers).Inc()
+		err = liberr.Wrap(
+			BudgetErr,
+			"collector",
+			r.collector,
+			"resource",
+			BudgetBuffers,
+			"max",
+			max)
+		return
+	}
+	r.buffers += n
+	r.gauge(BudgetBuffers, float64(r.buffers))
+	return
+}
+
+//
+// Release (filebacked) buffer bytes.
+func (r *Meter) Release(n int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.buffers -= n
+	if r.buffers < 0 {
+		r.buffers = 0
+	}
+	r.gauge(BudgetBuffers, float64(r.buffers))
+}
+
+//
+// Append an object to a (filebacked) buffer.
+// Returns BudgetErr (and the object is not appended) when
+// the budget has been exhausted. The bytes written are
+// reserved until the list is closed.
+func (r *Meter) Append(list *fb.List, object interface{}) (err error) {
+	r.mutex.Lock()
+	max := r.budget.MaxBufferBytes
+	exhausted := max > 0 && r.buffers >= max
+	r.mutex.Unlock()
+	if exhausted {
+		BudgetCounter.WithLabelValues(r.collector, BudgetBuffers).Inc()
+		err = liberr.Wrap(
+			BudgetErr,
+			"collector",
+			r.collector,
+			"resource",
+			BudgetBuffers,
+			"max",
+			max)
+		return
+	}
+	list.OnClose(r, r.Release)
+	before := list.Size()
+	err = list.Append(object)
+	r.mutex.Lock()
+	r.buffers += list.Size() - before
+	r.gauge(BudgetBuffers, float64(r.buffers))
+	r.mutex.Unlock()
+	return
+}
+
+//
+// Discard a (filebacked) buffer.
+// The list is closed and the bytes released.
+func (r *Meter) Discard(list *fb.List) {
+	list.Close()
+}
+
+//
+// Begin a (reconcile) transaction.
+// The reconcile interval floor is enforced before the
+// transaction begins. See: Reconcile().
+func (r *Meter) Begin(ctx context.Context, db model.DB, labels ...string) (tx *model.Tx, err error) {
+	err = r.Reconcile(ctx)
+	if err != nil {
+		return
+	}
+	tx, err = db.BeginContext(ctx, labels...)
+	return
+}
+
+//
+// Wait for the reconcile interval floor.
+// Blocks until the min interval has elapsed since the
+// last reconcile started. Returns the context error when
+// the context is done before the interval has elapsed.
+// Must not be called within a transaction.
+func (r *Meter) Reconcile(ctx context.Context) (err error) {
+	r.mutex.Lock()
+	wait := time.Duration(0)
+	if !r.reconciled.IsZero() {
+		wait = r.budget.MinInterval - time.Since(r.reconciled)
+	}
+	if wait <= 0 {
+		r.reconciled = time.Now()
+		r.mutex.Unlock()
+		return
+	}
+	r.reconciled = time.Now().Add(wait)
+	r.mutex.Unlock()
+	BudgetCounter.WithLabelValues(r.collector, BudgetInterval).Inc()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = liberr.Wrap(ctx.Err(), "collector", r.collector)
+	}
+
+	return
+}
+
+//
+// Set the budget.
+// Calls waiting are re-evaluated.
+func (r *Meter) setBudget(budget Budget) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.budget = budget
+	r.release()
+}
+
+//
+// Notify calls waiting for a slot.
+func (r *Meter) release() {
+	close(r.released)
+	r.released = make(chan struct{})
+}
+
+//
+// Apply the budget (meter) to a collector.
+func (c *Container) applyBudget(collector Collector) {
+	if budgeted, cast := collector.(Budgeted); cast {
+		budgeted.UseMeter(Budgets.For(collector.Name()))
+	}
+}
+
+//
+// Update the usage gauge.
+func (r *Meter) gauge(resource string, value float64) {
+	BudgetGauge.WithLabelValues(r.collector, resource).Set(value)
+}
@@ -0,0 +1,89 @@
+package container
+
+import (
+	"context"
+	"errors"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/onsi/gomega"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBudget(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer Budgets.Delete("budget")
+	Budgets.Set(
+		"budget",
+		Budget{
+			MaxBufferBytes: 1,
+			MaxCalls:       2,
+			MinInterval:    50 * time.Millisecond,
+		})
+	meter := Budgets.For("budget")
+	g.Expect(Budgets.For("budget")).To(gomega.BeIdenticalTo(meter))
+	// Calls.
+	inFlight := 0
+	peak := 0
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = meter.Call(context.Background(), func() error {
+				mutex.Lock()
+				inFlight++
+				if inFlight > peak {
+					peak = inFlight
+				}
+				mutex.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mutex.Lock()
+				inFlight--
+				mutex.Unlock()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	g.Expect(peak).To(gomega.Equal(2))
+	g.Expect(meter.Usage().Calls).To(gomega.Equal(0))
+	// Buffers.
+	list := fb.NewList()
+	err := meter.Append(list, &TestObject2{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	err = meter.Append(list, &TestObject2{ID: 2})
+	g.Expect(errors.Is(err, BudgetErr)).To(gomega.BeTrue())
+	g.Expect(list.Len()).To(gomega.Equal(1))
+	g.Expect(meter.Usage().BufferBytes).To(gomega.Equal(list.Size()))
+	meter.Discard(list)
+	g.Expect(meter.Usage().BufferBytes).To(gomega.Equal(int64(0)))
+	list = fb.NewList()
+	err = meter.Append(list, &TestObject2{ID: 3})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(meter.Usage().BufferBytes > 0).To(gomega.BeTrue())
+	list.Close()
+	g.Expect(meter.Usage().BufferBytes).To(gomega.Equal(int64(0)))
+	// Interval.
+	DB := model.New("/tmp/test-budget.db", &TestObject2{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	mark := time.Now()
+	g.Expect(meter.Reconcile(context.Background())).To(gomega.BeNil())
+	tx, err := meter.Begin(context.Background(), DB)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(time.Since(mark) >= 50*time.Millisecond).To(gomega.BeTrue())
+	_ = tx.End()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.Expect(meter.Reconcile(ctx)).ToNot(gomega.BeNil())
+	// Listed.
+	list2 := Budgets.List()
+	g.Expect(len(list2)).To(gomega.Equal(1))
+	g.Expect(list2[0].Budget.MaxCalls).To(gomega.Equal(2))
+}
@@ -6,9 +6,38 @@ with the collection of desired models.
 package container
 
 import (
+	"context"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
 	fb "github.com/konveyor/controller/pkg/filebacked"
 	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/logging"
+	"github.com/konveyor/controller/pkg/tracing"
 	"reflect"
+	"time"
+)
+
+//
+// Field (eq) tag values.
+const (
+	// Ignored (not compared or updated).
+	EqIgnored = "-"
+	// Stored-only. Owned by the controller (for example:
+	// analysis results attached to collected models). Not
+	// compared and never overwritten by the desired model.
+	EqStored = "stored"
+	// Miss counter (int). The number of consecutive reconciles
+	// in which the stored model was missing from the desired.
+	// Not compared. Must be `quiet`. See: Collection.GracePeriod.
+	EqMissed = "missed"
+)
+
+//
+// Errors.
+var (
+	// The reconcile would delete more stored models
+	// than permitted by the delete limits.
+	DeleteLimitErr = errors.New("delete limit exceeded")
 )
 
 //
@@ -20,6 +49,40 @@ type Shepherd interface {
 	Update(stored, desired model.Model)
 }
 
+//
+// Policy applied when the desired models contain
+// duplicate PKs.
+type DuplicatePolicy int
+
+//
+// Duplicate policies.
+const (
+	// The last duplicate is used.
+	LastWins DuplicatePolicy = iota
+	// The first duplicate is used.
+	FirstWins
+	// The reconcile fails.
+	DuplicateError
+	// Duplicates are merged (in order) into the first
+	// using the shepherd Update().
+	Merge
+)
+
+//
+// When the OnDeleted (garbage) hook is called.
+type GarbagePhase int
+
+//
+// Garbage phases.
+const (
+	// Called before the transaction is committed.
+	// An error fails the reconcile.
+	BeforeCommit GarbagePhase = iota
+	// Called after the transaction has been committed.
+	// Errors are logged.
+	AfterCommit
+)
+
 //
 // Disposition model.
 type dpnModel struct {
@@ -27,11 +90,17 @@ type dpnModel struct {
 	itr fb.Iterator
 	// Index within the iterator.
 	index int
+	// Materialized (merged) model.
+	object model.Model
 }
 
 //
 // Model.
 func (r *dpnModel) model() (m model.Model) {
+	if r.object != nil {
+		m = r.object
+		return
+	}
 	object := r.itr.At(r.index)
 	m = object.(model.Model)
 	return
@@ -52,6 +121,8 @@ type Dispositions map[string]*Disposition
 
 //
 // Model collection.
+// Not modified by reconcile and may be used concurrently
+// provided each reconcile uses a distinct transaction.
 type Collection struct {
 	// Stored models.
 	Stored fb.Iterator
@@ -59,59 +130,342 @@ type Collection struct {
 	Tx *model.Tx
 	// An (optional) shepherd.
 	Shepherd Shepherd
+	// Duplicate (desired) PK policy.
+	Duplicates DuplicatePolicy
+	// An (optional) garbage hook.
+	// Called once per reconcile with the deleted (stored)
+	// models so that dependent (external) resources may be
+	// cleaned up. Not called when nothing was deleted.
+	OnDeleted func([]model.Model) error
+	// When the OnDeleted hook is called.
+	OnDeletedPhase GarbagePhase
+	// Delete grace period.
+	// The number of consecutive reconciles in which a stored
+	// model must be missing from the desired before it is
+	// deleted. Protects against (flapping) collectors that
+	// intermittently return partial data. Requires a miss
+	// counter field (tagged `sql:"quiet" eq:"missed"`) which
+	// is updated (and reset) by the reconcile. Being quiet,
+	// counter updates are not reported to watches. 0 or 1 =
+	// deleted when first missing.
+	GracePeriod int
+	// Max number of stored models deleted by a reconcile.
+	// The reconcile fails (DeleteLimitErr) without deleting
+	// when exceeded. Protects against an empty or truncated
+	// desired collection wiping the inventory.
+	// 0 = not limited.
+	MaxDeleteCount int
+	// Max percent of the stored models deleted by a reconcile.
+	// The reconcile fails (DeleteLimitErr) without deleting
+	// when exceeded. 0 = not limited.
+	MaxDeletePercent int
+	// Override (ignore) the delete limits.
+	// Intended to be set explicitly (for example: by an admin)
+	// when a large deletion is expected.
+	ForceDelete bool
+	// An (optional) desired model validator.
+	// Called for each desired model (first pass) before any
+	// changes are applied. An error fails the reconcile.
+	Validate func(model.Model) error
+	// Transaction operation (get, insert, update, delete)
+	// timeout. An operation not completed within the timeout
+	// is reported as stalled and fails the reconcile with a
+	// Stalled error. 0 = not watched.
+	OpTimeout time.Duration
+	// An (optional) stalled operation hook.
+	// Called (by the watchdog) when the timeout is exceeded
+	// while the operation is still running.
+	OnStalled func(*Stalled)
+}
+
+//
+// Reconcile phases.
+const (
+	PhaseDispositions = "dispositions"
+	PhaseAdd          = "add"
+	PhaseUpdate       = "update"
+	PhaseDelete       = "delete"
+)
+
+//
+// Reconcile result.
+type Result struct {
+	// Reconcile (correlation) ID.
+	ReconcileID string
+	// Number of desired models.
+	Desired int
 	// Number of models added.
 	Added int
-	// Number models updated.
+	// Number of models updated.
 	Updated int
-	// Number models deleted.
+	// Number of models deleted.
 	Deleted int
+	// Number of (unchanged) models skipped.
+	Skipped int
+	// Number of (missing) models retained during
+	// the delete grace period.
+	Missed int
+	// PKs of changed models by action (added|updated|deleted).
+	Changed map[string][]string
+	// PKs of duplicate desired models.
+	Duplicated []string
+	// Duration by phase.
+	Durations map[string]time.Duration
+	// Errors.
+	Errors []error
+}
+
+//
+// Build a result.
+func newResult() *Result {
+	return &Result{
+		Changed:   map[string][]string{},
+		Durations: map[string]time.Duration{},
+	}
+}
+
+//
+// Record a changed model.
+func (r *Result) changed(m model.Model, action string) {
+	r.Changed[action] = append(r.Changed[action], m.Pk())
+	reconciled(m, action)
+}
+
+//
+// Record an error.
+func (r *Result) failed(err error) {
+	if err != nil {
+		r.Errors = append(r.Errors, err)
+	}
+}
+
+//
+// Add models included in desired but not stored.
+func (r *Collection) Add(desired fb.Iterator) (result *Result, err error) {
+	result, err = r.AddContext(r.context(), desired)
+	return
 }
 
 //
 // Add models included in desired but not stored.
-func (r *Collection) Add(desired fb.Iterator) error {
-	return r.add(r.dispositions(desired))
+// Aborted when the context is done.
+func (r *Collection) AddContext(ctx context.Context, desired fb.Iterator) (result *Result, err error) {
+	result = newResult()
+	mp, err := r.dispositions(ctx, result, desired)
+	if err == nil {
+		err = r.add(ctx, result, mp)
+	}
+
+	result.failed(err)
+
+	return
 }
 
 //
 // Update models.
-func (r *Collection) Update(desired fb.Iterator) error {
-	return r.update(r.dispositions(desired))
+func (r *Collection) Update(desired fb.Iterator) (result *Result, err error) {
+	result, err = r.UpdateContext(r.context(), desired)
+	return
+}
+
+//
+// Update models.
+// Aborted when the context is done.
+func (r *Collection) UpdateContext(ctx context.Context, desired fb.Iterator) (result *Result, err error) {
+	result = newResult()
+	mp, err := r.dispositions(ctx, result, desired)
+	if err == nil {
+		err = r.update(ctx, result, mp)
+	}
+
+	result.failed(err)
+
+	return
+}
+
+//
+// Delete stored models not included in the desired.
+func (r *Collection) Delete(desired fb.Iterator) (result *Result, err error) {
+	result, err = r.DeleteContext(r.context(), desired)
+	return
 }
 
 //
 // Delete stored models not included in the desired.
-func (r *Collection) Delete(desired fb.Iterator) error {
-	return r.delete(r.dispositions(desired))
+// Aborted when the context is done.
+func (r *Collection) DeleteContext(ctx context.Context, desired fb.Iterator) (result *Result, err error) {
+	result = newResult()
+	mp, err := r.dispositions(ctx, result, desired)
+	if err == nil {
+		err = r.delete(ctx, result, mp)
+	}
+
+	result.failed(err)
+
+	return
 }
 
 //
 // Reconcile the collection.
 // Ensure the stored collection is as desired.
-func (r *Collection) Reconcile(desired fb.Iterator) (err error) {
-	mp := r.dispositions(desired)
-	err = r.delete(mp)
-	if err != nil {
+// Each phase is traced using the transaction context.
+func (r *Collection) Reconcile(desired fb.Iterator) (result *Result, err error) {
+	result, err = r.ReconcileContext(r.context(), desired)
+	return
+}
+
+//
+// Reconcile the collection.
+// Ensure the stored collection is as desired.
+// Cancellation is checked between dispositions. When the
+// context is done, the reconcile is aborted and the error
+// returned. The caller is expected to end (rollback) the
+// transaction.
+// A reconcile ID is generated unless carried by the context
+// and propagated (using the transaction context) to the DB
+// spans, watch events and logs caused by the reconcile.
+// The reconcile is planned and the plan applied. See: Plan()
+// and ApplyPlan() to inspect the changes before they are
+// applied.
+func (r *Collection) ReconcileContext(ctx context.Context, desired fb.Iterator) (result *Result, err error) {
+	result = newResult()
+	ctx, restore := r.correlate(ctx)
+	defer restore()
+	result.ReconcileID = logging.ReconcileIDOf(ctx)
+	log := log.ForContext(ctx)
+	defer func() {
+		result.failed(err)
+		if err == nil {
+			ReconcileCounter.WithLabelValues(ReconcileSucceeded).Inc()
+		} else {
+			ReconcileCounter.WithLabelValues(ReconcileFailed).Inc()
+		}
+	}()
+	ctx, span := tracing.Start(
+		ctx,
+		"collection.reconcile",
+		tracing.Reconcile,
+		result.ReconcileID)
+	defer tracing.End(span, &err)
+	var plan *Plan
+	err = r.phase(ctx, result, PhasePlan, func() (err error) {
+		plan, err = r.plan(ctx, result, result.ReconcileID, desired)
 		return
-	}
-	err = r.add(mp)
+	})
 	if err != nil {
 		return
 	}
-	err = r.update(mp)
+	err = r.apply(ctx, result, plan)
 	if err != nil {
 		return
 	}
 
+	span.Set(
+		"added",
+		result.Added,
+		"updated",
+		result.Updated,
+		"deleted",
+		result.Deleted,
+		"skipped",
+		result.Skipped,
+		"missed",
+		result.Missed,
+		"duplicated",
+		len(result.Duplicated))
+
+	log.V(3).Info(
+		"collection reconciled.",
+		"added",
+		result.Added,
+		"updated",
+		result.Updated,
+		"deleted",
+		result.Deleted,
+		"skipped",
+		result.Skipped,
+		"missed",
+		result.Missed)
+
 	return
 }
 
+//
+// Run a (traced and timed) reconcile phase.
+func (r *Collection) phase(ctx context.Context, result *Result, name string, fn func() error) (err error) {
+	_, span := tracing.Start(ctx, "collection."+name, tracing.Phase, name)
+	defer tracing.End(span, &err)
+	mark := time.Now()
+	err = fn()
+	result.Durations[name] += time.Since(mark)
+	return
+}
+
+//
+// Correlate the reconcile.
+// A reconcile ID is generated unless carried by the context (or
+// the transaction context). The transaction context is updated
+// so that the DB spans, watch events and logs caused by the
+// reconcile carry the ID. The returned function restores the
+// transaction context (scoping the ID to the reconcile) and is
+// expected to be called when the reconcile is done.
+func (r *Collection) correlate(ctx context.Context) (context.Context, func()) {
+	id := logging.ReconcileIDOf(ctx)
+	if id == "" && r.Tx != nil {
+		id = logging.ReconcileIDOf(r.Tx.Context())
+	}
+	if id == "" {
+		id = logging.NewReconcileID()
+	}
+	if logging.ReconcileIDOf(ctx) != id {
+		ctx = logging.WithReconcileID(ctx, id)
+	}
+	restore := func() {}
+	if r.Tx != nil && logging.ReconcileIDOf(r.Tx.Context()) != id {
+		prior := r.Tx.Context()
+		r.Tx.SetContext(logging.WithReconcileID(prior, id))
+		restore = func() {
+			r.Tx.SetContext(prior)
+		}
+	}
+
+	return ctx, restore
+}
+
+//
+// Context.
+// The transaction context when available.
+func (r *Collection) context() context.Context {
+	if r.Tx != nil {
+		return r.Tx.Context()
+	}
+
+	return context.Background()
+}
+
 //
 // Build the dispositions.
-func (r *Collection) dispositions(desired fb.Iterator) (mp map[string]*Disposition) {
+// Duplicate desired models are handled according
+// to the duplicate policy and reported.
+func (r *Collection) dispositions(ctx context.Context, result *Result, desired fb.Iterator) (mp Dispositions, err error) {
+	result.Desired = desired.Len()
+	err = r.validate(ctx, desired)
+	if err != nil {
+		return
+	}
 	mp = map[string]*Disposition{}
 	for i := 0; i < r.Stored.Len(); i++ {
-		object := r.Stored.At(i)
+		err = canceled(ctx)
+		if err != nil {
+			mp = nil
+			return
+		}
+		object, gErr := fb.Get(r.Stored, i)
+		if gErr != nil {
+			err = gErr
+			mp = nil
+			return
+		}
 		m := object.(model.Model)
 		mp[m.Pk()] = &Disposition{
 			stored: &dpnModel{
@@ -121,34 +475,147 @@ func (r *Collection) dispositions(desired fb.Iterator) (mp map[string]*Dispositi
 		}
 	}
 	for i := 0; i < desired.Len(); i++ {
-		object := desired.At(i)
+		err = canceled(ctx)
+		if err != nil {
+			mp = nil
+			return
+		}
+		object, gErr := fb.Get(desired, i)
+		if gErr != nil {
+			err = gErr
+			mp = nil
+			return
+		}
 		m := object.(model.Model)
-		if dpn, found := mp[m.Pk()]; !found {
+		dpn, found := mp[m.Pk()]
+		if !found {
 			mp[m.Pk()] = &Disposition{
 				desired: &dpnModel{
 					itr:   desired,
 					index: i,
 				},
 			}
-		} else {
+			continue
+		}
+		if dpn.desired == nil {
 			dpn.desired = &dpnModel{
 				itr:   desired,
 				index: i,
 			}
+			continue
+		}
+		err = r.duplicate(result, dpn, m, i)
+		if err != nil {
+			mp = nil
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Validate the desired models.
+// The models are read by index so the iteration
+// is not affected by the (validation) pass.
+func (r *Collection) validate(ctx context.Context, desired fb.Iterator) (err error) {
+	if r.Validate == nil {
+		return
+	}
+	for i := 0; i < desired.Len(); i++ {
+		err = canceled(ctx)
+		if err != nil {
+			return
+		}
+		var object interface{}
+		object, err = fb.Get(desired, i)
+		if err != nil {
+			return
+		}
+		err = r.validated(object.(model.Model))
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Validate a desired model.
+func (r *Collection) validated(m model.Model) (err error) {
+	if r.Validate == nil {
+		return
+	}
+	err = r.Validate(m)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"model",
+			model.Describe(m))
+	}
+
+	return
+}
+
+//
+// Handle a duplicate desired model.
+func (r *Collection) duplicate(result *Result, dpn *Disposition, m model.Model, index int) (err error) {
+	result.Duplicated = append(result.Duplicated, m.Pk())
+	log.V(3).Info(
+		"duplicate desired model.",
+		"model",
+		model.Describe(m),
+		"policy",
+		r.Duplicates)
+	switch r.Duplicates {
+	case FirstWins:
+	case DuplicateError:
+		err = liberr.New(
+			"duplicate desired model.",
+			"model",
+			model.Describe(m))
+	case Merge:
+		merged := dpn.desired.model()
+		r.shepherd().Update(merged, m)
+		dpn.desired.object = merged
+	default:
+		dpn.desired = &dpnModel{
+			itr:   dpn.desired.itr,
+			index: index,
 		}
 	}
 
 	return
 }
 
+//
+// The shepherd.
+func (r *Collection) shepherd() (shepherd Shepherd) {
+	shepherd = r.Shepherd
+	if shepherd == nil {
+		shepherd = &DefaultShepherd{}
+	}
+
+	return
+}
+
 //
 // Add models included in desired but not stored.
-func (r *Collection) add(dispositions Dispositions) (err error) {
+func (r *Collection) add(ctx context.Context, result *Result, dispositions Dispositions) (err error) {
 	for _, dpn := range dispositions {
+		err = canceled(ctx)
+		if err != nil {
+			return
+		}
 		if dpn.desired != nil && dpn.stored == nil {
-			err = r.Tx.Insert(dpn.desired.model())
+			m := dpn.desired.model()
+			err = r.txOp(OpInsert, m, func() error {
+				return r.Tx.Insert(m)
+			})
 			if err == nil {
-				r.Added++
+				result.Added++
+				result.changed(m, "added")
 			} else {
 				return
 			}
@@ -160,26 +627,116 @@ func (r *Collection) add(dispositions Dispositions) (err error) {
 
 //
 // Update models.
-func (r *Collection) update(dispositions Dispositions) (err error) {
-	shepherd := r.Shepherd
-	if shepherd == nil {
-		shepherd = &DefaultShepherd{}
-	}
+func (r *Collection) update(ctx context.Context, result *Result, dispositions Dispositions) (err error) {
+	shepherd := r.shepherd()
 	for _, dpn := range dispositions {
+		err = canceled(ctx)
+		if err != nil {
+			return
+		}
 		if dpn.desired == nil || dpn.stored == nil {
 			continue
 		}
-		desired := dpn.desired.model()
-		stored := dpn.stored.model()
-		if shepherd.Equals(desired, stored) {
-			continue
+		err = r.merge(result, shepherd, dpn.stored.model(), dpn.desired.model())
+		if err != nil {
+			return
 		}
+	}
+
+	return
+}
+
+//
+// Update the stored model as desired.
+// The miss counter (grace period) is reset.
+func (r *Collection) merge(result *Result, shepherd Shepherd, stored, desired model.Model) (err error) {
+	equal := shepherd.Equals(desired, stored)
+	missed, err := r.missed(stored)
+	if err != nil {
+		return
+	}
+	found := missed == nil || missed.Value.Int() == 0
+	if equal && found {
+		result.Skipped++
+		return
+	}
+	if !equal {
 		shepherd.Update(stored, desired)
-		err = r.Tx.Update(stored)
-		if err == nil {
-			r.Updated++
-		} else {
-			return
+	}
+	if !found {
+		missed.Value.SetInt(0)
+	}
+	err = r.preserve(stored)
+	if err != nil {
+		return
+	}
+	err = r.txOp(OpModify, stored, func() error {
+		return r.Tx.Update(stored)
+	})
+	if err == nil {
+		result.Updated++
+		result.changed(stored, "updated")
+	}
+
+	return
+}
+
+//
+// Preserve stored-only fields.
+// Fields tagged `eq:"stored"` are refreshed (within the
+// transaction) so that values written after the stored
+// collection was listed are not overwritten by the update.
+func (r *Collection) preserve(stored model.Model) (err error) {
+	md, err := model.Inspect(stored)
+	if err != nil {
+		return
+	}
+	owned := []int{}
+	for i, f := range md.Fields {
+		if storedOnly(f) {
+			owned = append(owned, i)
+		}
+	}
+	if len(owned) == 0 {
+		return
+	}
+	current, err := keyOf(stored)
+	if err != nil {
+		return
+	}
+	err = r.txOp(OpGet, current, func() error {
+		return r.Tx.Get(current)
+	})
+	if err != nil {
+		return
+	}
+	mdCurrent, err := model.Inspect(current)
+	if err != nil {
+		return
+	}
+	for _, i := range owned {
+		md.Fields[i].Value.Set(*mdCurrent.Fields[i].Value)
+	}
+
+	return
+}
+
+//
+// Build a new model (of the same kind) with only
+// the PK and key (natural) fields set.
+func keyOf(m model.Model) (key model.Model, err error) {
+	md, err := model.Inspect(m)
+	if err != nil {
+		return
+	}
+	key = reflect.New(reflect.TypeOf(m).Elem()).Interface().(model.Model)
+	mdKey, err := model.Inspect(key)
+	if err != nil {
+		return
+	}
+	for i, f := range md.Fields {
+		if f.Pk() || f.Key() {
+			mdKey.Fields[i].Value.Set(*f.Value)
 		}
 	}
 
@@ -188,18 +745,213 @@ func (r *Collection) update(dispositions Dispositions) (err error) {
 
 //
 // Delete stored models not included in the desired.
-func (r *Collection) delete(dispositions Dispositions) (err error) {
+func (r *Collection) delete(ctx context.Context, result *Result, dispositions Dispositions) (err error) {
+	err = r.limit(ctx, dispositions)
+	if err != nil {
+		return
+	}
+	deleted := []model.Model{}
 	for _, dpn := range dispositions {
+		err = canceled(ctx)
+		if err != nil {
+			return
+		}
 		if dpn.stored != nil && dpn.desired == nil {
-			err = r.Tx.Delete(dpn.stored.model())
+			m := dpn.stored.model()
+			var retained bool
+			retained, err = r.retain(m)
+			if err != nil {
+				return
+			}
+			if retained {
+				result.Missed++
+				continue
+			}
+			err = r.txOp(OpRemove, m, func() error {
+				return r.Tx.Delete(m)
+			})
 			if err == nil {
-				r.Deleted++
+				result.Deleted++
+				result.changed(m, "deleted")
+				deleted = append(deleted, m)
 			} else {
 				return
 			}
 		}
 	}
 
+	err = r.garbage(deleted)
+
+	return
+}
+
+//
+// Retain a (stored) model missing from the desired
+// during the grace period. The miss counter is incremented
+// and the model updated. Returns false when the grace
+// period has expired.
+func (r *Collection) retain(m model.Model) (retained bool, err error) {
+	expired, err := r.expired(m)
+	if err != nil || expired {
+		return
+	}
+	missed, err := r.missed(m)
+	if err != nil {
+		return
+	}
+	n := missed.Value.Int() + 1
+	err = r.preserve(m)
+	if err != nil {
+		return
+	}
+	missed.Value.SetInt(n)
+	err = r.txOp(OpModify, m, func() error {
+		return r.Tx.Update(m)
+	})
+	if err != nil {
+		return
+	}
+
+	retained = true
+
+	log.V(3).Info(
+		"missing model retained.",
+		"model",
+		model.Describe(m),
+		"missed",
+		n)
+
+	return
+}
+
+//
+// The grace period for a (stored) model missing from
+// the desired has expired and the model is to be deleted.
+func (r *Collection) expired(m model.Model) (expired bool, err error) {
+	missed, err := r.missed(m)
+	if err != nil {
+		return
+	}
+
+	expired = missed == nil || missed.Value.Int()+1 >= int64(r.GracePeriod)
+
+	return
+}
+
+//
+// Enforce the delete limits.
+// Returns DeleteLimitErr when the number of stored models
+// to be deleted exceeds the limits.
+func (r *Collection) limit(ctx context.Context, dispositions Dispositions) (err error) {
+	if r.ForceDelete || (r.MaxDeleteCount < 1 && r.MaxDeletePercent < 1) {
+		return
+	}
+	n := 0
+	for _, dpn := range dispositions {
+		err = canceled(ctx)
+		if err != nil {
+			return
+		}
+		if dpn.stored != nil && dpn.desired == nil {
+			var expired bool
+			expired, err = r.expired(dpn.stored.model())
+			if err != nil {
+				return
+			}
+			if expired {
+				n++
+			}
+		}
+	}
+	stored := r.Stored.Len()
+	if (r.MaxDeleteCount > 0 && n > r.MaxDeleteCount) ||
+		(r.MaxDeletePercent > 0 && n*100 > r.MaxDeletePercent*stored) {
+		err = liberr.Wrap(
+			DeleteLimitErr,
+			"deleted",
+			n,
+			"stored",
+			stored,
+			"maxCount",
+			r.MaxDeleteCount,
+			"maxPercent",
+			r.MaxDeletePercent)
+		log.V(3).Info(
+			"delete limit exceeded.",
+			"deleted",
+			n,
+			"stored",
+			stored)
+	}
+
+	return
+}
+
+//
+// Get the miss counter field.
+// Returns nil when the grace period is not enabled.
+func (r *Collection) missed(m model.Model) (f *model.Field, err error) {
+	if r.GracePeriod < 2 {
+		return
+	}
+	md, err := model.Inspect(m)
+	if err != nil {
+		return
+	}
+	for _, f = range md.Fields {
+		if tag, found := f.Type.Tag.Lookup("eq"); found && tag == EqMissed && f.Quiet() {
+			switch f.Value.Kind() {
+			case reflect.Int,
+				reflect.Int8,
+				reflect.Int16,
+				reflect.Int32,
+				reflect.Int64:
+				return
+			}
+		}
+	}
+
+	f = nil
+	err = liberr.New(
+		"grace period requires an (int, quiet) miss counter field.",
+		"model",
+		model.Describe(m))
+
+	return
+}
+
+//
+// Call the OnDeleted (garbage) hook as specified
+// by the phase.
+func (r *Collection) garbage(deleted []model.Model) (err error) {
+	if r.OnDeleted == nil || len(deleted) == 0 {
+		return
+	}
+	switch r.OnDeletedPhase {
+	case AfterCommit:
+		r.Tx.OnCommit(func() {
+			hErr := r.OnDeleted(deleted)
+			if hErr != nil {
+				log.Trace(hErr)
+			}
+		})
+	default:
+		err = r.OnDeleted(deleted)
+		if err != nil {
+			err = liberr.Wrap(err)
+		}
+	}
+
+	return
+}
+
+//
+// Returns an error when the context is done.
+func canceled(ctx context.Context) (err error) {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = liberr.Wrap(ctxErr)
+	}
+
 	return
 }
 
@@ -209,12 +961,23 @@ func (r *Collection) delete(dispositions Dispositions) (err error) {
 //   - Is the PK.
 //   - Is (auto) incremented.
 //   - Has the `eq:"-"` tag.
+//   - Has the `eq:"stored"` tag.
+//   - Has the `eq:"missed"` tag.
 type DefaultShepherd struct {
+	// Use compiled field comparators. Compiled once
+	// (per model type) and cached. Cheap (scalar) fields
+	// are compared first.
+	Compiled bool
 }
 
 //
 // Model comparison.
 func (r *DefaultShepherd) Equals(mA, mB model.Model) bool {
+	if r.Compiled {
+		if p, err := planOf(mA, r.ignored); err == nil {
+			return p.equals(mA, mB)
+		}
+	}
 	mdA, _ := model.Inspect(mA)
 	mdB, _ := model.Inspect(mB)
 	for i := 0; i < len(mdA.Fields); i++ {
@@ -236,6 +999,12 @@ func (r *DefaultShepherd) Equals(mA, mB model.Model) bool {
 //
 // Update model A (stored) with model B (desired).
 func (r *DefaultShepherd) Update(mA, mB model.Model) {
+	if r.Compiled {
+		if p, err := planOf(mA, r.ignored); err == nil {
+			p.update(mA, mB)
+			return
+		}
+	}
 	mdA, _ := model.Inspect(mA)
 	mdB, _ := model.Inspect(mB)
 	for i := 0; i < len(mdA.Fields); i++ {
@@ -254,15 +1023,24 @@ func (r *DefaultShepherd) Update(mA, mB model.Model) {
 //   - Is the PK.
 //   - Is (auto) incremented.
 //   - Has the `eq:"-"` tag.
+//   - Has the `eq:"stored"` tag.
+//   - Has the `eq:"missed"` tag.
 func (r *DefaultShepherd) ignored(f *model.Field) bool {
 	if f.Pk() || f.Incremented() {
 		return true
 	}
 	if tag, found := f.Type.Tag.Lookup("eq"); found {
-		if tag == "-" {
+		if tag == EqIgnored || tag == EqStored || tag == EqMissed {
 			return true
 		}
 	}
 
 	return false
 }
+
+//
+// The field is stored-only (has the `eq:"stored"` tag).
+func storedOnly(f *model.Field) bool {
+	tag, found := f.Type.Tag.Lookup("eq")
+	return found && tag == EqStored
+}
@@ -1,12 +1,19 @@
 package container
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	fb "github.com/konveyor/controller/pkg/filebacked"
 	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/logging"
+	"github.com/konveyor/controller/pkg/tracing"
 	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 type TestObject2 struct {
@@ -22,6 +29,7 @@ func (r *TestObject2) Pk() string {
 
 func TestCollection(t *testing.T) {
 	var err error
+	var result *Result
 	g := gomega.NewGomegaWithT(t)
 	DB := model.New("/tmp/test2.db", &TestObject2{})
 	err = DB.Open(true)
@@ -51,11 +59,11 @@ func TestCollection(t *testing.T) {
 	collection := Collection{
 		Stored: stored,
 	}
-	err = collection.Reconcile(asIter(desired))
+	result, err = collection.Reconcile(asIter(desired))
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(collection.Added).To(gomega.Equal(0))
-	g.Expect(collection.Updated).To(gomega.Equal(0))
-	g.Expect(collection.Deleted).To(gomega.Equal(0))
+	g.Expect(result.Added).To(gomega.Equal(0))
+	g.Expect(result.Updated).To(gomega.Equal(0))
+	g.Expect(result.Deleted).To(gomega.Equal(0))
 
 	//
 	// Test adds.
@@ -80,11 +88,16 @@ func TestCollection(t *testing.T) {
 		Stored: stored,
 		Tx:     tx,
 	}
-	err = collection.Add(asIter(desired))
+	added := testutil.ToFloat64(
+		ReconciledCounter.WithLabelValues("TestObject2", "added"))
+	result, err = collection.Add(asIter(desired))
 	_ = tx.Commit()
-	g.Expect(collection.Added).To(gomega.Equal(4))
-	g.Expect(collection.Updated).To(gomega.Equal(0))
-	g.Expect(collection.Deleted).To(gomega.Equal(0))
+	g.Expect(result.Added).To(gomega.Equal(4))
+	g.Expect(testutil.ToFloat64(
+		ReconciledCounter.WithLabelValues("TestObject2", "added"))).To(
+		gomega.Equal(added + 4))
+	g.Expect(result.Updated).To(gomega.Equal(0))
+	g.Expect(result.Deleted).To(gomega.Equal(0))
 
 	//
 	// Test updates.
@@ -103,11 +116,11 @@ func TestCollection(t *testing.T) {
 		Stored: stored,
 		Tx:     tx,
 	}
-	err = collection.Update(asIter(desired))
+	result, err = collection.Update(asIter(desired))
 	_ = tx.Commit()
-	g.Expect(collection.Added).To(gomega.Equal(0))
-	g.Expect(collection.Updated).To(gomega.Equal(2))
-	g.Expect(collection.Deleted).To(gomega.Equal(0))
+	g.Expect(result.Added).To(gomega.Equal(0))
+	g.Expect(result.Updated).To(gomega.Equal(2))
+	g.Expect(result.Deleted).To(gomega.Equal(0))
 	updated := &TestObject2{ID: 6}
 	err = DB.Get(updated)
 	g.Expect(err).To(gomega.BeNil())
@@ -133,11 +146,11 @@ func TestCollection(t *testing.T) {
 		Stored: stored,
 		Tx:     tx,
 	}
-	err = collection.Delete(asIter(desired))
+	result, err = collection.Delete(asIter(desired))
 	_ = tx.Commit()
-	g.Expect(collection.Added).To(gomega.Equal(0))
-	g.Expect(collection.Updated).To(gomega.Equal(0))
-	g.Expect(collection.Deleted).To(gomega.Equal(2))
+	g.Expect(result.Added).To(gomega.Equal(0))
+	g.Expect(result.Updated).To(gomega.Equal(0))
+	g.Expect(result.Deleted).To(gomega.Equal(2))
 	deleted := &TestObject2{ID: 0}
 	err = DB.Get(deleted)
 	g.Expect(errors.Is(err, model.NotFound)).To(gomega.BeTrue())
@@ -173,11 +186,21 @@ func TestCollection(t *testing.T) {
 		Stored: stored,
 		Tx:     tx,
 	}
-	err = collection.Reconcile(asIter(desired))
+	result, err = collection.Reconcile(asIter(desired))
 	_ = tx.Commit()
-	g.Expect(collection.Added).To(gomega.Equal(5))
-	g.Expect(collection.Updated).To(gomega.Equal(2))
-	g.Expect(collection.Deleted).To(gomega.Equal(2))
+	g.Expect(result.Added).To(gomega.Equal(5))
+	g.Expect(result.Updated).To(gomega.Equal(2))
+	g.Expect(result.Deleted).To(gomega.Equal(2))
+	g.Expect(result.Skipped).To(gomega.Equal(len(desired) - 7))
+	g.Expect(len(result.Changed["added"])).To(gomega.Equal(5))
+	g.Expect(result.Changed["updated"]).To(
+		gomega.ConsistOf(
+			strconv.Itoa(desired[3].ID),
+			strconv.Itoa(desired[5].ID)))
+	g.Expect(len(result.Changed["deleted"])).To(gomega.Equal(2))
+	g.Expect(result.Durations).To(gomega.HaveKey(PhaseDispositions))
+	g.Expect(result.Durations).To(gomega.HaveKey(PhaseUpdate))
+	g.Expect(result.Errors).To(gomega.BeEmpty())
 }
 
 //
@@ -190,3 +213,1078 @@ func asIter(models []TestObject2) fb.Iterator {
 
 	return list.Iter()
 }
+
+func TestCollectionDuplicates(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-duplicates.db", &TestObject2{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	desired := []TestObject2{
+		{ID: 0, Name: "A", Age: 1},
+		{ID: 1, Name: "B", Age: 2},
+		{ID: 0, Name: "C", Age: 3},
+	}
+	reconcile := func(policy DuplicatePolicy) (result *Result, err error) {
+		stored, err := DB.Find(
+			&TestObject2{},
+			model.ListOptions{
+				Detail: model.MaxDetail,
+			})
+		g.Expect(err).To(gomega.BeNil())
+		tx, err := DB.Begin()
+		g.Expect(err).To(gomega.BeNil())
+		collection := &Collection{
+			Stored:     stored,
+			Tx:         tx,
+			Duplicates: policy,
+		}
+		result, err = collection.Reconcile(asIter(desired))
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.End()
+		}
+		return
+	}
+	// last wins.
+	result, err := reconcile(LastWins)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Duplicated).To(gomega.Equal([]string{"0"}))
+	g.Expect(result.Added).To(gomega.Equal(2))
+	m := &TestObject2{ID: 0}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("C"))
+	// first wins.
+	result, err = reconcile(FirstWins)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Updated).To(gomega.Equal(1))
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("A"))
+	// merge.
+	result, err = reconcile(Merge)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Updated).To(gomega.Equal(1))
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("C"))
+	// error.
+	result, err = reconcile(DuplicateError)
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(result.Duplicated).To(gomega.Equal([]string{"0"}))
+	g.Expect(result.Updated).To(gomega.Equal(0))
+	g.Expect(len(result.Errors)).To(gomega.Equal(1))
+}
+
+func TestCollectionCanceled(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-canceled.db", &TestObject2{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	desired := []TestObject2{}
+	for i := 0; i < 10; i++ {
+		desired = append(desired, TestObject2{ID: i, Name: strconv.Itoa(i)})
+	}
+	stored, err := DB.Find(
+		&TestObject2{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection := Collection{
+		Stored: stored,
+		Tx:     tx,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := collection.ReconcileContext(ctx, asIter(desired))
+	g.Expect(errors.Is(err, context.Canceled)).To(gomega.BeTrue())
+	g.Expect(result.Added).To(gomega.Equal(0))
+	g.Expect(len(result.Errors)).To(gomega.Equal(1))
+	_ = tx.End()
+	n, err := DB.Count(&TestObject2{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+}
+
+type CorrelatedHandler struct {
+	model.StockEventHandler
+	mutex sync.Mutex
+	ids   []string
+}
+
+func (r *CorrelatedHandler) Created(event model.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ids = append(r.ids, event.ReconcileID())
+}
+
+func (r *CorrelatedHandler) received() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.ids...)
+}
+
+func TestCollectionCorrelated(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	recorder := &tracing.Recorder{}
+	tracing.Use(recorder)
+	defer tracing.Use(nil)
+	DB := model.New("/tmp/test-correlated.db", &TestObject2{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	handler := &CorrelatedHandler{}
+	watch, err := DB.Watch(&TestObject2{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.EndWatch(watch)
+	desired := []TestObject2{}
+	for i := 0; i < 3; i++ {
+		desired = append(desired, TestObject2{ID: i, Name: strconv.Itoa(i)})
+	}
+	stored, err := DB.Find(
+		&TestObject2{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	// generated.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection := Collection{
+		Stored: stored,
+		Tx:     tx,
+	}
+	result, err := collection.Reconcile(asIter(desired))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.ReconcileID).ToNot(gomega.BeEmpty())
+	g.Expect(logging.ReconcileIDOf(tx.Context())).To(gomega.BeEmpty())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 100; i++ {
+		if len(handler.received()) < len(desired) {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+	received := handler.received()
+	g.Expect(len(received)).To(gomega.Equal(len(desired)))
+	for _, id := range received {
+		g.Expect(id).To(gomega.Equal(result.ReconcileID))
+	}
+	reconciled := recorder.Find("collection.reconcile")
+	g.Expect(len(reconciled)).To(gomega.Equal(1))
+	g.Expect(reconciled[0].Attributes[tracing.Reconcile]).To(
+		gomega.Equal(result.ReconcileID))
+	executed := recorder.Find("model.db.exec")
+	g.Expect(len(executed) > 0).To(gomega.BeTrue())
+	for _, span := range executed {
+		g.Expect(span.Attributes[tracing.Reconcile]).To(
+			gomega.Equal(result.ReconcileID))
+	}
+	// carried by the context.
+	stored, err = DB.Find(
+		&TestObject2{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	ctx := logging.WithReconcileID(context.Background(), "1234")
+	tx, err = DB.BeginContext(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = tx.End()
+	}()
+	collection = Collection{
+		Stored: stored,
+		Tx:     tx,
+	}
+	result, err = collection.Reconcile(asIter(desired))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.ReconcileID).To(gomega.Equal("1234"))
+	err = tx.End()
+	g.Expect(err).To(gomega.BeNil())
+	// scoped to the reconcile.
+	tx2, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	ids := []string{}
+	for i := 10; i < 12; i++ {
+		collection = Collection{
+			Stored: &fb.EmptyIterator{},
+			Tx:     tx2,
+		}
+		result, err = collection.Reconcile(
+			asIter([]TestObject2{{ID: i, Name: strconv.Itoa(i)}}))
+		g.Expect(err).To(gomega.BeNil())
+		ids = append(ids, result.ReconcileID)
+	}
+	g.Expect(ids[0]).ToNot(gomega.Equal(ids[1]))
+	err = tx2.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 100; i++ {
+		if len(handler.received()) < len(desired)+2 {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+	received = handler.received()
+	g.Expect(received[len(desired):]).To(gomega.Equal(ids))
+}
+
+type TestNested struct {
+	Address string
+	Zip     int
+}
+
+type TestObject3 struct {
+	ID       int    `sql:"pk"`
+	Revision int    `sql:"incremented"`
+	Name     string `sql:""`
+	Age      int    `sql:""`
+	Ignored  string `sql:"" eq:"-"`
+	Tags     []string
+	Labels   map[string]string
+	TestNested
+}
+
+func (r *TestObject3) Pk() string {
+	return strconv.Itoa(r.ID)
+}
+
+func newTestObject3() *TestObject3 {
+	return &TestObject3{
+		ID:     1,
+		Name:   "Elmer",
+		Age:    20,
+		Tags:   []string{"A", "B", "C", "D", "E", "F", "G", "H"},
+		Labels: map[string]string{"A": "1", "B": "2", "C": "3"},
+		TestNested: TestNested{
+			Address: "Main",
+			Zip:     100,
+		},
+	}
+}
+
+func TestCompiledShepherd(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	for _, shepherd := range []*DefaultShepherd{{}, {Compiled: true}} {
+		a := newTestObject3()
+		b := newTestObject3()
+		g.Expect(shepherd.Equals(a, b)).To(gomega.BeTrue())
+		b.ID = 2
+		b.Revision = 4
+		b.Ignored = "ignored"
+		g.Expect(shepherd.Equals(a, b)).To(gomega.BeTrue())
+		b.Zip = 200
+		g.Expect(shepherd.Equals(a, b)).To(gomega.BeFalse())
+		b.Zip = a.Zip
+		b.Tags = append(b.Tags, "Z")
+		g.Expect(shepherd.Equals(a, b)).To(gomega.BeFalse())
+		b.Name = "Larry"
+		shepherd.Update(a, b)
+		g.Expect(shepherd.Equals(a, b)).To(gomega.BeTrue())
+		g.Expect(a.ID).To(gomega.Equal(1))
+		g.Expect(a.Revision).To(gomega.Equal(0))
+		g.Expect(a.Ignored).To(gomega.Equal(""))
+		g.Expect(a.Name).To(gomega.Equal("Larry"))
+		g.Expect(a.Tags).To(gomega.Equal(b.Tags))
+	}
+}
+
+func benchmarkShepherd(b *testing.B, shepherd *DefaultShepherd) {
+	mA := newTestObject3()
+	mB := newTestObject3()
+	mB.Age = 21
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shepherd.Equals(mA, mA)
+		shepherd.Equals(mA, mB)
+	}
+}
+
+func BenchmarkShepherd(b *testing.B) {
+	benchmarkShepherd(b, &DefaultShepherd{})
+}
+
+func BenchmarkCompiledShepherd(b *testing.B) {
+	benchmarkShepherd(b, &DefaultShepherd{Compiled: true})
+}
+
+func TestCollectionStream(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-stream.db", &TestObject2{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 4; i++ {
+		err = DB.Insert(&TestObject2{ID: i, Name: strconv.Itoa(i), Age: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	stored, err := DB.Find(
+		&TestObject2{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection := Collection{
+		Stored: stored,
+		Tx:     tx,
+	}
+	desired := make(chan model.Model)
+	go func() {
+		defer close(desired)
+		// unchanged.
+		desired <- &TestObject2{ID: 0, Name: "0", Age: 0}
+		// updated.
+		desired <- &TestObject2{ID: 1, Name: "Larry", Age: 1}
+		// added.
+		desired <- &TestObject2{ID: 10, Name: "10", Age: 10}
+		// duplicate (last wins).
+		desired <- &TestObject2{ID: 10, Name: "Ashley", Age: 10}
+	}()
+	result, err := collection.ReconcileStream(context.Background(), desired)
+	g.Expect(err).To(gomega.BeNil())
+	_ = tx.Commit()
+	g.Expect(result.Added).To(gomega.Equal(1))
+	g.Expect(result.Updated).To(gomega.Equal(2))
+	g.Expect(result.Skipped).To(gomega.Equal(1))
+	g.Expect(result.Deleted).To(gomega.Equal(2))
+	g.Expect(result.Duplicated).To(gomega.Equal([]string{"10"}))
+	g.Expect(result.Durations).To(gomega.HaveKey(PhaseStream))
+	m := &TestObject2{ID: 10}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("Ashley"))
+	m = &TestObject2{ID: 1}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("Larry"))
+	n, err := DB.Count(&TestObject2{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(3)))
+	//
+	// Canceled; deletes not applied.
+	stored, err = DB.Find(
+		&TestObject2{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection = Collection{
+		Stored: stored,
+		Tx:     tx,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := collection.Stream(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	err = stream.Put(&TestObject2{ID: 20, Name: "20"})
+	g.Expect(err).To(gomega.BeNil())
+	cancel()
+	err = stream.Put(&TestObject2{ID: 21, Name: "21"})
+	g.Expect(errors.Is(err, context.Canceled)).To(gomega.BeTrue())
+	result, err = stream.Done()
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(result.Added).To(gomega.Equal(1))
+	g.Expect(result.Deleted).To(gomega.Equal(0))
+	g.Expect(stream.Put(&TestObject2{ID: 22})).ToNot(gomega.BeNil())
+	_ = tx.End()
+}
+
+type TestObject4 struct {
+	ID       int    `sql:"pk"`
+	Name     string `sql:""`
+	Analysis string `sql:"" eq:"stored"`
+}
+
+func (r *TestObject4) Pk() string {
+	return strconv.Itoa(r.ID)
+}
+
+func TestStoredFields(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-stored.db", &TestObject4{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	desired := []TestObject4{}
+	for i := 0; i < 2; i++ {
+		m := TestObject4{ID: i, Name: "Elmer"}
+		err = DB.Insert(&m)
+		g.Expect(err).To(gomega.BeNil())
+		desired = append(desired, m)
+	}
+	stored, err := DB.Find(
+		&TestObject4{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	// computed after the stored collection listed.
+	for i := 0; i < 2; i++ {
+		err = DB.Update(&TestObject4{ID: i, Name: "Elmer", Analysis: "computed"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	desired[0].Name = "Fudd"
+	desired[1].Analysis = "desired"
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection := Collection{
+		Stored: stored,
+		Tx:     tx,
+	}
+	list := fb.NewList()
+	for _, m := range desired {
+		list.Append(m)
+	}
+	result, err := collection.Reconcile(list.Iter())
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Updated).To(gomega.Equal(1))
+	g.Expect(result.Skipped).To(gomega.Equal(1))
+	for i := 0; i < 2; i++ {
+		m := &TestObject4{ID: i}
+		err = DB.Get(m)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(m.Name).To(gomega.Equal(desired[i].Name))
+		g.Expect(m.Analysis).To(gomega.Equal("computed"))
+	}
+}
+
+func TestGarbage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-garbage.db", &TestObject2{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	failed := errors.New("failed")
+	reconcile := func(desired []TestObject2, phase GarbagePhase, commit, fail bool) (deleted []string, err error) {
+		stored, err := DB.Find(
+			&TestObject2{},
+			model.ListOptions{
+				Detail: model.MaxDetail,
+			})
+		g.Expect(err).To(gomega.BeNil())
+		tx, err := DB.Begin()
+		g.Expect(err).To(gomega.BeNil())
+		collection := Collection{
+			Stored:         stored,
+			Tx:             tx,
+			OnDeletedPhase: phase,
+			OnDeleted: func(models []model.Model) error {
+				for _, m := range models {
+					deleted = append(deleted, m.Pk())
+				}
+				if fail {
+					return failed
+				}
+				return nil
+			},
+		}
+		_, err = collection.Reconcile(asIter(desired))
+		if err != nil || !commit {
+			_ = tx.End()
+			return
+		}
+		if phase == AfterCommit {
+			g.Expect(deleted).To(gomega.BeNil())
+		}
+		err = tx.Commit()
+		return
+	}
+	desired := []TestObject2{}
+	for i := 0; i < 4; i++ {
+		m := TestObject2{ID: i, Name: "Elmer"}
+		err = DB.Insert(&m)
+		g.Expect(err).To(gomega.BeNil())
+		desired = append(desired, m)
+	}
+	// before commit.
+	deleted, err := reconcile(desired[1:], BeforeCommit, false, false)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(deleted).To(gomega.Equal([]string{"0"}))
+	// before commit; failed.
+	deleted, err = reconcile(desired[1:], BeforeCommit, true, true)
+	g.Expect(errors.Is(err, failed)).To(gomega.BeTrue())
+	g.Expect(deleted).To(gomega.Equal([]string{"0"}))
+	// after commit; ended.
+	deleted, err = reconcile(desired[1:], AfterCommit, false, false)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(deleted).To(gomega.BeNil())
+	// after commit.
+	deleted, err = reconcile(desired[2:], AfterCommit, true, false)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(deleted)).To(gomega.Equal(2))
+	n, err := DB.Count(&TestObject2{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(2)))
+	// nothing deleted.
+	deleted, err = reconcile(desired[2:], BeforeCommit, true, false)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(deleted).To(gomega.BeNil())
+}
+
+type TestObject5 struct {
+	ID     int    `sql:"pk"`
+	Name   string `sql:""`
+	Missed int    `sql:"quiet" eq:"missed"`
+}
+
+func (r *TestObject5) Pk() string {
+	return strconv.Itoa(r.ID)
+}
+
+type GraceHandler struct {
+	model.StockEventHandler
+	updated int
+	deleted int
+	done    bool
+	// Set by the journal goroutine.
+	mutex sync.Mutex
+}
+
+func (h *GraceHandler) Updated(model.Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.updated++
+}
+
+func (h *GraceHandler) Deleted(model.Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.deleted++
+}
+
+func (h *GraceHandler) End() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.done = true
+}
+
+//
+// Events counted and done (ended).
+func (h *GraceHandler) counts() (updated, deleted int, done bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.updated, h.deleted, h.done
+}
+
+func TestGracePeriod(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-grace.db", &TestObject5{}, &TestObject2{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	reconcile := func(ids ...int) (result *Result) {
+		stored, err := DB.Find(
+			&TestObject5{},
+			model.ListOptions{
+				Detail: model.MaxDetail,
+			})
+		g.Expect(err).To(gomega.BeNil())
+		tx, err := DB.Begin()
+		g.Expect(err).To(gomega.BeNil())
+		collection := Collection{
+			Stored:      stored,
+			Tx:          tx,
+			GracePeriod: 3,
+		}
+		list := fb.NewList()
+		for _, id := range ids {
+			list.Append(TestObject5{ID: id, Name: "Elmer"})
+		}
+		result, err = collection.Reconcile(list.Iter())
+		g.Expect(err).To(gomega.BeNil())
+		err = tx.Commit()
+		g.Expect(err).To(gomega.BeNil())
+		return
+	}
+	missed := func(id int) int {
+		m := &TestObject5{ID: id}
+		err := DB.Get(m)
+		g.Expect(err).To(gomega.BeNil())
+		return m.Missed
+	}
+	result := reconcile(0, 1, 2)
+	g.Expect(result.Added).To(gomega.Equal(3))
+	handler := &GraceHandler{}
+	watch, err := DB.Watch(&TestObject5{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	// missing (1).
+	result = reconcile(0)
+	g.Expect(result.Missed).To(gomega.Equal(2))
+	g.Expect(result.Deleted).To(gomega.Equal(0))
+	g.Expect(result.Skipped).To(gomega.Equal(1))
+	g.Expect(missed(1)).To(gomega.Equal(1))
+	g.Expect(missed(2)).To(gomega.Equal(1))
+	// found (reset).
+	result = reconcile(0, 1)
+	g.Expect(result.Missed).To(gomega.Equal(1))
+	g.Expect(result.Updated).To(gomega.Equal(1))
+	g.Expect(result.Skipped).To(gomega.Equal(1))
+	g.Expect(missed(1)).To(gomega.Equal(0))
+	g.Expect(missed(2)).To(gomega.Equal(2))
+	// missing (3) deleted.
+	result = reconcile(0)
+	g.Expect(result.Deleted).To(gomega.Equal(1))
+	g.Expect(result.Changed["deleted"]).To(gomega.Equal([]string{"2"}))
+	g.Expect(result.Missed).To(gomega.Equal(1))
+	g.Expect(missed(1)).To(gomega.Equal(1))
+	// counter updates not reported.
+	DB.EndWatch(watch)
+	g.Eventually(func() bool {
+		_, _, done := handler.counts()
+		return done
+	}).Should(gomega.BeTrue())
+	updated, deleted, _ := handler.counts()
+	g.Expect(updated).To(gomega.Equal(0))
+	g.Expect(deleted).To(gomega.Equal(1))
+	// no counter.
+	err = DB.Insert(&TestObject2{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	stored, err := DB.Find(
+		&TestObject2{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = tx.End()
+	}()
+	collection := Collection{
+		Stored:      stored,
+		Tx:          tx,
+		GracePeriod: 3,
+	}
+	_, err = collection.Reconcile(asIter([]TestObject2{}))
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestDeleteLimit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-delete-limit.db", &TestObject2{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject2{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	reconcile := func(collection Collection, desired []TestObject2) (result *Result, err error) {
+		stored, err := DB.Find(
+			&TestObject2{},
+			model.ListOptions{
+				Detail: model.MaxDetail,
+			})
+		g.Expect(err).To(gomega.BeNil())
+		tx, err := DB.Begin()
+		g.Expect(err).To(gomega.BeNil())
+		defer func() {
+			_ = tx.End()
+		}()
+		collection.Stored = stored
+		collection.Tx = tx
+		result, err = collection.Reconcile(asIter(desired))
+		if err == nil {
+			err = tx.Commit()
+		}
+		return
+	}
+	// count.
+	_, err = reconcile(
+		Collection{MaxDeleteCount: 3},
+		[]TestObject2{})
+	g.Expect(errors.Is(err, DeleteLimitErr)).To(gomega.BeTrue())
+	n, err := DB.Count(&TestObject2{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(10)))
+	// percent.
+	desired := []TestObject2{}
+	for i := 0; i < 7; i++ {
+		desired = append(desired, TestObject2{ID: i})
+	}
+	_, err = reconcile(
+		Collection{MaxDeletePercent: 20},
+		desired)
+	g.Expect(errors.Is(err, DeleteLimitErr)).To(gomega.BeTrue())
+	result, err := reconcile(
+		Collection{MaxDeletePercent: 30, MaxDeleteCount: 3},
+		desired)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Deleted).To(gomega.Equal(3))
+	// override.
+	result, err = reconcile(
+		Collection{MaxDeleteCount: 1, ForceDelete: true},
+		[]TestObject2{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Deleted).To(gomega.Equal(7))
+}
+
+func TestCollectionValidate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-validate.db", &TestObject2{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	desired := []TestObject2{}
+	for i := 0; i < 4; i++ {
+		desired = append(desired, TestObject2{ID: i, Name: strconv.Itoa(i)})
+	}
+	reconcile := func(validate func(model.Model) error) (result *Result, err error) {
+		stored, err := DB.Find(
+			&TestObject2{},
+			model.ListOptions{
+				Detail: model.MaxDetail,
+			})
+		g.Expect(err).To(gomega.BeNil())
+		tx, err := DB.Begin()
+		g.Expect(err).To(gomega.BeNil())
+		defer func() {
+			_ = tx.End()
+		}()
+		collection := Collection{
+			Stored:   stored,
+			Tx:       tx,
+			Validate: validate,
+		}
+		result, err = collection.Reconcile(asIter(desired))
+		if err == nil {
+			err = tx.Commit()
+		}
+		return
+	}
+	// invalid.
+	validated := 0
+	_, err = reconcile(func(m model.Model) (err error) {
+		validated++
+		if m.(*TestObject2).ID == 3 {
+			err = errors.New("invalid")
+		}
+		return
+	})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(validated).To(gomega.Equal(4))
+	n, err := DB.Count(&TestObject2{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	// valid.
+	result, err := reconcile(func(model.Model) error { return nil })
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Desired).To(gomega.Equal(4))
+	g.Expect(result.Added).To(gomega.Equal(4))
+}
+
+func TestCollectionApply(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-apply.db", &TestObject5{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject5{ID: i, Name: strconv.Itoa(i), Missed: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	added := func() float64 {
+		return testutil.ToFloat64(
+			ReconciledCounter.WithLabelValues("TestObject5", "added"))
+	}
+	before := added()
+	deleted := []model.Model{}
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection := Collection{
+		Tx:          tx,
+		GracePeriod: 3,
+		OnDeleted: func(models []model.Model) error {
+			deleted = append(deleted, models...)
+			return nil
+		},
+	}
+	result, err := collection.Apply(
+		context.Background(),
+		// unchanged.
+		Change{Action: Upserted, Model: &TestObject5{ID: 0, Name: "0"}},
+		// miss counter reset.
+		Change{Action: Upserted, Model: &TestObject5{ID: 1, Name: "1"}},
+		// added.
+		Change{Action: Upserted, Model: &TestObject5{ID: 10, Name: "10"}},
+		// deleted.
+		Change{Action: Removed, Model: &TestObject5{ID: 2}},
+		// not stored.
+		Change{Action: Removed, Model: &TestObject5{ID: 20}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(tx.Commit()).To(gomega.BeNil())
+	g.Expect(result.Desired).To(gomega.Equal(3))
+	g.Expect(result.Added).To(gomega.Equal(1))
+	g.Expect(result.Updated).To(gomega.Equal(1))
+	g.Expect(result.Deleted).To(gomega.Equal(1))
+	g.Expect(result.Skipped).To(gomega.Equal(2))
+	g.Expect(result.ReconcileID).ToNot(gomega.BeEmpty())
+	g.Expect(result.Durations).To(gomega.HaveKey(PhaseIncremental))
+	g.Expect(added()).To(gomega.Equal(before + 1))
+	g.Expect(len(deleted)).To(gomega.Equal(1))
+	g.Expect(deleted[0].Pk()).To(gomega.Equal("2"))
+	m := &TestObject5{ID: 1}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Missed).To(gomega.Equal(0))
+	n, err := DB.Count(&TestObject5{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(3)))
+	//
+	// Updated.
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection = Collection{Tx: tx}
+	result, err = collection.Upsert(
+		context.Background(),
+		&TestObject5{ID: 10, Name: "Larry"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(tx.Commit()).To(gomega.BeNil())
+	g.Expect(result.Updated).To(gomega.Equal(1))
+	m = &TestObject5{ID: 10}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("Larry"))
+	//
+	// Invalid.
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	collection = Collection{
+		Tx: tx,
+		Validate: func(m model.Model) error {
+			return errors.New("invalid")
+		},
+	}
+	result, err = collection.Upsert(
+		context.Background(),
+		&TestObject5{ID: 30})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(result.Errors).ToNot(gomega.BeEmpty())
+	_ = tx.End()
+}
+
+func TestCollectionPlan(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-plan.db", &TestObject5{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject5{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = DB.Insert(&TestObject5{ID: 4, Name: "Elmer", Missed: 2})
+	g.Expect(err).To(gomega.BeNil())
+	stored, err := DB.Find(
+		&TestObject5{},
+		model.ListOptions{
+			Detail: model.MaxDetail,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	deleted := []model.Model{}
+	collection := Collection{
+		Stored:      stored,
+		GracePeriod: 3,
+		OnDeleted: func(models []model.Model) error {
+			deleted = append(deleted, models...)
+			return nil
+		},
+	}
+	list := fb.NewList()
+	list.Append(TestObject5{ID: 0, Name: "Elmer"})
+	list.Append(TestObject5{ID: 1, Name: "Daffy"})
+	list.Append(TestObject5{ID: 3, Name: "Bugs"})
+	plan, err := collection.Plan(context.Background(), list.Iter())
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(plan.ReconcileID).ToNot(gomega.BeEmpty())
+	g.Expect(plan.Desired).To(gomega.Equal(3))
+	g.Expect(plan.Skipped).To(gomega.Equal(1))
+	actions := []OpAction{}
+	pks := []string{}
+	for _, op := range plan.Operations {
+		actions = append(actions, op.Action)
+		pks = append(pks, op.PK)
+	}
+	g.Expect(actions).To(gomega.Equal([]OpAction{OpDelete, OpRetain, OpAdd, OpUpdate}))
+	g.Expect(pks).To(gomega.Equal([]string{"4", "2", "3", "1"}))
+	g.Expect(plan.Operations[1].Missed).To(gomega.Equal(int64(1)))
+	g.Expect(plan.Operations[3].Fields).To(gomega.Equal([]string{"Name"}))
+	g.Expect(plan.With(OpAdd)[0].Kind).To(gomega.Equal("TestObject5"))
+	_, err = json.Marshal(plan)
+	g.Expect(err).To(gomega.BeNil())
+	// nothing changed.
+	n, _ := DB.Count(&TestObject5{}, nil)
+	g.Expect(n).To(gomega.Equal(int64(4)))
+	// filtered.
+	filtered := plan.Filter(func(op *Operation) bool {
+		return op.Action != OpDelete
+	})
+	g.Expect(len(filtered.Operations)).To(gomega.Equal(3))
+	g.Expect(len(plan.Operations)).To(gomega.Equal(4))
+	// applied.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	result, err := collection.ApplyPlan(context.Background(), plan, tx)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(tx.Commit()).To(gomega.BeNil())
+	g.Expect(result.ReconcileID).To(gomega.Equal(plan.ReconcileID))
+	g.Expect(result.Added).To(gomega.Equal(1))
+	g.Expect(result.Updated).To(gomega.Equal(1))
+	g.Expect(result.Deleted).To(gomega.Equal(1))
+	g.Expect(result.Missed).To(gomega.Equal(1))
+	g.Expect(result.Durations).To(gomega.HaveKey(PhaseApply))
+	g.Expect(len(deleted)).To(gomega.Equal(1))
+	m := &TestObject5{ID: 1}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("Daffy"))
+	m = &TestObject5{ID: 2}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Missed).To(gomega.Equal(1))
+	// re-applied (idempotent).
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	result, err = collection.ApplyPlan(context.Background(), plan, tx)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(tx.Commit()).To(gomega.BeNil())
+	g.Expect(result.Added).To(gomega.Equal(0))
+	g.Expect(result.Updated).To(gomega.Equal(0))
+	g.Expect(result.Deleted).To(gomega.Equal(0))
+	g.Expect(result.Missed).To(gomega.Equal(0))
+	g.Expect(result.Skipped).To(gomega.Equal(5))
+	m = &TestObject5{ID: 2}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Missed).To(gomega.Equal(1))
+	n, _ = DB.Count(&TestObject5{}, nil)
+	g.Expect(n).To(gomega.Equal(int64(4)))
+	// unmarshaled.
+	b, err := json.Marshal(plan)
+	g.Expect(err).To(gomega.BeNil())
+	unmarshaled := &Plan{}
+	err = json.Unmarshal(b, unmarshaled)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(unmarshaled.Operations)).To(gomega.Equal(4))
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	_, err = collection.ApplyPlan(context.Background(), unmarshaled, tx)
+	g.Expect(err).ToNot(gomega.BeNil())
+	err = unmarshaled.Decode(&TestObject5{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(unmarshaled.Operations[3].Model.(*TestObject5).Name).To(gomega.Equal("Daffy"))
+	result, err = collection.ApplyPlan(context.Background(), unmarshaled, tx)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Skipped).To(gomega.Equal(5))
+	g.Expect(tx.Commit()).To(gomega.BeNil())
+}
+
+func TestStalled(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := model.New("/tmp/test-stalled.db", &TestObject2{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	failed := errors.New("failed")
+	fail := false
+	DB.Hook(
+		&TestObject2{},
+		model.Hooks{
+			BeforeInsert: func(tx *model.Tx, m model.Model) (err error) {
+				if m.Pk() == "2" {
+					time.Sleep(time.Millisecond * 200)
+					if fail {
+						err = failed
+					}
+				}
+				return
+			},
+		})
+	desired := []TestObject2{}
+	for i := 0; i < 4; i++ {
+		desired = append(desired, TestObject2{ID: i, Name: strconv.Itoa(i)})
+	}
+	reconcile := func(timeout time.Duration, onStalled func(*Stalled)) (result *Result, err error) {
+		tx, err := DB.Begin()
+		g.Expect(err).To(gomega.BeNil())
+		defer func() {
+			_ = tx.End()
+		}()
+		collection := Collection{
+			Stored:    asIter([]TestObject2{}),
+			Tx:        tx,
+			OpTimeout: timeout,
+			OnStalled: onStalled,
+		}
+		result, err = collection.Reconcile(asIter(desired))
+		return
+	}
+	// stalled.
+	reported := make(chan *Stalled, 1)
+	_, err = reconcile(
+		time.Millisecond*50,
+		func(stalled *Stalled) {
+			reported <- stalled
+		})
+	g.Expect(err).ToNot(gomega.BeNil())
+	stalled := &Stalled{}
+	g.Expect(errors.As(err, &stalled)).To(gomega.BeTrue())
+	g.Expect(stalled.Kind).To(gomega.Equal("TestObject2"))
+	g.Expect(stalled.PK).To(gomega.Equal("2"))
+	g.Expect(stalled.Op).To(gomega.Equal(OpInsert))
+	g.Expect(stalled.Timeout).To(gomega.Equal(time.Millisecond * 50))
+	g.Expect(stalled.Duration >= stalled.Timeout).To(gomega.BeTrue())
+	select {
+	case r := <-reported:
+		g.Expect(r.PK).To(gomega.Equal("2"))
+		g.Expect(r.Op).To(gomega.Equal(OpInsert))
+		g.Expect(r.Duration).To(gomega.Equal(time.Duration(0)))
+	case <-time.After(time.Second):
+		t.Fatal("stall not reported.")
+	}
+	// stalled and failed.
+	fail = true
+	_, err = reconcile(time.Millisecond*50, nil)
+	g.Expect(errors.Is(err, failed)).To(gomega.BeTrue())
+	fail = false
+	// not watched.
+	result, err := reconcile(0, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Added).To(gomega.Equal(4))
+	// completed within timeout.
+	result, err = reconcile(time.Second, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result.Added).To(gomega.Equal(4))
+}
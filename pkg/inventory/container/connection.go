@@ -0,0 +1,182 @@
+package container
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+//
+// Provider connection test interval.
+// The connection is tested (using Collector.Test()) by the
+// container while the collector is collecting or ready. The
+// test runs in the background so that a slow provider does
+// not delay the monitor.
+var ConnectionPoll = time.Minute
+
+//
+// Collector (optional) provider API version reporting.
+type APIVersioner interface {
+	// The provider API version.
+	// Example: 7.0.3.
+	APIVersion() string
+}
+
+//
+// Provider connection status (snapshot).
+// Maintained by the container so that UIs may report
+// the health of each (provider) source.
+type ConnectionStatus struct {
+	// Collector key.
+	Key Key `json:"key"`
+	// Collector name.
+	Name string `json:"name"`
+	// Connected (last test succeeded).
+	Connected bool `json:"connected"`
+	// Time of the last successful connection (test).
+	LastConnected *time.Time `json:"lastConnected,omitempty"`
+	// Time of the last test.
+	LastChecked *time.Time `json:"lastChecked,omitempty"`
+	// Latency (duration) of the last test.
+	Latency string `json:"latency,omitempty"`
+	// Last error.
+	Error string `json:"error,omitempty"`
+	// Provider API version.
+	APIVersion string `json:"apiVersion,omitempty"`
+}
+
+//
+// Provider connection (state).
+type connection struct {
+	// Connected.
+	connected bool
+	// Time of the last successful test.
+	connectedAt time.Time
+	// Time of the last test.
+	checked time.Time
+	// Latency of the last test.
+	latency time.Duration
+	// Last error.
+	err error
+	// Provider API version.
+	version string
+	// Test in progress.
+	testing bool
+}
+
+//
+// Test the provider connection (in the background) when due.
+// Ignored while a test is in progress.
+func (r *lifecycle) check() {
+	switch r.current() {
+	case Collecting, Ready:
+	default:
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.conn.testing || time.Since(r.conn.checked) < ConnectionPoll {
+		return
+	}
+	r.conn.testing = true
+	go r.test(r.done)
+}
+
+//
+// Test the provider connection.
+// The result is discarded when the monitor (done) has
+// been stopped while testing.
+func (r *lifecycle) test(done chan struct{}) {
+	mark := time.Now()
+	err := r.collector.Test()
+	latency := time.Since(mark)
+	version := ""
+	if versioner, cast := r.collector.(APIVersioner); cast {
+		version = versioner.APIVersion()
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.conn.testing = false
+	select {
+	case <-done:
+		return
+	default:
+	}
+	r.conn.checked = time.Now()
+	r.conn.latency = latency
+	r.conn.err = err
+	r.conn.connected = err == nil
+	if err == nil {
+		r.conn.connectedAt = r.conn.checked
+		r.conn.version = version
+	} else {
+		log.V(3).Info(
+			"collector connection test failed.",
+			"owner",
+			r.key,
+			"error",
+			err.Error())
+	}
+}
+
+//
+// Mark the provider connection as disconnected.
+func (r *lifecycle) disconnected(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.conn.connected = false
+	r.conn.checked = time.Time{}
+	if err != nil {
+		r.conn.err = err
+	}
+}
+
+//
+// Connection status snapshot.
+func (r *lifecycle) connectionStatus() (s ConnectionStatus) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s = ConnectionStatus{
+		Key:        r.key,
+		Name:       r.collector.Name(),
+		Connected:  r.conn.connected,
+		APIVersion: r.conn.version,
+	}
+	if !r.conn.connectedAt.IsZero() {
+		connected := r.conn.connectedAt
+		s.LastConnected = &connected
+	}
+	if !r.conn.checked.IsZero() {
+		checked := r.conn.checked
+		s.LastChecked = &checked
+		s.Latency = r.conn.latency.String()
+	}
+	if r.conn.err != nil {
+		s.Error = r.conn.err.Error()
+	}
+
+	return
+}
+
+//
+// Connection status of all collectors.
+func (c *Container) Connections() (list []ConnectionStatus) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	list = []ConnectionStatus{}
+	for _, lc := range c.lifecycle {
+		list = append(list, lc.connectionStatus())
+	}
+
+	return
+}
+
+//
+// Connection status of a collector by (CR) object.
+func (c *Container) ConnectionOf(owner meta.Object) (s ConnectionStatus, found bool) {
+	lc, found := c.find(owner)
+	if found {
+		s = lc.connectionStatus()
+	}
+
+	return
+}
@@ -1,6 +1,7 @@
 package container
 
 import (
+	"context"
 	liberr "github.com/konveyor/controller/pkg/error"
 	"github.com/konveyor/controller/pkg/inventory/model"
 	"github.com/konveyor/controller/pkg/logging"
@@ -8,11 +9,12 @@ import (
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sync"
+	"time"
 )
 
 //
 // Logger.
-var log = logging.WithName("container")
+var log = logging.WithName(logging.Container)
 
 //
 // Collector key.
@@ -21,8 +23,24 @@ type Key core.ObjectReference
 //
 // A container manages a collection of `Collector`.
 type Container struct {
+	// Restart backoff.
+	Backoff BackoffPolicy
+	// Collector health (status) poll interval.
+	// Default: StatusPoll.
+	StatusPoll time.Duration
+	// Checkpoint directory.
+	// When set, the watch events not delivered when the
+	// shutdown context is done are checkpointed (by DB) in
+	// the directory. See: Shutdown().
+	Checkpoint string
 	// Collection of data collectors.
 	content map[Key]Collector
+	// Collector lifecycles.
+	lifecycle map[Key]*lifecycle
+	// Paused kinds.
+	paused PauseSet
+	// Activation (standby).
+	activation Activation
 	// Mutex - protect the map..
 	mutex sync.RWMutex
 }
@@ -54,6 +72,7 @@ func (c *Container) List() []Collector {
 func (c *Container) Add(collector Collector) (err error) {
 	owner := collector.Owner()
 	key := c.key(owner)
+	var lc *lifecycle
 	add := func() {
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
@@ -62,14 +81,18 @@ func (c *Container) Add(collector Collector) (err error) {
 			return
 		}
 		c.content[key] = collector
+		lc = c.newLifecycle(key, collector)
 	}
 	add()
+	c.applyPaused(collector)
+	c.applyActivation(collector)
+	c.applyBudget(collector)
 	if err != nil {
 		return
 	}
-	err = collector.Start()
+	err = lc.start()
 	if err != nil {
-		return liberr.Wrap(err)
+		return
 	}
 
 	log.V(3).Info(
@@ -84,19 +107,22 @@ func (c *Container) Add(collector Collector) (err error) {
 // Replace a collector.
 func (c *Container) Replace(collector Collector) (p Collector, found bool, err error) {
 	key := c.key(collector.Owner())
+	var lc *lifecycle
 	replace := func() {
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
-		if p, found := c.content[key]; found {
-			p.Shutdown()
+		p, found = c.content[key]
+		if found {
+			c.lifecycle[key].stop()
 		}
 		c.content[key] = collector
+		lc = c.newLifecycle(key, collector)
 	}
 	replace()
-	err = collector.Start()
-	if err != nil {
-		err = liberr.Wrap(err)
-	}
+	c.applyPaused(collector)
+	c.applyActivation(collector)
+	c.applyBudget(collector)
+	err = lc.start()
 
 	log.V(3).Info(
 		"collector replaced.",
@@ -114,7 +140,10 @@ func (c *Container) Delete(owner meta.Object) (p Collector, found bool) {
 	key := c.key(owner)
 	if p, found = c.content[key]; found {
 		delete(c.content, key)
-		p.Shutdown()
+		c.lifecycle[key].stop()
+		delete(c.lifecycle, key)
+		Reconciles.Delete(p.Name())
+		Budgets.Delete(p.Name())
 		log.V(3).Info(
 			"collector deleted.",
 			"owner",
@@ -124,6 +153,176 @@ func (c *Container) Delete(owner meta.Object) (p Collector, found bool) {
 	return
 }
 
+//
+// All collectors have parity.
+func (c *Container) HasParity() bool {
+	for _, collector := range c.List() {
+		if !collector.HasParity() {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Wait for all collectors to have parity.
+// Returns an error when the context is done.
+func (c *Container) WaitForParity(ctx context.Context) (err error) {
+	for _, collector := range c.List() {
+		err = WaitForParity(ctx, collector)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Pause reconciliation of a kind.
+// Applied to all (pausable) collectors, including
+// those added while paused.
+func (c *Container) Pause(kind string) {
+	c.paused.Pause(kind)
+	for _, collector := range c.List() {
+		if pausable, cast := collector.(Pausable); cast {
+			pausable.Pause(kind)
+		}
+	}
+
+	log.V(3).Info(
+		"kind paused.",
+		"kind",
+		kind)
+}
+
+//
+// Resume reconciliation of a kind.
+func (c *Container) Resume(kind string) {
+	c.paused.Resume(kind)
+	for _, collector := range c.List() {
+		if pausable, cast := collector.(Pausable); cast {
+			pausable.Resume(kind)
+		}
+	}
+
+	log.V(3).Info(
+		"kind resumed.",
+		"kind",
+		kind)
+}
+
+//
+// List paused kinds.
+func (c *Container) Paused() []string {
+	return c.paused.List()
+}
+
+//
+// Start a (stopped) collector.
+func (c *Container) Start(owner meta.Object) (err error) {
+	lc, found := c.find(owner)
+	if !found {
+		err = liberr.New("not found.")
+		return
+	}
+	err = lc.start()
+	return
+}
+
+//
+// Stop a collector.
+// The collector is shutdown and will not be restarted.
+func (c *Container) Stop(owner meta.Object) (err error) {
+	lc, found := c.find(owner)
+	if !found {
+		err = liberr.New("not found.")
+		return
+	}
+	lc.stop()
+	return
+}
+
+//
+// Restart a collector.
+// The collector is shutdown, reset and started.
+func (c *Container) Restart(owner meta.Object) (err error) {
+	lc, found := c.find(owner)
+	if !found {
+		err = liberr.New("not found.")
+		return
+	}
+	lc.stop()
+	lc.reset()
+	err = lc.start()
+	return
+}
+
+//
+// Status snapshot of all collectors.
+func (c *Container) Status() (list []Status) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	list = []Status{}
+	for _, lc := range c.lifecycle {
+		list = append(list, lc.status())
+	}
+
+	return
+}
+
+//
+// Status of a collector by (CR) object.
+func (c *Container) StatusOf(owner meta.Object) (s Status, found bool) {
+	lc, found := c.find(owner)
+	if found {
+		s = lc.status()
+	}
+
+	return
+}
+
+//
+// Find a collector lifecycle by (CR) object.
+func (c *Container) find(owner meta.Object) (lc *lifecycle, found bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	lc, found = c.lifecycle[c.key(owner)]
+	return
+}
+
+//
+// Apply paused kinds to a collector.
+func (c *Container) applyPaused(collector Collector) {
+	if pausable, cast := collector.(Pausable); cast {
+		for _, kind := range c.paused.List() {
+			pausable.Pause(kind)
+		}
+	}
+}
+
+//
+// Build a collector lifecycle.
+func (c *Container) newLifecycle(key Key, collector Collector) (lc *lifecycle) {
+	backoff := c.Backoff
+	if backoff.Min == 0 {
+		backoff = DefaultBackoff
+	}
+	poll := c.StatusPoll
+	if poll < 1 {
+		poll = StatusPoll
+	}
+	lc = &lifecycle{
+		collector: collector,
+		key:       key,
+		backoff:   backoff,
+		poll:      poll,
+	}
+	c.lifecycle[key] = lc
+	return
+}
+
 //
 // Build a collector key for an object.
 func (*Container) key(owner meta.Object) Key {
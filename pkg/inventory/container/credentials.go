@@ -0,0 +1,78 @@
+package container
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	core "k8s.io/api/core/v1"
+)
+
+//
+// Collector (optional) credentials refresh.
+// Collectors are notified when the source credentials
+// (secret) have changed and reconnected rather than
+// failing until restarted.
+type Credentialed interface {
+	// The secret containing the source credentials.
+	Secret() *core.Secret
+	// Update the source credentials.
+	// The collector is stopped (shutdown) by the container
+	// before the update and restarted (reconnected) after.
+	UpdateCredentials(secret *core.Secret) error
+}
+
+//
+// Refresh credentials.
+// Collectors using the (updated) secret are stopped,
+// updated and started. Collectors with the same secret
+// resource version are skipped. A collector failing the
+// update is started with the current credentials.
+// Returns: the keys of restarted collectors.
+func (c *Container) RefreshCredentials(secret *core.Secret) (refreshed []Key, err error) {
+	refreshed = []Key{}
+	for _, collector := range c.List() {
+		credentialed, cast := collector.(Credentialed)
+		if !cast {
+			continue
+		}
+		current := credentialed.Secret()
+		if current == nil ||
+			current.Namespace != secret.Namespace ||
+			current.Name != secret.Name {
+			continue
+		}
+		if current.ResourceVersion != "" &&
+			current.ResourceVersion == secret.ResourceVersion {
+			continue
+		}
+		key := c.key(collector.Owner())
+		lc, found := c.find(collector.Owner())
+		if !found {
+			continue
+		}
+		lc.stop()
+		err = credentialed.UpdateCredentials(secret)
+		if err != nil {
+			err = liberr.Wrap(
+				err,
+				"update credentials failed.",
+				"owner",
+				key)
+			_ = lc.start()
+			return
+		}
+		lc.reset()
+		err = lc.start()
+		if err != nil {
+			return
+		}
+		refreshed = append(refreshed, key)
+
+		log.V(3).Info(
+			"collector credentials refreshed.",
+			"owner",
+			key,
+			"secret",
+			secret.Name)
+	}
+
+	return
+}
@@ -0,0 +1,76 @@
+package container
+
+import (
+	"github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+type CredentialedCollector struct {
+	TestCollector
+	secret *core.Secret
+	// Running when updated.
+	running bool
+	// Shutdown.
+	shutdown bool
+}
+
+func (r *CredentialedCollector) Start() error {
+	r.mutex.Lock()
+	r.shutdown = false
+	r.mutex.Unlock()
+	return r.TestCollector.Start()
+}
+
+func (r *CredentialedCollector) Shutdown() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.shutdown = true
+}
+
+func (r *CredentialedCollector) Secret() *core.Secret {
+	return r.secret
+}
+
+func (r *CredentialedCollector) UpdateCredentials(secret *core.Secret) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.running = !r.shutdown
+	r.secret = secret
+	return nil
+}
+
+func TestRefreshCredentials(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	secret := func(name, version string) *core.Secret {
+		return &core.Secret{
+			ObjectMeta: meta.ObjectMeta{
+				Namespace:       "test",
+				Name:            name,
+				ResourceVersion: version,
+			},
+		}
+	}
+	c := New()
+	collector := &CredentialedCollector{secret: secret("a", "1")}
+	err := c.Add(collector)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(collector.starts()).To(gomega.Equal(1))
+	// Other secret.
+	refreshed, err := c.RefreshCredentials(secret("b", "2"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(refreshed).To(gomega.BeEmpty())
+	// Same version.
+	refreshed, err = c.RefreshCredentials(secret("a", "1"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(refreshed).To(gomega.BeEmpty())
+	// Updated.
+	refreshed, err = c.RefreshCredentials(secret("a", "2"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(refreshed)).To(gomega.Equal(1))
+	g.Expect(collector.secret.ResourceVersion).To(gomega.Equal("2"))
+	g.Expect(collector.starts()).To(gomega.Equal(2))
+	g.Expect(collector.running).To(gomega.BeFalse())
+	c.Delete(collector.Owner())
+}
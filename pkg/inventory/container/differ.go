@@ -0,0 +1,231 @@
+package container
+
+import (
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/ref"
+	"reflect"
+	"sort"
+)
+
+//
+// Diff source (inventory).
+// Satisfied by model.DB, *model.Tx and *model.Reader (snapshot).
+// An exported snapshot (DB file) may be opened using model.New().
+type DiffSource interface {
+	// Find models.
+	Find(model interface{}, options model.ListOptions) (fb.Iterator, error)
+}
+
+//
+// Inventory differ.
+// Compares two inventories (A and B) by kind. Models are matched
+// by PK and compared using the shepherd. Useful for validating
+// migrations and debugging environment drift. Example:
+//   differ := &container.Differ{Models: models}
+//   report, err := differ.Diff(dbA, dbB)
+//   if !report.Equal() {
+//      ...
+//   }
+type Differ struct {
+	// Models (kinds) to be compared.
+	Models []interface{}
+	// An (optional) shepherd.
+	// Default: DefaultShepherd.
+	Shepherd Shepherd
+}
+
+//
+// Changed model.
+type ModelDiff struct {
+	// PK.
+	PK string
+	// Names of fields not equal (excluding the
+	// fields ignored by the DefaultShepherd).
+	Fields []string
+}
+
+//
+// Diff (by kind).
+type KindDiff struct {
+	// Kind.
+	Kind string
+	// PKs of models in B but not in A.
+	Added []string
+	// PKs of models in A but not in B.
+	Deleted []string
+	// Models in both but not equal.
+	Changed []ModelDiff
+	// Number of equal models.
+	Equal int
+}
+
+//
+// The kind has no differences.
+func (r *KindDiff) Empty() bool {
+	return len(r.Added) == 0 &&
+		len(r.Deleted) == 0 &&
+		len(r.Changed) == 0
+}
+
+//
+// Diff report.
+type DiffReport struct {
+	// Diff by kind (ordered as Differ.Models).
+	Kinds []*KindDiff
+}
+
+//
+// The inventories are equal.
+func (r *DiffReport) Equal() bool {
+	for _, kd := range r.Kinds {
+		if !kd.Empty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Get the diff by kind.
+func (r *DiffReport) Kind(kind string) (kd *KindDiff, found bool) {
+	for _, kd = range r.Kinds {
+		if kd.Kind == kind {
+			found = true
+			return
+		}
+	}
+
+	kd = nil
+
+	return
+}
+
+//
+// Compare inventory A with B.
+func (r *Differ) Diff(a, b DiffSource) (report *DiffReport, err error) {
+	report = &DiffReport{}
+	for _, m := range r.Models {
+		var kd *KindDiff
+		kd, err = r.diff(m, a, b)
+		if err != nil {
+			report = nil
+			return
+		}
+		report.Kinds = append(report.Kinds, kd)
+	}
+
+	log.V(3).Info(
+		"inventory diff built.",
+		"equal",
+		report.Equal())
+
+	return
+}
+
+//
+// Compare inventory A with B for the kind.
+func (r *Differ) diff(m interface{}, a, b DiffSource) (kd *KindDiff, err error) {
+	kd = &KindDiff{Kind: ref.ToKind(m)}
+	options := model.ListOptions{Detail: model.MaxDetail}
+	itrA, err := a.Find(m, options)
+	if err != nil {
+		return
+	}
+	defer itrA.Close()
+	itrB, err := b.Find(m, options)
+	if err != nil {
+		return
+	}
+	defer itrB.Close()
+	indexA := map[string]int{}
+	for i := 0; i < itrA.Len(); i++ {
+		object, gErr := fb.Get(itrA, i)
+		if gErr != nil {
+			err = gErr
+			return
+		}
+		mA := object.(model.Model)
+		indexA[mA.Pk()] = i
+	}
+	shepherd := r.shepherd()
+	for i := 0; i < itrB.Len(); i++ {
+		object, gErr := fb.Get(itrB, i)
+		if gErr != nil {
+			err = gErr
+			return
+		}
+		mB := object.(model.Model)
+		pk := mB.Pk()
+		index, found := indexA[pk]
+		if !found {
+			kd.Added = append(kd.Added, pk)
+			continue
+		}
+		delete(indexA, pk)
+		object, err = fb.Get(itrA, index)
+		if err != nil {
+			return
+		}
+		mA := object.(model.Model)
+		if shepherd.Equals(mA, mB) {
+			kd.Equal++
+			continue
+		}
+		kd.Changed = append(
+			kd.Changed,
+			ModelDiff{
+				PK:     pk,
+				Fields: changedFields(mA, mB),
+			})
+	}
+	for pk := range indexA {
+		kd.Deleted = append(kd.Deleted, pk)
+	}
+	sort.Strings(kd.Added)
+	sort.Strings(kd.Deleted)
+	sort.Slice(kd.Changed, func(i, j int) bool {
+		return kd.Changed[i].PK < kd.Changed[j].PK
+	})
+
+	return
+}
+
+//
+// The shepherd.
+func (r *Differ) shepherd() (shepherd Shepherd) {
+	shepherd = r.Shepherd
+	if shepherd == nil {
+		shepherd = &DefaultShepherd{}
+	}
+
+	return
+}
+
+//
+// Names of fields not equal.
+// Fields ignored by the DefaultShepherd are excluded.
+func changedFields(mA, mB model.Model) (names []string) {
+	mdA, err := model.Inspect(mA)
+	if err != nil {
+		return
+	}
+	mdB, err := model.Inspect(mB)
+	if err != nil {
+		return
+	}
+	shepherd := &DefaultShepherd{}
+	for i := 0; i < len(mdA.Fields); i++ {
+		fA := mdA.Fields[i]
+		fB := mdB.Fields[i]
+		if shepherd.ignored(fA) {
+			continue
+		}
+		if !reflect.DeepEqual(fA.Value.Interface(), fB.Value.Interface()) {
+			names = append(names, fA.Name)
+		}
+	}
+
+	return
+}
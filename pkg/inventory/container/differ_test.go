@@ -0,0 +1,81 @@
+package container
+
+import (
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/onsi/gomega"
+	"testing"
+)
+
+func TestDiffer(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	open := func(path string) model.DB {
+		DB := model.New(path, &TestObject2{}, &TestObject4{})
+		err := DB.Open(true)
+		g.Expect(err).To(gomega.BeNil())
+		return DB
+	}
+	dbA := open("/tmp/test-differ-a.db")
+	defer func() {
+		_ = dbA.Close(false)
+	}()
+	dbB := open("/tmp/test-differ-b.db")
+	defer func() {
+		_ = dbB.Close(false)
+	}()
+	for i := 0; i < 4; i++ {
+		for _, DB := range []model.DB{dbA, dbB} {
+			err := DB.Insert(&TestObject2{ID: i, Name: "Elmer", Age: 18})
+			g.Expect(err).To(gomega.BeNil())
+			err = DB.Insert(&TestObject4{ID: i, Name: "Elmer"})
+			g.Expect(err).To(gomega.BeNil())
+		}
+	}
+	differ := &Differ{
+		Models: []interface{}{
+			&TestObject2{},
+			&TestObject4{},
+		},
+	}
+	// equal.
+	report, err := differ.Diff(dbA, dbB)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(report.Equal()).To(gomega.BeTrue())
+	kd, found := report.Kind("TestObject2")
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(kd.Equal).To(gomega.Equal(4))
+	// drift.
+	err = dbB.Update(&TestObject2{ID: 1, Name: "Fudd", Age: 18})
+	g.Expect(err).To(gomega.BeNil())
+	err = dbB.Update(&TestObject2{ID: 2, Name: "Elmer", Age: 18})
+	g.Expect(err).To(gomega.BeNil())
+	err = dbB.Delete(&TestObject2{ID: 3})
+	g.Expect(err).To(gomega.BeNil())
+	err = dbB.Insert(&TestObject2{ID: 4, Name: "Bugs"})
+	g.Expect(err).To(gomega.BeNil())
+	err = dbB.Update(&TestObject4{ID: 0, Name: "Elmer", Analysis: "computed"})
+	g.Expect(err).To(gomega.BeNil())
+	report, err = differ.Diff(dbA, dbB)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(report.Equal()).To(gomega.BeFalse())
+	kd, _ = report.Kind("TestObject2")
+	g.Expect(kd.Added).To(gomega.Equal([]string{"4"}))
+	g.Expect(kd.Deleted).To(gomega.Equal([]string{"3"}))
+	g.Expect(kd.Changed).To(gomega.Equal(
+		[]ModelDiff{
+			{PK: "1", Fields: []string{"Name"}},
+		}))
+	g.Expect(kd.Equal).To(gomega.Equal(2))
+	// stored-only fields ignored.
+	kd, _ = report.Kind("TestObject4")
+	g.Expect(kd.Empty()).To(gomega.BeTrue())
+	// snapshot.
+	err = dbB.Snapshot(
+		func(reader *model.Reader) (err error) {
+			report, err = differ.Diff(reader, dbB)
+			return
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(report.Equal()).To(gomega.BeTrue())
+	_, found = report.Kind("Unknown")
+	g.Expect(found).To(gomega.BeFalse())
+}
@@ -13,6 +13,7 @@ package container
 // Build a new container.
 func New() *Container {
 	return &Container{
-		content: map[Key]Collector{},
+		content:   map[Key]Collector{},
+		lifecycle: map[Key]*lifecycle{},
 	}
 }
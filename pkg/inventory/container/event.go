@@ -0,0 +1,199 @@
+package container
+
+import (
+	"bytes"
+	"github.com/konveyor/controller/pkg/condition"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+)
+
+//
+// Event reasons.
+const (
+	ReconciledEvent       = "Reconciled"
+	ReconcileFailedEvent  = "ReconcileFailed"
+	ConditionAddedEvent   = "ConditionAdded"
+	ConditionUpdatedEvent = "ConditionUpdated"
+	ConditionDeletedEvent = "ConditionDeleted"
+)
+
+//
+// Default event throttle.
+var EventThrottle = time.Minute
+
+//
+// Default (message) templates by reason.
+var EventTemplates = map[string]string{
+	ReconciledEvent:       "Collection `{{.kind}}` reconciled in {{.duration}}.",
+	ReconcileFailedEvent:  "Collection `{{.kind}}` reconcile failed: {{.error}}",
+	ConditionAddedEvent:   "Condition `{{.type}}` ({{.status}}) added. {{.message}}",
+	ConditionUpdatedEvent: "Condition `{{.type}}` ({{.status}}) updated. {{.message}}",
+	ConditionDeletedEvent: "Condition `{{.type}}` deleted.",
+}
+
+//
+// Event recorder (bridge).
+// Records collection reconcile outcomes and condition
+// transitions as kubernetes events on the owning CR.
+// Identical events are throttled. Reconcile outcomes
+// are throttled by reason and kind.
+type EventRecorder struct {
+	// The kubernetes event recorder.
+	Recorder record.EventRecorder
+	// The owning CR.
+	Object runtime.Object
+	// Identical events are not recorded within the throttle.
+	// Default: EventThrottle.
+	Throttle time.Duration
+	// Message templates by reason.
+	// Default: EventTemplates.
+	Templates map[string]string
+	// Recorded (time) by event.
+	recorded map[string]time.Time
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Record a collection reconcile outcome.
+func (r *EventRecorder) Reconciled(kind string, duration time.Duration, err error) {
+	if err != nil {
+		r.event(
+			core.EventTypeWarning,
+			ReconcileFailedEvent,
+			kind,
+			map[string]string{
+				"kind":  kind,
+				"error": err.Error(),
+			})
+		return
+	}
+
+	r.event(
+		core.EventTypeNormal,
+		ReconciledEvent,
+		kind,
+		map[string]string{
+			"kind":     kind,
+			"duration": duration.Round(time.Millisecond).String(),
+		})
+}
+
+//
+// Record condition transitions.
+// Conditions (True) with the `Critical` or `Error`
+// category are recorded as warnings.
+func (r *EventRecorder) Conditions(changed condition.Explain) {
+	record := func(reason string, conditions map[string]condition.Condition) {
+		types := []string{}
+		for t := range conditions {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			cnd := conditions[t]
+			eventType := core.EventTypeNormal
+			if cnd.Status == condition.True &&
+				condition.SeverityOf(cnd.Category) >= condition.SeverityOf(condition.Error) &&
+				reason != ConditionDeletedEvent {
+				eventType = core.EventTypeWarning
+			}
+			r.Event(
+				eventType,
+				reason,
+				map[string]string{
+					"type":    cnd.Type,
+					"status":  cnd.Status,
+					"reason":  cnd.Reason,
+					"message": cnd.Message,
+				})
+		}
+	}
+	record(ConditionAddedEvent, changed.Added)
+	record(ConditionUpdatedEvent, changed.Updated)
+	record(ConditionDeletedEvent, changed.Deleted)
+}
+
+//
+// Record an event.
+// The message is rendered using the template for the reason.
+func (r *EventRecorder) Event(eventType, reason string, params map[string]string) {
+	r.event(eventType, reason, "", params)
+}
+
+//
+// Record an event.
+// Throttled by type, reason and key. When the key
+// is empty, the rendered message is used.
+func (r *EventRecorder) event(eventType, reason, key string, params map[string]string) {
+	if r.Recorder == nil || r.Object == nil {
+		return
+	}
+	message := r.render(reason, params)
+	if key == "" {
+		key = message
+	}
+	if r.throttled(eventType + reason + key) {
+		return
+	}
+
+	r.Recorder.Event(r.Object, eventType, reason, message)
+}
+
+//
+// Render the message.
+func (r *EventRecorder) render(reason string, params map[string]string) string {
+	templates := r.Templates
+	if templates == nil {
+		templates = EventTemplates
+	}
+	text, found := templates[reason]
+	if !found {
+		return reason
+	}
+	tmpl, err := template.New(reason).Parse(text)
+	if err != nil {
+		log.Trace(err)
+		return text
+	}
+	buffer := bytes.Buffer{}
+	err = tmpl.Execute(&buffer, params)
+	if err != nil {
+		log.Trace(err)
+		return text
+	}
+
+	return buffer.String()
+}
+
+//
+// The event has been recorded within the throttle.
+func (r *EventRecorder) throttled(key string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	throttle := r.Throttle
+	if throttle == 0 {
+		throttle = EventThrottle
+	}
+	if r.recorded == nil {
+		r.recorded = map[string]time.Time{}
+	}
+	now := time.Now()
+	for k, t := range r.recorded {
+		if now.Sub(t) > throttle {
+			delete(r.recorded, k)
+		}
+	}
+	if _, found := r.recorded[key]; found {
+		return true
+	}
+
+	r.recorded[key] = now
+
+	return false
+}
@@ -0,0 +1,37 @@
+package container
+
+import (
+	"errors"
+	"github.com/konveyor/controller/pkg/condition"
+	"github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func TestEventRecorder(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	fake := record.NewFakeRecorder(10)
+	recorder := EventRecorder{
+		Recorder: fake,
+		Object:   &core.Secret{},
+	}
+	// Reconciled (throttled).
+	recorder.Reconciled("Pod", time.Second, nil)
+	recorder.Reconciled("Pod", 2*time.Second, nil)
+	recorder.Reconciled("Pod", 0, errors.New("timeout"))
+	g.Expect(len(fake.Events)).To(gomega.Equal(2))
+	g.Expect(<-fake.Events).To(gomega.Equal("Normal Reconciled Collection `Pod` reconciled in 1s."))
+	g.Expect(<-fake.Events).To(gomega.Equal("Warning ReconcileFailed Collection `Pod` reconcile failed: timeout"))
+	// Conditions.
+	conditions := condition.Conditions{}
+	conditions.SetCondition(condition.Condition{
+		Type:     "NotFound",
+		Status:   condition.True,
+		Category: condition.Error,
+		Message:  "Not found.",
+	})
+	recorder.Conditions(conditions.Changed(condition.Conditions{}))
+	g.Expect(<-fake.Events).To(gomega.Equal("Warning ConditionAdded Condition `NotFound` (True) added. Not found."))
+}
@@ -0,0 +1,90 @@
+package container
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+)
+
+//
+// Dependency graph.
+// Used to order collections such that prerequisites
+// precede dependents.
+type Graph struct {
+	// Nodes in the order added.
+	nodes []string
+	// Dependencies by node.
+	dependencies map[string][]string
+}
+
+//
+// Add a node and its dependencies.
+func (r *Graph) Add(node string, dependsOn ...string) {
+	if r.dependencies == nil {
+		r.dependencies = map[string][]string{}
+	}
+	if _, found := r.dependencies[node]; !found {
+		r.nodes = append(r.nodes, node)
+	}
+
+	r.dependencies[node] = append(r.dependencies[node], dependsOn...)
+}
+
+//
+// Dependencies of a node.
+func (r *Graph) DependsOn(node string) []string {
+	return r.dependencies[node]
+}
+
+//
+// Sort (topological).
+// Prerequisites precede dependents. Otherwise, the order
+// in which nodes were added is preserved.
+// Returns an error when a dependency is not a node or
+// the graph contains a cycle.
+func (r *Graph) Sort() (sorted []string, err error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(node string, path []string) error
+	visit = func(node string, path []string) (err error) {
+		switch state[node] {
+		case visited:
+			return
+		case visiting:
+			err = liberr.New(
+				"dependency cycle.",
+				"path",
+				append(path, node))
+			return
+		}
+		state[node] = visiting
+		for _, dependency := range r.dependencies[node] {
+			if _, found := r.dependencies[dependency]; !found {
+				err = liberr.New(
+					"dependency not found.",
+					"node",
+					node,
+					"dependency",
+					dependency)
+				return
+			}
+			err = visit(dependency, append(path, node))
+			if err != nil {
+				return
+			}
+		}
+		state[node] = visited
+		sorted = append(sorted, node)
+		return
+	}
+	for _, node := range r.nodes {
+		err = visit(node, nil)
+		if err != nil {
+			sorted = nil
+			return
+		}
+	}
+
+	return
+}
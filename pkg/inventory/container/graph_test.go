@@ -0,0 +1,39 @@
+package container
+
+import (
+	"github.com/onsi/gomega"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	graph := Graph{}
+	graph.Add("VM", "Host", "Network")
+	graph.Add("Network")
+	graph.Add("Host", "Cluster")
+	graph.Add("Cluster")
+	graph.Add("Datastore")
+	sorted, err := graph.Sort()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(sorted).To(
+		gomega.Equal(
+			[]string{
+				"Cluster",
+				"Host",
+				"Network",
+				"VM",
+				"Datastore",
+			}))
+	// Not found.
+	graph = Graph{}
+	graph.Add("VM", "Host")
+	_, err = graph.Sort()
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Cycle.
+	graph = Graph{}
+	graph.Add("A", "B")
+	graph.Add("B", "C")
+	graph.Add("C", "A")
+	_, err = graph.Sort()
+	g.Expect(err).ToNot(gomega.BeNil())
+}
@@ -0,0 +1,448 @@
+package container
+
+import (
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/ref"
+	"io/ioutil"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//
+// Default time to wait for events to be delivered.
+var HarnessTimeout = 5 * time.Second
+
+//
+// Reconcile (testing) harness.
+// Wires a temporary directory, the models and fake collectors
+// for which the desired models are provided as slices. Each
+// collector has a DB in the temporary directory. Reconciles are
+// run on demand and the resulting (stored) models and events may
+// be asserted. Example:
+//   h := &container.Harness{Models: []interface{}{&Model{}}}
+//   err := h.Setup()
+//   defer h.Teardown()
+//   c, err := h.Collector("test")
+//   c.Set(&Model{ID: 1}, &Model{ID: 2})
+//   results, err := c.Reconcile()
+//   err = c.AssertCount(&Model{}, 2)
+//   err = c.AssertEvents(model.Created, 2)
+type Harness struct {
+	// Models (registered with each DB).
+	Models []interface{}
+	// Collection shepherd (optional).
+	Shepherd Shepherd
+	// Time to wait for events to be delivered.
+	// Default: HarnessTimeout.
+	Timeout time.Duration
+	// Container.
+	// Fake collectors are added.
+	Container *Container
+	// Temporary directory.
+	dir string
+	// Collectors by name.
+	collectors map[string]*FakeCollector
+}
+
+//
+// Setup the harness.
+// Creates the temporary directory and the container.
+func (h *Harness) Setup() (err error) {
+	h.dir, err = ioutil.TempDir("", "harness")
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	if h.Timeout == 0 {
+		h.Timeout = HarnessTimeout
+	}
+	h.Container = New()
+	h.collectors = map[string]*FakeCollector{}
+
+	return
+}
+
+//
+// Teardown the harness.
+// Collectors are deleted, DBs closed and the
+// temporary directory removed.
+func (h *Harness) Teardown() {
+	for _, c := range h.collectors {
+		h.Container.Delete(c.Owner())
+		c.close()
+	}
+	h.collectors = map[string]*FakeCollector{}
+	if h.dir != "" {
+		_ = os.RemoveAll(h.dir)
+		h.dir = ""
+	}
+}
+
+//
+// Get (or create) a fake collector by name.
+// The collector DB is opened (with the models) and
+// watched so that events may be asserted.
+func (h *Harness) Collector(name string) (c *FakeCollector, err error) {
+	c, found := h.collectors[name]
+	if found {
+		return
+	}
+	c = &FakeCollector{
+		name:     name,
+		harness:  h,
+		desired:  map[string][]model.Model{},
+		recorder: &recorder{},
+	}
+	c.db = model.New(
+		filepath.Join(h.dir, name+".db"),
+		h.Models...)
+	err = c.db.Open(true)
+	if err != nil {
+		return
+	}
+	for _, m := range h.Models {
+		var w *model.Watch
+		w, err = c.db.Watch(m.(model.Model), c.recorder)
+		if err != nil {
+			c.close()
+			return
+		}
+		c.watches = append(c.watches, w)
+	}
+	err = h.Container.Add(c)
+	if err != nil {
+		c.close()
+		return
+	}
+	h.collectors[name] = c
+
+	return
+}
+
+//
+// Reconcile all collectors.
+// Returns the results (by kind) by collector name.
+func (h *Harness) Reconcile() (results map[string]map[string]*Result, err error) {
+	results = map[string]map[string]*Result{}
+	for name, c := range h.collectors {
+		results[name], err = c.Reconcile()
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Fake collector.
+// The desired models are provided by the test.
+type FakeCollector struct {
+	// Name.
+	name string
+	// Harness.
+	harness *Harness
+	// DB.
+	db model.DB
+	// Desired models by kind.
+	desired map[string][]model.Model
+	// Watches.
+	watches []*model.Watch
+	// Event recorder.
+	recorder *recorder
+	// Has parity.
+	parity bool
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// The name.
+func (r *FakeCollector) Name() string {
+	return r.name
+}
+
+//
+// The resource that owns the collector.
+func (r *FakeCollector) Owner() meta.Object {
+	return &meta.ObjectMeta{
+		Name: r.name,
+		UID:  types.UID(r.name),
+	}
+}
+
+//
+// Start the collector.
+func (r *FakeCollector) Start() error {
+	return nil
+}
+
+//
+// Shutdown the collector.
+func (r *FakeCollector) Shutdown() {
+}
+
+//
+// The collector has been reconciled.
+func (r *FakeCollector) HasParity() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.parity
+}
+
+//
+// Get the associated DB.
+func (r *FakeCollector) DB() model.DB {
+	return r.db
+}
+
+//
+// Test connection with credentials.
+func (r *FakeCollector) Test() error {
+	return nil
+}
+
+//
+// Reset.
+func (r *FakeCollector) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.parity = false
+}
+
+//
+// Set the desired models.
+// Replaces the desired set (all kinds). Stored models
+// of kinds not included are deleted by Reconcile().
+func (r *FakeCollector) Set(models ...model.Model) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.desired = map[string][]model.Model{}
+	for _, m := range models {
+		kind := ref.ToKind(m)
+		r.desired[kind] = append(r.desired[kind], m)
+	}
+}
+
+//
+// Reconcile the stored models (all kinds) with the desired
+// models within a single transaction. Waits for the events to
+// be delivered. Returns the result by kind.
+func (r *FakeCollector) Reconcile() (results map[string]*Result, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	results = map[string]*Result{}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = tx.End()
+	}()
+	expected := r.recorder.len()
+	for _, m := range r.harness.Models {
+		kind := ref.ToKind(m)
+		desired := fb.NewList()
+		for _, d := range r.desired[kind] {
+			err = desired.Append(d)
+			if err != nil {
+				return
+			}
+		}
+		var stored fb.Iterator
+		stored, err = tx.Find(
+			m,
+			model.ListOptions{
+				Detail: model.MaxDetail,
+			})
+		if err != nil {
+			return
+		}
+		collection := Collection{
+			Stored:   stored,
+			Tx:       tx,
+			Shepherd: r.harness.Shepherd,
+		}
+		var result *Result
+		result, err = collection.Reconcile(desired.Iter())
+		if err != nil {
+			return
+		}
+		results[kind] = result
+		expected += result.Added + result.Updated + result.Deleted
+	}
+	err = tx.Commit()
+	if err != nil {
+		return
+	}
+	r.parity = true
+	err = r.recorder.wait(expected, r.harness.Timeout)
+
+	return
+}
+
+//
+// Events delivered (all kinds).
+func (r *FakeCollector) Events() []model.Event {
+	return r.recorder.list()
+}
+
+//
+// Assert the number of stored models of the kind.
+func (r *FakeCollector) AssertCount(kind model.Model, n int64) (err error) {
+	count, err := r.db.Count(kind, nil)
+	if err != nil {
+		return
+	}
+	if count != n {
+		err = liberr.New(
+			fmt.Sprintf(
+				"%s: expected: %d, stored: %d.",
+				ref.ToKind(kind),
+				n,
+				count))
+	}
+
+	return
+}
+
+//
+// Assert the models are stored (and equal).
+// Compared using the (harness) shepherd.
+func (r *FakeCollector) AssertStored(models ...model.Model) (err error) {
+	shepherd := r.harness.Shepherd
+	if shepherd == nil {
+		shepherd = &DefaultShepherd{}
+	}
+	for _, m := range models {
+		var object model.Model
+		object, err = keyOf(m)
+		if err != nil {
+			return
+		}
+		err = r.db.Get(object)
+		if err != nil {
+			err = liberr.Wrap(err, "model", model.Describe(m))
+			return
+		}
+		if !shepherd.Equals(object, m) {
+			err = liberr.New(
+				fmt.Sprintf(
+					"%s: not equal: stored: %s.",
+					model.Describe(m),
+					model.Describe(object)))
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Assert the number of events delivered with the action.
+func (r *FakeCollector) AssertEvents(action uint8, n int) (err error) {
+	count := 0
+	for _, event := range r.recorder.list() {
+		if event.Action == action {
+			count++
+		}
+	}
+	if count != n {
+		err = liberr.New(
+			fmt.Sprintf(
+				"events (action=%d): expected: %d, delivered: %d.",
+				action,
+				n,
+				count))
+	}
+
+	return
+}
+
+//
+// Close the DB.
+func (r *FakeCollector) close() {
+	for _, w := range r.watches {
+		r.db.EndWatch(w)
+	}
+	r.watches = nil
+	_ = r.db.Close(true)
+}
+
+//
+// Event recorder.
+type recorder struct {
+	model.StockEventHandler
+	// Delivered events.
+	events []model.Event
+	// Protect the list.
+	mutex sync.Mutex
+}
+
+//
+// A model has been created.
+func (r *recorder) Created(event model.Event) {
+	r.record(event)
+}
+
+//
+// A model has been updated.
+func (r *recorder) Updated(event model.Event) {
+	r.record(event)
+}
+
+//
+// A model has been deleted.
+func (r *recorder) Deleted(event model.Event) {
+	r.record(event)
+}
+
+//
+// Record the event.
+func (r *recorder) record(event model.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+//
+// Number of events delivered.
+func (r *recorder) len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.events)
+}
+
+//
+// Delivered events.
+func (r *recorder) list() []model.Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]model.Event{}, r.events...)
+}
+
+//
+// Wait for the number of events to be delivered.
+func (r *recorder) wait(n int, timeout time.Duration) (err error) {
+	deadline := time.Now().Add(timeout)
+	for r.len() < n {
+		if time.Now().After(deadline) {
+			err = liberr.New(
+				fmt.Sprintf(
+					"events: expected: %d, delivered: %d.",
+					n,
+					r.len()))
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return
+}
@@ -0,0 +1,57 @@
+package container
+
+import (
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/onsi/gomega"
+	"testing"
+)
+
+func TestHarness(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := &Harness{
+		Models: []interface{}{
+			&TestObject2{},
+			&TestObject4{},
+		},
+	}
+	err := h.Setup()
+	g.Expect(err).To(gomega.BeNil())
+	defer h.Teardown()
+	a, err := h.Collector("a")
+	g.Expect(err).To(gomega.BeNil())
+	b, err := h.Collector("b")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(h.Container.List())).To(gomega.Equal(2))
+	g.Expect(a.HasParity()).To(gomega.BeFalse())
+	// added.
+	a.Set(
+		&TestObject2{ID: 1, Name: "Elmer"},
+		&TestObject2{ID: 2, Name: "Elmer"},
+		&TestObject4{ID: 1, Name: "Elmer"})
+	b.Set(&TestObject2{ID: 1, Name: "Fudd"})
+	results, err := h.Reconcile()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(results["a"]["TestObject2"].Added).To(gomega.Equal(2))
+	g.Expect(results["a"]["TestObject4"].Added).To(gomega.Equal(1))
+	g.Expect(results["b"]["TestObject2"].Added).To(gomega.Equal(1))
+	g.Expect(a.HasParity()).To(gomega.BeTrue())
+	g.Expect(a.AssertCount(&TestObject2{}, 2)).To(gomega.BeNil())
+	g.Expect(a.AssertCount(&TestObject4{}, 1)).To(gomega.BeNil())
+	g.Expect(b.AssertCount(&TestObject2{}, 1)).To(gomega.BeNil())
+	g.Expect(b.AssertStored(&TestObject2{ID: 1, Name: "Fudd"})).To(gomega.BeNil())
+	g.Expect(b.AssertStored(&TestObject2{ID: 1, Name: "Elmer"})).ToNot(gomega.BeNil())
+	g.Expect(a.AssertEvents(model.Created, 3)).To(gomega.BeNil())
+	g.Expect(b.AssertEvents(model.Created, 1)).To(gomega.BeNil())
+	// updated and deleted.
+	a.Set(&TestObject2{ID: 1, Name: "Larry"})
+	result, err := a.Reconcile()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result["TestObject2"].Updated).To(gomega.Equal(1))
+	g.Expect(result["TestObject2"].Deleted).To(gomega.Equal(1))
+	g.Expect(result["TestObject4"].Deleted).To(gomega.Equal(1))
+	g.Expect(a.AssertStored(&TestObject2{ID: 1, Name: "Larry"})).To(gomega.BeNil())
+	g.Expect(a.AssertCount(&TestObject4{}, 0)).To(gomega.BeNil())
+	g.Expect(a.AssertEvents(model.Updated, 1)).To(gomega.BeNil())
+	g.Expect(a.AssertEvents(model.Deleted, 2)).To(gomega.BeNil())
+	g.Expect(len(a.Events())).To(gomega.Equal(6))
+}
@@ -0,0 +1,200 @@
+package container
+
+import (
+	"context"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/logging"
+	"github.com/konveyor/controller/pkg/tracing"
+	"time"
+)
+
+//
+// Reconcile phases (incremental).
+const (
+	PhaseIncremental = "incremental"
+)
+
+//
+// Incremental change action.
+type ChangeAction int
+
+//
+// Change actions.
+const (
+	// The model is added or updated (as needed).
+	Upserted ChangeAction = iota
+	// The model is deleted.
+	Removed
+)
+
+//
+// Incremental (single model) change.
+// Reported by collectors watching provider event streams.
+type Change struct {
+	// Action.
+	Action ChangeAction
+	// The (desired) model.
+	// Only the PK (and key) fields are needed when removed.
+	Model model.Model
+}
+
+//
+// Apply incremental changes.
+// Each change is applied (in order) using the same shepherd,
+// miss counter, stored-field preservation, validation and
+// garbage hook as a full reconcile. The stored collection is
+// not used; models are fetched (by PK) using the transaction.
+// A removed model is deleted without a grace period and a
+// removed model not stored is skipped. The caller is expected
+// to commit (or end) the transaction.
+// Example:
+//   tx, err := db.Begin()
+//   collection := container.Collection{Tx: tx}
+//   result, err := collection.Apply(
+//      ctx,
+//      container.Change{
+//         Action: container.Upserted,
+//         Model:  vm,
+//      })
+//   err = tx.Commit()
+func (r *Collection) Apply(ctx context.Context, changes ...Change) (result *Result, err error) {
+	result = newResult()
+	ctx, restore := r.correlate(ctx)
+	defer restore()
+	result.ReconcileID = logging.ReconcileIDOf(ctx)
+	log := log.ForContext(ctx)
+	defer func() {
+		result.failed(err)
+	}()
+	ctx, span := tracing.Start(
+		ctx,
+		"collection.apply",
+		tracing.Reconcile,
+		result.ReconcileID)
+	defer tracing.End(span, &err)
+	mark := time.Now()
+	shepherd := r.shepherd()
+	deleted := []model.Model{}
+	for _, change := range changes {
+		err = canceled(ctx)
+		if err != nil {
+			return
+		}
+		switch change.Action {
+		case Upserted:
+			result.Desired++
+			err = r.validated(change.Model)
+			if err == nil {
+				err = r.upsert(result, shepherd, change.Model)
+			}
+		case Removed:
+			var m model.Model
+			m, err = r.remove(result, change.Model)
+			if m != nil {
+				deleted = append(deleted, m)
+			}
+		default:
+			err = liberr.New(
+				"unknown change action.",
+				"action",
+				change.Action)
+		}
+		if err != nil {
+			return
+		}
+	}
+	err = r.garbage(deleted)
+	if err != nil {
+		return
+	}
+
+	result.Durations[PhaseIncremental] = time.Since(mark)
+
+	log.V(3).Info(
+		"collection changes applied.",
+		"added",
+		result.Added,
+		"updated",
+		result.Updated,
+		"deleted",
+		result.Deleted,
+		"skipped",
+		result.Skipped)
+
+	return
+}
+
+//
+// Add or update a model.
+func (r *Collection) Upsert(ctx context.Context, m model.Model) (result *Result, err error) {
+	result, err = r.Apply(ctx, Change{Action: Upserted, Model: m})
+	return
+}
+
+//
+// Delete a model.
+func (r *Collection) Remove(ctx context.Context, m model.Model) (result *Result, err error) {
+	result, err = r.Apply(ctx, Change{Action: Removed, Model: m})
+	return
+}
+
+//
+// Add or update the (desired) model.
+func (r *Collection) upsert(result *Result, shepherd Shepherd, desired model.Model) (err error) {
+	stored, err := keyOf(desired)
+	if err != nil {
+		return
+	}
+	err = r.txOp(OpGet, stored, func() error {
+		return r.Tx.Get(stored)
+	})
+	if err != nil {
+		if !errors.Is(err, model.NotFound) {
+			return
+		}
+		err = r.txOp(OpInsert, desired, func() error {
+			return r.Tx.Insert(desired)
+		})
+		if err == nil {
+			result.Added++
+			result.changed(desired, "added")
+		}
+		return
+	}
+
+	err = r.merge(result, shepherd, stored, desired)
+
+	return
+}
+
+//
+// Delete the (stored) model.
+// Returns the deleted model; nil when not stored.
+func (r *Collection) remove(result *Result, m model.Model) (deleted model.Model, err error) {
+	stored, err := keyOf(m)
+	if err != nil {
+		return
+	}
+	err = r.txOp(OpGet, stored, func() error {
+		return r.Tx.Get(stored)
+	})
+	if err != nil {
+		if errors.Is(err, model.NotFound) {
+			result.Skipped++
+			err = nil
+		}
+		return
+	}
+	err = r.txOp(OpRemove, stored, func() error {
+		return r.Tx.Delete(stored)
+	})
+	if err == nil {
+		result.Deleted++
+		result.changed(stored, "deleted")
+		deleted = stored
+	}
+
+	return
+}
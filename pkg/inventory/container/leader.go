@@ -0,0 +1,196 @@
+package container
+
+import (
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"k8s.io/client-go/tools/leaderelection"
+	"sync"
+)
+
+//
+// Collector (optional) active/passive support.
+// In standby, watches run and the DB is open (read) but
+// the collector does not update the DB.
+type Activatable interface {
+	// Enter standby.
+	Standby()
+	// Activate writer paths.
+	Activate()
+}
+
+//
+// Collector DB (optional) read-only support.
+// In standby, the DB is set read-only (SQLite mode=ro).
+// See: model.Client.ReadOnly().
+type ReadOnlyDB interface {
+	// Set the DB read-only.
+	ReadOnly(bool) error
+}
+
+//
+// Activation gate.
+// The zero value is active.
+type Activation struct {
+	// Closed on activation.
+	// Nil when active.
+	standby chan struct{}
+	// Protect the channel.
+	mutex sync.Mutex
+}
+
+//
+// Enter standby.
+func (r *Activation) Standby() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.standby == nil {
+		r.standby = make(chan struct{})
+	}
+}
+
+//
+// Activate.
+// Returns: true when in standby.
+func (r *Activation) Activate() (activated bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.standby != nil {
+		close(r.standby)
+		r.standby = nil
+		activated = true
+	}
+
+	return
+}
+
+//
+// Active (not in standby).
+func (r *Activation) Active() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.standby == nil
+}
+
+//
+// Wait while in standby.
+// Returns an error when the context is done.
+func (r *Activation) Wait(ctx context.Context) (err error) {
+	r.mutex.Lock()
+	ch := r.standby
+	r.mutex.Unlock()
+	if ch == nil {
+		return
+	}
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		err = liberr.Wrap(ctx.Err())
+	}
+
+	return
+}
+
+//
+// Container in standby (passive) mode.
+// Collectors are added in standby until activated.
+// The collector DBs are set read-only.
+func (c *Container) Standby() {
+	c.activation.Standby()
+	for _, collector := range c.List() {
+		if activatable, cast := collector.(Activatable); cast {
+			activatable.Standby()
+		}
+		c.readOnly(collector, true)
+	}
+
+	log.V(3).Info("container in standby.")
+}
+
+//
+// Activate the container.
+// Writer paths of all (activatable) collectors are activated
+// and the collector DBs are set writable.
+func (c *Container) Activate() {
+	c.activation.Activate()
+	for _, collector := range c.List() {
+		c.readOnly(collector, false)
+		if activatable, cast := collector.(Activatable); cast {
+			activatable.Activate()
+		}
+	}
+
+	log.V(3).Info("container activated.")
+}
+
+//
+// Deactivate the container.
+// Collectors are placed in standby and restarted so that
+// each will fully reconcile when (again) activated.
+func (c *Container) Deactivate() {
+	c.Standby()
+	for _, collector := range c.List() {
+		if _, cast := collector.(Activatable); !cast {
+			continue
+		}
+		err := c.Restart(collector.Owner())
+		if err != nil {
+			log.Trace(err)
+		}
+	}
+
+	log.V(3).Info("container deactivated.")
+}
+
+//
+// The container is active (not in standby).
+func (c *Container) Active() bool {
+	return c.activation.Active()
+}
+
+//
+// Leader election callbacks.
+// The container is placed in standby and activated when
+// leading. When leadership is lost, the container is
+// deactivated (placed back in standby).
+func (c *Container) LeaderCallbacks() leaderelection.LeaderCallbacks {
+	c.Standby()
+	return leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(context.Context) {
+			c.Activate()
+		},
+		OnStoppedLeading: func() {
+			c.Deactivate()
+		},
+	}
+}
+
+//
+// Apply the activation to a collector.
+func (c *Container) applyActivation(collector Collector) {
+	if c.activation.Active() {
+		return
+	}
+	if activatable, cast := collector.(Activatable); cast {
+		activatable.Standby()
+	}
+	c.readOnly(collector, true)
+}
+
+//
+// Set the collector DB read-only (when supported).
+func (c *Container) readOnly(collector Collector, readOnly bool) {
+	db, cast := collector.DB().(ReadOnlyDB)
+	if !cast {
+		return
+	}
+	err := db.ReadOnly(readOnly)
+	if err != nil {
+		log.Error(
+			err,
+			"set DB read-only failed.",
+			"collector",
+			collector.Name(),
+			"readOnly",
+			readOnly)
+	}
+}
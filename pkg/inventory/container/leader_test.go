@@ -0,0 +1,61 @@
+package container
+
+import (
+	"context"
+	"github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+type ActivatableCollector struct {
+	TestCollector
+	activation Activation
+}
+
+func (r *ActivatableCollector) Standby() {
+	r.activation.Standby()
+}
+
+func (r *ActivatableCollector) Activate() {
+	r.activation.Activate()
+}
+
+func TestActivation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	activation := Activation{}
+	g.Expect(activation.Active()).To(gomega.BeTrue())
+	g.Expect(activation.Wait(context.Background())).To(gomega.BeNil())
+	activation.Standby()
+	g.Expect(activation.Active()).To(gomega.BeFalse())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	g.Expect(activation.Wait(ctx)).ToNot(gomega.BeNil())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		activation.Activate()
+	}()
+	g.Expect(activation.Wait(context.Background())).To(gomega.BeNil())
+	g.Expect(activation.Active()).To(gomega.BeTrue())
+	g.Expect(activation.Activate()).To(gomega.BeFalse())
+}
+
+func TestContainerStandby(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	c := New()
+	callbacks := c.LeaderCallbacks()
+	g.Expect(c.Active()).To(gomega.BeFalse())
+	collector := &ActivatableCollector{}
+	err := c.Add(collector)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(collector.activation.Active()).To(gomega.BeFalse())
+	callbacks.OnStartedLeading(context.Background())
+	g.Expect(c.Active()).To(gomega.BeTrue())
+	g.Expect(collector.activation.Active()).To(gomega.BeTrue())
+	callbacks.OnStoppedLeading()
+	g.Expect(c.Active()).To(gomega.BeFalse())
+	g.Expect(collector.activation.Active()).To(gomega.BeFalse())
+	collector.mutex.Lock()
+	g.Expect(collector.started).To(gomega.Equal(2))
+	collector.mutex.Unlock()
+	c.Delete(collector.Owner())
+}
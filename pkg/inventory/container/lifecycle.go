@@ -0,0 +1,368 @@
+package container
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//
+// Collector (lifecycle) state.
+type State string
+
+//
+// States.
+const (
+	// Starting.
+	Connecting State = "connecting"
+	// Started; parity not achieved.
+	Collecting State = "collecting"
+	// Has parity.
+	Ready State = "ready"
+	// Failed.
+	Errored State = "error"
+	// Waiting to be restarted.
+	Backoff State = "backoff"
+	// Stopped.
+	Stopped State = "stopped"
+)
+
+//
+// Default collector health (status) poll interval.
+var StatusPoll = time.Second
+
+//
+// Collector (optional) error reporting.
+// A collector reporting an error is restarted
+// using the container backoff.
+type ErrorReporter interface {
+	// The (unrecovered) error.
+	// Returns nil when healthy.
+	Failed() error
+}
+
+//
+// Restart backoff.
+type BackoffPolicy struct {
+	// Initial delay.
+	Min time.Duration
+	// Maximum delay.
+	Max time.Duration
+	// Growth factor.
+	Factor float64
+	// Jitter (fraction of the delay).
+	Jitter float64
+}
+
+//
+// Default restart backoff.
+var DefaultBackoff = BackoffPolicy{
+	Min:    time.Second,
+	Max:    time.Minute * 5,
+	Factor: 2,
+	Jitter: 0.2,
+}
+
+//
+// Delay for the (zero-based) attempt.
+func (r *BackoffPolicy) Delay(attempt int) (d time.Duration) {
+	factor := r.Factor
+	if factor < 1 {
+		factor = 1
+	}
+	n := float64(r.Min) * math.Pow(factor, float64(attempt))
+	if r.Max > 0 && n > float64(r.Max) {
+		n = float64(r.Max)
+	}
+	if r.Jitter > 0 {
+		n += n * r.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	d = time.Duration(n)
+
+	return
+}
+
+//
+// Collector status (snapshot).
+type Status struct {
+	// Collector key.
+	Key Key `json:"key"`
+	// Collector name.
+	Name string `json:"name"`
+	// Current state.
+	State State `json:"state"`
+	// Last error.
+	Error string `json:"error,omitempty"`
+	// Number of restarts.
+	Restarts int `json:"restarts"`
+	// Time of the last state transition.
+	Since time.Time `json:"since"`
+	// Time of the next restart (backoff).
+	NextRestart *time.Time `json:"nextRestart,omitempty"`
+}
+
+//
+// Collector lifecycle.
+// Tracks the state and restarts failed collectors.
+type lifecycle struct {
+	// The collector.
+	collector Collector
+	// Collector key.
+	key Key
+	// Restart backoff.
+	backoff BackoffPolicy
+	// Health (status) poll interval.
+	poll time.Duration
+	// Current state.
+	state State
+	// Last error.
+	err error
+	// Number of restarts.
+	restarts int
+	// Consecutive failed attempts.
+	attempt int
+	// Time of the last state transition.
+	since time.Time
+	// Time of the next restart.
+	next time.Time
+	// Provider connection.
+	conn connection
+	// Stopped.
+	stopped bool
+	// Closed to stop the monitor.
+	done chan struct{}
+	// Protect fields.
+	mutex sync.Mutex
+	// Serialize transitions (start, restart, stop).
+	busy sync.Mutex
+}
+
+//
+// Start the collector and the monitor.
+// The collector is restarted when the start fails.
+// Ignored when already started (and not stopped).
+func (r *lifecycle) start() (err error) {
+	r.busy.Lock()
+	defer r.busy.Unlock()
+	r.mutex.Lock()
+	if r.done != nil && !r.stopped {
+		r.mutex.Unlock()
+		return
+	}
+	r.stopped = false
+	r.attempt = 0
+	r.done = make(chan struct{})
+	r.mutex.Unlock()
+	err = r.startCollector()
+	go r.run(r.done)
+	return
+}
+
+//
+// Stop the monitor and shutdown the collector.
+func (r *lifecycle) stop() {
+	r.busy.Lock()
+	defer r.busy.Unlock()
+	r.mutex.Lock()
+	if r.stopped {
+		r.mutex.Unlock()
+		return
+	}
+	r.stopped = true
+	close(r.done)
+	r.mutex.Unlock()
+	r.collector.Shutdown()
+	r.disconnected(nil)
+	r.set(Stopped, nil)
+}
+
+//
+// Restart the collector.
+// Ignored when the monitor (done) has been stopped.
+func (r *lifecycle) restart(done chan struct{}) (err error) {
+	r.busy.Lock()
+	defer r.busy.Unlock()
+	select {
+	case <-done:
+		return
+	default:
+	}
+	r.mutex.Lock()
+	r.restarts++
+	r.mutex.Unlock()
+	r.collector.Shutdown()
+	r.reset()
+	err = r.startCollector()
+
+	log.V(3).Info(
+		"collector restarted.",
+		"owner",
+		r.key,
+		"restarts",
+		r.restarts)
+
+	return
+}
+
+//
+// Start the collector.
+func (r *lifecycle) startCollector() (err error) {
+	r.set(Connecting, nil)
+	err = r.collector.Start()
+	if err != nil {
+		err = liberr.Wrap(err)
+		r.disconnected(err)
+		r.set(Errored, err)
+		return
+	}
+
+	r.set(Collecting, nil)
+
+	return
+}
+
+//
+// Monitor the collector.
+// Failed collectors are restarted after the backoff delay.
+// Collectors failed with a terminal error are not restarted.
+func (r *lifecycle) run(done chan struct{}) {
+	for {
+		delay := r.poll
+		failed := r.current() == Errored
+		if failed && liberr.Terminal(r.lastErr()) {
+			log.V(3).Info(
+				"collector failed (terminal), not restarted.",
+				"owner",
+				r.key)
+			<-done
+			return
+		}
+		if failed {
+			r.mutex.Lock()
+			delay = r.backoff.Delay(r.attempt)
+			r.attempt++
+			r.next = time.Now().Add(delay)
+			r.mutex.Unlock()
+			r.set(Backoff, r.lastErr())
+			log.V(3).Info(
+				"collector failed, restart scheduled.",
+				"owner",
+				r.key,
+				"delay",
+				delay)
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(delay):
+		}
+		if failed {
+			_ = r.restart(done)
+		} else {
+			r.probe()
+			r.check()
+		}
+	}
+}
+
+//
+// Probe the collector and update the state.
+func (r *lifecycle) probe() {
+	switch r.current() {
+	case Collecting, Ready:
+	default:
+		return
+	}
+	if reporter, cast := r.collector.(ErrorReporter); cast {
+		err := reporter.Failed()
+		if err != nil {
+			r.set(Errored, liberr.Wrap(err))
+			return
+		}
+	}
+	if r.collector.HasParity() {
+		r.mutex.Lock()
+		r.attempt = 0
+		r.mutex.Unlock()
+		r.set(Ready, nil)
+	} else {
+		r.set(Collecting, nil)
+	}
+}
+
+//
+// Set the state.
+func (r *lifecycle) set(state State, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stopped && state != Stopped {
+		return
+	}
+	r.err = err
+	if r.state == state {
+		return
+	}
+	r.state = state
+	r.since = time.Now()
+
+	log.V(3).Info(
+		"collector state changed.",
+		"owner",
+		r.key,
+		"state",
+		state)
+}
+
+//
+// Current state.
+func (r *lifecycle) current() State {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.state
+}
+
+//
+// Last error.
+func (r *lifecycle) lastErr() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+//
+// Status snapshot.
+func (r *lifecycle) status() (s Status) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s = Status{
+		Key:      r.key,
+		Name:     r.collector.Name(),
+		State:    r.state,
+		Restarts: r.restarts,
+		Since:    r.since,
+	}
+	if r.err != nil {
+		s.Error = r.err.Error()
+	}
+	if r.state == Backoff {
+		next := r.next
+		s.NextRestart = &next
+	}
+
+	return
+}
+
+//
+// Reset the collector.
+// Parity has been lost so watches on the collector DB
+// are reset (relist).
+func (r *lifecycle) reset() {
+	r.collector.Reset()
+	if db := r.collector.DB(); db != nil {
+		db.Relist(model.ResetParityLost)
+	}
+}
@@ -0,0 +1,330 @@
+package container
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/onsi/gomega"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sync"
+	"testing"
+	"time"
+)
+
+func init() {
+	ConnectionPoll = 10 * time.Millisecond
+}
+
+type TestCollector struct {
+	// Number of starts to fail.
+	failStart int
+	// Start failures are terminal.
+	terminal bool
+	// Reported error.
+	failed error
+	// Number of starts.
+	started int
+	// Has parity.
+	parity bool
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+func (r *TestCollector) Name() string {
+	return "test"
+}
+
+func (r *TestCollector) Owner() meta.Object {
+	return &meta.ObjectMeta{
+		UID: "TEST",
+	}
+}
+
+func (r *TestCollector) Start() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.started++
+	if r.failStart > 0 {
+		r.failStart--
+		if r.terminal {
+			return liberr.WrapTerminal(errors.New("start failed"))
+		}
+		return errors.New("start failed")
+	}
+	r.parity = true
+	return nil
+}
+
+func (r *TestCollector) Shutdown() {
+}
+
+func (r *TestCollector) HasParity() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.parity
+}
+
+func (r *TestCollector) DB() model.DB {
+	return nil
+}
+
+func (r *TestCollector) Test() error {
+	return nil
+}
+
+func (r *TestCollector) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.parity = false
+	r.failed = nil
+}
+
+func (r *TestCollector) Failed() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.failed
+}
+
+func (r *TestCollector) fail(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.failed = err
+}
+
+func (r *TestCollector) starts() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.started
+}
+
+func waitState(c *Container, owner meta.Object, state State) (s Status) {
+	for i := 0; i < 200; i++ {
+		s, _ = c.StatusOf(owner)
+		if s.State == state {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return
+}
+
+func TestLifecycle(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	c := New()
+	c.StatusPoll = 10 * time.Millisecond
+	c.Backoff = BackoffPolicy{
+		Min:    200 * time.Millisecond,
+		Max:    400 * time.Millisecond,
+		Factor: 2,
+	}
+	collector := &TestCollector{failStart: 2}
+	owner := collector.Owner()
+	err := c.Add(collector)
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Restarted (backoff) until ready.
+	s := waitState(c, owner, Ready)
+	g.Expect(s.State).To(gomega.Equal(Ready))
+	g.Expect(s.Restarts).To(gomega.Equal(2))
+	g.Expect(collector.starts()).To(gomega.Equal(3))
+	// Reported error.
+	collector.fail(errors.New("lost connection"))
+	s = waitState(c, owner, Backoff)
+	g.Expect(s.State).To(gomega.Equal(Backoff))
+	g.Expect(s.Error).ToNot(gomega.BeEmpty())
+	g.Expect(s.NextRestart).ToNot(gomega.BeNil())
+	s = waitState(c, owner, Ready)
+	g.Expect(s.State).To(gomega.Equal(Ready))
+	g.Expect(s.Restarts).To(gomega.Equal(3))
+	// Started (ignored).
+	n := collector.starts()
+	err = c.Start(owner)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(collector.starts()).To(gomega.Equal(n))
+	// Stop.
+	err = c.Stop(owner)
+	g.Expect(err).To(gomega.BeNil())
+	s, found := c.StatusOf(owner)
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(s.State).To(gomega.Equal(Stopped))
+	n = collector.starts()
+	time.Sleep(50 * time.Millisecond)
+	g.Expect(collector.starts()).To(gomega.Equal(n))
+	// Restart.
+	err = c.Restart(owner)
+	g.Expect(err).To(gomega.BeNil())
+	s = waitState(c, owner, Ready)
+	g.Expect(s.State).To(gomega.Equal(Ready))
+	// Status.
+	g.Expect(len(c.Status())).To(gomega.Equal(1))
+	c.Delete(owner)
+	g.Expect(len(c.Status())).To(gomega.Equal(0))
+}
+
+func TestBackoffDelay(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	backoff := BackoffPolicy{
+		Min:    time.Second,
+		Max:    time.Second * 10,
+		Factor: 2,
+	}
+	g.Expect(backoff.Delay(0)).To(gomega.Equal(time.Second))
+	g.Expect(backoff.Delay(2)).To(gomega.Equal(time.Second * 4))
+	g.Expect(backoff.Delay(10)).To(gomega.Equal(time.Second * 10))
+	backoff.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		d := backoff.Delay(1)
+		g.Expect(d >= time.Second).To(gomega.BeTrue())
+		g.Expect(d <= time.Second*3).To(gomega.BeTrue())
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	c := New()
+	c.StatusPoll = 10 * time.Millisecond
+	c.Backoff = BackoffPolicy{
+		Min:    10 * time.Millisecond,
+		Max:    10 * time.Millisecond,
+		Factor: 2,
+	}
+	collector := &TestCollector{failStart: 2, terminal: true}
+	owner := collector.Owner()
+	err := c.Add(collector)
+	g.Expect(liberr.Terminal(err)).To(gomega.BeTrue())
+	// Not restarted.
+	time.Sleep(100 * time.Millisecond)
+	s, _ := c.StatusOf(owner)
+	g.Expect(s.State).To(gomega.Equal(Errored))
+	g.Expect(collector.starts()).To(gomega.Equal(1))
+	// Restarted explicitly.
+	err = c.Restart(owner)
+	g.Expect(liberr.Terminal(err)).To(gomega.BeTrue())
+	g.Expect(collector.starts()).To(gomega.Equal(2))
+	err = c.Restart(owner)
+	g.Expect(err).To(gomega.BeNil())
+	s = waitState(c, owner, Ready)
+	g.Expect(s.State).To(gomega.Equal(Ready))
+	c.Delete(owner)
+}
+
+type VersionedCollector struct {
+	TestCollector
+	// Test error.
+	testErr error
+}
+
+func (r *VersionedCollector) Test() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.testErr
+}
+
+func (r *VersionedCollector) APIVersion() string {
+	return "7.0.3"
+}
+
+func (r *VersionedCollector) failTest(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.testErr = err
+}
+
+func waitConnected(c *Container, owner meta.Object, connected bool) (s ConnectionStatus) {
+	for i := 0; i < 200; i++ {
+		s, _ = c.ConnectionOf(owner)
+		if s.LastChecked != nil && s.Connected == connected {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return
+}
+
+func TestConnection(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	c := New()
+	c.StatusPoll = 10 * time.Millisecond
+	collector := &VersionedCollector{}
+	owner := collector.Owner()
+	err := c.Add(collector)
+	g.Expect(err).To(gomega.BeNil())
+	s := waitConnected(c, owner, true)
+	g.Expect(s.Connected).To(gomega.BeTrue())
+	g.Expect(s.Name).To(gomega.Equal("test"))
+	g.Expect(s.LastConnected).ToNot(gomega.BeNil())
+	g.Expect(s.Latency).ToNot(gomega.BeEmpty())
+	g.Expect(s.APIVersion).To(gomega.Equal("7.0.3"))
+	g.Expect(s.Error).To(gomega.BeEmpty())
+	// Failed.
+	collector.failTest(errors.New("connection refused"))
+	s = waitConnected(c, owner, false)
+	g.Expect(s.Connected).To(gomega.BeFalse())
+	g.Expect(s.Error).To(gomega.Equal("connection refused"))
+	g.Expect(s.LastConnected).ToNot(gomega.BeNil())
+	// Recovered.
+	collector.failTest(nil)
+	s = waitConnected(c, owner, true)
+	g.Expect(s.Connected).To(gomega.BeTrue())
+	g.Expect(s.Error).To(gomega.BeEmpty())
+	// Stopped.
+	err = c.Stop(owner)
+	g.Expect(err).To(gomega.BeNil())
+	s, _ = c.ConnectionOf(owner)
+	g.Expect(s.Connected).To(gomega.BeFalse())
+	g.Expect(len(c.Connections())).To(gomega.Equal(1))
+	c.Delete(owner)
+	g.Expect(len(c.Connections())).To(gomega.Equal(0))
+}
+
+type SlowCollector struct {
+	TestCollector
+	// Number of tests.
+	tests int
+	// Closed to complete the tests.
+	blocked chan struct{}
+}
+
+func (r *SlowCollector) Test() error {
+	r.mutex.Lock()
+	r.tests++
+	r.mutex.Unlock()
+	<-r.blocked
+	return nil
+}
+
+func (r *SlowCollector) tested() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.tests
+}
+
+func TestConnectionSlow(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	c := New()
+	c.StatusPoll = 10 * time.Millisecond
+	collector := &SlowCollector{blocked: make(chan struct{})}
+	owner := collector.Owner()
+	err := c.Add(collector)
+	g.Expect(err).To(gomega.BeNil())
+	s := waitState(c, owner, Ready)
+	g.Expect(s.State).To(gomega.Equal(Ready))
+	time.Sleep(100 * time.Millisecond)
+	// Not repeated while in progress.
+	g.Expect(collector.tested()).To(gomega.Equal(1))
+	// Monitor not blocked by the test.
+	collector.fail(errors.New("failed"))
+	for i := 0; i < 200; i++ {
+		s, _ = c.StatusOf(owner)
+		if s.State != Ready {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	g.Expect(s.State).ToNot(gomega.Equal(Ready))
+	close(collector.blocked)
+	s = waitState(c, owner, Ready)
+	g.Expect(s.State).To(gomega.Equal(Ready))
+	c.Delete(owner)
+}
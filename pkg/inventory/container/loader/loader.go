@@ -0,0 +1,57 @@
+package loader
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/container"
+	"github.com/konveyor/controller/pkg/logging"
+	"plugin"
+)
+
+//
+// Logger.
+var log = logging.WithName(logging.Container)
+
+//
+// Plugin symbol (Go plugin).
+// The registration function exported by a plugin.
+// Must be: func(*container.PluginRegistry) error.
+const PluginSymbol = "Register"
+
+//
+// Load a (Go) plugin.
+// The plugin (shared object) is opened and the exported
+// registration function (see: PluginSymbol) is called with
+// the registry. The plugin must be built with the same
+// version of Go and of the container package.
+func Load(registry *container.PluginRegistry, path string) (err error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		err = liberr.Wrap(err, "path", path)
+		return
+	}
+	symbol, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		err = liberr.Wrap(err, "path", path)
+		return
+	}
+	register, cast := symbol.(func(*container.PluginRegistry) error)
+	if !cast {
+		err = liberr.New(
+			"plugin: register function not valid.",
+			"path",
+			path)
+		return
+	}
+	err = register(registry)
+	if err != nil {
+		err = liberr.Wrap(err, "path", path)
+		return
+	}
+
+	log.V(3).Info(
+		"plugin loaded.",
+		"path",
+		path)
+
+	return
+}
@@ -0,0 +1,16 @@
+package loader
+
+import (
+	"github.com/konveyor/controller/pkg/inventory/container"
+	"github.com/onsi/gomega"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	registry := &container.PluginRegistry{}
+	// Not found.
+	err := Load(registry, "/tmp/not-found.so")
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(registry.Providers()).To(gomega.BeEmpty())
+}
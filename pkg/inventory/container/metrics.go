@@ -0,0 +1,150 @@
+package container
+
+import (
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// Reconcile result labels.
+const (
+	ReconcileSucceeded = "succeeded"
+	ReconcileFailed    = "failed"
+	// Deprecated: Use ReconcileSucceeded.
+	Succeeded = ReconcileSucceeded
+	// Deprecated: Use ReconcileFailed.
+	Failed = ReconcileFailed
+)
+
+//
+// Reconcile (collection) metrics.
+// Registered with the (web) metrics registry.
+var (
+	// Collection reconciles by result.
+	ReconcileCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_reconcile_total",
+			Help: "Number of collection reconciles.",
+		},
+		[]string{"result"})
+	// Reconciled models by kind and action.
+	ReconciledCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_reconciled_models_total",
+			Help: "Number of models added, updated and deleted by reconcile.",
+		},
+		[]string{"kind", "action"})
+	// Stalled transaction operations by kind and operation.
+	StalledCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_stalled_operations_total",
+			Help: "Number of transaction operations stalled during reconcile.",
+		},
+		[]string{"kind", "op"})
+)
+
+//
+// Count a reconciled model.
+func reconciled(m model.Model, action string) {
+	kind := reflect.TypeOf(m)
+	if kind.Kind() == reflect.Ptr {
+		kind = kind.Elem()
+	}
+
+	ReconciledCounter.WithLabelValues(kind.Name(), action).Inc()
+}
+
+//
+// Reconcile statistics (collection).
+type CollectionStats struct {
+	// Collector name.
+	Collector string `json:"collector"`
+	// Collection (model) kind.
+	Kind string `json:"kind"`
+	// Number of reconciles.
+	Count int `json:"count"`
+	// Number of failed reconciles.
+	Failed int `json:"failed"`
+	// Last reconciled.
+	Last time.Time `json:"last"`
+	// Duration of the last reconcile.
+	Duration string `json:"duration"`
+	// Last error description.
+	Error string `json:"error,omitempty"`
+}
+
+//
+// Reconcile statistics by collector and kind.
+var Reconciles = ReconcileStats{}
+
+//
+// Reconcile statistics.
+type ReconcileStats struct {
+	// Stats by collector and kind.
+	content map[[2]string]*CollectionStats
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Record a reconcile.
+func (r *ReconcileStats) Record(collector, kind string, duration time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[[2]string]*CollectionStats{}
+	}
+	key := [2]string{collector, kind}
+	stats, found := r.content[key]
+	if !found {
+		stats = &CollectionStats{
+			Collector: collector,
+			Kind:      kind,
+		}
+		r.content[key] = stats
+	}
+	stats.Count++
+	stats.Last = time.Now()
+	stats.Duration = duration.String()
+	stats.Error = ""
+	if err != nil {
+		stats.Failed++
+		stats.Error = err.Error()
+	}
+}
+
+//
+// List statistics.
+// Ordered by collector and kind.
+func (r *ReconcileStats) List() (list []CollectionStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list = []CollectionStats{}
+	for _, stats := range r.content {
+		list = append(list, *stats)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Collector != list[j].Collector {
+			return list[i].Collector < list[j].Collector
+		}
+		return list[i].Kind < list[j].Kind
+	})
+
+	return
+}
+
+//
+// Delete statistics for a collector.
+func (r *ReconcileStats) Delete(collector string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key := range r.content {
+		if key[0] == collector {
+			delete(r.content, key)
+		}
+	}
+}
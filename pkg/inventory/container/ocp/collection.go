@@ -2,6 +2,7 @@ package ocp
 
 import (
 	"context"
+	"github.com/konveyor/controller/pkg/inventory/container"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
@@ -16,6 +17,19 @@ type Collection interface {
 	Object() runtime.Object
 	// Initial reconcile.
 	Reconcile(context.Context) error
+	// Parity signal.
+	// Marked when the initial reconcile has completed.
+	Parity() *container.ParitySignal
+}
+
+//
+// Collection (optional) dependencies.
+// Dependents are reconciled after their prerequisites
+// have achieved parity.
+type Dependent interface {
+	// Kubernetes resource objects of the prerequisite
+	// collections.
+	DependsOn() []runtime.Object
 }
 
 //
@@ -23,6 +37,26 @@ type Collection interface {
 type BaseCollection struct {
 	// Associated data collector.
 	Collector *Collector
+	// Parity signal.
+	parity container.ParitySignal
+}
+
+//
+// Parity signal.
+func (r *BaseCollection) Parity() *container.ParitySignal {
+	return &r.parity
+}
+
+//
+// Collection has parity.
+func (r *BaseCollection) HasParity() bool {
+	return r.parity.HasParity()
+}
+
+//
+// Wait for the collection to have parity.
+func (r *BaseCollection) WaitForParity(ctx context.Context) error {
+	return r.parity.Wait(ctx)
 }
 
 //
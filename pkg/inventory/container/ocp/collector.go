@@ -4,9 +4,11 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/container"
 	libmodel "github.com/konveyor/controller/pkg/inventory/model"
 	"github.com/konveyor/controller/pkg/logging"
 	"github.com/konveyor/controller/pkg/ref"
+	"github.com/konveyor/controller/pkg/tracing"
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -18,6 +20,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -37,6 +41,9 @@ type Cluster interface {
 //
 // An OpenShift collector.
 type Collector struct {
+	// Records reconcile outcomes as events
+	// on the owning CR. Optional.
+	Events *container.EventRecorder
 	// The cluster CR.
 	cluster Cluster
 	// DB client.
@@ -47,6 +54,8 @@ type Collector struct {
 	log logr.Logger
 	// Collections
 	collections []Collection
+	// Collection dependencies.
+	graph container.Graph
 	// The k8s manager.
 	manager manager.Manager
 	// A k8s non-cached client.
@@ -61,9 +70,21 @@ type Collector struct {
 	// during collection reconciliation.
 	versionThreshold uint64
 	// The collector has (initial) parity.
-	parity bool
+	parity container.ParitySignal
 	// cancel function.
 	cancel func()
+	// Scheduled (periodic) reconciles.
+	scheduler container.Scheduler
+	// Paused kinds.
+	paused container.PauseSet
+	// Activation (standby).
+	activation container.Activation
+	// Collector context.
+	ctx context.Context
+	// Terminal (start) error.
+	failed error
+	// Protect the failed error.
+	mutex sync.Mutex
 }
 
 //
@@ -115,13 +136,34 @@ func (r *Collector) Client() client.Client {
 //
 // Reset.
 func (r *Collector) Reset() {
-	r.parity = false
+	r.mutex.Lock()
+	r.failed = nil
+	r.mutex.Unlock()
+	r.parity.Reset()
+	for _, collection := range r.collections {
+		collection.Parity().Reset()
+	}
+}
+
+//
+// The (terminal) error.
+// Reported to the container.
+func (r *Collector) Failed() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.failed
 }
 
 //
 // Collector has achieved parity.
 func (r *Collector) HasParity() bool {
-	return r.parity
+	return r.parity.HasParity()
+}
+
+//
+// Wait for the collector to achieve parity.
+func (r *Collector) WaitForParity(ctx context.Context) error {
+	return r.parity.Wait(ctx)
 }
 
 //
@@ -135,16 +177,50 @@ func (r *Collector) UpdateThreshold(m libmodel.Model) {
 	}
 }
 
+//
+// The secret containing the credentials.
+func (r *Collector) Secret() *core.Secret {
+	return r.secret
+}
+
+//
+// Update the credentials.
+// Expected to be restarted (reconnected) by the container.
+func (r *Collector) UpdateCredentials(secret *core.Secret) error {
+	r.secret = secret
+	return nil
+}
+
 // Test connection with credentials.
 func (r *Collector) Test() error {
 	return r.buildClient()
 }
 
+//
+// Schedule periodic (full) reconcile of a collection.
+// The spec is an interval (e.g. @every 1h) or cron expression.
+// Scheduled reconciles are started after initial parity.
+func (r *Collector) Schedule(collection Collection, spec string) error {
+	return r.scheduler.Add(
+		container.Schedule{
+			Name: ref.ToKind(collection.Object()),
+			Spec: spec,
+			Reconcile: func(ctx context.Context) error {
+				return r.reconcile(ctx, collection)
+			},
+		})
+}
+
 //
 // Start the collector.
 func (r *Collector) Start() error {
+	err := r.sortCollections()
+	if err != nil {
+		return err
+	}
 	ctx := context.Background()
 	ctx, r.cancel = context.WithCancel(ctx)
+	r.ctx = ctx
 	for _, collection := range r.collections {
 		collection.Bind(r)
 	}
@@ -156,6 +232,15 @@ func (r *Collector) Start() error {
 				break try
 			default:
 				err := r.start(ctx)
+				if liberr.Terminal(err) {
+					r.log.Error(
+						err,
+						"start failed (terminal).")
+					r.mutex.Lock()
+					r.failed = err
+					r.mutex.Unlock()
+					break try
+				}
 				if err != nil {
 					r.log.V(3).Error(
 						err,
@@ -181,6 +266,7 @@ func (r *Collector) Start() error {
 //   2. Reconcile all of the collections.
 //   3. Mark parity.
 //   4. Start apply events (coroutine).
+//   5. Start scheduled reconciles.
 func (r *Collector) start(ctx context.Context) (err error) {
 	r.versionThreshold = 0
 	r.eventChannel = make(chan ModelEvent, 100)
@@ -206,6 +292,7 @@ func (r *Collector) start(ctx context.Context) (err error) {
 		return
 	}
 	go r.applyEvents()
+	r.scheduler.Start(ctx)
 
 	r.log.V(3).Info(
 		"started.",
@@ -215,12 +302,141 @@ func (r *Collector) start(ctx context.Context) (err error) {
 	return
 }
 
+//
+// Sort collections by dependency.
+// Prerequisites precede dependents.
+func (r *Collector) sortCollections() (err error) {
+	r.graph = container.Graph{}
+	byKind := map[string]Collection{}
+	for _, collection := range r.collections {
+		kind := ref.ToKind(collection.Object())
+		byKind[kind] = collection
+		dependsOn := []string{}
+		if dependent, cast := collection.(Dependent); cast {
+			for _, object := range dependent.DependsOn() {
+				dependsOn = append(dependsOn, ref.ToKind(object))
+			}
+		}
+		r.graph.Add(kind, dependsOn...)
+	}
+	sorted, err := r.graph.Sort()
+	if err != nil {
+		return
+	}
+	r.collections = []Collection{}
+	for _, kind := range sorted {
+		r.collections = append(r.collections, byKind[kind])
+	}
+
+	return
+}
+
+//
+// Pause reconciliation of a kind.
+// The kind is matched with the collection (resource) kind
+// and the model kind. Model events for paused kinds are
+// discarded and collection reconciles are blocked.
+func (r *Collector) Pause(kind string) {
+	r.paused.Pause(kind)
+}
+
+//
+// Resume reconciliation of a kind.
+// Resumed collections (with parity) are reconciled
+// to apply changes discarded while paused.
+func (r *Collector) Resume(kind string) {
+	if !r.paused.Resume(kind) || r.ctx == nil {
+		return
+	}
+	for _, collection := range r.collections {
+		if !collection.Parity().HasParity() {
+			continue
+		}
+		if strings.EqualFold(ref.ToKind(collection.Object()), kind) {
+			collection := collection
+			go func() {
+				err := r.reconcile(r.ctx, collection)
+				if err != nil {
+					r.log.V(4).Error(
+						err, "resume reconcile failed.")
+				}
+			}()
+		}
+	}
+}
+
+//
+// Enter standby.
+// Model events are discarded and reconciles are
+// blocked until activated.
+func (r *Collector) Standby() {
+	r.activation.Standby()
+}
+
+//
+// Activate writer paths.
+func (r *Collector) Activate() {
+	r.activation.Activate()
+}
+
+//
+// Reconcile a collection.
+// Blocked until prerequisites have achieved parity,
+// while in standby and while the kind is paused.
+func (r *Collector) reconcile(ctx context.Context, collection Collection) (err error) {
+	err = r.activation.Wait(ctx)
+	if err != nil {
+		return
+	}
+	err = r.paused.Wait(ctx, ref.ToKind(collection.Object()))
+	if err != nil {
+		return
+	}
+	for _, kind := range r.graph.DependsOn(ref.ToKind(collection.Object())) {
+		for _, prerequisite := range r.collections {
+			if ref.ToKind(prerequisite.Object()) == kind {
+				err = prerequisite.Parity().Wait(ctx)
+				if err != nil {
+					return
+				}
+			}
+		}
+	}
+	ctx, span := tracing.Start(
+		ctx,
+		"collector.reconcile",
+		tracing.Kind,
+		ref.ToKind(collection.Object()))
+	defer tracing.End(span, &err)
+	mark := time.Now()
+	err = collection.Reconcile(ctx)
+	container.Reconciles.Record(
+		r.Name(),
+		ref.ToKind(collection.Object()),
+		time.Since(mark),
+		err)
+	if r.Events != nil {
+		r.Events.Reconciled(
+			ref.ToKind(collection.Object()),
+			time.Since(mark),
+			err)
+	}
+	if err != nil {
+		return
+	}
+
+	collection.Parity().Mark()
+
+	return
+}
+
 //
 // Reconcile collections.
+// Ordered by dependency.
 func (r *Collector) reconcileCollections(ctx context.Context) (err error) {
 	mark := time.Now()
 	for _, collection := range r.collections {
-		err = collection.Reconcile(ctx)
+		err = r.reconcile(ctx, collection)
 		if err != nil {
 			err = liberr.Wrap(
 				err,
@@ -236,7 +452,7 @@ func (r *Collector) reconcileCollections(ctx context.Context) (err error) {
 		"duration",
 		time.Since(mark))
 
-	r.parity = true
+	r.parity.Mark()
 
 	return
 }
@@ -248,6 +464,7 @@ func (r *Collector) reconcileCollections(ctx context.Context) (err error) {
 //   3. Cancel the context.
 func (r *Collector) Shutdown() {
 	r.log.V(3).Info("shutdown.")
+	r.scheduler.Shutdown()
 	r.terminate()
 	if r.cancel != nil {
 		r.cancel()
@@ -389,7 +606,29 @@ type ModelEvent struct {
 //
 // Apply the change to the DB.
 func (r *ModelEvent) Apply(rl *Collector) (err error) {
-	tx, err := rl.db.Begin()
+	if !rl.activation.Active() {
+		rl.log.V(4).Info(
+			"model event discarded (standby).",
+			ref.ToKind(r.model),
+			libmodel.Describe(r.model))
+		return
+	}
+	if rl.paused.Paused(ref.ToKind(r.model)) {
+		rl.log.V(4).Info(
+			"model event discarded (paused).",
+			ref.ToKind(r.model),
+			libmodel.Describe(r.model))
+		return
+	}
+	ctx, span := tracing.Start(
+		context.Background(),
+		"collector.event.apply",
+		tracing.Kind,
+		ref.ToKind(r.model),
+		"action",
+		r.action)
+	defer tracing.End(span, &err)
+	tx, err := rl.db.BeginContext(ctx)
 	if err != nil {
 		return
 	}
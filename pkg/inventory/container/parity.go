@@ -0,0 +1,115 @@
+package container
+
+import (
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sync"
+	"time"
+)
+
+//
+// Parity poll interval.
+// Used to wait for collectors that do not signal parity.
+var ParityPoll = time.Millisecond * 10
+
+//
+// Collector (optional) parity signaling.
+type ParityWaiter interface {
+	// Wait for parity.
+	// Returns an error when the context is done.
+	WaitForParity(ctx context.Context) error
+}
+
+//
+// Parity (initial sync) signal.
+// Marked when the first full reconcile has completed.
+// The zero value is ready to use.
+type ParitySignal struct {
+	// Closed when marked.
+	ch chan struct{}
+	// Marked.
+	marked bool
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Mark parity.
+// Waiters are released.
+func (r *ParitySignal) Mark() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.marked {
+		return
+	}
+	r.marked = true
+	close(r.channel())
+}
+
+//
+// Reset (clear) parity.
+func (r *ParitySignal) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.marked {
+		return
+	}
+	r.marked = false
+	r.ch = make(chan struct{})
+}
+
+//
+// Has parity.
+func (r *ParitySignal) HasParity() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.marked
+}
+
+//
+// Wait for parity.
+// Returns an error when the context is done.
+func (r *ParitySignal) Wait(ctx context.Context) (err error) {
+	r.mutex.Lock()
+	ch := r.channel()
+	r.mutex.Unlock()
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		err = liberr.Wrap(ctx.Err())
+	}
+
+	return
+}
+
+//
+// The channel.
+// Must be called with the mutex held.
+func (r *ParitySignal) channel() chan struct{} {
+	if r.ch == nil {
+		r.ch = make(chan struct{})
+	}
+
+	return r.ch
+}
+
+//
+// Wait for a collector to have parity.
+// The collector signal is used when supported.
+// Otherwise, HasParity() is polled.
+func WaitForParity(ctx context.Context, collector Collector) (err error) {
+	if waiter, cast := collector.(ParityWaiter); cast {
+		err = waiter.WaitForParity(ctx)
+		return
+	}
+	for !collector.HasParity() {
+		select {
+		case <-ctx.Done():
+			err = liberr.Wrap(ctx.Err())
+			return
+		case <-time.After(ParityPoll):
+		}
+	}
+
+	return
+}
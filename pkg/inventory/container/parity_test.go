@@ -0,0 +1,53 @@
+package container
+
+import (
+	"context"
+	"github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestParitySignal(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	signal := ParitySignal{}
+	g.Expect(signal.HasParity()).To(gomega.BeFalse())
+	// Timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := signal.Wait(ctx)
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Marked.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		signal.Mark()
+	}()
+	err = signal.Wait(context.Background())
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(signal.HasParity()).To(gomega.BeTrue())
+	signal.Mark()
+	// Reset.
+	signal.Reset()
+	g.Expect(signal.HasParity()).To(gomega.BeFalse())
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = signal.Wait(ctx)
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestContainerParity(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	c := New()
+	g.Expect(c.HasParity()).To(gomega.BeTrue())
+	collector := &TestCollector{failStart: 1}
+	_ = c.Add(collector)
+	g.Expect(c.HasParity()).To(gomega.BeFalse())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.WaitForParity(ctx)
+	g.Expect(err).ToNot(gomega.BeNil())
+	_ = c.Restart(collector.Owner())
+	err = c.WaitForParity(context.Background())
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(c.HasParity()).To(gomega.BeTrue())
+	c.Delete(collector.Owner())
+}
@@ -0,0 +1,104 @@
+package container
+
+import (
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//
+// Collector (optional) pause/resume of
+// reconciliation by kind.
+type Pausable interface {
+	// Pause reconciliation of a kind.
+	Pause(kind string)
+	// Resume reconciliation of a kind.
+	Resume(kind string)
+}
+
+//
+// Set of paused kinds.
+// Kinds are matched case-insensitive.
+// The zero value is ready to use.
+type PauseSet struct {
+	// Paused kinds.
+	// The channel is closed on resume.
+	kinds map[string]chan struct{}
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Pause a kind.
+func (r *PauseSet) Pause(kind string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.kinds == nil {
+		r.kinds = map[string]chan struct{}{}
+	}
+	kind = strings.ToLower(kind)
+	if _, found := r.kinds[kind]; !found {
+		r.kinds[kind] = make(chan struct{})
+	}
+}
+
+//
+// Resume a kind.
+// Returns: true when the kind was paused.
+func (r *PauseSet) Resume(kind string) (resumed bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	kind = strings.ToLower(kind)
+	if ch, found := r.kinds[kind]; found {
+		delete(r.kinds, kind)
+		close(ch)
+		resumed = true
+	}
+
+	return
+}
+
+//
+// A kind is paused.
+func (r *PauseSet) Paused(kind string) (paused bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_, paused = r.kinds[strings.ToLower(kind)]
+	return
+}
+
+//
+// List paused kinds.
+func (r *PauseSet) List() (list []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list = []string{}
+	for kind := range r.kinds {
+		list = append(list, kind)
+	}
+
+	sort.Strings(list)
+
+	return
+}
+
+//
+// Wait while a kind is paused.
+// Returns an error when the context is done.
+func (r *PauseSet) Wait(ctx context.Context, kind string) (err error) {
+	r.mutex.Lock()
+	ch, found := r.kinds[strings.ToLower(kind)]
+	r.mutex.Unlock()
+	if !found {
+		return
+	}
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		err = liberr.Wrap(ctx.Err())
+	}
+
+	return
+}
@@ -0,0 +1,58 @@
+package container
+
+import (
+	"context"
+	"github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+type PausableCollector struct {
+	TestCollector
+	paused PauseSet
+}
+
+func (r *PausableCollector) Pause(kind string) {
+	r.paused.Pause(kind)
+}
+
+func (r *PausableCollector) Resume(kind string) {
+	r.paused.Resume(kind)
+}
+
+func TestPauseSet(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	paused := PauseSet{}
+	g.Expect(paused.Paused("VM")).To(gomega.BeFalse())
+	g.Expect(paused.Wait(context.Background(), "VM")).To(gomega.BeNil())
+	paused.Pause("VM")
+	g.Expect(paused.Paused("vm")).To(gomega.BeTrue())
+	g.Expect(paused.List()).To(gomega.Equal([]string{"vm"}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	g.Expect(paused.Wait(ctx, "VM")).ToNot(gomega.BeNil())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		paused.Resume("VM")
+	}()
+	g.Expect(paused.Wait(context.Background(), "VM")).To(gomega.BeNil())
+	g.Expect(paused.Paused("VM")).To(gomega.BeFalse())
+	g.Expect(paused.Resume("VM")).To(gomega.BeFalse())
+}
+
+func TestContainerPause(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	c := New()
+	c.Pause("Host")
+	collector := &PausableCollector{}
+	err := c.Add(collector)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(collector.paused.Paused("Host")).To(gomega.BeTrue())
+	c.Pause("VM")
+	g.Expect(collector.paused.Paused("VM")).To(gomega.BeTrue())
+	g.Expect(c.Paused()).To(gomega.Equal([]string{"host", "vm"}))
+	c.Resume("Host")
+	g.Expect(collector.paused.Paused("Host")).To(gomega.BeFalse())
+	g.Expect(c.Paused()).To(gomega.Equal([]string{"vm"}))
+	c.Delete(collector.Owner())
+}
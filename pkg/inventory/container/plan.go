@@ -0,0 +1,526 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/logging"
+	"github.com/konveyor/controller/pkg/ref"
+	"github.com/konveyor/controller/pkg/tracing"
+	"reflect"
+	"sort"
+	"time"
+)
+
+//
+// Reconcile phases (plan).
+const (
+	PhasePlan  = "plan"
+	PhaseApply = "apply"
+)
+
+//
+// Planned operation action.
+type OpAction string
+
+//
+// Operation actions.
+// Ordered as applied.
+const (
+	// Delete the stored model.
+	OpDelete OpAction = "delete"
+	// Retain the (missing) stored model during the grace
+	// period. The miss counter is set.
+	OpRetain OpAction = "retain"
+	// Add the desired model.
+	OpAdd OpAction = "add"
+	// Update the stored model as desired.
+	OpUpdate OpAction = "update"
+)
+
+//
+// Action (apply) order.
+var opOrder = map[OpAction]int{
+	OpDelete: 0,
+	OpRetain: 1,
+	OpAdd:    2,
+	OpUpdate: 3,
+}
+
+//
+// Planned operation.
+type Operation struct {
+	// Action.
+	Action OpAction `json:"action"`
+	// Model kind.
+	Kind string `json:"kind"`
+	// Model PK.
+	PK string `json:"pk"`
+	// Names of the changed fields (update).
+	// Fields ignored by the DefaultShepherd are excluded.
+	Fields []string `json:"fields,omitempty"`
+	// The (planned) miss counter (retain).
+	Missed int64 `json:"missed,omitempty"`
+	// The model.
+	// Desired (add|update); stored (delete|retain).
+	Model model.Model `json:"model"`
+	// The (json) encoded model when unmarshaled.
+	// See: Plan.Decode().
+	encoded json.RawMessage
+}
+
+//
+// Unmarshal the operation.
+// The model (interface) cannot be decoded without the type
+// and is retained (encoded) until decoded by Plan.Decode().
+func (r *Operation) UnmarshalJSON(b []byte) (err error) {
+	type operation Operation
+	decoded := &struct {
+		*operation
+		Model json.RawMessage `json:"model"`
+	}{
+		operation: (*operation)(r),
+	}
+	err = json.Unmarshal(b, decoded)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	r.Model = nil
+	r.encoded = decoded.Model
+
+	return
+}
+
+//
+// Reconcile plan.
+// The operations needed to reconcile the stored collection
+// with the desired. May be inspected, logged, filtered and
+// persisted (JSON) before applied. See: Collection.ApplyPlan().
+type Plan struct {
+	// Reconcile (correlation) ID.
+	ReconcileID string `json:"reconcileID"`
+	// Number of desired models.
+	Desired int `json:"desired"`
+	// Number of (unchanged) models skipped.
+	Skipped int `json:"skipped"`
+	// PKs of duplicate desired models.
+	Duplicated []string `json:"duplicated,omitempty"`
+	// Operations (ordered by action then PK).
+	Operations []Operation `json:"operations"`
+}
+
+//
+// The plan has no operations.
+func (r *Plan) Empty() bool {
+	return len(r.Operations) == 0
+}
+
+//
+// Operations by action.
+func (r *Plan) With(action OpAction) (list []Operation) {
+	for _, op := range r.Operations {
+		if op.Action == action {
+			list = append(list, op)
+		}
+	}
+
+	return
+}
+
+//
+// Decode the (unmarshaled) operation models.
+// The model type is matched to the operation kind.
+// Example:
+//   plan := &container.Plan{}
+//   err := json.Unmarshal(b, plan)
+//   err = plan.Decode(&model.VM{})
+//   result, err := collection.ApplyPlan(ctx, plan, tx)
+func (r *Plan) Decode(kinds ...model.Model) (err error) {
+	types := map[string]reflect.Type{}
+	for _, m := range kinds {
+		types[ref.ToKind(m)] = reflect.TypeOf(m).Elem()
+	}
+	for i := range r.Operations {
+		op := &r.Operations[i]
+		if op.Model != nil || op.encoded == nil {
+			continue
+		}
+		mt, found := types[op.Kind]
+		if !found {
+			err = liberr.New(
+				"operation kind not decoded.",
+				"kind",
+				op.Kind)
+			return
+		}
+		m := reflect.New(mt).Interface().(model.Model)
+		err = json.Unmarshal(op.encoded, m)
+		if err != nil {
+			err = liberr.Wrap(
+				err,
+				"kind",
+				op.Kind,
+				"pk",
+				op.PK)
+			return
+		}
+		op.Model = m
+		op.encoded = nil
+	}
+
+	return
+}
+
+//
+// Filtered (copy) of the plan.
+// Includes the operations for which the function returns true.
+func (r *Plan) Filter(fn func(*Operation) bool) (filtered *Plan) {
+	copied := *r
+	filtered = &copied
+	filtered.Operations = []Operation{}
+	for i := range r.Operations {
+		op := &r.Operations[i]
+		if fn(op) {
+			filtered.Operations = append(filtered.Operations, *op)
+		}
+	}
+
+	return
+}
+
+//
+// Plan the reconcile.
+// The stored collection is compared with the desired using
+// the same validation, duplicate policy, shepherd, grace
+// period and delete limits as Reconcile() but nothing is
+// changed. Desired models are materialized in the plan.
+// Example:
+//   plan, err := collection.Plan(ctx, desired)
+//   for _, op := range plan.Operations {
+//      log.Info("planned", "action", op.Action, "pk", op.PK)
+//   }
+//   result, err := collection.ApplyPlan(ctx, plan, tx)
+func (r *Collection) Plan(ctx context.Context, desired fb.Iterator) (plan *Plan, err error) {
+	id := logging.ReconcileIDOf(ctx)
+	if id == "" {
+		id = logging.NewReconcileID()
+	}
+	ctx, span := tracing.Start(
+		ctx,
+		"collection.plan",
+		tracing.Reconcile,
+		id)
+	defer tracing.End(span, &err)
+	plan, err = r.plan(ctx, newResult(), id, desired)
+	if err != nil {
+		return
+	}
+
+	span.Set(
+		"operations",
+		len(plan.Operations),
+		"skipped",
+		plan.Skipped)
+
+	log.V(3).Info(
+		"collection reconcile planned.",
+		"reconcileID",
+		id,
+		"operations",
+		len(plan.Operations),
+		"skipped",
+		plan.Skipped)
+
+	return
+}
+
+//
+// Build the plan.
+// The dispositions and (unchanged) skipped models
+// are recorded in the result.
+func (r *Collection) plan(ctx context.Context, result *Result, id string, desired fb.Iterator) (plan *Plan, err error) {
+	var mp Dispositions
+	err = r.phase(ctx, result, PhaseDispositions, func() (err error) {
+		mp, err = r.dispositions(ctx, result, desired)
+		return
+	})
+	if err != nil {
+		return
+	}
+	err = r.limit(ctx, mp)
+	if err != nil {
+		return
+	}
+	plan = &Plan{
+		ReconcileID: id,
+		Desired:     result.Desired,
+		Duplicated:  result.Duplicated,
+		Operations:  []Operation{},
+	}
+	shepherd := r.shepherd()
+	for _, dpn := range mp {
+		err = canceled(ctx)
+		if err != nil {
+			plan = nil
+			return
+		}
+		var op *Operation
+		op, err = r.planned(shepherd, dpn)
+		if err != nil {
+			plan = nil
+			return
+		}
+		if op != nil {
+			plan.Operations = append(plan.Operations, *op)
+		} else {
+			result.Skipped++
+		}
+	}
+	plan.Skipped = result.Skipped
+	sort.Slice(plan.Operations, func(i, j int) bool {
+		a := plan.Operations[i]
+		b := plan.Operations[j]
+		if a.Action != b.Action {
+			return opOrder[a.Action] < opOrder[b.Action]
+		}
+		return a.PK < b.PK
+	})
+
+	return
+}
+
+//
+// Apply the plan.
+// Each operation is applied (in order) using the transaction
+// (nil = Collection.Tx). Operations are applied against the
+// current (stored) models so the plan may be re-applied after
+// a transient failure: models already added, updated, deleted
+// or retained are skipped. The garbage hook is called with the
+// deleted models. The caller is expected to commit (or end) the
+// transaction. An unmarshaled plan must be decoded.
+// See: Plan.Decode().
+func (r *Collection) ApplyPlan(ctx context.Context, plan *Plan, tx *model.Tx) (result *Result, err error) {
+	collection := *r
+	if tx != nil {
+		collection.Tx = tx
+	}
+	if plan.ReconcileID != "" && logging.ReconcileIDOf(ctx) == "" {
+		ctx = logging.WithReconcileID(ctx, plan.ReconcileID)
+	}
+	result = newResult()
+	ctx, restore := collection.correlate(ctx)
+	defer restore()
+	result.ReconcileID = logging.ReconcileIDOf(ctx)
+	result.Desired = plan.Desired
+	result.Duplicated = plan.Duplicated
+	result.Skipped = plan.Skipped
+	log := log.ForContext(ctx)
+	defer func() {
+		result.failed(err)
+	}()
+	ctx, span := tracing.Start(
+		ctx,
+		"collection.plan.apply",
+		tracing.Reconcile,
+		result.ReconcileID)
+	defer tracing.End(span, &err)
+	mark := time.Now()
+	err = collection.apply(ctx, result, plan)
+	if err != nil {
+		return
+	}
+
+	result.Durations[PhaseApply] = time.Since(mark)
+
+	log.V(3).Info(
+		"collection plan applied.",
+		"added",
+		result.Added,
+		"updated",
+		result.Updated,
+		"deleted",
+		result.Deleted,
+		"skipped",
+		result.Skipped,
+		"missed",
+		result.Missed)
+
+	return
+}
+
+//
+// Operation action (reconcile) phase.
+var opPhase = map[OpAction]string{
+	OpDelete: PhaseDelete,
+	OpRetain: PhaseDelete,
+	OpAdd:    PhaseAdd,
+	OpUpdate: PhaseUpdate,
+}
+
+//
+// Apply the operations by (reconcile) phase.
+// The garbage hook is called (delete phase) with the
+// deleted models.
+func (r *Collection) apply(ctx context.Context, result *Result, plan *Plan) (err error) {
+	for i := range plan.Operations {
+		op := &plan.Operations[i]
+		if _, found := opPhase[op.Action]; !found {
+			err = liberr.New(
+				"unknown operation action.",
+				"action",
+				op.Action)
+			return
+		}
+		if op.Model == nil {
+			err = liberr.New(
+				"operation model not decoded.",
+				"kind",
+				op.Kind,
+				"pk",
+				op.PK)
+			return
+		}
+	}
+	shepherd := r.shepherd()
+	for _, name := range []string{PhaseDelete, PhaseAdd, PhaseUpdate} {
+		err = r.phase(ctx, result, name, func() (err error) {
+			deleted := []model.Model{}
+			for i := range plan.Operations {
+				op := &plan.Operations[i]
+				if opPhase[op.Action] != name {
+					continue
+				}
+				err = canceled(ctx)
+				if err != nil {
+					return
+				}
+				switch op.Action {
+				case OpAdd, OpUpdate:
+					err = r.upsert(result, shepherd, op.Model)
+				case OpDelete:
+					var m model.Model
+					m, err = r.remove(result, op.Model)
+					if m != nil {
+						deleted = append(deleted, m)
+					}
+				case OpRetain:
+					err = r.retainAt(result, op)
+				}
+				if err != nil {
+					err = liberr.Wrap(
+						err,
+						"action",
+						op.Action,
+						"pk",
+						op.PK)
+					return
+				}
+			}
+			err = r.garbage(deleted)
+			return
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// The planned operation for the disposition.
+// Returns nil when nothing is to be done.
+func (r *Collection) planned(shepherd Shepherd, dpn *Disposition) (op *Operation, err error) {
+	switch {
+	case dpn.desired != nil && dpn.stored == nil:
+		m := dpn.desired.model()
+		op = r.operation(OpAdd, m)
+	case dpn.desired == nil && dpn.stored != nil:
+		m := dpn.stored.model()
+		var expired bool
+		expired, err = r.expired(m)
+		if err != nil {
+			return
+		}
+		if expired {
+			op = r.operation(OpDelete, m)
+			return
+		}
+		var missed *model.Field
+		missed, err = r.missed(m)
+		if err != nil {
+			return
+		}
+		op = r.operation(OpRetain, m)
+		op.Missed = missed.Value.Int() + 1
+	default:
+		stored := dpn.stored.model()
+		desired := dpn.desired.model()
+		var missed *model.Field
+		missed, err = r.missed(stored)
+		if err != nil {
+			return
+		}
+		found := missed == nil || missed.Value.Int() == 0
+		if shepherd.Equals(desired, stored) && found {
+			return
+		}
+		op = r.operation(OpUpdate, desired)
+		op.Fields = changedFields(stored, desired)
+	}
+
+	return
+}
+
+//
+// Build an operation.
+func (r *Collection) operation(action OpAction, m model.Model) *Operation {
+	return &Operation{
+		Action: action,
+		Kind:   ref.ToKind(m),
+		PK:     m.Pk(),
+		Model:  m,
+	}
+}
+
+//
+// Retain the (stored) model as planned.
+// The miss counter is set to the planned value. Skipped when
+// the model is no longer stored or already retained.
+func (r *Collection) retainAt(result *Result, op *Operation) (err error) {
+	current, err := keyOf(op.Model)
+	if err != nil {
+		return
+	}
+	err = r.txOp(OpGet, current, func() error {
+		return r.Tx.Get(current)
+	})
+	if err != nil {
+		if errors.Is(err, model.NotFound) {
+			result.Skipped++
+			err = nil
+		}
+		return
+	}
+	missed, err := r.missed(current)
+	if err != nil {
+		return
+	}
+	if missed == nil || missed.Value.Int() == op.Missed {
+		result.Skipped++
+		return
+	}
+	missed.Value.SetInt(op.Missed)
+	err = r.txOp(OpModify, current, func() error {
+		return r.Tx.Update(current)
+	})
+	if err == nil {
+		result.Missed++
+	}
+
+	return
+}
@@ -0,0 +1,292 @@
+package container
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"sync"
+)
+
+//
+// The DB (fed) by a plugin.
+// The subset of model.DB a plugin needs to read and
+// write its models.
+type PluginDB interface {
+	// Get the specified model.
+	Get(model.Model) error
+	// List models based on the type of slice.
+	List(interface{}, model.ListOptions) error
+	// Count based on the specified model.
+	Count(model.Model, model.Predicate) (int64, error)
+	// Begin a transaction.
+	Begin(...string) (*model.Tx, error)
+	// With transaction.
+	With(fn func(*model.Tx) error, labels ...string) error
+	// Insert a model.
+	Insert(model.Model) error
+	// Update a model.
+	Update(model.Model, ...model.Predicate) error
+	// Delete a model.
+	Delete(model.Model) error
+}
+
+//
+// Collector plugin (environment).
+// Passed to the plugin by Init().
+type PluginContext struct {
+	// The resource that owns the collector.
+	Owner meta.Object
+	// The DB (fed) by the plugin.
+	DB PluginDB
+	// The secret containing the source credentials.
+	Secret *core.Secret
+}
+
+//
+// Collector plugin.
+// A (stable) interface implemented by providers so that
+// new providers may feed the inventory without forking
+// the controller. Plugins are adapted to a Collector (see:
+// PluginCollector) by the registry. Plugins are registered
+// in-process or loaded from a Go plugin (see: loader.Load()).
+// No remote (sidecar) transport is provided.
+type Plugin interface {
+	// Initialize the plugin.
+	// Called before Start() and again when the
+	// credentials have been updated.
+	Init(ctx PluginContext) error
+	// Start the plugin.
+	// Expected to start a goroutine and return quickly.
+	Start() error
+	// Stop the plugin.
+	// Expected to disconnect and return quickly.
+	Stop()
+	// Test connection with credentials.
+	Test() error
+	// The plugin version.
+	Version() string
+}
+
+//
+// Plugin (optional) parity.
+// Plugins not implementing Parity have parity
+// once started.
+type Parity interface {
+	// The plugin has achieved parity.
+	HasParity() bool
+}
+
+//
+// Plugin factory.
+type PluginFactory func() Plugin
+
+//
+// Plugin registry.
+// Plugin factories keyed by provider (type).
+type PluginRegistry struct {
+	// Factories by provider.
+	content map[string]PluginFactory
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Register a plugin factory for a provider.
+func (r *PluginRegistry) Register(provider string, factory PluginFactory) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[string]PluginFactory{}
+	}
+	if _, found := r.content[provider]; found {
+		err = liberr.New(
+			"plugin: duplicate provider.",
+			"provider",
+			provider)
+		return
+	}
+	r.content[provider] = factory
+
+	log.V(3).Info(
+		"plugin registered.",
+		"provider",
+		provider)
+
+	return
+}
+
+//
+// Registered providers (sorted).
+func (r *PluginRegistry) Providers() (list []string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	list = []string{}
+	for provider := range r.content {
+		list = append(list, provider)
+	}
+	sort.Strings(list)
+
+	return
+}
+
+//
+// Build a collector for the provider.
+// The plugin is created and initialized with the context
+// for which the DB is set to the (collector) DB.
+func (r *PluginRegistry) Collector(provider string, db model.DB, ctx PluginContext) (collector *PluginCollector, err error) {
+	r.mutex.RLock()
+	factory, found := r.content[provider]
+	r.mutex.RUnlock()
+	if !found {
+		err = liberr.New(
+			"plugin: provider not registered.",
+			"provider",
+			provider)
+		return
+	}
+	ctx.DB = db
+	p := factory()
+	err = p.Init(ctx)
+	if err != nil {
+		err = liberr.Wrap(err, "provider", provider)
+		return
+	}
+	collector = &PluginCollector{
+		Provider: provider,
+		Plugin:   p,
+		db:       db,
+		ctx:      ctx,
+	}
+
+	return
+}
+
+//
+// The (global) plugin registry.
+var Plugins = &PluginRegistry{}
+
+//
+// Plugin (adapted) collector.
+type PluginCollector struct {
+	// Provider.
+	Provider string
+	// The plugin.
+	Plugin Plugin
+	// DB.
+	db model.DB
+	// Plugin context.
+	ctx PluginContext
+	// Started.
+	started bool
+	// Protect fields.
+	mutex sync.RWMutex
+}
+
+//
+// The name.
+func (r *PluginCollector) Name() string {
+	return r.Provider + "/" + r.ctx.Owner.GetName()
+}
+
+//
+// The resource that owns the collector.
+func (r *PluginCollector) Owner() meta.Object {
+	return r.ctx.Owner
+}
+
+//
+// Start the collector.
+func (r *PluginCollector) Start() (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err = r.Plugin.Start()
+	if err != nil {
+		return
+	}
+	r.started = true
+	return
+}
+
+//
+// Shutdown the collector.
+func (r *PluginCollector) Shutdown() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Plugin.Stop()
+	r.started = false
+}
+
+//
+// The collector has achieved parity.
+func (r *PluginCollector) HasParity() bool {
+	if parity, cast := r.Plugin.(Parity); cast {
+		return parity.HasParity()
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.started
+}
+
+//
+// Get the associated DB.
+func (r *PluginCollector) DB() model.DB {
+	return r.db
+}
+
+//
+// Test connection with credentials.
+func (r *PluginCollector) Test() error {
+	return r.Plugin.Test()
+}
+
+//
+// Reset.
+func (r *PluginCollector) Reset() {
+}
+
+//
+// The plugin version.
+func (r *PluginCollector) Version() string {
+	return r.Plugin.Version()
+}
+
+//
+// The secret containing the source credentials.
+func (r *PluginCollector) Secret() *core.Secret {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.ctx.Secret
+}
+
+//
+// Update the source credentials.
+// The plugin is initialized with the updated secret. A
+// started plugin is stopped before (re)initialized and
+// started after.
+func (r *PluginCollector) UpdateCredentials(secret *core.Secret) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	started := r.started
+	if started {
+		r.Plugin.Stop()
+		r.started = false
+	}
+	ctx := r.ctx
+	ctx.Secret = secret
+	err = r.Plugin.Init(ctx)
+	if err == nil {
+		r.ctx = ctx
+	}
+	if started {
+		sErr := r.Plugin.Start()
+		if sErr == nil {
+			r.started = true
+		} else if err == nil {
+			err = sErr
+		}
+	}
+
+	return
+}
@@ -0,0 +1,95 @@
+package container
+
+import (
+	"github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+type TestPlugin struct {
+	ctx     PluginContext
+	inits   int
+	stops   int
+	running bool
+}
+
+func (r *TestPlugin) Init(ctx PluginContext) error {
+	r.ctx = ctx
+	r.inits++
+	return nil
+}
+
+func (r *TestPlugin) Start() error {
+	r.running = true
+	return nil
+}
+
+func (r *TestPlugin) Stop() {
+	r.running = false
+	r.stops++
+}
+
+func (r *TestPlugin) Test() error {
+	return nil
+}
+
+func (r *TestPlugin) Version() string {
+	return "1.0"
+}
+
+func TestPlugins(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	registry := &PluginRegistry{}
+	p := &TestPlugin{}
+	err := registry.Register("test", func() Plugin { return p })
+	g.Expect(err).To(gomega.BeNil())
+	err = registry.Register("test", func() Plugin { return p })
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(registry.Providers()).To(gomega.Equal([]string{"test"}))
+	// Not registered.
+	_, err = registry.Collector("other", nil, PluginContext{})
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Collector.
+	owner := &meta.ObjectMeta{Name: "plugin", UID: "PLUGIN"}
+	collector, err := registry.Collector(
+		"test",
+		nil,
+		PluginContext{
+			Owner: owner,
+			Secret: &core.Secret{
+				ObjectMeta: meta.ObjectMeta{
+					Namespace: "test",
+					Name:      "a",
+				},
+			},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(p.inits).To(gomega.Equal(1))
+	g.Expect(collector.Name()).To(gomega.Equal("test/plugin"))
+	g.Expect(collector.Version()).To(gomega.Equal("1.0"))
+	c := New()
+	err = c.Add(collector)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(p.running).To(gomega.BeTrue())
+	g.Expect(c.HasParity()).To(gomega.BeTrue())
+	// Credentials.
+	refreshed, err := c.RefreshCredentials(
+		&core.Secret{
+			ObjectMeta: meta.ObjectMeta{
+				Namespace:       "test",
+				Name:            "a",
+				ResourceVersion: "2",
+			},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(refreshed)).To(gomega.Equal(1))
+	g.Expect(p.inits).To(gomega.Equal(2))
+	g.Expect(p.ctx.Secret.ResourceVersion).To(gomega.Equal("2"))
+	g.Expect(p.running).To(gomega.BeTrue())
+	g.Expect(p.stops).To(gomega.Equal(1))
+	// Stopped.
+	c.Delete(owner)
+	g.Expect(p.running).To(gomega.BeFalse())
+	g.Expect(collector.HasParity()).To(gomega.BeFalse())
+}
@@ -0,0 +1,268 @@
+package container
+
+import (
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sync"
+	"time"
+)
+
+//
+// Work item priority.
+type Priority int
+
+const (
+	Low Priority = iota
+	Normal
+	High
+)
+
+//
+// The priority within range (Low-High).
+func (p Priority) clamped() Priority {
+	switch {
+	case p < Low:
+		return Low
+	case p > High:
+		return High
+	default:
+		return p
+	}
+}
+
+//
+// Queued work item.
+type Item struct {
+	// Key (deduplicated).
+	Key string
+	// Priority.
+	Priority Priority
+}
+
+//
+// Work queue.
+// Keyed items are deduplicated: an item added while queued
+// is queued once (at the highest priority) and an item added
+// while processing is re-queued when done. Items are dequeued
+// by priority lane (high first) then FIFO. Failed items may be
+// re-queued with a per-key backoff (rate limit).
+type WorkQueue struct {
+	// Per-key (failure) backoff.
+	Backoff BackoffPolicy
+	// Priority lanes (FIFO).
+	lanes [High + 1][]string
+	// Queued items (priority) by key.
+	queued map[string]Priority
+	// Keys being processed.
+	processing map[string]bool
+	// Keys added while processing.
+	dirty map[string]Priority
+	// Consecutive failures by key.
+	failures map[string]int
+	// Shutdown.
+	shutdown bool
+	// Signal items added.
+	cond *sync.Cond
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// New work queue.
+func NewWorkQueue() (q *WorkQueue) {
+	q = &WorkQueue{
+		Backoff:    DefaultBackoff,
+		queued:     map[string]Priority{},
+		processing: map[string]bool{},
+		dirty:      map[string]Priority{},
+		failures:   map[string]int{},
+	}
+	q.cond = sync.NewCond(&q.mutex)
+	return
+}
+
+//
+// Add an item.
+// The priority is clamped to the range Low-High.
+func (q *WorkQueue) Add(key string, priority Priority) {
+	priority = priority.clamped()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.shutdown {
+		return
+	}
+	if q.processing[key] {
+		if p, found := q.dirty[key]; !found || priority > p {
+			q.dirty[key] = priority
+		}
+		return
+	}
+	if p, found := q.queued[key]; found {
+		if priority <= p {
+			return
+		}
+		q.remove(key, p)
+	}
+
+	q.push(key, priority)
+}
+
+//
+// Add an item after the delay.
+func (q *WorkQueue) AddAfter(key string, priority Priority, delay time.Duration) {
+	if delay <= 0 {
+		q.Add(key, priority)
+		return
+	}
+	time.AfterFunc(
+		delay,
+		func() {
+			q.Add(key, priority)
+		})
+}
+
+//
+// Add an item (rate limited).
+// Delayed using the backoff for consecutive failures.
+func (q *WorkQueue) AddRateLimited(key string, priority Priority) {
+	q.mutex.Lock()
+	attempt := q.failures[key]
+	q.failures[key]++
+	q.mutex.Unlock()
+	q.AddAfter(key, priority, q.Backoff.Delay(attempt))
+}
+
+//
+// Forget (reset) the failures for a key.
+func (q *WorkQueue) Forget(key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	delete(q.failures, key)
+}
+
+//
+// Get the next item.
+// Blocks until an item is queued or shutdown.
+// Done() must be called when processing has completed.
+func (q *WorkQueue) Get() (item Item, shutdown bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for q.len() == 0 && !q.shutdown {
+		q.cond.Wait()
+	}
+	if q.len() == 0 {
+		shutdown = true
+		return
+	}
+	for p := High; p >= Low; p-- {
+		lane := q.lanes[p]
+		if len(lane) == 0 {
+			continue
+		}
+		item = Item{Key: lane[0], Priority: p}
+		q.lanes[p] = lane[1:]
+		break
+	}
+	delete(q.queued, item.Key)
+	q.processing[item.Key] = true
+
+	return
+}
+
+//
+// Done processing an item.
+// Items added while processing are re-queued.
+func (q *WorkQueue) Done(key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	delete(q.processing, key)
+	if p, found := q.dirty[key]; found {
+		delete(q.dirty, key)
+		if !q.shutdown {
+			q.push(key, p)
+		}
+	}
+}
+
+//
+// Number of queued items.
+func (q *WorkQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.len()
+}
+
+//
+// Shutdown the queue.
+// Blocked Get() calls return shutdown.
+func (q *WorkQueue) Shutdown() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.shutdown = true
+	q.cond.Broadcast()
+}
+
+//
+// Process items using workers until the context is done.
+// Failed items are re-queued (rate limited) unless the
+// error is terminal.
+func (q *WorkQueue) Run(ctx context.Context, workers int, fn func(Item) error) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, shutdown := q.Get()
+				if shutdown {
+					return
+				}
+				err := fn(item)
+				q.Done(item.Key)
+				if liberr.Retryable(err) {
+					log.Trace(err, "key", item.Key)
+					q.AddRateLimited(item.Key, item.Priority)
+					continue
+				}
+				if err != nil {
+					log.Trace(err, "key", item.Key)
+				}
+				q.Forget(item.Key)
+			}
+		}()
+	}
+	<-ctx.Done()
+	q.Shutdown()
+	wg.Wait()
+}
+
+//
+// Push an item.
+// Must be called with the mutex held.
+func (q *WorkQueue) push(key string, priority Priority) {
+	q.queued[key] = priority
+	q.lanes[priority] = append(q.lanes[priority], key)
+	q.cond.Signal()
+}
+
+//
+// Remove a queued item from its lane.
+// Must be called with the mutex held.
+func (q *WorkQueue) remove(key string, priority Priority) {
+	lane := q.lanes[priority]
+	for i := range lane {
+		if lane[i] == key {
+			q.lanes[priority] = append(lane[:i:i], lane[i+1:]...)
+			break
+		}
+	}
+
+	delete(q.queued, key)
+}
+
+//
+// Number of queued items.
+// Must be called with the mutex held.
+func (q *WorkQueue) len() int {
+	return len(q.queued)
+}
@@ -0,0 +1,96 @@
+package container
+
+import (
+	"context"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/onsi/gomega"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkQueue(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	q := NewWorkQueue()
+	// Dedup and priority.
+	q.Add("a", Low)
+	q.Add("b", Normal)
+	q.Add("a", Low)
+	q.Add("c", Low)
+	q.Add("c", High)
+	g.Expect(q.Len()).To(gomega.Equal(3))
+	item, _ := q.Get()
+	g.Expect(item).To(gomega.Equal(Item{Key: "c", Priority: High}))
+	item, _ = q.Get()
+	g.Expect(item.Key).To(gomega.Equal("b"))
+	// Added while processing.
+	q.Add("b", Normal)
+	g.Expect(q.Len()).To(gomega.Equal(1))
+	q.Done("b")
+	g.Expect(q.Len()).To(gomega.Equal(2))
+	q.Done("c")
+	g.Expect(q.Len()).To(gomega.Equal(2))
+	// Delayed.
+	q.AddAfter("d", High, 20*time.Millisecond)
+	g.Expect(q.Len()).To(gomega.Equal(2))
+	g.Eventually(q.Len).Should(gomega.Equal(3))
+	// Clamped.
+	q.Add("e", High+1)
+	q.Add("f", Low-1)
+	g.Expect(q.Len()).To(gomega.Equal(5))
+	// Shutdown.
+	q.Shutdown()
+	n := 0
+	for {
+		_, shutdown := q.Get()
+		if shutdown {
+			break
+		}
+		n++
+	}
+	g.Expect(n).To(gomega.Equal(5))
+}
+
+func TestWorkQueueRun(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	q := NewWorkQueue()
+	q.Backoff = BackoffPolicy{
+		Min:    time.Millisecond,
+		Max:    time.Millisecond * 10,
+		Factor: 2,
+	}
+	mutex := sync.Mutex{}
+	calls := map[string]int{}
+	count := func(key string) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return calls[key]
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx, 2, func(item Item) (err error) {
+			mutex.Lock()
+			calls[item.Key]++
+			n := calls[item.Key]
+			mutex.Unlock()
+			switch item.Key {
+			case "retry":
+				if n < 3 {
+					err = errors.New("transient")
+				}
+			case "terminal":
+				err = liberr.WrapTerminal(errors.New("denied"))
+			}
+			return
+		})
+		close(done)
+	}()
+	q.Add("retry", Normal)
+	q.Add("terminal", Normal)
+	g.Eventually(func() int { return count("retry") }).Should(gomega.Equal(3))
+	g.Consistently(func() int { return count("terminal") }, "50ms").Should(gomega.Equal(1))
+	cancel()
+	<-done
+}
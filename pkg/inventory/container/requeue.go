@@ -0,0 +1,165 @@
+package container
+
+import (
+	"github.com/konveyor/controller/pkg/inventory/model"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+)
+
+//
+// Requeue (bridge) handler.
+// Model watch event handler that converts model events into
+// reconcile requests for the owning CR. The owner is read from
+// the model field (column) named by `Field` which may be:
+//   - string: "namespace/name" or "name".
+//   - core.ObjectReference.
+//   - types.NamespacedName.
+// Requests are added to the queue and/or sent to the channel
+// (as generic events) for use with source.Channel. Example:
+//   w, err := db.Watch(
+//      &Model{},
+//      &container.RequeueHandler{
+//         Field: "Owner",
+//         Queue: queue,
+//      })
+type RequeueHandler struct {
+	model.StockEventHandler
+	// Owner reference field name.
+	Field string
+	// Namespace used when the reference has no namespace.
+	Namespace string
+	// Watch options.
+	Watch model.WatchOptions
+	// Requests are added to the queue (optional).
+	Queue workqueue.Interface
+	// Generic events are sent to the channel (optional).
+	// The send blocks the watch until received.
+	Channel chan<- event.GenericEvent
+}
+
+//
+// Watch options.
+func (r *RequeueHandler) Options() model.WatchOptions {
+	return r.Watch
+}
+
+//
+// A model has been created.
+func (r *RequeueHandler) Created(event model.Event) {
+	r.requeue(event.Model)
+}
+
+//
+// A model has been updated.
+// Both the prior and updated owners are requeued
+// when the reference has changed.
+func (r *RequeueHandler) Updated(event model.Event) {
+	r.requeue(event.Model, event.Updated)
+}
+
+//
+// A model has been deleted.
+func (r *RequeueHandler) Deleted(event model.Event) {
+	r.requeue(event.Model)
+}
+
+//
+// An error has occurred delivering an event.
+func (r *RequeueHandler) Error(err error) {
+	log.Trace(err)
+}
+
+//
+// Get the reconcile requests for the models.
+// Models without an owner reference are ignored and
+// duplicate requests are omitted.
+func (r *RequeueHandler) Requests(models ...model.Model) (list []reconcile.Request) {
+	found := map[types.NamespacedName]bool{}
+	for _, m := range models {
+		if m == nil || reflect.ValueOf(m).IsNil() {
+			continue
+		}
+		name, ok := r.owner(m)
+		if !ok || found[name] {
+			continue
+		}
+		found[name] = true
+		list = append(
+			list,
+			reconcile.Request{
+				NamespacedName: name,
+			})
+	}
+
+	return
+}
+
+//
+// Requeue the owners of the models.
+func (r *RequeueHandler) requeue(models ...model.Model) {
+	for _, request := range r.Requests(models...) {
+		if r.Queue != nil {
+			r.Queue.Add(request)
+		}
+		if r.Channel != nil {
+			r.Channel <- event.GenericEvent{
+				Meta: &meta.ObjectMeta{
+					Namespace: request.Namespace,
+					Name:      request.Name,
+				},
+			}
+		}
+		log.V(4).Info(
+			"requeue: owner requeued.",
+			"field",
+			r.Field,
+			"owner",
+			request.NamespacedName)
+	}
+}
+
+//
+// Get the owner referenced by the model field.
+func (r *RequeueHandler) owner(m model.Model) (name types.NamespacedName, found bool) {
+	md, err := model.Inspect(m)
+	if err != nil {
+		log.Trace(err)
+		return
+	}
+	f := md.Field(r.Field)
+	if f == nil {
+		return
+	}
+	value := reflect.Indirect(*f.Value)
+	if !value.IsValid() {
+		return
+	}
+	switch ref := value.Interface().(type) {
+	case string:
+		part := strings.SplitN(ref, "/", 2)
+		if len(part) == 2 {
+			name.Namespace = part[0]
+			name.Name = part[1]
+		} else {
+			name.Name = part[0]
+		}
+	case core.ObjectReference:
+		name.Namespace = ref.Namespace
+		name.Name = ref.Name
+	case types.NamespacedName:
+		name = ref
+	}
+	if name.Namespace == "" {
+		name.Namespace = r.Namespace
+	}
+
+	found = name.Name != ""
+
+	return
+}
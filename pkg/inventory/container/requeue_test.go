@@ -0,0 +1,134 @@
+package container
+
+import (
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strconv"
+	"testing"
+)
+
+type OwnedObject struct {
+	ID    int                  `sql:"pk"`
+	Owner string               `sql:""`
+	Ref   core.ObjectReference `sql:""`
+}
+
+func (m *OwnedObject) Pk() string {
+	return strconv.Itoa(m.ID)
+}
+
+func (m *OwnedObject) String() string {
+	return m.Pk()
+}
+
+func (m *OwnedObject) Labels() model.Labels {
+	return nil
+}
+
+func TestRequeueHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	request := func(ns, name string) reconcile.Request {
+		return reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: ns,
+				Name:      name,
+			},
+		}
+	}
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	channel := make(chan event.GenericEvent, 10)
+	handler := &RequeueHandler{
+		Field:     "Owner",
+		Namespace: "default",
+		Queue:     queue,
+		Channel:   channel,
+	}
+	// requests.
+	list := handler.Requests(
+		&OwnedObject{ID: 1, Owner: "ns1/a"},
+		&OwnedObject{ID: 2, Owner: "b"},
+		&OwnedObject{ID: 3, Owner: "ns1/a"},
+		&OwnedObject{ID: 4})
+	g.Expect(list).To(gomega.Equal(
+		[]reconcile.Request{
+			request("ns1", "a"),
+			request("default", "b"),
+		}))
+	// created.
+	handler.Created(model.Event{Model: &OwnedObject{Owner: "ns1/a"}})
+	g.Expect(queue.Len()).To(gomega.Equal(1))
+	item, _ := queue.Get()
+	g.Expect(item).To(gomega.Equal(request("ns1", "a")))
+	queue.Done(item)
+	generic := <-channel
+	g.Expect(generic.Meta.GetNamespace()).To(gomega.Equal("ns1"))
+	g.Expect(generic.Meta.GetName()).To(gomega.Equal("a"))
+	// updated (owner changed).
+	handler.Updated(
+		model.Event{
+			Model:   &OwnedObject{Owner: "ns1/a"},
+			Updated: &OwnedObject{Owner: "ns2/b"},
+		})
+	g.Expect(queue.Len()).To(gomega.Equal(2))
+	g.Expect(len(channel)).To(gomega.Equal(2))
+	for queue.Len() > 0 {
+		item, _ = queue.Get()
+		queue.Done(item)
+		<-channel
+	}
+	// deleted (no owner).
+	handler.Deleted(model.Event{Model: &OwnedObject{}})
+	g.Expect(queue.Len()).To(gomega.Equal(0))
+	g.Expect(len(channel)).To(gomega.Equal(0))
+	// object reference.
+	handler.Field = "Ref"
+	list = handler.Requests(
+		&OwnedObject{
+			Ref: core.ObjectReference{
+				Namespace: "ns3",
+				Name:      "c",
+			},
+		},
+		&OwnedObject{})
+	g.Expect(list).To(gomega.Equal([]reconcile.Request{request("ns3", "c")}))
+	// unknown field.
+	handler.Field = "Unknown"
+	g.Expect(handler.Requests(&OwnedObject{Owner: "a"})).To(gomega.BeEmpty())
+}
+
+func TestRequeueWatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := &Harness{
+		Models: []interface{}{
+			&OwnedObject{},
+		},
+	}
+	err := h.Setup()
+	g.Expect(err).To(gomega.BeNil())
+	defer h.Teardown()
+	c, err := h.Collector("a")
+	g.Expect(err).To(gomega.BeNil())
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	w, err := c.DB().Watch(
+		&OwnedObject{},
+		&RequeueHandler{
+			Field: "Owner",
+			Queue: queue,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	defer c.DB().EndWatch(w)
+	c.Set(
+		&OwnedObject{ID: 1, Owner: "ns/a"},
+		&OwnedObject{ID: 2, Owner: "ns/b"},
+		&OwnedObject{ID: 3, Owner: "ns/a"})
+	_, err = c.Reconcile()
+	g.Expect(err).To(gomega.BeNil())
+	g.Eventually(queue.Len).Should(gomega.Equal(2))
+}
@@ -0,0 +1,181 @@
+package container
+
+import (
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/robfig/cron/v3"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Interval spec prefix.
+const EveryPrefix = "@every "
+
+//
+// Scheduled reconcile.
+type Schedule struct {
+	// Name (used for logging).
+	Name string
+	// Schedule spec.
+	// Either an interval: `@every <duration>` (e.g. @every 30m),
+	// a descriptor (e.g. @hourly) or a 5-field cron
+	// expression: <minute> <hour> <day> <month> <weekday>.
+	Spec string
+	// Reconcile (full or scoped).
+	Reconcile func(context.Context) error
+}
+
+//
+// Scheduler.
+// Triggers reconciles on a schedule, independent of
+// event-driven updates, to self-heal drift caused by
+// missed events. Reconciles for a schedule do not overlap.
+type Scheduler struct {
+	// Scheduled entries.
+	entries []*scheduled
+	// Context (started).
+	ctx context.Context
+	// Cancel function.
+	cancel func()
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Add a schedule.
+// Schedules added after Start() are started immediately
+// and canceled by Shutdown() or the Start() context.
+func (r *Scheduler) Add(schedule Schedule) (err error) {
+	next, err := ParseSchedule(schedule.Spec)
+	if err != nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry := &scheduled{
+		Schedule: schedule,
+		next:     next,
+	}
+	r.entries = append(r.entries, entry)
+	if r.cancel != nil {
+		ctx, cancel := context.WithCancel(r.ctx)
+		entry.cancel = cancel
+		go entry.run(ctx)
+	}
+
+	return
+}
+
+//
+// Start the scheduler.
+func (r *Scheduler) Start(ctx context.Context) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.cancel != nil {
+		return
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	for _, entry := range r.entries {
+		go entry.run(r.ctx)
+	}
+}
+
+//
+// Shutdown the scheduler.
+func (r *Scheduler) Shutdown() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.cancel = nil
+	r.ctx = nil
+	for _, entry := range r.entries {
+		if entry.cancel != nil {
+			entry.cancel()
+			entry.cancel = nil
+		}
+	}
+}
+
+//
+// Scheduled entry.
+type scheduled struct {
+	Schedule
+	// Next time function.
+	next NextFunc
+	// Cancel function (added after start).
+	cancel func()
+}
+
+//
+// Run the schedule.
+func (r *scheduled) run(ctx context.Context) {
+	for {
+		now := time.Now()
+		next := r.next(now)
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		mark := time.Now()
+		err := r.Reconcile(ctx)
+		if err != nil {
+			log.Trace(
+				err,
+				"schedule",
+				r.Name)
+			continue
+		}
+
+		log.V(3).Info(
+			"scheduled reconcile succeeded.",
+			"schedule",
+			r.Name,
+			"duration",
+			time.Since(mark))
+	}
+}
+
+//
+// Computes the next time after the specified time.
+// Returns the zero time when none.
+type NextFunc func(time.Time) time.Time
+
+//
+// Parse a schedule spec.
+// Cron expressions and descriptors are parsed by the
+// (standard) robfig/cron parser.
+func ParseSchedule(spec string) (next NextFunc, err error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, EveryPrefix) {
+		d, pErr := time.ParseDuration(
+			strings.TrimSpace(strings.TrimPrefix(spec, EveryPrefix)))
+		if pErr != nil || d <= 0 {
+			err = liberr.New("interval not valid.", "spec", spec)
+			return
+		}
+		next = func(t time.Time) time.Time {
+			return t.Add(d)
+		}
+		return
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		err = liberr.Wrap(err, "spec", spec)
+		return
+	}
+
+	next = schedule.Next
+
+	return
+}
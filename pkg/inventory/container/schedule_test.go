@@ -0,0 +1,105 @@
+package container
+
+import (
+	"context"
+	"github.com/onsi/gomega"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	at := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02 15:04", s)
+		return t
+	}
+	now := at("2021-03-15 10:07")
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"@every 90s", now.Add(90 * time.Second)},
+		{"* * * * *", at("2021-03-15 10:08")},
+		{"*/15 * * * *", at("2021-03-15 10:15")},
+		{"0 * * * *", at("2021-03-15 11:00")},
+		{"@hourly", at("2021-03-15 11:00")},
+		{"30 2 * * *", at("2021-03-16 02:30")},
+		{"0 0 1 * *", at("2021-04-01 00:00")},
+		{"0 9 * * 1-5", at("2021-03-16 09:00")},
+		{"0 9 * * 6,0", at("2021-03-20 09:00")},
+		{"0 0 29 2 *", at("2024-02-29 00:00")},
+		{"5-10/5 10 15 3 *", at("2021-03-15 10:10")},
+	}
+	for _, c := range cases {
+		next, err := ParseSchedule(c.spec)
+		g.Expect(err).To(gomega.BeNil(), c.spec)
+		g.Expect(next(now)).To(gomega.Equal(c.next), c.spec)
+	}
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every x",
+		"@every -1s",
+	} {
+		_, err := ParseSchedule(spec)
+		g.Expect(err).ToNot(gomega.BeNil(), spec)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mutex := sync.Mutex{}
+	count := 0
+	scheduler := Scheduler{}
+	err := scheduler.Add(
+		Schedule{
+			Name: "test",
+			Spec: "@every 10ms",
+			Reconcile: func(ctx context.Context) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				count++
+				return nil
+			},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	scheduler.Start(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	scheduler.Shutdown()
+	mutex.Lock()
+	n := count
+	mutex.Unlock()
+	g.Expect(n > 3).To(gomega.BeTrue())
+	time.Sleep(50 * time.Millisecond)
+	mutex.Lock()
+	defer mutex.Unlock()
+	g.Expect(count).To(gomega.Equal(n))
+}
+
+func TestSchedulerAdded(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler := Scheduler{}
+	scheduler.Start(ctx)
+	defer scheduler.Shutdown()
+	canceled := make(chan struct{})
+	err := scheduler.Add(
+		Schedule{
+			Name: "added",
+			Spec: "@every 10ms",
+			Reconcile: func(ctx context.Context) error {
+				<-ctx.Done()
+				close(canceled)
+				return ctx.Err()
+			},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	g.Eventually(canceled).Should(gomega.BeClosed())
+}
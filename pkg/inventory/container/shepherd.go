@@ -0,0 +1,165 @@
+package container
+
+import (
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+//
+// Compiled (shepherd) plans by model type.
+var plans sync.Map
+
+//
+// Field comparator.
+type comparator func(a, b reflect.Value) bool
+
+//
+// Compiled field.
+type planField struct {
+	// Index path (reflect.Value.FieldByIndex).
+	path []int
+	// Comparator.
+	equals comparator
+	// Cost rank. Cheap fields are compared first.
+	cost int
+}
+
+//
+// Compiled shepherd plan.
+// The compared (not ignored) fields of a model type
+// ordered by cost.
+type plan struct {
+	fields []planField
+}
+
+//
+// Get the compiled plan for the model type.
+// Compiled once and cached.
+func planOf(m model.Model, ignored func(*model.Field) bool) (p *plan, err error) {
+	mt := reflect.TypeOf(m)
+	if cached, found := plans.Load(mt); found {
+		p = cached.(*plan)
+		return
+	}
+	p, err = compile(m, ignored)
+	if err != nil {
+		return
+	}
+	plans.Store(mt, p)
+
+	return
+}
+
+//
+// Compile the plan.
+// The index path of each field (reported by model.Inspect) is
+// found by matching the address and type of each field.
+func compile(m model.Model, ignored func(*model.Field) bool) (p *plan, err error) {
+	md, err := model.Inspect(m)
+	if err != nil {
+		return
+	}
+	type addr struct {
+		ptr uintptr
+		t   reflect.Type
+	}
+	paths := map[addr][]int{}
+	var walk func(v reflect.Value, prefix []int)
+	walk = func(v reflect.Value, prefix []int) {
+		for i := 0; i < v.NumField(); i++ {
+			fv := v.Field(i)
+			if !fv.CanSet() {
+				continue
+			}
+			path := append(append([]int{}, prefix...), i)
+			paths[addr{fv.UnsafeAddr(), fv.Type()}] = path
+			if fv.Kind() == reflect.Struct {
+				walk(fv, path)
+			}
+		}
+	}
+	walk(reflect.ValueOf(m).Elem(), nil)
+	p = &plan{}
+	for _, f := range md.Fields {
+		if ignored(f) {
+			continue
+		}
+		path, found := paths[addr{f.Value.UnsafeAddr(), f.Value.Type()}]
+		if !found {
+			continue
+		}
+		equals, cost := comparatorOf(f.Value.Kind())
+		p.fields = append(
+			p.fields,
+			planField{
+				path:   path,
+				equals: equals,
+				cost:   cost,
+			})
+	}
+	sort.SliceStable(p.fields, func(i, j int) bool {
+		return p.fields[i].cost < p.fields[j].cost
+	})
+
+	return
+}
+
+//
+// Comparator (and cost) by kind.
+func comparatorOf(kind reflect.Kind) (equals comparator, cost int) {
+	switch kind {
+	case reflect.Bool:
+		cost = 0
+		equals = func(a, b reflect.Value) bool {
+			return a.Bool() == b.Bool()
+		}
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		cost = 0
+		equals = func(a, b reflect.Value) bool {
+			return a.Int() == b.Int()
+		}
+	case reflect.String:
+		cost = 1
+		equals = func(a, b reflect.Value) bool {
+			return a.String() == b.String()
+		}
+	default:
+		cost = 2
+		equals = func(a, b reflect.Value) bool {
+			return reflect.DeepEqual(a.Interface(), b.Interface())
+		}
+	}
+
+	return
+}
+
+//
+// Models are equal.
+// Short-circuits on the first (cheapest) field not equal.
+func (p *plan) equals(mA, mB model.Model) bool {
+	vA := reflect.ValueOf(mA).Elem()
+	vB := reflect.ValueOf(mB).Elem()
+	for _, f := range p.fields {
+		if !f.equals(vA.FieldByIndex(f.path), vB.FieldByIndex(f.path)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Update model A with model B.
+func (p *plan) update(mA, mB model.Model) {
+	vA := reflect.ValueOf(mA).Elem()
+	vB := reflect.ValueOf(mB).Elem()
+	for _, f := range p.fields {
+		vA.FieldByIndex(f.path).Set(vB.FieldByIndex(f.path))
+	}
+}
@@ -0,0 +1,174 @@
+package container
+
+import (
+	"context"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	pathlib "path"
+)
+
+//
+// Collector (optional) dependencies.
+// Used to order the shutdown such that dependents
+// are stopped (and closed) before prerequisites.
+type Dependent interface {
+	// Names of the collectors depended on.
+	DependsOn() []string
+}
+
+//
+// DB (optional) watch event checkpoint.
+// See: model.Client.Checkpoint().
+type Checkpointer interface {
+	// Checkpoint the watch events not delivered.
+	Checkpoint(path string) (int, error)
+}
+
+//
+// Shutdown (drain) the container.
+// Collectors are stopped (dependents first) so that no further
+// changes are made. Then, the queued watch events are delivered
+// (drained) and the DBs closed in the same (dependency) order.
+// When the context is done before the watch events have been
+// delivered, the events not delivered are checkpointed in the
+// Checkpoint directory (when set) so they may be replayed after
+// a restart. See: CheckpointPath(). Last, the backing (filebacked)
+// files owned by the process are released so that stale files
+// are not left behind. The DBs are closed even when the drain
+// or checkpoint fails; the (first) error is returned.
+func (c *Container) Shutdown(ctx context.Context) (err error) {
+	collectors := c.shutdownOrder()
+	stopped := []*lifecycle{}
+	c.mutex.Lock()
+	for _, collector := range collectors {
+		key := c.key(collector.Owner())
+		if lc, found := c.lifecycle[key]; found {
+			stopped = append(stopped, lc)
+		}
+		delete(c.content, key)
+		delete(c.lifecycle, key)
+		Reconciles.Delete(collector.Name())
+		Budgets.Delete(collector.Name())
+	}
+	c.mutex.Unlock()
+	for _, lc := range stopped {
+		lc.stop()
+	}
+	dbs := []model.DB{}
+	names := map[model.DB]string{}
+	for _, collector := range collectors {
+		db := collector.DB()
+		if db == nil {
+			continue
+		}
+		if _, found := names[db]; found {
+			continue
+		}
+		names[db] = collector.Name()
+		dbs = append(dbs, db)
+	}
+	checkpointed := 0
+	for _, db := range dbs {
+		dErr := db.Drain(ctx)
+		if dErr == nil {
+			continue
+		}
+		if err == nil {
+			err = dErr
+		}
+		n, cErr := c.checkpoint(names[db], db)
+		if cErr != nil {
+			log.Trace(cErr)
+		}
+		checkpointed += n
+	}
+	for _, db := range dbs {
+		cErr := db.Close(false)
+		if cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	released := fb.Release()
+
+	log.V(3).Info(
+		"container shutdown.",
+		"collectors",
+		len(collectors),
+		"dbs",
+		len(dbs),
+		"checkpointed",
+		checkpointed,
+		"released",
+		released)
+
+	return
+}
+
+//
+// Path of the (watch event) checkpoint by collector name.
+// Empty when the Checkpoint directory is not set. When the
+// DB is shared by collectors, the checkpoint is named for
+// the first collector in shutdown order.
+// Example:
+//   path := c.CheckpointPath(collector.Name())
+//   if path != "" {
+//       _, err = db.Replay(path, handler)
+//   }
+func (c *Container) CheckpointPath(name string) (path string) {
+	if c.Checkpoint != "" {
+		path = pathlib.Join(c.Checkpoint, name+".json")
+	}
+
+	return
+}
+
+//
+// Checkpoint the watch events not delivered by the DB.
+func (c *Container) checkpoint(name string, db model.DB) (n int, err error) {
+	path := c.CheckpointPath(name)
+	if path == "" {
+		return
+	}
+	checkpointer, cast := db.(Checkpointer)
+	if !cast {
+		return
+	}
+	n, err = checkpointer.Checkpoint(path)
+	return
+}
+
+//
+// Collectors in shutdown order.
+// Dependents precede prerequisites. Dependencies on collectors
+// not in the container are ignored. Not ordered when the
+// dependencies contain a cycle.
+func (c *Container) shutdownOrder() (list []Collector) {
+	collectors := c.List()
+	byName := map[string][]Collector{}
+	graph := Graph{}
+	for _, collector := range collectors {
+		byName[collector.Name()] = append(byName[collector.Name()], collector)
+	}
+	for _, collector := range collectors {
+		dependsOn := []string{}
+		if dependent, cast := collector.(Dependent); cast {
+			for _, name := range dependent.DependsOn() {
+				if _, found := byName[name]; found {
+					dependsOn = append(dependsOn, name)
+				}
+			}
+		}
+		graph.Add(collector.Name(), dependsOn...)
+	}
+	sorted, err := graph.Sort()
+	if err != nil {
+		log.Trace(err)
+		list = collectors
+		return
+	}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		list = append(list, byName[sorted[i]]...)
+	}
+
+	return
+}
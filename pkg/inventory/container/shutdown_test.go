@@ -0,0 +1,191 @@
+package container
+
+import (
+	"context"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/onsi/gomega"
+	"io/ioutil"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"os"
+	pathlib "path"
+	"sync"
+	"testing"
+	"time"
+)
+
+type DependentCollector struct {
+	TestCollector
+	// Name.
+	name string
+	// DB.
+	db model.DB
+	// Dependencies.
+	dependsOn []string
+	// Shutdown (collector names) recorded.
+	shutdown *[]string
+}
+
+func (r *DependentCollector) Name() string {
+	return r.name
+}
+
+func (r *DependentCollector) Owner() meta.Object {
+	return &meta.ObjectMeta{
+		UID: types.UID(r.name),
+	}
+}
+
+func (r *DependentCollector) DB() model.DB {
+	return r.db
+}
+
+func (r *DependentCollector) DependsOn() []string {
+	return r.dependsOn
+}
+
+func (r *DependentCollector) Shutdown() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	*r.shutdown = append(*r.shutdown, r.name)
+}
+
+type SlowHandler struct {
+	model.StockEventHandler
+	// Number of events delivered.
+	created int
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+func (r *SlowHandler) Created(model.Event) {
+	time.Sleep(time.Millisecond)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.created++
+}
+
+func (r *SlowHandler) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.created
+}
+
+func TestShutdown(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	shutdown := []string{}
+	dbA := model.New("/tmp/test-shutdown-a.db", &TestObject2{})
+	g.Expect(dbA.Open(true)).To(gomega.BeNil())
+	defer func() {
+		_ = os.Remove("/tmp/test-shutdown-a.db")
+	}()
+	dbB := model.New("/tmp/test-shutdown-b.db", &TestObject2{})
+	g.Expect(dbB.Open(true)).To(gomega.BeNil())
+	defer func() {
+		_ = os.Remove("/tmp/test-shutdown-b.db")
+	}()
+	c := New()
+	for _, collector := range []*DependentCollector{
+		{name: "b", db: dbB, dependsOn: []string{"a", "missing"}, shutdown: &shutdown},
+		{name: "a", db: dbA, shutdown: &shutdown},
+	} {
+		g.Expect(c.Add(collector)).To(gomega.BeNil())
+	}
+	handler := &SlowHandler{}
+	_, err := dbB.Watch(&TestObject2{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 20; i++ {
+		g.Expect(dbB.Insert(&TestObject2{ID: i})).To(gomega.BeNil())
+	}
+	list := fb.NewList()
+	list.Append(&TestObject2{ID: 1})
+	itr := list.Iter()
+	g.Expect(itr.Len()).To(gomega.Equal(1))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err = c.Shutdown(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	// Dependents first.
+	g.Expect(shutdown).To(gomega.Equal([]string{"b", "a"}))
+	// Queued events delivered.
+	g.Expect(handler.count()).To(gomega.Equal(20))
+	g.Expect(dbB.Watches()).To(gomega.BeEmpty())
+	// Removed.
+	g.Expect(c.List()).To(gomega.BeEmpty())
+	// Backing files released.
+	g.Expect(fb.Release()).To(gomega.Equal(0))
+}
+
+type BlockedHandler struct {
+	model.StockEventHandler
+	// Closed to deliver the events.
+	blocked chan struct{}
+	// Number of events delivered.
+	created int
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+func (r *BlockedHandler) Created(model.Event) {
+	<-r.blocked
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.created++
+}
+
+func (r *BlockedHandler) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.created
+}
+
+func TestShutdownCheckpoint(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	path := "/tmp/test-shutdown-checkpoint.db"
+	db := model.New(path, &TestObject2{})
+	g.Expect(db.Open(true)).To(gomega.BeNil())
+	defer func() {
+		_ = os.Remove(path)
+	}()
+	dir, err := ioutil.TempDir("", "checkpoint")
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	shutdown := []string{}
+	c := New()
+	c.Checkpoint = dir
+	err = c.Add(&DependentCollector{name: "a", db: db, shutdown: &shutdown})
+	g.Expect(err).To(gomega.BeNil())
+	handler := &BlockedHandler{blocked: make(chan struct{})}
+	_, err = db.Watch(&TestObject2{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 20; i++ {
+		g.Expect(db.Insert(&TestObject2{ID: i})).To(gomega.BeNil())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	err = c.Shutdown(ctx)
+	g.Expect(err).ToNot(gomega.BeNil())
+	close(handler.blocked)
+	time.Sleep(time.Millisecond * 50)
+	delivered := handler.count()
+	g.Expect(delivered < 20).To(gomega.BeTrue())
+	// Checkpointed.
+	checkpoint := c.CheckpointPath("a")
+	g.Expect(checkpoint).To(gomega.Equal(pathlib.Join(dir, "a.json")))
+	_, err = os.Stat(checkpoint)
+	g.Expect(err).To(gomega.BeNil())
+	// Replayed (after restart).
+	db = model.New(path, &TestObject2{})
+	g.Expect(db.Open(false)).To(gomega.BeNil())
+	replayed := &SlowHandler{}
+	n, err := db.(*model.Client).Replay(checkpoint, replayed)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(20 - delivered))
+	g.Expect(replayed.count()).To(gomega.Equal(n))
+	_, err = os.Stat(checkpoint)
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+	g.Expect(db.Close(true)).To(gomega.BeNil())
+}
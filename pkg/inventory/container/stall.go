@@ -0,0 +1,107 @@
+package container
+
+import (
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/ref"
+	"time"
+)
+
+//
+// Transaction operations.
+const (
+	OpGet    = "get"
+	OpInsert = "insert"
+	OpModify = "update"
+	OpRemove = "delete"
+)
+
+//
+// A transaction operation has stalled.
+// The operation did not complete within the timeout
+// (Example: lock contention). See: Collection.OpTimeout.
+type Stalled struct {
+	// Model kind.
+	Kind string
+	// Model PK.
+	PK string
+	// Operation (get|insert|update|delete).
+	Op string
+	// The timeout.
+	Timeout time.Duration
+	// Duration of the operation.
+	// 0 = not completed (when reported to OnStalled).
+	Duration time.Duration
+}
+
+//
+// Error description.
+func (e *Stalled) Error() string {
+	return fmt.Sprintf(
+		"%s %s/%s stalled: exceeded %s.",
+		e.Op,
+		e.Kind,
+		e.PK,
+		e.Timeout)
+}
+
+//
+// Run a (watched) transaction operation.
+// When the operation does not complete within the timeout, the
+// stall is logged, counted and reported (OnStalled) while still
+// running. The operation is run (and the transaction used) only
+// by the calling goroutine so it cannot be abandoned. When it
+// completes late, the error returned by the operation is returned
+// (with the stall as context) or, when the operation succeeded, a
+// Stalled error is returned so that the reconcile fails (and may
+// be retried). The watchdog only reads its own copy of the stall.
+func (r *Collection) txOp(op string, m model.Model, fn func() error) (err error) {
+	if r.OpTimeout <= 0 {
+		err = fn()
+		return
+	}
+	stalled := Stalled{
+		Kind:    ref.ToKind(m),
+		PK:      m.Pk(),
+		Op:      op,
+		Timeout: r.OpTimeout,
+	}
+	onStalled := r.OnStalled
+	watchdog := time.AfterFunc(r.OpTimeout, func() {
+		reported := stalled
+		StalledCounter.WithLabelValues(reported.Kind, op).Inc()
+		log.Info(
+			"transaction operation stalled.",
+			"kind",
+			reported.Kind,
+			"pk",
+			reported.PK,
+			"op",
+			op,
+			"timeout",
+			reported.Timeout)
+		if onStalled != nil {
+			onStalled(&reported)
+		}
+	})
+	mark := time.Now()
+	err = fn()
+	if watchdog.Stop() {
+		return
+	}
+	late := stalled
+	late.Duration = time.Since(mark)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"stalled",
+			late.Error(),
+			"duration",
+			late.Duration)
+		return
+	}
+	err = liberr.Wrap(&late)
+
+	return
+}
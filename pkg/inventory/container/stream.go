@@ -0,0 +1,263 @@
+package container
+
+import (
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"reflect"
+	"time"
+)
+
+//
+// Reconcile phases (streamed).
+const (
+	PhaseStream = "stream"
+)
+
+//
+// Streamed (incremental) reconcile.
+// Desired models are added and updated as they are put
+// (discovered). Stored models not put are deleted only
+// when the stream is done (complete).
+type Stream struct {
+	// The collection.
+	collection *Collection
+	// Context.
+	ctx context.Context
+	// Index of stored models by PK.
+	stored map[string]int
+	// PKs of models put.
+	seen map[string]bool
+	// The result.
+	result *Result
+	// Started.
+	started time.Time
+	// Failed.
+	err error
+	// Ended.
+	ended bool
+}
+
+//
+// Start a streamed reconcile.
+// The stored models are indexed.
+func (r *Collection) Stream(ctx context.Context) (stream *Stream, err error) {
+	stream = &Stream{
+		collection: r,
+		ctx:        ctx,
+		stored:     map[string]int{},
+		seen:       map[string]bool{},
+		result:     newResult(),
+		started:    time.Now(),
+	}
+	mark := time.Now()
+	for i := 0; i < r.Stored.Len(); i++ {
+		err = canceled(ctx)
+		if err != nil {
+			stream = nil
+			return
+		}
+		object, gErr := fb.Get(r.Stored, i)
+		if gErr != nil {
+			err = gErr
+			stream = nil
+			return
+		}
+		m := object.(model.Model)
+		stream.stored[m.Pk()] = i
+	}
+
+	stream.result.Durations[PhaseDispositions] = time.Since(mark)
+
+	return
+}
+
+//
+// Reconcile the collection with desired models received
+// on the channel. Deletes are applied when the channel
+// is closed. Aborted when the context is done.
+func (r *Collection) ReconcileStream(ctx context.Context, desired <-chan model.Model) (result *Result, err error) {
+	stream, err := r.Stream(ctx)
+	if err != nil {
+		result = newResult()
+		result.failed(err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			err = liberr.Wrap(ctx.Err())
+			stream.fail(err)
+			result, _ = stream.Done()
+			return
+		case m, open := <-desired:
+			if !open {
+				result, err = stream.Done()
+				return
+			}
+			err = stream.Put(m)
+			if err != nil {
+				result, _ = stream.Done()
+				return
+			}
+		}
+	}
+}
+
+//
+// Put a desired model.
+// The model is added or updated (as needed). Duplicates are
+// handled according to the collection duplicate policy.
+func (r *Stream) Put(m model.Model) (err error) {
+	if r.ended {
+		err = liberr.New("stream done.")
+		return
+	}
+	if r.err != nil {
+		err = r.err
+		return
+	}
+	defer func() {
+		r.fail(err)
+	}()
+	err = canceled(r.ctx)
+	if err != nil {
+		return
+	}
+	pk := m.Pk()
+	if r.seen[pk] {
+		err = r.duplicate(m)
+		return
+	}
+	r.seen[pk] = true
+	index, found := r.stored[pk]
+	if !found {
+		err = r.collection.Tx.Insert(m)
+		if err == nil {
+			r.result.Added++
+			r.result.changed(m, "added")
+		}
+		return
+	}
+	object, err := fb.Get(r.collection.Stored, index)
+	if err != nil {
+		return
+	}
+	err = r.update(object.(model.Model), m)
+
+	return
+}
+
+//
+// Done.
+// The stream is complete. Stored models not put are deleted.
+// Deletes are not applied when the stream has failed.
+func (r *Stream) Done() (result *Result, err error) {
+	result = r.result
+	if r.ended {
+		err = r.err
+		return
+	}
+	r.ended = true
+	defer func() {
+		r.fail(err)
+		err = r.err
+		result.failed(err)
+		result.Durations[PhaseStream] = time.Since(r.started)
+		if err == nil {
+			ReconcileCounter.WithLabelValues(ReconcileSucceeded).Inc()
+		} else {
+			ReconcileCounter.WithLabelValues(ReconcileFailed).Inc()
+		}
+	}()
+	if r.err != nil {
+		return
+	}
+	mark := time.Now()
+	for pk, index := range r.stored {
+		if r.seen[pk] {
+			continue
+		}
+		err = canceled(r.ctx)
+		if err != nil {
+			return
+		}
+		object, gErr := fb.Get(r.collection.Stored, index)
+		if gErr != nil {
+			err = gErr
+			return
+		}
+		m := object.(model.Model)
+		err = r.collection.Tx.Delete(m)
+		if err != nil {
+			return
+		}
+		r.result.Deleted++
+		r.result.changed(m, "deleted")
+	}
+
+	result.Durations[PhaseDelete] = time.Since(mark)
+
+	return
+}
+
+//
+// Update the stored model as desired.
+func (r *Stream) update(stored, desired model.Model) (err error) {
+	shepherd := r.collection.shepherd()
+	if shepherd.Equals(desired, stored) {
+		r.result.Skipped++
+		return
+	}
+	shepherd.Update(stored, desired)
+	err = r.collection.Tx.Update(stored)
+	if err == nil {
+		r.result.Updated++
+		r.result.changed(stored, "updated")
+	}
+
+	return
+}
+
+//
+// Handle a duplicate desired model.
+// For last-wins and merge, the (already applied) model
+// is fetched and updated.
+func (r *Stream) duplicate(m model.Model) (err error) {
+	r.result.Duplicated = append(r.result.Duplicated, m.Pk())
+	log.V(3).Info(
+		"duplicate desired model.",
+		"model",
+		model.Describe(m),
+		"policy",
+		r.collection.Duplicates)
+	switch r.collection.Duplicates {
+	case FirstWins:
+	case DuplicateError:
+		err = liberr.New(
+			"duplicate desired model.",
+			"model",
+			model.Describe(m))
+	default:
+		mv := reflect.ValueOf(m).Elem()
+		applied := reflect.New(mv.Type())
+		applied.Elem().Set(mv)
+		stored := applied.Interface().(model.Model)
+		err = r.collection.Tx.Get(stored)
+		if err != nil {
+			return
+		}
+		err = r.update(stored, m)
+	}
+
+	return
+}
+
+//
+// Record the (first) failure.
+func (r *Stream) fail(err error) {
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
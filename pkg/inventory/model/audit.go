@@ -0,0 +1,413 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/ref"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//
+// Audit actions.
+const (
+	AuditInsert = "insert"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+	// API request.
+	AuditRequest = "request"
+)
+
+//
+// Audit (model) kind of API request entries.
+const AuditRequestKind = "Request"
+
+//
+// Errors.
+var (
+	// Audit not enabled.
+	AuditNotEnabledErr = errors.New("audit not enabled")
+)
+
+//
+// Actor context key.
+type actorKey struct{}
+
+//
+// Associate an actor (who) with the context.
+// Transactions begun with the context record the
+// actor in the audit log.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+//
+// Get the actor associated with the context.
+func ActorOf(ctx context.Context) (actor string) {
+	if ctx == nil {
+		return
+	}
+	actor, _ = ctx.Value(actorKey{}).(string)
+	return
+}
+
+//
+// Audit model.
+// Records who/when/what for each model written.
+// Auditing is opt-in and enabled by including `&Audit{}`
+// in the models passed to New().
+type Audit struct {
+	PK string `sql:"pk(id)"`
+	// Monotonic ID.
+	ID int64 `sql:"key"`
+	// Timestamp (unix nanoseconds).
+	Timestamp int64 `sql:"index(timestamp)"`
+	// Actor (who).
+	Actor string `sql:"index(actor)"`
+	// Action (insert|update|delete).
+	Action string `sql:""`
+	// Model kind.
+	Kind string `sql:"index(model)"`
+	// Model primary key.
+	Model string `sql:"index(model)"`
+	// Changed fields (JSON).
+	// Each field maps to: [old, new].
+	Diff string `sql:""`
+}
+
+func (m *Audit) Pk() string {
+	return m.PK
+}
+
+func (m *Audit) String() string {
+	return m.Action + " " + m.Kind + ": " + m.Model
+}
+
+func (m *Audit) Equals(other Model) bool {
+	if audit, cast := other.(*Audit); cast {
+		return audit.ID == m.ID
+	}
+
+	return false
+}
+
+func (m *Audit) Labels() Labels {
+	return nil
+}
+
+//
+// Get the timestamp.
+func (m *Audit) Time() time.Time {
+	return time.Unix(0, m.Timestamp)
+}
+
+//
+// Get the changed fields.
+// Each field maps to: [old, new].
+func (m *Audit) Changes() (diff map[string][]interface{}, err error) {
+	diff = map[string][]interface{}{}
+	if m.Diff == "" {
+		return
+	}
+	err = json.Unmarshal([]byte(m.Diff), &diff)
+	if err != nil {
+		err = liberr.Wrap(err)
+	}
+
+	return
+}
+
+//
+// Get the API request detail.
+// Only entries with the AuditRequest action.
+func (m *Audit) Request() (request RequestAudit, err error) {
+	if m.Action != AuditRequest {
+		err = liberr.New(
+			"not a request entry.",
+			"action",
+			m.Action)
+		return
+	}
+	err = json.Unmarshal([]byte(m.Diff), &request)
+	if err != nil {
+		err = liberr.Wrap(err)
+	}
+
+	return
+}
+
+//
+// API request (audit) detail.
+type RequestAudit struct {
+	// HTTP method.
+	Method string `json:"method"`
+	// Route (template).
+	// Example: /providers/:owner/vms.
+	Route string `json:"route"`
+	// Request (URL) path.
+	Path string `json:"path"`
+	// Payload (SHA-256) digest.
+	Digest string `json:"digest,omitempty"`
+	// Response status.
+	Status int `json:"status"`
+}
+
+//
+// Audit log query.
+// Each (non-zero) criteria is matched.
+type AuditQuery struct {
+	// Model kind.
+	Kind string
+	// Model primary key.
+	PK string
+	// Actor.
+	Actor string
+	// Recorded after.
+	Since time.Time
+}
+
+//
+// Build the predicate.
+func (r *AuditQuery) predicate() (p Predicate) {
+	list := []Predicate{}
+	if r.Kind != "" {
+		list = append(list, Eq("Kind", r.Kind))
+	}
+	if r.PK != "" {
+		list = append(list, Eq("Model", r.PK))
+	}
+	if r.Actor != "" {
+		list = append(list, Eq("Actor", r.Actor))
+	}
+	if !r.Since.IsZero() {
+		list = append(list, Gt("Timestamp", r.Since.UnixNano()))
+	}
+	if len(list) > 0 {
+		p = And(list...)
+	}
+
+	return
+}
+
+//
+// Audit ID generator.
+// IDs are unix nanoseconds, adjusted to be monotonic.
+type auditSerial struct {
+	last  int64
+	mutex sync.Mutex
+}
+
+//
+// Next ID.
+func (r *auditSerial) next() (id int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	id = time.Now().UnixNano()
+	if id <= r.last {
+		id = r.last + 1
+	}
+	r.last = id
+	return
+}
+
+var auditID auditSerial
+
+//
+// Record an audit entry for the model written by the transaction.
+// The `before` and `after` models may be nil.
+func (r *Tx) audit(action string, before, after Model) (err error) {
+	if !r.auditing {
+		return
+	}
+	model := after
+	if model == nil {
+		model = before
+	}
+	if _, cast := model.(*Audit); cast {
+		return
+	}
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		return
+	}
+	if diff == nil && action == AuditUpdate {
+		return
+	}
+	b, err := json.Marshal(diff)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	id := auditID.next()
+	entry := &Audit{
+		ID:        id,
+		Timestamp: id,
+		Actor:     ActorOf(r.Context()),
+		Action:    action,
+		Kind:      ref.ToKind(model),
+		Model:     model.Pk(),
+		Diff:      string(b),
+	}
+	err = Table{r.db()}.Insert(entry)
+	return
+}
+
+//
+// Build the diff of changed (non-pk) fields.
+// Each field maps to: [old, new].
+// Returns nil when no fields changed.
+func auditDiff(before, after Model) (diff map[string][]interface{}, err error) {
+	values := func(m Model) (values map[string]interface{}, err error) {
+		values = map[string]interface{}{}
+		if m == nil {
+			return
+		}
+		md, err := Inspect(m)
+		if err != nil {
+			return
+		}
+		for _, f := range md.Fields {
+			if f.Pk() || f.Virtual() {
+				continue
+			}
+			switch f.Value.Kind() {
+			case reflect.Bool:
+				values[f.Name] = f.Value.Bool()
+			case reflect.Int,
+				reflect.Int8,
+				reflect.Int16,
+				reflect.Int32,
+				reflect.Int64:
+				values[f.Name] = f.Value.Int()
+			default:
+				values[f.Name] = f.Pull()
+			}
+		}
+		return
+	}
+	old, err := values(before)
+	if err != nil {
+		return
+	}
+	new, err := values(after)
+	if err != nil {
+		return
+	}
+	for name, v := range new {
+		if before != nil && reflect.DeepEqual(old[name], v) {
+			continue
+		}
+		if diff == nil {
+			diff = map[string][]interface{}{}
+		}
+		diff[name] = []interface{}{old[name], v}
+	}
+	if after == nil {
+		for name, v := range old {
+			if diff == nil {
+				diff = map[string][]interface{}{}
+			}
+			diff[name] = []interface{}{v, nil}
+		}
+	}
+
+	return
+}
+
+//
+// List audit entries matching the query.
+// Sorted by ID (oldest first).
+func (r *Client) AuditLog(query AuditQuery) (list []Audit, err error) {
+	if !r.auditing {
+		err = liberr.Wrap(AuditNotEnabledErr)
+		return
+	}
+	list = []Audit{}
+	err = r.List(
+		&list,
+		ListOptions{
+			Predicate: query.predicate(),
+			SortBy:    []string{"ID"},
+			Detail:    MaxDetail,
+		})
+
+	return
+}
+
+//
+// Record an API request in the audit log.
+// The actor associated with the context is recorded. The
+// entry kind is AuditRequestKind and the PK is the method
+// and route. The detail (JSON) is stored in the Diff field.
+// See: Audit.Request().
+func (r *Client) AuditRequest(ctx context.Context, request RequestAudit) (err error) {
+	if !r.auditing {
+		err = liberr.Wrap(AuditNotEnabledErr)
+		return
+	}
+	b, err := json.Marshal(request)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	id := auditID.next()
+	err = r.Insert(
+		&Audit{
+			ID:        id,
+			Timestamp: id,
+			Actor:     ActorOf(ctx),
+			Action:    AuditRequest,
+			Kind:      AuditRequestKind,
+			Model:     request.Method + " " + request.Route,
+			Diff:      string(b),
+		})
+
+	return
+}
+
+//
+// Delete audit entries recorded before the specified time.
+// Returns the number of entries deleted.
+func (r *Client) PruneAudit(before time.Time) (n int64, err error) {
+	if !r.auditing {
+		err = liberr.Wrap(AuditNotEnabledErr)
+		return
+	}
+	err = r.With(
+		func(tx *Tx) (err error) {
+			list := []Audit{}
+			err = tx.List(
+				&list,
+				ListOptions{
+					Predicate: Lt("Timestamp", before.UnixNano()),
+				})
+			if err != nil {
+				return
+			}
+			for i := range list {
+				err = Table{tx.db()}.Delete(&list[i])
+				if err != nil {
+					return
+				}
+				n++
+			}
+			return
+		},
+		"audit.prune")
+	if err != nil {
+		n = 0
+		return
+	}
+
+	r.log.V(3).Info(
+		"audit pruned.",
+		"before",
+		before,
+		"count",
+		n)
+
+	return
+}
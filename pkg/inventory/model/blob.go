@@ -0,0 +1,358 @@
+package model
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/ref"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+//
+// Blob (streamed) chunk size.
+var BlobChunkSize = 64 * 1024
+
+//
+// Max size of a blob.
+// Applies to streamed blobs and []byte fields without
+// a `max=N` tag. Zero = not limited.
+var MaxBlobSize int64 = 64 * 1024 * 1024
+
+//
+// Regex used for `max=N` tags.
+var MaxRegex = regexp.MustCompile(`(max)=([0-9]+)`)
+
+//
+// Errors.
+var (
+	// Blob size limit exceeded.
+	BlobSizeErr = errors.New("blob size limit exceeded")
+	// Streamed blobs not enabled.
+	BlobNotEnabledErr = errors.New("blobs not enabled")
+)
+
+//
+// Byte slice type.
+var bytesType = reflect.TypeOf([]byte{})
+
+//
+// Blob chunk model.
+// Streamed blobs are stored in chunks associated
+// with a (parent) model by name. Streamed blobs are
+// enabled by including the BlobChunk in the models.
+type BlobChunk struct {
+	PK     string `sql:"pk(kind;parent;name;seq)"`
+	Kind   string `sql:"key"`
+	Parent string `sql:"key"`
+	Name   string `sql:"key"`
+	Seq    int    `sql:"key"`
+	Data   []byte `sql:"blob"`
+}
+
+func (m *BlobChunk) Pk() string {
+	return m.PK
+}
+
+func (m *BlobChunk) String() string {
+	return m.Kind + "/" + m.Parent + "/" + m.Name + "#" + strconv.Itoa(m.Seq)
+}
+
+func (m *BlobChunk) Labels() Labels {
+	return nil
+}
+
+//
+// Get whether the field is a blob.
+// A []byte field with the `blob` option.
+func (f *Field) Blob() bool {
+	return f.Value.Type() == bytesType && f.hasOpt("blob")
+}
+
+//
+// Max (blob) size.
+// Format: max=N.
+func (f *Field) Max() (n int64) {
+	n = MaxBlobSize
+	for _, opt := range f.options() {
+		m := MaxRegex.FindStringSubmatch(opt)
+		if len(m) == 3 {
+			n, _ = strconv.ParseInt(m[2], 10, 64)
+			break
+		}
+	}
+
+	return
+}
+
+//
+// Validate blob field sizes.
+func (r *Definition) validateBlobs() (err error) {
+	for _, f := range r.Fields {
+		if !f.Blob() {
+			continue
+		}
+		max := f.Max()
+		if max > 0 && int64(f.Value.Len()) > max {
+			err = liberr.Wrap(
+				BlobSizeErr,
+				"kind",
+				r.Kind,
+				"field",
+				f.Name,
+				"max",
+				max)
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Write (stream) a named blob associated with the model.
+// The content is read and stored in chunks, replacing
+// the blob (by name) when it exists. Returns the number
+// of bytes written.
+func (r *Tx) PutBlob(model Model, name string, reader io.Reader) (n int64, err error) {
+	if !r.blobs {
+		err = liberr.Wrap(BlobNotEnabledErr)
+		return
+	}
+	err = r.DeleteBlob(model, name)
+	if err != nil {
+		return
+	}
+	kind := ref.ToKind(model)
+	bfr := make([]byte, BlobChunkSize)
+	for seq := 0; ; seq++ {
+		read, rErr := io.ReadFull(reader, bfr)
+		if read > 0 || seq == 0 {
+			n += int64(read)
+			if MaxBlobSize > 0 && n > MaxBlobSize {
+				err = liberr.Wrap(
+					BlobSizeErr,
+					"kind",
+					kind,
+					"name",
+					name,
+					"max",
+					MaxBlobSize)
+				return
+			}
+			chunk := &BlobChunk{
+				Kind:   kind,
+				Parent: model.Pk(),
+				Name:   name,
+				Seq:    seq,
+				Data:   bfr[:read],
+			}
+			err = Table{r.db()}.Insert(chunk)
+			if err != nil {
+				return
+			}
+		}
+		if rErr == io.EOF || rErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rErr != nil {
+			err = liberr.Wrap(rErr)
+			return
+		}
+	}
+
+	r.log.V(3).Info(
+		"blob written.",
+		"model",
+		Describe(model),
+		"name",
+		name,
+		"bytes",
+		n)
+
+	return
+}
+
+//
+// Read (stream) a named blob associated with the model.
+// Chunks are fetched as read. Returns NotFound when the
+// blob does not exist.
+func (r *Tx) GetBlob(model Model, name string) (reader io.ReadCloser, err error) {
+	if !r.blobs {
+		err = liberr.Wrap(BlobNotEnabledErr)
+		return
+	}
+	reader, err = openBlob(r.Get, model, name)
+	return
+}
+
+//
+// Delete the named blob associated with the model.
+func (r *Tx) DeleteBlob(model Model, name string) (err error) {
+	if !r.blobs {
+		err = liberr.Wrap(BlobNotEnabledErr)
+		return
+	}
+	err = r.deleteBlobs(
+		model,
+		And(
+			Eq("Kind", ref.ToKind(model)),
+			Eq("Parent", model.Pk()),
+			Eq("Name", name)))
+	return
+}
+
+//
+// Delete blob chunks associated with the model
+// and matching the predicate.
+func (r *Tx) deleteBlobs(model Model, predicate Predicate) (err error) {
+	list := []BlobChunk{}
+	err = Table{r.db()}.List(
+		&list,
+		ListOptions{
+			Predicate: predicate,
+		})
+	if err != nil {
+		return
+	}
+	for i := range list {
+		err = Table{r.db()}.Delete(&list[i])
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Delete all blobs associated with the model.
+func (r *Tx) deleteAllBlobs(model Model) (err error) {
+	if !r.blobs {
+		return
+	}
+	if _, cast := model.(*BlobChunk); cast {
+		return
+	}
+	err = r.deleteBlobs(
+		model,
+		And(
+			Eq("Kind", ref.ToKind(model)),
+			Eq("Parent", model.Pk())))
+	return
+}
+
+//
+// Write (stream) a named blob associated with the model.
+// Delegated to Tx.PutBlob().
+func (r *Client) PutBlob(model Model, name string, reader io.Reader) (n int64, err error) {
+	err = r.With(
+		func(tx *Tx) (err error) {
+			n, err = tx.PutBlob(model, name, reader)
+			return
+		})
+	if err != nil {
+		n = 0
+	}
+
+	return
+}
+
+//
+// Read (stream) a named blob associated with the model.
+// Chunks are fetched as read. Returns NotFound when the
+// blob does not exist.
+func (r *Client) GetBlob(model Model, name string) (reader io.ReadCloser, err error) {
+	if !r.blobs {
+		err = liberr.Wrap(BlobNotEnabledErr)
+		return
+	}
+	reader, err = openBlob(r.Get, model, name)
+	return
+}
+
+//
+// Open a blob reader.
+func openBlob(get func(Model) error, model Model, name string) (reader io.ReadCloser, err error) {
+	br := &blobReader{
+		get: get,
+		chunk: BlobChunk{
+			Kind:   ref.ToKind(model),
+			Parent: model.Pk(),
+			Name:   name,
+		},
+	}
+	err = br.fetch()
+	if err != nil {
+		return
+	}
+
+	reader = br
+
+	return
+}
+
+//
+// Blob reader.
+type blobReader struct {
+	// Get (model) function.
+	get func(Model) error
+	// Current chunk.
+	chunk BlobChunk
+	// Unread data (current chunk).
+	data []byte
+	// Last chunk read.
+	done bool
+}
+
+//
+// Fetch the next chunk.
+func (r *blobReader) fetch() (err error) {
+	chunk := &BlobChunk{
+		Kind:   r.chunk.Kind,
+		Parent: r.chunk.Parent,
+		Name:   r.chunk.Name,
+		Seq:    r.chunk.Seq,
+	}
+	err = r.get(chunk)
+	if err != nil {
+		return
+	}
+	r.data = chunk.Data
+	r.chunk.Seq++
+
+	return
+}
+
+//
+// Read.
+func (r *blobReader) Read(p []byte) (n int, err error) {
+	for len(r.data) == 0 {
+		if r.done {
+			err = io.EOF
+			return
+		}
+		err = r.fetch()
+		if err != nil {
+			if errors.Is(err, NotFound) {
+				r.done = true
+				err = nil
+				continue
+			}
+			return
+		}
+	}
+	n = copy(p, r.data)
+	r.data = r.data[n:]
+
+	return
+}
+
+//
+// Close.
+func (r *blobReader) Close() (err error) {
+	r.done = true
+	r.data = nil
+	return
+}
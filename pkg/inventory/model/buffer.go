@@ -0,0 +1,359 @@
+package model
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/ref"
+	"sync"
+	"time"
+)
+
+//
+// Write buffer defaults.
+var (
+	// Coalesce window.
+	BufferWindow = time.Millisecond * 100
+	// Max number of buffered (pending) writes.
+	BufferMax = 1000
+)
+
+//
+// Buffered write actions.
+const (
+	// Upsert (insert or update).
+	bufferPut = iota
+	// Delete.
+	bufferDelete
+)
+
+//
+// Write buffer.
+// Absorbs bursts of writes (Example: chatty provider event
+// streams) by coalescing successive writes of the same model
+// (kind and PK) within the window into the latest. Buffered
+// writes are flushed in one transaction when the window (started
+// by the first buffered write) has elapsed or when the max is
+// reached. Writes are applied in the order last buffered. When
+// the transaction fails, each write is applied (retried) in its
+// own transaction so that one bad model does not fail the flush;
+// writes that still fail are logged and dropped. The error of a
+// (window) flush is returned by the next call to Put(), Delete(),
+// Flush() or Close(). Close() must be called to flush the
+// pending writes.
+// Example:
+//   buffer := &model.Buffer{DB: db, Labels: []string{"provider"}}
+//   defer buffer.Close()
+//   err := buffer.Put(vm)
+type Buffer struct {
+	// DB.
+	DB DB
+	// Coalesce window. Default: BufferWindow.
+	Window time.Duration
+	// Max number of pending writes. Default: BufferMax.
+	// Reaching the max flushes (synchronously).
+	Max int
+	// Transaction labels.
+	Labels []string
+	// Pending writes.
+	pending map[bufferKey]*bufferedWrite
+	// Pending (last buffered) order.
+	order []bufferKey
+	// Error of the (window) flush not yet returned.
+	err error
+	// Flush timer.
+	timer *time.Timer
+	// Statistics.
+	report BufferReport
+	// Protect the pending writes.
+	mutex sync.Mutex
+	// Serialize flushes.
+	flushMutex sync.Mutex
+}
+
+//
+// Buffered write key.
+type bufferKey struct {
+	// Model kind.
+	kind string
+	// Model PK.
+	pk string
+}
+
+//
+// Buffered write.
+type bufferedWrite struct {
+	// Action.
+	action int
+	// Model.
+	model Model
+}
+
+//
+// Write buffer report.
+type BufferReport struct {
+	// Number of pending writes.
+	Pending int `json:"pending"`
+	// Number of buffered writes.
+	Buffered int64 `json:"buffered"`
+	// Number of writes coalesced (absorbed).
+	Coalesced int64 `json:"coalesced"`
+	// Number of flushes.
+	Flushes int64 `json:"flushes"`
+	// Number of writes flushed.
+	Flushed int64 `json:"flushed"`
+	// Number of writes failed (dropped).
+	Failed int64 `json:"failed"`
+}
+
+//
+// Buffer an upsert (insert or update) of the model.
+// The model is copied (shallow). Returns the error of
+// the flush when the max is reached.
+func (r *Buffer) Put(model Model) (err error) {
+	err = r.add(bufferPut, Clone(model))
+	return
+}
+
+//
+// Buffer a delete of the model.
+// The model is copied (shallow). Returns the error of
+// the flush when the max is reached.
+func (r *Buffer) Delete(model Model) (err error) {
+	err = r.add(bufferDelete, Clone(model))
+	return
+}
+
+//
+// Flush the pending writes.
+// Returns the error of the (batch) transaction when not
+// recovered by applying each write individually.
+func (r *Buffer) Flush() (err error) {
+	err = r.flush()
+	if err == nil {
+		err = r.failed()
+	}
+
+	return
+}
+
+//
+// Flush the pending writes.
+func (r *Buffer) flush() (err error) {
+	r.flushMutex.Lock()
+	defer r.flushMutex.Unlock()
+	writes := r.take()
+	if len(writes) == 0 {
+		return
+	}
+	mark := time.Now()
+	failed := 0
+	err = r.DB.With(
+		func(tx *Tx) (err error) {
+			for _, w := range writes {
+				err = w.apply(tx)
+				if err != nil {
+					return
+				}
+			}
+			return
+		},
+		r.Labels...)
+	if err != nil {
+		log.V(3).Info(
+			"buffer: flush failed, retrying each write.",
+			"reason",
+			err.Error())
+		err = nil
+		for _, w := range writes {
+			wErr := r.DB.With(w.apply, r.Labels...)
+			if wErr != nil {
+				failed++
+				log.Trace(wErr)
+			}
+		}
+		if failed > 0 {
+			err = liberr.New(
+				"buffered writes failed.",
+				"failed",
+				failed)
+		}
+	}
+	r.mutex.Lock()
+	r.report.Flushes++
+	r.report.Flushed += int64(len(writes) - failed)
+	r.report.Failed += int64(failed)
+	r.mutex.Unlock()
+
+	log.V(4).Info(
+		"buffer: flushed.",
+		"writes",
+		len(writes),
+		"failed",
+		failed,
+		"duration",
+		time.Since(mark))
+
+	return
+}
+
+//
+// Close the buffer.
+// The pending writes are flushed.
+func (r *Buffer) Close() (err error) {
+	r.mutex.Lock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.mutex.Unlock()
+	err = r.Flush()
+	return
+}
+
+//
+// Report statistics.
+func (r *Buffer) Report() (report BufferReport) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	report = r.report
+	report.Pending = len(r.order)
+	return
+}
+
+//
+// Add (coalesce) a write.
+// The (generated) PK is ensured. A coalesced write is
+// moved to the end so that writes are applied in order.
+func (r *Buffer) add(action int, model Model) (err error) {
+	if md, err := Inspect(model); err == nil {
+		Table{}.EnsurePk(md)
+	}
+	key := bufferKey{
+		kind: ref.ToKind(model),
+		pk:   model.Pk(),
+	}
+	r.mutex.Lock()
+	if r.pending == nil {
+		r.pending = map[bufferKey]*bufferedWrite{}
+	}
+	r.report.Buffered++
+	if w, found := r.pending[key]; found {
+		w.action = action
+		w.model = model
+		r.report.Coalesced++
+		for i := range r.order {
+			if r.order[i] == key {
+				r.order = append(r.order[:i], r.order[i+1:]...)
+				break
+			}
+		}
+	} else {
+		r.pending[key] = &bufferedWrite{
+			action: action,
+			model:  model,
+		}
+	}
+	r.order = append(r.order, key)
+	full := len(r.order) >= r.max()
+	if !full && r.timer == nil {
+		r.timer = time.AfterFunc(r.window(), r.flushed)
+	}
+	r.mutex.Unlock()
+	if full {
+		err = r.Flush()
+	} else {
+		err = r.failed()
+	}
+
+	return
+}
+
+//
+// Window elapsed.
+// The error is retained until returned.
+func (r *Buffer) flushed() {
+	err := r.flush()
+	if err != nil {
+		r.mutex.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.mutex.Unlock()
+	}
+}
+
+//
+// Get (and clear) the error of the (window) flush.
+func (r *Buffer) failed() (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err = r.err
+	r.err = nil
+	return
+}
+
+//
+// Take (and clear) the pending writes.
+// The timer is stopped.
+func (r *Buffer) take() (writes []*bufferedWrite) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	for _, key := range r.order {
+		writes = append(writes, r.pending[key])
+	}
+	r.pending = nil
+	r.order = nil
+	return
+}
+
+//
+// The coalesce window.
+func (r *Buffer) window() (d time.Duration) {
+	d = r.Window
+	if d <= 0 {
+		d = BufferWindow
+	}
+
+	return
+}
+
+//
+// The max pending writes.
+func (r *Buffer) max() (n int) {
+	n = r.Max
+	if n <= 0 {
+		n = BufferMax
+	}
+
+	return
+}
+
+//
+// Apply the write.
+// Puts are inserted when not stored, else updated.
+// Deletes of models not stored are ignored.
+func (w *bufferedWrite) apply(tx *Tx) (err error) {
+	switch w.action {
+	case bufferDelete:
+		err = tx.Delete(w.model)
+		if errors.Is(err, NotFound) {
+			err = nil
+		}
+	default:
+		stored := Clone(w.model)
+		err = tx.Get(stored)
+		if err != nil {
+			if errors.Is(err, NotFound) {
+				err = tx.Insert(w.model)
+			}
+			return
+		}
+		err = tx.Update(w.model)
+	}
+
+	return
+}
@@ -0,0 +1,234 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"os"
+	"sort"
+)
+
+//
+// Checkpointed (watch) event.
+// Encoded as JSON so that the checkpoint may be
+// read (replayed) by another process.
+type checkpointed struct {
+	// Event ID.
+	ID uint64 `json:"id"`
+	// Labels.
+	Labels []string `json:"labels,omitempty"`
+	// Action.
+	Action uint8 `json:"action"`
+	// Reconcile ID.
+	ReconcileID string `json:"reconcileID,omitempty"`
+	// Model kind.
+	Kind string `json:"kind"`
+	// Model.
+	Model json.RawMessage `json:"model"`
+	// Updated model.
+	Updated json.RawMessage `json:"updated,omitempty"`
+}
+
+//
+// Checkpoint the watch events not delivered.
+// The events queued for the watches ended by Drain() and not
+// yet taken for delivery are taken from the queues (and will
+// not be delivered) and written to the file at the path as
+// JSON lines ordered by event ID. Events queued for multiple
+// watches are written once. Intended to be called (on shutdown)
+// when the drain has not completed before the context is done
+// so that the events are not lost. The file is not written when
+// no events are pending. Returns the number of events written.
+// See: Replay().
+func (r *Client) Checkpoint(path string) (n int, err error) {
+	events := r.journal.checkpoint()
+	if len(events) == 0 {
+		return
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		err = liberr.Wrap(err, "path", path)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, event := range events {
+		entry := checkpointed{
+			ID:          event.ID,
+			Labels:      event.Labels,
+			Action:      event.Action,
+			ReconcileID: event.reconcileID,
+			Kind:        Definition{}.kind(event.Model),
+		}
+		entry.Model, err = json.Marshal(event.Model)
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+		if event.Updated != nil {
+			entry.Updated, err = json.Marshal(event.Updated)
+			if err != nil {
+				err = liberr.Wrap(err)
+				return
+			}
+		}
+		err = encoder.Encode(&entry)
+		if err != nil {
+			err = liberr.Wrap(err, "path", path)
+			return
+		}
+		n++
+	}
+	err = writer.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		err = liberr.Wrap(err, "path", path)
+		return
+	}
+
+	r.log.V(3).Info(
+		"watch events checkpointed.",
+		"path",
+		path,
+		"events",
+		n)
+
+	return
+}
+
+//
+// Replay checkpointed watch events.
+// The events in the file (written by Checkpoint()) are delivered
+// in order to the handler and the file is deleted. The models
+// are decoded using the DB models; events for models not in the
+// DB are skipped. Returns the number of events delivered.
+func (r *Client) Replay(path string, handler EventHandler) (n int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			err = liberr.Wrap(err, "path", path)
+		}
+		return
+	}
+	defer func() {
+		_ = file.Close()
+		if err == nil {
+			_ = os.Remove(path)
+		}
+	}()
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for decoder.More() {
+		entry := checkpointed{}
+		err = decoder.Decode(&entry)
+		if err != nil {
+			err = liberr.Wrap(err, "path", path)
+			return
+		}
+		md, found := r.dm.Find(entry.Kind)
+		if !found {
+			r.log.V(3).Info(
+				"checkpointed event kind not found.",
+				"kind",
+				entry.Kind)
+			continue
+		}
+		event := Event{
+			ID:          entry.ID,
+			Labels:      entry.Labels,
+			Action:      entry.Action,
+			reconcileID: entry.ReconcileID,
+		}
+		event.Model = md.NewModel().(Model)
+		err = json.Unmarshal(entry.Model, event.Model)
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+		if entry.Updated != nil {
+			event.Updated = md.NewModel().(Model)
+			err = json.Unmarshal(entry.Updated, event.Updated)
+			if err != nil {
+				err = liberr.Wrap(err)
+				return
+			}
+		}
+		switch event.Action {
+		case Created:
+			handler.Created(event)
+		case Updated:
+			handler.Updated(event)
+		case Deleted:
+			handler.Deleted(event)
+		default:
+			continue
+		}
+		n++
+	}
+
+	r.log.V(3).Info(
+		"checkpointed watch events replayed.",
+		"path",
+		path,
+		"events",
+		n)
+
+	return
+}
+
+//
+// Take the events not delivered by the (draining) watches.
+// Ordered by event ID.
+func (r *Journal) checkpoint() (events []Event) {
+	r.mutex.Lock()
+	watches := r.draining
+	r.draining = nil
+	r.mutex.Unlock()
+	taken := map[uint64]Event{}
+	for _, w := range watches {
+		for _, b := range w.queue.checkpoint() {
+			for b.itr != nil {
+				event := Event{}
+				if !event.next(b.itr) {
+					break
+				}
+				taken[event.ID] = event
+			}
+			b.close()
+		}
+	}
+	for _, event := range taken {
+		events = append(events, event)
+	}
+	sort.Slice(
+		events,
+		func(i, j int) bool {
+			return events[i].ID < events[j].ID
+		})
+
+	return
+}
+
+//
+// Take the queued batches and close the queue.
+// The batches will not be delivered.
+func (q *eventQueue) checkpoint() (batches []*batch) {
+	defer q.signal()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	batches = q.batches
+	q.batches = nil
+	q.events = 0
+	q.bytes = 0
+	q.closed = true
+	return
+}
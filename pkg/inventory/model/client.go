@@ -1,12 +1,19 @@
 package model
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/go-logr/logr"
 	liberr "github.com/konveyor/controller/pkg/error"
 	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/logging"
+	"github.com/konveyor/controller/pkg/tracing"
+	"io"
 	"os"
+	"reflect"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -21,14 +28,38 @@ type DB interface {
 	Execute(sql string) (sql.Result, error)
 	// Get the specified model.
 	Get(Model) error
+	// Get the specified model with context.
+	GetContext(context.Context, Model) error
 	// List models based on the type of slice.
 	List(interface{}, ListOptions) error
+	// List models with context.
+	ListContext(context.Context, interface{}, ListOptions) error
 	// Find models.
 	Find(interface{}, ListOptions) (fb.Iterator, error)
+	// Find models with context.
+	FindContext(context.Context, interface{}, ListOptions) (fb.Iterator, error)
+	// Iterate models (cursor).
+	ForEach(Model, ListOptions, func(Model) error) error
+	// Iterate models (cursor) with context.
+	ForEachContext(context.Context, Model, ListOptions, func(Model) error) error
 	// Count based on the specified model.
 	Count(Model, Predicate) (int64, error)
+	// Count with context.
+	CountContext(context.Context, Model, Predicate) (int64, error)
+	// Facets (distinct values with counts) of the model fields.
+	Facets(Model, []string, Predicate) (Facets, error)
+	// Search (federated) across the model kinds.
+	SearchAll(string, []Model, int) ([]SearchResult, error)
+	// Schema (DDL and table descriptions).
+	Schema() (*DBSchema, error)
+	// Snapshot (consistent) reads.
+	Snapshot(func(*Reader) error) error
+	// Snapshot (consistent) reads with context.
+	SnapshotContext(context.Context, func(*Reader) error) error
 	// Begin a transaction.
 	Begin(...string) (*Tx, error)
+	// Begin a transaction with context.
+	BeginContext(context.Context, ...string) (*Tx, error)
 	// With transaction.
 	With(fn func(*Tx) error, labels ...string) error
 	// Insert a model.
@@ -41,6 +72,94 @@ type DB interface {
 	Watch(Model, EventHandler) (*Watch, error)
 	// End a watch.
 	EndWatch(watch *Watch)
+	// End a watch by ID.
+	EndWatchID(id uint64) error
+	// Reset all watches (relist) with the reason.
+	Relist(reason string)
+	// Deliver queued watch events and end the watches.
+	Drain(context.Context) error
+	// Watch reports.
+	Watches() []WatchReport
+	// Open transaction reports.
+	Transactions() []TxReport
+	// Register access hooks for a model kind.
+	Hook(Model, Hooks)
+	// Set the row limit for a model kind.
+	SetLimit(Model, RowLimit)
+	// Row limit reports.
+	Limits() []LimitReport
+	// List audit entries.
+	AuditLog(AuditQuery) ([]Audit, error)
+	// Delete audit entries recorded before the specified time.
+	PruneAudit(time.Time) (int64, error)
+	// Record an API request in the audit log.
+	AuditRequest(context.Context, RequestAudit) error
+	// Dry-run transaction.
+	DryRun(fn func(*Tx) error, labels ...string) (Counters, error)
+	// Set the journal (watch) retention policy.
+	SetRetention(Retention)
+	// Set the max number of watch (event) delivery workers.
+	SetDispatchWorkers(int)
+	// Write (stream) a named blob associated with a model.
+	PutBlob(Model, string, io.Reader) (int64, error)
+	// Read (stream) a named blob associated with a model.
+	GetBlob(Model, string) (io.ReadCloser, error)
+}
+
+//
+// DB health (optional).
+// Implemented by the Client. See: HealthOf().
+type HealthReporter interface {
+	// Health report.
+	Health() Health
+}
+
+//
+// Get the health report for a DB.
+// A DB not implementing HealthReporter is reported
+// as open (assumed).
+func HealthOf(db DB) (h Health) {
+	if reporter, cast := db.(HealthReporter); cast {
+		h = reporter.Health()
+		return
+	}
+	h.Open = true
+	return
+}
+
+//
+// DB health report.
+type Health struct {
+	// The DB can be queried.
+	Open bool `json:"open"`
+	// Error description when not open.
+	Error string `json:"error,omitempty"`
+	// Number of watches.
+	Watches int `json:"watches"`
+	// Number of queued (undelivered) event batches.
+	Backlog int `json:"backlog"`
+	// Number of watches not delivering events.
+	// The queue is full or the watch is not running.
+	Stalled int `json:"stalled"`
+	// Number of events discarded (retention).
+	Discarded uint64 `json:"discarded"`
+	// Number of events collapsed by compaction.
+	Compacted uint64 `json:"compacted"`
+	// Number of running (watch) delivery workers.
+	Workers int `json:"workers"`
+}
+
+//
+// Open transaction report.
+type TxReport struct {
+	// Labels associated with the transaction.
+	Labels []string `json:"labels"`
+	// Started timestamp.
+	Started time.Time `json:"started"`
+	// Elapsed since started.
+	Age string `json:"age"`
+	// Dry-run mode.
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 //
@@ -56,6 +175,18 @@ type Client struct {
 	pool Pool
 	// Journal
 	journal Journal
+	// Open transactions.
+	open txSet
+	// Access hooks.
+	hooks hookSet
+	// Row limits.
+	limits limitSet
+	// Auditing enabled.
+	auditing bool
+	// Streamed blobs enabled.
+	blobs bool
+	// Read-only (standby).
+	readOnly bool
 	// Logger
 	log logr.Logger
 }
@@ -84,12 +215,41 @@ func (r *Client) Open(delete bool) (err error) {
 	if err != nil {
 		panic(err)
 	}
+	if delete {
+		r.journal.Relist(ResetRebuilt)
+	}
+	if r.readOnly {
+		roErr := r.pool.ReadOnly(true)
+		if roErr != nil {
+			panic(roErr)
+		}
+	}
 
 	r.log.V(3).Info("session pool opened.")
 
 	return
 }
 
+//
+// Set the DB read-only.
+// The writer sessions are opened using (SQLite) mode=ro so
+// that writes fail while in standby. May be set before the
+// DB is opened; the schema is built before it is applied.
+func (r *Client) ReadOnly(readOnly bool) (err error) {
+	r.readOnly = readOnly
+	err = r.pool.ReadOnly(readOnly)
+	if err != nil {
+		return
+	}
+
+	r.log.V(3).Info(
+		"DB read-only set.",
+		"readOnly",
+		readOnly)
+
+	return
+}
+
 //
 // Close the database.
 // The session pool and journal are closed.
@@ -131,10 +291,18 @@ func (r *Client) Execute(sql string) (result sql.Result, err error) {
 //
 // Get the model.
 func (r *Client) Get(model Model) (err error) {
+	err = r.GetContext(context.Background(), model)
+	return
+}
+
+//
+// Get the model with context.
+// The context is used to link (tracing) spans.
+func (r *Client) GetContext(ctx context.Context, model Model) (err error) {
 	session := r.pool.Reader()
 	defer session.Return()
 	mark := time.Now()
-	err = Table{session.db}.Get(model)
+	err = Table{Traced(ctx, session.db)}.Get(model)
 	if err == nil {
 		r.log.V(4).Info(
 			"get succeeded.",
@@ -151,10 +319,19 @@ func (r *Client) Get(model Model) (err error) {
 // List models.
 // The `list` must be: *[]Model.
 func (r *Client) List(list interface{}, options ListOptions) (err error) {
+	err = r.ListContext(context.Background(), list, options)
+	return
+}
+
+//
+// List models with context.
+// The context is used to link (tracing) spans.
+// The `list` must be: *[]Model.
+func (r *Client) ListContext(ctx context.Context, list interface{}, options ListOptions) (err error) {
 	session := r.pool.Reader()
 	defer session.Return()
 	mark := time.Now()
-	err = Table{session.db}.List(list, options)
+	err = Table{Traced(ctx, session.db)}.List(list, options)
 	if err == nil {
 		r.log.V(4).Info(
 			"list succeeded.",
@@ -170,10 +347,18 @@ func (r *Client) List(list interface{}, options ListOptions) (err error) {
 //
 // Find models.
 func (r *Client) Find(model interface{}, options ListOptions) (itr fb.Iterator, err error) {
+	itr, err = r.FindContext(context.Background(), model, options)
+	return
+}
+
+//
+// Find models with context.
+// The context is used to link (tracing) spans.
+func (r *Client) FindContext(ctx context.Context, model interface{}, options ListOptions) (itr fb.Iterator, err error) {
 	session := r.pool.Reader()
 	defer session.Return()
 	mark := time.Now()
-	itr, err = Table{session.db}.Find(model, options)
+	itr, err = Table{Traced(ctx, session.db)}.Find(model, options)
 	if err == nil {
 		r.log.V(4).Info(
 			"list succeeded.",
@@ -186,13 +371,50 @@ func (r *Client) Find(model interface{}, options ListOptions) (itr fb.Iterator,
 	return
 }
 
+//
+// Iterate models.
+// The reader session is held until iteration is done.
+func (r *Client) ForEach(model Model, options ListOptions, fn func(Model) error) (err error) {
+	err = r.ForEachContext(context.Background(), model, options, fn)
+	return
+}
+
+//
+// Iterate models with context.
+// The context is used to link (tracing) spans.
+// The reader session is held until iteration is done.
+func (r *Client) ForEachContext(ctx context.Context, model Model, options ListOptions, fn func(Model) error) (err error) {
+	session := r.pool.Reader()
+	defer session.Return()
+	mark := time.Now()
+	err = Table{Traced(ctx, session.db)}.ForEach(model, options, fn)
+	if err == nil {
+		r.log.V(4).Info(
+			"iterate succeeded.",
+			"options",
+			options,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
 //
 // Count models.
 func (r *Client) Count(model Model, predicate Predicate) (n int64, err error) {
+	n, err = r.CountContext(context.Background(), model, predicate)
+	return
+}
+
+//
+// Count models with context.
+// The context is used to link (tracing) spans.
+func (r *Client) CountContext(ctx context.Context, model Model, predicate Predicate) (n int64, err error) {
 	session := r.pool.Reader()
 	defer session.Return()
 	mark := time.Now()
-	n, err = Table{session.db}.Count(model, predicate)
+	n, err = Table{Traced(ctx, session.db)}.Count(model, predicate)
 	if err == nil {
 		r.log.V(4).Info(
 			"count succeeded.",
@@ -208,6 +430,16 @@ func (r *Client) Count(model Model, predicate Predicate) (n int64, err error) {
 //
 // Begin a transaction.
 func (r *Client) Begin(labels ...string) (tx *Tx, error error) {
+	tx, error = r.BeginContext(context.Background(), labels...)
+	return
+}
+
+//
+// Begin a transaction with context.
+// The context is used to link (tracing) spans for statements
+// executed and events reported by the transaction. The reconcile
+// ID (when carried by the context) is included in the logs.
+func (r *Client) BeginContext(ctx context.Context, labels ...string) (tx *Tx, error error) {
 	mark := time.Now()
 	session := r.pool.Writer()
 	realTx, err := session.Begin()
@@ -228,10 +460,19 @@ func (r *Client) Begin(labels ...string) (tx *Tx, error error) {
 			tx:  realTx,
 			log: r.log,
 		},
-		started: time.Now(),
-		labels:  labels,
-		log:     r.log,
+		started:  time.Now(),
+		labels:   labels,
+		logger:   r.log,
+		log:      correlated(r.log, ctx),
+		ctx:      ctx,
+		open:     &r.open,
+		hooks:    &r.hooks,
+		limits:   &r.limits,
+		auditing: r.auditing,
+		blobs:    r.blobs,
+		counters: Counters{},
 	}
+	r.open.add(tx)
 
 	r.log.V(4).Info("tx begin.", "duration", time.Since(mark))
 
@@ -320,6 +561,10 @@ func (r *Client) Delete(model Model) (err error) {
 // Watch model events.
 func (r *Client) Watch(model Model, handler EventHandler) (w *Watch, err error) {
 	mark := time.Now()
+	err = validRedacted(model, handler.Options().Redacted)
+	if err != nil {
+		return
+	}
 	w, err = r.journal.Watch(model, handler)
 	if err != nil {
 		return
@@ -333,7 +578,7 @@ func (r *Client) Watch(model Model, handler EventHandler) (w *Watch, err error)
 	options := handler.Options()
 	var snapshot fb.Iterator
 	if options.Snapshot {
-		snapshot, err = r.Find(model, ListOptions{Detail: MaxDetail})
+		snapshot, err = r.watchSnapshot(model, options)
 		if err != nil {
 			return
 		}
@@ -365,6 +610,116 @@ func (r *Client) EndWatch(watch *Watch) {
 		Describe(watch.Model))
 }
 
+//
+// End watch by ID.
+// Returns NotFound when the watch does not exist.
+func (r *Client) EndWatchID(id uint64) (err error) {
+	watch, found := r.journal.find(id)
+	if !found {
+		err = liberr.Wrap(NotFound, "watch", id)
+		return
+	}
+
+	r.EndWatch(watch)
+
+	return
+}
+
+//
+// Reset all watches with the reason.
+// Handlers are instructed to re-synchronize (list).
+// Should be called after the DB content has been rebuilt
+// outside of the journal (for example: migration applied).
+func (r *Client) Relist(reason string) {
+	r.journal.Relist(reason)
+}
+
+//
+// Drain the journal.
+// Queued watch events are delivered and the watches ended.
+// Should be called before the DB is closed (for example: on
+// shutdown) so that events are not lost.
+func (r *Client) Drain(ctx context.Context) (err error) {
+	err = r.journal.Drain(ctx)
+	return
+}
+
+//
+// Health report.
+func (r *Client) Health() (h Health) {
+	h.Watches, h.Backlog, h.Stalled = r.journal.stats()
+	h.Discarded, h.Compacted = r.journal.retained()
+	h.Workers, _ = r.journal.dispatcher.stats()
+	if r.dm == nil {
+		h.Error = "not opened."
+		return
+	}
+	session := r.pool.Reader()
+	defer session.Return()
+	n := int64(0)
+	err := session.db.QueryRow("SELECT 1").Scan(&n)
+	if err != nil {
+		h.Error = err.Error()
+		return
+	}
+
+	h.Open = true
+
+	return
+}
+
+//
+// Register access hooks for a model kind.
+// Hooks are called (in order registered) for each
+// insert, update and (including cascaded) delete.
+func (r *Client) Hook(model Model, hooks Hooks) {
+	r.hooks.add(model, hooks)
+}
+
+//
+// Set the row limit for a model kind.
+// Enforced on insert according to the eviction policy.
+// A limit with Max = 0 removes the limit.
+func (r *Client) SetLimit(model Model, limit RowLimit) {
+	r.limits.set(model, limit)
+}
+
+//
+// Row limit reports.
+func (r *Client) Limits() []LimitReport {
+	return r.limits.reports()
+}
+
+//
+// Set the journal (watch) retention policy.
+// Limits the events queued for each watch.
+func (r *Client) SetRetention(policy Retention) {
+	r.journal.SetRetention(policy)
+}
+
+//
+// Set the max number of watch (event) delivery workers.
+// Events are delivered to many watches concurrently while
+// preserving the order for each. 0 = DispatchWorkers.
+// Limiting the workers permits a blocked handler to delay
+// delivery to other watches.
+func (r *Client) SetDispatchWorkers(n int) {
+	r.journal.SetWorkers(n)
+}
+
+//
+// Watch reports.
+func (r *Client) Watches() []WatchReport {
+	return r.journal.reports()
+}
+
+//
+// Open transaction reports.
+// Ordered by started (oldest first).
+func (r *Client) Transactions() []TxReport {
+	return r.open.reports()
+}
+
 //
 // Build the data model.
 func (r *Client) build() (err error) {
@@ -373,12 +728,25 @@ func (r *Client) build() (err error) {
 	if err != nil {
 		return err
 	}
+	_, r.auditing = r.dm.FindWith(&Audit{})
+	_, r.blobs = r.dm.FindWith(&BlobChunk{})
 	ddls, err := r.dm.DDL()
 	if err != nil {
 		return err
 	}
 	session := r.pool.Writer()
 	defer session.Return()
+	table := Table{session.db}
+	rebuilt := []*Definition{}
+	for _, md := range r.dm.Definitions() {
+		changed, err := table.searchChanged(md)
+		if err != nil {
+			return err
+		}
+		if changed {
+			rebuilt = append(rebuilt, md)
+		}
+	}
 	for _, ddl := range ddls {
 		_, err := session.db.Exec(ddl)
 		if err != nil {
@@ -394,6 +762,16 @@ func (r *Client) build() (err error) {
 				ddl)
 		}
 	}
+	for _, md := range rebuilt {
+		err := table.searchRebuild(md)
+		if err != nil {
+			return err
+		}
+		r.log.V(3).Info(
+			"search index rebuilt.",
+			"kind",
+			md.Kind)
+	}
 
 	return nil
 }
@@ -414,20 +792,76 @@ type Tx struct {
 	// DataModel.
 	dm *DataModel
 	// Logger.
+	// Correlated using the context.
 	log logr.Logger
+	// Logger (not correlated).
+	logger logr.Logger
 	// Started timestamp.
 	started time.Time
 	// Labels associated with the transaction.
 	labels []string
 	// Ended.
 	ended bool
+	// Context.
+	ctx context.Context
+	// Open transactions.
+	open *txSet
+	// Access hooks.
+	hooks *hookSet
+	// Row limits.
+	limits *limitSet
+	// Row counts (by kind) maintained by row limits.
+	rows map[string]int64
+	// Kinds (inserted) with rows to be evicted.
+	evicted map[string]Model
+	// Auditing enabled.
+	auditing bool
+	// Streamed blobs enabled.
+	blobs bool
+	// Dry-run mode.
+	dryRun bool
+	// Counters by kind.
+	counters Counters
+	// Number of staged events.
+	events int
+	// Functions called after commit.
+	committed []func()
+}
+
+//
+// Context.
+func (r *Tx) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+//
+// Set the context.
+// Used to link the transaction to an operation (such as a
+// reconcile) started after the transaction began. Spans for
+// statements executed and events staged after the context is
+// set are linked using the new context. The context may be
+// restored when the operation is done. Events are stamped
+// with the reconcile ID carried by the context when staged.
+func (r *Tx) SetContext(ctx context.Context) {
+	r.ctx = ctx
+	r.log = correlated(r.logger, ctx)
+}
+
+//
+// Traced DB transaction.
+func (r *Tx) db() DBTX {
+	return Traced(r.Context(), r.real)
 }
 
 //
 // Execute SQL.
 func (r *Tx) Execute(sql string) (result sql.Result, err error) {
 	mark := time.Now()
-	result, err = r.real.Exec(sql)
+	result, err = r.db().Exec(sql)
 	if err == nil {
 		r.log.V(4).Info(
 			"execute succeeded.",
@@ -444,7 +878,7 @@ func (r *Tx) Execute(sql string) (result sql.Result, err error) {
 // Get the model.
 func (r *Tx) Get(model Model) (err error) {
 	mark := time.Now()
-	err = Table{r.real}.Get(model)
+	err = Table{r.db()}.Get(model)
 	if err == nil {
 		r.log.V(4).Info(
 			"get succeeded.",
@@ -462,7 +896,7 @@ func (r *Tx) Get(model Model) (err error) {
 // The `list` must be: *[]Model.
 func (r *Tx) List(list interface{}, options ListOptions) (err error) {
 	mark := time.Now()
-	err = Table{r.real}.List(list, options)
+	err = Table{r.db()}.List(list, options)
 	if err == nil {
 		r.log.V(4).Info(
 			"list succeeded.",
@@ -479,7 +913,7 @@ func (r *Tx) List(list interface{}, options ListOptions) (err error) {
 // List models.
 func (r *Tx) Find(model interface{}, options ListOptions) (itr fb.Iterator, err error) {
 	mark := time.Now()
-	itr, err = Table{r.real}.Find(model, options)
+	itr, err = Table{r.db()}.Find(model, options)
 	if err == nil {
 		r.log.V(4).Info(
 			"iter succeeded",
@@ -492,11 +926,28 @@ func (r *Tx) Find(model interface{}, options ListOptions) (itr fb.Iterator, err
 	return
 }
 
+//
+// Iterate models.
+func (r *Tx) ForEach(model Model, options ListOptions, fn func(Model) error) (err error) {
+	mark := time.Now()
+	err = Table{r.db()}.ForEach(model, options, fn)
+	if err == nil {
+		r.log.V(4).Info(
+			"iterate succeeded.",
+			"options",
+			options,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
 //
 // Count models.
 func (r *Tx) Count(model Model, predicate Predicate) (n int64, err error) {
 	mark := time.Now()
-	n, err = Table{r.real}.Count(model, predicate)
+	n, err = Table{r.db()}.Count(model, predicate)
 	if err == nil {
 		r.log.V(4).Info(
 			"count succeeded.",
@@ -512,21 +963,42 @@ func (r *Tx) Count(model Model, predicate Predicate) (n int64, err error) {
 // Insert the model.
 func (r *Tx) Insert(model Model) (err error) {
 	mark := time.Now()
-	err = Table{r.real}.Insert(model)
+	err = r.hooks.run(r, model, beforeInsert)
 	if err != nil {
 		return
 	}
-	event := Event{
-		ID:     serial.next(1),
-		Labels: r.labels,
-		Action: Created,
-		Model:  model,
+	err = r.limits.enforce(r, model)
+	if err != nil {
+		return
+	}
+	err = Table{r.db()}.Insert(model)
+	if err != nil {
+		return
+	}
+	err = r.stage(
+		Event{
+			ID:     serial.next(1),
+			Labels: r.labels,
+			Action: Created,
+			Model:  model,
+		})
+	if err != nil {
+		return
 	}
-	event.append(r.staged)
 	err = r.labeler.Insert(model)
 	if err != nil {
 		return
 	}
+	err = r.audit(AuditInsert, nil, model)
+	if err != nil {
+		return
+	}
+	err = r.hooks.run(r, model, afterInsert)
+	if err != nil {
+		return
+	}
+
+	r.counters.kind(model).Inserted++
 
 	r.log.V(3).Info(
 		"insert succeeded.",
@@ -544,26 +1016,49 @@ func (r *Tx) Update(model Model, predicate ...Predicate) (err error) {
 	mark := time.Now()
 	current := model
 	current = Clone(model)
-	err = Table{r.real}.Get(current)
+	err = Table{r.db()}.Get(current)
 	if err != nil {
 		return
 	}
-	err = Table{r.real}.Update(model, predicate...)
+	err = r.hooks.run(r, model, beforeUpdate)
 	if err != nil {
 		return
 	}
-	event := Event{
-		ID:      serial.next(1),
-		Labels:  r.labels,
-		Action:  Updated,
-		Model:   current,
-		Updated: model,
+	err = Table{r.db()}.Update(model, predicate...)
+	if err != nil {
+		return
+	}
+	quiet, err := r.quiet(current, model)
+	if err != nil {
+		return
+	}
+	if !quiet {
+		err = r.stage(
+			Event{
+				ID:      serial.next(1),
+				Labels:  r.labels,
+				Action:  Updated,
+				Model:   current,
+				Updated: model,
+			})
+		if err != nil {
+			return
+		}
 	}
-	event.append(r.staged)
 	err = r.labeler.Replace(model)
 	if err != nil {
 		return
 	}
+	err = r.audit(AuditUpdate, current, model)
+	if err != nil {
+		return
+	}
+	err = r.hooks.run(r, model, afterUpdate)
+	if err != nil {
+		return
+	}
+
+	r.counters.kind(model).Updated++
 
 	r.log.V(3).Info(
 		"update succeeded.",
@@ -575,12 +1070,62 @@ func (r *Tx) Update(model Model, predicate ...Predicate) (err error) {
 	return
 }
 
+//
+// Stage the event (reported on commit).
+// The event is stamped with the reconcile ID carried
+// by the (current) transaction context.
+func (r *Tx) stage(event Event) (err error) {
+	event.reconcileID = logging.ReconcileIDOf(r.Context())
+	err = event.append(r.staged)
+	if err != nil {
+		return
+	}
+
+	r.events++
+
+	return
+}
+
+//
+// Determine whether the update changed only
+// fields tagged `quiet`.
+func (r *Tx) quiet(current, updated Model) (quiet bool, err error) {
+	mdA, err := Inspect(current)
+	if err != nil {
+		return
+	}
+	for _, f := range mdA.Fields {
+		if f.Quiet() {
+			quiet = true
+			break
+		}
+	}
+	if !quiet {
+		return
+	}
+	mdB, err := Inspect(updated)
+	if err != nil {
+		return
+	}
+	for i, f := range mdA.Fields {
+		if f.Quiet() || f.Virtual() {
+			continue
+		}
+		if !reflect.DeepEqual(f.Pull(), mdB.Fields[i].Pull()) {
+			quiet = false
+			break
+		}
+	}
+
+	return
+}
+
 //
 // Delete (cascading) of the model.
 func (r *Tx) Delete(model Model) (err error) {
-	err = Table{r.real}.Get(model)
+	err = Table{r.db()}.Get(model)
 	if err != nil {
-		if errors.As(err, &NotFound) {
+		if errors.Is(err, NotFound) {
 			return
 		}
 		return
@@ -597,6 +1142,10 @@ func (r *Tx) Delete(model Model) (err error) {
 				return
 			}
 		} else {
+			err = fb.ErrOf(cascaded)
+			if err != nil {
+				return
+			}
 			break
 		}
 	}
@@ -612,15 +1161,28 @@ func (r *Tx) Delete(model Model) (err error) {
 // Commit a transaction.
 // Staged changes are committed in the DB.
 // The transaction is ended and the session returned.
+// In dry-run mode, the transaction is rolled back.
 func (r *Tx) Commit() (err error) {
 	if r.ended {
 		return
 	}
+	if r.dryRun {
+		err = r.End()
+		return
+	}
+	err = r.limits.evict(r)
+	if err != nil {
+		return
+	}
 	r.ended = true
 	defer func() {
 		r.session.Return()
+		r.open.remove(r)
 		if err == nil {
 			r.report()
+			for _, fn := range r.committed {
+				fn()
+			}
 		}
 	}()
 	mark := time.Now()
@@ -639,6 +1201,14 @@ func (r *Tx) Commit() (err error) {
 	return
 }
 
+//
+// Register a function to be called after the
+// transaction has been committed (and events reported).
+// Not called when the transaction is ended (rolled back).
+func (r *Tx) OnCommit(fn func()) {
+	r.committed = append(r.committed, fn)
+}
+
 //
 // End a transaction.
 // Staged changes are discarded.
@@ -650,7 +1220,9 @@ func (r *Tx) End() (err error) {
 	r.ended = true
 	defer func() {
 		r.session.Return()
+		r.open.remove(r)
 		r.staged = fb.NewList()
+		r.events = 0
 	}()
 	mark := time.Now()
 	err = r.real.Rollback()
@@ -674,24 +1246,46 @@ func (r *Tx) End() (err error) {
 // The model must be complete (fetched from the DB).
 func (r *Tx) delete(model Model) (err error) {
 	mark := time.Now()
-	err = Table{r.real}.Delete(model)
+	err = r.hooks.run(r, model, beforeDelete)
+	if err != nil {
+		return
+	}
+	err = Table{r.db()}.Delete(model)
 	if err != nil {
-		if errors.As(err, &NotFound) {
+		if errors.Is(err, NotFound) {
 			err = nil
 		}
 		return
 	}
-	event := Event{
-		ID:     serial.next(1),
-		Labels: r.labels,
-		Action: Deleted,
-		Model:  model,
+	r.limits.deleted(r, model)
+	err = r.stage(
+		Event{
+			ID:     serial.next(1),
+			Labels: r.labels,
+			Action: Deleted,
+			Model:  model,
+		})
+	if err != nil {
+		return
 	}
-	event.append(r.staged)
 	err = r.labeler.Delete(model)
 	if err != nil {
 		return
 	}
+	err = r.deleteAllBlobs(model)
+	if err != nil {
+		return
+	}
+	err = r.audit(AuditDelete, model, nil)
+	if err != nil {
+		return
+	}
+	err = r.hooks.run(r, model, afterDelete)
+	if err != nil {
+		return
+	}
+
+	r.counters.kind(model).Deleted++
 
 	r.log.V(3).Info(
 		"delete succeeded.",
@@ -709,8 +1303,69 @@ func (r *Tx) report() {
 	if r.staged.Len() == 0 {
 		return
 	}
-	r.journal.Report(r.staged)
+	ctx, span := tracing.Start(
+		r.Context(),
+		"model.watch.dispatch",
+		tracing.Count,
+		r.staged.Len())
+	defer span.End()
+	r.journal.report(ctx, r.staged, r.events)
 	r.staged = fb.NewList()
+	r.events = 0
+}
+
+//
+// Set of open transactions.
+type txSet struct {
+	// Content.
+	content map[*Tx]bool
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Add a transaction.
+func (r *txSet) add(tx *Tx) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[*Tx]bool{}
+	}
+	r.content[tx] = true
+}
+
+//
+// Remove a transaction.
+func (r *txSet) remove(tx *Tx) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.content, tx)
+}
+
+//
+// Build the reports.
+func (r *txSet) reports() (list []TxReport) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list = []TxReport{}
+	for tx := range r.content {
+		list = append(
+			list,
+			TxReport{
+				Labels:  tx.labels,
+				Started: tx.started,
+				Age:     time.Since(tx.started).String(),
+				DryRun:  tx.dryRun,
+			})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Started.Before(list[j].Started)
+	})
+
+	return
 }
 
 //
@@ -810,3 +1465,14 @@ func (r *Labeler) Replace(model Model) (err error) {
 
 	return
 }
+
+//
+// Get the logger with the reconcile ID
+// (when carried by the context).
+func correlated(log logr.Logger, ctx context.Context) logr.Logger {
+	if id := logging.ReconcileIDOf(ctx); id != "" {
+		log = log.WithValues(logging.ReconcileID, id)
+	}
+
+	return log
+}
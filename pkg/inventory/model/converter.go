@@ -0,0 +1,518 @@
+package model
+
+import (
+	"encoding"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+//
+// Regex used for `convert=name` tags.
+var ConvertRegex = regexp.MustCompile(`^(convert)=(.+)$`)
+
+//
+// Column (value) converter.
+// Converts between a (custom) Go type and the column
+// representation so that types such as IP addresses,
+// quantities and enums may be stored (and compared by
+// predicates) without being json encoded.
+type Converter interface {
+	// Column (SQL) type: TEXT|INTEGER|BLOB.
+	Column() string
+	// Encode the (field) value as the column value.
+	// Must return: string (TEXT), int64 (INTEGER)
+	// or []byte (BLOB).
+	Encode(value interface{}) (column interface{}, err error)
+	// Decode the column value into the (field) value.
+	// The `value` is a pointer to a new value of the
+	// field (element) type.
+	Decode(column interface{}, value interface{}) (err error)
+}
+
+//
+// Converter registry.
+// Converters keyed by Go type and by name. Fields of a
+// registered type (or pointer to it) use the converter
+// unless tagged `convert=name`.
+type ConverterRegistry struct {
+	// Converters by type.
+	byType map[reflect.Type]Converter
+	// Converters by name.
+	byName map[string]Converter
+	// Protect the maps.
+	mutex sync.RWMutex
+}
+
+//
+// Register a converter for the type of `object`.
+// Pointers are registered by element type.
+func (r *ConverterRegistry) Register(object interface{}, converter Converter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.byType == nil {
+		r.byType = map[reflect.Type]Converter{}
+	}
+	t := reflect.TypeOf(object)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	r.byType[t] = converter
+
+	log.V(3).Info(
+		"converter registered.",
+		"type",
+		t.String())
+}
+
+//
+// Register a named converter.
+// Referenced by fields tagged `convert=name`.
+func (r *ConverterRegistry) RegisterNamed(name string, converter Converter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.byName == nil {
+		r.byName = map[string]Converter{}
+	}
+	r.byName[name] = converter
+
+	log.V(3).Info(
+		"converter registered.",
+		"name",
+		name)
+}
+
+//
+// Find the converter for a type.
+// Pointers are matched by element type.
+func (r *ConverterRegistry) Find(t reflect.Type) (converter Converter, found bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	converter, found = r.byType[t]
+	return
+}
+
+//
+// Find a named converter.
+func (r *ConverterRegistry) Named(name string) (converter Converter, found bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	converter, found = r.byName[name]
+	return
+}
+
+//
+// The (global) converter registry.
+var Converters = &ConverterRegistry{}
+
+//
+// Register a converter for the type of `object`.
+func RegisterConverter(object interface{}, converter Converter) {
+	Converters.Register(object, converter)
+}
+
+//
+// Text converter.
+// Stores types implementing encoding.TextMarshaler (and
+// encoding.TextUnmarshaler) as TEXT. Example:
+//   model.RegisterConverter(net.IP{}, &model.TextConverter{})
+type TextConverter struct {
+}
+
+//
+// Column (SQL) type.
+func (r *TextConverter) Column() string {
+	return "TEXT"
+}
+
+//
+// Encode the value as text.
+func (r *TextConverter) Encode(value interface{}) (column interface{}, err error) {
+	m, cast := addressable(value).(encoding.TextMarshaler)
+	if !cast {
+		err = liberr.Wrap(
+			ConverterErr,
+			"type",
+			fmt.Sprintf("%T", value))
+		return
+	}
+	b, err := m.MarshalText()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	column = string(b)
+	return
+}
+
+//
+// Decode the value from text.
+func (r *TextConverter) Decode(column interface{}, value interface{}) (err error) {
+	m, cast := value.(encoding.TextUnmarshaler)
+	if !cast {
+		err = liberr.Wrap(
+			ConverterErr,
+			"type",
+			fmt.Sprintf("%T", value))
+		return
+	}
+	s, _ := column.(string)
+	err = m.UnmarshalText([]byte(s))
+	if err != nil {
+		err = liberr.Wrap(err)
+	}
+
+	return
+}
+
+//
+// String converter.
+// Stores types implementing fmt.Stringer as TEXT and
+// decoded using the Parse function. Example:
+//   model.RegisterConverter(
+//       resource.Quantity{},
+//       &model.StringConverter{
+//           Parse: func(s string) (interface{}, error) {
+//               return resource.ParseQuantity(s)
+//           },
+//       })
+type StringConverter struct {
+	// Parse the (string) column value.
+	// Must return a value of the field (element) type.
+	Parse func(s string) (interface{}, error)
+}
+
+//
+// Column (SQL) type.
+func (r *StringConverter) Column() string {
+	return "TEXT"
+}
+
+//
+// Encode the value as the string.
+func (r *StringConverter) Encode(value interface{}) (column interface{}, err error) {
+	s, cast := addressable(value).(fmt.Stringer)
+	if !cast {
+		err = liberr.Wrap(
+			ConverterErr,
+			"type",
+			fmt.Sprintf("%T", value))
+		return
+	}
+	column = s.String()
+	return
+}
+
+//
+// Decode the string using the Parse function.
+// The empty string is decoded as the zero value.
+func (r *StringConverter) Decode(column interface{}, value interface{}) (err error) {
+	s, _ := column.(string)
+	if s == "" {
+		return
+	}
+	parsed, err := r.Parse(s)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	v := reflect.Indirect(reflect.ValueOf(value))
+	pv := reflect.ValueOf(parsed)
+	if !pv.IsValid() || pv.Type() != v.Type() {
+		err = liberr.Wrap(
+			ConverterErr,
+			"type",
+			fmt.Sprintf("%T", parsed))
+		return
+	}
+	v.Set(pv)
+	return
+}
+
+//
+// Enum converter.
+// Stores (int) enumerated values as TEXT names. The
+// value is the index of the name. Example:
+//   model.RegisterConverter(Color(0), model.EnumConverter{"red", "green"})
+type EnumConverter []string
+
+//
+// Column (SQL) type.
+func (r EnumConverter) Column() string {
+	return "TEXT"
+}
+
+//
+// Encode the value as the name.
+func (r EnumConverter) Encode(value interface{}) (column interface{}, err error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		n := v.Int()
+		if n >= 0 && n < int64(len(r)) {
+			column = r[n]
+			return
+		}
+	}
+	err = liberr.Wrap(
+		ConverterErr,
+		"value",
+		fmt.Sprint(value))
+	return
+}
+
+//
+// Decode the name into the value.
+// The empty name is decoded as the zero value.
+func (r EnumConverter) Decode(column interface{}, value interface{}) (err error) {
+	s, _ := column.(string)
+	if s == "" {
+		return
+	}
+	v := reflect.Indirect(reflect.ValueOf(value))
+	for n, name := range r {
+		if name == s {
+			v.SetInt(int64(n))
+			return
+		}
+	}
+	err = liberr.Wrap(
+		ConverterErr,
+		"value",
+		s)
+	return
+}
+
+//
+// Get the field converter.
+// Resolved by Inspect().
+// Returns nil when the field is not converted.
+func (f *Field) Converter() (converter Converter) {
+	converter = f.converter
+	return
+}
+
+//
+// Resolve the field converter.
+// Format: convert=name.
+func (f *Field) resolveConverter() (converter Converter) {
+	for _, opt := range f.options() {
+		m := ConvertRegex.FindStringSubmatch(opt)
+		if len(m) == 3 {
+			converter, _ = Converters.Named(m[2])
+			return
+		}
+	}
+	converter, _ = Converters.Find(f.Value.Type())
+
+	return
+}
+
+//
+// Get whether the field is converted.
+func (f *Field) Converted() bool {
+	return f.Converter() != nil
+}
+
+//
+// Validate converted field values.
+// Returns ConverterErr when a value cannot be encoded.
+// Null (nil pointer) values are not validated.
+func (r *Definition) validateConverted() (err error) {
+	for _, f := range r.Fields {
+		converter := f.Converter()
+		if converter == nil {
+			continue
+		}
+		if f.Value.Kind() == reflect.Ptr && f.Value.IsNil() {
+			continue
+		}
+		value := reflect.Indirect(*f.Value)
+		_, eErr := converter.Encode(value.Interface())
+		if eErr != nil {
+			err = liberr.Wrap(
+				ConverterErr,
+				"field",
+				f.Name,
+				"reason",
+				eErr.Error())
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Validate the field converter.
+func (f *Field) validConverter() (err error) {
+	converter := f.Converter()
+	if converter == nil {
+		if f.hasConvertOpt() {
+			err = liberr.Wrap(ConverterErr, "field", f.Name)
+		}
+		return
+	}
+	switch converter.Column() {
+	case "TEXT", "INTEGER", "BLOB":
+	default:
+		err = liberr.Wrap(
+			ConverterErr,
+			"field",
+			f.Name,
+			"column",
+			converter.Column())
+	}
+
+	return
+}
+
+//
+// Get whether the field is tagged `convert=name`.
+func (f *Field) hasConvertOpt() bool {
+	for _, opt := range f.options() {
+		if ConvertRegex.MatchString(opt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Pull the converted value into the `staging` fields.
+// Values that cannot be encoded are staged as the
+// (column) zero value. See: validateConverted().
+func (f *Field) pullConverted(converter Converter, value reflect.Value) interface{} {
+	column, err := converter.Encode(value.Interface())
+	if err != nil {
+		column = nil
+	}
+	switch converter.Column() {
+	case "INTEGER":
+		f.int, _ = column.(int64)
+		return f.int
+	case "BLOB":
+		f.bytes, _ = column.([]byte)
+		if f.bytes == nil {
+			f.bytes = []byte{}
+		}
+		return f.bytes
+	default:
+		f.string, _ = column.(string)
+		return f.string
+	}
+}
+
+//
+// Push the converted value from the `staging` fields.
+// Returns DecodeErr when the column value cannot
+// be decoded and StrictDecode is set.
+func (f *Field) pushConverted(converter Converter, value reflect.Value) (err error) {
+	var column interface{}
+	switch converter.Column() {
+	case "INTEGER":
+		column = f.int
+	case "BLOB":
+		column = f.bytes
+	default:
+		column = f.string
+	}
+	tv := reflect.New(value.Type())
+	dErr := converter.Decode(column, tv.Interface())
+	if dErr != nil {
+		err = liberr.Wrap(
+			DecodeErr,
+			"field",
+			f.Name,
+			"reason",
+			dErr.Error())
+		err = f.tolerate(err)
+		return
+	}
+	value.Set(tv.Elem())
+	return
+}
+
+//
+// Convert the specified `object` to a (column) value
+// appropriate for the (converted) field.
+// Accepts the field type (or pointer) or the column value.
+func (f *Field) convertedValue(converter Converter, object interface{}) (value interface{}, err error) {
+	val := reflect.ValueOf(object)
+	if val.Kind() == reflect.Ptr && f.convertible(val.Elem()) {
+		if val.IsNil() {
+			err = liberr.Wrap(PredicateValueErr, "field", f.Name)
+			return
+		}
+		val = val.Elem()
+	}
+	if f.convertible(val) {
+		value, err = converter.Encode(val.Interface())
+		if err != nil {
+			err = liberr.Wrap(PredicateValueErr, "field", f.Name)
+		}
+		return
+	}
+	switch v := object.(type) {
+	case string:
+		if converter.Column() == "TEXT" {
+			value = v
+			return
+		}
+	case int:
+		if converter.Column() == "INTEGER" {
+			value = int64(v)
+			return
+		}
+	case int64:
+		if converter.Column() == "INTEGER" {
+			value = v
+			return
+		}
+	case []byte:
+		if converter.Column() == "BLOB" {
+			value = v
+			return
+		}
+	}
+	err = liberr.Wrap(PredicateValueErr, "field", f.Name)
+	return
+}
+
+//
+// Get whether the value is of the (converted) field type.
+func (f *Field) convertible(value reflect.Value) bool {
+	if !value.IsValid() || !f.Converted() {
+		return false
+	}
+	t := f.Value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return value.Type() == t
+}
+
+//
+// Get an (addressable) pointer to a copy of the value so
+// that methods with pointer receivers are included.
+func addressable(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		return value
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+
+	return p.Interface()
+}
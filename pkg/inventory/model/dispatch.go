@@ -0,0 +1,147 @@
+package model
+
+import (
+	"sync"
+)
+
+//
+// Watch dispatcher defaults.
+var (
+	// Max number of (concurrent) delivery workers.
+	// 0 = not limited (a worker for each ready watch).
+	DispatchWorkers = 0
+	// Max number of events delivered to a watch before
+	// the worker moves on to the next (ready) watch.
+	DispatchQuantum = 100
+)
+
+//
+// Watch (event) dispatcher.
+// Delivers events to watches using a pool of workers rather
+// than a goroutine for each watch. A watch is scheduled (made
+// ready) when events are queued and is delivered by only one
+// worker at a time so that the order of events delivered to
+// each handler is preserved. Workers are started as needed
+// (up to the max) and exit when no watches are ready. A worker
+// delivers at most a quantum of events before the watch is
+// rescheduled so that a busy watch does not starve others.
+// Note: a blocked handler (Example: a typed watch with events
+// not being received) holds a worker. When the number of workers
+// is limited, delivery to other watches may be delayed.
+type dispatcher struct {
+	// Max number of workers.
+	// 0 = DispatchWorkers.
+	workers int
+	// Number of running workers.
+	running int
+	// Ready (scheduled) watches.
+	ready []*Watch
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Set the max number of workers.
+func (d *dispatcher) setWorkers(n int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.workers = n
+	d.spawn(len(d.ready))
+}
+
+//
+// Start delivery to the watch.
+func (d *dispatcher) start(w *Watch) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	w.started = true
+	d.add(w)
+}
+
+//
+// The watch has been started.
+func (d *dispatcher) started(w *Watch) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return w.started
+}
+
+//
+// Schedule delivery to the watch.
+// Ignored when the watch has not been started.
+func (d *dispatcher) schedule(w *Watch) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if w.started {
+		d.add(w)
+	}
+}
+
+//
+// Dispatcher statistics.
+// Returns the number of running workers and ready watches.
+func (d *dispatcher) stats() (running, ready int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.running, len(d.ready)
+}
+
+//
+// Add the watch to the ready list.
+// The watch is flagged as signaled so that a worker currently
+// delivering to the watch will reschedule it.
+func (d *dispatcher) add(w *Watch) {
+	w.signaled = true
+	if w.scheduled {
+		return
+	}
+	w.scheduled = true
+	d.ready = append(d.ready, w)
+	d.spawn(1)
+}
+
+//
+// Start (up to n) workers.
+// Running workers are busy delivering (idle workers exit)
+// so a worker is started for each watch made ready until
+// the max has been reached.
+func (d *dispatcher) spawn(n int) {
+	max := d.workers
+	if max < 1 {
+		max = DispatchWorkers
+	}
+	for i := 0; i < n && (max < 1 || d.running < max); i++ {
+		d.running++
+		go d.work()
+	}
+}
+
+//
+// Worker (main) loop.
+// Exits when no watches are ready.
+func (d *dispatcher) work() {
+	for {
+		d.mutex.Lock()
+		if len(d.ready) == 0 {
+			d.running--
+			d.mutex.Unlock()
+			return
+		}
+		w := d.ready[0]
+		d.ready = d.ready[1:]
+		w.signaled = false
+		d.mutex.Unlock()
+		more := w.deliver(DispatchQuantum)
+		d.mutex.Lock()
+		switch {
+		case !w.Alive():
+			w.started = false
+			w.scheduled = false
+		case more || w.signaled:
+			d.ready = append(d.ready, w)
+		default:
+			w.scheduled = false
+		}
+		d.mutex.Unlock()
+	}
+}
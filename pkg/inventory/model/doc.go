@@ -17,6 +17,7 @@
 //         +cascade = cascade delete.
 //   `sql:"unique(G)"`
 //       Unique index. `G` = unique-together fields.
+//       Violations are reported as AlreadyExists.
 //   `sql:"index(G)"`
 //       Non-unique index. `G` = unique-together fields.
 //   `sql:"const"`
@@ -27,6 +28,57 @@
 //       The field detail level.  n = level number.
 //   `sql:incremented`
 //       The field is auto-incremented.
+//   `sql:"notnull"`
+//       The (pointer) field is not nullable.
+//   `sql:"default=V"`
+//       The column default. `V` = value. A value containing
+//       commas is quoted: default='a,b' ('' = quote).
+//   `sql:"enum(a,b,c)"`
+//       The (string or int) field value must be one of the
+//       enumerated values. Violations are reported as EnumError.
+//   `sql:"unix"`
+//       The time.Time field is stored as unix nanoseconds.
+//   `sql:"blob"`
+//       The []byte field is stored as BLOB.
+//   `sql:"max=N"`
+//       The []byte (blob) field size limit. Default: MaxBlobSize.
+//   `sql:"quiet"`
+//       Updates that change only quiet fields are not reported
+//       (as events) to watches.
+//   `sql:"search"`
+//       The (string) field is full text (FTS) indexed.
+//       See: DB.SearchAll().
+//   `sql:"collate=C"`
+//       The (string) column collation used by comparisons and
+//       indexes. `C` = binary|nocase|rtrim. Predicates may
+//       override the collation. See: NoCase(), Collate().
+//   `sql:"convert=name"`
+//       The field is stored using the named converter.
+//       See: Converters.RegisterNamed().
+//
+// Fields of type time.Time (and *time.Time) are columns only when
+// (explicitly) tagged with `sql`. They are stored in UTC as fixed
+// width RFC3339 text (or unix nanoseconds) and scanned in the
+// TimeLocation. Fields of type time.Duration are stored
+// as nanoseconds. Both support (=,<,>) predicates.
+//
+// Fields of type []byte are (json) encoded unless tagged as `blob`.
+// Larger content may be streamed (in chunks) as named blobs
+// associated with a model using PutBlob() and GetBlob() when the
+// BlobChunk is included in the models. Blobs are deleted with
+// the model.
+//
+// Fields of a type with a registered converter (and pointers
+// to it) are stored using the converter column representation
+// and predicates accept either the type or the column value.
+// Types implementing encoding.TextMarshaler (net.IP) may use a
+// TextConverter. Converters are not registered by default.
+// Values that cannot be encoded fail the write (ConverterErr).
+// See: RegisterConverter().
+//
+// Columns are NOT NULL except for pointer fields (*string, *bool,
+// *int..) which are nullable and scanned as nil when NULL. A nil
+// (unset) pointer field is omitted on insert so the default applies.
 //
 // Each struct must implement the `Model` interface.
 // Basic CRUD operations may be performed on each model using
@@ -113,6 +165,17 @@
 //           },
 //       })
 //
+// Predicates may be nested and negated.
+// List persons named "Elmer" or "Daffy" not in their teens.
+//   err := DB.List(
+//       &persons,
+//       ListOptions{
+//           Predicate: And(
+//               In("Name", "Elmer", "Daffy"),
+//               Not(Between("Age", 13, 19)),
+//           },
+//       })
+//
 // Transactions.
 //
 // Explicit:
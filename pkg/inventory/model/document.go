@@ -0,0 +1,470 @@
+package model
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//
+// Errors.
+var (
+	// The document kind is not registered.
+	DocumentKindErr = errors.New("document kind not registered")
+	// The document is not valid (schema).
+	SchemaErr = errors.New("document not valid")
+)
+
+//
+// Register the (JSON) content types so that documents
+// may be (gob) encoded as event (filebacked) content.
+func init() {
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+//
+// Document (dynamic) model.
+// JSON documents of kinds registered at runtime (by name)
+// stored in a generic table. Lets plugins extend the inventory
+// without (compiled) model structs. Documents are opt-in and
+// enabled by including `&Document{}` in the models passed to New().
+type Document struct {
+	PK string `sql:"pk(kind;id)"`
+	// Document kind.
+	Kind string `sql:"key"`
+	// Document ID (unique within the kind).
+	ID string `sql:"key"`
+	// Revision.
+	Revision int `sql:"incremented"`
+	// Content.
+	Content map[string]interface{} `sql:""`
+}
+
+func (m *Document) Pk() string {
+	return m.PK
+}
+
+func (m *Document) String() string {
+	return m.Kind + "/" + m.ID
+}
+
+func (m *Document) Labels() Labels {
+	return nil
+}
+
+//
+// Document (JSON) schema.
+// Supported keywords: type, properties, required, items,
+// enum and additionalProperties (bool).
+type Schema struct {
+	// Type: object|array|string|number|integer|boolean|null.
+	Type string `json:"type,omitempty"`
+	// Object properties.
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// Required (object) properties.
+	Required []string `json:"required,omitempty"`
+	// Array items.
+	Items *Schema `json:"items,omitempty"`
+	// Enumerated values.
+	Enum []interface{} `json:"enum,omitempty"`
+	// Properties not defined are permitted.
+	// Default: true.
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+}
+
+//
+// Validate the (JSON decoded) value.
+func (r *Schema) Validate(value interface{}) (err error) {
+	err = r.validate("$", value)
+	return
+}
+
+//
+// Validate the value at the (JSON) path.
+func (r *Schema) validate(path string, value interface{}) (err error) {
+	invalid := func(reason string) error {
+		return liberr.Wrap(
+			SchemaErr,
+			"path",
+			path,
+			"reason",
+			reason)
+	}
+	if len(r.Enum) > 0 {
+		matched := false
+		for _, v := range r.Enum {
+			if fmt.Sprint(v) == fmt.Sprint(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			err = invalid("not enumerated.")
+			return
+		}
+	}
+	switch r.Type {
+	case "":
+	case "object":
+		object, cast := value.(map[string]interface{})
+		if !cast {
+			err = invalid("must be object.")
+			return
+		}
+		for _, name := range r.Required {
+			if _, found := object[name]; !found {
+				err = invalid("property: " + name + " required.")
+				return
+			}
+		}
+		names := []string{}
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, found := r.Properties[name]
+			if !found {
+				if r.AdditionalProperties != nil && !*r.AdditionalProperties {
+					err = invalid("property: " + name + " not defined.")
+					return
+				}
+				continue
+			}
+			err = property.validate(path+"."+name, object[name])
+			if err != nil {
+				return
+			}
+		}
+	case "array":
+		array, cast := value.([]interface{})
+		if !cast {
+			err = invalid("must be array.")
+			return
+		}
+		if r.Items != nil {
+			for i := range array {
+				err = r.Items.validate(fmt.Sprintf("%s[%d]", path, i), array[i])
+				if err != nil {
+					return
+				}
+			}
+		}
+	case "string":
+		if _, cast := value.(string); !cast {
+			err = invalid("must be string.")
+		}
+	case "number":
+		if _, cast := value.(float64); !cast {
+			err = invalid("must be number.")
+		}
+	case "integer":
+		n, cast := value.(float64)
+		if !cast || n != float64(int64(n)) {
+			err = invalid("must be integer.")
+		}
+	case "boolean":
+		if _, cast := value.(bool); !cast {
+			err = invalid("must be boolean.")
+		}
+	case "null":
+		if value != nil {
+			err = invalid("must be null.")
+		}
+	default:
+		err = invalid("type: " + r.Type + " not supported.")
+	}
+
+	return
+}
+
+//
+// Document kind.
+type DocumentKind struct {
+	// Kind name.
+	Name string
+	// Schema (optional).
+	// Schemaless when nil.
+	Schema *Schema
+}
+
+//
+// Dynamic documents.
+// Provides CRUD, List and Watch for documents by (registered)
+// kind. Example:
+//   docs := &model.Documents{DB: db}
+//   err := docs.Register("Plugin", schema)
+//   doc, err := docs.Create("Plugin", "p1", content)
+type Documents struct {
+	// DB.
+	DB DB
+	// Registered kinds.
+	kinds map[string]*DocumentKind
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Register a document kind by name.
+// The (optional) JSON schema is used to validate documents
+// when created and updated. Schemaless when empty.
+func (r *Documents) Register(name string, schema []byte) (err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		err = liberr.New("document kind name required.")
+		return
+	}
+	kind := &DocumentKind{Name: name}
+	if len(schema) > 0 {
+		kind.Schema = &Schema{}
+		err = json.Unmarshal(schema, kind.Schema)
+		if err != nil {
+			err = liberr.Wrap(
+				err,
+				"kind",
+				name)
+			return
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.kinds == nil {
+		r.kinds = map[string]*DocumentKind{}
+	}
+	r.kinds[name] = kind
+
+	log.V(3).Info(
+		"document kind registered.",
+		"kind",
+		name,
+		"schema",
+		kind.Schema != nil)
+
+	return
+}
+
+//
+// Registered document kinds (ordered by name).
+func (r *Documents) Kinds() (list []DocumentKind) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	list = []DocumentKind{}
+	for _, kind := range r.kinds {
+		list = append(list, *kind)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return
+}
+
+//
+// Create a document.
+func (r *Documents) Create(kind, id string, content map[string]interface{}) (doc *Document, err error) {
+	doc = &Document{
+		Kind:    kind,
+		ID:      id,
+		Content: content,
+	}
+	err = r.validate(doc)
+	if err != nil {
+		doc = nil
+		return
+	}
+	err = r.DB.Insert(doc)
+	if err != nil {
+		doc = nil
+	}
+
+	return
+}
+
+//
+// Get a document.
+// Returns NotFound when not found.
+func (r *Documents) Get(kind, id string) (doc *Document, err error) {
+	_, err = r.kind(kind)
+	if err != nil {
+		return
+	}
+	doc = &Document{
+		Kind: kind,
+		ID:   id,
+	}
+	err = r.DB.Get(doc)
+	if err != nil {
+		doc = nil
+	}
+
+	return
+}
+
+//
+// Update a document.
+func (r *Documents) Update(doc *Document) (err error) {
+	err = r.validate(doc)
+	if err != nil {
+		return
+	}
+	err = r.DB.Update(doc)
+	return
+}
+
+//
+// Delete a document.
+func (r *Documents) Delete(kind, id string) (err error) {
+	_, err = r.kind(kind)
+	if err != nil {
+		return
+	}
+	err = r.DB.Delete(
+		&Document{
+			Kind: kind,
+			ID:   id,
+		})
+
+	return
+}
+
+//
+// List documents of the kind.
+// The options predicate (when specified) is
+// combined with the kind.
+func (r *Documents) List(kind string, options ListOptions) (list []Document, err error) {
+	_, err = r.kind(kind)
+	if err != nil {
+		return
+	}
+	predicate := Predicate(Eq("Kind", kind))
+	if options.Predicate != nil {
+		predicate = And(predicate, options.Predicate)
+	}
+	options.Predicate = predicate
+	if options.Detail == 0 {
+		options.Detail = MaxDetail
+	}
+	list = []Document{}
+	err = r.DB.List(&list, options)
+	return
+}
+
+//
+// Watch documents of the kind.
+// Only events for documents of the kind are
+// delivered to the handler.
+func (r *Documents) Watch(kind string, handler EventHandler) (w *Watch, err error) {
+	_, err = r.kind(kind)
+	if err != nil {
+		return
+	}
+	w, err = r.DB.Watch(
+		&Document{},
+		&documentHandler{
+			EventHandler: handler,
+			kind:         kind,
+		})
+
+	return
+}
+
+//
+// Get a registered kind.
+func (r *Documents) kind(name string) (kind *DocumentKind, err error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	kind, found := r.kinds[name]
+	if !found {
+		err = liberr.Wrap(
+			DocumentKindErr,
+			"kind",
+			name)
+	}
+
+	return
+}
+
+//
+// Validate the document using the kind schema.
+// The content is normalized (JSON) before validated.
+func (r *Documents) validate(doc *Document) (err error) {
+	kind, err := r.kind(doc.Kind)
+	if err != nil {
+		return
+	}
+	if doc.ID == "" {
+		err = liberr.Wrap(
+			SchemaErr,
+			"kind",
+			doc.Kind,
+			"reason",
+			"id required.")
+		return
+	}
+	if doc.Content == nil {
+		doc.Content = map[string]interface{}{}
+	}
+	b, err := json.Marshal(doc.Content)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	content := map[string]interface{}{}
+	err = json.Unmarshal(b, &content)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	if kind.Schema != nil {
+		err = kind.Schema.Validate(content)
+		if err != nil {
+			return
+		}
+	}
+
+	doc.Content = content
+
+	return
+}
+
+//
+// Document (kind) event handler.
+type documentHandler struct {
+	EventHandler
+	// Document kind.
+	kind string
+}
+
+//
+// Watch options.
+// The filter matches documents of the kind.
+func (r *documentHandler) Options() (options WatchOptions) {
+	options = r.EventHandler.Options()
+	filter := options.Filter
+	options.Filter = func(m Model) bool {
+		doc, cast := m.(*Document)
+		if !cast || doc.Kind != r.kind {
+			return false
+		}
+		if filter != nil {
+			return filter(m)
+		}
+
+		return true
+	}
+	if options.Snapshot {
+		predicate := Predicate(Eq("Kind", r.kind))
+		if options.Predicate != nil {
+			predicate = And(predicate, options.Predicate)
+		}
+		options.Predicate = predicate
+	}
+
+	return
+}
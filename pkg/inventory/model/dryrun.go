@@ -0,0 +1,101 @@
+package model
+
+import (
+	"github.com/konveyor/controller/pkg/ref"
+)
+
+//
+// Transaction counters for a model kind.
+type Counter struct {
+	// Number of models inserted.
+	Inserted int `json:"inserted"`
+	// Number of models updated.
+	Updated int `json:"updated"`
+	// Number of models deleted (including cascaded).
+	Deleted int `json:"deleted"`
+}
+
+//
+// Transaction counters by model kind.
+type Counters map[string]*Counter
+
+//
+// Counter for the model kind.
+func (r Counters) kind(model Model) (c *Counter) {
+	kind := ref.ToKind(model)
+	c, found := r[kind]
+	if !found {
+		c = &Counter{}
+		r[kind] = c
+	}
+
+	return
+}
+
+//
+// Total (all kinds).
+func (r Counters) Total() (total Counter) {
+	for _, c := range r {
+		total.Inserted += c.Inserted
+		total.Updated += c.Updated
+		total.Deleted += c.Deleted
+	}
+
+	return
+}
+
+//
+// Copy.
+func (r Counters) copy() (copied Counters) {
+	copied = Counters{}
+	for kind, c := range r {
+		cc := *c
+		copied[kind] = &cc
+	}
+
+	return
+}
+
+//
+// Enable dry-run mode.
+// Statements are executed (and validated) within the
+// transaction and counted but Commit() rolls back the
+// transaction and no events are reported.
+func (r *Tx) DryRun() {
+	r.dryRun = true
+}
+
+//
+// The transaction is in dry-run mode.
+func (r *Tx) IsDryRun() bool {
+	return r.dryRun
+}
+
+//
+// Counters (by kind) of models written by the transaction.
+func (r *Tx) Counters() Counters {
+	return r.counters.copy()
+}
+
+//
+// Dry-run.
+// The function is called with a dry-run transaction
+// and the counters are returned. Nothing is committed.
+func (r *Client) DryRun(fn func(*Tx) error, labels ...string) (counters Counters, err error) {
+	tx, err := r.Begin(labels...)
+	if err != nil {
+		return
+	}
+	tx.DryRun()
+	defer func() {
+		_ = tx.End()
+	}()
+	err = fn(tx)
+	if err != nil {
+		return
+	}
+
+	counters = tx.Counters()
+
+	return
+}
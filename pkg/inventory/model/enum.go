@@ -0,0 +1,166 @@
+package model
+
+import (
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//
+// Regex used for `enum(a,b,c)` tags.
+var EnumRegex = regexp.MustCompile(`(enum)(\()(.*)(\))`)
+
+//
+// Enum value not valid.
+// A model field tagged `enum(..)` does not contain one
+// of the enumerated values.
+// Matched using: errors.Is(err, EnumErr) or errors.As().
+type EnumError struct {
+	// Model kind.
+	Kind string
+	// Field name.
+	Field string
+	// The (invalid) value.
+	Value string
+	// Enumerated values.
+	Values []string
+}
+
+//
+// Enum value not valid (sentinel).
+var EnumErr = &EnumError{}
+
+//
+// Error description.
+func (e *EnumError) Error() string {
+	if e.Field == "" {
+		return "enum value not valid"
+	}
+
+	return fmt.Sprintf(
+		"%s.%s: '%s' must be one of: (%s)",
+		e.Kind,
+		e.Field,
+		e.Value,
+		strings.Join(e.Values, ","))
+}
+
+//
+// Supports errors.Is().
+func (e *EnumError) Is(target error) bool {
+	_, cast := target.(*EnumError)
+	return cast
+}
+
+//
+// Get the enumerated values.
+// Format: enum(a,b,c).
+func (f *Field) Enum() (values []string) {
+	for _, opt := range f.options() {
+		m := EnumRegex.FindStringSubmatch(opt)
+		if len(m) == 5 {
+			for _, v := range strings.Split(m[3], ",") {
+				values = append(values, strings.TrimSpace(v))
+			}
+			break
+		}
+	}
+
+	return
+}
+
+//
+// Enum CHECK constraint (column) DDL.
+// Returns "" when the field is not an enum.
+func (f *Field) enumSQL() (check string, err error) {
+	values := f.Enum()
+	if len(values) == 0 {
+		return
+	}
+	literals := []string{}
+	for _, v := range values {
+		switch f.kind() {
+		case reflect.String:
+			literals = append(literals, "'"+strings.ReplaceAll(v, "'", "''")+"'")
+		case reflect.Int,
+			reflect.Int8,
+			reflect.Int16,
+			reflect.Int32,
+			reflect.Int64:
+			n, pErr := strconv.ParseInt(v, 0, 64)
+			if pErr != nil {
+				err = liberr.Wrap(FieldTypeErr, "field", f.Name, "enum", v)
+				return
+			}
+			literals = append(literals, strconv.FormatInt(n, 10))
+		default:
+			err = liberr.Wrap(FieldTypeErr, "field", f.Name)
+			return
+		}
+	}
+
+	check = fmt.Sprintf(
+		"CHECK (%s IN (%s))",
+		f.Name,
+		strings.Join(literals, ","))
+
+	return
+}
+
+//
+// Validate enum field values.
+// Null (nil pointer) values are not validated.
+func (r *Definition) validateEnums() (err error) {
+	for _, f := range r.Fields {
+		values := f.Enum()
+		if len(values) == 0 {
+			continue
+		}
+		if f.Value.Kind() == reflect.Ptr && f.Value.IsNil() {
+			continue
+		}
+		v := reflect.Indirect(*f.Value)
+		value := ""
+		switch v.Kind() {
+		case reflect.String:
+			value = v.String()
+		default:
+			value = strconv.FormatInt(v.Int(), 10)
+		}
+		matched := false
+		for _, allowed := range values {
+			if allowed == value {
+				matched = true
+				break
+			}
+			if v.Kind() != reflect.String {
+				n, pErr := strconv.ParseInt(allowed, 0, 64)
+				if pErr == nil && n == v.Int() {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			err = liberr.Wrap(
+				&EnumError{
+					Kind:   r.Kind,
+					Field:  f.Name,
+					Value:  value,
+					Values: values,
+				},
+				"kind",
+				r.Kind,
+				"field",
+				f.Name,
+				"value",
+				value)
+			return
+		}
+	}
+
+	return
+}
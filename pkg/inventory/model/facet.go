@@ -0,0 +1,201 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"text/template"
+	"time"
+)
+
+//
+// Facet (group by) SQL.
+// One (UNION ALL) query for all of the fields.
+var FacetSQL = `
+{{ range $i,$f := .Fields -}}
+{{ if $i }}UNION ALL
+{{ end -}}
+SELECT
+{{ $i }},
+{{ $f.Name }},
+COUNT(*)
+FROM {{ $.Table }}
+{{ if $.Predicate -}}
+WHERE
+{{ $.Predicate.Expr }}
+{{ end -}}
+GROUP BY {{ $f.Name }}
+{{ end -}}
+ORDER BY 1, 3 DESC, 2
+;
+`
+
+//
+// Facet (distinct field) value.
+type Facet struct {
+	// Field value.
+	Value interface{} `json:"value"`
+	// Number of models with the value.
+	Count int64 `json:"count"`
+}
+
+//
+// Facets by field name.
+// Values are ordered by count (descending) then value.
+type Facets map[string][]Facet
+
+//
+// Facet template data.
+type facetTmplData struct {
+	// Table name.
+	Table string
+	// Fields.
+	Fields []*Field
+	// Predicate.
+	Predicate Predicate
+}
+
+//
+// Facets (distinct values with counts) of the model fields.
+// Qualified by the (optional) predicate. JSON encoded and
+// blob fields are not supported.
+func (t Table) Facets(model interface{}, fields []string, predicate Predicate) (facets Facets, err error) {
+	md, err := Inspect(model)
+	if err != nil {
+		return
+	}
+	facets = Facets{}
+	if len(fields) == 0 {
+		return
+	}
+	selected := []*Field{}
+	for _, name := range fields {
+		f := md.Field(name)
+		if f == nil {
+			err = liberr.New(
+				"field not found.",
+				"kind",
+				md.Kind,
+				"field",
+				name)
+			return
+		}
+		if f.Encoded() || f.Blob() {
+			err = liberr.New(
+				"field not supported.",
+				"kind",
+				md.Kind,
+				"field",
+				name)
+			return
+		}
+		selected = append(selected, f)
+		facets[f.Name] = []Facet{}
+	}
+	options := ListOptions{Predicate: predicate}
+	err = options.Build(md)
+	if err != nil {
+		return
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(FacetSQL)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		facetTmplData{
+			Table:     md.Kind,
+			Fields:    selected,
+			Predicate: predicate,
+		})
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	stmt := bfr.String()
+	params := options.Params()
+	cursor, err := t.DB.Query(stmt, params...)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"sql",
+			stmt,
+			"params",
+			params)
+		return
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+	for cursor.Next() {
+		var index int
+		facet := Facet{}
+		err = cursor.Scan(&index, &facet.Value, &facet.Count)
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+		if b, cast := facet.Value.([]byte); cast {
+			facet.Value = string(b)
+		}
+		name := selected[index].Name
+		facets[name] = append(facets[name], facet)
+	}
+	err = cursor.Err()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+
+	log.V(5).Info(
+		"table: facets succeeded.",
+		"sql",
+		stmt,
+		"params",
+		params)
+
+	return
+}
+
+//
+// Facets (distinct values with counts) of the model fields.
+func (r *Client) Facets(model Model, fields []string, predicate Predicate) (facets Facets, err error) {
+	session := r.pool.Reader()
+	defer session.Return()
+	mark := time.Now()
+	facets, err = Table{Traced(context.Background(), session.db)}.Facets(model, fields, predicate)
+	if err == nil {
+		r.log.V(4).Info(
+			"facets succeeded.",
+			"fields",
+			fields,
+			"predicate",
+			predicate,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
+//
+// Facets (distinct values with counts) of the model fields.
+func (r *Tx) Facets(model Model, fields []string, predicate Predicate) (facets Facets, err error) {
+	mark := time.Now()
+	facets, err = Table{r.db()}.Facets(model, fields, predicate)
+	if err == nil {
+		r.log.V(4).Info(
+			"facets succeeded.",
+			"fields",
+			fields,
+			"predicate",
+			predicate,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -27,6 +28,17 @@ const (
 //   }
 var DefaultDetail = 0
 
+//
+// Max size of (json) encoded field values decoded when
+// scanned from the DB. Zero = not limited.
+var MaxEncodedSize int64 = 64 * 1024 * 1024
+
+//
+// Fail (Get, List) when a stored field value cannot be
+// decoded (DecodeErr). Otherwise, the error is logged and
+// the field is set to the zero value.
+var StrictDecode = false
+
 //
 // Regex used for `pk(fields)` tags.
 var PkRegex = regexp.MustCompile(`(pk)((\()(.+)(\)))?`)
@@ -35,6 +47,10 @@ var PkRegex = regexp.MustCompile(`(pk)((\()(.+)(\)))?`)
 // Regex used for `unique(group)` tags.
 var UniqueRegex = regexp.MustCompile(`(unique)(\()(.+)(\))`)
 
+//
+// Regex used for `default=value` tags.
+var DefaultRegex = regexp.MustCompile(`(default)=(.*)`)
+
 //
 // Regex used for `index(group)` tags.
 var IndexRegex = regexp.MustCompile(`(index)(\()(.+)(\))`)
@@ -43,6 +59,21 @@ var IndexRegex = regexp.MustCompile(`(index)(\()(.+)(\))`)
 // Regex used for `fk(table)` tags.
 var FkRegex = regexp.MustCompile(`(fk)(\()(.+)(\))`)
 
+//
+// Regex used for `collate=name` tags.
+var CollateRegex = regexp.MustCompile(`^(collate)=(.+)$`)
+
+//
+// Collations.
+const (
+	// Exact (byte) comparison. The default.
+	CollateBinary = "BINARY"
+	// Case-insensitive (ASCII) comparison.
+	CollateNoCase = "NOCASE"
+	// Trailing spaces ignored.
+	CollateRTrim = "RTRIM"
+)
+
 //
 // Regex used for detail.
 var DetailRegex = regexp.MustCompile(`(d)([0-9]+)`)
@@ -62,14 +93,20 @@ type Field struct {
 	string string
 	// Staging (int) values.
 	int int64
+	// Staging (null) values.
+	null bool
+	// Staging (blob) values.
+	bytes []byte
 	// Referenced as a parameter.
 	isParam bool
+	// Converter (resolved).
+	converter Converter
 }
 
 //
 // Validate.
 func (f *Field) Validate() error {
-	switch f.Value.Kind() {
+	switch f.kind() {
 	case reflect.String:
 	case reflect.Int,
 		reflect.Int8,
@@ -87,6 +124,28 @@ func (f *Field) Validate() error {
 	if f.Detail() > MaxDetail {
 		return liberr.Wrap(DetailErr)
 	}
+	if f.Search() && f.kind() != reflect.String {
+		return liberr.Wrap(SearchTypeErr)
+	}
+	if f.Value.Kind() == reflect.Ptr && f.Pk() {
+		return liberr.Wrap(PkTypeErr)
+	}
+	if _, found := f.Default(); found {
+		if _, err := f.defaultSQL(); err != nil {
+			return err
+		}
+	}
+	if _, err := f.enumSQL(); err != nil {
+		return err
+	}
+	if err := f.validConverter(); err != nil {
+		return err
+	}
+	if collation, found := f.Collate(); found {
+		if !validCollation(collation) || f.kind() != reflect.String {
+			return liberr.Wrap(CollateErr, "field", f.Name)
+		}
+	}
 
 	return nil
 }
@@ -96,7 +155,28 @@ func (f *Field) Validate() error {
 // Populate the appropriate `staging` field using the
 // model field value.
 func (f *Field) Pull() interface{} {
-	switch f.Value.Kind() {
+	f.null = false
+	if f.Value.Kind() == reflect.Ptr {
+		if f.Value.IsNil() {
+			f.null = true
+			return nil
+		}
+	}
+	value := reflect.Indirect(*f.Value)
+	if converter := f.Converter(); converter != nil {
+		return f.pullConverted(converter, value)
+	}
+	if f.Time() {
+		return f.pullTime(value)
+	}
+	if f.Blob() {
+		f.bytes = value.Bytes()
+		if f.bytes == nil {
+			f.bytes = []byte{}
+		}
+		return f.bytes
+	}
+	switch value.Kind() {
 	case reflect.Struct:
 		object := f.Value.Interface()
 		b, err := json.Marshal(&object)
@@ -127,10 +207,10 @@ func (f *Field) Pull() interface{} {
 		}
 		return f.string
 	case reflect.String:
-		f.string = f.Value.String()
+		f.string = value.String()
 		return f.string
 	case reflect.Bool:
-		b := f.Value.Bool()
+		b := value.Bool()
 		if b {
 			f.int = 1
 		}
@@ -140,7 +220,7 @@ func (f *Field) Pull() interface{} {
 		reflect.Int16,
 		reflect.Int32,
 		reflect.Int64:
-		f.int = f.Value.Int()
+		f.int = value.Int()
 		if f.Incremented() {
 			f.int++
 		}
@@ -152,92 +232,201 @@ func (f *Field) Pull() interface{} {
 
 //
 // Pointer used for Scan().
+// Null values are scanned as the zero value (or nil
+// for pointer fields).
 func (f *Field) Ptr() interface{} {
-	switch f.Value.Kind() {
-	case reflect.Bool,
-		reflect.Int,
-		reflect.Int8,
-		reflect.Int16,
-		reflect.Int32,
-		reflect.Int64:
-		return &f.int
-	default:
-		return &f.string
-	}
+	return (*scanner)(f)
 }
 
 //
 // Push to the model.
 // Set the model field value using the `staging` field.
-func (f *Field) Push() {
-	switch f.Value.Kind() {
+// Returns DecodeErr when the (json) encoded value cannot
+// be decoded and StrictDecode is set.
+func (f *Field) Push() (err error) {
+	if f.Value.Kind() == reflect.Ptr {
+		if f.null {
+			f.Value.Set(reflect.Zero(f.Value.Type()))
+			return
+		}
+		// new (not shared) value.
+		f.Value.Set(reflect.New(f.Value.Type().Elem()))
+	} else if f.null {
+		f.Value.Set(reflect.Zero(f.Value.Type()))
+		return
+	}
+	value := reflect.Indirect(*f.Value)
+	if converter := f.Converter(); converter != nil {
+		err = f.pushConverted(converter, value)
+		return
+	}
+	if f.Time() {
+		f.pushTime(value)
+		return
+	}
+	if f.Blob() {
+		value.SetBytes(f.bytes)
+		return
+	}
+	switch value.Kind() {
 	case reflect.Struct:
 		if len(f.string) == 0 {
 			break
 		}
-		tv := reflect.New(f.Value.Type())
+		tv := reflect.New(value.Type())
 		object := tv.Interface()
-		err := json.Unmarshal([]byte(f.string), &object)
-		if err == nil {
-			tv = reflect.ValueOf(object)
-			f.Value.Set(tv.Elem())
+		err = f.decode(&object)
+		if err != nil {
+			err = f.tolerate(err)
+			return
 		}
+		tv = reflect.ValueOf(object)
+		value.Set(tv.Elem())
 	case reflect.Slice,
 		reflect.Map:
 		if len(f.string) == 0 {
 			break
 		}
-		tv := reflect.New(f.Value.Type())
+		tv := reflect.New(value.Type())
 		object := tv.Interface()
-		err := json.Unmarshal([]byte(f.string), object)
-		if err == nil {
-			tv = reflect.ValueOf(object)
-			tv = reflect.Indirect(tv)
-			f.Value.Set(tv)
+		err = f.decode(object)
+		if err != nil {
+			err = f.tolerate(err)
+			return
 		}
+		tv = reflect.ValueOf(object)
+		tv = reflect.Indirect(tv)
+		value.Set(tv)
 	case reflect.String:
-		f.Value.SetString(f.string)
+		value.SetString(f.string)
 	case reflect.Bool:
 		b := false
 		if f.int != 0 {
 			b = true
 		}
-		f.Value.SetBool(b)
+		value.SetBool(b)
 	case reflect.Int,
 		reflect.Int8,
 		reflect.Int16,
 		reflect.Int32,
 		reflect.Int64:
-		f.Value.SetInt(f.int)
+		value.SetInt(f.int)
+	}
+
+	return
+}
+
+//
+// Decode the (json) encoded `staging` string.
+// The encoded size is limited by MaxEncodedSize and panics
+// raised while decoding are recovered and reported as DecodeErr.
+func (f *Field) decode(object interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = liberr.Wrap(
+				DecodeErr,
+				"field",
+				f.Name,
+				"reason",
+				fmt.Sprint(p))
+		}
+	}()
+	if MaxEncodedSize > 0 && int64(len(f.string)) > MaxEncodedSize {
+		err = liberr.Wrap(
+			DecodeErr,
+			"field",
+			f.Name,
+			"size",
+			len(f.string),
+			"max",
+			MaxEncodedSize)
+		return
+	}
+	dErr := json.Unmarshal([]byte(f.string), object)
+	if dErr != nil {
+		err = liberr.Wrap(
+			DecodeErr,
+			"field",
+			f.Name,
+			"reason",
+			dErr.Error())
+	}
+
+	return
+}
+
+//
+// Tolerate a decode error unless StrictDecode is set.
+// The error is logged and the field left as the zero value.
+func (f *Field) tolerate(err error) error {
+	if StrictDecode {
+		return err
 	}
+	log.Info(
+		"field value not decoded.",
+		"field",
+		f.Name,
+		"reason",
+		err.Error())
+
+	return nil
 }
 
 //
 // Column DDL.
 func (f *Field) DDL() string {
 	part := []string{
-		f.Name, // name
-		"",     // type
-		"",     // constraint
+		f.Name,      // name
+		f.SQLType(), // type
+		"",          // constraint
 	}
-	switch f.Value.Kind() {
+	switch {
+	case f.Pk():
+		part[2] = "PRIMARY KEY"
+	case f.Nullable():
+		part = part[:2]
+	default:
+		part[2] = "NOT NULL"
+	}
+	if value, err := f.defaultSQL(); err == nil && value != "" {
+		part = append(part, "DEFAULT "+value)
+	}
+	if check, err := f.enumSQL(); err == nil && check != "" {
+		part = append(part, check)
+	}
+	if collation, found := f.Collate(); found {
+		part = append(part, "COLLATE "+collation)
+	}
+
+	return strings.Join(part, " ")
+}
+
+//
+// Column (SQL) type.
+func (f *Field) SQLType() (t string) {
+	if converter := f.Converter(); converter != nil {
+		t = converter.Column()
+		return
+	}
+	switch f.kind() {
 	case reflect.Bool,
 		reflect.Int,
 		reflect.Int8,
 		reflect.Int16,
 		reflect.Int32,
 		reflect.Int64:
-		part[1] = "INTEGER"
+		t = "INTEGER"
 	default:
-		part[1] = "TEXT"
-	}
-	if f.Pk() {
-		part[2] = "PRIMARY KEY"
-	} else {
-		part[2] = "NOT NULL"
+		t = "TEXT"
+		if f.Time() && f.Unix() {
+			t = "INTEGER"
+		}
+		if f.Blob() {
+			t = "BLOB"
+		}
 	}
 
-	return strings.Join(part, " ")
+	return
 }
 
 //
@@ -250,7 +439,7 @@ func (f *Field) Param() string {
 //
 // Get whether field is the primary key.
 func (f *Field) Pk() (matched bool) {
-	for _, opt := range strings.Split(f.Tag, ",") {
+	for _, opt := range f.options() {
 		m := PkRegex.FindStringSubmatch(opt)
 		if m != nil {
 			matched = true
@@ -266,7 +455,7 @@ func (f *Field) Pk() (matched bool) {
 // when generation is not enabled.
 func (f *Field) WithFields() (withFields map[string]bool) {
 	withFields = map[string]bool{}
-	for _, opt := range strings.Split(f.Tag, ",") {
+	for _, opt := range f.options() {
 		opt = strings.TrimSpace(opt)
 		m := PkRegex.FindStringSubmatch(opt)
 		if len(m) == 6 {
@@ -309,11 +498,26 @@ func (f *Field) Virtual() bool {
 	return f.hasOpt("virtual")
 }
 
+//
+// Get whether the field is quiet.
+// Updates that change only quiet fields are not
+// reported (as events) to watches.
+func (f *Field) Quiet() bool {
+	return f.hasOpt("quiet")
+}
+
+//
+// Get whether field is (full text) searchable.
+// See: DB.SearchAll().
+func (f *Field) Search() bool {
+	return f.hasOpt("search")
+}
+
 //
 // Get whether the field is unique.
 func (f *Field) Unique() []string {
 	list := []string{}
-	for _, opt := range strings.Split(f.Tag, ",") {
+	for _, opt := range f.options() {
 		opt = strings.TrimSpace(opt)
 		m := UniqueRegex.FindStringSubmatch(opt)
 		if len(m) == 5 {
@@ -328,7 +532,7 @@ func (f *Field) Unique() []string {
 // Get whether the field has non-unique index.
 func (f *Field) Index() []string {
 	list := []string{}
-	for _, opt := range strings.Split(f.Tag, ",") {
+	for _, opt := range f.options() {
 		opt = strings.TrimSpace(opt)
 		m := IndexRegex.FindStringSubmatch(opt)
 		if len(m) == 5 {
@@ -346,7 +550,7 @@ func (f *Field) Index() []string {
 //   +must = referenced model must exist.
 //   +cascade = cascade delete.
 func (f *Field) Fk() (fk *FK) {
-	for _, opt := range strings.Split(f.Tag, ",") {
+	for _, opt := range f.options() {
 		opt = strings.TrimSpace(opt)
 		m := FkRegex.FindStringSubmatch(opt)
 		if len(m) == 5 {
@@ -377,6 +581,114 @@ func (f *Field) Fk() (fk *FK) {
 	return
 }
 
+//
+// Get whether the field (column) is nullable.
+// Pointer fields are nullable unless tagged `notnull`.
+func (f *Field) Nullable() bool {
+	return f.Value.Kind() == reflect.Ptr &&
+		!f.Pk() &&
+		!f.hasOpt("notnull")
+}
+
+//
+// Get the default value.
+// Format: default=<value> or default='<value>'.
+// The quoted value may contain commas and an
+// (escaped) quote is written as ''.
+func (f *Field) Default() (value string, found bool) {
+	for _, opt := range f.options() {
+		opt = strings.TrimSpace(opt)
+		m := DefaultRegex.FindStringSubmatch(opt)
+		if len(m) == 3 {
+			value = m[2]
+			found = true
+			break
+		}
+	}
+	n := len(value)
+	if n > 1 && value[0] == '\'' && value[n-1] == '\'' {
+		value = strings.ReplaceAll(value[1:n-1], "''", "'")
+	}
+
+	return
+}
+
+//
+// Default value (SQL literal).
+// Returns "" when no default is specified.
+func (f *Field) defaultSQL() (literal string, err error) {
+	value, found := f.Default()
+	if !found {
+		return
+	}
+	switch f.kind() {
+	case reflect.Bool:
+		b, pErr := strconv.ParseBool(value)
+		if pErr != nil {
+			err = liberr.Wrap(DefaultErr, "field", f.Name)
+			return
+		}
+		literal = "0"
+		if b {
+			literal = "1"
+		}
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		n, pErr := strconv.ParseInt(value, 0, 64)
+		if pErr != nil {
+			err = liberr.Wrap(DefaultErr, "field", f.Name)
+			return
+		}
+		literal = strconv.FormatInt(n, 10)
+	default:
+		if f.Time() {
+			t, pErr := time.Parse(time.RFC3339Nano, value)
+			if pErr != nil {
+				err = liberr.Wrap(DefaultErr, "field", f.Name)
+				return
+			}
+			if f.Unix() {
+				literal = strconv.FormatInt(t.UnixNano(), 10)
+				break
+			}
+			value = t.UTC().Format(TimeFormat)
+		}
+		literal = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+
+	return
+}
+
+//
+// Get the (column) collation.
+// Format: collate=<name>. Example: `sql:"collate=nocase"`.
+func (f *Field) Collate() (collation string, found bool) {
+	for _, opt := range f.options() {
+		m := CollateRegex.FindStringSubmatch(opt)
+		if len(m) == 3 {
+			collation = strings.ToUpper(strings.TrimSpace(m[2]))
+			found = true
+			break
+		}
+	}
+
+	return
+}
+
+//
+// The collation is supported.
+func validCollation(collation string) bool {
+	switch collation {
+	case CollateBinary, CollateNoCase, CollateRTrim:
+		return true
+	}
+
+	return false
+}
+
 //
 // Get whether field is auto-incremented.
 func (f *Field) Incremented() bool {
@@ -386,6 +698,14 @@ func (f *Field) Incremented() bool {
 // Convert the specified `object` to a value
 // (type) appropriate for the field.
 func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
+	if converter := f.Converter(); converter != nil {
+		value, err = f.convertedValue(converter, object)
+		return
+	}
+	if f.Time() {
+		value, err = f.timeValue(object)
+		return
+	}
 	val := reflect.ValueOf(object)
 	switch val.Kind() {
 	case reflect.Ptr:
@@ -396,7 +716,7 @@ func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
 		err = liberr.Wrap(PredicateValueErr)
 		return
 	}
-	switch f.Value.Kind() {
+	switch f.kind() {
 	case reflect.String:
 		switch val.Kind() {
 		case reflect.String:
@@ -447,6 +767,15 @@ func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
 		reflect.Int64:
 		switch val.Kind() {
 		case reflect.String:
+			if f.Duration() {
+				d, pErr := time.ParseDuration(val.String())
+				if pErr != nil {
+					err = liberr.Wrap(pErr)
+					return
+				}
+				value = int64(d)
+				break
+			}
 			n, err := strconv.ParseInt(val.String(), 0, 64)
 			if err != nil {
 				err = liberr.Wrap(err)
@@ -477,6 +806,9 @@ func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
 //
 // Get whether the field is `json` encoded.
 func (f *Field) Encoded() (encoded bool) {
+	if f.Time() || f.Blob() || f.Converted() {
+		return
+	}
 	switch f.Value.Kind() {
 	case reflect.Struct,
 		reflect.Slice,
@@ -494,7 +826,7 @@ func (f *Field) Encoded() (encoded bool) {
 //        Other = DefaultDetail
 func (f *Field) Detail() (level int) {
 	level = DefaultDetail
-	for _, opt := range strings.Split(f.Tag, ",") {
+	for _, opt := range f.options() {
 		opt = strings.TrimSpace(opt)
 		m := DetailRegex.FindStringSubmatch(opt)
 		if len(m) == 3 {
@@ -519,7 +851,7 @@ func (f *Field) MatchDetail(level int) bool {
 //
 // Get whether field has an option.
 func (f *Field) hasOpt(name string) bool {
-	for _, opt := range strings.Split(f.Tag, ",") {
+	for _, opt := range f.options() {
 		opt = strings.TrimSpace(opt)
 		if opt == name {
 			return true
@@ -561,3 +893,129 @@ func (f *FK) DDL(field *Field) string {
 func (f *FK) needsIndex() bool {
 	return f.Cascade && !f.Must
 }
+
+//
+// Tag options.
+// Split on commas not enclosed in parentheses or in a
+// quoted (option) value. A value is quoted when it begins
+// with a single quote following `=`. Example: default='a,b'.
+func (f *Field) options() (list []string) {
+	tag := f.Tag
+	depth := 0
+	start := 0
+	quoted := false
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case quoted:
+			if c == '\'' {
+				if i+1 < len(tag) && tag[i+1] == '\'' {
+					i++
+				} else {
+					quoted = false
+				}
+			}
+		case c == '\'' && i > 0 && tag[i-1] == '=':
+			quoted = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',':
+			if depth == 0 {
+				list = append(list, strings.TrimSpace(tag[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	list = append(list, strings.TrimSpace(tag[start:]))
+
+	return
+}
+
+//
+// Field kind.
+// The element kind for pointer fields.
+func (f *Field) kind() reflect.Kind {
+	if f.Value.Kind() == reflect.Ptr {
+		return f.Value.Type().Elem().Kind()
+	}
+
+	return f.Value.Kind()
+}
+
+//
+// Field (sql) scanner.
+// Populates the `staging` fields.
+type scanner Field
+
+//
+// Scan the column value.
+func (r *scanner) Scan(src interface{}) (err error) {
+	f := (*Field)(r)
+	f.null = src == nil
+	f.string = ""
+	f.int = 0
+	f.bytes = nil
+	column := f.SQLType()
+	if column == "BLOB" {
+		switch v := src.(type) {
+		case []byte:
+			f.bytes = append([]byte{}, v...)
+		case string:
+			f.bytes = []byte(v)
+		}
+		return
+	}
+	switch v := src.(type) {
+	case nil:
+	case int64:
+		f.int = v
+		f.string = strconv.FormatInt(v, 10)
+	case float64:
+		f.int = int64(v)
+		f.string = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			f.int = 1
+		}
+		f.string = strconv.FormatBool(v)
+	case []byte:
+		f.string = string(v)
+	case string:
+		f.string = v
+	default:
+		err = liberr.New(
+			"scan type not supported.",
+			"field",
+			f.Name,
+			"type",
+			fmt.Sprintf("%T", src))
+		return
+	}
+	integer := false
+	if f.Converted() {
+		integer = column == "INTEGER"
+	} else {
+		switch f.kind() {
+		case reflect.Bool,
+			reflect.Int,
+			reflect.Int8,
+			reflect.Int16,
+			reflect.Int32,
+			reflect.Int64:
+			integer = true
+		}
+	}
+	if integer {
+		switch src.(type) {
+		case []byte, string:
+			f.int, err = strconv.ParseInt(f.string, 0, 64)
+			if err != nil {
+				err = liberr.Wrap(err, "field", f.Name)
+			}
+		}
+	}
+
+	return
+}
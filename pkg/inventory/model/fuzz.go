@@ -0,0 +1,54 @@
+// +build gofuzz
+
+package model
+
+//
+// Fuzzed model.
+// Fields decoded from (json) encoded columns.
+type fuzzModel struct {
+	PK     string             `sql:"pk"`
+	Object struct{ A, B int } `sql:""`
+	Map    map[string]string  `sql:""`
+	List   []interface{}      `sql:""`
+}
+
+func (m *fuzzModel) Pk() string {
+	return m.PK
+}
+
+func (m *fuzzModel) String() string {
+	return m.PK
+}
+
+func (m *fuzzModel) Labels() Labels {
+	return nil
+}
+
+//
+// Decode errors are reported.
+func init() {
+	StrictDecode = true
+}
+
+//
+// Fuzz (go-fuzz) entry point.
+// The data is pushed as the (encoded) value of each field.
+func Fuzz(data []byte) int {
+	md, err := Inspect(&fuzzModel{})
+	if err != nil {
+		return 0
+	}
+	decoded := 0
+	for _, f := range md.Fields {
+		f.string = string(data)
+		err = f.Push()
+		if err == nil {
+			decoded++
+		}
+	}
+	if decoded == 0 {
+		return 0
+	}
+
+	return 1
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"github.com/konveyor/controller/pkg/ref"
+	"sync"
+)
+
+//
+// Hook function.
+// Called with the transaction so that derived data
+// may be maintained within the same transaction. An error
+// returned by a `before` hook aborts the operation.
+type HookFunc func(tx *Tx, model Model) error
+
+//
+// Model (kind) access hooks.
+// Each is optional.
+type Hooks struct {
+	BeforeInsert HookFunc
+	AfterInsert  HookFunc
+	BeforeUpdate HookFunc
+	AfterUpdate  HookFunc
+	BeforeDelete HookFunc
+	AfterDelete  HookFunc
+}
+
+//
+// Registered hooks by kind.
+type hookSet struct {
+	// Hooks by kind.
+	content map[string][]Hooks
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Register hooks for the model kind.
+func (r *hookSet) add(model Model, hooks Hooks) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[string][]Hooks{}
+	}
+	kind := ref.ToKind(model)
+	r.content[kind] = append(r.content[kind], hooks)
+}
+
+//
+// Run the selected hook (in order registered) for the model.
+func (r *hookSet) run(tx *Tx, model Model, selector func(*Hooks) HookFunc) (err error) {
+	if r == nil {
+		return
+	}
+	r.mutex.RLock()
+	list := r.content[ref.ToKind(model)]
+	r.mutex.RUnlock()
+	for i := range list {
+		fn := selector(&list[i])
+		if fn == nil {
+			continue
+		}
+		err = fn(tx, model)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Hook selectors.
+var (
+	beforeInsert = func(h *Hooks) HookFunc { return h.BeforeInsert }
+	afterInsert  = func(h *Hooks) HookFunc { return h.AfterInsert }
+	beforeUpdate = func(h *Hooks) HookFunc { return h.BeforeUpdate }
+	afterUpdate  = func(h *Hooks) HookFunc { return h.AfterUpdate }
+	beforeDelete = func(h *Hooks) HookFunc { return h.BeforeDelete }
+	afterDelete  = func(h *Hooks) HookFunc { return h.AfterDelete }
+)
@@ -16,6 +16,9 @@ func Inspect(model interface{}) (md *Definition, err error) {
 	if err != nil {
 		return
 	}
+	for _, f := range md.Fields {
+		f.converter = f.resolveConverter()
+	}
 	err = md.validate()
 	if err != nil {
 		return
@@ -97,6 +100,19 @@ func (r *Definition) PkField() *Field {
 	return nil
 }
 
+//
+// Get the (auto) incremented field.
+// Returns: nil when not found.
+func (r *Definition) IncrementedField() *Field {
+	for _, f := range r.Fields {
+		if f.Incremented() {
+			return f
+		}
+	}
+
+	return nil
+}
+
 //
 // Field by name.
 func (r *Definition) Field(name string) *Field {
@@ -188,6 +204,40 @@ func (r *Definition) fields(model interface{}) (fields []*Field, err error) {
 				}
 				fields = append(fields, nested...)
 			}
+		case reflect.Ptr:
+			_, converted := Converters.Find(ft.Type)
+			switch ft.Type.Elem().Kind() {
+			case reflect.Struct:
+				if ft.Type.Elem() != timeType && !converted {
+					continue
+				}
+				if _, found := ft.Tag.Lookup(Tag); !found && !converted {
+					continue
+				}
+			case reflect.String,
+				reflect.Bool,
+				reflect.Int,
+				reflect.Int8,
+				reflect.Int16,
+				reflect.Int32,
+				reflect.Int64:
+			default:
+				if !converted {
+					continue
+				}
+			}
+			sqlTag, _ := ft.Tag.Lookup(Tag)
+			if sqlTag == "-" {
+				continue
+			}
+			fields = append(
+				fields,
+				&Field{
+					Tag:   sqlTag,
+					Name:  ft.Name,
+					Value: &fv,
+					Type:  &ft,
+				})
 		case reflect.Slice,
 			reflect.Map,
 			reflect.String,
@@ -344,9 +394,16 @@ func (r *DataModel) cascade(tx *Tx, relation *FkRelation, md *Definition) (casca
 			var refMd *Definition
 			model, hasNext := iter.Next()
 			if !hasNext {
+				err = fb.ErrOf(iter)
+				if err != nil {
+					return
+				}
 				break
 			}
-			list.Append(model)
+			err = list.Append(model)
+			if err != nil {
+				return
+			}
 			refMd, err = Inspect(model)
 			if err != nil {
 				return
@@ -356,7 +413,10 @@ func (r *DataModel) cascade(tx *Tx, relation *FkRelation, md *Definition) (casca
 			if err != nil {
 				return
 			}
-			list.Append(nIter)
+			err = list.Append(nIter)
+			if err != nil {
+				return
+			}
 		}
 	}
 
@@ -1,13 +1,16 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-logr/logr"
 	liberr "github.com/konveyor/controller/pkg/error"
 	fb "github.com/konveyor/controller/pkg/filebacked"
 	"github.com/konveyor/controller/pkg/logging"
 	"github.com/konveyor/controller/pkg/ref"
+	"reflect"
 	"sync"
+	"time"
 )
 
 //
@@ -21,16 +24,35 @@ var (
 	Parity  uint8 = 0x01
 	Error   uint8 = 0x02
 	End     uint8 = 0x04
+	Reset   uint8 = 0x08
 	Created uint8 = 0x10
 	Updated uint8 = 0x20
 	Deleted uint8 = 0x40
 )
 
+//
+// Reset reasons.
+const (
+	// Events have been discarded (see: Retention).
+	ResetDiscarded = "discarded"
+	// The DB has been rebuilt.
+	ResetRebuilt = "rebuilt"
+	// The (collector) parity has been lost.
+	ResetParityLost = "parity-lost"
+)
+
 //
 // Model event.
+// Events are delivered to each watch in the order reported
+// and are stamped with a (per-watch) sequence number. Compaction
+// (see: Retention) collapses events for the same model so the
+// order of events for each model (PK) is always preserved.
 type Event struct {
 	// ID.
 	ID uint64
+	// Sequence number.
+	// Stamped (consecutively) by the watch on delivery.
+	Seq uint64
 	// Labels.
 	Labels []string
 	// The event subject.
@@ -39,6 +61,54 @@ type Event struct {
 	Action uint8
 	// The updated model.
 	Updated Model
+	// The context of the transaction that
+	// reported the event.
+	ctx context.Context
+	// The reconcile ID carried by the transaction
+	// context when the event was staged.
+	reconcileID string
+}
+
+//
+// Staged (encoded) event.
+type stagedEvent struct {
+	ID          uint64
+	Labels      []string
+	Action      uint8
+	ReconcileID string
+}
+
+//
+// The context of the transaction that reported the event.
+// Used to link (tracing) spans for event delivery.
+func (r *Event) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+//
+// The reconcile ID carried by the context of the
+// transaction when the event was staged (or reported).
+// Returns "" when not found.
+func (r *Event) ReconcileID() string {
+	if r.reconcileID != "" {
+		return r.reconcileID
+	}
+
+	return logging.ReconcileIDOf(r.Context())
+}
+
+//
+// Set the (delivery) context.
+// The context carries the reconcile ID of the event.
+func (r *Event) setContext(ctx context.Context) {
+	r.ctx = ctx
+	if r.reconcileID != "" && logging.ReconcileIDOf(ctx) != r.reconcileID {
+		r.ctx = logging.WithReconcileID(r.Context(), r.reconcileID)
+	}
 }
 
 //
@@ -64,6 +134,8 @@ func (r *Event) String() string {
 		action = "error"
 	case End:
 		action = "end"
+	case Reset:
+		action = "reset"
 	case Created:
 		action = "created"
 	case Updated:
@@ -88,16 +160,25 @@ func (r *Event) String() string {
 //   Event (self)
 //   Event.Model
 //   Event.Updated (optional)
-func (r *Event) append(list *fb.List) {
-	list.Append(Event{
-		ID:     r.ID,
-		Labels: r.Labels,
-		Action: r.Action,
+func (r *Event) append(list *fb.List) (err error) {
+	err = list.Append(stagedEvent{
+		ID:          r.ID,
+		Labels:      r.Labels,
+		Action:      r.Action,
+		ReconcileID: r.reconcileID,
 	})
-	list.Append(r.Model)
+	if err != nil {
+		return
+	}
+	err = list.Append(r.Model)
+	if err != nil {
+		return
+	}
 	if r.Action == Updated {
-		list.Append(r.Updated)
+		err = list.Append(r.Updated)
 	}
+
+	return
 }
 
 //
@@ -107,10 +188,15 @@ func (r *Event) append(list *fb.List) {
 //   Event.Model
 //   Event.Updated (optional)
 func (r *Event) next(itr fb.Iterator) (hasNext bool) {
-	hasNext = itr.NextWith(r)
+	staged := stagedEvent{}
+	hasNext = itr.NextWith(&staged)
 	if !hasNext {
 		return
 	}
+	r.ID = staged.ID
+	r.Labels = staged.Labels
+	r.Action = staged.Action
+	r.reconcileID = staged.ReconcileID
 	object, hasNext := itr.Next()
 	if hasNext {
 		r.Model = object.(Model)
@@ -137,6 +223,28 @@ type WatchOptions struct {
 	// Initial snapshot.
 	// List models and report as `Created` events.
 	Snapshot bool
+	// Snapshot (list) predicate.
+	Predicate Predicate
+	// Snapshot page size.
+	// Models are listed in pages within a (read)
+	// transaction. 0 = SnapshotPageSize.
+	Page int
+	// Event filter.
+	// Only matched models (including the snapshot)
+	// are reported when specified.
+	Filter func(Model) bool
+	// Redacted fields.
+	// The named fields of each (filtered) model are cleared
+	// (set to the zero value) before the transform. The names
+	// are validated when the watch is created.
+	Redacted []string
+	// Event (model) transform.
+	// Applied to each (filtered) model, including the snapshot
+	// and both models of an update, before delivered. Used to
+	// redact (see: Redact) or project (summarize) the model. The
+	// model is owned by the watch and may be modified in place.
+	// A nil result and the event is not delivered.
+	Transform func(Model) Model
 }
 
 //
@@ -162,6 +270,85 @@ type EventHandler interface {
 	End()
 }
 
+//
+// Event handler (optional) reset.
+// Events have been discarded (see: Retention) or the DB
+// has been rebuilt (see: Journal.Relist).
+type ResetHandler interface {
+	// Called before the next event is delivered. The handler
+	// should re-synchronize (list) as needed.
+	Reset(reason string)
+}
+
+//
+// Redact (watch transform).
+// The named fields are cleared (set to the zero value). Fails
+// closed: the model is dropped (nil) and the event not delivered
+// when the model cannot be inspected or a field is not found.
+// Prefer WatchOptions.Redacted which is validated when the
+// watch is created.
+// Example:
+//   options := WatchOptions{
+//      Transform: Redact("Password", "Token"),
+//   }
+func Redact(fields ...string) func(Model) Model {
+	return func(m Model) Model {
+		return redact(m, fields)
+	}
+}
+
+//
+// Clear the named fields.
+// Returns nil when the model cannot be inspected
+// or a field is not found.
+func redact(m Model, fields []string) Model {
+	md, err := Inspect(m)
+	if err != nil {
+		log.Error(err, "redact failed, model dropped.")
+		return nil
+	}
+	for _, name := range fields {
+		f := md.Field(name)
+		if f == nil {
+			log.Info(
+				"redacted field not found, model dropped.",
+				"kind",
+				md.Kind,
+				"field",
+				name)
+			return nil
+		}
+		f.Value.Set(reflect.Zero(f.Value.Type()))
+	}
+
+	return m
+}
+
+//
+// Validate the redacted field names.
+func validRedacted(m Model, fields []string) (err error) {
+	if len(fields) == 0 {
+		return
+	}
+	md, err := Inspect(m)
+	if err != nil {
+		return
+	}
+	for _, name := range fields {
+		if md.Field(name) == nil {
+			err = liberr.New(
+				"redacted field not found.",
+				"kind",
+				md.Kind,
+				"field",
+				name)
+			return
+		}
+	}
+
+	return
+}
+
 //
 // Model event watch.
 type Watch struct {
@@ -172,15 +359,124 @@ type Watch struct {
 	// ID
 	id uint64
 	// Event queue.
-	queue chan fb.Iterator
+	queue *eventQueue
 	// Journal.
 	journal *Journal
 	// Logger.
 	log logr.Logger
-	// Started
+	// Started (dispatcher).
 	started bool
-	// Done
+	// Done (protected by the mutex).
 	done bool
+	// Scheduled (ready or being delivered) by the dispatcher.
+	scheduled bool
+	// Signaled (events queued) while scheduled.
+	signaled bool
+	// Snapshot (not yet delivered).
+	snapshot fb.Iterator
+	// Event filter.
+	filter func(Model) bool
+	// Event (model) transform.
+	transform func(Model) Model
+	// Batch (partially) delivered.
+	current *batch
+	// Closed when the (started) watch has ended.
+	ended chan struct{}
+	// Number of events delivered.
+	delivered uint64
+	// Sequence number (last delivered).
+	seq uint64
+	// Revision (ID) of the last event delivered.
+	revision uint64
+	// Last event delivered.
+	lastEvent time.Time
+	// Protect delivery stats, done and ended.
+	mutex sync.Mutex
+}
+
+//
+// Watch report.
+type WatchReport struct {
+	// Watch ID.
+	ID uint64 `json:"id"`
+	// Model (kind) watched.
+	Kind string `json:"kind"`
+	// Handler identity.
+	Handler string `json:"handler"`
+	// Snapshot predicate (SQL expression).
+	Predicate string `json:"predicate,omitempty"`
+	// Revision (ID) of the last event delivered.
+	Revision uint64 `json:"revision"`
+	// Number of queued (undelivered) event batches.
+	Backlog int `json:"backlog"`
+	// The queue is full or the watch is not running.
+	Stalled bool `json:"stalled"`
+	// Number of events delivered.
+	Delivered uint64 `json:"delivered"`
+	// Last event delivered.
+	LastEvent *time.Time `json:"lastEvent,omitempty"`
+	// Number of queued (undelivered) events.
+	Queued int `json:"queued"`
+	// Size (bytes) of queued events.
+	Bytes int64 `json:"bytes"`
+	// Number of events discarded.
+	Discarded uint64 `json:"discarded"`
+	// Number of events collapsed by compaction.
+	Compacted uint64 `json:"compacted"`
+}
+
+//
+// Build the report.
+func (w *Watch) report() (report WatchReport) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	report = WatchReport{
+		ID:        w.id,
+		Kind:      ref.ToKind(w.Model),
+		Handler:   w.handlerID(),
+		Revision:  w.revision,
+		Backlog:   w.queue.len(),
+		Delivered: w.delivered,
+	}
+	if predicate := w.Handler.Options().Predicate; predicate != nil {
+		report.Predicate = predicate.Expr()
+	}
+	report.Queued, report.Bytes, report.Discarded, report.Compacted = w.queue.stats()
+	report.Stalled = w.queue.full() || w.done
+	if !w.lastEvent.IsZero() {
+		last := w.lastEvent
+		report.LastEvent = &last
+	}
+
+	return
+}
+
+//
+// Record an event delivered.
+func (w *Watch) recordDelivered(event *Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.delivered++
+	w.revision = event.ID
+	w.lastEvent = time.Now()
+}
+
+//
+// Handler identity.
+// The handler description (fmt.Stringer) when implemented.
+// Else, the handler type.
+func (w *Watch) handlerID() string {
+	if stringer, cast := w.Handler.(fmt.Stringer); cast {
+		return stringer.String()
+	}
+
+	return fmt.Sprintf("%T", w.Handler)
+}
+
+//
+// Watch ID.
+func (w *Watch) ID() uint64 {
+	return w.id
 }
 
 //
@@ -202,6 +498,8 @@ func (w *Watch) End() {
 //
 // The watch has not ended.
 func (w *Watch) Alive() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	return !w.done
 }
 
@@ -213,90 +511,210 @@ func (w *Watch) Match(model Model) bool {
 
 //
 // Queue event.
-func (w *Watch) notify(itr fb.Iterator) {
-	defer func() {
-		recover()
-	}()
-	select {
-	case w.queue <- itr:
-	default:
+// Events may be discarded as needed to enforce the
+// retention policy.
+func (w *Watch) notify(b *batch, policy Retention) {
+	discarded := w.queue.put(b, policy)
+	if discarded > 0 {
 		description := "full queue, event discarded"
-		w.Handler.Error(liberr.New(description))
-		w.log.V(3).Info(description)
+		w.Handler.Error(
+			liberr.New(
+				description,
+				"discarded",
+				discarded))
+		w.log.V(3).Info(
+			description,
+			"discarded",
+			discarded)
 	}
 }
 
 //
 // Run the watch.
-// Forward events to the `handler`.
+// Forward events to the `handler`. The snapshot followed by
+// the queued events are delivered by the (journal) dispatcher.
 func (w *Watch) Start(snapshot fb.Iterator) {
-	if w.started {
+	if !w.Alive() || w.journal.dispatcher.started(w) {
 		return
 	}
 	w.log.V(3).Info("watch started.")
 	w.Handler.Started(w.id)
-	run := func() {
-		defer func() {
-			w.started = false
-			w.done = true
-			w.Handler.End()
-			w.log.V(3).Info("watch stopped.")
-		}()
-		for {
-			m, hasNext := snapshot.Next()
-			if hasNext {
-				w.Handler.Created(
-					Event{
-						Action: Created,
-						Model:  m.(Model),
-					})
-			} else {
+	options := w.Handler.Options()
+	w.filter = options.Filter
+	if w.filter == nil {
+		w.filter = func(Model) bool {
+			return true
+		}
+	}
+	w.transform = options.Transform
+	if w.transform == nil {
+		w.transform = func(m Model) Model {
+			return m
+		}
+	}
+	if len(options.Redacted) > 0 {
+		transform := w.transform
+		w.transform = func(m Model) Model {
+			m = redact(m, options.Redacted)
+			if m == nil {
+				return nil
+			}
+			return transform(m)
+		}
+	}
+	w.snapshot = snapshot
+	w.mutex.Lock()
+	w.ended = make(chan struct{})
+	w.mutex.Unlock()
+	w.journal.dispatcher.start(w)
+}
+
+//
+// Deliver (up to the quantum of) events to the handler.
+// Called by a dispatcher worker and never concurrently for
+// the same watch. Returns true when more events may be
+// pending.
+func (w *Watch) deliver(quantum int) (more bool) {
+	if !w.Alive() {
+		return
+	}
+	delivered := 0
+	if w.snapshot != nil {
+		for delivered < quantum {
+			m, hasNext := w.snapshot.Next()
+			if !hasNext {
 				w.log.V(3).Info("has parity.")
 				w.Handler.Parity()
+				w.snapshot = nil
 				break
 			}
+			if !w.filter(m.(Model)) {
+				continue
+			}
+			transformed := w.transform(m.(Model))
+			if transformed == nil {
+				continue
+			}
+			w.seq++
+			w.Handler.Created(
+				Event{
+					Seq:    w.seq,
+					Action: Created,
+					Model:  transformed,
+				})
+			delivered++
 		}
-		for itr := range w.queue {
-			for {
-				event := Event{}
-				hasNext := event.next(itr)
-				if !hasNext {
-					break
-				}
-				if !w.Match(event.Model) {
-					continue
+		more = true
+		return
+	}
+	for delivered < quantum {
+		if w.current == nil {
+			b, ok, closed := w.queue.take()
+			if !ok {
+				if closed {
+					w.finish()
 				}
-				w.log.V(5).Info(
-					"event received.",
-					"event",
-					event.String())
-				switch event.Action {
-				case Created:
-					w.Handler.Created(event)
-				case Updated:
-					w.Handler.Updated(event)
-				case Deleted:
-					w.Handler.Deleted(event)
-				default:
-					w.log.Info(
-						"unknown action.",
-						"event",
-						event.String())
+				return
+			}
+			if b.reset {
+				w.log.V(3).Info(
+					"reset.",
+					"reason",
+					b.reason)
+				if h, cast := w.Handler.(ResetHandler); cast {
+					h.Reset(b.reason)
 				}
 			}
+			w.current = b
 		}
+		b := w.current
+		event := Event{}
+		if b.itr == nil || !event.next(b.itr) {
+			b.close()
+			w.current = nil
+			continue
+		}
+		event.setContext(b.ctx)
+		if !w.Match(event.Model) || !w.filter(event.Model) {
+			continue
+		}
+		if !w.transformed(&event) {
+			continue
+		}
+		w.log.V(5).Info(
+			"event received.",
+			"event",
+			event.String(),
+			logging.ReconcileID,
+			event.ReconcileID())
+		w.seq++
+		event.Seq = w.seq
+		switch event.Action {
+		case Created:
+			w.Handler.Created(event)
+		case Updated:
+			w.Handler.Updated(event)
+		case Deleted:
+			w.Handler.Deleted(event)
+		default:
+			w.log.Info(
+				"unknown action.",
+				"event",
+				event.String())
+			continue
+		}
+		w.recordDelivered(&event)
+		delivered++
+	}
+
+	more = true
+
+	return
+}
+
+//
+// Transform the event models.
+// Returns false when the event is not to be delivered.
+func (w *Watch) transformed(event *Event) bool {
+	event.Model = w.transform(event.Model)
+	if event.Model == nil {
+		return false
 	}
+	if event.Updated != nil {
+		event.Updated = w.transform(event.Updated)
+		if event.Updated == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// The (started) watch has ended.
+// The queued events have been delivered.
+func (w *Watch) finish() {
+	w.mutex.Lock()
+	w.done = true
+	w.mutex.Unlock()
+	w.Handler.End()
+	close(w.ended)
+	w.log.V(3).Info("watch stopped.")
+}
 
-	w.started = true
-	go run()
+//
+// Closed when the (started) watch has ended.
+// Nil when not started.
+func (w *Watch) endedSignal() chan struct{} {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.ended
 }
 
 //
 // Terminate.
 func (w *Watch) terminate() {
-	if w.started {
-		close(w.queue)
-	}
+	w.queue.close()
 }
 
 //
@@ -308,6 +726,16 @@ type Journal struct {
 	log logr.Logger
 	// List of registered watches.
 	watches []*Watch
+	// Retention policy.
+	retention Retention
+	// Events discarded by ended watches.
+	discarded uint64
+	// Events collapsed by ended watches.
+	compacted uint64
+	// Watches ended (and not drained) by Drain().
+	draining []*Watch
+	// Event dispatcher.
+	dispatcher dispatcher
 }
 
 //
@@ -331,7 +759,11 @@ func (r *Journal) Watch(model Model, handler EventHandler) (*Watch, error) {
 		log:     log,
 	}
 	r.watches = append(r.watches, watch)
-	watch.queue = make(chan fb.Iterator, 250)
+	watch.queue = newQueue(
+		QueueCapacity,
+		func() {
+			r.dispatcher.schedule(watch)
+		})
 
 	r.log.V(3).Info(
 		"watch created.",
@@ -353,6 +785,9 @@ func (r *Journal) End(watch *Watch) {
 			continue
 		}
 		w.terminate()
+		_, _, discarded, compacted := w.queue.stats()
+		r.discarded += discarded
+		r.compacted += compacted
 		r.log.V(3).Info(
 			"watch end requested.",
 			"watch",
@@ -362,15 +797,99 @@ func (r *Journal) End(watch *Watch) {
 	r.watches = kept
 }
 
+//
+// Find a watch by ID.
+func (r *Journal) find(id uint64) (watch *Watch, found bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, w := range r.watches {
+		if w.id == id {
+			watch = w
+			found = true
+			break
+		}
+	}
+
+	return
+}
+
 //
 // Transaction committed.
 // Recorded (staged) events are forwarded to watches.
 func (r *Journal) Report(staged *fb.List) {
+	r.ReportContext(context.Background(), staged)
+}
+
+//
+// Transaction committed.
+// Recorded (staged) events are forwarded to watches
+// with the transaction context.
+func (r *Journal) ReportContext(ctx context.Context, staged *fb.List) {
+	events := 0
+	itr := staged.Iter()
+	for {
+		event := Event{}
+		if !event.next(itr) {
+			break
+		}
+		events++
+	}
+	itr.Close()
+	r.report(ctx, staged, events)
+}
+
+//
+// Forward (staged) events to watches.
+func (r *Journal) report(ctx context.Context, staged *fb.List, events int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, w := range r.watches {
+		w.notify(
+			&batch{
+				ctx:    ctx,
+				itr:    staged.Iter(),
+				events: events,
+				bytes:  staged.Size(),
+				queued: time.Now(),
+			},
+			r.retention)
+	}
+}
+
+//
+// Set the retention policy.
+func (r *Journal) SetRetention(policy Retention) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
+	r.retention = policy
+}
+
+//
+// Set the max number of dispatcher (delivery) workers.
+// 0 = DispatchWorkers.
+func (r *Journal) SetWorkers(n int) {
+	r.dispatcher.setWorkers(n)
+}
+
+//
+// Relist.
+// Each watch is reset with the reason, instructing the
+// handler to re-synchronize (list). Called when the DB has
+// been rebuilt (provider re-added, migration applied) so that
+// watches do not silently go stale.
+func (r *Journal) Relist(reason string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	for _, w := range r.watches {
-		w.notify(staged.Iter())
+		w.queue.relist(reason)
 	}
+
+	r.log.V(3).Info(
+		"journal: relist.",
+		"reason",
+		reason,
+		"watches",
+		len(r.watches))
 }
 
 //
@@ -386,6 +905,93 @@ func (r *Journal) Close() (err error) {
 	return
 }
 
+//
+// Drain the journal.
+// All watches are ended after the queued events have been
+// delivered. Blocks until the (started) watches have ended
+// or the context is done. The events not delivered when the
+// context is done may be checkpointed. See: Client.Checkpoint().
+func (r *Journal) Drain(ctx context.Context) (err error) {
+	r.mutex.Lock()
+	watches := r.watches[:]
+	r.draining = append(r.draining, watches...)
+	r.mutex.Unlock()
+	for _, w := range watches {
+		r.End(w)
+	}
+	for _, w := range watches {
+		ended := w.endedSignal()
+		if ended == nil {
+			continue
+		}
+		select {
+		case <-ended:
+		case <-ctx.Done():
+			err = liberr.Wrap(ctx.Err())
+			return
+		}
+	}
+	r.mutex.Lock()
+	r.draining = nil
+	r.mutex.Unlock()
+
+	r.log.V(3).Info(
+		"journal drained.",
+		"watches",
+		len(watches))
+
+	return
+}
+
+//
+// Watch reports.
+// Ordered by watch ID.
+func (r *Journal) reports() (list []WatchReport) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	list = []WatchReport{}
+	for _, w := range r.watches {
+		list = append(list, w.report())
+	}
+
+	return
+}
+
+//
+// Journal statistics.
+// Returns the number of watches, number of queued
+// event batches and number of stalled watches.
+func (r *Journal) stats() (watches, backlog, stalled int) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, w := range r.watches {
+		watches++
+		backlog += w.queue.len()
+		if w.queue.full() || !w.Alive() {
+			stalled++
+		}
+	}
+
+	return
+}
+
+//
+// Number of events discarded and collapsed (compacted)
+// by watches.
+func (r *Journal) retained() (discarded, compacted uint64) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	discarded = r.discarded
+	compacted = r.compacted
+	for _, w := range r.watches {
+		_, _, d, c := w.queue.stats()
+		discarded += d
+		compacted += c
+	}
+
+	return
+}
+
 //
 // Model is being watched.
 // Determine if there a watch interested in the model.
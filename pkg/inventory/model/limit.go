@@ -0,0 +1,266 @@
+package model
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/ref"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+//
+// Row limit eviction policy.
+type EvictionPolicy int
+
+//
+// Eviction policies.
+const (
+	// Inserts are rejected (RowLimitErr) when
+	// the limit has been reached.
+	RejectWrites EvictionPolicy = iota
+	// The oldest rows are deleted (within the
+	// transaction) when the transaction is committed.
+	EvictOldest
+)
+
+//
+// Errors.
+var (
+	// Row limit exceeded.
+	RowLimitErr = errors.New("row limit exceeded")
+)
+
+//
+// Row limit (by kind).
+// Prevents unbounded kinds (for example: events or logs)
+// from growing the DB indefinitely.
+// Example:
+//   db.SetLimit(
+//      &Event{},
+//      model.RowLimit{
+//         Max:    10000,
+//         Policy: model.EvictOldest,
+//         Field:  "Timestamp",
+//      })
+type RowLimit struct {
+	// Max number of rows.
+	// 0 = not limited.
+	Max int64
+	// Eviction policy.
+	Policy EvictionPolicy
+	// Field (name) used to order rows by age for eviction.
+	// Rows with the lowest values are evicted first.
+	// Default: the PK.
+	Field string
+}
+
+//
+// Row limit report.
+type LimitReport struct {
+	// Model kind.
+	Kind string `json:"kind"`
+	// Max number of rows.
+	Max int64 `json:"max"`
+	// Eviction policy.
+	Policy EvictionPolicy `json:"policy"`
+	// Number of rows evicted (committed).
+	Evicted uint64 `json:"evicted"`
+	// Number of inserts rejected.
+	Rejected uint64 `json:"rejected"`
+}
+
+//
+// Row limit (and counters) for a kind.
+type kindLimit struct {
+	RowLimit
+	// Number of rows evicted.
+	evicted uint64
+	// Number of inserts rejected.
+	rejected uint64
+}
+
+//
+// Row limits by kind.
+type limitSet struct {
+	// Limits by kind.
+	content map[string]*kindLimit
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Set the row limit for the model kind.
+// A limit with Max = 0 removes the limit.
+func (r *limitSet) set(model Model, limit RowLimit) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[string]*kindLimit{}
+	}
+	kind := ref.ToKind(model)
+	if limit.Max < 1 {
+		delete(r.content, kind)
+		return
+	}
+	updated := &kindLimit{RowLimit: limit}
+	if current, found := r.content[kind]; found {
+		updated.evicted = atomic.LoadUint64(&current.evicted)
+		updated.rejected = atomic.LoadUint64(&current.rejected)
+	}
+
+	r.content[kind] = updated
+}
+
+//
+// Get the row limit for the model kind.
+func (r *limitSet) get(model Model) (limit *kindLimit, found bool) {
+	if r == nil {
+		return
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	limit, found = r.content[ref.ToKind(model)]
+	return
+}
+
+//
+// Row limit reports (ordered by kind).
+func (r *limitSet) reports() (list []LimitReport) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	list = []LimitReport{}
+	for kind, limit := range r.content {
+		list = append(
+			list,
+			LimitReport{
+				Kind:     kind,
+				Max:      limit.Max,
+				Policy:   limit.Policy,
+				Evicted:  atomic.LoadUint64(&limit.evicted),
+				Rejected: atomic.LoadUint64(&limit.rejected),
+			})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Kind < list[j].Kind
+	})
+
+	return
+}
+
+//
+// Enforce the row limit for a model to be inserted.
+// Inserting an existing (PK) model does not add a row.
+// The (rejected) row count is counted once by the
+// transaction and then maintained as rows are inserted.
+// Evicted (oldest) rows are trimmed in a batch when the
+// transaction is committed.
+func (r *limitSet) enforce(tx *Tx, model Model) (err error) {
+	limit, found := r.get(model)
+	if !found {
+		return
+	}
+	err = Table{tx.db()}.Get(Clone(model))
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, NotFound) {
+		return
+	}
+	err = nil
+	kind := ref.ToKind(model)
+	if limit.Policy == EvictOldest {
+		if tx.evicted == nil {
+			tx.evicted = map[string]Model{}
+		}
+		tx.evicted[kind] = model
+		return
+	}
+	count, found := tx.rows[kind]
+	if !found {
+		count, err = Table{tx.db()}.Count(model, nil)
+		if err != nil {
+			return
+		}
+		if tx.rows == nil {
+			tx.rows = map[string]int64{}
+		}
+	}
+	if count >= limit.Max {
+		tx.rows[kind] = count
+		atomic.AddUint64(&limit.rejected, 1)
+		err = liberr.Wrap(
+			RowLimitErr,
+			"kind",
+			kind,
+			"max",
+			limit.Max)
+		tx.log.V(3).Info(
+			"insert rejected (row limit).",
+			"kind",
+			kind,
+			"max",
+			limit.Max)
+		return
+	}
+
+	tx.rows[kind] = count + 1
+
+	return
+}
+
+//
+// A row has been deleted.
+// The (maintained) row count is decremented.
+func (r *limitSet) deleted(tx *Tx, model Model) {
+	kind := ref.ToKind(model)
+	if n, found := tx.rows[kind]; found && n > 0 {
+		tx.rows[kind] = n - 1
+	}
+}
+
+//
+// Evict (delete) the oldest rows of the kinds
+// inserted by the transaction.
+// Uses the (purger) KeepLast retention.
+// Counted when the transaction is committed.
+func (r *limitSet) evict(tx *Tx) (err error) {
+	for kind, model := range tx.evicted {
+		limit, found := r.get(model)
+		if !found || limit.Policy != EvictOldest {
+			continue
+		}
+		policy := &RetentionPolicy{
+			Kind:     model,
+			Field:    limit.Field,
+			KeepLast: int(limit.Max),
+		}
+		err = policy.validate()
+		if err != nil {
+			return
+		}
+		purger := Purger{}
+		evicted, tErr := purger.trim(tx, policy)
+		if tErr != nil {
+			err = tErr
+			return
+		}
+		delete(tx.evicted, kind)
+		if evicted == 0 {
+			continue
+		}
+		tx.OnCommit(func() {
+			atomic.AddUint64(&limit.evicted, uint64(evicted))
+		})
+		tx.log.V(3).Info(
+			"rows evicted (row limit).",
+			"kind",
+			kind,
+			"max",
+			limit.Max,
+			"evicted",
+			evicted)
+	}
+
+	return
+}
@@ -0,0 +1,232 @@
+package model
+
+import (
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/ref"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	pathlib "path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Default (manager) DB directory.
+const ManagerDir = "/tmp"
+
+//
+// Managed DB.
+type Managed struct {
+	// DB.
+	DB DB
+	// Owner (CR) kind.
+	Kind string
+	// Owner (CR) namespace/name.
+	Name string
+	// DB file path.
+	Path string
+	// Opened timestamp.
+	Opened time.Time
+}
+
+//
+// DB manager.
+// Owns a DB for each (provider) CR. The directory and
+// models are shared by all managed DBs.
+type Manager struct {
+	// Directory containing the DB files.
+	// Default: ManagerDir.
+	Dir string
+	// Models used to build each DB.
+	Models []interface{}
+	// Delete existing DB files on open.
+	Purge bool
+	// Managed DBs by owner UID.
+	content map[types.UID]*Managed
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Open the DB for the owner (CR).
+// The DB is created and opened as needed.
+func (r *Manager) Open(owner meta.Object) (db DB, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.content == nil {
+		r.content = map[types.UID]*Managed{}
+	}
+	if m, found := r.content[owner.GetUID()]; found {
+		db = m.DB
+		return
+	}
+	m := &Managed{
+		Kind: ref.ToKind(owner),
+		Name: pathlib.Join(
+			owner.GetNamespace(),
+			owner.GetName()),
+		Path:   r.path(owner),
+		Opened: time.Now(),
+	}
+	m.DB = New(m.Path, r.Models...)
+	err = m.DB.Open(r.Purge)
+	if err != nil {
+		err = liberr.Wrap(err, "owner", m.Name)
+		return
+	}
+	r.content[owner.GetUID()] = m
+	db = m.DB
+
+	log.V(3).Info(
+		"manager: DB opened.",
+		"kind",
+		m.Kind,
+		"owner",
+		m.Name,
+		"path",
+		m.Path)
+
+	return
+}
+
+//
+// Get the DB for the owner (CR).
+func (r *Manager) Get(owner meta.Object) (db DB, found bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	m, found := r.content[owner.GetUID()]
+	if found {
+		db = m.DB
+	}
+
+	return
+}
+
+//
+// List managed DBs.
+// Ordered by kind and name.
+func (r *Manager) List() (list []Managed) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	list = []Managed{}
+	for _, m := range r.content {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	return
+}
+
+//
+// Close the DB for the owner (CR).
+// The DB file is kept.
+func (r *Manager) Close(owner meta.Object) (err error) {
+	err = r.remove(owner, false)
+	return
+}
+
+//
+// Delete the DB for the owner (CR).
+// Called when the CR is deleted. The DB is closed
+// and the file deleted.
+func (r *Manager) Delete(owner meta.Object) (err error) {
+	err = r.remove(owner, true)
+	return
+}
+
+//
+// Shutdown.
+// All DBs are closed. The DB files are kept.
+func (r *Manager) Shutdown() (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for uid, m := range r.content {
+		cErr := m.DB.Close(false)
+		if cErr != nil && err == nil {
+			err = liberr.Wrap(cErr, "owner", m.Name)
+		}
+		delete(r.content, uid)
+	}
+
+	log.V(3).Info("manager: shutdown.")
+
+	return
+}
+
+//
+// Aggregate health.
+// Open when all DBs can be queried.
+func (r *Manager) Health() (h Health) {
+	h.Open = true
+	for _, m := range r.List() {
+		mh := HealthOf(m.DB)
+		if !mh.Open {
+			h.Open = false
+			h.Error = m.Name + ": " + mh.Error
+		}
+		h.Watches += mh.Watches
+		h.Backlog += mh.Backlog
+		h.Stalled += mh.Stalled
+	}
+
+	return
+}
+
+//
+// Number of managed DBs.
+func (r *Manager) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.content)
+}
+
+//
+// Close and remove the DB for the owner (CR).
+func (r *Manager) remove(owner meta.Object, purge bool) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	m, found := r.content[owner.GetUID()]
+	if !found {
+		return
+	}
+	delete(r.content, owner.GetUID())
+	err = m.DB.Close(purge)
+	if err != nil {
+		err = liberr.Wrap(err, "owner", m.Name)
+		return
+	}
+
+	log.V(3).Info(
+		"manager: DB closed.",
+		"kind",
+		m.Kind,
+		"owner",
+		m.Name,
+		"deleted",
+		purge)
+
+	return
+}
+
+//
+// DB file path for the owner (CR).
+func (r *Manager) path(owner meta.Object) string {
+	dir := r.Dir
+	if dir == "" {
+		dir = ManagerDir
+	}
+	name := fmt.Sprintf(
+		"%s-%s.db",
+		strings.ToLower(ref.ToKind(owner)),
+		owner.GetUID())
+
+	return pathlib.Join(dir, name)
+}
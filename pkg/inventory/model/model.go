@@ -9,7 +9,7 @@ import (
 
 //
 // Package logger.
-var log = logging.WithName("model")
+var log = logging.WithName(logging.Model)
 
 //
 // Errors.
@@ -77,6 +77,15 @@ type Model interface {
 	Pk() string
 }
 
+//
+// Validated model.
+// Validation is performed by the web layer
+// before models are written.
+type Validator interface {
+	// Validate the model.
+	Validate() error
+}
+
 //
 // Labeled model.
 type Labeled interface {
@@ -1,12 +1,22 @@
 package model
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	liberr "github.com/konveyor/controller/pkg/error"
 	"github.com/konveyor/controller/pkg/ref"
+	"github.com/konveyor/controller/pkg/tracing"
 	"github.com/onsi/gomega"
+	"io/ioutil"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"math"
+	"net"
+	"os"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 )
@@ -51,6 +61,17 @@ func (m *PlainObject) Labels() Labels {
 	return nil
 }
 
+type SearchObject struct {
+	ID    int    `sql:"pk"`
+	Name  string `sql:"search"`
+	Notes string `sql:"search"`
+	Owner string `sql:""`
+}
+
+func (m *SearchObject) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
 type DetailA struct {
 	PK int `sql:"pk"`
 	FK int `sql:"fk(PlainObject +cascade +must)"`
@@ -142,7 +163,11 @@ type TestHandler struct {
 	updated []int
 	deleted []int
 	err     []error
+	reset   int
 	done    bool
+	// Events are delivered (and the fields set) by
+	// the journal goroutines; read using the accessors.
+	mutex sync.Mutex
 }
 
 func (w *TestHandler) Options() WatchOptions {
@@ -150,14 +175,20 @@ func (w *TestHandler) Options() WatchOptions {
 }
 
 func (w *TestHandler) Started(uint64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	w.started = true
 }
 
 func (w *TestHandler) Parity() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	w.parity = true
 }
 
 func (w *TestHandler) Created(e Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	if object, cast := e.Model.(*TestObject); cast {
 		w.all = append(w.all, TestEvent{action: e.Action, model: object})
 		w.created = append(w.created, object.ID)
@@ -165,6 +196,8 @@ func (w *TestHandler) Created(e Event) {
 }
 
 func (w *TestHandler) Updated(e Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	if object, cast := e.Model.(*TestObject); cast {
 		w.all = append(w.all, TestEvent{
 			action:  e.Action,
@@ -175,6 +208,8 @@ func (w *TestHandler) Updated(e Event) {
 	}
 }
 func (w *TestHandler) Deleted(e Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	if object, cast := e.Model.(*TestObject); cast {
 		w.all = append(w.all, TestEvent{action: e.Action, model: object})
 		w.deleted = append(w.deleted, object.ID)
@@ -182,13 +217,90 @@ func (w *TestHandler) Deleted(e Event) {
 }
 
 func (w *TestHandler) Error(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	w.err = append(w.err, err)
 }
 
+func (w *TestHandler) Reset(string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.reset++
+}
+
 func (w *TestHandler) End() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	w.done = true
 }
 
+//
+// Events delivered (copy).
+func (w *TestHandler) events() []TestEvent {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]TestEvent{}, w.all...)
+}
+
+//
+// IDs of the models created (copy).
+func (w *TestHandler) createdIDs() []int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]int{}, w.created...)
+}
+
+//
+// IDs of the models updated (copy).
+func (w *TestHandler) updatedIDs() []int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]int{}, w.updated...)
+}
+
+//
+// IDs of the models deleted (copy).
+func (w *TestHandler) deletedIDs() []int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]int{}, w.deleted...)
+}
+
+//
+// Started, parity and done (ended) flags.
+func (w *TestHandler) flags() (started, parity, done bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.started, w.parity, w.done
+}
+
+//
+// Handler ended (End() called).
+func (w *TestHandler) ended() bool {
+	_, _, done := w.flags()
+	return done
+}
+
+type TracedHandler struct {
+	TestHandler
+	ctx []context.Context
+}
+
+func (w *TracedHandler) Created(e Event) {
+	w.TestHandler.Created(e)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.ctx = append(w.ctx, e.Context())
+}
+
+//
+// Event contexts (copy).
+func (w *TracedHandler) contexts() []context.Context {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]context.Context{}, w.ctx...)
+}
+
 type MutatingHandler struct {
 	options WatchOptions
 	DB
@@ -241,6 +353,9 @@ func (w *MutatingHandler) Error(err error) {
 	return
 }
 
+func (w *MutatingHandler) Reset(string) {
+}
+
 func (w *MutatingHandler) End() {
 }
 
@@ -859,6 +974,309 @@ func TestList(t *testing.T) {
 	g.Expect(count).To(gomega.Equal(int64(9)))
 }
 
+func TestForEach(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-foreach.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", Age: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	ids := []int{}
+	err = DB.ForEach(
+		&TestObject{},
+		ListOptions{Predicate: Gt("Age", 4)},
+		func(m Model) error {
+			ids = append(ids, m.(*TestObject).ID)
+			return nil
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids).To(gomega.Equal([]int{5, 6, 7, 8, 9}))
+	// Stopped.
+	stopped := errors.New("stopped")
+	n := 0
+	err = DB.ForEach(
+		&TestObject{},
+		ListOptions{},
+		func(m Model) error {
+			n++
+			if n == 3 {
+				return stopped
+			}
+			return nil
+		})
+	g.Expect(errors.Is(err, stopped)).To(gomega.BeTrue())
+	g.Expect(n).To(gomega.Equal(3))
+}
+
+func TestFacets(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-facets.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	names := []string{"Elmer", "Elmer", "Elmer", "Daffy", "Daffy", "Bugs"}
+	for i, name := range names {
+		err = DB.Insert(&TestObject{ID: i, Name: name, Age: i % 2, Bool: i < 2})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	facets, err := DB.Facets(&TestObject{}, []string{"name", "Age"}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(facets["Name"]).To(gomega.Equal(
+		[]Facet{
+			{Value: "Elmer", Count: 3},
+			{Value: "Daffy", Count: 2},
+			{Value: "Bugs", Count: 1},
+		}))
+	g.Expect(facets["Age"]).To(gomega.Equal(
+		[]Facet{
+			{Value: int64(0), Count: 3},
+			{Value: int64(1), Count: 3},
+		}))
+	// predicate.
+	facets, err = DB.Facets(&TestObject{}, []string{"Name"}, Gt("ID", 2))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(facets["Name"]).To(gomega.Equal(
+		[]Facet{
+			{Value: "Daffy", Count: 2},
+			{Value: "Bugs", Count: 1},
+		}))
+	// none matched.
+	facets, err = DB.Facets(&TestObject{}, []string{"Name"}, Eq("Name", "Porky"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(facets["Name"]).To(gomega.BeEmpty())
+	// not supported.
+	_, err = DB.Facets(&TestObject{}, []string{"Slice"}, nil)
+	g.Expect(err).ToNot(gomega.BeNil())
+	_, err = DB.Facets(&TestObject{}, []string{"Unknown"}, nil)
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestSearchAll(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-search.db", &SearchObject{}, &PlainObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	objects := []*SearchObject{
+		{ID: 0, Name: "web server", Notes: "production"},
+		{ID: 1, Name: "database", Notes: "web backend"},
+		{ID: 2, Name: "web", Notes: "proxy"},
+		{ID: 3, Name: "cache", Notes: "staging", Owner: "web"},
+	}
+	for _, m := range objects {
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = DB.Insert(&PlainObject{ID: 0, Name: "webhook"})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&PlainObject{ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	kinds := []Model{&SearchObject{}, &PlainObject{}}
+	// ranked.
+	results, err := DB.SearchAll("web", kinds, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(4))
+	g.Expect(results[0].Kind).To(gomega.Equal("SearchObject"))
+	g.Expect(results[0].Model.Pk()).To(gomega.Equal("2"))
+	g.Expect(results[0].Rank).To(gomega.Equal(4.0))
+	g.Expect(results[1].Kind).To(gomega.Equal("PlainObject"))
+	g.Expect(results[1].Model.(*PlainObject).Name).To(gomega.Equal("webhook"))
+	g.Expect(results[2].Model.Pk()).To(gomega.Equal("0"))
+	g.Expect(results[2].Kind).To(gomega.Equal("SearchObject"))
+	g.Expect(results[3].Model.Pk()).To(gomega.Equal("1"))
+	// ranked (limited).
+	results, err = DB.SearchAll("web", []Model{&SearchObject{}}, 1)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(1))
+	g.Expect(results[0].Model.Pk()).To(gomega.Equal("2"))
+	// terms (prefix).
+	results, err = DB.SearchAll("back data", kinds, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(1))
+	g.Expect(results[0].Model.(*SearchObject).Name).To(gomega.Equal("database"))
+	// index maintained.
+	objects[1].Notes = "frontend"
+	err = DB.Update(objects[1])
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Delete(objects[2])
+	g.Expect(err).To(gomega.BeNil())
+	results, err = DB.SearchAll("web", []Model{&SearchObject{}}, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(1))
+	g.Expect(results[0].Model.Pk()).To(gomega.Equal("0"))
+	// all (searchable) kinds.
+	results, err = DB.SearchAll("front", nil, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(1))
+	// limit.
+	results, err = DB.SearchAll("e", kinds, 2)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(2))
+	// empty.
+	results, err = DB.SearchAll(" ", kinds, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(results).To(gomega.BeEmpty())
+	// reopened.
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	DB = New("/tmp/test-search.db", &SearchObject{}, &PlainObject{})
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	results, err = DB.SearchAll("front", nil, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(1))
+	// index (fields) changed.
+	_, err = DB.Execute(
+		"DROP TABLE SearchObjectSearch;" +
+			"CREATE VIRTUAL TABLE SearchObjectSearch USING fts4(content=\"SearchObject\",Name);")
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	DB = New("/tmp/test-search.db", &SearchObject{}, &PlainObject{})
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	results, err = DB.SearchAll("production", nil, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(results)).To(gomega.Equal(1))
+}
+
+func TestBuffer(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-buffer.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	err = DB.Insert(&TestObject{ID: 9, Name: "Porky"})
+	g.Expect(err).To(gomega.BeNil())
+	handler := &TestHandler{name: "buffer"}
+	w, err := DB.Watch(&TestObject{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.EndWatch(w)
+	buffer := &Buffer{DB: DB, Window: time.Hour, Max: 3}
+	for i := 0; i < 10; i++ {
+		buffer.Put(&TestObject{ID: 0, Name: "Elmer", Age: i})
+	}
+	buffer.Put(&TestObject{ID: 1, Name: "Daffy"})
+	buffer.Delete(&TestObject{ID: 1})
+	// coalesced.
+	report := buffer.Report()
+	g.Expect(report.Pending).To(gomega.Equal(2))
+	g.Expect(report.Buffered).To(gomega.Equal(int64(12)))
+	g.Expect(report.Coalesced).To(gomega.Equal(int64(10)))
+	n, _ := DB.Count(&TestObject{}, nil)
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	err = buffer.Flush()
+	g.Expect(err).To(gomega.BeNil())
+	m := &TestObject{ID: 0}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Age).To(gomega.Equal(9))
+	err = DB.Get(&TestObject{ID: 1})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	// max reached.
+	buffer.Put(&TestObject{ID: 2, Name: "Bugs"})
+	buffer.Put(&TestObject{ID: 9, Name: "Porky", Age: 2})
+	buffer.Put(&TestObject{ID: 3, Name: "Tweety"})
+	report = buffer.Report()
+	g.Expect(report.Pending).To(gomega.Equal(0))
+	g.Expect(report.Flushes).To(gomega.Equal(int64(2)))
+	g.Expect(report.Flushed).To(gomega.Equal(int64(5)))
+	n, _ = DB.Count(&TestObject{}, nil)
+	g.Expect(n).To(gomega.Equal(int64(4)))
+	// window elapsed.
+	buffer.Window = time.Millisecond * 10
+	buffer.Delete(&TestObject{ID: 3})
+	g.Eventually(func() int64 {
+		n, _ := DB.Count(&TestObject{}, nil)
+		return n
+	}).Should(gomega.Equal(int64(3)))
+	// ordered and copied.
+	buffer.Window = time.Hour
+	buffer.Max = 10
+	m = &TestObject{ID: 5, Name: "Marvin"}
+	g.Expect(buffer.Put(m)).To(gomega.BeNil())
+	g.Expect(buffer.Put(&TestObject{ID: 6, Name: "Taz"})).To(gomega.BeNil())
+	m.Name = "Gossamer"
+	g.Expect(buffer.Put(m)).To(gomega.BeNil())
+	m.Name = "changed"
+	g.Expect(len(buffer.order)).To(gomega.Equal(2))
+	g.Expect(buffer.pending[buffer.order[0]].model.(*TestObject).ID).To(gomega.Equal(6))
+	g.Expect(buffer.pending[buffer.order[1]].model.(*TestObject).Name).To(gomega.Equal("Gossamer"))
+	// close.
+	g.Expect(buffer.Put(&TestObject{ID: 4, Name: "Sylvester"})).To(gomega.BeNil())
+	err = buffer.Close()
+	g.Expect(err).To(gomega.BeNil())
+	n, _ = DB.Count(&TestObject{}, nil)
+	g.Expect(n).To(gomega.Equal(int64(6)))
+	m = &TestObject{ID: 5}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("Gossamer"))
+	// events (inserted or updated).
+	g.Eventually(func() int {
+		return len(handler.createdIDs()) +
+			len(handler.updatedIDs()) +
+			len(handler.deletedIDs())
+	}).Should(gomega.Equal(8))
+	g.Expect(len(handler.updatedIDs())).To(gomega.Equal(1))
+}
+
+func TestSnapshot(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-snapshot.db", &TestObject{}, &PlainObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+		err = DB.Insert(&PlainObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = DB.Snapshot(
+		func(r *Reader) (err error) {
+			list := []TestObject{}
+			err = r.List(&list, ListOptions{})
+			g.Expect(err).To(gomega.BeNil())
+			g.Expect(len(list)).To(gomega.Equal(3))
+			// changed after the snapshot.
+			err = DB.Insert(&TestObject{ID: 3, Name: "Elmer"})
+			g.Expect(err).To(gomega.BeNil())
+			err = DB.Update(&PlainObject{ID: 0, Name: "Fudd"})
+			g.Expect(err).To(gomega.BeNil())
+			n, err := r.Count(&TestObject{}, nil)
+			g.Expect(err).To(gomega.BeNil())
+			g.Expect(n).To(gomega.Equal(int64(3)))
+			plain := &PlainObject{ID: 0}
+			err = r.Get(plain)
+			g.Expect(err).To(gomega.BeNil())
+			g.Expect(plain.Name).To(gomega.Equal("Elmer"))
+			return
+		})
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(4)))
+	// error returned.
+	failed := errors.New("failed")
+	err = DB.Snapshot(
+		func(r *Reader) error {
+			return failed
+		})
+	g.Expect(err).To(gomega.Equal(failed))
+}
+
 func TestFind(t *testing.T) {
 	var err error
 	g := gomega.NewGomegaWithT(t)
@@ -993,16 +1411,18 @@ func TestWatch(t *testing.T) {
 		err = DB.Delete(object)
 		g.Expect(err).To(gomega.BeNil())
 	}
+	// Wait for the deleted events as well; the check
+	// (previously) repeated created (flagged by vet).
 	for i := 0; i < N; i++ {
 		time.Sleep(time.Millisecond * 10)
 		if len(handlerA.created) != N ||
 			len(handlerA.updated) != N ||
-			len(handlerA.created) != N ||
+			len(handlerA.deleted) != N ||
 			len(handlerB.created) != N ||
 			len(handlerB.updated) != N ||
-			len(handlerB.created) != N ||
+			len(handlerB.deleted) != N ||
 			len(handlerC.created) != N ||
-			len(handlerC.created) != N {
+			len(handlerC.deleted) != N {
 			continue
 		} else {
 			break
@@ -1190,6 +1610,113 @@ func TestCloseDB(t *testing.T) {
 	g.Expect(handler.done).To(gomega.BeTrue())
 }
 
+func TestHealth(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-health.db", &TestObject{})
+	h := HealthOf(DB)
+	g.Expect(h.Open).To(gomega.BeFalse())
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	handler := &TestHandler{name: "A"}
+	watch, err := DB.Watch(&TestObject{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 10; i++ {
+		if !watch.started {
+			time.Sleep(50 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+	h = HealthOf(DB)
+	g.Expect(h.Open).To(gomega.BeTrue())
+	g.Expect(h.Watches).To(gomega.Equal(1))
+	g.Expect(h.Stalled).To(gomega.Equal(0))
+	_ = DB.Close(true)
+	h = HealthOf(DB)
+	g.Expect(h.Open).To(gomega.BeFalse())
+	g.Expect(h.Watches).To(gomega.Equal(0))
+}
+
+func TestFilteredWatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-filtered-watch.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	for i := 0; i < 4; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", Age: i % 2})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	handler := &TestHandler{
+		options: WatchOptions{
+			Snapshot:  true,
+			Predicate: Eq("Age", 1),
+			Filter: func(m Model) bool {
+				return m.(*TestObject).Age == 1
+			},
+		},
+		name: "A",
+	}
+	watch, err := DB.Watch(&TestObject{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 4; i < 6; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", Age: i % 2})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	g.Eventually(func() int {
+		return len(handler.createdIDs())
+	}).Should(gomega.BeNumerically(">=", 3))
+	DB.EndWatch(watch)
+	g.Eventually(handler.ended).Should(gomega.BeTrue())
+	g.Expect(handler.createdIDs()).To(gomega.Equal([]int{1, 3, 5}))
+}
+
+func TestSnapshotWatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-snapshot-watch.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	N := 25
+	for i := N - 1; i >= 0; i-- {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	handler := &TestHandler{
+		options: WatchOptions{
+			Snapshot: true,
+			Page:     7,
+		},
+		name: "A",
+	}
+	watch, err := DB.Watch(&TestObject{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	for i := N; i < N+5; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	g.Eventually(func() int {
+		return len(handler.createdIDs())
+	}).Should(gomega.BeNumerically(">=", N+5))
+	DB.EndWatch(watch)
+	g.Eventually(handler.ended).Should(gomega.BeTrue())
+	_, parity, _ := handler.flags()
+	g.Expect(parity).To(gomega.BeTrue())
+	created := handler.createdIDs()
+	g.Expect(len(created)).To(gomega.Equal(N + 5))
+	snapshot := append([]int{}, created[:N]...)
+	sort.Ints(snapshot)
+	for i := 0; i < N; i++ {
+		g.Expect(snapshot[i]).To(gomega.Equal(i))
+	}
+	g.Expect(created[N:]).To(gomega.Equal([]int{25, 26, 27, 28, 29}))
+	g.Expect(handler.events()[0].model.Name).To(gomega.Equal("Elmer"))
+}
+
 func TestMutatingWatch(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	DB := New("/tmp/test-mutating-watch.db", &TestObject{})
@@ -1232,6 +1759,12 @@ func TestMutatingWatch(t *testing.T) {
 			break
 		}
 	}
+	DB.EndWatch(watchA)
+	DB.EndWatch(watchB)
+	for watchA.Alive() || watchB.Alive() {
+		time.Sleep(time.Millisecond * 10)
+	}
+	_ = DB.Close(false)
 }
 
 func TestExecute(t *testing.T) {
@@ -1554,3 +2087,2107 @@ func fieldNames(fields []*Field) (names []string) {
 
 	return
 }
+
+func TestTracing(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	recorder := &tracing.Recorder{}
+	tracing.Use(recorder)
+	defer tracing.Use(nil)
+	DB := New("/tmp/test-tracing.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	handler := &TracedHandler{}
+	watch, err := DB.Watch(&TestObject{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.EndWatch(watch)
+	ctx, root := tracing.Start(context.TODO(), "test")
+	tx, err := DB.BeginContext(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 0, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	root.End()
+	g.Eventually(func() int {
+		return len(handler.contexts())
+	}).Should(gomega.Equal(1))
+	// statements.
+	rootID := recorder.Find("test")[0].ID
+	executed := recorder.Find("model.db.exec")
+	g.Expect(len(executed) > 0).To(gomega.BeTrue())
+	for _, span := range executed {
+		g.Expect(span.Parent).To(gomega.Equal(rootID))
+		g.Expect(span.Attributes[tracing.Statement]).ToNot(gomega.BeEmpty())
+	}
+	// dispatch.
+	dispatched := recorder.Find("model.watch.dispatch")
+	g.Expect(len(dispatched)).To(gomega.Equal(1))
+	g.Expect(dispatched[0].Parent).To(gomega.Equal(rootID))
+	// delivered.
+	_, delivered := tracing.Start(handler.contexts()[0], "delivered")
+	delivered.End()
+	g.Expect(recorder.Find("delivered")[0].Parent).To(
+		gomega.Equal(dispatched[0].ID))
+	// reads.
+	recorder.Reset()
+	ctx, root = tracing.Start(context.TODO(), "read")
+	err = DB.GetContext(ctx, &TestObject{ID: 0})
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestObject{}
+	err = DB.ListContext(ctx, &list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.CountContext(ctx, &TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	root.End()
+	rootID = recorder.Find("read")[0].ID
+	queried := recorder.Find("model.db.query")
+	g.Expect(len(queried)).To(gomega.Equal(3))
+	for _, span := range queried {
+		g.Expect(span.Parent).To(gomega.Equal(rootID))
+	}
+}
+
+func TestReports(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-reports.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	// transactions.
+	g.Expect(DB.Transactions()).To(gomega.BeEmpty())
+	tx, err := DB.Begin("A")
+	g.Expect(err).To(gomega.BeNil())
+	txs := DB.Transactions()
+	g.Expect(len(txs)).To(gomega.Equal(1))
+	g.Expect(txs[0].Labels).To(gomega.Equal([]string{"A"}))
+	err = tx.Insert(&TestObject{ID: 0, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(DB.Transactions()).To(gomega.BeEmpty())
+	// watches.
+	g.Expect(DB.Watches()).To(gomega.BeEmpty())
+	handler := &TestHandler{}
+	watch, err := DB.Watch(&TestObject{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.EndWatch(watch)
+	err = DB.Insert(&TestObject{ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 100; i++ {
+		if DB.Watches()[0].Delivered < 1 {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+	watches := DB.Watches()
+	g.Expect(len(watches)).To(gomega.Equal(1))
+	g.Expect(watches[0].Kind).To(gomega.Equal("TestObject"))
+	g.Expect(watches[0].Delivered).To(gomega.Equal(uint64(1)))
+	g.Expect(watches[0].LastEvent).ToNot(gomega.BeNil())
+	g.Expect(watches[0].Stalled).To(gomega.BeFalse())
+	g.Expect(watches[0].Handler).To(gomega.Equal("*model.TestHandler"))
+	g.Expect(watches[0].Revision > 0).To(gomega.BeTrue())
+	g.Expect(watches[0].ID).To(gomega.Equal(watch.ID()))
+	// end by ID.
+	err = DB.EndWatchID(watch.ID())
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(DB.Watches()).To(gomega.BeEmpty())
+	err = DB.EndWatchID(watch.ID())
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+func TestManager(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	manager := &Manager{
+		Dir:    "/tmp",
+		Models: []interface{}{&TestObject{}},
+		Purge:  true,
+	}
+	ownerA := &meta.ObjectMeta{Namespace: "ns", Name: "a", UID: "test-a"}
+	ownerB := &meta.ObjectMeta{Namespace: "ns", Name: "b", UID: "test-b"}
+	dbA, err := manager.Open(ownerA)
+	g.Expect(err).To(gomega.BeNil())
+	dbA2, err := manager.Open(ownerA)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(dbA2).To(gomega.BeIdenticalTo(dbA))
+	dbB, err := manager.Open(ownerB)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(manager.Len()).To(gomega.Equal(2))
+	err = dbA.Insert(&TestObject{ID: 0, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	err = dbB.Get(&TestObject{ID: 0})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	list := manager.List()
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].Name).To(gomega.Equal("ns/a"))
+	g.Expect(list[0].Path).To(gomega.Equal("/tmp/objectmeta-test-a.db"))
+	g.Expect(manager.Health().Open).To(gomega.BeTrue())
+	// close (keep file).
+	err = manager.Close(ownerA)
+	g.Expect(err).To(gomega.BeNil())
+	_, found := manager.Get(ownerA)
+	g.Expect(found).To(gomega.BeFalse())
+	_, err = os.Stat(list[0].Path)
+	g.Expect(err).To(gomega.BeNil())
+	// delete.
+	err = manager.Delete(ownerB)
+	g.Expect(err).To(gomega.BeNil())
+	_, err = os.Stat(list[1].Path)
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+	// shutdown.
+	_, err = manager.Open(ownerB)
+	g.Expect(err).To(gomega.BeNil())
+	err = manager.Shutdown()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(manager.Len()).To(gomega.Equal(0))
+	_ = os.Remove(list[0].Path)
+	_ = os.Remove(list[1].Path)
+}
+
+func TestHooks(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-hooks.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	called := []string{}
+	record := func(name string) HookFunc {
+		return func(tx *Tx, m Model) error {
+			called = append(called, name+":"+m.(*TestObject).Name)
+			return nil
+		}
+	}
+	DB.Hook(
+		&TestObject{},
+		Hooks{
+			BeforeInsert: func(tx *Tx, m Model) error {
+				// denormalized.
+				m.(*TestObject).Age = len(m.(*TestObject).Name)
+				return nil
+			},
+			AfterInsert:  record("afterInsert"),
+			BeforeUpdate: record("beforeUpdate"),
+			AfterUpdate:  record("afterUpdate"),
+			AfterDelete:  record("afterDelete"),
+		})
+	m := &TestObject{ID: 0, Name: "Elmer"}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	fetched := &TestObject{ID: 0}
+	g.Expect(DB.Get(fetched)).To(gomega.BeNil())
+	g.Expect(fetched.Age).To(gomega.Equal(5))
+	m.Name = "Larry"
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Delete(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(called).To(
+		gomega.Equal([]string{
+			"afterInsert:Elmer",
+			"beforeUpdate:Larry",
+			"afterUpdate:Larry",
+			"afterDelete:Larry",
+		}))
+	// before hook aborts.
+	DB.Hook(
+		&TestObject{},
+		Hooks{
+			BeforeInsert: func(tx *Tx, m Model) error {
+				return errors.New("denied")
+			},
+		})
+	err = DB.Insert(&TestObject{ID: 1, Name: "Ashley"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	err = DB.Get(&TestObject{ID: 1})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+func TestAudit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-audit.db", &TestObject{}, &Audit{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	mark := time.Now()
+	ctx := WithActor(context.Background(), "elmer")
+	tx, err := DB.BeginContext(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	m := &TestObject{ID: 0, Name: "Elmer", Age: 18}
+	err = tx.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	m.Age = 19
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	// unchanged.
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Delete(m)
+	g.Expect(err).To(gomega.BeNil())
+	// all.
+	list, err := DB.AuditLog(AuditQuery{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(4))
+	g.Expect(list[0].Action).To(gomega.Equal(AuditInsert))
+	g.Expect(list[0].Actor).To(gomega.Equal("elmer"))
+	g.Expect(list[0].Kind).To(gomega.Equal(ref.ToKind(m)))
+	g.Expect(list[0].Model).To(gomega.Equal(m.Pk()))
+	g.Expect(list[1].Action).To(gomega.Equal(AuditUpdate))
+	g.Expect(list[1].Actor).To(gomega.Equal(""))
+	diff, err := list[1].Changes()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(diff["Age"]).To(gomega.Equal([]interface{}{18.0, 19.0}))
+	g.Expect(diff).ToNot(gomega.HaveKey("Name"))
+	g.Expect(list[3].Action).To(gomega.Equal(AuditDelete))
+	g.Expect(list[3].Time().After(mark)).To(gomega.BeTrue())
+	// query.
+	list, err = DB.AuditLog(AuditQuery{Actor: "elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	list, err = DB.AuditLog(AuditQuery{Kind: ref.ToKind(m), PK: m.Pk()})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(4))
+	list, err = DB.AuditLog(AuditQuery{Since: time.Now()})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(0))
+	// request.
+	err = DB.AuditRequest(
+		WithActor(context.Background(), "bugs"),
+		RequestAudit{
+			Method: "POST",
+			Route:  "/things",
+			Path:   "/things",
+			Digest: "abc",
+			Status: 201,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	list, err = DB.AuditLog(AuditQuery{Kind: AuditRequestKind})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Action).To(gomega.Equal(AuditRequest))
+	g.Expect(list[0].Actor).To(gomega.Equal("bugs"))
+	g.Expect(list[0].Model).To(gomega.Equal("POST /things"))
+	request, err := list[0].Request()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(request.Digest).To(gomega.Equal("abc"))
+	g.Expect(request.Status).To(gomega.Equal(201))
+	// retention.
+	n, err := DB.PruneAudit(time.Now())
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(5)))
+	list, err = DB.AuditLog(AuditQuery{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(0))
+	// not enabled.
+	DB2 := New("/tmp/test-audit2.db", &TestObject{})
+	err = DB2.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB2.Close(false)
+	}()
+	err = DB2.Insert(&TestObject{ID: 0, Name: "Larry"})
+	g.Expect(err).To(gomega.BeNil())
+	_, err = DB2.AuditLog(AuditQuery{})
+	g.Expect(errors.Is(err, AuditNotEnabledErr)).To(gomega.BeTrue())
+	err = DB2.AuditRequest(context.Background(), RequestAudit{})
+	g.Expect(errors.Is(err, AuditNotEnabledErr)).To(gomega.BeTrue())
+}
+
+func TestDryRun(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-dry-run.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	err = DB.Insert(&TestObject{ID: 0, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	// tx.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	tx.DryRun()
+	g.Expect(tx.IsDryRun()).To(gomega.BeTrue())
+	g.Expect(len(DB.Transactions())).To(gomega.Equal(1))
+	g.Expect(DB.Transactions()[0].DryRun).To(gomega.BeTrue())
+	err = tx.Insert(&TestObject{ID: 1, Name: "Larry"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Update(&TestObject{ID: 0, Name: "Ashley"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	kind := ref.ToKind(&TestObject{})
+	counters := tx.Counters()
+	g.Expect(*counters[kind]).To(gomega.Equal(Counter{Inserted: 1, Updated: 1}))
+	err = DB.Get(&TestObject{ID: 1})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	m := &TestObject{ID: 0}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("Elmer"))
+	// with.
+	counters, err = DB.DryRun(
+		func(tx *Tx) (err error) {
+			err = tx.Delete(&TestObject{ID: 0})
+			return
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(counters.Total()).To(gomega.Equal(Counter{Deleted: 1}))
+	g.Expect(DB.Get(&TestObject{ID: 0})).To(gomega.BeNil())
+	// validated.
+	_, err = DB.DryRun(
+		func(tx *Tx) (err error) {
+			err = tx.Update(&TestObject{ID: 2, Name: "Elmer"})
+			return
+		})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+type UniqueObject struct {
+	PK    string `sql:"pk(id)"`
+	ID    int    `sql:"key"`
+	Name  string `sql:"unique(a)"`
+	Phone string `sql:"unique(a)"`
+	Email string `sql:"unique(b)"`
+}
+
+func (m *UniqueObject) Pk() string {
+	return m.PK
+}
+
+func TestUnique(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-unique.db", &UniqueObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	m := &UniqueObject{ID: 0, Name: "Elmer", Phone: "1234", Email: "elmer@a.com"}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	// upsert.
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	// same group (partial).
+	err = DB.Insert(&UniqueObject{ID: 1, Name: "Elmer", Phone: "5678", Email: "x"})
+	g.Expect(err).To(gomega.BeNil())
+	// conflict on insert.
+	err = DB.Insert(&UniqueObject{ID: 2, Name: "Elmer", Phone: "1234", Email: "y"})
+	g.Expect(errors.Is(err, AlreadyExistsErr)).To(gomega.BeTrue())
+	ae := &AlreadyExists{}
+	g.Expect(errors.As(err, &ae)).To(gomega.BeTrue())
+	g.Expect(ae.Kind).To(gomega.Equal("UniqueObject"))
+	g.Expect(ae.Group).To(gomega.Equal("a"))
+	g.Expect(ae.Fields).To(gomega.Equal([]string{"Name", "Phone"}))
+	report := liberr.ToReport(err, false)
+	g.Expect(report.Context["fields"]).To(gomega.Equal([]string{"Name", "Phone"}))
+	// conflict on update.
+	err = DB.Update(&UniqueObject{ID: 1, Name: "Larry", Phone: "5678", Email: "elmer@a.com"})
+	g.Expect(errors.Is(err, AlreadyExistsErr)).To(gomega.BeTrue())
+	g.Expect(errors.As(err, &ae)).To(gomega.BeTrue())
+	g.Expect(ae.Group).To(gomega.Equal("b"))
+	g.Expect(ae.Fields).To(gomega.Equal([]string{"Email"}))
+	// other errors.
+	err = DB.Update(&UniqueObject{ID: 3})
+	g.Expect(errors.Is(err, AlreadyExistsErr)).To(gomega.BeFalse())
+}
+
+type NullObject struct {
+	PK      string  `sql:"pk(id)"`
+	ID      int     `sql:"key"`
+	Name    *string `sql:""`
+	Age     *int    `sql:"default=18"`
+	Active  *bool   `sql:"notnull,default=true"`
+	Comment string  `sql:"default=it's"`
+	Label   string  `sql:"default='a, ''b''',d1"`
+}
+
+func (m *NullObject) Pk() string {
+	return m.PK
+}
+
+func TestNullable(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-nullable.db", &NullObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	md, err := Inspect(&NullObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(md.Field("Name").DDL()).To(gomega.Equal("Name TEXT"))
+	g.Expect(md.Field("Age").DDL()).To(gomega.Equal("Age INTEGER DEFAULT 18"))
+	g.Expect(md.Field("Active").DDL()).To(gomega.Equal("Active INTEGER NOT NULL DEFAULT 1"))
+	g.Expect(md.Field("Comment").DDL()).To(gomega.Equal("Comment TEXT NOT NULL DEFAULT 'it''s'"))
+	g.Expect(md.Field("Label").DDL()).To(gomega.Equal("Label TEXT NOT NULL DEFAULT 'a, ''b'''"))
+	g.Expect(md.Field("Label").Detail()).To(gomega.Equal(1))
+	// unset.
+	err = DB.Insert(&NullObject{ID: 0})
+	g.Expect(err).To(gomega.BeNil())
+	m := &NullObject{ID: 0}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.BeNil())
+	g.Expect(*m.Age).To(gomega.Equal(18))
+	g.Expect(*m.Active).To(gomega.BeTrue())
+	// empty.
+	empty := ""
+	zero := 0
+	no := false
+	err = DB.Insert(&NullObject{ID: 1, Name: &empty, Age: &zero, Active: &no})
+	g.Expect(err).To(gomega.BeNil())
+	m = &NullObject{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Name).ToNot(gomega.BeNil())
+	g.Expect(*m.Name).To(gomega.Equal(""))
+	g.Expect(*m.Age).To(gomega.Equal(0))
+	g.Expect(*m.Active).To(gomega.BeFalse())
+	// cleared.
+	m.Name = nil
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	m = &NullObject{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.BeNil())
+	// predicate.
+	list := []NullObject{}
+	err = DB.List(&list, ListOptions{Predicate: Eq("Age", 18)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	// null scanned as zero value.
+	m.Comment = "hello"
+	md, _ = Inspect(m)
+	f := md.Field("Comment")
+	err = f.Ptr().(interface{ Scan(interface{}) error }).Scan(nil)
+	g.Expect(err).To(gomega.BeNil())
+	f.Push()
+	g.Expect(m.Comment).To(gomega.Equal(""))
+	// not valid.
+	type BadDefault struct {
+		PK  string `sql:"pk"`
+		Age int    `sql:"default=old"`
+	}
+	_, err = Inspect(&BadDefault{})
+	g.Expect(errors.Is(err, DefaultErr)).To(gomega.BeTrue())
+}
+
+type TimeObject struct {
+	PK       string        `sql:"pk(id)"`
+	ID       int           `sql:"key"`
+	Created  time.Time     `sql:""`
+	Updated  *time.Time    `sql:""`
+	Deadline time.Time     `sql:"unix"`
+	Timeout  time.Duration `sql:""`
+	Seen     time.Time
+	Expired  *time.Time
+}
+
+func (m *TimeObject) Pk() string {
+	return m.PK
+}
+
+func TestTime(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-time.db", &TimeObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	md, err := Inspect(&TimeObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(md.Field("Created").DDL()).To(gomega.Equal("Created TEXT NOT NULL"))
+	g.Expect(md.Field("Updated").DDL()).To(gomega.Equal("Updated TEXT"))
+	g.Expect(md.Field("Deadline").DDL()).To(gomega.Equal("Deadline INTEGER NOT NULL"))
+	g.Expect(md.Field("Timeout").DDL()).To(gomega.Equal("Timeout INTEGER NOT NULL"))
+	g.Expect(md.Field("Seen")).To(gomega.BeNil())
+	g.Expect(md.Field("Expired")).To(gomega.BeNil())
+	est := time.FixedZone("EST", -5*3600)
+	base := time.Date(2020, 6, 1, 8, 30, 0, 1000, est)
+	for i := 0; i < 5; i++ {
+		updated := base.Add(time.Duration(i) * time.Hour)
+		m := &TimeObject{
+			ID:       i,
+			Created:  base.Add(time.Duration(i) * time.Minute),
+			Deadline: base.Add(time.Duration(i) * 24 * time.Hour),
+			Timeout:  time.Duration(i) * time.Second,
+		}
+		if i%2 == 0 {
+			m.Updated = &updated
+		}
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	m := &TimeObject{ID: 2}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Created.Equal(base.Add(2 * time.Minute))).To(gomega.BeTrue())
+	g.Expect(m.Created.Location()).To(gomega.Equal(time.UTC))
+	g.Expect(m.Updated).ToNot(gomega.BeNil())
+	g.Expect(m.Updated.Equal(base.Add(2 * time.Hour))).To(gomega.BeTrue())
+	g.Expect(m.Deadline.Equal(base.Add(48 * time.Hour))).To(gomega.BeTrue())
+	g.Expect(m.Timeout).To(gomega.Equal(2 * time.Second))
+	m = &TimeObject{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Updated).To(gomega.BeNil())
+	// predicates.
+	list := []TimeObject{}
+	err = DB.List(&list, ListOptions{Predicate: Gt("Created", base.Add(2*time.Minute))})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	err = DB.List(&list, ListOptions{Predicate: Lt("Deadline", base.Add(24*time.Hour).UTC())})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	err = DB.List(&list, ListOptions{Predicate: Eq("Created", base.In(time.UTC).Format(time.RFC3339Nano))})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	err = DB.List(&list, ListOptions{Predicate: Gt("Timeout", "2500ms")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	err = DB.List(&list, ListOptions{Predicate: Lt("Timeout", time.Second)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	// location.
+	TimeLocation = est
+	defer func() {
+		TimeLocation = time.UTC
+	}()
+	m = &TimeObject{ID: 0}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Created).To(gomega.Equal(base))
+}
+
+type EnumObject struct {
+	PK       string  `sql:"pk(id)"`
+	ID       int     `sql:"key"`
+	Phase    string  `sql:"enum(New,Running,Done),index(a)"`
+	Priority int     `sql:"enum(1,2,3)"`
+	Next     *string `sql:"enum(Running,Done)"`
+}
+
+func (m *EnumObject) Pk() string {
+	return m.PK
+}
+
+func TestEnum(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-enum.db", &EnumObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	md, err := Inspect(&EnumObject{})
+	g.Expect(err).To(gomega.BeNil())
+	f := md.Field("Phase")
+	g.Expect(f.Enum()).To(gomega.Equal([]string{"New", "Running", "Done"}))
+	g.Expect(f.Index()).To(gomega.Equal([]string{"a"}))
+	g.Expect(f.DDL()).To(
+		gomega.Equal("Phase TEXT NOT NULL CHECK (Phase IN ('New','Running','Done'))"))
+	g.Expect(md.Field("Priority").DDL()).To(
+		gomega.Equal("Priority INTEGER NOT NULL CHECK (Priority IN (1,2,3))"))
+	// valid.
+	m := &EnumObject{ID: 0, Phase: "New", Priority: 1}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	next := "Done"
+	m.Next = &next
+	m.Priority = 3
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	// not valid.
+	err = DB.Insert(&EnumObject{ID: 1, Phase: "Junk", Priority: 1})
+	g.Expect(errors.Is(err, EnumErr)).To(gomega.BeTrue())
+	ee := &EnumError{}
+	g.Expect(errors.As(err, &ee)).To(gomega.BeTrue())
+	g.Expect(ee.Field).To(gomega.Equal("Phase"))
+	g.Expect(ee.Value).To(gomega.Equal("Junk"))
+	g.Expect(ee.Values).To(gomega.Equal([]string{"New", "Running", "Done"}))
+	m.Priority = 4
+	err = DB.Update(m)
+	g.Expect(errors.Is(err, EnumErr)).To(gomega.BeTrue())
+	next = "New"
+	m.Priority = 2
+	err = DB.Update(m)
+	g.Expect(errors.Is(err, EnumErr)).To(gomega.BeTrue())
+	// check constraint.
+	_, err = DB.Execute("UPDATE EnumObject SET Phase = 'Junk';")
+	g.Expect(err).ToNot(gomega.BeNil())
+	// enum type not valid.
+	type BadEnum struct {
+		PK   string `sql:"pk"`
+		Flag bool   `sql:"enum(true)"`
+	}
+	_, err = Inspect(&BadEnum{})
+	g.Expect(errors.Is(err, FieldTypeErr)).To(gomega.BeTrue())
+}
+
+type BlobObject struct {
+	PK    string `sql:"pk(id)"`
+	ID    int    `sql:"key"`
+	Thumb []byte `sql:"blob,max=16"`
+	Raw   []byte `sql:""`
+}
+
+func (m *BlobObject) Pk() string {
+	return m.PK
+}
+
+func TestBlob(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-blob.db", &BlobObject{}, &BlobChunk{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	md, err := Inspect(&BlobObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(md.Field("Thumb").DDL()).To(gomega.Equal("Thumb BLOB NOT NULL"))
+	g.Expect(md.Field("Raw").DDL()).To(gomega.Equal("Raw TEXT NOT NULL"))
+	// field.
+	m := &BlobObject{ID: 0, Thumb: []byte{0, 1, 2, 0xff}}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	fetched := &BlobObject{ID: 0}
+	err = DB.Get(fetched)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(fetched.Thumb).To(gomega.Equal([]byte{0, 1, 2, 0xff}))
+	m.Thumb = make([]byte, 17)
+	err = DB.Update(m)
+	g.Expect(errors.Is(err, BlobSizeErr)).To(gomega.BeTrue())
+	// streamed.
+	chunkSize := BlobChunkSize
+	BlobChunkSize = 10
+	defer func() {
+		BlobChunkSize = chunkSize
+	}()
+	content := []byte("The quick brown fox jumps over the lazy dog.")
+	n, err := DB.PutBlob(m, "report", bytes.NewReader(content))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(len(content))))
+	n, err = DB.PutBlob(m, "empty", bytes.NewReader([]byte{}))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	chunks, err := DB.Count(&BlobChunk{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(chunks).To(gomega.Equal(int64(6)))
+	reader, err := DB.GetBlob(m, "report")
+	g.Expect(err).To(gomega.BeNil())
+	read, err := ioutil.ReadAll(reader)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(read).To(gomega.Equal(content))
+	_ = reader.Close()
+	reader, err = DB.GetBlob(m, "empty")
+	g.Expect(err).To(gomega.BeNil())
+	read, err = ioutil.ReadAll(reader)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(read)).To(gomega.Equal(0))
+	_, err = DB.GetBlob(m, "none")
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	// replaced.
+	n, err = DB.PutBlob(m, "report", bytes.NewReader(content[:4]))
+	g.Expect(err).To(gomega.BeNil())
+	reader, err = DB.GetBlob(m, "report")
+	g.Expect(err).To(gomega.BeNil())
+	read, err = ioutil.ReadAll(reader)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(read).To(gomega.Equal(content[:4]))
+	// limit.
+	maxSize := MaxBlobSize
+	MaxBlobSize = 20
+	defer func() {
+		MaxBlobSize = maxSize
+	}()
+	_, err = DB.PutBlob(m, "report", bytes.NewReader(content))
+	g.Expect(errors.Is(err, BlobSizeErr)).To(gomega.BeTrue())
+	reader, err = DB.GetBlob(m, "report")
+	g.Expect(err).To(gomega.BeNil())
+	read, err = ioutil.ReadAll(reader)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(read).To(gomega.Equal(content[:4]))
+	// deleted with the model.
+	err = DB.Delete(m)
+	g.Expect(err).To(gomega.BeNil())
+	chunks, err = DB.Count(&BlobChunk{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(chunks).To(gomega.Equal(int64(0)))
+	// not enabled.
+	DB2 := New("/tmp/test-blob2.db", &BlobObject{})
+	err = DB2.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB2.Close(false)
+	}()
+	err = DB2.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	_, err = DB2.PutBlob(m, "report", bytes.NewReader(content))
+	g.Expect(errors.Is(err, BlobNotEnabledErr)).To(gomega.BeTrue())
+	err = DB2.Delete(m)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+type RetentionHandler struct {
+	StockEventHandler
+	gate    chan struct{}
+	blocked chan struct{}
+	events  []string
+	seq     []uint64
+	errors  int
+	resets  int
+	reasons []string
+	mutex   sync.Mutex
+}
+
+func (h *RetentionHandler) record(action string, e Event, m Model) {
+	object := m.(*TestObject)
+	if object.ID == 100 {
+		h.blocked <- struct{}{}
+		<-h.gate
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.events = append(h.events, action+":"+object.Name)
+	h.seq = append(h.seq, e.Seq)
+}
+
+func (h *RetentionHandler) Created(e Event) {
+	h.record("C", e, e.Model)
+}
+
+func (h *RetentionHandler) Updated(e Event) {
+	h.record("U", e, e.Updated)
+}
+
+func (h *RetentionHandler) Deleted(e Event) {
+	h.record("D", e, e.Model)
+}
+
+func (h *RetentionHandler) Reset(reason string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.resets++
+	h.reasons = append(h.reasons, reason)
+}
+
+func (h *RetentionHandler) Error(error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.errors++
+}
+
+func (h *RetentionHandler) delivered(n int) (events []string) {
+	for i := 0; i < 100; i++ {
+		h.mutex.Lock()
+		events = h.events
+		h.mutex.Unlock()
+		if len(events) >= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return
+}
+
+func TestRetention(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-retention.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	watch := func() (h *RetentionHandler, w *Watch) {
+		h = &RetentionHandler{
+			gate:    make(chan struct{}),
+			blocked: make(chan struct{}),
+		}
+		w, err := DB.Watch(&TestObject{}, h)
+		g.Expect(err).To(gomega.BeNil())
+		err = DB.Insert(&TestObject{ID: 100, Name: "blocker"})
+		g.Expect(err).To(gomega.BeNil())
+		<-h.blocked
+		return
+	}
+	// compaction.
+	DB.SetRetention(Retention{MaxEvents: 3, Compact: true})
+	h, w := watch()
+	g.Expect(DB.Insert(&TestObject{ID: 0, Name: "A"})).To(gomega.BeNil())
+	g.Expect(DB.Update(&TestObject{ID: 0, Name: "B"})).To(gomega.BeNil())
+	g.Expect(DB.Update(&TestObject{ID: 0, Name: "C"})).To(gomega.BeNil())
+	g.Expect(DB.Insert(&TestObject{ID: 1, Name: "D"})).To(gomega.BeNil())
+	g.Expect(DB.Delete(&TestObject{ID: 1})).To(gomega.BeNil())
+	g.Expect(DB.Insert(&TestObject{ID: 2, Name: "E"})).To(gomega.BeNil())
+	report := DB.Watches()[0]
+	g.Expect(report.Queued).To(gomega.Equal(2))
+	g.Expect(report.Compacted).To(gomega.Equal(uint64(4)))
+	g.Expect(report.Bytes > 0).To(gomega.BeTrue())
+	close(h.gate)
+	g.Expect(h.delivered(2)).To(gomega.Equal([]string{"C:C", "C:E"}))
+	g.Expect(h.seq).To(gomega.Equal([]uint64{2, 3}))
+	g.Expect(h.errors).To(gomega.Equal(0))
+	g.Expect(h.resets).To(gomega.Equal(0))
+	DB.EndWatch(w)
+	g.Expect(HealthOf(DB).Compacted).To(gomega.Equal(uint64(4)))
+	// discarded.
+	DB.SetRetention(Retention{MaxEvents: 2})
+	h, w = watch()
+	for i := 0; i < 4; i++ {
+		err = DB.Update(&TestObject{ID: 0, Name: fmt.Sprintf("N%d", i)})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	close(h.gate)
+	g.Expect(h.delivered(2)).To(gomega.Equal([]string{"U:N2", "U:N3"}))
+	g.Expect(h.seq).To(gomega.Equal([]uint64{2, 3}))
+	g.Expect(h.errors).To(gomega.Equal(2))
+	g.Expect(h.resets).To(gomega.Equal(1))
+	g.Expect(h.reasons).To(gomega.Equal([]string{ResetDiscarded}))
+	DB.EndWatch(w)
+	g.Expect(HealthOf(DB).Discarded).To(gomega.Equal(uint64(2)))
+	// max age.
+	DB.SetRetention(Retention{MaxAge: 5 * time.Millisecond})
+	h, w = watch()
+	g.Expect(DB.Update(&TestObject{ID: 0, Name: "old"})).To(gomega.BeNil())
+	time.Sleep(20 * time.Millisecond)
+	g.Expect(DB.Update(&TestObject{ID: 0, Name: "new"})).To(gomega.BeNil())
+	close(h.gate)
+	g.Expect(h.delivered(1)).To(gomega.Equal([]string{"U:new"}))
+	DB.EndWatch(w)
+	g.Expect(HealthOf(DB).Discarded).To(gomega.Equal(uint64(3)))
+	// queue full.
+	DB.SetRetention(Retention{})
+	capacity := QueueCapacity
+	QueueCapacity = 2
+	defer func() {
+		QueueCapacity = capacity
+	}()
+	h, w = watch()
+	for i := 0; i < 4; i++ {
+		err = DB.Update(&TestObject{ID: 0, Name: fmt.Sprintf("F%d", i)})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	close(h.gate)
+	g.Expect(h.delivered(1)).To(gomega.Equal([]string{"U:F3"}))
+	g.Expect(h.resets).To(gomega.Equal(1))
+	DB.EndWatch(w)
+	g.Expect(HealthOf(DB).Discarded).To(gomega.Equal(uint64(6)))
+}
+
+func TestDecode(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-decode.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	m := &TestObject{
+		ID:     0,
+		Name:   "Elmer",
+		Object: TestEncoded{Name: "json"},
+	}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	execute := func(sql string) {
+		tx, err := DB.Begin()
+		g.Expect(err).To(gomega.BeNil())
+		_, err = tx.Execute(sql)
+		g.Expect(err).To(gomega.BeNil())
+		err = tx.Commit()
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// malformed (tolerated).
+	execute("UPDATE TestObject SET Object = '{\"Name\":';")
+	got := &TestObject{ID: 0}
+	err = DB.Get(got)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.Name).To(gomega.Equal("Elmer"))
+	g.Expect(got.Object.Name).To(gomega.Equal(""))
+	// malformed (strict).
+	StrictDecode = true
+	defer func() {
+		StrictDecode = false
+	}()
+	err = DB.Get(got)
+	g.Expect(errors.Is(err, DecodeErr)).To(gomega.BeTrue())
+	// type mismatch.
+	execute("UPDATE TestObject SET Object = '[1,2]';")
+	err = DB.Get(got)
+	g.Expect(errors.Is(err, DecodeErr)).To(gomega.BeTrue())
+	// size limit.
+	execute("UPDATE TestObject SET Object = '{\"Name\":\"json\"}';")
+	saved := MaxEncodedSize
+	MaxEncodedSize = 4
+	err = DB.Get(got)
+	MaxEncodedSize = saved
+	g.Expect(errors.Is(err, DecodeErr)).To(gomega.BeTrue())
+	// valid.
+	err = DB.Get(got)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.Object.Name).To(gomega.Equal("json"))
+}
+
+func TestRelist(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-relist.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	h := &RetentionHandler{
+		gate:    make(chan struct{}),
+		blocked: make(chan struct{}),
+	}
+	w, err := DB.Watch(&TestObject{}, h)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.EndWatch(w)
+	err = DB.Insert(&TestObject{ID: 100, Name: "blocker"})
+	g.Expect(err).To(gomega.BeNil())
+	<-h.blocked
+	// stale events discarded.
+	g.Expect(DB.Insert(&TestObject{ID: 0, Name: "A"})).To(gomega.BeNil())
+	DB.Relist(ResetRebuilt)
+	close(h.gate)
+	g.Expect(DB.Insert(&TestObject{ID: 1, Name: "B"})).To(gomega.BeNil())
+	g.Expect(h.delivered(1)).To(gomega.Equal([]string{"C:B"}))
+	h.mutex.Lock()
+	g.Expect(h.resets).To(gomega.Equal(1))
+	g.Expect(h.reasons).To(gomega.Equal([]string{ResetRebuilt}))
+	h.mutex.Unlock()
+	g.Expect(DB.Watches()[0].Discarded).To(gomega.Equal(uint64(1)))
+}
+
+func TestRowLimit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-row-limit.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	// reject.
+	DB.SetLimit(&TestObject{}, RowLimit{Max: 3})
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = DB.Insert(&TestObject{ID: 3, Name: "Elmer"})
+	g.Expect(errors.Is(err, RowLimitErr)).To(gomega.BeTrue())
+	err = DB.Insert(&TestObject{ID: 2, Name: "Fudd"})
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(3)))
+	// evict oldest.
+	DB.SetLimit(
+		&TestObject{},
+		RowLimit{
+			Max:    3,
+			Policy: EvictOldest,
+			Field:  "ID",
+		})
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	for i := 3; i < 5; i++ {
+		err = tx.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Sort: []int{2}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	for i, m := range list {
+		g.Expect(m.ID).To(gomega.Equal(i + 2))
+	}
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{SortBy: []string{"ID"}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(list[0].ID).To(gomega.Equal(2))
+	err = DB.List(&list, ListOptions{SortBy: []string{"Unknown"}})
+	g.Expect(err).ToNot(gomega.BeNil())
+	// reject (counted once by the tx).
+	DB.SetLimit(&TestObject{}, RowLimit{Max: 4})
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 5, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 6, Name: "Elmer"})
+	g.Expect(errors.Is(err, RowLimitErr)).To(gomega.BeTrue())
+	err = tx.Delete(&TestObject{ID: 2})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 6, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.End()
+	g.Expect(err).To(gomega.BeNil())
+	DB.SetLimit(
+		&TestObject{},
+		RowLimit{
+			Max:    3,
+			Policy: EvictOldest,
+			Field:  "ID",
+		})
+	reports := DB.Limits()
+	g.Expect(len(reports)).To(gomega.Equal(1))
+	g.Expect(reports[0].Evicted).To(gomega.Equal(uint64(2)))
+	g.Expect(reports[0].Rejected).To(gomega.Equal(uint64(2)))
+	// removed.
+	DB.SetLimit(&TestObject{}, RowLimit{})
+	err = DB.Insert(&TestObject{ID: 5, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(DB.Limits()).To(gomega.BeEmpty())
+}
+
+type DocumentHandler struct {
+	StockEventHandler
+	mutex   sync.Mutex
+	created []string
+}
+
+func (r *DocumentHandler) Created(event Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.created = append(r.created, event.Model.(*Document).ID)
+}
+
+func (r *DocumentHandler) received() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.created...)
+}
+
+func TestDocuments(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-documents.db", &Document{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	docs := &Documents{DB: DB}
+	// not registered.
+	_, err = docs.Create("Plugin", "p0", nil)
+	g.Expect(errors.Is(err, DocumentKindErr)).To(gomega.BeTrue())
+	err = docs.Register(
+		"Plugin",
+		[]byte(`{
+			"type": "object",
+			"required": ["name"],
+			"additionalProperties": false,
+			"properties": {
+				"name": {"type": "string"},
+				"size": {"type": "integer"},
+				"mode": {"enum": ["A", "B"]},
+				"tags": {"type": "array", "items": {"type": "string"}}
+			}
+		}`))
+	g.Expect(err).To(gomega.BeNil())
+	err = docs.Register("Note", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(docs.Kinds())).To(gomega.Equal(2))
+	// watch.
+	handler := &DocumentHandler{}
+	w, err := docs.Watch("Plugin", handler)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.EndWatch(w)
+	// validated.
+	for _, content := range []map[string]interface{}{
+		{"size": 1},
+		{"name": 1},
+		{"name": "A", "size": 1.5},
+		{"name": "A", "mode": "C"},
+		{"name": "A", "tags": []interface{}{"a", 2}},
+		{"name": "A", "other": true},
+	} {
+		_, err = docs.Create("Plugin", "p0", content)
+		g.Expect(errors.Is(err, SchemaErr)).To(gomega.BeTrue())
+	}
+	// create.
+	doc, err := docs.Create(
+		"Plugin",
+		"p1",
+		map[string]interface{}{
+			"name": "One",
+			"size": 1,
+			"mode": "A",
+			"tags": []string{"a", "b"},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(doc.PK).ToNot(gomega.BeEmpty())
+	_, err = docs.Create("Plugin", "p2", map[string]interface{}{"name": "Two"})
+	g.Expect(err).To(gomega.BeNil())
+	_, err = docs.Create("Note", "p1", map[string]interface{}{"any": 1})
+	g.Expect(err).To(gomega.BeNil())
+	// get.
+	doc, err = docs.Get("Plugin", "p1")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(doc.Content["name"]).To(gomega.Equal("One"))
+	g.Expect(doc.Content["size"]).To(gomega.Equal(float64(1)))
+	// update.
+	doc.Content["size"] = 2
+	err = docs.Update(doc)
+	g.Expect(err).To(gomega.BeNil())
+	doc, err = docs.Get("Plugin", "p1")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(doc.Content["size"]).To(gomega.Equal(float64(2)))
+	doc.Content["size"] = "big"
+	err = docs.Update(doc)
+	g.Expect(errors.Is(err, SchemaErr)).To(gomega.BeTrue())
+	// list.
+	list, err := docs.List("Plugin", ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	list, err = docs.List("Note", ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Content["any"]).To(gomega.Equal(float64(1)))
+	// delete.
+	err = docs.Delete("Plugin", "p2")
+	g.Expect(err).To(gomega.BeNil())
+	_, err = docs.Get("Plugin", "p2")
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	// watched (kind).
+	for i := 0; i < 100; i++ {
+		if len(handler.received()) < 2 {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	g.Expect(handler.received()).To(gomega.Equal([]string{"p1", "p2"}))
+}
+
+func TestOutbox(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-outbox.db", &TestObject{}, &Outbox{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	mutex := sync.Mutex{}
+	delivered := []string{}
+	failures := 2
+	dispatcher := &OutboxDispatcher{
+		DB:          DB,
+		Retry:       time.Millisecond * 10,
+		MaxAttempts: 3,
+		Senders: map[string]Sender{
+			"hook": SenderFunc(func(_ context.Context, n *Outbox) (err error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				if failures > 0 {
+					failures--
+					err = errors.New("failed")
+					return
+				}
+				payload := map[string]string{}
+				err = n.Decode(&payload)
+				if err == nil {
+					delivered = append(delivered, payload["name"])
+				}
+				return
+			}),
+			"dead": SenderFunc(func(context.Context, *Outbox) error {
+				return errors.New("failed")
+			}),
+		},
+	}
+	err = dispatcher.Start()
+	g.Expect(err).To(gomega.BeNil())
+	defer dispatcher.Shutdown()
+	received := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string{}, delivered...)
+	}
+	// rolled back (no phantom).
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 0, Name: "A"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Notify("hook", map[string]string{"name": "A"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.End()
+	g.Expect(err).To(gomega.BeNil())
+	// committed (retried).
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 1, Name: "B"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Notify("hook", map[string]string{"name": "B"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Notify("dead", map[string]string{"name": "C"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 200; i++ {
+		n, _ := DB.Count(&Outbox{}, Eq("Dead", false))
+		if len(received()) < 1 || n > 0 {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+	g.Expect(received()).To(gomega.Equal([]string{"B"}))
+	list := []Outbox{}
+	err = DB.List(&list, ListOptions{Detail: MaxDetail})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Topic).To(gomega.Equal("dead"))
+	g.Expect(list[0].Dead).To(gomega.BeTrue())
+	g.Expect(list[0].Attempts).To(gomega.Equal(3))
+	g.Expect(list[0].Error).To(gomega.Equal("failed"))
+	// dead (retention).
+	dispatcher.Retain = time.Nanosecond
+	_, err = dispatcher.Dispatch()
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.Count(&Outbox{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+}
+
+func TestOutboxTimeout(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-outbox-timeout.db", &Outbox{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	blocked := make(chan struct{})
+	defer close(blocked)
+	dispatcher := &OutboxDispatcher{
+		DB:      DB,
+		Timeout: time.Millisecond * 10,
+		Senders: map[string]Sender{
+			"slow": SenderFunc(func(context.Context, *Outbox) error {
+				<-blocked
+				return nil
+			}),
+		},
+	}
+	err = DB.With(func(tx *Tx) error {
+		return tx.Notify("slow", map[string]string{"name": "A"})
+	})
+	g.Expect(err).To(gomega.BeNil())
+	_, err = dispatcher.Dispatch()
+	g.Expect(err).To(gomega.BeNil())
+	list := []Outbox{}
+	err = DB.List(&list, ListOptions{Detail: MaxDetail})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Attempts).To(gomega.Equal(1))
+	g.Expect(list[0].Error).To(gomega.ContainSubstring("timed out"))
+}
+
+func TestDrain(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-drain.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	h := &RetentionHandler{
+		gate:    make(chan struct{}),
+		blocked: make(chan struct{}),
+	}
+	_, err = DB.Watch(&TestObject{}, h)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 100, Name: "blocker"})
+	g.Expect(err).To(gomega.BeNil())
+	<-h.blocked
+	g.Expect(DB.Insert(&TestObject{ID: 0, Name: "A"})).To(gomega.BeNil())
+	g.Expect(DB.Insert(&TestObject{ID: 1, Name: "B"})).To(gomega.BeNil())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(h.gate)
+	}()
+	// queued events delivered.
+	err = DB.Drain(context.Background())
+	g.Expect(err).To(gomega.BeNil())
+	h.mutex.Lock()
+	g.Expect(h.events).To(gomega.Equal([]string{"C:A", "C:B"}))
+	h.mutex.Unlock()
+	g.Expect(DB.Watches()).To(gomega.BeEmpty())
+	// context done.
+	h = &RetentionHandler{
+		gate:    make(chan struct{}),
+		blocked: make(chan struct{}),
+	}
+	_, err = DB.Watch(&TestObject{}, h)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Update(&TestObject{ID: 100, Name: "blocker"})
+	g.Expect(err).To(gomega.BeNil())
+	<-h.blocked
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = DB.Drain(ctx)
+	g.Expect(errors.Is(err, context.DeadlineExceeded)).To(gomega.BeTrue())
+	close(h.gate)
+}
+
+func TestSchema(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-schema.db",
+		&DetailA{},
+		&PlainObject{},
+		&UniqueObject{},
+		&TestObject{})
+	_, err := DB.Schema()
+	g.Expect(err).ToNot(gomega.BeNil())
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	schema, err := DB.Schema()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(schema.DDL) > 0).To(gomega.BeTrue())
+	tables := map[string]TableSchema{}
+	order := map[string]int{}
+	for i, table := range schema.Tables {
+		tables[table.Name] = table
+		order[table.Name] = i
+	}
+	g.Expect(order["PlainObject"] < order["DetailA"]).To(gomega.BeTrue())
+	// columns.
+	plain := tables["PlainObject"]
+	g.Expect(plain.Columns).To(gomega.Equal(
+		[]ColumnSchema{
+			{Name: "ID", Type: "INTEGER", Pk: true},
+			{Name: "Name", Type: "TEXT"},
+			{Name: "Age", Type: "INTEGER"},
+		}))
+	// relations.
+	detail := tables["DetailA"]
+	g.Expect(detail.Relations).To(gomega.Equal(
+		[]RelationSchema{
+			{
+				Column:  "FK",
+				Table:   "PlainObject",
+				Field:   "ID",
+				Must:    true,
+				Cascade: true,
+			},
+		}))
+	// indexes.
+	unique := tables["UniqueObject"]
+	g.Expect(unique.Indexes).To(gomega.Equal(
+		[]IndexSchema{
+			{Name: "UniqueObjectIndex", Columns: []string{"ID"}},
+			{Name: "a", Unique: true, Columns: []string{"Name", "Phone"}},
+			{Name: "b", Unique: true, Columns: []string{"Email"}},
+		}))
+	object := tables["TestObject"]
+	g.Expect(object.Indexes).To(gomega.ContainElement(
+		IndexSchema{Name: "TestObjectaIndex", Columns: []string{"Name", "Age"}}))
+	for _, column := range object.Columns {
+		switch column.Name {
+		case "PK":
+			g.Expect(column.Pk).To(gomega.BeTrue())
+		case "ID":
+			g.Expect(column.Key).To(gomega.BeTrue())
+		case "Object":
+			g.Expect(column.Encoded).To(gomega.BeTrue())
+			g.Expect(column.Type).To(gomega.Equal("TEXT"))
+		}
+	}
+}
+
+type PurgeObject struct {
+	ID      int       `sql:"pk"`
+	Owner   string    `sql:""`
+	Created time.Time `sql:""`
+}
+
+func (m *PurgeObject) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m *PurgeObject) String() string {
+	return m.Pk()
+}
+
+func (m *PurgeObject) Labels() Labels {
+	return nil
+}
+
+func TestPurge(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-purge.db", &PurgeObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	now := time.Now()
+	// owner A: 0-5 (0,1 expired); owner B: 6-7.
+	for i := 0; i < 8; i++ {
+		m := &PurgeObject{
+			ID:      i,
+			Owner:   "A",
+			Created: now.Add(-time.Hour * time.Duration(8-i)),
+		}
+		if i > 5 {
+			m.Owner = "B"
+		}
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	count := func() (ids []int) {
+		list := []PurgeObject{}
+		err := DB.List(&list, ListOptions{})
+		g.Expect(err).To(gomega.BeNil())
+		for _, m := range list {
+			ids = append(ids, m.ID)
+		}
+		sort.Ints(ids)
+		return
+	}
+	// invalid.
+	purger := &Purger{
+		DB: DB,
+		Policies: []RetentionPolicy{
+			{Kind: &PurgeObject{}, MaxAge: time.Hour},
+		},
+	}
+	g.Expect(purger.Start()).ToNot(gomega.BeNil())
+	// dry-run.
+	purger = &Purger{
+		DB:     DB,
+		DryRun: true,
+		Policies: []RetentionPolicy{
+			{
+				Kind:     &PurgeObject{},
+				Field:    "Created",
+				MaxAge:   time.Hour*6 + time.Minute,
+				KeepLast: 2,
+				GroupBy:  []string{"Owner"},
+			},
+		},
+	}
+	reports, err := purger.Purge()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(reports)).To(gomega.Equal(1))
+	g.Expect(reports[0].Kind).To(gomega.Equal("PurgeObject"))
+	g.Expect(reports[0].Expired).To(gomega.Equal(int64(2)))
+	g.Expect(reports[0].Trimmed).To(gomega.Equal(int64(2)))
+	g.Expect(reports[0].DryRun).To(gomega.BeTrue())
+	g.Expect(count()).To(gomega.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7}))
+	// purged.
+	purger.DryRun = false
+	reports, err = purger.Purge()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(reports[0].Expired).To(gomega.Equal(int64(2)))
+	g.Expect(reports[0].Trimmed).To(gomega.Equal(int64(2)))
+	g.Expect(count()).To(gomega.Equal([]int{4, 5, 6, 7}))
+	cumulative := purger.Reports()
+	g.Expect(cumulative[0].Expired).To(gomega.Equal(int64(4)))
+	// background.
+	purger.Policies[0].KeepLast = 1
+	purger.Policies[0].GroupBy = nil
+	purger.Interval = time.Hour
+	err = purger.Start()
+	g.Expect(err).To(gomega.BeNil())
+	g.Eventually(count).Should(gomega.Equal([]int{7}))
+	purger.Shutdown()
+}
+
+func TestDispatcher(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-dispatcher.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	quantum := DispatchQuantum
+	DispatchQuantum = 3
+	defer func() {
+		DispatchQuantum = quantum
+	}()
+	DB.SetDispatchWorkers(2)
+	N := 20
+	expected := []int{}
+	for i := 0; i < N; i++ {
+		expected = append(expected, i)
+		if i < N/2 {
+			err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+			g.Expect(err).To(gomega.BeNil())
+		}
+	}
+	handlers := []*TestHandler{}
+	for i := 0; i < 40; i++ {
+		h := &TestHandler{options: WatchOptions{Snapshot: true}}
+		_, err = DB.Watch(&TestObject{}, h)
+		g.Expect(err).To(gomega.BeNil())
+		handlers = append(handlers, h)
+	}
+	for i := N / 2; i < N; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(HealthOf(DB).Workers <= 2).To(gomega.BeTrue())
+	}
+	err = DB.Drain(context.Background())
+	g.Expect(err).To(gomega.BeNil())
+	for _, h := range handlers {
+		g.Expect(h.parity).To(gomega.BeTrue())
+		g.Expect(h.created).To(gomega.Equal(expected))
+		g.Expect(h.done).To(gomega.BeTrue())
+	}
+	g.Eventually(func() int {
+		return HealthOf(DB).Workers
+	}).Should(gomega.Equal(0))
+}
+
+func TestWatchTransform(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-watch-transform.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	err = DB.Insert(&TestObject{ID: 0, Name: "Elmer", Age: 10})
+	g.Expect(err).To(gomega.BeNil())
+	trusted := &TestHandler{
+		options: WatchOptions{Snapshot: true},
+	}
+	redacted := &TestHandler{
+		options: WatchOptions{
+			Snapshot:  true,
+			Transform: Redact("Name", "Age"),
+		},
+	}
+	dropped := &TestHandler{
+		options: WatchOptions{
+			Transform: func(m Model) Model {
+				if m.(*TestObject).ID == 2 {
+					return nil
+				}
+				return m
+			},
+		},
+	}
+	for _, h := range []*TestHandler{trusted, redacted, dropped} {
+		_, err = DB.Watch(&TestObject{}, h)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	for i := 1; i < 3; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", Age: 10})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	m := &TestObject{ID: 1}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	m.Name = "Fudd"
+	g.Expect(DB.Update(m)).To(gomega.BeNil())
+	err = DB.Drain(context.Background())
+	g.Expect(err).To(gomega.BeNil())
+	// trusted.
+	g.Expect(trusted.created).To(gomega.Equal([]int{0, 1, 2}))
+	g.Expect(trusted.all[3].updated.Name).To(gomega.Equal("Fudd"))
+	for _, e := range trusted.all[:3] {
+		g.Expect(e.model.Name).To(gomega.Equal("Elmer"))
+	}
+	// redacted.
+	g.Expect(redacted.created).To(gomega.Equal([]int{0, 1, 2}))
+	g.Expect(redacted.updated).To(gomega.Equal([]int{1}))
+	for _, e := range redacted.all {
+		g.Expect(e.model.Name).To(gomega.Equal(""))
+		g.Expect(e.model.Age).To(gomega.Equal(0))
+		if e.updated != nil {
+			g.Expect(e.updated.Name).To(gomega.Equal(""))
+		}
+	}
+	// dropped.
+	g.Expect(dropped.created).To(gomega.Equal([]int{1}))
+	g.Expect(dropped.updated).To(gomega.Equal([]int{1}))
+}
+
+func TestWatchRedacted(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-watch-redacted.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	redacted := &TestHandler{
+		options: WatchOptions{
+			Snapshot: true,
+			Redacted: []string{"Name"},
+		},
+	}
+	misspelled := &TestHandler{
+		options: WatchOptions{
+			Snapshot:  true,
+			Transform: Redact("Nmae"),
+		},
+	}
+	for _, h := range []*TestHandler{redacted, misspelled} {
+		_, err = DB.Watch(&TestObject{}, h)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Validated on create.
+	_, err = DB.Watch(
+		&TestObject{},
+		&TestHandler{
+			options: WatchOptions{
+				Redacted: []string{"Nmae"},
+			},
+		})
+	g.Expect(err).ToNot(gomega.BeNil())
+	for i := 0; i < 2; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", Age: 10})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = DB.Drain(context.Background())
+	g.Expect(err).To(gomega.BeNil())
+	// redacted.
+	g.Expect(redacted.created).To(gomega.Equal([]int{0, 1}))
+	for _, e := range redacted.all {
+		g.Expect(e.model.Name).To(gomega.Equal(""))
+		g.Expect(e.model.Age).To(gomega.Equal(10))
+	}
+	// fails closed (dropped).
+	g.Expect(misspelled.created).To(gomega.BeEmpty())
+}
+
+func TestStableList(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-stable-list.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", Age: i % 2})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// By PK.
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Stable: true})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(10))
+	g.Expect(sort.SliceIsSorted(list, func(i, j int) bool {
+		return list[i].PK < list[j].PK
+	})).To(gomega.BeTrue())
+	// Paginated.
+	paged := []TestObject{}
+	for offset := 0; offset < 10; offset += 3 {
+		page := []TestObject{}
+		err = DB.List(
+			&page,
+			ListOptions{
+				Stable: true,
+				Page:   &Page{Offset: offset, Limit: 3},
+			})
+		g.Expect(err).To(gomega.BeNil())
+		paged = append(paged, page...)
+	}
+	g.Expect(len(paged)).To(gomega.Equal(10))
+	for i := range paged {
+		g.Expect(paged[i].PK).To(gomega.Equal(list[i].PK))
+	}
+	// Sorted with PK tie-breaker.
+	list = []TestObject{}
+	md, _ := Inspect(&TestObject{})
+	position := 0
+	for i, f := range md.Fields {
+		if f.Name == "Age" {
+			position = i + 1
+		}
+	}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Detail: MaxDetail,
+			Sort:   []int{position},
+			Stable: true,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(sort.SliceIsSorted(list, func(i, j int) bool {
+		if list[i].Age != list[j].Age {
+			return list[i].Age < list[j].Age
+		}
+		return list[i].PK < list[j].PK
+	})).To(gomega.BeTrue())
+}
+
+func TestExpressionPredicates(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-expression-predicates.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	names := []string{"Elmer", "Fudd", "Daffy", "Duck", "50%_off"}
+	for i, name := range names {
+		err = DB.Insert(&TestObject{ID: i, Name: name, Age: i * 10})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	ids := func(predicate Predicate) (list []int) {
+		models := []TestObject{}
+		err := DB.List(
+			&models,
+			ListOptions{
+				Predicate: predicate,
+				Sort:      []int{2},
+			})
+		g.Expect(err).To(gomega.BeNil())
+		list = []int{}
+		for _, m := range models {
+			list = append(list, m.ID)
+		}
+		return
+	}
+	// In.
+	g.Expect(ids(In("Name", "Fudd", "Duck"))).To(gomega.Equal([]int{1, 3}))
+	g.Expect(ids(In("Name"))).To(gomega.Equal([]int{}))
+	// Ranges.
+	g.Expect(ids(Gte("Age", 20))).To(gomega.Equal([]int{2, 3, 4}))
+	g.Expect(ids(Lte("Age", 20))).To(gomega.Equal([]int{0, 1, 2}))
+	g.Expect(ids(Between("Age", 10, 30))).To(gomega.Equal([]int{1, 2, 3}))
+	g.Expect(ids(Between("Age", "10", "30"))).To(gomega.Equal([]int{1, 2, 3}))
+	// Patterns.
+	g.Expect(ids(Like("Name", "d%"))).To(gomega.Equal([]int{2, 3}))
+	g.Expect(ids(Glob("Name", "D*"))).To(gomega.Equal([]int{2, 3}))
+	g.Expect(ids(Glob("Name", "d*"))).To(gomega.Equal([]int{}))
+	g.Expect(ids(Like("Name", EscapeLike("50%_")+"%"))).To(gomega.Equal([]int{4}))
+	g.Expect(ids(Like("Name", "50_%"))).To(gomega.Equal([]int{4}))
+	// Not and nested.
+	g.Expect(ids(Not(In("Name", "Fudd", "Duck")))).To(gomega.Equal([]int{0, 2, 4}))
+	g.Expect(ids(
+		And(
+			Or(Eq("Name", "Elmer"), Eq("Name", "Daffy")),
+			Not(Lt("Age", 10))))).To(gomega.Equal([]int{2}))
+	g.Expect(ids(
+		Or(
+			And(Eq("Name", "Elmer"), Eq("Age", 0)),
+			And(Eq("Name", "Duck"), Gt("Age", 100))))).To(gomega.Equal([]int{0}))
+	// Type checked.
+	err = DB.List(&[]TestObject{}, ListOptions{Predicate: Like("Age", "1%")})
+	g.Expect(err).ToNot(gomega.BeNil())
+	err = DB.List(&[]TestObject{}, ListOptions{Predicate: Between("Name", "A", "B")})
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Conditional update.
+	m := &TestObject{ID: 1, Name: "Fudd", Age: 11}
+	err = DB.Update(m, Or(Eq("Age", 0), Eq("Age", 1)))
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+type CollateObject struct {
+	ID    int    `sql:"pk"`
+	Name  string `sql:"collate=nocase,unique(a)"`
+	Alias string `sql:""`
+}
+
+func (m *CollateObject) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+type BadCollateObject struct {
+	ID  int `sql:"pk"`
+	Age int `sql:"collate=nocase"`
+}
+
+func (m *BadCollateObject) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func TestCollate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-collate.db", &CollateObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	err = DB.Insert(&CollateObject{ID: 0, Name: "Elmer", Alias: "Fudd"})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&CollateObject{ID: 1, Name: "Daffy", Alias: "Duck"})
+	g.Expect(err).To(gomega.BeNil())
+	// Unique (nocase).
+	err = DB.Insert(&CollateObject{ID: 2, Name: "ELMER"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	count := func(predicate Predicate) int {
+		list := []CollateObject{}
+		err := DB.List(&list, ListOptions{Predicate: predicate})
+		g.Expect(err).To(gomega.BeNil())
+		return len(list)
+	}
+	// Column collation.
+	g.Expect(count(Eq("Name", "elmer"))).To(gomega.Equal(1))
+	g.Expect(count(In("Name", "ELMER", "daffy"))).To(gomega.Equal(2))
+	g.Expect(count(Collate(Eq("Name", "elmer"), CollateBinary))).To(gomega.Equal(0))
+	// Predicate modifier.
+	g.Expect(count(Eq("Alias", "fudd"))).To(gomega.Equal(0))
+	g.Expect(count(NoCase(Eq("Alias", "fudd")))).To(gomega.Equal(1))
+	g.Expect(count(NoCase(Neq("Alias", "FUDD")))).To(gomega.Equal(1))
+	g.Expect(count(NoCase(Eq("Alias", []string{"FUDD", "duck"})))).To(gomega.Equal(2))
+	g.Expect(count(
+		NoCase(
+			Or(
+				Eq("Alias", "fudd"),
+				Not(In("Alias", "DUCK")))))).To(gomega.Equal(1))
+	// Invalid.
+	err = DB.List(
+		&[]CollateObject{},
+		ListOptions{
+			Predicate: Collate(Eq("Alias", "fudd"), "other"),
+		})
+	g.Expect(errors.Is(err, CollateErr)).To(gomega.BeTrue())
+	// Schema.
+	schema, err := DB.Schema()
+	g.Expect(err).To(gomega.BeNil())
+	for _, table := range schema.Tables {
+		if table.Name != "CollateObject" {
+			continue
+		}
+		for _, column := range table.Columns {
+			if column.Name == "Name" {
+				g.Expect(column.Collate).To(gomega.Equal(CollateNoCase))
+			}
+		}
+	}
+	// Not (str) field.
+	_, err = Inspect(&BadCollateObject{})
+	g.Expect(errors.Is(err, CollateErr)).To(gomega.BeTrue())
+}
+
+type TestColor int
+
+type ConvertObject struct {
+	ID    int                `sql:"pk"`
+	Addr  net.IP             `sql:""`
+	Mask  *net.IP            `sql:""`
+	Color TestColor          `sql:"convert=color"`
+	Size  resource.Quantity  `sql:""`
+	Max   *resource.Quantity `sql:""`
+}
+
+func (m *ConvertObject) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+type BadConvertObject struct {
+	ID    int       `sql:"pk"`
+	Color TestColor `sql:"convert=other"`
+}
+
+func (m *BadConvertObject) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func TestConverter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	Converters.RegisterNamed("color", EnumConverter{"red", "green", "blue"})
+	RegisterConverter(net.IP{}, &TextConverter{})
+	RegisterConverter(
+		resource.Quantity{},
+		&StringConverter{
+			Parse: func(s string) (interface{}, error) {
+				return resource.ParseQuantity(s)
+			},
+		})
+	DB := New("/tmp/test-convert.db", &ConvertObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	mask := net.ParseIP("255.255.255.0")
+	limit := resource.MustParse("2Gi")
+	err = DB.Insert(
+		&ConvertObject{
+			ID:    0,
+			Addr:  net.ParseIP("10.0.0.1"),
+			Mask:  &mask,
+			Color: 2,
+			Size:  resource.MustParse("500Mi"),
+			Max:   &limit,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(
+		&ConvertObject{
+			ID:    1,
+			Addr:  net.ParseIP("10.0.0.2"),
+			Color: 1,
+			Size:  resource.MustParse("1Gi"),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	// Get.
+	m := &ConvertObject{ID: 0}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Addr.String()).To(gomega.Equal("10.0.0.1"))
+	g.Expect(m.Mask.String()).To(gomega.Equal("255.255.255.0"))
+	g.Expect(m.Color).To(gomega.Equal(TestColor(2)))
+	g.Expect(m.Size.String()).To(gomega.Equal("500Mi"))
+	g.Expect(m.Max.String()).To(gomega.Equal("2Gi"))
+	m = &ConvertObject{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Mask).To(gomega.BeNil())
+	g.Expect(m.Max).To(gomega.BeNil())
+	// Not encoded.
+	m.Color = 7
+	err = DB.Update(m)
+	g.Expect(errors.Is(err, ConverterErr)).To(gomega.BeTrue())
+	// Predicates.
+	count := func(predicate Predicate) int {
+		list := []ConvertObject{}
+		err := DB.List(&list, ListOptions{Predicate: predicate})
+		g.Expect(err).To(gomega.BeNil())
+		return len(list)
+	}
+	g.Expect(count(Eq("Addr", net.ParseIP("10.0.0.2")))).To(gomega.Equal(1))
+	g.Expect(count(Eq("Addr", "10.0.0.1"))).To(gomega.Equal(1))
+	g.Expect(count(Eq("Color", TestColor(1)))).To(gomega.Equal(1))
+	g.Expect(count(In("Color", "green", "blue"))).To(gomega.Equal(2))
+	g.Expect(count(Eq("Size", resource.MustParse("1Gi")))).To(gomega.Equal(1))
+	err = DB.List(
+		&[]ConvertObject{},
+		ListOptions{
+			Predicate: Gt("Addr", "10.0.0.1"),
+		})
+	g.Expect(errors.Is(err, PredicateTypeErr)).To(gomega.BeTrue())
+	// Schema.
+	schema, err := DB.Schema()
+	g.Expect(err).To(gomega.BeNil())
+	for _, table := range schema.Tables {
+		if table.Name != "ConvertObject" {
+			continue
+		}
+		for _, column := range table.Columns {
+			if column.Name == "Color" {
+				g.Expect(column.Type).To(gomega.Equal("TEXT"))
+				g.Expect(column.Converted).To(gomega.BeTrue())
+				g.Expect(column.Encoded).To(gomega.BeFalse())
+			}
+		}
+	}
+	// Not found.
+	_, err = Inspect(&BadConvertObject{})
+	g.Expect(errors.Is(err, ConverterErr)).To(gomega.BeTrue())
+}
+
+type QuietObject struct {
+	ID    int    `sql:"pk"`
+	Name  string `sql:""`
+	Count int    `sql:"quiet"`
+}
+
+func (m *QuietObject) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+type QuietHandler struct {
+	StockEventHandler
+	updated []string
+	done    bool
+}
+
+func (h *QuietHandler) Updated(e Event) {
+	h.updated = append(h.updated, e.Updated.(*QuietObject).Name)
+}
+
+func (h *QuietHandler) End() {
+	h.done = true
+}
+
+func TestQuiet(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-quiet.db", &QuietObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	md, err := Inspect(&QuietObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(md.Field("Count").Quiet()).To(gomega.BeTrue())
+	g.Expect(md.Field("Name").Quiet()).To(gomega.BeFalse())
+	m := &QuietObject{ID: 1, Name: "A"}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	handler := &QuietHandler{}
+	watch, err := DB.Watch(&QuietObject{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	// quiet.
+	m.Count++
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	// reported.
+	m.Count++
+	m.Name = "B"
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	stored := &QuietObject{ID: 1}
+	err = DB.Get(stored)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stored.Count).To(gomega.Equal(2))
+	DB.EndWatch(watch)
+	for i := 0; i < 100; i++ {
+		if !handler.done {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+	g.Expect(handler.updated).To(gomega.Equal([]string{"B"}))
+}
+
+func TestReadOnly(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-read-only.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	err = DB.Insert(&TestObject{ID: 0, Name: "A"})
+	g.Expect(err).To(gomega.BeNil())
+	client := DB.(*Client)
+	// Standby.
+	err = client.ReadOnly(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 1, Name: "B"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	m := &TestObject{ID: 0}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("A"))
+	// Activated.
+	err = client.ReadOnly(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 1, Name: "B"})
+	g.Expect(err).To(gomega.BeNil())
+}
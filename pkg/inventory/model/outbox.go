@@ -0,0 +1,486 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sync"
+	"time"
+)
+
+//
+// Outbox dispatcher defaults.
+var (
+	// Delay before the first retry.
+	// Doubled for each failed attempt.
+	OutboxRetry = time.Second * 5
+	// Max delay between retries.
+	OutboxMaxRetry = time.Minute * 5
+	// Max delivery attempts before the
+	// notification is marked dead.
+	OutboxMaxAttempts = 10
+	// Interval between (pending) outbox scans.
+	OutboxInterval = time.Second * 30
+	// Send timeout.
+	OutboxTimeout = time.Second * 30
+	// Dead notifications retained (since created).
+	OutboxRetain = time.Hour * 24 * 7
+)
+
+//
+// Outbox (notification) model.
+// Notifications are written in the same transaction as
+// the model changes and delivered after commit by the
+// OutboxDispatcher. A rolled back transaction leaves no
+// (phantom) notification and a committed notification is
+// retained until delivered. The outbox is opt-in and enabled
+// by including `&Outbox{}` in the models passed to New().
+type Outbox struct {
+	PK string `sql:"pk(id)"`
+	// Monotonic (serial) ID.
+	// Orders notifications as written.
+	ID int64 `sql:"key"`
+	// Topic used to select the sender.
+	// Example: webhook.
+	Topic string `sql:"index(topic)"`
+	// Payload (JSON).
+	Payload string `sql:""`
+	// Created timestamp (unix nanoseconds).
+	Created int64 `sql:""`
+	// Number of delivery attempts.
+	Attempts int `sql:""`
+	// Next attempt timestamp (unix nanoseconds).
+	Next int64 `sql:"index(next)"`
+	// Last delivery error.
+	Error string `sql:""`
+	// Delivery abandoned (max attempts).
+	// Deleted after the retention period.
+	Dead bool `sql:"index(next)"`
+}
+
+func (m *Outbox) Pk() string {
+	return m.PK
+}
+
+func (m *Outbox) String() string {
+	return m.Topic + "#" + m.PK
+}
+
+func (m *Outbox) Labels() Labels {
+	return nil
+}
+
+//
+// Decode the payload.
+func (m *Outbox) Decode(object interface{}) (err error) {
+	err = json.Unmarshal([]byte(m.Payload), object)
+	if err != nil {
+		err = liberr.Wrap(err)
+	}
+
+	return
+}
+
+var outboxID auditSerial
+
+//
+// Write a notification to the outbox.
+// The payload is encoded as JSON. The notification is
+// delivered (by the dispatcher) after the transaction
+// is committed.
+func (r *Tx) Notify(topic string, payload interface{}) (err error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	now := time.Now().UnixNano()
+	err = r.Insert(
+		&Outbox{
+			ID:      outboxID.next(),
+			Topic:   topic,
+			Payload: string(b),
+			Created: now,
+			Next:    now,
+		})
+
+	return
+}
+
+//
+// Outbox notification sender.
+// Returns an error when the notification has not
+// been delivered and should be retried. The context
+// is canceled when the send has timed out.
+type Sender interface {
+	Send(context.Context, *Outbox) error
+}
+
+//
+// Sender function.
+type SenderFunc func(context.Context, *Outbox) error
+
+//
+// Send the notification.
+func (f SenderFunc) Send(ctx context.Context, n *Outbox) error {
+	return f(ctx, n)
+}
+
+//
+// Outbox dispatcher.
+// Delivers pending notifications (in order written) using
+// the sender for the topic. Woken by (outbox) watch events
+// after each commit and periodically to retry failed
+// deliveries with (exponential) backoff. A delivered
+// notification is deleted. A send not completed within
+// the timeout has failed. Dead notifications are deleted
+// after the retention period. Delivery is at-least-once.
+// Example:
+//   dispatcher := &model.OutboxDispatcher{
+//      DB: db,
+//      Senders: map[string]model.Sender{
+//         "webhook": &Webhook{},
+//      },
+//   }
+//   err := dispatcher.Start()
+type OutboxDispatcher struct {
+	// DB.
+	DB DB
+	// Senders by topic.
+	// Notifications without a sender remain pending.
+	Senders map[string]Sender
+	// Delay before the first retry.
+	// Default: OutboxRetry.
+	Retry time.Duration
+	// Max delay between retries.
+	// Default: OutboxMaxRetry.
+	MaxRetry time.Duration
+	// Max delivery attempts.
+	// Default: OutboxMaxAttempts.
+	MaxAttempts int
+	// Interval between scans.
+	// Default: OutboxInterval.
+	Interval time.Duration
+	// Send timeout.
+	// Default: OutboxTimeout.
+	Timeout time.Duration
+	// Dead notifications retained (since created).
+	// Default: OutboxRetain.
+	Retain time.Duration
+	// Wake (dispatch) channel.
+	wake chan struct{}
+	// Done channel.
+	done chan struct{}
+	// Outbox watch.
+	watch *Watch
+	// Run (goroutine) ended.
+	ended sync.WaitGroup
+}
+
+//
+// Start the dispatcher.
+func (r *OutboxDispatcher) Start() (err error) {
+	r.wake = make(chan struct{}, 1)
+	r.done = make(chan struct{})
+	r.watch, err = r.DB.Watch(
+		&Outbox{},
+		&outboxHandler{
+			wake: r.wake,
+		})
+	if err != nil {
+		return
+	}
+	r.ended.Add(1)
+	go r.run()
+
+	log.V(3).Info("outbox dispatcher started.")
+
+	return
+}
+
+//
+// Shutdown the dispatcher.
+func (r *OutboxDispatcher) Shutdown() {
+	if r.done == nil {
+		return
+	}
+	r.DB.EndWatch(r.watch)
+	close(r.done)
+	r.ended.Wait()
+	r.done = nil
+
+	log.V(3).Info("outbox dispatcher shutdown.")
+}
+
+//
+// Wake the dispatcher.
+func (r *OutboxDispatcher) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+//
+// Main (dispatch) loop.
+func (r *OutboxDispatcher) run() {
+	defer r.ended.Done()
+	for {
+		delay, err := r.Dispatch()
+		if err != nil {
+			log.Trace(err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-r.wake:
+		case <-timer.C:
+		case <-r.done:
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+	}
+}
+
+//
+// Dispatch (deliver) pending notifications.
+// Dead notifications past retention are deleted.
+// Returns the delay until the next scan is needed.
+func (r *OutboxDispatcher) Dispatch() (delay time.Duration, err error) {
+	delay = r.interval()
+	err = r.purge()
+	if err != nil {
+		return
+	}
+	if len(r.Senders) == 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	list := []Outbox{}
+	err = r.DB.List(
+		&list,
+		ListOptions{
+			Predicate: And(
+				r.pending(),
+				Lt("Next", now+1)),
+			SortBy: []string{"ID"},
+			Detail: MaxDetail,
+		})
+	if err != nil {
+		return
+	}
+	for i := range list {
+		n := &list[i]
+		err = r.deliver(r.Senders[n.Topic], n)
+		if err != nil {
+			return
+		}
+	}
+	next, err := r.next()
+	if err != nil {
+		return
+	}
+	if next > 0 && next < delay {
+		delay = next
+	}
+
+	return
+}
+
+//
+// Deliver the notification.
+// Deleted when sent. Otherwise, the next attempt is
+// scheduled (or the notification marked dead).
+func (r *OutboxDispatcher) deliver(sender Sender, n *Outbox) (err error) {
+	sendErr := r.send(sender, n)
+	if sendErr == nil {
+		err = r.DB.Delete(n)
+		if err == nil {
+			log.V(4).Info(
+				"outbox: notification delivered.",
+				"notification",
+				n.String())
+		}
+		return
+	}
+	n.Attempts++
+	n.Error = sendErr.Error()
+	n.Next = time.Now().Add(r.backoff(n.Attempts)).UnixNano()
+	n.Dead = n.Attempts >= r.maxAttempts()
+	err = r.DB.Update(n)
+	if err != nil {
+		return
+	}
+
+	log.V(3).Info(
+		"outbox: delivery failed.",
+		"notification",
+		n.String(),
+		"attempts",
+		n.Attempts,
+		"dead",
+		n.Dead,
+		"error",
+		n.Error)
+
+	return
+}
+
+//
+// Send the notification.
+// The sender is abandoned when the send has not
+// completed within the timeout.
+func (r *OutboxDispatcher) send(sender Sender, n *Outbox) (err error) {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = OutboxTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	sent := make(chan error, 1)
+	go func() {
+		sent <- sender.Send(ctx, n)
+	}()
+	select {
+	case err = <-sent:
+	case <-ctx.Done():
+		err = liberr.New(
+			"outbox: send timed out.",
+			"timeout",
+			timeout)
+	}
+
+	return
+}
+
+//
+// Delete dead notifications past retention.
+func (r *OutboxDispatcher) purge() (err error) {
+	retain := r.Retain
+	if retain == 0 {
+		retain = OutboxRetain
+	}
+	cutoff := time.Now().Add(-retain).UnixNano()
+	list := []Outbox{}
+	err = r.DB.List(
+		&list,
+		ListOptions{
+			Predicate: And(
+				Eq("Dead", true),
+				Lt("Created", cutoff)),
+		})
+	if err != nil {
+		return
+	}
+	for i := range list {
+		err = r.DB.Delete(&list[i])
+		if err != nil {
+			return
+		}
+	}
+	if len(list) > 0 {
+		log.V(3).Info(
+			"outbox: dead notifications deleted.",
+			"count",
+			len(list))
+	}
+
+	return
+}
+
+//
+// Delay until the next (pending) attempt.
+// Returns 0 when nothing is pending.
+func (r *OutboxDispatcher) next() (delay time.Duration, err error) {
+	list := []Outbox{}
+	err = r.DB.List(
+		&list,
+		ListOptions{
+			Predicate: r.pending(),
+			SortBy:    []string{"Next"},
+			Detail:    MaxDetail,
+			Page:      &Page{Limit: 1},
+		})
+	if err != nil || len(list) == 0 {
+		return
+	}
+	delay = time.Until(time.Unix(0, list[0].Next))
+	if delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+
+	return
+}
+
+//
+// Pending notifications predicate.
+// Matches (not dead) notifications with a sender.
+func (r *OutboxDispatcher) pending() Predicate {
+	topics := []Predicate{}
+	for topic := range r.Senders {
+		topics = append(topics, Eq("Topic", topic))
+	}
+
+	return And(Eq("Dead", false), Or(topics...))
+}
+
+//
+// Retry (exponential) backoff for the attempt.
+func (r *OutboxDispatcher) backoff(attempts int) (d time.Duration) {
+	d = r.Retry
+	if d == 0 {
+		d = OutboxRetry
+	}
+	max := r.MaxRetry
+	if max == 0 {
+		max = OutboxMaxRetry
+	}
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	return
+}
+
+//
+// Max delivery attempts.
+func (r *OutboxDispatcher) maxAttempts() (n int) {
+	n = r.MaxAttempts
+	if n == 0 {
+		n = OutboxMaxAttempts
+	}
+
+	return
+}
+
+//
+// Scan interval.
+func (r *OutboxDispatcher) interval() (d time.Duration) {
+	d = r.Interval
+	if d == 0 {
+		d = OutboxInterval
+	}
+
+	return
+}
+
+//
+// Outbox (watch) event handler.
+// Wakes the dispatcher when notifications are committed.
+type outboxHandler struct {
+	StockEventHandler
+	// Wake channel.
+	wake chan struct{}
+}
+
+//
+// Notification created (committed).
+func (r *outboxHandler) Created(Event) {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
@@ -73,6 +73,93 @@ func Lt(field string, value interface{}) *LtPredicate {
 	}
 }
 
+//
+// New Gte (>=) predicate.
+func Gte(field string, value interface{}) *GtePredicate {
+	return &GtePredicate{
+		SimplePredicate{
+			Field: field,
+			Value: value,
+		},
+	}
+}
+
+//
+// New Lte (<=) predicate.
+func Lte(field string, value interface{}) *LtePredicate {
+	return &LtePredicate{
+		SimplePredicate{
+			Field: field,
+			Value: value,
+		},
+	}
+}
+
+//
+// New Between (inclusive range) predicate.
+func Between(field string, low, high interface{}) *BetweenPredicate {
+	return &BetweenPredicate{
+		SimplePredicate: SimplePredicate{
+			Field: field,
+			Value: low,
+		},
+		High: high,
+	}
+}
+
+//
+// New In (list) predicate.
+func In(field string, values ...interface{}) *InPredicate {
+	return &InPredicate{
+		SimplePredicate{
+			Field: field,
+			Value: values,
+		},
+	}
+}
+
+//
+// New Like (pattern) predicate.
+// Case-insensitive (ASCII) pattern with the `%` (any) and
+// `_` (single) wildcards. See: EscapeLike().
+func Like(field string, pattern string) *LikePredicate {
+	return &LikePredicate{
+		SimplePredicate{
+			Field: field,
+			Value: pattern,
+		},
+	}
+}
+
+//
+// New Glob (pattern) predicate.
+// Case-sensitive (unix) pattern with the `*`, `?`
+// and `[...]` wildcards.
+func Glob(field string, pattern string) *GlobPredicate {
+	return &GlobPredicate{
+		SimplePredicate{
+			Field: field,
+			Value: pattern,
+		},
+	}
+}
+
+//
+// Escape the LIKE wildcards (and escape character)
+// so that the string is matched literally.
+// Example:
+//   Like("Name", EscapeLike(prefix)+"%")
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+//
+// LIKE escaper.
+var likeEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`%`, `\%`,
+	`_`, `\_`)
+
 //
 // AND predicate.
 func And(predicates ...Predicate) *AndPredicate {
@@ -93,6 +180,38 @@ func Or(predicates ...Predicate) *OrPredicate {
 	}
 }
 
+//
+// NOT predicate.
+func Not(predicate Predicate) *NotPredicate {
+	return &NotPredicate{
+		Predicate: predicate,
+	}
+}
+
+//
+// Case-insensitive (NOCASE) predicate modifier.
+// See: Collate().
+// Example:
+//   NoCase(Eq("Name", "elmer"))
+func NoCase(predicate Predicate) Predicate {
+	return Collate(predicate, CollateNoCase)
+}
+
+//
+// Collation predicate modifier.
+// The collation is applied to the (equality and list)
+// comparisons of the predicate, including the nested
+// predicates, overriding the column collation. Not
+// applicable to the LIKE (case-insensitive) and GLOB
+// (case-sensitive) patterns.
+func Collate(predicate Predicate, collation string) Predicate {
+	if collated, cast := predicate.(collatable); cast {
+		collated.collate(strings.ToUpper(collation))
+	}
+
+	return predicate
+}
+
 //
 // Label predicate.
 func Match(labels Labels) *LabelPredicate {
@@ -110,6 +229,13 @@ type Predicate interface {
 	Expr() string
 }
 
+//
+// Predicate supports collation.
+type collatable interface {
+	// Set the collation.
+	collate(collation string)
+}
+
 //
 // Simple predicate.
 type SimplePredicate struct {
@@ -117,10 +243,32 @@ type SimplePredicate struct {
 	Field string
 	// Field value.
 	Value interface{}
+	// Collation.
+	collation string
 	// SQL expression.
 	expr string
 }
 
+//
+// Set the collation.
+func (p *SimplePredicate) collate(collation string) {
+	p.collation = collation
+}
+
+//
+// Collation clause.
+func (p *SimplePredicate) collateSQL() (clause string, err error) {
+	if p.collation == "" {
+		return
+	}
+	if !validCollation(p.collation) {
+		err = liberr.Wrap(CollateErr, "collation", p.collation)
+		return
+	}
+	clause = "COLLATE " + p.collation
+	return
+}
+
 //
 // Find referenced field.
 func (p *SimplePredicate) match(fields []*Field) (*Field, bool) {
@@ -147,6 +295,10 @@ func (p *SimplePredicate) build(operator string, options *FilterOptions) error {
 	if !found {
 		return liberr.Wrap(PredicateRefErr)
 	}
+	collate, err := p.collateSQL()
+	if err != nil {
+		return err
+	}
 	switch p.Value.(type) {
 	case Field:
 		value := p.Value.(Field)
@@ -172,6 +324,115 @@ func (p *SimplePredicate) build(operator string, options *FilterOptions) error {
 				options.Param(f.Name, v)},
 			" ")
 	}
+	if collate != "" {
+		p.expr += " " + collate
+	}
+
+	return nil
+}
+
+//
+// Build (ordered) comparison.
+// The field must be a time, an integer or converted
+// to an INTEGER column.
+func (p *SimplePredicate) ordered(operator string, options *FilterOptions) error {
+	f, found := p.match(options.fields)
+	if !found {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	err := orderable(f)
+	if err != nil {
+		return err
+	}
+
+	return p.build(operator, options)
+}
+
+//
+// Validate the field supports ordered comparison.
+// The field must be a time, an integer or converted
+// to an INTEGER column.
+func orderable(f *Field) error {
+	if f.Time() {
+		return nil
+	}
+	if f.Converted() {
+		if f.SQLType() != "INTEGER" {
+			return PredicateTypeErr
+		}
+		return nil
+	}
+	switch f.kind() {
+	case reflect.String,
+		reflect.Bool:
+		return PredicateTypeErr
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		return nil
+	default:
+		return FieldTypeErr
+	}
+}
+
+//
+// Build (IN) list.
+func (p *SimplePredicate) in(f *Field, pv reflect.Value, options *FilterOptions) error {
+	collate, err := p.collateSQL()
+	if err != nil {
+		return err
+	}
+	name := f.Name
+	if collate != "" {
+		name += " " + collate
+	}
+	params := []string{}
+	for i := 0; i < pv.Len(); i++ {
+		v, err := f.AsValue(pv.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		params = append(
+			params,
+			options.Param(f.Name, v))
+	}
+	p.expr = strings.Join(
+		[]string{
+			name,
+			"IN",
+			"(",
+			strings.Join(params, ","),
+			")"},
+		" ")
+
+	return nil
+}
+
+//
+// Build (string) pattern.
+func (p *SimplePredicate) pattern(operator, escape string, options *FilterOptions) error {
+	f, found := p.match(options.fields)
+	if !found {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	if f.kind() != reflect.String {
+		return liberr.Wrap(PredicateTypeErr, "field", f.Name)
+	}
+	pattern, cast := p.Value.(string)
+	if !cast {
+		return liberr.Wrap(PredicateValueErr, "field", f.Name)
+	}
+	p.expr = strings.Join(
+		[]string{
+			f.Name,
+			operator,
+			options.Param(f.Name, pattern)},
+		" ")
+	if escape != "" {
+		p.expr += " ESCAPE '" + escape + "'"
+	}
 
 	return nil
 }
@@ -192,29 +453,13 @@ func (p *EqPredicate) Build(options *FilterOptions) error {
 	pv := reflect.ValueOf(p.Value)
 	switch pv.Kind() {
 	case reflect.Slice:
-		params := []string{}
-		for i := 0; i < pv.Len(); i++ {
-			v, err := f.AsValue(pv.Index(i).Interface())
-			if err != nil {
-				return err
-			}
-			params = append(
-				params,
-				options.Param(f.Name, v))
+		if f.convertible(pv) {
+			return p.build("=", options)
 		}
-		p.expr = strings.Join(
-			[]string{
-				f.Name,
-				"IN",
-				"(",
-				strings.Join(params, ","),
-				")"},
-			" ")
+		return p.in(f, pv, options)
 	default:
 		return p.build("=", options)
 	}
-
-	return nil
 }
 
 //
@@ -250,62 +495,175 @@ type GtPredicate struct {
 //
 // Build.
 func (p *GtPredicate) Build(options *FilterOptions) error {
+	return p.ordered(">", options)
+}
+
+//
+// Render the expression.
+func (p *GtPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Less than (<) predicate.
+type LtPredicate struct {
+	SimplePredicate
+}
+
+//
+// Build.
+func (p *LtPredicate) Build(options *FilterOptions) error {
+	return p.ordered("<", options)
+}
+
+//
+// Render the expression.
+func (p *LtPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Greater than or equal (>=) predicate.
+type GtePredicate struct {
+	SimplePredicate
+}
+
+//
+// Build.
+func (p *GtePredicate) Build(options *FilterOptions) error {
+	return p.ordered(">=", options)
+}
+
+//
+// Render the expression.
+func (p *GtePredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Less than or equal (<=) predicate.
+type LtePredicate struct {
+	SimplePredicate
+}
+
+//
+// Build.
+func (p *LtePredicate) Build(options *FilterOptions) error {
+	return p.ordered("<=", options)
+}
+
+//
+// Render the expression.
+func (p *LtePredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Between (inclusive range) predicate.
+// The Value is the low bound.
+type BetweenPredicate struct {
+	SimplePredicate
+	// The high bound.
+	High interface{}
+}
+
+//
+// Build.
+func (p *BetweenPredicate) Build(options *FilterOptions) error {
 	f, found := p.match(options.fields)
 	if !found {
 		return liberr.Wrap(PredicateRefErr)
 	}
-	switch f.Value.Kind() {
-	case reflect.String,
-		reflect.Bool:
-		return PredicateTypeErr
-	case reflect.Int,
-		reflect.Int8,
-		reflect.Int16,
-		reflect.Int32,
-		reflect.Int64:
-		return p.build(">", options)
-	default:
-		return FieldTypeErr
+	err := orderable(f)
+	if err != nil {
+		return err
+	}
+	low, err := f.AsValue(p.Value)
+	if err != nil {
+		return err
+	}
+	high, err := f.AsValue(p.High)
+	if err != nil {
+		return err
 	}
+	p.expr = strings.Join(
+		[]string{
+			f.Name,
+			"BETWEEN",
+			options.Param(f.Name, low),
+			"AND",
+			options.Param(f.Name, high)},
+		" ")
+
+	return nil
 }
 
 //
 // Render the expression.
-func (p *GtPredicate) Expr() string {
+func (p *BetweenPredicate) Expr() string {
 	return p.expr
 }
 
 //
-// Less than (<) predicate.
-type LtPredicate struct {
+// In (list) predicate.
+type InPredicate struct {
 	SimplePredicate
 }
 
 //
 // Build.
-func (p *LtPredicate) Build(options *FilterOptions) error {
+func (p *InPredicate) Build(options *FilterOptions) error {
 	f, found := p.match(options.fields)
 	if !found {
 		return liberr.Wrap(PredicateRefErr)
 	}
-	switch f.Value.Kind() {
-	case reflect.String,
-		reflect.Bool:
-		return PredicateTypeErr
-	case reflect.Int,
-		reflect.Int8,
-		reflect.Int16,
-		reflect.Int32,
-		reflect.Int64:
-		return p.build("<", options)
-	default:
-		return FieldTypeErr
+	pv := reflect.ValueOf(p.Value)
+	if pv.Kind() != reflect.Slice {
+		return liberr.Wrap(PredicateValueErr, "field", f.Name)
 	}
+
+	return p.in(f, pv, options)
 }
 
 //
 // Render the expression.
-func (p *LtPredicate) Expr() string {
+func (p *InPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Like (pattern) predicate.
+type LikePredicate struct {
+	SimplePredicate
+}
+
+//
+// Build.
+func (p *LikePredicate) Build(options *FilterOptions) error {
+	return p.pattern("LIKE", `\`, options)
+}
+
+//
+// Render the expression.
+func (p *LikePredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Glob (pattern) predicate.
+type GlobPredicate struct {
+	SimplePredicate
+}
+
+//
+// Build.
+func (p *GlobPredicate) Build(options *FilterOptions) error {
+	return p.pattern("GLOB", "", options)
+}
+
+//
+// Render the expression.
+func (p *GlobPredicate) Expr() string {
 	return p.expr
 }
 
@@ -316,6 +674,14 @@ type CompoundPredicate struct {
 	Predicates []Predicate
 }
 
+//
+// Set the collation.
+func (p *CompoundPredicate) collate(collation string) {
+	for _, predicate := range p.Predicates {
+		Collate(predicate, collation)
+	}
+}
+
 //
 // And predicate.
 type AndPredicate struct {
@@ -343,7 +709,11 @@ func (p *AndPredicate) Expr() string {
 		predicates = append(predicates, p.Expr())
 	}
 
-	expr := strings.Join(predicates, " AND ")
+	if len(predicates) == 0 {
+		return "1"
+	}
+
+	expr := "(" + strings.Join(predicates, " AND ") + ")"
 
 	return expr
 }
@@ -375,11 +745,40 @@ func (p *OrPredicate) Expr() string {
 		predicates = append(predicates, p.Expr())
 	}
 
-	expr := strings.Join(predicates, " OR ")
+	if len(predicates) == 0 {
+		return "0"
+	}
+
+	expr := "(" + strings.Join(predicates, " OR ") + ")"
 
 	return expr
 }
 
+//
+// NOT predicate.
+type NotPredicate struct {
+	// The negated predicate.
+	Predicate Predicate
+}
+
+//
+// Set the collation.
+func (p *NotPredicate) collate(collation string) {
+	Collate(p.Predicate, collation)
+}
+
+//
+// Build.
+func (p *NotPredicate) Build(options *FilterOptions) error {
+	return p.Predicate.Build(options)
+}
+
+//
+// Render the expression.
+func (p *NotPredicate) Expr() string {
+	return "NOT (" + p.Predicate.Expr() + ")"
+}
+
 //
 // Label predicate.
 type LabelPredicate struct {
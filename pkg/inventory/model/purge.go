@@ -0,0 +1,540 @@
+package model
+
+import (
+	"bytes"
+	liberr "github.com/konveyor/controller/pkg/error"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/ref"
+	"github.com/prometheus/client_golang/prometheus"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+)
+
+//
+// Purger defaults.
+var (
+	// Interval between purges.
+	PurgeInterval = time.Minute * 10
+)
+
+//
+// Purge reasons.
+const (
+	// Older than the max age.
+	PurgeExpired = "expired"
+	// Not within the last N (of the group).
+	PurgeTrimmed = "trimmed"
+)
+
+//
+// Purge metrics.
+// Registered with the (web) metrics registry.
+var (
+	// Purged models by kind and reason.
+	PurgedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_purged_models_total",
+			Help: "Number of models deleted by retention policies.",
+		},
+		[]string{"kind", "reason"})
+)
+
+//
+// Data retention policy (by kind).
+// Intended for event-like kinds which are appended but
+// never (otherwise) deleted. Models older than the max age
+// are deleted, then all but the last (latest) N models of
+// each group. Not to be confused with the (watch) journal
+// Retention policy.
+// Example:
+//   purger := &model.Purger{
+//      DB: db,
+//      Policies: []model.RetentionPolicy{
+//         {
+//            Kind:     &Event{},
+//            Field:    "Timestamp",
+//            MaxAge:   time.Hour * 24,
+//            KeepLast: 100,
+//            GroupBy:  []string{"VM"},
+//         },
+//      },
+//   }
+//   err := purger.Start()
+type RetentionPolicy struct {
+	// Model kind.
+	Kind Model
+	// Timestamp field (name). Either time.Time or an
+	// integer (unix nanoseconds). Required by MaxAge.
+	// Used to order models by age; Default: the PK.
+	Field string
+	// Max age. 0 = not limited.
+	MaxAge time.Duration
+	// Number of (latest) models retained for each group.
+	// 0 = not limited.
+	KeepLast int
+	// Group (by field names) used by KeepLast.
+	// Empty = all models of the kind.
+	GroupBy []string
+}
+
+//
+// Validate the policy.
+func (r *RetentionPolicy) validate() (err error) {
+	if r.Kind == nil {
+		err = liberr.New("retention policy: kind required.")
+		return
+	}
+	md, err := Inspect(r.Kind)
+	if err != nil {
+		return
+	}
+	if r.MaxAge > 0 && r.Field == "" {
+		err = liberr.New(
+			"retention policy: field required by max age.",
+			"kind",
+			md.Kind)
+		return
+	}
+	names := r.GroupBy
+	if r.Field != "" {
+		names = append([]string{r.Field}, names...)
+	}
+	for _, name := range names {
+		if md.Field(name) == nil {
+			err = liberr.New(
+				"retention policy: field not found.",
+				"kind",
+				md.Kind,
+				"field",
+				name)
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Purge result (by kind).
+type PurgeReport struct {
+	// Model kind.
+	Kind string `json:"kind"`
+	// Number of models expired.
+	Expired int64 `json:"expired"`
+	// Number of models trimmed.
+	Trimmed int64 `json:"trimmed"`
+	// Dry-run: counted but not deleted.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Last purged.
+	Purged time.Time `json:"purged"`
+	// Error description.
+	Error string `json:"error,omitempty"`
+}
+
+//
+// Data retention purger.
+// Enforces (periodically) the retention policies. Each kind
+// is purged in its own transaction so that one failed kind
+// does not prevent the others from being purged. In dry-run
+// mode, the models to be purged are counted (and reported)
+// but not deleted.
+type Purger struct {
+	// DB.
+	DB DB
+	// Retention policies.
+	Policies []RetentionPolicy
+	// Interval between purges.
+	// Default: PurgeInterval.
+	Interval time.Duration
+	// Dry-run mode.
+	DryRun bool
+	// Cumulative reports by kind.
+	reports map[string]*PurgeReport
+	// Protect the reports.
+	mutex sync.Mutex
+	// Done channel.
+	done chan struct{}
+	// Run (goroutine) ended.
+	ended sync.WaitGroup
+}
+
+//
+// Start the purger.
+// The policies are validated.
+func (r *Purger) Start() (err error) {
+	for i := range r.Policies {
+		err = r.Policies[i].validate()
+		if err != nil {
+			return
+		}
+	}
+	r.done = make(chan struct{})
+	r.ended.Add(1)
+	go r.run()
+
+	log.V(3).Info(
+		"purger started.",
+		"policies",
+		len(r.Policies),
+		"dryRun",
+		r.DryRun)
+
+	return
+}
+
+//
+// Shutdown the purger.
+func (r *Purger) Shutdown() {
+	if r.done == nil {
+		return
+	}
+	close(r.done)
+	r.ended.Wait()
+	r.done = nil
+
+	log.V(3).Info("purger shutdown.")
+}
+
+//
+// Cumulative reports (ordered by kind).
+func (r *Purger) Reports() (list []PurgeReport) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list = []PurgeReport{}
+	for _, report := range r.reports {
+		list = append(list, *report)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Kind < list[j].Kind
+	})
+
+	return
+}
+
+//
+// Main (purge) loop.
+func (r *Purger) run() {
+	defer r.ended.Done()
+	for {
+		_, err := r.Purge()
+		if err != nil {
+			log.Trace(err)
+		}
+		timer := time.NewTimer(r.interval())
+		select {
+		case <-timer.C:
+		case <-r.done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+//
+// Purge (enforce) the retention policies.
+// Returns the report for each policy and the error of the
+// last policy failed.
+func (r *Purger) Purge() (list []PurgeReport, err error) {
+	list = []PurgeReport{}
+	for i := range r.Policies {
+		policy := &r.Policies[i]
+		report := PurgeReport{
+			Kind:   ref.ToKind(policy.Kind),
+			DryRun: r.DryRun,
+			Purged: time.Now(),
+		}
+		fn := func(tx *Tx) (err error) {
+			report.Expired, err = r.expire(tx, policy)
+			if err != nil {
+				return
+			}
+			report.Trimmed, err = r.trim(tx, policy)
+			return
+		}
+		var pErr error
+		if r.DryRun {
+			_, pErr = r.DB.DryRun(fn, "purge")
+		} else {
+			pErr = r.DB.With(fn, "purge")
+		}
+		if pErr != nil {
+			err = pErr
+			report.Expired = 0
+			report.Trimmed = 0
+			report.Error = pErr.Error()
+		}
+		r.record(report)
+		list = append(list, report)
+
+		log.V(3).Info(
+			"purged.",
+			"kind",
+			report.Kind,
+			"expired",
+			report.Expired,
+			"trimmed",
+			report.Trimmed,
+			"dryRun",
+			report.DryRun)
+	}
+
+	return
+}
+
+//
+// Delete models older than the max age.
+func (r *Purger) expire(tx *Tx, policy *RetentionPolicy) (n int64, err error) {
+	if policy.MaxAge <= 0 {
+		return
+	}
+	md, err := Inspect(policy.Kind)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-policy.MaxAge)
+	var value interface{} = cutoff
+	if field := md.Field(policy.Field); !field.Time() {
+		value = cutoff.UnixNano()
+	}
+	itr, err := tx.Find(
+		policy.Kind,
+		ListOptions{
+			Detail:    MaxDetail,
+			Predicate: Lt(policy.Field, value),
+		})
+	if err != nil {
+		return
+	}
+	defer itr.Close()
+	for {
+		object, hasNext := itr.Next()
+		if !hasNext {
+			err = fb.ErrOf(itr)
+			if err != nil {
+				return
+			}
+			break
+		}
+		err = tx.Delete(object.(Model))
+		if err != nil {
+			return
+		}
+		n++
+	}
+
+	return
+}
+
+//
+// Delete all but the last (latest) N models of each group.
+// Only the excess (oldest) models of each group are fetched.
+func (r *Purger) trim(tx *Tx, policy *RetentionPolicy) (n int64, err error) {
+	if policy.KeepLast <= 0 {
+		return
+	}
+	md, err := Inspect(policy.Kind)
+	if err != nil {
+		return
+	}
+	sorted := []string{}
+	if policy.Field != "" {
+		sorted = append(sorted, policy.Field)
+	}
+	sorted = append(sorted, md.PkField().Name)
+	groups, err := r.groups(tx, md, policy)
+	if err != nil {
+		return
+	}
+	for _, group := range groups {
+		itr, fErr := tx.Find(
+			policy.Kind,
+			ListOptions{
+				Detail:    MaxDetail,
+				Predicate: group.predicate,
+				SortBy:    sorted,
+				Page: &Page{
+					Limit: int(group.excess),
+				},
+			})
+		if fErr != nil {
+			err = fErr
+			return
+		}
+		for {
+			object, hasNext := itr.Next()
+			if !hasNext {
+				err = fb.ErrOf(itr)
+				break
+			}
+			err = tx.Delete(object.(Model))
+			if err != nil {
+				break
+			}
+			n++
+		}
+		itr.Close()
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Group (trimmed) SQL.
+// Groups with more than N models.
+var TrimGroupSQL = `
+SELECT
+{{ range $i,$f := .Fields -}}
+{{ $f.Name }},
+{{ end -}}
+COUNT(*)
+FROM {{ .Table }}
+GROUP BY
+{{ range $i,$f := .Fields -}}
+{{ if $i }},{{ end }}{{ $f.Name }}
+{{ end -}}
+HAVING COUNT(*) > {{ .Keep }}
+;
+`
+
+//
+// Trim group template data.
+type trimTmplData struct {
+	// Table name.
+	Table string
+	// Group (by) fields.
+	Fields []*Field
+	// Number of models retained.
+	Keep int
+}
+
+//
+// Group (to be trimmed).
+type trimGroup struct {
+	// Selects the models of the group.
+	predicate Predicate
+	// Number of models to be deleted.
+	excess int64
+}
+
+//
+// Groups with more than KeepLast models.
+func (r *Purger) groups(tx *Tx, md *Definition, policy *RetentionPolicy) (groups []trimGroup, err error) {
+	if len(policy.GroupBy) == 0 {
+		count, cErr := tx.Count(policy.Kind, nil)
+		if cErr != nil {
+			err = cErr
+			return
+		}
+		excess := count - int64(policy.KeepLast)
+		if excess > 0 {
+			groups = append(groups, trimGroup{excess: excess})
+		}
+		return
+	}
+	fields := []*Field{}
+	for _, name := range policy.GroupBy {
+		fields = append(fields, md.Field(name))
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(TrimGroupSQL)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		trimTmplData{
+			Table:  md.Kind,
+			Fields: fields,
+			Keep:   policy.KeepLast,
+		})
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	stmt := bfr.String()
+	cursor, err := tx.db().Query(stmt)
+	if err != nil {
+		err = liberr.Wrap(err, "sql", stmt)
+		return
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+	for cursor.Next() {
+		var count int64
+		values := make([]interface{}, len(fields))
+		ptrs := []interface{}{}
+		for i := range values {
+			ptrs = append(ptrs, &values[i])
+		}
+		ptrs = append(ptrs, &count)
+		err = cursor.Scan(ptrs...)
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+		predicates := []Predicate{}
+		for i, f := range fields {
+			v := values[i]
+			if b, cast := v.([]byte); cast {
+				v = string(b)
+			}
+			predicates = append(predicates, Eq(f.Name, v))
+		}
+		groups = append(
+			groups,
+			trimGroup{
+				predicate: And(predicates...),
+				excess:    count - int64(policy.KeepLast),
+			})
+	}
+	err = cursor.Err()
+	if err != nil {
+		err = liberr.Wrap(err)
+	}
+
+	return
+}
+
+//
+// Record (accumulate) the report.
+// Metrics are counted when not dry-run.
+func (r *Purger) record(report PurgeReport) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.reports == nil {
+		r.reports = map[string]*PurgeReport{}
+	}
+	cumulative, found := r.reports[report.Kind]
+	if !found {
+		cumulative = &PurgeReport{Kind: report.Kind}
+		r.reports[report.Kind] = cumulative
+	}
+	cumulative.Expired += report.Expired
+	cumulative.Trimmed += report.Trimmed
+	cumulative.DryRun = report.DryRun
+	cumulative.Purged = report.Purged
+	cumulative.Error = report.Error
+	if !report.DryRun {
+		PurgedCounter.WithLabelValues(report.Kind, PurgeExpired).Add(float64(report.Expired))
+		PurgedCounter.WithLabelValues(report.Kind, PurgeTrimmed).Add(float64(report.Trimmed))
+	}
+}
+
+//
+// Interval between purges.
+func (r *Purger) interval() (d time.Duration) {
+	d = r.Interval
+	if d <= 0 {
+		d = PurgeInterval
+	}
+
+	return
+}
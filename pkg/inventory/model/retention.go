@@ -0,0 +1,397 @@
+package model
+
+import (
+	"context"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/ref"
+	"sync"
+	"time"
+)
+
+//
+// Default watch queue capacity (batches).
+var QueueCapacity = 250
+
+//
+// Journal retention policy.
+// Limits the (undelivered) events queued for each watch so
+// that slow watches do not accumulate unbounded history. When
+// a limit is exceeded, the queue is compacted (as specified) and
+// then the oldest events are discarded.
+// Zero values = not limited.
+type Retention struct {
+	// Max number of queued events.
+	MaxEvents int
+	// Max age of queued events.
+	MaxAge time.Duration
+	// Max (encoded) size of queued events.
+	MaxBytes int64
+	// Compact the queue by collapsing multiple events
+	// for the same model (PK) into the latest. The order
+	// of events for each model is preserved.
+	Compact bool
+}
+
+//
+// The policy is defined.
+func (r *Retention) defined() bool {
+	return r.MaxEvents > 0 ||
+		r.MaxAge > 0 ||
+		r.MaxBytes > 0 ||
+		r.Compact
+}
+
+//
+// Queued event batch.
+type batch struct {
+	// Context.
+	ctx context.Context
+	// Events.
+	itr fb.Iterator
+	// Number of events.
+	events int
+	// Size (bytes).
+	bytes int64
+	// Queued timestamp.
+	queued time.Time
+	// Events have been discarded (or the DB rebuilt)
+	// since the previous batch was delivered.
+	reset bool
+	// Reset reason.
+	reason string
+}
+
+//
+// Close the batch.
+func (b *batch) close() {
+	if b.itr != nil {
+		b.itr.Close()
+	}
+}
+
+//
+// Watch event queue.
+type eventQueue struct {
+	// Queued batches.
+	batches []*batch
+	// Capacity (batches).
+	capacity int
+	// Number of queued events.
+	events int
+	// Size (bytes) of queued events.
+	bytes int64
+	// Number of events discarded.
+	discarded uint64
+	// Number of events collapsed by compaction.
+	compacted uint64
+	// Events have been discarded (or the DB rebuilt)
+	// since the last batch was taken.
+	reset bool
+	// Reset reason.
+	reason string
+	// Closed.
+	closed bool
+	// Called (unlocked) when a batch is queued (or closed).
+	ready func()
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// New queue.
+func newQueue(capacity int, ready func()) (q *eventQueue) {
+	q = &eventQueue{
+		capacity: capacity,
+		ready:    ready,
+	}
+	return
+}
+
+//
+// Signal that a batch is ready.
+func (q *eventQueue) signal() {
+	if q.ready != nil {
+		q.ready()
+	}
+}
+
+//
+// Queue a batch and enforce the retention policy.
+// Without a (defined) policy, the batch and the queued batches
+// are discarded when the queue is full so that the reset is
+// delivered in order. Returns the number of events discarded.
+func (q *eventQueue) put(b *batch, policy Retention) (discarded int) {
+	defer q.signal()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		b.close()
+		return
+	}
+	if !policy.defined() && len(q.batches) >= q.capacity {
+		discarded = b.events
+		q.discarded += uint64(discarded)
+		b.close()
+		discarded += q.clear(ResetDiscarded)
+		return
+	}
+	q.batches = append(q.batches, b)
+	q.events += b.events
+	q.bytes += b.bytes
+	if policy.Compact && q.exceeded(policy) && len(q.batches) > 1 {
+		q.compact()
+	}
+	discarded = q.expire(policy)
+	for len(q.batches) > 0 && q.exceeded(policy) {
+		discarded += q.drop()
+	}
+	q.discarded += uint64(discarded)
+	if discarded > 0 {
+		q.setReset(ResetDiscarded)
+	}
+
+	return
+}
+
+//
+// Take the next batch.
+// Returns ok=false when no batch is queued and closed=true
+// when (also) the queue has been closed.
+func (q *eventQueue) take() (b *batch, ok, closed bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.batches) == 0 {
+		closed = q.closed
+		return
+	}
+	b = q.batches[0]
+	q.batches = q.batches[1:]
+	q.events -= b.events
+	q.bytes -= b.bytes
+	b.reset = q.reset
+	b.reason = q.reason
+	q.reset = false
+	q.reason = ""
+	ok = true
+
+	return
+}
+
+//
+// Reset the watch with the reason.
+// Queued (stale) batches are discarded and an empty batch
+// is queued so that the reset is delivered.
+func (q *eventQueue) relist(reason string) {
+	defer q.signal()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return
+	}
+	q.clear(reason)
+}
+
+//
+// Discard the queued batches and queue an empty batch so
+// that the reset is delivered after events already taken
+// and before any events queued afterwards.
+// Returns the number of events discarded.
+func (q *eventQueue) clear(reason string) (discarded int) {
+	for len(q.batches) > 0 {
+		discarded += q.drop()
+	}
+	q.discarded += uint64(discarded)
+	q.setReset(reason)
+	q.batches = append(q.batches, &batch{queued: time.Now()})
+	return
+}
+
+//
+// Flag the queue as reset.
+// The first reason is kept until the reset is delivered.
+func (q *eventQueue) setReset(reason string) {
+	if !q.reset {
+		q.reason = reason
+	}
+	q.reset = true
+}
+
+//
+// Close the queue.
+// Queued batches are delivered before take() reports closed.
+func (q *eventQueue) close() {
+	defer q.signal()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+}
+
+//
+// Number of queued batches.
+func (q *eventQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.batches)
+}
+
+//
+// The queue is full.
+func (q *eventQueue) full() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.batches) >= q.capacity
+}
+
+//
+// Statistics.
+func (q *eventQueue) stats() (events int, bytes int64, discarded, compacted uint64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.events, q.bytes, q.discarded, q.compacted
+}
+
+//
+// A limit has been exceeded.
+func (q *eventQueue) exceeded(policy Retention) bool {
+	return len(q.batches) > q.capacity ||
+		(policy.MaxEvents > 0 && q.events > policy.MaxEvents) ||
+		(policy.MaxBytes > 0 && q.bytes > policy.MaxBytes)
+}
+
+//
+// Drop the oldest batch.
+// Returns the number of events discarded.
+func (q *eventQueue) drop() (discarded int) {
+	b := q.batches[0]
+	q.batches = q.batches[1:]
+	q.events -= b.events
+	q.bytes -= b.bytes
+	discarded = b.events
+	b.close()
+	return
+}
+
+//
+// Drop batches older than the max age.
+// Returns the number of events discarded.
+func (q *eventQueue) expire(policy Retention) (discarded int) {
+	if policy.MaxAge == 0 {
+		return
+	}
+	for len(q.batches) > 0 {
+		if time.Since(q.batches[0].queued) <= policy.MaxAge {
+			break
+		}
+		discarded += q.drop()
+	}
+
+	return
+}
+
+//
+// Compact queued batches into a single batch.
+// Multiple events for the same model (kind and PK) are
+// collapsed into the latest.
+func (q *eventQueue) compact() {
+	type entry struct {
+		event Event
+		order int
+	}
+	latest := q.batches[len(q.batches)-1]
+	collapsed := map[string]*entry{}
+	order := 0
+	total := 0
+	for _, b := range q.batches {
+		for {
+			event := Event{}
+			hasNext := event.next(b.itr)
+			if !hasNext {
+				break
+			}
+			total++
+			order++
+			key := ref.ToKind(event.Model) + "/" + event.Model.Pk()
+			prior, found := collapsed[key]
+			if !found {
+				collapsed[key] = &entry{event: event, order: order}
+				continue
+			}
+			merged, keep := collapse(prior.event, event)
+			if keep {
+				prior.event = merged
+				prior.order = order
+			} else {
+				delete(collapsed, key)
+			}
+		}
+		b.close()
+	}
+	ordered := make([]*entry, order+1)
+	for _, e := range collapsed {
+		ordered[e.order] = e
+	}
+	list := fb.NewList()
+	events := 0
+	for _, e := range ordered {
+		if e == nil {
+			continue
+		}
+		err := e.event.append(list)
+		if err != nil {
+			log.Error(err, "compact failed: queue cleared.")
+			list.Close()
+			q.batches = nil
+			q.events = 0
+			q.bytes = 0
+			q.discarded += uint64(total)
+			q.clear(ResetDiscarded)
+			return
+		}
+		events++
+	}
+	q.compacted += uint64(total - events)
+	q.batches = []*batch{
+		{
+			ctx:    latest.ctx,
+			itr:    list.Iter(),
+			events: events,
+			bytes:  list.Size(),
+			queued: latest.queued,
+		},
+	}
+	q.events = events
+	q.bytes = list.Size()
+}
+
+//
+// Collapse two events for the same model.
+// Returns the merged event and whether it is kept.
+func collapse(prior, next Event) (merged Event, keep bool) {
+	merged = next
+	keep = true
+	switch prior.Action {
+	case Created:
+		switch next.Action {
+		case Updated:
+			merged.Action = Created
+			merged.Model = next.Updated
+			merged.Updated = nil
+		case Deleted:
+			keep = false
+		}
+	case Updated:
+		switch next.Action {
+		case Updated:
+			merged.Model = prior.Model
+		}
+	case Deleted:
+		switch next.Action {
+		case Created:
+			merged.Action = Updated
+			merged.Model = prior.Model
+			merged.Updated = next.Model
+		}
+	}
+
+	return
+}
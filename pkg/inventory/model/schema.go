@@ -0,0 +1,229 @@
+package model
+
+import (
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sort"
+)
+
+//
+// DB schema.
+// The generated DDL and a (machine-readable) description of
+// the tables. Intended for generating documentation and ER
+// diagrams from the registered models.
+type DBSchema struct {
+	// Generated DDL (ordered as applied).
+	DDL []string `json:"ddl"`
+	// Tables (dependency ordered).
+	Tables []TableSchema `json:"tables"`
+}
+
+//
+// Table schema.
+type TableSchema struct {
+	// Table name (model kind).
+	Name string `json:"name"`
+	// Columns (ordered as declared).
+	Columns []ColumnSchema `json:"columns"`
+	// Indexes.
+	Indexes []IndexSchema `json:"indexes"`
+	// Relations (foreign keys).
+	Relations []RelationSchema `json:"relations"`
+}
+
+//
+// Column schema.
+type ColumnSchema struct {
+	// Column (field) name.
+	Name string `json:"name"`
+	// SQL type.
+	Type string `json:"type"`
+	// Primary key.
+	Pk bool `json:"pk,omitempty"`
+	// Part of the natural key.
+	Key bool `json:"key,omitempty"`
+	// Nullable.
+	Nullable bool `json:"nullable,omitempty"`
+	// Immutable (not updated).
+	Const bool `json:"const,omitempty"`
+	// Managed internally by the DB.
+	Virtual bool `json:"virtual,omitempty"`
+	// Full text (FTS) indexed.
+	Search bool `json:"search,omitempty"`
+	// JSON encoded.
+	Encoded bool `json:"encoded,omitempty"`
+	// Default value.
+	Default *string `json:"default,omitempty"`
+	// Enumerated values.
+	Enum []string `json:"enum,omitempty"`
+	// Collation.
+	Collate string `json:"collate,omitempty"`
+	// Stored using a (registered) converter.
+	Converted bool `json:"converted,omitempty"`
+}
+
+//
+// Index schema.
+type IndexSchema struct {
+	// Index name.
+	// The group name for unique (constraint) indexes.
+	Name string `json:"name"`
+	// Unique.
+	Unique bool `json:"unique,omitempty"`
+	// Indexed columns.
+	Columns []string `json:"columns"`
+}
+
+//
+// Relation (foreign key) schema.
+type RelationSchema struct {
+	// Referencing column.
+	Column string `json:"column"`
+	// Referenced table.
+	Table string `json:"table"`
+	// Referenced (PK) column.
+	Field string `json:"field"`
+	// The referenced model must exist (constraint).
+	Must bool `json:"must,omitempty"`
+	// Delete cascaded.
+	Cascade bool `json:"cascade,omitempty"`
+}
+
+//
+// Build the schema.
+func (r *DataModel) Schema() (schema *DBSchema, err error) {
+	ddl, err := r.DDL()
+	if err != nil {
+		return
+	}
+	schema = &DBSchema{
+		DDL:    ddl,
+		Tables: []TableSchema{},
+	}
+	fkRelation := FkRelation{dm: r}
+	for _, md := range fkRelation.Definitions() {
+		var table TableSchema
+		table, err = Table{}.Schema(md, r)
+		if err != nil {
+			schema = nil
+			return
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+
+	return
+}
+
+//
+// Describe the table.
+func (t Table) Schema(md *Definition, dm *DataModel) (schema TableSchema, err error) {
+	schema = TableSchema{
+		Name:      md.Kind,
+		Columns:   []ColumnSchema{},
+		Indexes:   []IndexSchema{},
+		Relations: []RelationSchema{},
+	}
+	for _, f := range md.RealFields(md.Fields) {
+		column := ColumnSchema{
+			Name:      f.Name,
+			Type:      f.SQLType(),
+			Pk:        f.Pk(),
+			Key:       f.Key(),
+			Nullable:  f.Nullable(),
+			Const:     f.hasOpt("const"),
+			Virtual:   f.Virtual(),
+			Search:    f.Search(),
+			Encoded:   f.Encoded(),
+			Enum:      f.Enum(),
+			Converted: f.Converted(),
+		}
+		if collation, found := f.Collate(); found {
+			column.Collate = collation
+		}
+		if value, found := f.Default(); found {
+			column.Default = &value
+		}
+		schema.Columns = append(schema.Columns, column)
+	}
+	if keyFields := md.RealFields(md.KeyFields()); len(keyFields) > 0 {
+		schema.Indexes = append(
+			schema.Indexes,
+			IndexSchema{
+				Name:    md.Kind + "Index",
+				Columns: columnNames(keyFields),
+			})
+	}
+	indexed := t.indexed(md)
+	groups := []string{}
+	for group := range indexed {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		schema.Indexes = append(
+			schema.Indexes,
+			IndexSchema{
+				Name:    md.Kind + group + "Index",
+				Columns: columnNames(md.RealFields(indexed[group])),
+			})
+	}
+	unique := md.Unique()
+	groups = []string{}
+	for group := range unique {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		schema.Indexes = append(
+			schema.Indexes,
+			IndexSchema{
+				Name:    group,
+				Unique:  true,
+				Columns: unique[group],
+			})
+	}
+	for _, fk := range md.Fks() {
+		refMd, found := dm.Find(fk.Table)
+		if !found {
+			err = liberr.New(
+				"FK ref not found.",
+				"kind",
+				md.Kind,
+				"ref",
+				fk.Table)
+			return
+		}
+		schema.Relations = append(
+			schema.Relations,
+			RelationSchema{
+				Column:  fk.Owner.Name,
+				Table:   refMd.Kind,
+				Field:   refMd.PkField().Name,
+				Must:    fk.Must,
+				Cascade: fk.Cascade,
+			})
+	}
+
+	return
+}
+
+//
+// Schema (DDL and table descriptions) of the registered models.
+func (r *Client) Schema() (schema *DBSchema, err error) {
+	if r.dm == nil {
+		err = liberr.New("DB not opened.")
+		return
+	}
+	schema, err = r.dm.Schema()
+	return
+}
+
+//
+// Field names.
+func columnNames(fields []*Field) (list []string) {
+	list = []string{}
+	for _, f := range fields {
+		list = append(list, f.Name)
+	}
+
+	return
+}
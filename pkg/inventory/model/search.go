@@ -0,0 +1,480 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//
+// Full text search (FTS4) DDL.
+// The (external content) index is maintained by triggers and
+// rebuilt when created or the indexed fields have changed.
+var SearchDDL = `
+CREATE VIRTUAL TABLE IF NOT EXISTS {{.Table}}Search
+USING fts4(
+content="{{.Table}}",
+{{ range $i,$f := .Fields -}}
+{{ if $i }},{{ end -}}
+{{ $f.Name }}
+{{ end -}}
+);
+CREATE TRIGGER IF NOT EXISTS {{.Table}}SearchBD
+BEFORE DELETE ON {{.Table}}
+BEGIN
+DELETE FROM {{.Table}}Search WHERE docid = old.rowid;
+END;
+CREATE TRIGGER IF NOT EXISTS {{.Table}}SearchBU
+BEFORE UPDATE ON {{.Table}}
+BEGIN
+DELETE FROM {{.Table}}Search WHERE docid = old.rowid;
+END;
+CREATE TRIGGER IF NOT EXISTS {{.Table}}SearchAI
+AFTER INSERT ON {{.Table}}
+BEGIN
+INSERT INTO {{.Table}}Search (
+docid
+{{ range $i,$f := .Fields -}}
+,{{ $f.Name }}
+{{ end -}}
+)
+VALUES (
+new.rowid
+{{ range $i,$f := .Fields -}}
+,new.{{ $f.Name }}
+{{ end -}}
+);
+END;
+CREATE TRIGGER IF NOT EXISTS {{.Table}}SearchAU
+AFTER UPDATE ON {{.Table}}
+BEGIN
+INSERT INTO {{.Table}}Search (
+docid
+{{ range $i,$f := .Fields -}}
+,{{ $f.Name }}
+{{ end -}}
+)
+VALUES (
+new.rowid
+{{ range $i,$f := .Fields -}}
+,new.{{ $f.Name }}
+{{ end -}}
+);
+END;
+`
+
+//
+// Full text search (FTS4) drop DDL.
+var SearchDropDDL = `
+DROP TRIGGER IF EXISTS {{.Table}}SearchBD;
+DROP TRIGGER IF EXISTS {{.Table}}SearchBU;
+DROP TRIGGER IF EXISTS {{.Table}}SearchAI;
+DROP TRIGGER IF EXISTS {{.Table}}SearchAU;
+DROP TABLE IF EXISTS {{.Table}}Search;
+`
+
+//
+// Default search (result) limit.
+var SearchLimit = 100
+
+//
+// Search result.
+type SearchResult struct {
+	// Model kind.
+	Kind string `json:"kind"`
+	// Rank (higher is better).
+	Rank float64 `json:"rank"`
+	// Matched model.
+	Model Model `json:"model"`
+}
+
+//
+// Build full text search DDL.
+// Only kinds with `search` fields are indexed.
+func (t Table) SearchDDL(md *Definition) (list []string, err error) {
+	fields := md.SearchFields()
+	if len(fields) == 0 {
+		return
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(SearchDDL)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:  md.Kind,
+			Fields: fields,
+		})
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	list = append(list, bfr.String())
+	return
+}
+
+//
+// Prepare the full text search index for the DDL.
+// An index with different (indexed) fields is dropped.
+// Returns true when the index is to be (re)built.
+func (t Table) searchChanged(md *Definition) (changed bool, err error) {
+	fields := md.SearchFields()
+	if len(fields) == 0 {
+		return
+	}
+	stmt := "PRAGMA table_info(" + md.Kind + "Search);"
+	cursor, err := t.DB.Query(stmt)
+	if err != nil {
+		err = liberr.Wrap(err, "sql", stmt)
+		return
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+	columns, err := cursor.Columns()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	names := []string{}
+	for cursor.Next() {
+		var name string
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		values[1] = &name
+		err = cursor.Scan(values...)
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+		names = append(names, name)
+	}
+	err = cursor.Err()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	_ = cursor.Close()
+	if len(names) == 0 {
+		changed = true
+		return
+	}
+	expected := []string{}
+	for _, f := range fields {
+		expected = append(expected, f.Name)
+	}
+	if strings.Join(names, ",") == strings.Join(expected, ",") {
+		return
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(SearchDropDDL)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(bfr, TmplData{Table: md.Kind})
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	_, err = t.DB.Exec(bfr.String())
+	if err != nil {
+		err = liberr.Wrap(err, "ddl", bfr.String())
+		return
+	}
+
+	changed = true
+
+	return
+}
+
+//
+// Rebuild the full text search index.
+func (t Table) searchRebuild(md *Definition) (err error) {
+	stmt := "INSERT INTO " + md.Kind + "Search (" + md.Kind + "Search) VALUES ('rebuild');"
+	_, err = t.DB.Exec(stmt)
+	if err != nil {
+		err = liberr.Wrap(err, "sql", stmt)
+	}
+
+	return
+}
+
+//
+// Full text (search) indexed fields.
+func (r *Definition) SearchFields() (list []*Field) {
+	for _, f := range r.RealFields(r.Fields) {
+		if f.Search() {
+			list = append(list, f)
+		}
+	}
+
+	return
+}
+
+//
+// Search (federated) across the model kinds.
+// Kinds with `search` fields are matched using the full text
+// (FTS) index; each term (word) is prefix matched. Otherwise,
+// the query is (case-insensitive) matched within the string
+// fields. The results of all kinds are ranked and truncated
+// to the limit (0 = SearchLimit). Results are ranked by how
+// closely a (matched) field value matches the query: exact,
+// prefix, contains, then matched terms. Each kind is ordered
+// by rank before the limit is applied. When no kinds are
+// specified, all kinds with `search` fields are searched.
+func (r *Client) SearchAll(query string, kinds []Model, limit int) (results []SearchResult, err error) {
+	results = []SearchResult{}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return
+	}
+	if limit <= 0 {
+		limit = SearchLimit
+	}
+	if len(kinds) == 0 {
+		for _, m := range r.models {
+			model, cast := m.(Model)
+			if !cast {
+				continue
+			}
+			md, found := r.dm.FindWith(model)
+			if found && len(md.SearchFields()) > 0 {
+				kinds = append(kinds, model)
+			}
+		}
+	}
+	mark := time.Now()
+	session := r.pool.Reader()
+	defer session.Return()
+	table := Table{Traced(context.Background(), session.db)}
+	for _, kind := range kinds {
+		md, mErr := Inspect(kind)
+		if mErr != nil {
+			err = mErr
+			return
+		}
+		predicate := &searchPredicate{query: query}
+		err = table.ForEach(
+			kind,
+			ListOptions{
+				Detail:    MaxDetail,
+				Predicate: predicate,
+				Stable:    true,
+				Page:      &Page{Limit: limit},
+			},
+			func(m Model) (err error) {
+				results = append(
+					results,
+					SearchResult{
+						Kind:  md.Kind,
+						Rank:  predicate.rank(m),
+						Model: m,
+					})
+				return
+			})
+		if err != nil {
+			return
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		if results[i].Kind != results[j].Kind {
+			return results[i].Kind < results[j].Kind
+		}
+		return results[i].Model.Pk() < results[j].Model.Pk()
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	r.log.V(4).Info(
+		"search succeeded.",
+		"query",
+		query,
+		"kinds",
+		len(kinds),
+		"matched",
+		len(results),
+		"duration",
+		time.Since(mark))
+
+	return
+}
+
+//
+// Search (text) predicate.
+// Uses the FTS index when the kind has `search` fields.
+// Matched models are ordered by rank.
+type searchPredicate struct {
+	// Query (text).
+	query string
+	// Matched (string) fields.
+	fields []*Field
+	// SQL expression.
+	expr string
+}
+
+//
+// Build.
+func (p *searchPredicate) Build(options *FilterOptions) (err error) {
+	md := &Definition{Kind: options.table, Fields: options.fields}
+	escaped := strings.NewReplacer(
+		`\`, `\\`,
+		"%", `\%`,
+		"_", `\_`).Replace(p.query)
+	p.fields = md.SearchFields()
+	if len(p.fields) > 0 {
+		terms := []string{}
+		for _, term := range p.terms() {
+			terms = append(terms, `"`+term+`*"`)
+		}
+		p.expr = strings.Join(
+			[]string{
+				"rowid IN (SELECT docid FROM",
+				options.table + "Search",
+				"WHERE",
+				options.table + "Search",
+				"MATCH",
+				options.Param("search", strings.Join(terms, " ")) + ")",
+			},
+			" ")
+		p.order(options, escaped)
+		return
+	}
+	for _, f := range md.RealFields(md.Fields) {
+		if f.Value.Kind() == reflect.String {
+			p.fields = append(p.fields, f)
+		}
+	}
+	if len(p.fields) == 0 {
+		p.expr = "0"
+		return
+	}
+	param := options.Param("search", "%"+escaped+"%")
+	matched := []string{}
+	for _, f := range p.fields {
+		matched = append(
+			matched,
+			f.Name+" LIKE "+param+` ESCAPE '\'`)
+	}
+	p.expr = "(" + strings.Join(matched, " OR ") + ")"
+	p.order(options, escaped)
+
+	return
+}
+
+//
+// Order (descending) by rank.
+// The SQL rank (exact, prefix, contains, other) of
+// the best ranked field.
+func (p *searchPredicate) order(options *FilterOptions, escaped string) {
+	exact := options.Param("exact", escaped)
+	prefix := options.Param("prefix", escaped+"%")
+	contains := options.Param("contains", "%"+escaped+"%")
+	ranked := []string{}
+	for _, f := range p.fields {
+		like := func(param string) string {
+			return f.Name + " LIKE " + param + ` ESCAPE '\'`
+		}
+		ranked = append(
+			ranked,
+			"CASE WHEN "+like(exact)+" THEN 4"+
+				" WHEN "+like(prefix)+" THEN 3"+
+				" WHEN "+like(contains)+" THEN 2"+
+				" ELSE 1 END")
+	}
+	rank := ranked[0]
+	if len(ranked) > 1 {
+		rank = "MAX(" + strings.Join(ranked, ",") + ")"
+	}
+
+	options.order = append(options.order, rank+" DESC")
+}
+
+//
+// Render the expression.
+func (p *searchPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Query terms (words).
+// Quotes are removed.
+func (p *searchPredicate) terms() (terms []string) {
+	query := strings.ReplaceAll(p.query, `"`, " ")
+	for _, term := range strings.Fields(query) {
+		term = strings.TrimRight(term, "*")
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+
+	return
+}
+
+//
+// Rank the (matched) model.
+// The best ranked (string) field value:
+//   4 = exact match.
+//   3 = prefix match.
+//   2 = contains the query.
+//   (0-1] = fraction of terms contained.
+func (p *searchPredicate) rank(m Model) (rank float64) {
+	md, err := Inspect(m)
+	if err != nil {
+		return
+	}
+	query := strings.ToLower(p.query)
+	terms := p.terms()
+	for _, f := range p.fields {
+		mf := md.Field(f.Name)
+		if mf == nil {
+			continue
+		}
+		fv := *mf.Value
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		value := strings.ToLower(fv.String())
+		score := 0.0
+		switch {
+		case value == query:
+			score = 4
+		case strings.HasPrefix(value, query):
+			score = 3
+		case strings.Contains(value, query):
+			score = 2
+		case len(terms) > 0:
+			n := 0
+			for _, term := range terms {
+				if strings.Contains(value, strings.ToLower(term)) {
+					n++
+				}
+			}
+			score = float64(n) / float64(len(terms))
+		}
+		if score > rank {
+			rank = score
+		}
+	}
+
+	return
+}
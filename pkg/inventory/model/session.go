@@ -72,6 +72,10 @@ func (s *Session) reset() {
 type Pool struct {
 	// Journal.
 	journal *Journal
+	// DB path.
+	path string
+	// Writers opened read-only.
+	readOnly bool
 	// All sessions.
 	sessions []*Session
 	// Next (free) sessions.
@@ -94,25 +98,16 @@ func (p *Pool) Open(nWriter, nReader int, path string, journal *Journal) (err er
 		}
 	}()
 	p.journal = journal
+	p.path = path
 	total := nWriter + nReader
 	p.next.writer = make(chan *Session, nWriter)
 	p.next.reader = make(chan *Session, nReader)
 	for id := 0; id < total; id++ {
 		session := &Session{id: id}
-		session.db, err = sql.Open("sqlite3", path)
+		session.db, err = p.connect(false)
 		if err != nil {
 			return
 		}
-		pragma := []string{
-			"PRAGMA foreign_keys = ON",
-			"PRAGMA journal_mode = WAL",
-		}
-		for _, stmt := range pragma {
-			_, err = session.db.Exec(stmt)
-			if err != nil {
-				return
-			}
-		}
 		p.sessions = append(
 			p.sessions,
 			session)
@@ -126,6 +121,70 @@ func (p *Pool) Open(nWriter, nReader int, path string, journal *Journal) (err er
 	return
 }
 
+//
+// Set the writers read-only.
+// The writer connections are reopened (when free) using
+// mode=ro so that the DB cannot be written. Used in
+// standby. See: Client.ReadOnly().
+func (p *Pool) ReadOnly(readOnly bool) (err error) {
+	if p.next.writer == nil || p.readOnly == readOnly {
+		return
+	}
+	taken := []*Session{}
+	defer func() {
+		for _, session := range taken {
+			p.next.writer <- session
+		}
+	}()
+	for i := 0; i < cap(p.next.writer); i++ {
+		taken = append(taken, <-p.next.writer)
+	}
+	for _, session := range taken {
+		var db *sql.DB
+		db, err = p.connect(readOnly)
+		if err != nil {
+			return
+		}
+		_ = session.db.Close()
+		session.db = db
+	}
+
+	p.readOnly = readOnly
+
+	return
+}
+
+//
+// Open a DB connection.
+// The read-only (mode=ro) connection does not set the
+// journal mode which requires a write.
+func (p *Pool) connect(readOnly bool) (db *sql.DB, err error) {
+	dsn := p.path
+	pragma := []string{
+		"PRAGMA foreign_keys = ON",
+		"PRAGMA journal_mode = WAL",
+	}
+	if readOnly {
+		dsn = "file:" + p.path + "?mode=ro"
+		pragma = pragma[:1]
+	}
+	db, err = sql.Open("sqlite3", dsn)
+	if err != nil {
+		err = liberr.Wrap(err, "path", p.path)
+		return
+	}
+	for _, stmt := range pragma {
+		_, err = db.Exec(stmt)
+		if err != nil {
+			_ = db.Close()
+			err = liberr.Wrap(err, "path", p.path)
+			return
+		}
+	}
+
+	return
+}
+
 //
 // Close the pool.
 // Close DB connections.
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"github.com/go-logr/logr"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"time"
+)
+
+//
+// Default watch snapshot page size.
+var SnapshotPageSize = 1000
+
+//
+// Snapshot reader.
+// Reads (of any kind) are executed within a single read
+// transaction and see a consistent point-in-time view.
+type Reader struct {
+	// Context.
+	ctx context.Context
+	// Read transaction.
+	real *sql.Tx
+	// Logger.
+	log logr.Logger
+}
+
+//
+// Get the model.
+func (r *Reader) Get(model Model) (err error) {
+	mark := time.Now()
+	err = Table{r.db()}.Get(model)
+	if err == nil {
+		r.log.V(4).Info(
+			"get succeeded.",
+			"model",
+			Describe(model),
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
+//
+// List models.
+// The `list` must be: *[]Model.
+func (r *Reader) List(list interface{}, options ListOptions) (err error) {
+	mark := time.Now()
+	err = Table{r.db()}.List(list, options)
+	if err == nil {
+		r.log.V(4).Info(
+			"list succeeded.",
+			"options",
+			options,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
+//
+// Find models.
+func (r *Reader) Find(model interface{}, options ListOptions) (itr fb.Iterator, err error) {
+	mark := time.Now()
+	itr, err = Table{r.db()}.Find(model, options)
+	if err == nil {
+		r.log.V(4).Info(
+			"find succeeded.",
+			"options",
+			options,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
+//
+// Iterate models.
+func (r *Reader) ForEach(model Model, options ListOptions, fn func(Model) error) (err error) {
+	mark := time.Now()
+	err = Table{r.db()}.ForEach(model, options, fn)
+	if err == nil {
+		r.log.V(4).Info(
+			"iterate succeeded.",
+			"options",
+			options,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
+//
+// Count models.
+func (r *Reader) Count(model Model, predicate Predicate) (n int64, err error) {
+	mark := time.Now()
+	n, err = Table{r.db()}.Count(model, predicate)
+	if err == nil {
+		r.log.V(4).Info(
+			"count succeeded.",
+			"predicate",
+			predicate,
+			"duration",
+			time.Since(mark))
+	}
+
+	return
+}
+
+//
+// Get the (traced) DB.
+func (r *Reader) db() DBTX {
+	return Traced(r.ctx, r.real)
+}
+
+//
+// Snapshot read.
+// The function is called with a reader for which all reads
+// are executed within a single read transaction so that reads
+// composed from several tables see a consistent view.
+func (r *Client) Snapshot(fn func(*Reader) error) (err error) {
+	err = r.SnapshotContext(context.Background(), fn)
+	return
+}
+
+//
+// Snapshot read with context.
+// The context is used to link (tracing) spans for statements
+// executed by the reader.
+func (r *Client) SnapshotContext(ctx context.Context, fn func(*Reader) error) (err error) {
+	mark := time.Now()
+	session := r.pool.Reader()
+	defer session.Return()
+	realTx, err := session.Begin()
+	if err != nil {
+		return
+	}
+	reader := &Reader{
+		ctx:  ctx,
+		real: realTx,
+		log:  r.log,
+	}
+	err = fn(reader)
+	if err != nil {
+		return
+	}
+
+	r.log.V(4).Info(
+		"snapshot succeeded.",
+		"duration",
+		time.Since(mark))
+
+	return
+}
+
+//
+// Build the (initial) watch snapshot.
+// Models are listed in pages within a single snapshot (read)
+// transaction so that the snapshot is stable. The watch is
+// registered with the journal before the snapshot is started so
+// that changes committed after the snapshot are queued as events
+// and there is no gap between the list and the watch.
+func (r *Client) watchSnapshot(model Model, options WatchOptions) (itr fb.Iterator, err error) {
+	mark := time.Now()
+	size := options.Page
+	if size < 1 {
+		size = SnapshotPageSize
+	}
+	list := fb.NewList()
+	err = r.Snapshot(
+		func(reader *Reader) (err error) {
+			for offset := 0; ; offset += size {
+				var page fb.Iterator
+				page, err = reader.Find(
+					model,
+					ListOptions{
+						Detail:    MaxDetail,
+						Predicate: options.Predicate,
+						Page: &Page{
+							Offset: offset,
+							Limit:  size,
+						},
+					})
+				if err != nil {
+					return
+				}
+				n := page.Len()
+				err = list.Append(page)
+				page.Close()
+				if err != nil {
+					return
+				}
+				if n < size {
+					break
+				}
+			}
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	itr = list.Iter()
+
+	r.log.V(4).Info(
+		"watch snapshot built.",
+		"model",
+		Describe(model),
+		"count",
+		itr.Len(),
+		"duration",
+		time.Since(mark))
+
+	return
+}
@@ -12,6 +12,8 @@ import (
 	fb "github.com/konveyor/controller/pkg/filebacked"
 	"github.com/mattn/go-sqlite3"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 )
@@ -109,11 +111,14 @@ WHERE
 {{ if .Predicate -}}
 {{ .Predicate.Expr }}
 {{ end -}}
-{{ if .Sort -}}
+{{ if or .Sort .Stable -}}
 ORDER BY
 {{ range $i,$n := .Sort -}}
 {{ if $i }},{{ end }}{{ $n }}
 {{ end -}}
+{{ if .Stable -}}
+{{ if .Sort }},{{ end }}{{ .Pk.Name }}
+{{ end -}}
 {{ end -}}
 {{ if .Page -}}
 LIMIT {{.Page.Limit}} OFFSET {{.Page.Offset}}
@@ -146,6 +151,16 @@ var (
 	PredicateValueErr = errors.New("predicate value not valid")
 	// Invalid detail level.
 	DetailErr = errors.New("detail level must be <= MaxDetail")
+	// Invalid default value.
+	DefaultErr = errors.New("default value not valid for field")
+	// Stored (encoded) field value cannot be decoded.
+	DecodeErr = errors.New("field value cannot be decoded")
+	// Searchable field type error.
+	SearchTypeErr = errors.New("search field must be (str)")
+	// Invalid collation.
+	CollateErr = errors.New("collation must be (binary, nocase, rtrim) on (str) field")
+	// Converter not found or not valid.
+	ConverterErr = errors.New("converter not found or not valid for field")
 )
 
 //
@@ -158,6 +173,10 @@ var (
 //   fk:<table>(field) - Foreign key.
 //   unique(<group>) - Unique constraint collated by <group>.
 //   const - Not updated.
+//   enum(<a,b,c>) - Enumerated values (CHECK constraint).
+//   notnull - Pointer field (column) not nullable.
+//   default=<value> - Column default value (may be 'quoted').
+//   search - Full text (FTS) indexed.
 type Table struct {
 	// Database connection.
 	DB DBTX
@@ -198,6 +217,13 @@ func (t Table) DDL(model interface{}, dm *DataModel) (list []string, err error)
 	for _, stmt := range ddl {
 		list = append(list, stmt)
 	}
+	ddl, err = t.SearchDDL(md)
+	if err != nil {
+		return
+	}
+	for _, stmt := range ddl {
+		list = append(list, stmt)
+	}
 
 	return
 }
@@ -264,30 +290,7 @@ func (t Table) KeyIndexDDL(md *Definition) (list []string, err error) {
 // Build non-unique index DDL.
 func (t Table) IndexDDL(md *Definition) (list []string, err error) {
 	tpl := template.New("")
-	index := map[string][]*Field{}
-	for _, field := range md.Fields {
-		for _, group := range field.Index() {
-			list, found := index[group]
-			if found {
-				index[group] = append(list, field)
-			} else {
-				index[group] = []*Field{field}
-			}
-		}
-	}
-	for _, fk := range md.Fks() {
-		if !fk.needsIndex() {
-			continue
-		}
-		group := fk.Owner.Name + "__FK__"
-		list, found := index[group]
-		if found {
-			index[group] = append(list, fk.Owner)
-		} else {
-			index[group] = []*Field{fk.Owner}
-		}
-	}
-	for group, idxFields := range index {
+	for group, idxFields := range t.indexed(md) {
 		tpl, err = tpl.Parse(IndexDDL)
 		if err != nil {
 			err = liberr.Wrap(err)
@@ -311,6 +314,27 @@ func (t Table) IndexDDL(md *Definition) (list []string, err error) {
 	return
 }
 
+//
+// Non-unique index fields by group.
+// Includes the (cascade) FK indexes.
+func (t Table) indexed(md *Definition) (index map[string][]*Field) {
+	index = map[string][]*Field{}
+	for _, field := range md.Fields {
+		for _, group := range field.Index() {
+			index[group] = append(index[group], field)
+		}
+	}
+	for _, fk := range md.Fks() {
+		if !fk.needsIndex() {
+			continue
+		}
+		group := fk.Owner.Name + "__FK__"
+		index[group] = append(index[group], fk.Owner)
+	}
+
+	return
+}
+
 //
 // Insert the model in the DB.
 // Expects the primary key (PK) to be set.
@@ -319,6 +343,18 @@ func (t Table) Insert(model interface{}) (err error) {
 	if err != nil {
 		return
 	}
+	err = md.validateEnums()
+	if err != nil {
+		return
+	}
+	err = md.validateBlobs()
+	if err != nil {
+		return
+	}
+	err = md.validateConverted()
+	if err != nil {
+		return
+	}
 	t.EnsurePk(md)
 	stmt, err := t.insertSQL(md)
 	if err != nil {
@@ -329,7 +365,17 @@ func (t Table) Insert(model interface{}) (err error) {
 	if err != nil {
 		if sql3Err, cast := err.(sqlite3.Error); cast {
 			if sql3Err.Code == sqlite3.ErrConstraint {
-				return t.Update(model)
+				ae := t.alreadyExists(md, err)
+				err = t.Update(model)
+				if ae != nil && errors.Is(err, NotFound) {
+					err = liberr.Wrap(
+						ae,
+						"kind",
+						ae.Kind,
+						"fields",
+						ae.Fields)
+				}
+				return
 			}
 		}
 		err = liberr.Wrap(
@@ -366,6 +412,18 @@ func (t Table) Update(model interface{}, predicate ...Predicate) (err error) {
 	if err != nil {
 		return
 	}
+	err = md.validateEnums()
+	if err != nil {
+		return
+	}
+	err = md.validateBlobs()
+	if err != nil {
+		return
+	}
+	err = md.validateConverted()
+	if err != nil {
+		return
+	}
 	t.EnsurePk(md)
 	options := &ListOptions{}
 	if len(predicate) > 0 {
@@ -378,6 +436,15 @@ func (t Table) Update(model interface{}, predicate ...Predicate) (err error) {
 	params := append(t.Params(md), options.Params()...)
 	r, err := t.DB.Exec(stmt, params...)
 	if err != nil {
+		if ae := t.alreadyExists(md, err); ae != nil {
+			err = liberr.Wrap(
+				ae,
+				"kind",
+				ae.Kind,
+				"fields",
+				ae.Fields)
+			return
+		}
 		err = liberr.Wrap(
 			err,
 			"sql",
@@ -562,6 +629,65 @@ func (t Table) List(list interface{}, options ListOptions) (err error) {
 	return
 }
 
+//
+// Iterate models in the DB.
+// Qualified by the list options.
+// The function is called for each model as the cursor
+// advances. Iteration stops when an error is returned.
+func (t Table) ForEach(model Model, options ListOptions, fn func(Model) error) (err error) {
+	md, err := Inspect(model)
+	if err != nil {
+		return
+	}
+	stmt, err := t.listSQL(md, &options)
+	if err != nil {
+		return
+	}
+	params := options.Params()
+	cursor, err := t.DB.Query(stmt, params...)
+	if err != nil {
+		err = liberr.Wrap(err, "sql", stmt, "params", params)
+		return
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+	n := 0
+	for cursor.Next() {
+		mt := reflect.TypeOf(model)
+		mPtr := reflect.New(mt.Elem())
+		mInt := mPtr.Interface()
+		mDef, _ := Inspect(mInt)
+		options.fields = mDef.Fields
+		err = t.scan(cursor, options.Fields())
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+		err = fn(mInt.(Model))
+		if err != nil {
+			return
+		}
+		n++
+	}
+	err = cursor.Err()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+
+	log.V(5).Info(
+		"table: iterate succeeded.",
+		"sql",
+		stmt,
+		"params",
+		params,
+		"matched",
+		n)
+
+	return
+}
+
 //
 // Find models in the DB.
 // Qualified by the list options.
@@ -595,7 +721,11 @@ func (t Table) Find(model interface{}, options ListOptions) (itr fb.Iterator, er
 			err = liberr.Wrap(err)
 			return
 		}
-		list.Append(mPtr.Interface())
+		err = list.Append(mPtr.Interface())
+		if err != nil {
+			list.Close()
+			return
+		}
 	}
 
 	itr = list.Iter()
@@ -712,18 +842,14 @@ func (t Table) EnsurePk(md *Definition) {
 // Get constraint DDL.
 func (t Table) Constraints(md *Definition, dm *DataModel) (constraints []string, err error) {
 	constraints = []string{}
-	unique := map[string][]string{}
-	for _, field := range md.Fields {
-		for _, name := range field.Unique() {
-			list, found := unique[name]
-			if found {
-				unique[name] = append(list, field.Name)
-			} else {
-				unique[name] = []string{field.Name}
-			}
-		}
-	}
-	for _, list := range unique {
+	unique := md.Unique()
+	groups := []string{}
+	for name := range unique {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		list := unique[name]
 		constraints = append(
 			constraints,
 			fmt.Sprintf(
@@ -764,11 +890,18 @@ func (t Table) insertSQL(md *Definition) (sql string, err error) {
 		return
 	}
 	bfr := &bytes.Buffer{}
+	fields := []*Field{}
+	for _, f := range md.RealFields(md.Fields) {
+		if _, found := f.Default(); found && f.Pull() == nil {
+			continue // defaulted.
+		}
+		fields = append(fields, f)
+	}
 	err = tpl.Execute(
 		bfr,
 		TmplData{
 			Table:  md.Kind,
-			Fields: md.RealFields(md.Fields),
+			Fields: fields,
 		})
 	if err != nil {
 		err = liberr.Wrap(err)
@@ -945,7 +1078,10 @@ func (t Table) scan(row Row, fields []*Field) (err error) {
 		return
 	}
 	for _, f := range fields {
-		f.Push()
+		err = f.Push()
+		if err != nil {
+			return
+		}
 	}
 
 	return
@@ -986,8 +1122,24 @@ func (t TmplData) Page() *Page {
 
 //
 // Sort criteria
-func (t TmplData) Sort() []int {
-	return t.Options.Sort
+// Predicate ordering, field positions then field names.
+func (t TmplData) Sort() (list []string) {
+	list = append(list, t.Options.order...)
+	for _, n := range t.Options.Sort {
+		list = append(list, strconv.Itoa(n))
+	}
+	for _, f := range t.Options.sortBy {
+		list = append(list, f.Name)
+	}
+
+	return
+}
+
+//
+// Stable (PK) ordering.
+// Not applicable to count.
+func (t TmplData) Stable() bool {
+	return t.Options.Stable && !t.Count
 }
 
 //
@@ -997,6 +1149,15 @@ type FilterOptions struct {
 	Page *Page
 	// Sort by field position.
 	Sort []int
+	// Sort by field name.
+	// Applied after Sort.
+	SortBy []string
+	// Stable (deterministic) ordering.
+	// Models are ordered by PK when no sort is specified;
+	// otherwise, the PK is the last sort (tie-breaker). The
+	// (SQLite) row order is unspecified without ordering and
+	// may differ between queries; required by pagination.
+	Stable bool
 	// Field detail level.
 	// Defaults:
 	//   0 = primary and natural fields.
@@ -1008,6 +1169,10 @@ type FilterOptions struct {
 	table string
 	// Fields.
 	fields []*Field
+	// Sort (by name) fields.
+	sortBy []*Field
+	// Ordering (expressions) contributed by the predicate.
+	order []string
 	// Params.
 	params []interface{}
 }
@@ -1017,6 +1182,21 @@ type FilterOptions struct {
 func (l *FilterOptions) Build(md *Definition) (err error) {
 	l.table = md.Kind
 	l.fields = md.Fields
+	l.sortBy = nil
+	l.order = nil
+	for _, name := range l.SortBy {
+		f := md.Field(name)
+		if f == nil {
+			err = liberr.New(
+				"sort: field not found.",
+				"kind",
+				md.Kind,
+				"field",
+				name)
+			return
+		}
+		l.sortBy = append(l.sortBy, f)
+	}
 	if l.Predicate != nil {
 		err = l.Predicate.Build(l)
 	}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"encoding/json"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"time"
+)
+
+//
+// Time (column) format.
+// Fixed width (UTC) so that stored values are ordered
+// and may be compared as text.
+const TimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+//
+// Location of time values scanned from the DB.
+// Times are stored in UTC.
+var TimeLocation = time.UTC
+
+//
+// Time type.
+var timeType = reflect.TypeOf(time.Time{})
+
+//
+// Duration type.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+//
+// Get whether the field is a time.Time (or *time.Time).
+func (f *Field) Time() bool {
+	t := f.Value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t == timeType
+}
+
+//
+// Get whether the field is a time.Duration (or *time.Duration).
+// Durations are stored as (int) nanoseconds.
+func (f *Field) Duration() bool {
+	t := f.Value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t == durationType
+}
+
+//
+// Get whether the (time) field is stored as unix
+// nanoseconds rather than text (RFC3339).
+func (f *Field) Unix() bool {
+	return f.hasOpt("unix")
+}
+
+//
+// Pull time into the `staging` fields.
+func (f *Field) pullTime(value reflect.Value) interface{} {
+	t := value.Interface().(time.Time)
+	if f.Unix() {
+		f.int = 0
+		if !t.IsZero() {
+			f.int = t.UnixNano()
+		}
+		return f.int
+	}
+
+	f.string = t.UTC().Format(TimeFormat)
+
+	return f.string
+}
+
+//
+// Push time from the `staging` fields.
+func (f *Field) pushTime(value reflect.Value) {
+	t := time.Time{}
+	if f.Unix() {
+		if f.int != 0 {
+			t = time.Unix(0, f.int)
+		}
+	} else {
+		parsed, err := time.Parse(time.RFC3339Nano, f.string)
+		if err == nil {
+			t = parsed
+		} else {
+			// previously stored (json) encoded.
+			_ = json.Unmarshal([]byte(f.string), &t)
+		}
+	}
+	if !t.IsZero() {
+		t = t.In(TimeLocation)
+	}
+
+	value.Set(reflect.ValueOf(t))
+}
+
+//
+// Convert the specified `object` to a (time) value
+// appropriate for the field.
+// Accepts time.Time, *time.Time or an RFC3339 string.
+func (f *Field) timeValue(object interface{}) (value interface{}, err error) {
+	var t time.Time
+	switch v := object.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			err = liberr.Wrap(PredicateValueErr)
+			return
+		}
+		t = *v
+	case string:
+		t, err = time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			err = liberr.Wrap(PredicateValueErr, "value", v)
+			return
+		}
+	default:
+		err = liberr.Wrap(PredicateValueErr)
+		return
+	}
+
+	value = f.pullTime(reflect.ValueOf(t))
+
+	return
+}
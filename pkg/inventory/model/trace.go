@@ -0,0 +1,73 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"github.com/konveyor/controller/pkg/logging"
+	"github.com/konveyor/controller/pkg/tracing"
+)
+
+//
+// Wrap the DB with (tracing) spans for each statement.
+// Returns the DB unchanged when tracing is not enabled.
+// The reconcile ID (when carried by the context) is set
+// on each span.
+func Traced(ctx context.Context, db DBTX) DBTX {
+	if !tracing.Enabled() {
+		return db
+	}
+	traced := &tracedDB{
+		DBTX: db,
+		ctx:  ctx,
+	}
+	if id := logging.ReconcileIDOf(ctx); id != "" {
+		traced.kv = []interface{}{tracing.Reconcile, id}
+	}
+
+	return traced
+}
+
+//
+// Traced DB.
+type tracedDB struct {
+	DBTX
+	// Context.
+	ctx context.Context
+	// Span attributes.
+	kv []interface{}
+}
+
+//
+// Execute a statement.
+func (r *tracedDB) Exec(stmt string, args ...interface{}) (result sql.Result, err error) {
+	span := r.start("model.db.exec", stmt)
+	defer tracing.End(span, &err)
+	result, err = r.DBTX.Exec(stmt, args...)
+	return
+}
+
+//
+// Execute a query.
+func (r *tracedDB) Query(stmt string, args ...interface{}) (rows *sql.Rows, err error) {
+	span := r.start("model.db.query", stmt)
+	defer tracing.End(span, &err)
+	rows, err = r.DBTX.Query(stmt, args...)
+	return
+}
+
+//
+// Execute a query for a single row.
+// Errors are reported by the row scan and are not recorded.
+func (r *tracedDB) QueryRow(stmt string, args ...interface{}) *sql.Row {
+	span := r.start("model.db.query", stmt)
+	defer span.End()
+	return r.DBTX.QueryRow(stmt, args...)
+}
+
+//
+// Start a (statement) span.
+func (r *tracedDB) start(name, stmt string) tracing.Span {
+	kv := append([]interface{}{tracing.Statement, stmt}, r.kv...)
+	_, span := tracing.Start(r.ctx, name, kv...)
+	return span
+}
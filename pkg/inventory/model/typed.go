@@ -0,0 +1,249 @@
+//go:build go1.18
+// +build go1.18
+
+package model
+
+import (
+	"context"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"sync"
+)
+
+//
+// Default typed watch (channel) buffer.
+var TypedBuffer = 100
+
+//
+// Typed model event.
+// Model events have Action = Created|Updated|Deleted. The
+// watch lifecycle is reported as Parity, Reset (with the reason)
+// and Error (with the error) events. The (events) channel is
+// closed when the watch has ended.
+type TypedEvent[T Model] struct {
+	// ID.
+	ID uint64
+	// Sequence number.
+	Seq uint64
+	// Labels.
+	Labels []string
+	// The event action.
+	Action uint8
+	// The prior model (updated|deleted).
+	Old T
+	// The new model (created|updated).
+	New T
+	// Reset reason.
+	Reason string
+	// Error.
+	Err error
+	// The context of the transaction that
+	// reported the event.
+	ctx context.Context
+}
+
+//
+// The event subject.
+// The new model when created or updated, else the prior.
+func (r *TypedEvent[T]) Model() (m T) {
+	if r.Action == Deleted {
+		m = r.Old
+	} else {
+		m = r.New
+	}
+
+	return
+}
+
+//
+// The context of the transaction that reported the event.
+func (r *TypedEvent[T]) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+//
+// Get whether the event has the specified label.
+func (r *TypedEvent[T]) HasLabel(label string) bool {
+	for _, l := range r.Labels {
+		if l == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Typed model watch.
+type TypedWatch[T Model] struct {
+	// Events.
+	// Closed when the watch has ended.
+	Events <-chan TypedEvent[T]
+	// Watch.
+	watch *Watch
+	// Handler.
+	handler *typedHandler[T]
+}
+
+//
+// Watch ID.
+func (w *TypedWatch[T]) ID() uint64 {
+	return w.watch.ID()
+}
+
+//
+// End the watch.
+// Events not yet received are discarded.
+func (w *TypedWatch[T]) End() {
+	w.handler.cancel()
+	w.watch.End()
+}
+
+//
+// Watch a model kind with typed events.
+// The kind is determined by the (pointer) type parameter.
+// The events are buffered (see: TypedBuffer); the watch does not
+// deliver further events until the consumer has received them.
+// Example:
+//   w, err := model.WatchTyped[*VM](db, model.WatchOptions{})
+//   defer w.End()
+//   for event := range w.Events {
+//      if event.Action == model.Updated {
+//         diff(event.Old, event.New)
+//      }
+//   }
+func WatchTyped[T Model](db DB, options WatchOptions) (w *TypedWatch[T], err error) {
+	mt := reflect.TypeOf((*T)(nil)).Elem()
+	if mt.Kind() != reflect.Ptr {
+		err = liberr.Wrap(
+			MustBePtrErr,
+			"type",
+			mt.String())
+		return
+	}
+	kind := reflect.New(mt.Elem()).Interface().(T)
+	events := make(chan TypedEvent[T], TypedBuffer)
+	handler := &typedHandler[T]{
+		options: options,
+		events:  events,
+		done:    make(chan struct{}),
+	}
+	watch, err := db.Watch(kind, handler)
+	if err != nil {
+		return
+	}
+	w = &TypedWatch[T]{
+		Events:  events,
+		watch:   watch,
+		handler: handler,
+	}
+
+	return
+}
+
+//
+// Typed (channel) event handler.
+type typedHandler[T Model] struct {
+	// Watch options.
+	options WatchOptions
+	// Events.
+	events chan TypedEvent[T]
+	// Closed when the consumer has ended the watch.
+	done chan struct{}
+	// Cancel once.
+	once sync.Once
+}
+
+//
+// Watch options.
+func (h *typedHandler[T]) Options() WatchOptions {
+	return h.options
+}
+
+//
+// Watch has started.
+func (h *typedHandler[T]) Started(uint64) {
+}
+
+//
+// Parity marker.
+func (h *typedHandler[T]) Parity() {
+	h.send(TypedEvent[T]{Action: Parity})
+}
+
+//
+// A model has been created.
+func (h *typedHandler[T]) Created(event Event) {
+	typed := h.typed(event)
+	typed.New, _ = event.Model.(T)
+	h.send(typed)
+}
+
+//
+// A model has been updated.
+func (h *typedHandler[T]) Updated(event Event) {
+	typed := h.typed(event)
+	typed.Old, _ = event.Model.(T)
+	typed.New, _ = event.Updated.(T)
+	h.send(typed)
+}
+
+//
+// A model has been deleted.
+func (h *typedHandler[T]) Deleted(event Event) {
+	typed := h.typed(event)
+	typed.Old, _ = event.Model.(T)
+	h.send(typed)
+}
+
+//
+// An error has occurred delivering an event.
+func (h *typedHandler[T]) Error(err error) {
+	h.send(TypedEvent[T]{Action: Error, Err: err})
+}
+
+//
+// Events have been discarded or the DB rebuilt.
+func (h *typedHandler[T]) Reset(reason string) {
+	h.send(TypedEvent[T]{Action: Reset, Reason: reason})
+}
+
+//
+// The watch has ended.
+func (h *typedHandler[T]) End() {
+	close(h.events)
+}
+
+//
+// Build the typed event.
+func (h *typedHandler[T]) typed(event Event) TypedEvent[T] {
+	return TypedEvent[T]{
+		ID:     event.ID,
+		Seq:    event.Seq,
+		Labels: event.Labels,
+		Action: event.Action,
+		ctx:    event.ctx,
+	}
+}
+
+//
+// Send the event.
+// Discarded when the consumer has ended the watch.
+func (h *typedHandler[T]) send(event TypedEvent[T]) {
+	select {
+	case h.events <- event:
+	case <-h.done:
+	}
+}
+
+//
+// The consumer has ended the watch.
+func (h *typedHandler[T]) cancel() {
+	h.once.Do(func() {
+		close(h.done)
+	})
+}
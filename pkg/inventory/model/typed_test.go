@@ -0,0 +1,61 @@
+//go:build go1.18
+// +build go1.18
+
+package model
+
+import (
+	"github.com/onsi/gomega"
+	"testing"
+)
+
+func TestWatchTyped(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-watch-typed.db", &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(false)
+	}()
+	err = DB.Insert(&TestObject{ID: 0, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	w, err := WatchTyped[*TestObject](DB, WatchOptions{Snapshot: true})
+	g.Expect(err).To(gomega.BeNil())
+	// snapshot.
+	event := <-w.Events
+	g.Expect(event.Action).To(gomega.Equal(Created))
+	g.Expect(event.New.ID).To(gomega.Equal(0))
+	g.Expect(event.Old).To(gomega.BeNil())
+	event = <-w.Events
+	g.Expect(event.Action).To(gomega.Equal(Parity))
+	// created.
+	err = DB.Insert(&TestObject{ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	event = <-w.Events
+	g.Expect(event.Action).To(gomega.Equal(Created))
+	g.Expect(event.Model().ID).To(gomega.Equal(1))
+	// updated.
+	m := &TestObject{ID: 1}
+	g.Expect(DB.Get(m)).To(gomega.BeNil())
+	m.Name = "Fudd"
+	g.Expect(DB.Update(m)).To(gomega.BeNil())
+	event = <-w.Events
+	g.Expect(event.Action).To(gomega.Equal(Updated))
+	g.Expect(event.Old.Name).To(gomega.Equal("Elmer"))
+	g.Expect(event.New.Name).To(gomega.Equal("Fudd"))
+	g.Expect(event.Model()).To(gomega.Equal(event.New))
+	// deleted.
+	g.Expect(DB.Delete(m)).To(gomega.BeNil())
+	event = <-w.Events
+	g.Expect(event.Action).To(gomega.Equal(Deleted))
+	g.Expect(event.Old.ID).To(gomega.Equal(1))
+	g.Expect(event.Model()).To(gomega.Equal(event.Old))
+	// ended.
+	err = DB.Insert(&TestObject{ID: 2, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	w.End()
+	for range w.Events {
+	}
+	// not pointer.
+	_, err = WatchTyped[Model](DB, WatchOptions{})
+	g.Expect(err).ToNot(gomega.BeNil())
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"sort"
+	"strings"
+)
+
+//
+// Unique constraint (message) prefix reported by sqlite.
+const uniqueFailed = "UNIQUE constraint failed:"
+
+//
+// Already exists.
+// A write conflicts with another model on the fields of
+// a `unique(group)` constraint.
+// Matched using: errors.Is(err, AlreadyExistsErr) or errors.As().
+type AlreadyExists struct {
+	// Model kind.
+	Kind string
+	// Constraint (group) name.
+	Group string
+	// Conflicting fields.
+	Fields []string
+}
+
+//
+// Already exists (sentinel).
+var AlreadyExistsErr = &AlreadyExists{}
+
+//
+// Error description.
+func (e *AlreadyExists) Error() string {
+	if e.Kind == "" {
+		return "already exists"
+	}
+
+	return fmt.Sprintf(
+		"%s already exists: (%s)",
+		e.Kind,
+		strings.Join(e.Fields, ","))
+}
+
+//
+// Supports errors.Is().
+func (e *AlreadyExists) Is(target error) bool {
+	_, cast := target.(*AlreadyExists)
+	return cast
+}
+
+//
+// Unique constraints (fields) by group name.
+func (r *Definition) Unique() (groups map[string][]string) {
+	groups = map[string][]string{}
+	for _, field := range r.Fields {
+		for _, name := range field.Unique() {
+			groups[name] = append(groups[name], field.Name)
+		}
+	}
+
+	return
+}
+
+//
+// Map a unique constraint violation reported by sqlite
+// to an AlreadyExists error.
+// Returns: nil when not a unique constraint violation.
+func (t Table) alreadyExists(md *Definition, err error) (ae *AlreadyExists) {
+	sql3Err, cast := err.(sqlite3.Error)
+	if !cast || sql3Err.Code != sqlite3.ErrConstraint {
+		return
+	}
+	msg := sql3Err.Error()
+	n := strings.Index(msg, uniqueFailed)
+	if n == -1 {
+		return
+	}
+	ae = &AlreadyExists{Kind: md.Kind}
+	for _, column := range strings.Split(msg[n+len(uniqueFailed):], ",") {
+		column = strings.TrimSpace(column)
+		if n := strings.LastIndex(column, "."); n != -1 {
+			column = column[n+1:]
+		}
+		ae.Fields = append(ae.Fields, column)
+	}
+	matched := append([]string{}, ae.Fields...)
+	sort.Strings(matched)
+	for name, fields := range md.Unique() {
+		fields = append([]string{}, fields...)
+		sort.Strings(fields)
+		if strings.Join(fields, ",") == strings.Join(matched, ",") {
+			ae.Group = name
+			break
+		}
+	}
+
+	return
+}
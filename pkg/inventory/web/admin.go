@@ -0,0 +1,475 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/container"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"time"
+)
+
+//
+// Routes.
+const (
+	AdminRoot        = "/admin"
+	AdminWatches     = AdminRoot + "/watches"
+	AdminTxs         = AdminRoot + "/transactions"
+	AdminCollections = AdminRoot + "/collections"
+	AdminBudgets     = AdminRoot + "/budgets"
+	AdminFileBacked  = AdminRoot + "/filebacked"
+	AdminAudit       = AdminRoot + "/audit"
+	AdminTokens      = AdminRoot + "/tokens"
+	AdminLogging     = AdminRoot + "/logging"
+	AdminConnections = AdminRoot + "/connections"
+	AdminPaused      = AdminRoot + "/paused"
+	PprofRoot        = "/debug/pprof"
+	WatchParam       = "watch"
+	KindParam        = "kind"
+	PKParam          = "pk"
+	ActorParam       = "actor"
+	SinceParam       = "since"
+	TokenParam       = "token"
+)
+
+//
+// Admin server default address.
+// Local only.
+const AdminAddress = "127.0.0.1:8081"
+
+//
+// Collector watch reports.
+type CollectorWatches struct {
+	// Collector name.
+	Name string `json:"name"`
+	// Watches.
+	Watches []model.WatchReport `json:"watches"`
+}
+
+//
+// Collector open transaction reports.
+type CollectorTxs struct {
+	// Collector name.
+	Name string `json:"name"`
+	// Open transactions.
+	Transactions []model.TxReport `json:"transactions"`
+}
+
+//
+// Collector audit log.
+type CollectorAudit struct {
+	// Collector name.
+	Name string `json:"name"`
+	// Audit entries.
+	Entries []model.Audit `json:"entries"`
+}
+
+//
+// Token (issue) request.
+type TokenRequest struct {
+	// Subject. Example: user or service account.
+	Subject string `json:"subject" binding:"required"`
+	// TTL (duration). Example: 8h.
+	// Default: TokenService.TTL.
+	TTL string `json:"ttl,omitempty"`
+	// Scopes.
+	Scopes []Scope `json:"scopes" binding:"required"`
+}
+
+//
+// Issued token.
+type IssuedToken struct {
+	// Token (claims).
+	Token
+	// Signed (bearer) token.
+	Signed string `json:"token"`
+}
+
+//
+// Admin (debug) handler.
+// Exposes internal state used to debug a wedged controller:
+//   GET    /admin/watches              - Watches by collector.
+//   DELETE /admin/watches/:name/:watch - End a (leaked) watch.
+//   GET    /admin/transactions         - Open transactions by collector.
+//   GET    /admin/collections          - Collection reconcile statistics.
+//   GET    /admin/budgets              - Collector budget usage.
+//   GET    /admin/filebacked           - File-backed collection disk usage.
+//   GET    /admin/audit                - Audit log by collector.
+//   POST   /admin/tokens               - Issue a (scoped) token.
+//   DELETE /admin/tokens/:token        - Revoke a token (by ID).
+//   GET    /admin/logging              - List log levels.
+//   PUT    /admin/logging/:name        - Set a log level.
+//   DELETE /admin/logging/:name        - Reset a log level.
+//   GET    /admin/connections          - Provider connection status.
+//   GET    /admin/connections/:name    - Provider connection status by name.
+//   GET    /admin/paused               - List paused kinds.
+//   PUT    /admin/paused/:kind         - Pause reconciliation of a kind.
+//   DELETE /admin/paused/:kind         - Resume reconciliation of a kind.
+//   GET    /debug/pprof/*              - Runtime profiling (pprof).
+// Not intended for the public server. See: AdminServer.
+type AdminHandler struct {
+	// Reference to the container.
+	Container *container.Container
+	// Token service (optional).
+	Tokens *TokenService
+}
+
+//
+// Add routes.
+func (h *AdminHandler) AddRoutes(r *gin.Engine) {
+	r.GET(AdminWatches, h.Watches)
+	r.DELETE(AdminWatches+"/:"+NameParam+"/:"+WatchParam, h.EndWatch)
+	r.GET(AdminTxs, h.Transactions)
+	r.GET(AdminCollections, h.Collections)
+	r.GET(AdminBudgets, h.Budgets)
+	r.GET(AdminFileBacked, h.FileBacked)
+	r.GET(AdminAudit, h.Audit)
+	r.GET(AdminPaused, h.Paused)
+	r.PUT(AdminPaused+"/:"+KindParam, h.Pause)
+	r.DELETE(AdminPaused+"/:"+KindParam, h.Resume)
+	if h.Tokens != nil {
+		r.POST(AdminTokens, h.IssueToken)
+		r.DELETE(AdminTokens+"/:"+TokenParam, h.RevokeToken)
+	}
+	levels := &LoggingHandler{}
+	levels.AddRoutes(r)
+	connections := &ConnectionHandler{Container: h.Container}
+	connections.AddRoutes(r)
+	r.GET(PprofRoot+"/cmdline", gin.WrapF(pprof.Cmdline))
+	r.GET(PprofRoot+"/profile", gin.WrapF(pprof.Profile))
+	r.GET(PprofRoot+"/symbol", gin.WrapF(pprof.Symbol))
+	r.POST(PprofRoot+"/symbol", gin.WrapF(pprof.Symbol))
+	r.GET(PprofRoot+"/trace", gin.WrapF(pprof.Trace))
+	r.GET(PprofRoot+"/", gin.WrapF(pprof.Index))
+	for _, name := range []string{
+		"allocs",
+		"block",
+		"goroutine",
+		"heap",
+		"mutex",
+		"threadcreate",
+	} {
+		r.GET(PprofRoot+"/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+}
+
+//
+// List watches.
+func (h *AdminHandler) Watches(ctx *gin.Context) {
+	list := []CollectorWatches{}
+	for _, collector := range h.collectors() {
+		db := collector.DB()
+		if db == nil {
+			continue
+		}
+		list = append(
+			list,
+			CollectorWatches{
+				Name:    collector.Name(),
+				Watches: db.Watches(),
+			})
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+//
+// End a watch.
+// The watch is identified by collector name and watch ID.
+func (h *AdminHandler) EndWatch(ctx *gin.Context) {
+	name := ctx.Param(NameParam)
+	id, err := strconv.ParseUint(ctx.Param(WatchParam), 10, 64)
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+	for _, collector := range h.collectors() {
+		if collector.Name() != name {
+			continue
+		}
+		db := collector.DB()
+		if db == nil {
+			break
+		}
+		err = db.EndWatchID(id)
+		if err != nil {
+			if errors.Is(err, model.NotFound) {
+				break
+			}
+			Fail(ctx, err)
+			return
+		}
+		log.Info(
+			"watch ended.",
+			"collector",
+			name,
+			"watch",
+			id)
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	Fail(ctx, liberr.Wrap(NotFoundErr, "collector", name, "watch", id))
+}
+
+//
+// List open transactions.
+func (h *AdminHandler) Transactions(ctx *gin.Context) {
+	list := []CollectorTxs{}
+	for _, collector := range h.collectors() {
+		db := collector.DB()
+		if db == nil {
+			continue
+		}
+		list = append(
+			list,
+			CollectorTxs{
+				Name:         collector.Name(),
+				Transactions: db.Transactions(),
+			})
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+//
+// List collection reconcile statistics.
+func (h *AdminHandler) Collections(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, container.Reconciles.List())
+}
+
+//
+// List paused kinds.
+func (h *AdminHandler) Paused(ctx *gin.Context) {
+	list := []string{}
+	if h.Container != nil {
+		list = h.Container.Paused()
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+//
+// Pause reconciliation of a kind.
+func (h *AdminHandler) Pause(ctx *gin.Context) {
+	if h.Container == nil {
+		Fail(ctx, UnavailableErr)
+		return
+	}
+	kind := ctx.Param(KindParam)
+	h.Container.Pause(kind)
+	log.Info(
+		"kind paused.",
+		"kind",
+		kind)
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//
+// Resume reconciliation of a kind.
+func (h *AdminHandler) Resume(ctx *gin.Context) {
+	if h.Container == nil {
+		Fail(ctx, UnavailableErr)
+		return
+	}
+	kind := ctx.Param(KindParam)
+	h.Container.Resume(kind)
+	log.Info(
+		"kind resumed.",
+		"kind",
+		kind)
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//
+// List collector budget usage.
+func (h *AdminHandler) Budgets(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, container.Budgets.List())
+}
+
+//
+// File-backed disk usage.
+func (h *AdminHandler) FileBacked(ctx *gin.Context) {
+	usage, err := fb.DiskUsage()
+	if err != nil {
+		Fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, usage)
+}
+
+//
+// List the audit log.
+// Query params:
+//   kind  - Entry (model) kind. Example: Request.
+//   pk    - Model (primary key).
+//   actor - Actor.
+//   since - RFC3339 time.
+// Collectors with auditing not enabled are omitted.
+func (h *AdminHandler) Audit(ctx *gin.Context) {
+	query := model.AuditQuery{
+		Kind:  ctx.Query(KindParam),
+		PK:    ctx.Query(PKParam),
+		Actor: ctx.Query(ActorParam),
+	}
+	if s := ctx.Query(SinceParam); s != "" {
+		since, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			Fail(ctx, &BadRequest{Err: err})
+			return
+		}
+		query.Since = since
+	}
+	list := []CollectorAudit{}
+	for _, collector := range h.collectors() {
+		db := collector.DB()
+		if db == nil {
+			continue
+		}
+		entries, err := db.AuditLog(query)
+		if err != nil {
+			if errors.Is(err, model.AuditNotEnabledErr) {
+				continue
+			}
+			Fail(ctx, err)
+			return
+		}
+		list = append(
+			list,
+			CollectorAudit{
+				Name:    collector.Name(),
+				Entries: entries,
+			})
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+//
+// Issue a token.
+func (h *AdminHandler) IssueToken(ctx *gin.Context) {
+	request := TokenRequest{}
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		return
+	}
+	var ttl time.Duration
+	if request.TTL != "" {
+		ttl, err = time.ParseDuration(request.TTL)
+		if err == nil && ttl < 0 {
+			err = liberr.New("ttl must be >= 0.")
+		}
+		if err != nil {
+			Fail(ctx, &BadRequest{Err: err})
+			return
+		}
+	}
+	signed, token, err := h.Tokens.Issue(request.Subject, ttl, request.Scopes...)
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+
+	ctx.JSON(
+		http.StatusCreated,
+		IssuedToken{
+			Token:  token,
+			Signed: signed,
+		})
+}
+
+//
+// Revoke a token.
+func (h *AdminHandler) RevokeToken(ctx *gin.Context) {
+	err := h.Tokens.Revoke(ctx.Param(TokenParam))
+	if err != nil {
+		Fail(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//
+// Collectors.
+func (h *AdminHandler) collectors() []container.Collector {
+	if h.Container == nil {
+		return nil
+	}
+
+	return h.Container.List()
+}
+
+//
+// Admin server.
+// Opt-in server (mux) for the admin handler. Listens
+// on a separate (local by default) address so that
+// internal state is not exposed by the public server.
+type AdminServer struct {
+	// Listen address. Default: AdminAddress.
+	Address string
+	// Reference to the container.
+	Container *container.Container
+	// Token service (optional).
+	Tokens *TokenService
+	// HTTP server.
+	server *http.Server
+}
+
+//
+// Start the admin server.
+func (w *AdminServer) Start() {
+	if w.Address == "" {
+		w.Address = AdminAddress
+	}
+	router := gin.New()
+	router.Use(gin.Recovery())
+	handler := &AdminHandler{
+		Container: w.Container,
+		Tokens:    w.Tokens,
+	}
+	handler.AddRoutes(router)
+	w.server = &http.Server{
+		Addr:    w.Address,
+		Handler: router,
+	}
+	go func() {
+		err := w.server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Trace(err)
+		}
+	}()
+
+	log.V(3).Info(
+		"web: admin started.",
+		"address",
+		w.Address)
+}
+
+//
+// Stop the admin server.
+func (w *AdminServer) Stop(ctx context.Context) (err error) {
+	if w.server == nil {
+		return
+	}
+	err = w.server.Shutdown(ctx)
+	if err != nil {
+		_ = w.server.Close()
+		err = liberr.Wrap(err)
+	}
+
+	log.V(3).Info(
+		"web: admin stopped.",
+		"address",
+		w.Address)
+
+	return
+}
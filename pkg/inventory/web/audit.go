@@ -0,0 +1,155 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+//
+// Actor (request) header.
+// Used to identify the actor when the client
+// certificate is not available.
+const ActorHeader = "X-Actor"
+
+//
+// Request audit middleware.
+// Mutating (POST, PUT, PATCH, DELETE) requests are recorded in
+// the audit log of the DB: the actor, route, (SHA-256) digest of
+// the payload and the response status. The actor is set in the
+// request context (see: model.WithActor) so that changes made by
+// handlers are attributed. Requests are not recorded when auditing
+// is not enabled on the DB. See: model.Client.AuditRequest().
+// Example:
+//   auditor := web.Auditor{DB: db}
+//   server.Start(auditor.Middleware())
+type Auditor struct {
+	// DB (audit log).
+	DB model.DB
+	// Determine the actor.
+	// Default: the actor already set in the request context
+	// (Example: token subject), the client certificate (CN),
+	// the ActorHeader, then the remote address.
+	Actor func(ctx *gin.Context) string
+}
+
+//
+// Build the middleware.
+func (r *Auditor) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete:
+		default:
+			ctx.Next()
+			return
+		}
+		digest, err := r.digest(ctx.Request)
+		if err != nil {
+			Fail(ctx, &BadRequest{Err: err})
+			return
+		}
+		actor := r.actor(ctx)
+		ctx.Request = ctx.Request.WithContext(
+			model.WithActor(ctx.Request.Context(), actor))
+		ctx.Next()
+		r.record(ctx, digest)
+	}
+}
+
+//
+// Record the request.
+func (r *Auditor) record(ctx *gin.Context, digest string) {
+	if r.DB == nil {
+		return
+	}
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	err := r.DB.AuditRequest(
+		ctx.Request.Context(),
+		model.RequestAudit{
+			Method: ctx.Request.Method,
+			Route:  route,
+			Path:   ctx.Request.URL.Path,
+			Digest: digest,
+			Status: ctx.Writer.Status(),
+		})
+	if err != nil {
+		if !errors.Is(err, model.AuditNotEnabledErr) {
+			log.Trace(err)
+		}
+		return
+	}
+
+	log.V(3).Info(
+		"audit: request recorded.",
+		"method",
+		ctx.Request.Method,
+		"route",
+		route,
+		"status",
+		ctx.Writer.Status())
+}
+
+//
+// Digest (SHA-256) of the request body.
+// The body is restored so that it may be read by handlers.
+// Returns "" when the request has no body.
+func (r *Auditor) digest(request *http.Request) (digest string, err error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return
+	}
+	b, err := ioutil.ReadAll(request.Body)
+	_ = request.Body.Close()
+	if err != nil {
+		return
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if len(b) == 0 {
+		return
+	}
+	sum := sha256.Sum256(b)
+	digest = hex.EncodeToString(sum[:])
+
+	return
+}
+
+//
+// Determine the actor.
+func (r *Auditor) actor(ctx *gin.Context) (actor string) {
+	if r.Actor != nil {
+		actor = r.Actor(ctx)
+		return
+	}
+	actor = model.ActorOf(ctx.Request.Context())
+	if actor != "" {
+		return
+	}
+	tls := ctx.Request.TLS
+	if tls != nil && len(tls.PeerCertificates) > 0 {
+		actor = tls.PeerCertificates[0].Subject.CommonName
+		if actor != "" {
+			return
+		}
+	}
+	actor = ctx.GetHeader(ActorHeader)
+	if actor != "" {
+		return
+	}
+	actor = ctx.Request.RemoteAddr
+	if host, _, err := net.SplitHostPort(actor); err == nil {
+		actor = host
+	}
+
+	return
+}
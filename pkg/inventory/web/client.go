@@ -3,6 +3,7 @@ package web
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/gorilla/websocket"
 	liberr "github.com/konveyor/controller/pkg/error"
@@ -10,10 +11,13 @@ import (
 	"github.com/konveyor/controller/pkg/logging"
 	"github.com/konveyor/controller/pkg/ref"
 	"io/ioutil"
+	"net"
 	"net/http"
 	liburl "net/url"
 	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,6 +30,38 @@ const (
 	WatchSnapshot = "snapshot"
 )
 
+//
+// Watch transports.
+const (
+	// Websocket.
+	WatchWebSocket = "websocket"
+	// Event-stream (SSE).
+	WatchEventStream = "event-stream"
+)
+
+//
+// Websocket close reasons.
+const (
+	// Last revision prefix.
+	RevisionReason = "revision="
+)
+
+//
+// The server is going away.
+// Reported to the watch event handler.
+type GoingAway struct {
+	// The last revision (event ID) delivered.
+	Revision uint64
+}
+
+//
+// Error description.
+func (e *GoingAway) Error() string {
+	return fmt.Sprintf(
+		"server going away, last revision=%d",
+		e.Revision)
+}
+
 type WatchOptions = libmodel.WatchOptions
 
 //
@@ -106,9 +142,17 @@ type Client struct {
 	Transport http.RoundTripper
 	// Headers.
 	Header http.Header
+	// Watch transport.
+	// Default: websocket with fallback to the event-stream
+	// when the websocket upgrade is rejected.
+	WatchTransport string
 	// Reply.
 	Reply struct {
 		Header http.Header
+		// Problem details (RFC 7807) of the failed request.
+		// Nil when the request succeeded or the reply
+		// is not a problem.
+		Problem *liberr.Problem
 	}
 }
 
@@ -160,6 +204,7 @@ func (r *Client) Get(url string, out interface{}, params ...Param) (status int,
 		return
 	}
 	status = response.StatusCode
+	r.decodeProblem(response, content)
 	if status == http.StatusOK {
 		err = json.Unmarshal(content, out)
 		if err != nil {
@@ -219,6 +264,70 @@ func (r *Client) Post(url string, in interface{}, out interface{}) (status int,
 		return
 	}
 	status = response.StatusCode
+	r.decodeProblem(response, content)
+	if status == http.StatusOK || status == http.StatusCreated {
+		if out == nil {
+			return
+		}
+		err = json.Unmarshal(content, out)
+		if err != nil {
+			err = liberr.Wrap(
+				err,
+				"json unmarshal failed.",
+				"url",
+				url)
+			return
+		}
+	}
+
+	return
+}
+
+//
+// HTTP PUT (method).
+func (r *Client) Put(url string, in interface{}, out interface{}) (status int, err error) {
+	parsedURL, err := liburl.Parse(url)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"URL not valid.",
+			"url",
+			url)
+		return
+	}
+	body, _ := json.Marshal(in)
+	reader := bytes.NewReader(body)
+	request := &http.Request{
+		Header: r.Header,
+		Method: http.MethodPut,
+		Body:   ioutil.NopCloser(reader),
+		URL:    parsedURL,
+	}
+	client := http.Client{Transport: r.Transport}
+	response, err := client.Do(request)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"PUT failed.",
+			"url",
+			url)
+		return
+	}
+	r.Reply.Header = response.Header
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"Read body failed.",
+			"url",
+			url)
+		return
+	}
+	status = response.StatusCode
+	r.decodeProblem(response, content)
 	if status == http.StatusOK {
 		if out == nil {
 			return
@@ -238,16 +347,71 @@ func (r *Client) Post(url string, in interface{}, out interface{}) (status int,
 }
 
 //
-// Watch a resource.
-func (r *Client) Watch(url string, resource interface{}, h EventHandler) (status int, w *Watch, err error) {
-	url = r.patchURL(url)
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 45 * time.Second,
-		Proxy:            http.ProxyFromEnvironment,
+// HTTP DELETE (method).
+func (r *Client) Delete(url string) (status int, err error) {
+	parsedURL, err := liburl.Parse(url)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"URL not valid.",
+			"url",
+			url)
+		return
 	}
-	if ht, cast := r.Transport.(*http.Transport); cast {
-		dialer.TLSClientConfig = ht.TLSClientConfig
+	request := &http.Request{
+		Header: r.Header,
+		Method: http.MethodDelete,
+		URL:    parsedURL,
+	}
+	client := http.Client{Transport: r.Transport}
+	response, err := client.Do(request)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"DELETE failed.",
+			"url",
+			url)
+		return
 	}
+	r.Reply.Header = response.Header
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"Read body failed.",
+			"url",
+			url)
+		return
+	}
+	status = response.StatusCode
+	r.decodeProblem(response, content)
+
+	return
+}
+
+//
+// Decode the problem details (RFC 7807) of the reply.
+// Set (Reply.Problem) when the reply is a problem.
+func (r *Client) decodeProblem(response *http.Response, content []byte) {
+	r.Reply.Problem = nil
+	mediaType := response.Header.Get("Content-Type")
+	if !strings.HasPrefix(mediaType, liberr.ProblemType) {
+		return
+	}
+	problem := &liberr.Problem{}
+	err := json.Unmarshal(content, problem)
+	if err != nil {
+		return
+	}
+	r.Reply.Problem = problem
+}
+
+//
+// Watch a resource.
+func (r *Client) Watch(url string, resource interface{}, h EventHandler) (status int, w *Watch, err error) {
 	options := []string{""}
 	if h.Options().Snapshot {
 		options = []string{WatchSnapshot}
@@ -258,7 +422,55 @@ func (r *Client) Watch(url string, resource interface{}, h EventHandler) (status
 	for k, v := range r.Header {
 		header[k] = v
 	}
-	post := func(w *WatchReader) (pStatus int, pErr error) {
+	reader := &WatchReader{
+		resource: resource,
+		handler:  h,
+	}
+	switch r.WatchTransport {
+	case WatchEventStream:
+		reader.repair = r.streamPost(url, header)
+		status, err = reader.repair(reader)
+	default:
+		reader.repair = r.socketPost(url, header)
+		status, err = reader.repair(reader)
+		if err != nil || r.WatchTransport == WatchWebSocket {
+			break
+		}
+		switch status {
+		case http.StatusBadRequest,
+			http.StatusUpgradeRequired:
+			reader.repair = r.streamPost(url, header)
+			status, err = reader.repair(reader)
+		}
+	}
+	if err != nil || status != http.StatusOK {
+		return
+	}
+	w = &Watch{reader: reader}
+	runtime.SetFinalizer(
+		w,
+		func(w *Watch) {
+			w.End()
+		})
+
+	reader.start()
+
+	return
+}
+
+//
+// Build the function used to open (post) the watch
+// using the websocket transport.
+func (r *Client) socketPost(url string, header http.Header) func(*WatchReader) (int, error) {
+	url = r.patchURL(url)
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 45 * time.Second,
+		Proxy:            http.ProxyFromEnvironment,
+	}
+	if ht, cast := r.Transport.(*http.Transport); cast {
+		dialer.TLSClientConfig = ht.TLSClientConfig
+	}
+	return func(w *WatchReader) (pStatus int, pErr error) {
 		socket, response, pErr := dialer.Dial(url, header)
 		if response != nil {
 			pStatus = response.StatusCode
@@ -279,29 +491,55 @@ func (r *Client) Watch(url string, resource interface{}, h EventHandler) (status
 				url)
 			return
 		} else {
-			w.webSocket = socket
+			w.conn = socket
 		}
 		return
 	}
-	reader := &WatchReader{
-		resource: resource,
-		handler:  h,
-		repair:   post,
-	}
-	status, err = post(reader)
-	if err != nil || status != http.StatusOK {
-		return
-	}
-	w = &Watch{reader: reader}
-	runtime.SetFinalizer(
-		w,
-		func(w *Watch) {
-			w.End()
-		})
+}
 
-	reader.start()
+//
+// Build the function used to open (post) the watch
+// using the event-stream (SSE) transport.
+func (r *Client) streamPost(url string, header http.Header) func(*WatchReader) (int, error) {
+	return func(w *WatchReader) (pStatus int, pErr error) {
+		parsedURL, pErr := liburl.Parse(url)
+		if pErr != nil {
+			pErr = liberr.Wrap(
+				pErr,
+				"URL not valid.",
+				"url",
+				url)
+			return
+		}
+		request := &http.Request{
+			Header: http.Header{},
+			Method: http.MethodGet,
+			URL:    parsedURL,
+		}
+		for k, v := range header {
+			request.Header[k] = v
+		}
+		request.Header.Set("Accept", EventStreamType)
+		client := http.Client{Transport: r.Transport}
+		response, pErr := client.Do(request)
+		if pErr != nil {
+			pErr = liberr.Wrap(
+				pErr,
+				"open event-stream failed.",
+				"url",
+				url)
+			return
+		}
+		pStatus = response.StatusCode
+		if pStatus != http.StatusOK {
+			_ = response.Body.Close()
+			return
+		}
 
-	return
+		w.conn = newEventStream(response)
+
+		return
+	}
 }
 
 //
@@ -326,6 +564,22 @@ func (r *Client) patchURL(in string) (out string) {
 	return
 }
 
+//
+// Watch (client) connection.
+// Satisfied by the websocket and event-stream.
+type watchConn interface {
+	// Read the next event.
+	ReadJSON(interface{}) error
+	// Send an event.
+	WriteJSON(interface{}) error
+	// Close the connection.
+	Close() error
+	// Local address.
+	LocalAddr() net.Addr
+	// Remote address.
+	RemoteAddr() net.Addr
+}
+
 //
 // Watch (event) reader.
 type WatchReader struct {
@@ -333,8 +587,8 @@ type WatchReader struct {
 	id uint64
 	// Repair function.
 	repair func(*WatchReader) (int, error)
-	// Web socket.
-	webSocket *websocket.Conn
+	// Connection.
+	conn watchConn
 	// Web resource.
 	resource interface{}
 	// Event handler.
@@ -354,7 +608,7 @@ func (r *WatchReader) Terminate() {
 		return
 	}
 	r.done = true
-	_ = r.webSocket.Close()
+	_ = r.conn.Close()
 	r.log.V(3).Info("reader terminated.")
 }
 
@@ -371,9 +625,9 @@ func (r *WatchReader) resetLog() {
 	r.log = logging.WithName(
 		"web|watch|reader",
 		"local",
-		r.webSocket.LocalAddr(),
+		r.conn.LocalAddr(),
 		"remote",
-		r.webSocket.RemoteAddr(),
+		r.conn.RemoteAddr(),
 		"resource",
 		ref.ToKind(r.resource),
 		"watch",
@@ -391,7 +645,7 @@ func (r *WatchReader) start() {
 	r.done = false
 	go func() {
 		defer func() {
-			_ = r.webSocket.Close()
+			_ = r.conn.Close()
 			r.started = false
 			r.done = true
 			r.handler.End()
@@ -403,11 +657,14 @@ func (r *WatchReader) start() {
 				Resource: r.clone(r.resource),
 				Updated:  r.clone(r.resource),
 			}
-			err := r.webSocket.ReadJSON(&event)
+			err := r.conn.ReadJSON(&event)
 			if err != nil {
 				if r.done {
 					break
 				}
+				if goingAway := r.goingAway(err); goingAway != nil {
+					err = goingAway
+				}
 				time.Sleep(time.Second * 3)
 				r.handler.Error(&Watch{reader: r}, err)
 				continue
@@ -425,6 +682,10 @@ func (r *WatchReader) start() {
 				r.handler.Parity()
 			case libmodel.Error:
 				r.handler.Error(&Watch{reader: r}, nil)
+			case libmodel.Reset:
+				if h, cast := r.handler.(libmodel.ResetHandler); cast {
+					h.Reset(event.Reason)
+				}
 			case libmodel.End:
 				return
 			case libmodel.Created:
@@ -438,6 +699,30 @@ func (r *WatchReader) start() {
 	}()
 }
 
+//
+// Build a `GoingAway` error when the websocket has been
+// closed by the server with the `going-away` code.
+// Returns: nil when not going away.
+func (r *WatchReader) goingAway(err error) (goingAway *GoingAway) {
+	if goingAway, cast := err.(*GoingAway); cast {
+		return goingAway
+	}
+	closeErr, cast := err.(*websocket.CloseError)
+	if !cast || closeErr.Code != websocket.CloseGoingAway {
+		return
+	}
+	goingAway = &GoingAway{}
+	if strings.HasPrefix(closeErr.Text, RevisionReason) {
+		n, pErr := strconv.ParseUint(
+			strings.TrimPrefix(closeErr.Text, RevisionReason), 10, 64)
+		if pErr == nil {
+			goingAway.Revision = n
+		}
+	}
+
+	return
+}
+
 //
 // Clone resource.
 func (r *WatchReader) clone(in interface{}) (out interface{}) {
@@ -487,7 +772,7 @@ func (r *Watch) Repair() (err error) {
 // End the watch.
 func (r *Watch) End() {
 	r.reader.log.V(3).Info("wtach end requested.")
-	_ = r.reader.webSocket.WriteJSON(
+	_ = r.reader.conn.WriteJSON(
 		Event{
 			Action: libmodel.End,
 		})
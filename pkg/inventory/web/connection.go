@@ -0,0 +1,71 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/container"
+	"net/http"
+	"sort"
+)
+
+//
+// Routes.
+const (
+	ConnectionsRoot = AdminConnections
+)
+
+//
+// Provider connection status handler.
+// Reports the (container maintained) connection status
+// of each collector:
+//   GET /admin/connections       - Connection status of all collectors.
+//   GET /admin/connections/:name - Connection status by collector name.
+// The status includes (provider) errors so the routes are added
+// by the AdminHandler and are not intended for the public server.
+// See: AdminServer.
+type ConnectionHandler struct {
+	// Reference to the container.
+	Container *container.Container
+}
+
+//
+// Add routes.
+func (h *ConnectionHandler) AddRoutes(r *gin.Engine) {
+	r.GET(ConnectionsRoot, h.List)
+	r.GET(ConnectionsRoot+"/:"+NameParam, h.Get)
+}
+
+//
+// List connection status (ordered by name).
+func (h *ConnectionHandler) List(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.connections())
+}
+
+//
+// Get connection status by collector name.
+func (h *ConnectionHandler) Get(ctx *gin.Context) {
+	name := ctx.Param(NameParam)
+	for _, status := range h.connections() {
+		if status.Name == name {
+			ctx.JSON(http.StatusOK, status)
+			return
+		}
+	}
+
+	Fail(ctx, liberr.Wrap(NotFoundErr, "collector", name))
+}
+
+//
+// Connection status (ordered by name).
+func (h *ConnectionHandler) connections() (list []container.ConnectionStatus) {
+	list = []container.ConnectionStatus{}
+	if h.Container == nil {
+		return
+	}
+	list = h.Container.Connections()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return
+}
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"strings"
+)
+
+//
+// Params.
+const (
+	// Filter (query) param.
+	// Format: <field>:<op>:<value>
+	FilterParam = "filter"
+	// Separates the values of the `in`
+	// and `between` operators.
+	FilterValueSep = "|"
+)
+
+//
+// Filtered handler.
+// The (repeatable) `filter` parameter is parsed into a
+// predicate. Multiple filters are AND-ed.
+// Operators:
+//   eq, neq, gt, gte, lt, lte, like, glob, in, between.
+// The `in` values and the `between` (low, high) bounds
+// are separated by `|`. The values are converted to the
+// field type when the predicate is built.
+// Example:
+//   ?filter=name:like:web%&filter=age:between:18|65
+type Filtered struct {
+	// The predicate built using the `filter` parameters.
+	// Nil when no filters are passed.
+	Filter model.Predicate
+}
+
+//
+// Prepare the handler to fulfil the request.
+// Set the `Filter` field using passed parameters.
+// Returns the HTTP status. See: PrepareErr().
+func (h *Filtered) Prepare(ctx *gin.Context) int {
+	return StatusOf(h.PrepareErr(ctx))
+}
+
+//
+// Prepare the handler to fulfil the request.
+// Set the `Filter` field using passed parameters.
+// Returns BadRequestErr when the parameters are not valid.
+func (h *Filtered) PrepareErr(ctx *gin.Context) (err error) {
+	h.Filter = nil
+	predicates := []model.Predicate{}
+	for _, filter := range ctx.QueryArray(FilterParam) {
+		p, pErr := h.parse(filter)
+		if pErr != nil {
+			err = liberr.Wrap(
+				&BadRequest{Err: pErr},
+				FilterParam,
+				filter)
+			return
+		}
+		predicates = append(predicates, p)
+	}
+	switch len(predicates) {
+	case 0:
+	case 1:
+		h.Filter = predicates[0]
+	default:
+		h.Filter = model.And(predicates...)
+	}
+
+	return
+}
+
+//
+// Parse a filter.
+func (h *Filtered) parse(filter string) (p model.Predicate, err error) {
+	part := strings.SplitN(filter, ":", 3)
+	if len(part) != 3 || part[0] == "" {
+		err = errors.New("filter must be <field>:<op>:<value>")
+		return
+	}
+	field, op, value := part[0], strings.ToLower(part[1]), part[2]
+	switch op {
+	case "eq":
+		p = model.Eq(field, value)
+	case "neq":
+		p = model.Neq(field, value)
+	case "gt":
+		p = model.Gt(field, value)
+	case "gte":
+		p = model.Gte(field, value)
+	case "lt":
+		p = model.Lt(field, value)
+	case "lte":
+		p = model.Lte(field, value)
+	case "like":
+		p = model.Like(field, value)
+	case "glob":
+		p = model.Glob(field, value)
+	case "in":
+		values := []interface{}{}
+		for _, v := range strings.Split(value, FilterValueSep) {
+			values = append(values, v)
+		}
+		p = model.In(field, values...)
+	case "between":
+		bound := strings.Split(value, FilterValueSep)
+		if len(bound) != 2 {
+			err = errors.New("between value must be <low>|<high>")
+			return
+		}
+		p = model.Between(field, bound[0], bound[1])
+	default:
+		err = errors.New("filter op not supported")
+	}
+
+	return
+}
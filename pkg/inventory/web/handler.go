@@ -1,6 +1,8 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-logr/logr"
@@ -10,8 +12,12 @@ import (
 	"github.com/konveyor/controller/pkg/inventory/model"
 	"github.com/konveyor/controller/pkg/logging"
 	"github.com/konveyor/controller/pkg/ref"
+	"github.com/konveyor/controller/pkg/tracing"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -32,18 +38,29 @@ type Paged struct {
 //
 // Prepare the handler to fulfil the request.
 // Set the `page` field using passed parameters.
+// Returns the HTTP status. See: PrepareErr().
 func (h *Paged) Prepare(ctx *gin.Context) int {
-	status := h.setPage(ctx)
-	if status != http.StatusOK {
-		return status
-	}
+	return StatusOf(h.PrepareErr(ctx))
+}
 
-	return http.StatusOK
+//
+// Prepare the handler to fulfil the request.
+// Set the `page` field using passed parameters.
+// Returns BadRequestErr when the parameters are not valid.
+// Example:
+//   err := h.PrepareErr(ctx)
+//   if err != nil {
+//       web.Fail(ctx, err)
+//       return
+//   }
+func (h *Paged) PrepareErr(ctx *gin.Context) (err error) {
+	err = h.setPage(ctx)
+	return
 }
 
 //
 // Set the `page` field.
-func (h *Paged) setPage(ctx *gin.Context) int {
+func (h *Paged) setPage(ctx *gin.Context) (err error) {
 	q := ctx.Request.URL.Query()
 	page := model.Page{
 		Limit:  int(^uint(0) >> 1),
@@ -51,46 +68,55 @@ func (h *Paged) setPage(ctx *gin.Context) int {
 	}
 	pLimit := q.Get("limit")
 	if len(pLimit) != 0 {
-		nLimit, err := strconv.Atoi(pLimit)
-		if err != nil || nLimit < 0 {
-			return http.StatusBadRequest
+		nLimit, nErr := strconv.Atoi(pLimit)
+		if nErr != nil || nLimit < 0 {
+			err = liberr.Wrap(
+				&BadRequest{Err: errors.New("limit must be an integer >= 0")},
+				"limit",
+				pLimit)
+			return
 		}
 		page.Limit = nLimit
 	}
 	pOffset := q.Get("offset")
 	if len(pOffset) != 0 {
-		nOffset, err := strconv.Atoi(pOffset)
-		if err != nil || nOffset < 0 {
-			return http.StatusBadRequest
+		nOffset, nErr := strconv.Atoi(pOffset)
+		if nErr != nil || nOffset < 0 {
+			err = liberr.Wrap(
+				&BadRequest{Err: errors.New("offset must be an integer >= 0")},
+				"offset",
+				pOffset)
+			return
 		}
 		page.Offset = nOffset
 	}
 
 	h.Page = page
-	return http.StatusOK
+	return
 }
 
 //
 // Parity (not-partial) request handler.
 type Parity struct {
+	// Report 503 (service unavailable) rather
+	// than 206 (partial content) without parity.
+	Strict bool
 }
 
 //
 // Ensure collector has achieved parity.
+// Waits up to the specified duration.
 func (c *Parity) EnsureParity(r container.Collector, w time.Duration) int {
-	wait := w
-	poll := time.Microsecond * 100
-	for {
-		mark := time.Now()
-		if r.HasParity() {
-			return http.StatusOK
-		}
-		if wait > 0 {
-			time.Sleep(poll)
-			wait -= time.Since(mark)
-		} else {
-			break
-		}
+	if !r.HasParity() && w > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), w)
+		defer cancel()
+		_ = container.WaitForParity(ctx, r)
+	}
+	if r.HasParity() {
+		return http.StatusOK
+	}
+	if c.Strict {
+		return http.StatusServiceUnavailable
 	}
 
 	return http.StatusPartialContent
@@ -105,10 +131,16 @@ type ResourceBuilder func(model.Model) interface{}
 type Event struct {
 	// ID
 	ID uint64
+	// Sequence number (per-watch).
+	Seq uint64
 	// Labels.
 	Labels []string
 	// Action.
 	Action uint8
+	// Reset reason.
+	Reason string `json:",omitempty"`
+	// ID of the reconcile that caused the event.
+	ReconcileID string `json:",omitempty"`
 	// Affected Resource.
 	Resource interface{}
 	// Updated resource.
@@ -118,7 +150,21 @@ type Event struct {
 //
 // String representation.
 func (r *Event) String() string {
-	action := "unknown"
+	kind := ""
+	if r.Resource != nil {
+		kind = ref.ToKind(r.Resource)
+	}
+	return fmt.Sprintf(
+		"event-%.4d: %s kind=%s",
+		r.ID,
+		r.action(),
+		kind)
+}
+
+//
+// Action (name).
+func (r *Event) action() (action string) {
+	action = "unknown"
 	switch r.Action {
 	case model.Started:
 		action = "started"
@@ -128,6 +174,8 @@ func (r *Event) String() string {
 		action = "error"
 	case model.End:
 		action = "end"
+	case model.Reset:
+		action = "reset"
 	case model.Created:
 		action = "created"
 	case model.Updated:
@@ -135,15 +183,72 @@ func (r *Event) String() string {
 	case model.Deleted:
 		action = "deleted"
 	}
-	kind := ""
-	if r.Resource != nil {
-		kind = ref.ToKind(r.Resource)
+
+	return
+}
+
+//
+// Watch (writer) transport.
+type watchTransport interface {
+	// Write an event.
+	write(event Event) error
+	// Read an event sent by the peer.
+	// Blocks until an event is received or
+	// the connection is closed.
+	read(event *Event) error
+	// Notify the peer the server is going away.
+	goingAway(reason string)
+	// Close the connection.
+	close()
+	// Remote address.
+	remote() string
+}
+
+//
+// Websocket transport.
+type socketTransport struct {
+	// Negotiated web socket.
+	conn *websocket.Conn
+	// Write deadline (duration).
+	timeout time.Duration
+}
+
+//
+// Write an event.
+// The write deadline is set when specified.
+func (r *socketTransport) write(event Event) error {
+	if r.timeout > 0 {
+		_ = r.conn.SetWriteDeadline(time.Now().Add(r.timeout))
 	}
-	return fmt.Sprintf(
-		"event-%.4d: %s kind=%s",
-		r.ID,
-		action,
-		kind)
+	return r.conn.WriteJSON(event)
+}
+
+//
+// Read an event.
+func (r *socketTransport) read(event *Event) error {
+	return r.conn.ReadJSON(event)
+}
+
+//
+// Send the `going-away` close frame.
+func (r *socketTransport) goingAway(reason string) {
+	_ = r.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseGoingAway, reason),
+		time.Now().Add(time.Second))
+	_ = r.conn.Close()
+}
+
+//
+// Close the socket.
+func (r *socketTransport) close() {
+	_ = r.conn.Close()
+}
+
+//
+// Remote address.
+func (r *socketTransport) remote() string {
+	return r.conn.RemoteAddr().String()
 }
 
 //
@@ -154,14 +259,22 @@ func (r *Event) String() string {
 type WatchWriter struct {
 	// Watch options.
 	options model.WatchOptions
-	// Negotiated web socket.
-	webSocket *websocket.Conn
+	// Negotiated transport.
+	transport watchTransport
 	// Resource.
 	builder ResourceBuilder
 	// Logger.
 	log logr.Logger
+	// The associated watch.
+	watch *model.Watch
+	// The http server.
+	server *http.Server
+	// Last delivered revision (event ID).
+	revision uint64
 	// Done.
 	done bool
+	// Protect done and revision.
+	mutex sync.Mutex
 }
 
 //
@@ -175,6 +288,8 @@ func (r *WatchWriter) Options() model.WatchOptions {
 // Detect connection closed by peer or broken
 // and end the watch.
 func (r *WatchWriter) Start(watch *model.Watch) {
+	r.watch = watch
+	writers.add(r)
 	go func() {
 		time.Sleep(time.Second)
 		defer func() {
@@ -182,8 +297,8 @@ func (r *WatchWriter) Start(watch *model.Watch) {
 		}()
 		for {
 			event := Event{}
-			err := r.webSocket.ReadJSON(&event)
-			if r.done {
+			err := r.transport.read(&event)
+			if r.isDone() {
 				return
 			}
 			if err != nil {
@@ -262,28 +377,79 @@ func (r *WatchWriter) Error(err error) {
 	})
 }
 
+//
+// Events have been discarded or the DB rebuilt.
+// The peer is instructed to re-list.
+func (r *WatchWriter) Reset(reason string) {
+	r.log.V(3).Info(
+		"event: reset.",
+		"reason",
+		reason)
+	r.write(
+		context.Background(),
+		Event{
+			Action: model.Reset,
+			Reason: reason,
+		})
+}
+
 //
 // An event watch has ended.
 func (r *WatchWriter) End() {
 	r.log.V(3).Info("event: ended.")
+	writers.delete(r)
 	r.send(model.Event{
 		Action: model.End,
 	})
+	r.mutex.Lock()
 	r.done = true
+	r.mutex.Unlock()
 	time.Sleep(50 * time.Millisecond)
-	_ = r.webSocket.Close()
+	r.transport.close()
 }
 
 //
-// Write event to the socket.
-func (r *WatchWriter) send(e model.Event) {
+// The server is going away.
+// Notify the peer with the last delivered revision
+// and end the watch.
+func (r *WatchWriter) goingAway() {
+	r.mutex.Lock()
 	if r.done {
+		r.mutex.Unlock()
 		return
 	}
+	r.done = true
+	revision := r.revision
+	r.mutex.Unlock()
+	reason := fmt.Sprintf("%s%d", RevisionReason, revision)
+	r.transport.goingAway(reason)
+	if r.watch != nil {
+		r.watch.End()
+	}
+
+	r.log.V(3).Info(
+		"going away.",
+		"revision",
+		revision)
+}
+
+//
+// The writer is done.
+func (r *WatchWriter) isDone() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.done
+}
+
+//
+// Send (build and write) the event.
+func (r *WatchWriter) send(e model.Event) {
 	event := Event{
-		ID:     e.ID,
-		Labels: e.Labels,
-		Action: e.Action,
+		ID:          e.ID,
+		Seq:         e.Seq,
+		Labels:      e.Labels,
+		Action:      e.Action,
+		ReconcileID: e.ReconcileID(),
 	}
 	if e.Model != nil {
 		event.Resource = r.builder(e.Model)
@@ -291,9 +457,35 @@ func (r *WatchWriter) send(e model.Event) {
 	if e.Updated != nil {
 		event.Updated = r.builder(e.Updated)
 	}
-	err := r.webSocket.WriteJSON(event)
+	r.write(e.Context(), event)
+	switch e.Action {
+	case model.Created,
+		model.Updated,
+		model.Deleted:
+		r.mutex.Lock()
+		if e.ID > r.revision {
+			r.revision = e.ID
+		}
+		r.mutex.Unlock()
+	}
+}
+
+//
+// Write the event to the transport.
+func (r *WatchWriter) write(ctx context.Context, event Event) {
+	if r.isDone() {
+		return
+	}
+	_, span := tracing.Start(
+		ctx,
+		"web.watch.send",
+		"event",
+		event.ID)
+	defer span.End()
+	err := r.transport.write(event)
 	if err != nil {
-		r.log.V(4).Error(err, "websocket send failed.")
+		span.Error(err)
+		r.log.V(4).Error(err, "send failed.")
 	}
 
 	r.log.V(5).Info(
@@ -302,22 +494,107 @@ func (r *WatchWriter) send(e model.Event) {
 		event)
 }
 
+//
+// Live watch writers.
+var writers = WriterList{
+	content: map[*WatchWriter]bool{},
+}
+
+//
+// List of (live) watch writers.
+type WriterList struct {
+	// Writers.
+	content map[*WatchWriter]bool
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Add a writer.
+func (r *WriterList) add(writer *WatchWriter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.content[writer] = true
+}
+
+//
+// Delete a writer.
+func (r *WriterList) delete(writer *WatchWriter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.content, writer)
+}
+
+//
+// Number of writers.
+func (r *WriterList) len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.content)
+}
+
+//
+// Notify writers associated with the server
+// that the server is going away.
+// Returns: the number of writers notified.
+func (r *WriterList) goingAway(server *http.Server) (n int) {
+	r.mutex.Lock()
+	matched := []*WatchWriter{}
+	for writer := range r.content {
+		if writer.server == server {
+			matched = append(matched, writer)
+			delete(r.content, writer)
+		}
+	}
+	r.mutex.Unlock()
+	for _, writer := range matched {
+		writer.goingAway()
+		n++
+	}
+
+	return
+}
+
 //
 // Watched (handler).
 type Watched struct {
 	// Watch requested.
 	WatchRequest bool
+	// (Optional) fields redacted (cleared) before each
+	// model is sent to the peer. Used to strip fields not to
+	// be exposed to (external) clients. Validated when the
+	// watch is created. Example: []string{"Password"}.
+	Redacted []string
+	// (Optional) model transform applied before each
+	// model is sent to the peer (after redacted).
+	Transform func(model.Model) model.Model
 	// Watch options.
 	options model.WatchOptions
+	// Event-stream (SSE) transport requested.
+	eventStream bool
 }
 
 //
 // Prepare the handler to fulfil the request.
 // Set the `WatchRequest` and `snapshot` fields based on passed headers.
 // The header value is a list of options.
+// The event-stream (SSE) transport is negotiated using
+// the `Accept` header.  Default: websocket.
+// Returns the HTTP status. See: PrepareErr().
 func (h *Watched) Prepare(ctx *gin.Context) int {
+	return StatusOf(h.PrepareErr(ctx))
+}
+
+//
+// Prepare the handler to fulfil the request.
+// Same as Prepare() but returns the (typed) error
+// to be reported using Fail().
+func (h *Watched) PrepareErr(ctx *gin.Context) (err error) {
 	header, found := ctx.Request.Header[WatchHeader]
 	h.WatchRequest = found
+	h.eventStream = strings.Contains(
+		ctx.GetHeader("Accept"),
+		EventStreamType)
 	for _, option := range header {
 		switch option {
 		case WatchSnapshot:
@@ -325,47 +602,74 @@ func (h *Watched) Prepare(ctx *gin.Context) int {
 		}
 	}
 
-	return http.StatusOK
+	return
 }
 
 //
 // Watch model.
+// The websocket transport is used unless the event-stream
+// was negotiated. When the event-stream is used, this
+// method blocks until the watch has ended.
 func (r *Watched) Watch(
 	ctx *gin.Context,
 	db model.DB,
 	m model.Model,
 	rb ResourceBuilder) (err error) {
 	//
-	upGrader := websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-	}
-	socket, err := upGrader.Upgrade(ctx.Writer, ctx.Request, nil)
-	if err != nil {
-		err = liberr.Wrap(
-			err,
-			"websocket upgrade failed.",
-			"url",
-			ctx.Request.URL)
-		return
+	var transport watchTransport
+	var stream *streamTransport
+	if r.eventStream {
+		stream, err = newStreamTransport(ctx)
+		if err != nil {
+			return
+		}
+		transport = stream
+	} else {
+		upGrader := websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{WatchProtocol},
+		}
+		socket, uErr := upGrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if uErr != nil {
+			err = liberr.Wrap(
+				uErr,
+				"websocket upgrade failed.",
+				"url",
+				ctx.Request.URL)
+			return
+		}
+		transport = &socketTransport{
+			conn:    socket,
+			timeout: watchWriteTimeout(ctx),
+		}
 	}
 	name := "web|watch|writer"
+	server, _ := ctx.Request.Context().Value(http.ServerContextKey).(*http.Server)
+	options := r.options
+	if len(r.Redacted) > 0 {
+		options.Redacted = r.Redacted
+	}
+	if r.Transform != nil {
+		options.Transform = r.Transform
+	}
 	writer := &WatchWriter{
-		options:   r.options,
-		webSocket: socket,
+		options:   options,
+		transport: transport,
+		server:    server,
 		builder:   rb,
 		log: logging.WithName(name).WithValues(
 			"peer",
-			socket.RemoteAddr()),
+			transport.remote()),
 	}
 	watch, err := db.Watch(m, writer)
 	if err != nil {
-		_ = socket.Close()
+		transport.close()
 		return
 	}
 	writer.log = logging.WithName(name).WithValues(
 		"peer",
-		socket.RemoteAddr(),
+		transport.remote(),
 		"watch",
 		watch.String())
 
@@ -378,6 +682,106 @@ func (r *Watched) Watch(
 		"watch",
 		watch.String())
 
+	if stream != nil {
+		stream.wait()
+	}
+
+	return
+}
+
+//
+// Handler that serves model kinds.
+// The models are reported by the schema handler.
+type ModelHandler interface {
+	RequestHandler
+	// Models (kinds) served by the handler.
+	Models() []model.Model
+}
+
+//
+// Kind (schema) description.
+type Kind struct {
+	// Kind name.
+	Name string `json:"kind"`
+	// Routes serving the kind.
+	Routes []string `json:"routes"`
+	// Fields (columns).
+	Fields []KindField `json:"fields"`
+}
+
+//
+// Kind field (schema) description.
+type KindField struct {
+	// Field name.
+	Name string `json:"name"`
+	// Field type.
+	Type string `json:"type"`
+	// Field is the primary key.
+	Pk bool `json:"pk,omitempty"`
+	// Field is a natural key.
+	Key bool `json:"key,omitempty"`
+	// Field may be referenced in predicates.
+	Filterable bool `json:"filterable"`
+}
+
+//
+// Build the kind description using the model
+// definition (reflection).
+func (r *Kind) With(m model.Model, routes []string) (err error) {
+	md, err := model.Inspect(m)
+	if err != nil {
+		return
+	}
+	r.Name = md.Kind
+	r.Routes = routes
+	r.Fields = []KindField{}
+	for _, f := range md.Fields {
+		r.Fields = append(
+			r.Fields,
+			KindField{
+				Name:       f.Name,
+				Type:       r.fieldType(f),
+				Pk:         f.Pk(),
+				Key:        f.Key(),
+				Filterable: !f.Encoded(),
+			})
+	}
+
+	return
+}
+
+//
+// Field type.
+func (r *Kind) fieldType(f *model.Field) (t string) {
+	kind := f.Value.Kind()
+	if kind == reflect.Ptr {
+		kind = f.Value.Type().Elem().Kind()
+	}
+	switch kind {
+	case reflect.String:
+		t = "string"
+	case reflect.Bool:
+		t = "boolean"
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64:
+		t = "integer"
+	case reflect.Float32,
+		reflect.Float64:
+		t = "number"
+	case reflect.Slice:
+		t = "array"
+	default:
+		t = "object"
+	}
+
 	return
 }
 
@@ -390,6 +794,8 @@ type SchemaHandler struct {
 	Version string
 	// Schema release.
 	Release int
+	// Served kinds.
+	kinds []Kind
 }
 
 //
@@ -406,15 +812,20 @@ func (h *SchemaHandler) List(ctx *gin.Context) {
 		Version string   `json:"version,omitempty"`
 		Release int      `json:"release,omitempty"`
 		Paths   []string `json:"paths"`
+		Kinds   []Kind   `json:"kinds"`
 	}
 	schema := Schema{
 		Version: h.Version,
 		Release: h.Release,
 		Paths:   []string{},
+		Kinds:   h.kinds,
 	}
 	for _, rte := range h.router.Routes() {
 		schema.Paths = append(schema.Paths, rte.Path)
 	}
+	if schema.Kinds == nil {
+		schema.Kinds = []Kind{}
+	}
 
 	ctx.JSON(http.StatusOK, schema)
 }
@@ -422,5 +833,5 @@ func (h *SchemaHandler) List(ctx *gin.Context) {
 //
 // Not supported.
 func (h SchemaHandler) Get(ctx *gin.Context) {
-	ctx.Status(http.StatusMethodNotAllowed)
+	Fail(ctx, MethodNotAllowedErr)
 }
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"errors"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/onsi/gomega"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	// valid.
+	ctx, _ := testContext("")
+	ctx.Request.URL.RawQuery = "limit=10&offset=2"
+	h := &Paged{}
+	g.Expect(h.Prepare(ctx)).To(gomega.Equal(http.StatusOK))
+	g.Expect(h.Page.Limit).To(gomega.Equal(10))
+	g.Expect(h.Page.Offset).To(gomega.Equal(2))
+	// not valid.
+	ctx.Request.URL.RawQuery = "limit=-1"
+	g.Expect(h.Prepare(ctx)).To(gomega.Equal(http.StatusBadRequest))
+	err := h.PrepareErr(ctx)
+	g.Expect(errors.Is(err, BadRequestErr)).To(gomega.BeTrue())
+	// watched.
+	ctx.Request.Header.Set(WatchHeader, WatchSnapshot)
+	w := &Watched{}
+	g.Expect(w.Prepare(ctx)).To(gomega.Equal(http.StatusOK))
+	g.Expect(w.WatchRequest).To(gomega.BeTrue())
+	// tenanted (owner not passed).
+	tenanted := &Tenanted{}
+	g.Expect(tenanted.Prepare(ctx)).To(gomega.Equal(http.StatusNotFound))
+}
+
+func TestKindFieldType(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	size := uint64(0)
+	object := struct {
+		ID     int
+		Name   string
+		Count  uint32
+		Size   *uint64
+		Ratio  float64
+		Weight float32
+		Ready  bool
+		Tags   []string
+		Labels map[string]int
+	}{
+		Size: &size,
+	}
+	types := map[string]string{}
+	kind := Kind{}
+	mv := reflect.ValueOf(&object).Elem()
+	for i := 0; i < mv.NumField(); i++ {
+		fv := mv.Field(i)
+		f := &model.Field{
+			Name:  mv.Type().Field(i).Name,
+			Value: &fv,
+		}
+		types[f.Name] = kind.fieldType(f)
+	}
+	g.Expect(types).To(gomega.Equal(map[string]string{
+		"ID":     "integer",
+		"Name":   "string",
+		"Count":  "integer",
+		"Size":   "integer",
+		"Ratio":  "number",
+		"Weight": "number",
+		"Ready":  "boolean",
+		"Tags":   "array",
+		"Labels": "object",
+	}))
+}
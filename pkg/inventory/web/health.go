@@ -0,0 +1,145 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/container"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"net/http"
+)
+
+//
+// Routes.
+const (
+	HealthRoot = "/healthz"
+	ReadyRoot  = "/readyz"
+)
+
+//
+// Health (probe) handler.
+// Provides kubernetes liveness and readiness probes:
+//   /healthz - Each DB can be queried.
+//   /readyz  - Healthy, each collector has parity and the
+//              journal backlog is within the threshold.
+// Stalled watches (slow clients) are reported but do not
+// fail either probe. See: AdminHandler.Watches(). A failed
+// probe is reported as problem details (503) with the
+// report included. See: HealthProblem.
+type HealthHandler struct {
+	// Reference to the container.
+	Container *container.Container
+	// Maximum (per-DB) journal backlog for readiness.
+	// Default: no threshold.
+	MaxBacklog int
+}
+
+//
+// Collector health report.
+type CollectorHealth struct {
+	// Collector name.
+	Name string `json:"name"`
+	// Collector has parity.
+	Parity bool `json:"parity"`
+	// DB health.
+	DB model.Health `json:"db"`
+}
+
+//
+// Health report.
+type HealthReport struct {
+	// Healthy (liveness).
+	Healthy bool `json:"healthy"`
+	// Ready (readiness).
+	Ready bool `json:"ready"`
+	// Number of stalled watches.
+	Stalled int `json:"stalled"`
+	// Collectors.
+	Collectors []CollectorHealth `json:"collectors"`
+}
+
+//
+// Failed (health) probe.
+// Problem details (RFC 7807) extended with the report.
+type HealthProblem struct {
+	liberr.Problem
+	// Health report.
+	Report HealthReport `json:"report"`
+}
+
+//
+// Add routes.
+func (h *HealthHandler) AddRoutes(r *gin.Engine) {
+	r.GET(HealthRoot, h.Health)
+	r.GET(ReadyRoot, h.Ready)
+}
+
+//
+// Liveness probe.
+func (h *HealthHandler) Health(ctx *gin.Context) {
+	report := h.report()
+	if report.Healthy {
+		ctx.JSON(http.StatusOK, report)
+	} else {
+		h.fail(ctx, report)
+	}
+}
+
+//
+// Readiness probe.
+func (h *HealthHandler) Ready(ctx *gin.Context) {
+	report := h.report()
+	if report.Ready {
+		ctx.JSON(http.StatusOK, report)
+	} else {
+		h.fail(ctx, report)
+	}
+}
+
+//
+// Fail the probe.
+// Not logged (as server errors are by Fail) since probes
+// are expected to fail while starting.
+func (h *HealthHandler) fail(ctx *gin.Context, report HealthReport) {
+	problem := HealthProblem{
+		Problem: liberr.ToProblem(UnavailableErr, ctx.Request.URL.Path),
+		Report:  report,
+	}
+	ctx.Header("Content-Type", liberr.ProblemType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
+
+//
+// Build the report.
+func (h *HealthHandler) report() (report HealthReport) {
+	report.Healthy = true
+	report.Ready = true
+	report.Collectors = []CollectorHealth{}
+	if h.Container == nil {
+		return
+	}
+	for _, collector := range h.Container.List() {
+		ch := CollectorHealth{
+			Name:   collector.Name(),
+			Parity: collector.HasParity(),
+		}
+		if db := collector.DB(); db != nil {
+			ch.DB = model.HealthOf(db)
+		}
+		if !ch.DB.Open {
+			report.Healthy = false
+		}
+		report.Stalled += ch.DB.Stalled
+		if !ch.Parity {
+			report.Ready = false
+		}
+		if h.MaxBacklog > 0 && ch.DB.Backlog > h.MaxBacklog {
+			report.Ready = false
+		}
+		report.Collectors = append(report.Collectors, ch)
+	}
+	if !report.Healthy {
+		report.Ready = false
+	}
+
+	return
+}
@@ -0,0 +1,188 @@
+package web
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//
+// Default request limits.
+var (
+	// Request timeout.
+	// Watch requests are not limited.
+	RequestTimeout = time.Minute
+	// Max body size of write (POST, PUT, PATCH) requests.
+	MaxBodySize int64 = 8 * 1024 * 1024
+	// Write deadline for each event written to a watch
+	// (slow) client. The watch is ended when exceeded.
+	WatchWriteTimeout = time.Second * 30
+)
+
+//
+// Context keys.
+const (
+	// Watch write timeout (gin context).
+	watchWriteKey = "web.watch.write"
+)
+
+//
+// Connection (request context) key.
+type connKey struct{}
+
+//
+// Request limits.
+// Zero values = default (see: RequestTimeout, MaxBodySize
+// and WatchWriteTimeout). Negative values = not limited.
+type Limits struct {
+	// Request timeout.
+	// The request context deadline is set and the (streamed)
+	// list is truncated when exceeded.
+	Timeout time.Duration
+	// Max body size of write requests.
+	// Requests with larger bodies are rejected (413).
+	MaxBody int64
+	// Watch (event) write deadline.
+	WatchWrite time.Duration
+}
+
+//
+// Request limits middleware.
+// Installed by the WebServer (see: WebServer.Limits).
+func (l *Limits) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(watchWriteKey, l.watchWrite())
+		if !l.body(ctx) {
+			return
+		}
+		l.timeout(ctx)
+	}
+}
+
+//
+// Limit the request body.
+// Returns false when the request has been rejected.
+func (l *Limits) body(ctx *gin.Context) bool {
+	max := l.MaxBody
+	if max == 0 {
+		max = MaxBodySize
+	}
+	if max < 0 {
+		return true
+	}
+	switch ctx.Request.Method {
+	case http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch:
+	default:
+		return true
+	}
+	if ctx.Request.ContentLength > max {
+		log.V(3).Info(
+			"limits: request body too large.",
+			"url",
+			ctx.Request.URL,
+			"size",
+			ctx.Request.ContentLength,
+			"max",
+			max)
+		Fail(
+			ctx,
+			liberr.Wrap(
+				TooLargeErr,
+				"size",
+				ctx.Request.ContentLength,
+				"max",
+				max))
+		return false
+	}
+	if ctx.Request.Body != nil {
+		ctx.Request.Body = http.MaxBytesReader(
+			ctx.Writer,
+			ctx.Request.Body,
+			max)
+	}
+
+	return true
+}
+
+//
+// Limit the request duration.
+// Watch requests are not limited.
+func (l *Limits) timeout(ctx *gin.Context) {
+	timeout := l.Timeout
+	if timeout == 0 {
+		timeout = RequestTimeout
+	}
+	if timeout < 0 || watchRequest(ctx) {
+		ctx.Next()
+		return
+	}
+	reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+	defer cancel()
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+	ctx.Next()
+	if reqCtx.Err() == context.DeadlineExceeded {
+		log.V(3).Info(
+			"limits: request timeout.",
+			"url",
+			ctx.Request.URL,
+			"timeout",
+			timeout)
+		if !ctx.Writer.Written() {
+			Fail(ctx, liberr.Wrap(TimeoutErr, "timeout", timeout.String()))
+		}
+	}
+}
+
+//
+// The watch write deadline.
+func (l *Limits) watchWrite() (d time.Duration) {
+	d = l.WatchWrite
+	if d == 0 {
+		d = WatchWriteTimeout
+	}
+
+	return
+}
+
+//
+// The request is a watch.
+func watchRequest(ctx *gin.Context) bool {
+	if _, found := ctx.Request.Header[WatchHeader]; found {
+		return true
+	}
+
+	return strings.Contains(ctx.GetHeader("Accept"), EventStreamType) ||
+		strings.EqualFold(ctx.GetHeader("Upgrade"), "websocket")
+}
+
+//
+// Get the watch write deadline for the request.
+func watchWriteTimeout(ctx *gin.Context) (d time.Duration) {
+	d = WatchWriteTimeout
+	if v, found := ctx.Get(watchWriteKey); found {
+		d = v.(time.Duration)
+	}
+
+	return
+}
+
+//
+// Store the connection in the (connection) context.
+// Used as the http.Server ConnContext so that write
+// deadlines may be set on watch connections.
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+//
+// Get the connection for the request.
+func connOf(request *http.Request) (conn net.Conn) {
+	conn, _ = request.Context().Value(connKey{}).(net.Conn)
+	return
+}
@@ -0,0 +1,178 @@
+package web
+
+import (
+	"bufio"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	fb "github.com/konveyor/controller/pkg/filebacked"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"net/http"
+)
+
+//
+// Streamed list flush thresholds.
+var (
+	// Flush after number of rows.
+	ListFlushRows = 100
+	// Flush after number of bytes.
+	ListFlushBytes = 1 << 16
+)
+
+//
+// Streamed (list) handler.
+// The list is fetched into a (file-backed) list rather than
+// materialized in memory and written incrementally. The DB
+// (reader) session is released before writing so that a slow
+// client does not hold it. Rendered as JSON, YAML or protobuf
+// as negotiated using the `Accept` header. See: Negotiate().
+type Streamed struct {
+	// Flush after number of rows.
+	// Default: ListFlushRows.
+	FlushRows int
+	// Flush after number of bytes.
+	// Default: ListFlushBytes.
+	FlushBytes int
+}
+
+//
+// Stream the list of models.
+// Each model is written as returned by the builder.
+// The status (200) is written with the first row so
+// that errors before the first row may be reported.
+// Errors after the first row truncate the response.
+// The list is truncated when the request context is
+// done (see: Limits).
+func (h *Streamed) Stream(
+	ctx *gin.Context,
+	db model.DB,
+	m model.Model,
+	options model.ListOptions,
+	rb ResourceBuilder) (err error) {
+	//
+	writer := &listWriter{
+		ctx:        ctx,
+		encoder:    encoderFor(Negotiate(ctx)),
+		flushRows:  h.FlushRows,
+		flushBytes: h.FlushBytes,
+	}
+	itr, err := db.FindContext(ctx.Request.Context(), m, options)
+	if err != nil {
+		return
+	}
+	defer itr.Close()
+	for {
+		object, hasNext := itr.Next()
+		if !hasNext {
+			err = fb.ErrOf(itr)
+			break
+		}
+		err = ctx.Request.Context().Err()
+		if err != nil {
+			err = liberr.Wrap(err)
+			break
+		}
+		err = writer.write(rb(object.(model.Model)))
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		if writer.started {
+			log.Trace(err)
+		}
+		return
+	}
+	err = writer.end()
+	if err != nil {
+		log.Trace(err)
+	}
+
+	return
+}
+
+//
+// Streamed list writer.
+type listWriter struct {
+	// Request context.
+	ctx *gin.Context
+	// Encoder.
+	encoder encoder
+	// Buffered writer.
+	buffer *bufio.Writer
+	// Flush after number of rows.
+	flushRows int
+	// Flush after number of bytes.
+	flushBytes int
+	// Rows (buffered) since last flush.
+	rows int
+	// Rows written.
+	written int
+	// Started (status written).
+	started bool
+}
+
+//
+// Write a row.
+func (r *listWriter) write(object interface{}) (err error) {
+	if !r.started {
+		err = r.encoder.accepts(object)
+		if err != nil {
+			return
+		}
+	}
+	r.start()
+	err = r.encoder.item(r.buffer, r.written, object)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	r.written++
+	r.rows++
+	if r.rows >= r.flushRows || r.buffer.Buffered() >= r.flushBytes {
+		err = r.flush()
+	}
+
+	return
+}
+
+//
+// End the list.
+func (r *listWriter) end() (err error) {
+	r.start()
+	_ = r.encoder.end(r.buffer, r.written)
+	err = r.flush()
+	return
+}
+
+//
+// Start the response.
+// Write the status, headers and list opening.
+func (r *listWriter) start() {
+	if r.started {
+		return
+	}
+	r.started = true
+	if r.flushRows < 1 {
+		r.flushRows = ListFlushRows
+	}
+	if r.flushBytes < 1 {
+		r.flushBytes = ListFlushBytes
+	}
+	r.ctx.Header("Content-Type", r.encoder.contentType())
+	r.ctx.Status(http.StatusOK)
+	r.buffer = bufio.NewWriterSize(r.ctx.Writer, r.flushBytes)
+	_ = r.encoder.begin(r.buffer)
+}
+
+//
+// Flush the buffer and the response.
+func (r *listWriter) flush() (err error) {
+	err = r.buffer.Flush()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	r.ctx.Writer.Flush()
+	r.rows = 0
+	return
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/konveyor/controller/pkg/logging"
+	"net/http"
+)
+
+//
+// Routes.
+const (
+	LoggingRoot = AdminLogging
+	NameParam   = "name"
+)
+
+//
+// Log level (subsystem).
+type LogLevel struct {
+	// Subsystem (logger) name.
+	Name string `json:"name"`
+	// Level.
+	Level int `json:"level"`
+}
+
+//
+// Logging (admin) handler.
+// Provides runtime control of log levels by subsystem:
+//   GET    /admin/logging       - List levels.
+//   PUT    /admin/logging/:name - Set the level.
+//   DELETE /admin/logging/:name - Reset the level.
+// The routes are added by the AdminHandler and are not
+// intended for the public server. See: AdminServer.
+type LoggingHandler struct {
+}
+
+//
+// Add routes.
+func (h *LoggingHandler) AddRoutes(r *gin.Engine) {
+	r.GET(LoggingRoot, h.List)
+	r.PUT(LoggingRoot+"/:"+NameParam, h.Update)
+	r.DELETE(LoggingRoot+"/:"+NameParam, h.Delete)
+}
+
+//
+// List levels.
+// The default level is listed with an empty name.
+func (h *LoggingHandler) List(ctx *gin.Context) {
+	list := []LogLevel{
+		{Level: logging.Current().Level},
+	}
+	for name, level := range logging.Levels() {
+		list = append(
+			list,
+			LogLevel{
+				Name:  name,
+				Level: level,
+			})
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+//
+// Set (PUT) the level.
+func (h *LoggingHandler) Update(ctx *gin.Context) {
+	level := LogLevel{}
+	err := ctx.BindJSON(&level)
+	if err != nil {
+		return
+	}
+	level.Name = ctx.Param(NameParam)
+	logging.SetLevel(level.Name, level.Level)
+	log.Info(
+		"log level set.",
+		"name",
+		level.Name,
+		"level",
+		level.Level)
+
+	ctx.JSON(http.StatusOK, level)
+}
+
+//
+// Reset (DELETE) the level.
+func (h *LoggingHandler) Delete(ctx *gin.Context) {
+	name := ctx.Param(NameParam)
+	logging.ResetLevel(name)
+	log.Info(
+		"log level reset.",
+		"name",
+		name)
+
+	ctx.Status(http.StatusNoContent)
+}
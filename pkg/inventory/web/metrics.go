@@ -0,0 +1,308 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/konveyor/controller/pkg/inventory/container"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+//
+// Routes.
+const (
+	MetricsRoot = "/metrics"
+)
+
+//
+// Shared metrics registry.
+// The host controller may register additional collectors.
+var Registry = prometheus.NewRegistry()
+
+//
+// Web metrics.
+var (
+	// Requests by method, route and status.
+	RequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_web_requests_total",
+			Help: "Number of web requests.",
+		},
+		[]string{"method", "route", "status"})
+	// Request duration by method and route.
+	RequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "inventory_web_request_duration_seconds",
+			Help:    "Web request duration.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route"})
+	// Watch (websocket) connections.
+	WatchGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "inventory_web_watches",
+			Help: "Number of watch connections.",
+		},
+		func() float64 {
+			return float64(writers.len())
+		})
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		RequestCounter,
+		RequestDuration,
+		WatchGauge,
+		container.ReconcileCounter,
+		container.ReconciledCounter,
+		container.StalledCounter,
+		container.BudgetGauge,
+		container.BudgetCounter,
+		model.PurgedCounter,
+		logging.DroppedCounter)
+}
+
+//
+// Request metrics middleware.
+func RequestMetrics(ctx *gin.Context) {
+	mark := time.Now()
+	ctx.Next()
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := ctx.Request.Method
+	status := strconv.Itoa(ctx.Writer.Status())
+	RequestCounter.WithLabelValues(method, route, status).Inc()
+	RequestDuration.WithLabelValues(method, route).Observe(
+		time.Since(mark).Seconds())
+}
+
+//
+// Metrics handler.
+// Serves the shared registry and DB (collector) metrics
+// in the prometheus text format.
+type MetricsHandler struct {
+	// Reference to the container.
+	Container *container.Container
+	// Reference to the (optional) DB manager.
+	Manager *model.Manager
+	// Handler (local) registry.
+	registry *prometheus.Registry
+}
+
+//
+// Add routes.
+func (h *MetricsHandler) AddRoutes(r *gin.Engine) {
+	h.registry = prometheus.NewRegistry()
+	h.registry.MustRegister(
+		&dbCollector{
+			container: h.Container,
+			manager:   h.Manager,
+		})
+	handler := promhttp.HandlerFor(
+		prometheus.Gatherers{Registry, h.registry},
+		promhttp.HandlerOpts{})
+	r.GET(MetricsRoot, gin.WrapH(handler))
+}
+
+//
+// DB (collector) metrics.
+type dbCollector struct {
+	// Reference to the container.
+	container *container.Container
+	// Reference to the DB manager.
+	manager *model.Manager
+}
+
+//
+// DB metric descriptions.
+var (
+	dbOpenDesc = prometheus.NewDesc(
+		"inventory_db_open",
+		"DB can be queried.",
+		[]string{"collector"},
+		nil)
+	dbWatchDesc = prometheus.NewDesc(
+		"inventory_db_watches",
+		"Number of DB watches.",
+		[]string{"collector"},
+		nil)
+	dbBacklogDesc = prometheus.NewDesc(
+		"inventory_db_backlog",
+		"Number of queued (undelivered) event batches.",
+		[]string{"collector"},
+		nil)
+	dbDiscardedDesc = prometheus.NewDesc(
+		"inventory_db_events_discarded_total",
+		"Number of watch events discarded (retention).",
+		[]string{"collector"},
+		nil)
+	dbCompactedDesc = prometheus.NewDesc(
+		"inventory_db_events_compacted_total",
+		"Number of watch events collapsed by compaction.",
+		[]string{"collector"},
+		nil)
+	dbEvictedDesc = prometheus.NewDesc(
+		"inventory_db_rows_evicted_total",
+		"Number of rows evicted (row limit).",
+		[]string{"collector", "kind"},
+		nil)
+	dbRejectedDesc = prometheus.NewDesc(
+		"inventory_db_rows_rejected_total",
+		"Number of inserts rejected (row limit).",
+		[]string{"collector", "kind"},
+		nil)
+	parityDesc = prometheus.NewDesc(
+		"inventory_collector_parity",
+		"Collector has parity.",
+		[]string{"collector"},
+		nil)
+	managedDesc = prometheus.NewDesc(
+		"inventory_managed_dbs",
+		"Number of managed DBs.",
+		nil,
+		nil)
+	managedOpenDesc = prometheus.NewDesc(
+		"inventory_managed_db_open",
+		"Managed DB can be queried.",
+		[]string{"kind", "owner"},
+		nil)
+	managedWatchDesc = prometheus.NewDesc(
+		"inventory_managed_db_watches",
+		"Number of managed DB watches.",
+		[]string{"kind", "owner"},
+		nil)
+	managedBacklogDesc = prometheus.NewDesc(
+		"inventory_managed_db_backlog",
+		"Number of managed DB queued (undelivered) event batches.",
+		[]string{"kind", "owner"},
+		nil)
+)
+
+//
+// Describe metrics.
+func (r *dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbOpenDesc
+	ch <- dbWatchDesc
+	ch <- dbBacklogDesc
+	ch <- dbDiscardedDesc
+	ch <- dbCompactedDesc
+	ch <- dbEvictedDesc
+	ch <- dbRejectedDesc
+	ch <- parityDesc
+	ch <- managedDesc
+	ch <- managedOpenDesc
+	ch <- managedWatchDesc
+	ch <- managedBacklogDesc
+}
+
+//
+// Collect metrics.
+func (r *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	r.collectManaged(ch)
+	if r.container == nil {
+		return
+	}
+	for _, collector := range r.container.List() {
+		name := collector.Name()
+		ch <- prometheus.MustNewConstMetric(
+			parityDesc,
+			prometheus.GaugeValue,
+			bool2f(collector.HasParity()),
+			name)
+		db := collector.DB()
+		if db == nil {
+			continue
+		}
+		h := model.HealthOf(db)
+		ch <- prometheus.MustNewConstMetric(
+			dbOpenDesc,
+			prometheus.GaugeValue,
+			bool2f(h.Open),
+			name)
+		ch <- prometheus.MustNewConstMetric(
+			dbWatchDesc,
+			prometheus.GaugeValue,
+			float64(h.Watches),
+			name)
+		ch <- prometheus.MustNewConstMetric(
+			dbBacklogDesc,
+			prometheus.GaugeValue,
+			float64(h.Backlog),
+			name)
+		ch <- prometheus.MustNewConstMetric(
+			dbDiscardedDesc,
+			prometheus.CounterValue,
+			float64(h.Discarded),
+			name)
+		ch <- prometheus.MustNewConstMetric(
+			dbCompactedDesc,
+			prometheus.CounterValue,
+			float64(h.Compacted),
+			name)
+		for _, limit := range db.Limits() {
+			ch <- prometheus.MustNewConstMetric(
+				dbEvictedDesc,
+				prometheus.CounterValue,
+				float64(limit.Evicted),
+				name,
+				limit.Kind)
+			ch <- prometheus.MustNewConstMetric(
+				dbRejectedDesc,
+				prometheus.CounterValue,
+				float64(limit.Rejected),
+				name,
+				limit.Kind)
+		}
+	}
+}
+
+//
+// Collect managed DB metrics.
+func (r *dbCollector) collectManaged(ch chan<- prometheus.Metric) {
+	if r.manager == nil {
+		return
+	}
+	list := r.manager.List()
+	ch <- prometheus.MustNewConstMetric(
+		managedDesc,
+		prometheus.GaugeValue,
+		float64(len(list)))
+	for _, m := range list {
+		h := model.HealthOf(m.DB)
+		ch <- prometheus.MustNewConstMetric(
+			managedOpenDesc,
+			prometheus.GaugeValue,
+			bool2f(h.Open),
+			m.Kind,
+			m.Name)
+		ch <- prometheus.MustNewConstMetric(
+			managedWatchDesc,
+			prometheus.GaugeValue,
+			float64(h.Watches),
+			m.Kind,
+			m.Name)
+		ch <- prometheus.MustNewConstMetric(
+			managedBacklogDesc,
+			prometheus.GaugeValue,
+			float64(h.Backlog),
+			m.Kind,
+			m.Name)
+	}
+}
+
+//
+// Convert bool to (gauge) float.
+func bool2f(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
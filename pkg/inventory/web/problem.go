@@ -0,0 +1,187 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"github.com/konveyor/controller/pkg/ref"
+	"net/http"
+)
+
+//
+// Errors.
+var (
+	// The request is not valid.
+	BadRequestErr = errors.New("request not valid")
+	// The resource was not found.
+	NotFoundErr = errors.New("resource not found")
+	// The request is not authenticated.
+	UnauthorizedErr = errors.New("token required")
+	// The request is not permitted.
+	ForbiddenErr = errors.New("not permitted")
+	// The request body is too large.
+	TooLargeErr = errors.New("request body too large")
+	// The request timed out.
+	TimeoutErr = errors.New("request timeout")
+	// The method is not supported by the resource.
+	MethodNotAllowedErr = errors.New("method not allowed")
+	// The resource cannot be rendered as the accepted content type.
+	NotAcceptableErr = errors.New("content type not acceptable")
+	// The service is not available.
+	UnavailableErr = errors.New("service unavailable")
+)
+
+//
+// Bad request.
+// Wraps the cause (error) of a request not valid.
+type BadRequest struct {
+	// The cause.
+	Err error
+}
+
+//
+// Error description.
+func (e *BadRequest) Error() string {
+	if e.Err == nil {
+		return BadRequestErr.Error()
+	}
+
+	return e.Err.Error()
+}
+
+//
+// Matched by errors.Is(BadRequestErr).
+func (e *BadRequest) Is(target error) bool {
+	return target == BadRequestErr
+}
+
+//
+// Unwrap the cause.
+func (e *BadRequest) Unwrap() error {
+	return e.Err
+}
+
+//
+// Register the status mappings.
+func init() {
+	liberr.Statuses.Register(
+		liberr.StatusMapping{
+			Target: BadRequestErr,
+			Status: http.StatusBadRequest,
+		},
+		liberr.StatusMapping{
+			Target: model.EnumErr,
+			Status: http.StatusBadRequest,
+		},
+		liberr.StatusMapping{
+			Target: model.SchemaErr,
+			Status: http.StatusBadRequest,
+		},
+		liberr.StatusMapping{
+			Target: model.PredicateRefErr,
+			Status: http.StatusBadRequest,
+		},
+		liberr.StatusMapping{
+			Target: model.PredicateTypeErr,
+			Status: http.StatusBadRequest,
+		},
+		liberr.StatusMapping{
+			Target: model.PredicateValueErr,
+			Status: http.StatusBadRequest,
+		},
+		liberr.StatusMapping{
+			Target: UnauthorizedErr,
+			Status: http.StatusUnauthorized,
+		},
+		liberr.StatusMapping{
+			Target: TokenInvalidErr,
+			Status: http.StatusUnauthorized,
+		},
+		liberr.StatusMapping{
+			Target: TokenExpiredErr,
+			Status: http.StatusUnauthorized,
+		},
+		liberr.StatusMapping{
+			Target: TokenRevokedErr,
+			Status: http.StatusUnauthorized,
+		},
+		liberr.StatusMapping{
+			Target: ForbiddenErr,
+			Status: http.StatusForbidden,
+		},
+		liberr.StatusMapping{
+			Match: func(err error) bool {
+				var denied *ref.PolicyDenied
+				return errors.As(err, &denied)
+			},
+			Status: http.StatusForbidden,
+			Title:  "Policy Denied",
+		},
+		liberr.StatusMapping{
+			Target: NotFoundErr,
+			Status: http.StatusNotFound,
+		},
+		liberr.StatusMapping{
+			Target: model.NotFound,
+			Status: http.StatusNotFound,
+		},
+		liberr.StatusMapping{
+			Target: TokenNotFoundErr,
+			Status: http.StatusNotFound,
+		},
+		liberr.StatusMapping{
+			Target: ConflictErr,
+			Status: http.StatusConflict,
+		},
+		liberr.StatusMapping{
+			Target: model.AlreadyExistsErr,
+			Status: http.StatusConflict,
+		},
+		liberr.StatusMapping{
+			Target: TooLargeErr,
+			Status: http.StatusRequestEntityTooLarge,
+		},
+		liberr.StatusMapping{
+			Target: TimeoutErr,
+			Status: http.StatusGatewayTimeout,
+		},
+		liberr.StatusMapping{
+			Target: MethodNotAllowedErr,
+			Status: http.StatusMethodNotAllowed,
+		},
+		liberr.StatusMapping{
+			Target: NotAcceptableErr,
+			Status: http.StatusNotAcceptable,
+		},
+		liberr.StatusMapping{
+			Target: UnavailableErr,
+			Status: http.StatusServiceUnavailable,
+		})
+}
+
+//
+// HTTP status for the error.
+// Mapped using the status registry (see: liberr.Statuses).
+// Returns 200 (OK) when the error is nil.
+func StatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	return liberr.Statuses.Find(err).Status
+}
+
+//
+// Fail the request.
+// The error is mapped to an HTTP status (see: liberr.Statuses)
+// and rendered as problem details (RFC 7807). Server (5xx)
+// errors are logged.
+func Fail(ctx *gin.Context, err error) {
+	problem := liberr.ToProblem(err, ctx.Request.URL.Path)
+	if problem.Status >= http.StatusInternalServerError {
+		log.Trace(err, "url", ctx.Request.URL)
+	}
+	ctx.Header("Content-Type", liberr.ProblemType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
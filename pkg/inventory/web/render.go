@@ -0,0 +1,275 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//
+// Rendered content types.
+const (
+	JSONType     = "application/json"
+	YAMLType     = "application/yaml"
+	ProtobufType = "application/x-protobuf"
+)
+
+//
+// Accepted media types (mapped to rendered content types).
+var MediaTypes = map[string]string{
+	"*/*":                    JSONType,
+	"application/*":          JSONType,
+	"application/json":       JSONType,
+	"application/yaml":       YAMLType,
+	"application/x-yaml":     YAMLType,
+	"text/yaml":              YAMLType,
+	"application/protobuf":   ProtobufType,
+	"application/x-protobuf": ProtobufType,
+}
+
+//
+// Negotiate the (response) content type.
+// Media ranges in the `Accept` header are matched by quality (q)
+// then order. Unsupported media ranges are ignored.
+// Default: JSON.
+func Negotiate(ctx *gin.Context) (contentType string) {
+	type accepted struct {
+		contentType string
+		quality     float64
+	}
+	contentType = JSONType
+	list := []accepted{}
+	for _, part := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		matched, found := MediaTypes[mediaType]
+		if !found {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && kv[0] == "q" {
+				q, err := strconv.ParseFloat(kv[1], 64)
+				if err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			list = append(list, accepted{matched, quality})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].quality > list[j].quality
+	})
+	if len(list) > 0 {
+		contentType = list[0].contentType
+	}
+
+	return
+}
+
+//
+// Render the object using the negotiated content type.
+// Rendered as protobuf only when the object is a (generated)
+// protobuf message; otherwise NotAcceptableErr is reported.
+// Example:
+//   web.Render(ctx, http.StatusOK, resource)
+func Render(ctx *gin.Context, status int, object interface{}) {
+	contentType := Negotiate(ctx)
+	if contentType == JSONType {
+		ctx.JSON(status, object)
+		return
+	}
+	var b []byte
+	var err error
+	switch contentType {
+	case ProtobufType:
+		b, err = toProtobuf(object)
+	default:
+		b, err = yaml.Marshal(object)
+	}
+	if err != nil {
+		Fail(ctx, err)
+		return
+	}
+
+	ctx.Data(status, contentType, b)
+}
+
+//
+// Streaming (list) encoder.
+type encoder interface {
+	// Content type.
+	contentType() string
+	// Validate the object may be encoded.
+	accepts(object interface{}) error
+	// Begin the list.
+	begin(w io.Writer) error
+	// Write an item.
+	item(w io.Writer, index int, object interface{}) error
+	// End the list.
+	end(w io.Writer, written int) error
+}
+
+//
+// Streaming encoders by content type.
+var encoders = map[string]func() encoder{
+	JSONType: func() encoder {
+		return &jsonEncoder{}
+	},
+	YAMLType: func() encoder {
+		return &yamlEncoder{}
+	},
+	ProtobufType: func() encoder {
+		return &protobufEncoder{}
+	},
+}
+
+//
+// Get the (streaming) encoder for the content type.
+// Default: JSON.
+func encoderFor(contentType string) encoder {
+	if fn, found := encoders[contentType]; found {
+		return fn()
+	}
+
+	return &jsonEncoder{}
+}
+
+//
+// JSON (array) encoder.
+type jsonEncoder struct{}
+
+func (r *jsonEncoder) contentType() string {
+	return JSONType + "; charset=utf-8"
+}
+
+func (r *jsonEncoder) accepts(object interface{}) (err error) {
+	return
+}
+
+func (r *jsonEncoder) begin(w io.Writer) (err error) {
+	_, err = w.Write([]byte{'['})
+	return
+}
+
+func (r *jsonEncoder) item(w io.Writer, index int, object interface{}) (err error) {
+	b, err := json.Marshal(object)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	if index > 0 {
+		b = append([]byte{','}, b...)
+	}
+	_, err = w.Write(b)
+	return
+}
+
+func (r *jsonEncoder) end(w io.Writer, written int) (err error) {
+	_, err = w.Write([]byte{']'})
+	return
+}
+
+//
+// YAML (sequence) encoder.
+// Each item is written as a sequence entry.
+type yamlEncoder struct{}
+
+func (r *yamlEncoder) contentType() string {
+	return YAMLType + "; charset=utf-8"
+}
+
+func (r *yamlEncoder) accepts(object interface{}) (err error) {
+	return
+}
+
+func (r *yamlEncoder) begin(w io.Writer) (err error) {
+	return
+}
+
+func (r *yamlEncoder) item(w io.Writer, index int, object interface{}) (err error) {
+	b, err := yaml.Marshal(object)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	b = bytes.TrimRight(b, "\n")
+	b = bytes.ReplaceAll(b, []byte("\n"), []byte("\n  "))
+	b = append([]byte("- "), b...)
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return
+}
+
+func (r *yamlEncoder) end(w io.Writer, written int) (err error) {
+	if written == 0 {
+		_, err = w.Write([]byte("[]\n"))
+	}
+	return
+}
+
+//
+// Protobuf encoder.
+// The list is written as a stream of (varint) length-delimited
+// messages. The items must be (generated) protobuf messages.
+type protobufEncoder struct{}
+
+func (r *protobufEncoder) contentType() string {
+	return ProtobufType
+}
+
+func (r *protobufEncoder) accepts(object interface{}) (err error) {
+	if _, cast := object.(proto.Message); !cast {
+		err = liberr.Wrap(
+			NotAcceptableErr,
+			"type",
+			fmt.Sprintf("%T", object))
+	}
+	return
+}
+
+func (r *protobufEncoder) begin(w io.Writer) (err error) {
+	return
+}
+
+func (r *protobufEncoder) item(w io.Writer, index int, object interface{}) (err error) {
+	b, err := toProtobuf(object)
+	if err != nil {
+		return
+	}
+	b = append(protowire.AppendVarint(nil, uint64(len(b))), b...)
+	_, err = w.Write(b)
+	return
+}
+
+func (r *protobufEncoder) end(w io.Writer, written int) (err error) {
+	return
+}
+
+//
+// Encode the (protobuf message) object.
+// Returns NotAcceptableErr when the object is not a message.
+func toProtobuf(object interface{}) (b []byte, err error) {
+	err = (&protobufEncoder{}).accepts(object)
+	if err != nil {
+		return
+	}
+	b, err = proto.Marshal(object.(proto.Message))
+	if err != nil {
+		err = liberr.Wrap(err)
+	}
+
+	return
+}
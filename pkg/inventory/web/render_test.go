@@ -0,0 +1,96 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testContext(accept string) (ctx *gin.Context, recorder *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx.Request.Header.Set("Accept", accept)
+	return
+}
+
+func TestNegotiate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	for accept, expected := range map[string]string{
+		"":                                  JSONType,
+		"text/html":                         JSONType,
+		"application/yaml":                  YAMLType,
+		"application/x-protobuf":            ProtobufType,
+		"application/protobuf":              ProtobufType,
+		"application/json;q=0.5, text/yaml": YAMLType,
+		"application/x-protobuf;q=0.1, */*": JSONType,
+	} {
+		ctx, _ := testContext(accept)
+		g.Expect(Negotiate(ctx)).To(gomega.Equal(expected), accept)
+	}
+}
+
+func TestRenderProtobuf(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	// message.
+	ctx, recorder := testContext(ProtobufType)
+	Render(ctx, http.StatusOK, wrapperspb.String("Elmer"))
+	g.Expect(recorder.Code).To(gomega.Equal(http.StatusOK))
+	g.Expect(recorder.Header().Get("Content-Type")).To(gomega.Equal(ProtobufType))
+	decoded := &wrapperspb.StringValue{}
+	err := proto.Unmarshal(recorder.Body.Bytes(), decoded)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(decoded.Value).To(gomega.Equal("Elmer"))
+	// not a message.
+	ctx, recorder = testContext(ProtobufType)
+	Render(ctx, http.StatusOK, map[string]string{"name": "Elmer"})
+	g.Expect(recorder.Code).To(gomega.Equal(http.StatusNotAcceptable))
+}
+
+func TestStreamProtobuf(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	// messages (length-delimited).
+	ctx, recorder := testContext(ProtobufType)
+	writer := &listWriter{
+		ctx:     ctx,
+		encoder: encoderFor(Negotiate(ctx)),
+	}
+	for _, v := range []int64{1, 300} {
+		err := writer.write(wrapperspb.Int64(v))
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err := writer.end()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(recorder.Code).To(gomega.Equal(http.StatusOK))
+	g.Expect(recorder.Header().Get("Content-Type")).To(gomega.Equal(ProtobufType))
+	decoded := []int64{}
+	b := recorder.Body.Bytes()
+	for len(b) > 0 {
+		n, size := protowire.ConsumeVarint(b)
+		g.Expect(size > 0).To(gomega.BeTrue())
+		b = b[size:]
+		m := &wrapperspb.Int64Value{}
+		err = proto.Unmarshal(b[:n], m)
+		g.Expect(err).To(gomega.BeNil())
+		decoded = append(decoded, m.Value)
+		b = b[n:]
+	}
+	g.Expect(decoded).To(gomega.Equal([]int64{1, 300}))
+	// not a message; rejected before the status is written.
+	ctx, _ = testContext(ProtobufType)
+	writer = &listWriter{
+		ctx:     ctx,
+		encoder: encoderFor(Negotiate(ctx)),
+	}
+	err = writer.write(map[string]string{"name": "Elmer"})
+	g.Expect(errors.Is(err, NotAcceptableErr)).To(gomega.BeTrue())
+	g.Expect(writer.started).To(gomega.BeFalse())
+	g.Expect(ctx.Writer.Written()).To(gomega.BeFalse())
+}
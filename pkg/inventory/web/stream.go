@@ -0,0 +1,316 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Event-stream (SSE) content type.
+const (
+	EventStreamType = "text/event-stream"
+)
+
+//
+// Event-stream (SSE) event names.
+const (
+	// The server is going away.
+	// The data is the last delivered revision.
+	GoingAwayEvent = "going-away"
+)
+
+//
+// Event-stream keep-alive (comment) interval.
+// Prevents intermediaries from closing idle streams.
+var EventStreamKeepAlive = time.Second * 30
+
+//
+// Event-stream (SSE) watch transport.
+// Used when websockets are blocked. Events are written as
+// (chunked) server-sent events. The peer ends the watch by
+// closing the connection.
+type streamTransport struct {
+	// Response writer.
+	writer gin.ResponseWriter
+	// Request context done.
+	requestDone <-chan struct{}
+	// Remote address.
+	remoteAddr string
+	// Connection (when known).
+	conn net.Conn
+	// Write deadline (duration).
+	timeout time.Duration
+	// Closed.
+	closed chan struct{}
+	// Close once.
+	once sync.Once
+	// Protect the writer.
+	mutex sync.Mutex
+}
+
+//
+// Build a new event-stream transport.
+func newStreamTransport(ctx *gin.Context) (t *streamTransport, err error) {
+	if _, cast := ctx.Writer.(http.Flusher); !cast {
+		err = liberr.New("streaming not supported.")
+		return
+	}
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", EventStreamType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	t = &streamTransport{
+		writer:      ctx.Writer,
+		requestDone: ctx.Request.Context().Done(),
+		remoteAddr:  ctx.Request.RemoteAddr,
+		conn:        connOf(ctx.Request),
+		timeout:     watchWriteTimeout(ctx),
+		closed:      make(chan struct{}),
+	}
+
+	go t.keepAlive()
+
+	return
+}
+
+//
+// Write an event.
+func (r *streamTransport) write(event Event) (err error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	err = r.frame(
+		fmt.Sprintf(
+			"id: %d\nevent: %s\ndata: %s\n\n",
+			event.ID,
+			event.action(),
+			data))
+	return
+}
+
+//
+// Read an event.
+// The peer cannot send events; blocks until the peer
+// has closed the connection or the transport is closed.
+func (r *streamTransport) read(event *Event) (err error) {
+	select {
+	case <-r.requestDone:
+		err = liberr.New("closed by peer.")
+	case <-r.closed:
+		err = liberr.New("closed.")
+	}
+
+	return
+}
+
+//
+// Send the `going-away` event and close.
+func (r *streamTransport) goingAway(reason string) {
+	_ = r.frame(
+		fmt.Sprintf(
+			"event: %s\ndata: %s\n\n",
+			GoingAwayEvent,
+			reason))
+	r.close()
+}
+
+//
+// Close the stream.
+// Waits for an in-progress write.
+func (r *streamTransport) close() {
+	r.once.Do(func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		close(r.closed)
+	})
+}
+
+//
+// Remote address.
+func (r *streamTransport) remote() string {
+	return r.remoteAddr
+}
+
+//
+// Wait for the stream to be closed.
+// The transport is closed (when not already) to prevent
+// writes after the request has completed.
+func (r *streamTransport) wait() {
+	select {
+	case <-r.requestDone:
+	case <-r.closed:
+	}
+
+	r.close()
+}
+
+//
+// Write and flush a frame.
+// The write deadline is set (when specified) on the
+// connection and cleared after the frame is written.
+func (r *streamTransport) frame(s string) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	select {
+	case <-r.closed:
+		err = liberr.New("closed.")
+		return
+	default:
+	}
+	if r.conn != nil && r.timeout > 0 {
+		_ = r.conn.SetWriteDeadline(time.Now().Add(r.timeout))
+		defer func() {
+			_ = r.conn.SetWriteDeadline(time.Time{})
+		}()
+	}
+	_, err = io.WriteString(r.writer, s)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+
+	r.writer.Flush()
+
+	return
+}
+
+//
+// Periodically write a comment to keep the stream alive.
+func (r *streamTransport) keepAlive() {
+	ticker := time.NewTicker(EventStreamKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = r.frame(": keep-alive\n\n")
+		case <-r.requestDone:
+			return
+		case <-r.closed:
+			return
+		}
+	}
+}
+
+//
+// Event-stream (SSE) reader.
+// Provides the (client) watch connection over
+// the event-stream transport.
+type eventStream struct {
+	// The http response.
+	response *http.Response
+	// Buffered body reader.
+	reader *bufio.Reader
+	// Remote address.
+	remoteAddr streamAddr
+}
+
+//
+// Build a new event-stream reader.
+func newEventStream(response *http.Response) *eventStream {
+	return &eventStream{
+		response:   response,
+		reader:     bufio.NewReader(response.Body),
+		remoteAddr: streamAddr(response.Request.URL.Host),
+	}
+}
+
+//
+// Read the next event.
+// The `going-away` event is returned as a GoingAway error.
+func (r *eventStream) ReadJSON(event interface{}) (err error) {
+	name := ""
+	data := bytes.Buffer{}
+	for {
+		line, rErr := r.reader.ReadString('\n')
+		if rErr != nil {
+			err = rErr
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			if name == GoingAwayEvent {
+				goingAway := &GoingAway{}
+				reason := data.String()
+				if strings.HasPrefix(reason, RevisionReason) {
+					n, pErr := strconv.ParseUint(
+						strings.TrimPrefix(reason, RevisionReason), 10, 64)
+					if pErr == nil {
+						goingAway.Revision = n
+					}
+				}
+				err = goingAway
+				return
+			}
+			err = json.Unmarshal(data.Bytes(), event)
+			return
+		case strings.HasPrefix(line, ":"):
+			// comment.
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+}
+
+//
+// Events cannot be sent to the server.
+// The watch is ended by closing the connection.
+func (r *eventStream) WriteJSON(interface{}) error {
+	return nil
+}
+
+//
+// Close the stream.
+func (r *eventStream) Close() error {
+	return r.response.Body.Close()
+}
+
+//
+// Local address.
+func (r *eventStream) LocalAddr() net.Addr {
+	return streamAddr("")
+}
+
+//
+// Remote address.
+func (r *eventStream) RemoteAddr() net.Addr {
+	return r.remoteAddr
+}
+
+//
+// Event-stream address.
+type streamAddr string
+
+//
+// Network.
+func (a streamAddr) Network() string {
+	return "tcp"
+}
+
+//
+// String representation.
+func (a streamAddr) String() string {
+	return string(a)
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"strings"
+)
+
+//
+// Params.
+const (
+	// Owner (tenant) route param.
+	OwnerParam = "owner"
+)
+
+//
+// Build a route scoped by owner (tenant).
+// Example: Scoped("/providers", "/vms") returns:
+//   /providers/:owner/vms
+func Scoped(root, path string) string {
+	return strings.Join(
+		[]string{
+			strings.TrimRight(root, "/"),
+			":" + OwnerParam,
+			strings.TrimLeft(path, "/"),
+		},
+		"/")
+}
+
+//
+// Tenant (owner) scoped handler.
+// The owner is the route segment named by `OwnerParam`.
+// The owner predicate is injected into list options and
+// the owner filter into the watch (including the snapshot)
+// so that one server may safely serve many owners.
+type Tenanted struct {
+	Paged
+	Watched
+	Filtered
+	// Owner (model) field name.
+	OwnerField string
+	// The owner (ID) passed in the route.
+	Owner string
+}
+
+//
+// Prepare the handler to fulfil the request.
+// Set the `Owner` field using the route param and the
+// paged, watched and filtered fields using the passed parameters.
+// Filters are not supported by watches (the events are not
+// matched against predicates).
+// Returns the HTTP status. See: PrepareErr().
+func (h *Tenanted) Prepare(ctx *gin.Context) int {
+	return StatusOf(h.PrepareErr(ctx))
+}
+
+//
+// Prepare the handler to fulfil the request.
+// Same as Prepare() but returns the (typed) error
+// to be reported using Fail().
+func (h *Tenanted) PrepareErr(ctx *gin.Context) (err error) {
+	h.Owner = ctx.Param(OwnerParam)
+	if h.Owner == "" {
+		err = liberr.Wrap(NotFoundErr, "param", OwnerParam)
+		return
+	}
+	err = h.Paged.PrepareErr(ctx)
+	if err != nil {
+		return
+	}
+	err = h.Watched.PrepareErr(ctx)
+	if err != nil {
+		return
+	}
+	err = h.Filtered.PrepareErr(ctx)
+	if err != nil {
+		return
+	}
+	if h.WatchRequest && h.Filter != nil {
+		err = liberr.Wrap(
+			&BadRequest{Err: errors.New("filter not supported by watch")},
+			FilterParam,
+			ctx.QueryArray(FilterParam))
+		return
+	}
+	h.Watched.options.Predicate = h.Predicate()
+	h.Watched.options.Filter = h.Match
+
+	return
+}
+
+//
+// Build the owner predicate.
+// The owner predicate is AND-ed with the filter and the
+// (optional) predicates passed.
+func (h *Tenanted) Predicate(predicates ...model.Predicate) model.Predicate {
+	owner := model.Eq(h.OwnerField, h.Owner)
+	if h.Filter != nil {
+		predicates = append([]model.Predicate{h.Filter}, predicates...)
+	}
+	if len(predicates) == 0 {
+		return owner
+	}
+
+	return model.And(append([]model.Predicate{owner}, predicates...)...)
+}
+
+//
+// Build list options.
+// The page and owner predicate are injected.
+func (h *Tenanted) ListOptions(predicates ...model.Predicate) model.ListOptions {
+	return model.ListOptions{
+		Page:      &h.Page,
+		Predicate: h.Predicate(predicates...),
+		Stable:    true,
+	}
+}
+
+//
+// Model is owned by the owner (tenant).
+func (h *Tenanted) Match(m model.Model) bool {
+	md, err := model.Inspect(m)
+	if err != nil {
+		return false
+	}
+	name := strings.ToLower(h.OwnerField)
+	for _, f := range md.Fields {
+		if strings.ToLower(f.Name) == name {
+			return fmt.Sprintf("%v", f.Value.Interface()) == h.Owner
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+//
+// Default interval between checks for changed
+// certificate files.
+var CertPoll = time.Second * 10
+
+//
+// Certificate provider.
+type CertProvider interface {
+	// Get the (current) certificate.
+	Certificate() (*tls.Certificate, error)
+}
+
+//
+// File-backed certificate provider.
+// The certificate is (re)loaded when the certificate
+// or key file has changed.
+type FileCertProvider struct {
+	// Certificate path.
+	CertPath string
+	// Key path.
+	KeyPath string
+	// Interval between checks for changed files.
+	// Default: CertPoll.
+	Poll time.Duration
+	// Loaded certificate.
+	cert *tls.Certificate
+	// Modification time of the loaded files.
+	modTime time.Time
+	// Last checked.
+	checked time.Time
+	// Protect the certificate.
+	mutex sync.Mutex
+}
+
+//
+// Get the (current) certificate.
+// Reloaded as needed.
+func (r *FileCertProvider) Certificate() (cert *tls.Certificate, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	poll := r.Poll
+	if poll == 0 {
+		poll = CertPoll
+	}
+	if r.cert != nil && time.Since(r.checked) < poll {
+		cert = r.cert
+		return
+	}
+	r.checked = time.Now()
+	modTime, err := r.lastModified()
+	if err != nil {
+		if r.cert != nil {
+			log.Trace(err)
+			cert = r.cert
+			err = nil
+		}
+		return
+	}
+	if r.cert != nil && !modTime.After(r.modTime) {
+		cert = r.cert
+		return
+	}
+	loaded, err := tls.LoadX509KeyPair(r.CertPath, r.KeyPath)
+	if err != nil {
+		err = liberr.Wrap(
+			err,
+			"certificate not loaded.",
+			"cert",
+			r.CertPath,
+			"key",
+			r.KeyPath)
+		if r.cert != nil {
+			log.Trace(err)
+			cert = r.cert
+			err = nil
+		}
+		return
+	}
+	r.cert = &loaded
+	r.modTime = modTime
+	cert = r.cert
+
+	log.V(3).Info(
+		"web: certificate loaded.",
+		"cert",
+		r.CertPath)
+
+	return
+}
+
+//
+// Latest modification time of the files.
+func (r *FileCertProvider) lastModified() (modTime time.Time, err error) {
+	for _, path := range []string{r.CertPath, r.KeyPath} {
+		st, sErr := os.Stat(path)
+		if sErr != nil {
+			err = liberr.Wrap(sErr)
+			return
+		}
+		if st.ModTime().After(modTime) {
+			modTime = st.ModTime()
+		}
+	}
+
+	return
+}
+
+//
+// Build the TLS configuration.
+func (w *WebServer) tlsConfig() (cfg *tls.Config, err error) {
+	provider := w.TLS.Provider
+	if provider == nil {
+		provider = &FileCertProvider{
+			CertPath: w.TLS.Certificate,
+			KeyPath:  w.TLS.Key,
+		}
+	}
+	_, err = provider.Certificate()
+	if err != nil {
+		return
+	}
+	cfg = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return provider.Certificate()
+		},
+	}
+	if w.TLS.CA == "" {
+		return
+	}
+	pem, err := ioutil.ReadFile(w.TLS.CA)
+	if err != nil {
+		err = liberr.Wrap(err, "ca", w.TLS.CA)
+		return
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		err = liberr.New("CA not valid.", "ca", w.TLS.CA)
+		return
+	}
+	cfg.ClientCAs = pool
+	if w.TLS.RequireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return
+}
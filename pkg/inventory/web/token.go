@@ -0,0 +1,465 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Default token TTL.
+var TokenTTL = time.Hour
+
+//
+// Params.
+const (
+	// Namespace (route) param.
+	NamespaceParam = "namespace"
+)
+
+//
+// Websocket (sub) protocols.
+// Browser websocket clients cannot set the Authorization header
+// so the (signed) token may be passed as a protocol prefixed by
+// TokenProtocol along with the WatchProtocol which is selected
+// (echoed) by the server. Example (javascript):
+//   new WebSocket(url, ["inventory.watch", "bearer." + token])
+const (
+	// The watch protocol.
+	WatchProtocol = "inventory.watch"
+	// The token protocol prefix.
+	TokenProtocol = "bearer."
+)
+
+//
+// Context keys.
+const (
+	// Validated token (gin context).
+	tokenKey = "web.token"
+)
+
+//
+// Errors.
+var (
+	// The token is malformed or the signature not valid.
+	TokenInvalidErr = errors.New("token not valid")
+	// The token has expired.
+	TokenExpiredErr = errors.New("token expired")
+	// The token has been revoked.
+	TokenRevokedErr = errors.New("token revoked")
+	// The token was not issued (or has expired).
+	TokenNotFoundErr = errors.New("token not found")
+)
+
+//
+// Token scope.
+// Empty (kind and namespace) fields match any.
+type Scope struct {
+	// Kind. Example: VM.
+	Kind string `json:"kind,omitempty"`
+	// Namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// Read-write. Default: read-only.
+	Write bool `json:"write,omitempty"`
+}
+
+//
+// The scope permits access.
+func (s *Scope) Allows(kind, namespace string, write bool) bool {
+	if s.Kind != "" && s.Kind != kind {
+		return false
+	}
+	if s.Namespace != "" && s.Namespace != namespace {
+		return false
+	}
+	if write && !s.Write {
+		return false
+	}
+
+	return true
+}
+
+//
+// Token (claims).
+type Token struct {
+	// Token ID.
+	ID string `json:"id"`
+	// Subject. Example: user or service account.
+	Subject string `json:"sub"`
+	// Scopes.
+	Scopes []Scope `json:"scopes"`
+	// Expiration (unix seconds).
+	Expiration int64 `json:"exp"`
+}
+
+//
+// The token has expired.
+func (t *Token) Expired() bool {
+	return time.Now().Unix() >= t.Expiration
+}
+
+//
+// A scope permits access.
+func (t *Token) Allows(kind, namespace string, write bool) bool {
+	for i := range t.Scopes {
+		if t.Scopes[i].Allows(kind, namespace, write) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Token service.
+// Issues scoped, expiring (HMAC signed) bearer tokens and
+// validates them in the auth middleware so that UI sessions
+// and automation are granted least-privilege access. The kind
+// is determined by the route (see: WebServer.Tokens) and the
+// namespace by the NamespaceParam route param. Routes without
+// the namespace param (Example: cross-namespace lists) match
+// only scopes without a namespace. Write (non-GET) requests
+// must be permitted by a read-write scope. Routes not serving
+// a kind (Example: /metrics) match scopes without a kind.
+// The token is passed in the Authorization (bearer) header or,
+// by websocket clients, as a protocol. See: TokenProtocol. Issued tokens are tracked (and
+// revoked) in memory until expired; tokens issued before a
+// restart are revoked by rotating the secret. A generated
+// secret (not specified) is not persisted so tokens are not
+// valid after a restart.
+// Example:
+//   tokens := &web.TokenService{Secret: secret}
+//   signed, _, err := tokens.Issue(
+//       "ui",
+//       time.Hour,
+//       web.Scope{Kind: "VM", Namespace: "ns1"})
+type TokenService struct {
+	// HMAC (signing) secret.
+	// Default: generated.
+	Secret []byte
+	// Default TTL. Default: TokenTTL.
+	TTL time.Duration
+	// Routes not requiring a token.
+	// Default: HealthRoot, ReadyRoot.
+	Exempt []string
+	// Kinds by route.
+	kinds map[string]string
+	// Issued (not expired) token expiration by ID.
+	issued map[string]int64
+	// Revoked (not expired) token expiration by ID.
+	revoked map[string]int64
+	// Protect the maps and secret.
+	mutex sync.RWMutex
+}
+
+//
+// Issue a token.
+// The TTL (0 = default) is used to set the expiration.
+// Returns the signed token.
+func (r *TokenService) Issue(subject string, ttl time.Duration, scopes ...Scope) (signed string, token Token, err error) {
+	if len(scopes) == 0 {
+		err = liberr.New("scope required.")
+		return
+	}
+	if ttl == 0 {
+		ttl = r.TTL
+	}
+	if ttl == 0 {
+		ttl = TokenTTL
+	}
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	token = Token{
+		ID:         hex.EncodeToString(id),
+		Subject:    subject,
+		Scopes:     scopes,
+		Expiration: time.Now().Add(ttl).Unix(),
+	}
+	claims, err := json.Marshal(token)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	secret, err := r.secret()
+	if err != nil {
+		return
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(claims)
+	signed = encoded + "." + r.sign(secret, encoded)
+	r.mutex.Lock()
+	r.prune()
+	r.issued[token.ID] = token.Expiration
+	r.mutex.Unlock()
+
+	log.V(3).Info(
+		"token: issued.",
+		"id",
+		token.ID,
+		"subject",
+		subject,
+		"ttl",
+		ttl)
+
+	return
+}
+
+//
+// Validate a (signed) token.
+func (r *TokenService) Validate(signed string) (token *Token, err error) {
+	part := strings.Split(signed, ".")
+	if len(part) != 2 {
+		err = liberr.Wrap(TokenInvalidErr)
+		return
+	}
+	secret, err := r.secret()
+	if err != nil {
+		return
+	}
+	if !hmac.Equal([]byte(part[1]), []byte(r.sign(secret, part[0]))) {
+		err = liberr.Wrap(TokenInvalidErr)
+		return
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(part[0])
+	if err != nil {
+		err = liberr.Wrap(TokenInvalidErr)
+		return
+	}
+	token = &Token{}
+	err = json.Unmarshal(claims, token)
+	if err != nil {
+		err = liberr.Wrap(TokenInvalidErr)
+		return
+	}
+	if token.Expired() {
+		err = liberr.Wrap(TokenExpiredErr, "id", token.ID)
+		return
+	}
+	r.mutex.RLock()
+	_, revoked := r.revoked[token.ID]
+	r.mutex.RUnlock()
+	if revoked {
+		err = liberr.Wrap(TokenRevokedErr, "id", token.ID)
+		return
+	}
+
+	return
+}
+
+//
+// Revoke an (issued) token.
+func (r *TokenService) Revoke(id string) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.prune()
+	expiration, found := r.issued[id]
+	if !found {
+		err = liberr.Wrap(TokenNotFoundErr, "id", id)
+		return
+	}
+	delete(r.issued, id)
+	r.revoked[id] = expiration
+
+	log.V(3).Info(
+		"token: revoked.",
+		"id",
+		id)
+
+	return
+}
+
+//
+// Auth middleware.
+// Requests without a valid (bearer) token are rejected (401).
+// Requests not permitted by the token scopes are rejected (403).
+// The token subject is set as the (audit) actor. See: TokenOf().
+func (r *TokenService) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if r.exempt(ctx) {
+			ctx.Next()
+			return
+		}
+		signed, found := r.bearer(ctx)
+		if !found {
+			Fail(ctx, UnauthorizedErr)
+			return
+		}
+		token, err := r.Validate(signed)
+		if err != nil {
+			log.V(3).Info(
+				"token: rejected.",
+				"url",
+				ctx.Request.URL,
+				"reason",
+				err.Error())
+			Fail(ctx, err)
+			return
+		}
+		kind := r.kind(ctx)
+		namespace := ctx.Param(NamespaceParam)
+		write := ctx.Request.Method != http.MethodGet &&
+			ctx.Request.Method != http.MethodHead
+		if !token.Allows(kind, namespace, write) {
+			log.V(3).Info(
+				"token: forbidden.",
+				"id",
+				token.ID,
+				"kind",
+				kind,
+				"namespace",
+				namespace,
+				"write",
+				write)
+			Fail(
+				ctx,
+				liberr.Wrap(
+					ForbiddenErr,
+					"kind",
+					kind,
+					"namespace",
+					namespace,
+					"write",
+					write))
+			return
+		}
+		ctx.Set(tokenKey, token)
+		ctx.Request = ctx.Request.WithContext(
+			model.WithActor(ctx.Request.Context(), token.Subject))
+		ctx.Next()
+	}
+}
+
+//
+// The (signed) bearer token passed in the request.
+// The Authorization header or (websocket) token protocol.
+func (r *TokenService) bearer(ctx *gin.Context) (signed string, found bool) {
+	header := ctx.GetHeader("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		signed = strings.TrimPrefix(header, "Bearer ")
+		found = true
+		return
+	}
+	for _, header := range ctx.Request.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocol = strings.TrimSpace(protocol)
+			if strings.HasPrefix(protocol, TokenProtocol) {
+				signed = strings.TrimPrefix(protocol, TokenProtocol)
+				found = true
+				return
+			}
+		}
+	}
+
+	return
+}
+
+//
+// Index the kinds by route.
+// Called by the WebServer when the routes have been added.
+func (r *TokenService) index(kinds []Kind) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.kinds = map[string]string{}
+	for _, kind := range kinds {
+		for _, route := range kind.Routes {
+			part := strings.SplitN(route, " ", 2)
+			r.kinds[part[len(part)-1]] = kind.Name
+		}
+	}
+}
+
+//
+// The kind served by the (matched) route.
+func (r *TokenService) kind(ctx *gin.Context) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.kinds[ctx.FullPath()]
+}
+
+//
+// The route is exempt.
+func (r *TokenService) exempt(ctx *gin.Context) bool {
+	exempt := r.Exempt
+	if exempt == nil {
+		exempt = []string{HealthRoot, ReadyRoot}
+	}
+	for _, route := range exempt {
+		if ctx.FullPath() == route {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Prune expired tokens.
+// The mutex must be held.
+func (r *TokenService) prune() {
+	if r.issued == nil {
+		r.issued = map[string]int64{}
+	}
+	if r.revoked == nil {
+		r.revoked = map[string]int64{}
+	}
+	now := time.Now().Unix()
+	for _, m := range []map[string]int64{r.issued, r.revoked} {
+		for id, expiration := range m {
+			if now >= expiration {
+				delete(m, id)
+			}
+		}
+	}
+}
+
+//
+// The signing secret.
+// Generated as needed.
+func (r *TokenService) secret() (secret []byte, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.Secret) == 0 {
+		r.Secret = make([]byte, 32)
+		_, err = rand.Read(r.Secret)
+		if err != nil {
+			r.Secret = nil
+			err = liberr.Wrap(err)
+			return
+		}
+	}
+	secret = r.Secret
+	return
+}
+
+//
+// Sign (HMAC) the encoded claims.
+func (r *TokenService) sign(secret []byte, encoded string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+//
+// Get the validated token for the request.
+// Returns nil when not validated.
+func TokenOf(ctx *gin.Context) (token *Token) {
+	if v, found := ctx.Get(tokenKey); found {
+		token, _ = v.(*Token)
+	}
+
+	return
+}
@@ -0,0 +1,220 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTokenRouter(tokens *TokenService) (router *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	router = gin.New()
+	router.Use(tokens.Middleware())
+	ok := func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	}
+	router.GET(HealthRoot, ok)
+	router.GET(ReadyRoot, ok)
+	router.GET("/vms", ok)
+	router.GET("/namespaces/:namespace/vms", ok)
+	router.PUT("/namespaces/:namespace/vms", ok)
+	router.GET("/namespaces/:namespace/hosts", ok)
+	router.GET("/watch", ok)
+	tokens.index(
+		[]Kind{
+			{
+				Name: "VM",
+				Routes: []string{
+					"GET /vms",
+					"GET /namespaces/:namespace/vms",
+					"PUT /namespaces/:namespace/vms",
+				},
+			},
+			{
+				Name: "Host",
+				Routes: []string{
+					"GET /namespaces/:namespace/hosts",
+				},
+			},
+		})
+	return
+}
+
+func testTokenRequest(router *gin.Engine, method, path string, header http.Header) int {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	router.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func bearer(signed string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + signed}}
+}
+
+func TestScopeAllows(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	token := Token{
+		Scopes: []Scope{
+			{Kind: "VM", Namespace: "ns1"},
+			{Kind: "Host", Write: true},
+		},
+	}
+	g.Expect(token.Allows("VM", "ns1", false)).To(gomega.BeTrue())
+	g.Expect(token.Allows("VM", "ns1", true)).To(gomega.BeFalse())
+	g.Expect(token.Allows("VM", "ns2", false)).To(gomega.BeFalse())
+	g.Expect(token.Allows("VM", "", false)).To(gomega.BeFalse())
+	g.Expect(token.Allows("Network", "ns1", false)).To(gomega.BeFalse())
+	g.Expect(token.Allows("Host", "ns2", true)).To(gomega.BeTrue())
+	g.Expect(token.Allows("Host", "", false)).To(gomega.BeTrue())
+	g.Expect((&Token{}).Allows("VM", "", false)).To(gomega.BeFalse())
+	g.Expect((&Scope{}).Allows("VM", "ns1", false)).To(gomega.BeTrue())
+}
+
+func TestTokenMiddleware(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tokens := &TokenService{Secret: []byte("secret")}
+	router := testTokenRouter(tokens)
+	signed, token, err := tokens.Issue(
+		"Elmer",
+		time.Minute,
+		Scope{Kind: "VM", Namespace: "ns1"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(token.Subject).To(gomega.Equal("Elmer"))
+	// exempt.
+	g.Expect(testTokenRequest(router, http.MethodGet, HealthRoot, nil)).To(gomega.Equal(http.StatusOK))
+	g.Expect(testTokenRequest(router, http.MethodGet, ReadyRoot, nil)).To(gomega.Equal(http.StatusOK))
+	// missing.
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", nil)).
+		To(gomega.Equal(http.StatusUnauthorized))
+	// malformed.
+	for _, header := range []http.Header{
+		{"Authorization": {"Basic " + signed}},
+		{"Authorization": {"Bearer"}},
+		bearer(""),
+		bearer("invalid"),
+		bearer(signed + ".invalid"),
+	} {
+		g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", header)).
+			To(gomega.Equal(http.StatusUnauthorized), header.Get("Authorization"))
+	}
+	// valid.
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", bearer(signed))).
+		To(gomega.Equal(http.StatusOK))
+	// bad signature.
+	part := strings.Split(signed, ".")
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", bearer(part[0]+".invalid"))).
+		To(gomega.Equal(http.StatusUnauthorized))
+	other := &TokenService{Secret: []byte("other")}
+	otherSigned, _, err := other.Issue("Elmer", time.Minute, Scope{Kind: "VM", Namespace: "ns1"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", bearer(otherSigned))).
+		To(gomega.Equal(http.StatusUnauthorized))
+	// tampered claims (escalated scope).
+	token.Scopes = []Scope{{Write: true}}
+	claims, _ := json.Marshal(token)
+	tampered := base64.RawURLEncoding.EncodeToString(claims) + "." + part[1]
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", bearer(tampered))).
+		To(gomega.Equal(http.StatusUnauthorized))
+	// scope.
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns2/vms", bearer(signed))).
+		To(gomega.Equal(http.StatusForbidden))
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/hosts", bearer(signed))).
+		To(gomega.Equal(http.StatusForbidden))
+	g.Expect(testTokenRequest(router, http.MethodGet, "/vms", bearer(signed))).
+		To(gomega.Equal(http.StatusForbidden))
+	g.Expect(testTokenRequest(router, http.MethodPut, "/namespaces/ns1/vms", bearer(signed))).
+		To(gomega.Equal(http.StatusForbidden))
+	g.Expect(testTokenRequest(router, http.MethodGet, "/watch", bearer(signed))).
+		To(gomega.Equal(http.StatusForbidden))
+	writer, _, err := tokens.Issue("Elmer", 0, Scope{Kind: "VM", Write: true})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(testTokenRequest(router, http.MethodPut, "/namespaces/ns1/vms", bearer(writer))).
+		To(gomega.Equal(http.StatusOK))
+	g.Expect(testTokenRequest(router, http.MethodGet, "/vms", bearer(writer))).
+		To(gomega.Equal(http.StatusOK))
+	// revoked.
+	err = tokens.Revoke(token.ID)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", bearer(signed))).
+		To(gomega.Equal(http.StatusUnauthorized))
+	err = tokens.Revoke(token.ID)
+	g.Expect(err).ToNot(gomega.BeNil())
+	// expired.
+	expired, _, err := tokens.Issue("Elmer", -time.Minute, Scope{Kind: "VM"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", bearer(expired))).
+		To(gomega.Equal(http.StatusUnauthorized))
+}
+
+func TestTokenExempt(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tokens := &TokenService{
+		Secret: []byte("secret"),
+		Exempt: []string{"/watch"},
+	}
+	router := testTokenRouter(tokens)
+	g.Expect(testTokenRequest(router, http.MethodGet, "/watch", nil)).To(gomega.Equal(http.StatusOK))
+	g.Expect(testTokenRequest(router, http.MethodGet, HealthRoot, nil)).To(gomega.Equal(http.StatusUnauthorized))
+	g.Expect(testTokenRequest(router, http.MethodGet, ReadyRoot, nil)).To(gomega.Equal(http.StatusUnauthorized))
+}
+
+func TestTokenProtocol(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tokens := &TokenService{Secret: []byte("secret")}
+	router := testTokenRouter(tokens)
+	signed, _, err := tokens.Issue("Elmer", time.Minute, Scope{Kind: "VM", Namespace: "ns1"})
+	g.Expect(err).To(gomega.BeNil())
+	protocol := func(value ...string) http.Header {
+		return http.Header{"Sec-Websocket-Protocol": value}
+	}
+	for _, header := range []http.Header{
+		protocol(WatchProtocol + ", " + TokenProtocol + signed),
+		protocol(TokenProtocol + signed + "," + WatchProtocol),
+		protocol(WatchProtocol, TokenProtocol+signed),
+	} {
+		g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", header)).
+			To(gomega.Equal(http.StatusOK), header.Get("Sec-Websocket-Protocol"))
+	}
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns2/vms", protocol(TokenProtocol+signed))).
+		To(gomega.Equal(http.StatusForbidden))
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", protocol(WatchProtocol))).
+		To(gomega.Equal(http.StatusUnauthorized))
+	g.Expect(testTokenRequest(router, http.MethodGet, "/namespaces/ns1/vms", protocol(TokenProtocol+"invalid"))).
+		To(gomega.Equal(http.StatusUnauthorized))
+}
+
+func TestTokenOf(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tokens := &TokenService{Secret: []byte("secret")}
+	router := testTokenRouter(tokens)
+	signed, issued, err := tokens.Issue("Elmer", time.Minute, Scope{Kind: "VM"})
+	g.Expect(err).To(gomega.BeNil())
+	var token *Token
+	router.GET("/token", func(ctx *gin.Context) {
+		token = TokenOf(ctx)
+		ctx.Status(http.StatusOK)
+	})
+	g.Expect(testTokenRequest(router, http.MethodGet, "/token", bearer(signed))).
+		To(gomega.Equal(http.StatusForbidden))
+	g.Expect(token).To(gomega.BeNil())
+	tokens.Exempt = []string{"/token"}
+	g.Expect(testTokenRequest(router, http.MethodGet, "/token", bearer(signed))).
+		To(gomega.Equal(http.StatusOK))
+	g.Expect(token).To(gomega.BeNil())
+	tokens.Exempt = nil
+	unscoped, _, err := tokens.Issue("Elmer", time.Minute, Scope{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(testTokenRequest(router, http.MethodGet, "/token", bearer(unscoped))).
+		To(gomega.Equal(http.StatusOK))
+	g.Expect(token).ToNot(gomega.BeNil())
+	g.Expect(token.Subject).To(gomega.Equal(issued.Subject))
+}
@@ -0,0 +1,38 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/konveyor/controller/pkg/tracing"
+)
+
+//
+// Request tracing middleware.
+// The request context carries the span so that spans
+// started by handlers are linked. The trace propagated
+// by the client (traceparent header) is continued.
+func RequestTracing(ctx *gin.Context) {
+	if !tracing.Enabled() {
+		ctx.Next()
+		return
+	}
+	reqCtx, span := tracing.Start(
+		tracing.Extract(ctx.Request.Context(), ctx.Request.Header),
+		"web.request",
+		tracing.Method,
+		ctx.Request.Method)
+	defer span.End()
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+	ctx.Next()
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	span.Set(
+		tracing.Route,
+		route,
+		tracing.Status,
+		ctx.Writer.Status())
+	if len(ctx.Errors) > 0 {
+		span.Error(ctx.Errors.Last())
+	}
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//
+// Version headers.
+const (
+	// Version (name) that served the request.
+	VersionHeader = "X-API-Version"
+	// The version is deprecated.
+	DeprecationHeader = "Deprecation"
+	// The version will be removed (http-date).
+	SunsetHeader = "Sunset"
+	// Link to the successor version.
+	LinkHeader = "Link"
+)
+
+//
+// Versioned (route) handler.
+// Routes are added to the version route group.
+type VersionHandler interface {
+	// Add routes to the (version) route group.
+	AddVersionRoutes(*gin.RouterGroup)
+}
+
+//
+// API version.
+// Handlers are registered per version and the routes are
+// added to the version group (path prefix). Requests served
+// by a deprecated version include the `Deprecation` header
+// and (when specified) the `Sunset` and `Link` headers.
+// Example:
+//   server.Versions = []web.Version{
+//      {
+//         Name:       "v1",
+//         Deprecated: true,
+//         Successor:  "v2",
+//         Handlers:   []web.VersionHandler{&v1.Handler{}},
+//      },
+//      {
+//         Name:     "v2",
+//         Handlers: []web.VersionHandler{&v2.Handler{}},
+//      },
+//   }
+type Version struct {
+	// Name (path prefix).
+	// Example: v1.
+	Name string
+	// Handlers.
+	Handlers []VersionHandler
+	// The version is deprecated.
+	Deprecated bool
+	// When (optional) the version will be removed.
+	Sunset time.Time
+	// Successor version (name).
+	Successor string
+}
+
+//
+// The version path prefix.
+func (r *Version) Path() string {
+	return "/" + strings.Trim(r.Name, "/")
+}
+
+//
+// Version middleware.
+// Sets the version and deprecation headers.
+func (r *Version) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header(VersionHeader, r.Name)
+		if r.Deprecated {
+			ctx.Header(DeprecationHeader, "true")
+			if !r.Sunset.IsZero() {
+				ctx.Header(
+					SunsetHeader,
+					r.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if r.Successor != "" {
+				successor := Version{Name: r.Successor}
+				ctx.Header(
+					LinkHeader,
+					"<"+successor.Path()+">; rel=\"successor-version\"")
+			}
+		}
+		ctx.Next()
+	}
+}
+
+//
+// Add the version routes.
+// The route group is created with the version middleware
+// and routes added by each handler.
+func (r *Version) addRoutes(e *gin.Engine, h VersionHandler) {
+	group := e.Group(r.Path(), r.Middleware())
+	h.AddVersionRoutes(group)
+}
@@ -1,18 +1,24 @@
 package web
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
 	"github.com/konveyor/controller/pkg/inventory/container"
+	"github.com/konveyor/controller/pkg/inventory/model"
 	"github.com/konveyor/controller/pkg/logging"
+	"net/http"
 	"regexp"
+	"sort"
 	"time"
 )
 
 //
 // Package logger.
-var log = logging.WithName("web")
+var log = logging.WithName(logging.Web)
 
 //
 // Web server
@@ -21,10 +27,31 @@ type WebServer struct {
 	Port int
 	// Allowed CORS origins.
 	AllowedOrigins []string
+	// CORS.
+	CORS struct {
+		// Allowed methods.
+		// Default: GET.
+		AllowedMethods []string
+		// Allowed headers.
+		// Default: Authorization, Origin.
+		AllowedHeaders []string
+		// Headers exposed to the client.
+		ExposedHeaders []string
+		// Preflight (response) caching.
+		// Default: 12h.
+		MaxAge time.Duration
+	}
 	// Reference to the container.
 	Container *container.Container
 	// Handlers
 	Handlers []RequestHandler
+	// Versioned API handlers.
+	Versions []Version
+	// Request limits.
+	Limits Limits
+	// Token (auth) service.
+	// Requests must be authorized when specified.
+	Tokens *TokenService
 	// Compiled CORS origins.
 	allowedOrigins []*regexp.Regexp
 	// TLS.
@@ -35,37 +62,111 @@ type WebServer struct {
 		Certificate string
 		// Key path
 		Key string
+		// Client CA path.
+		// Client certificates are verified when specified.
+		CA string
+		// Client certificates are required (mTLS).
+		RequireClientCert bool
+		// Certificate provider.
+		// Overrides Certificate and Key when specified.
+		Provider CertProvider
 	}
+	// HTTP server.
+	server *http.Server
 }
 
 //
 // Start the web-server.
 // Initializes `gin` with routes and CORS origins.
-// Creates an http server to handle TLS
-func (w *WebServer) Start(middleware ...gin.HandlerFunc) {
+// Request metrics are recorded and request limits enforced.
+// Requests are authorized when the token service is specified.
+// Creates an http server to handle TLS.  The certificate
+// is reloaded when changed (rotated). Returns an error when
+// the TLS configuration is not valid.
+func (w *WebServer) Start(middleware ...gin.HandlerFunc) (err error) {
+	var tlsConfig *tls.Config
+	if w.TLS.Enabled {
+		tlsConfig, err = w.tlsConfig()
+		if err != nil {
+			return
+		}
+	}
 	router := gin.Default()
-	router.Use(cors.New(cors.Config{
-		AllowMethods:     []string{"GET"},
-		AllowHeaders:     []string{"Authorization", "Origin"},
-		AllowOriginFunc:  w.allow,
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	router.Use(cors.New(w.corsConfig()))
+	router.Use(RequestMetrics)
+	router.Use(RequestTracing)
+	router.Use(w.Limits.Middleware())
+	if w.Tokens != nil {
+		router.Use(w.Tokens.Middleware())
+	}
 	for _, h := range middleware {
 		router.Use(h)
 	}
 	w.buildOrigins()
 	w.addRoutes(router)
-	if w.TLS.Enabled {
-		go router.RunTLS(w.address(), w.TLS.Certificate, w.TLS.Key)
-	} else {
-		go router.Run(w.address())
+	w.server = &http.Server{
+		Addr:        w.address(),
+		Handler:     router,
+		ConnContext: withConn,
+		TLSConfig:   tlsConfig,
 	}
+	run := func() {
+		var err error
+		if w.TLS.Enabled {
+			err = w.server.ListenAndServeTLS("", "")
+		} else {
+			err = w.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Trace(err)
+		}
+	}
+
+	go run()
 
 	log.V(3).Info(
 		"web: engine started.",
 		"address",
 		w.address())
+
+	return
+}
+
+//
+// Stop the web-server.
+//   1. Stop accepting connections.
+//   2. Notify watch (websocket) clients that the server
+//      is going away. The last delivered revision is included.
+//   3. Drain in-flight requests until done or the
+//      context deadline is reached. Remaining connections
+//      are then closed.
+func (w *WebServer) Stop(ctx context.Context) (err error) {
+	if w.server == nil {
+		return
+	}
+	mark := time.Now()
+	w.server.SetKeepAlivesEnabled(false)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.server.Shutdown(ctx)
+	}()
+	nWatch := writers.goingAway(w.server)
+	err = <-done
+	if err != nil {
+		_ = w.server.Close()
+		err = liberr.Wrap(err)
+	}
+
+	log.V(3).Info(
+		"web: engine stopped.",
+		"address",
+		w.address(),
+		"watches",
+		nWatch,
+		"duration",
+		time.Since(mark))
+
+	return
 }
 
 //
@@ -82,6 +183,31 @@ func (w *WebServer) address() string {
 	return fmt.Sprintf(":%d", w.Port)
 }
 
+//
+// Build the CORS configuration.
+// Defaults applied as needed.
+func (w *WebServer) corsConfig() (cfg cors.Config) {
+	cfg = cors.Config{
+		AllowMethods:     w.CORS.AllowedMethods,
+		AllowHeaders:     w.CORS.AllowedHeaders,
+		ExposeHeaders:    w.CORS.ExposedHeaders,
+		AllowOriginFunc:  w.allow,
+		AllowCredentials: true,
+		MaxAge:           w.CORS.MaxAge,
+	}
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = []string{http.MethodGet}
+	}
+	if len(cfg.AllowHeaders) == 0 {
+		cfg.AllowHeaders = []string{"Authorization", "Origin"}
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 12 * time.Hour
+	}
+
+	return
+}
+
 //
 // Build a REGEX for each CORS origin.
 func (w *WebServer) buildOrigins() {
@@ -97,10 +223,86 @@ func (w *WebServer) buildOrigins() {
 
 //
 // Add the routes.
+// Versioned routes are added to the version route group.
+// The kinds served by each `ModelHandler` (or versioned handler
+// with Models()) are described (with routes) and reported by
+// the `SchemaHandler`.
 func (w *WebServer) addRoutes(r *gin.Engine) {
+	kinds := []Kind{}
 	for _, h := range w.Handlers {
-		h.AddRoutes(r)
+		handler := h
+		kinds = w.describe(r, kinds, h, func() {
+			handler.AddRoutes(r)
+		})
+	}
+	for i := range w.Versions {
+		version := &w.Versions[i]
+		for _, h := range version.Handlers {
+			handler := h
+			kinds = w.describe(r, kinds, h, func() {
+				version.addRoutes(r, handler)
+			})
+		}
 	}
+	for _, h := range w.Handlers {
+		if schema, cast := h.(*SchemaHandler); cast {
+			schema.kinds = kinds
+		}
+	}
+	if w.Tokens != nil {
+		w.Tokens.index(kinds)
+	}
+}
+
+//
+// Add routes using the function and describe the
+// kinds served by the handler.
+func (w *WebServer) describe(r *gin.Engine, kinds []Kind, h interface{}, add func()) []Kind {
+	before := w.routes(r)
+	add()
+	mh, cast := h.(interface {
+		Models() []model.Model
+	})
+	if !cast {
+		return kinds
+	}
+	added := []string{}
+	for route := range w.routes(r) {
+		if _, found := before[route]; !found {
+			added = append(added, route)
+		}
+	}
+	sort.Strings(added)
+next:
+	for _, m := range mh.Models() {
+		kind := Kind{}
+		err := kind.With(m, added)
+		if err != nil {
+			log.Trace(err)
+			continue
+		}
+		for i := range kinds {
+			if kinds[i].Name == kind.Name {
+				kinds[i].Routes = append(kinds[i].Routes, added...)
+				sort.Strings(kinds[i].Routes)
+				continue next
+			}
+		}
+		kinds = append(kinds, kind)
+	}
+
+	return kinds
+}
+
+//
+// Set of routes (method path) added to the router.
+func (w *WebServer) routes(r *gin.Engine) (routes map[string]bool) {
+	routes = map[string]bool{}
+	for _, rte := range r.Routes() {
+		routes[rte.Method+" "+rte.Path] = true
+	}
+
+	return
 }
 
 //
@@ -0,0 +1,326 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/konveyor/controller/pkg/inventory/model"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//
+// Params.
+const (
+	// Primary key.
+	PkParam = "pk"
+)
+
+//
+// Header.
+const (
+	// Expected (model) revision.
+	IfMatchHeader = "If-Match"
+)
+
+//
+// Errors.
+var (
+	// Revision conflict.
+	ConflictErr = errors.New("revision conflict")
+)
+
+//
+// Writable model (route) handler.
+// Provides POST, PUT and DELETE for a model kind.
+// Models implementing `model.Validator` are validated
+// before written.  Optimistic locking is provided when the
+// model has an `incremented` (revision) field.  The expected
+// revision is passed using the `If-Match` header. Else, the
+// revision included in the (PUT) body is used.
+// Routes:
+//   POST   <Path>
+//   PUT    <Path>/:pk
+//   DELETE <Path>/:pk
+type WriteHandler struct {
+	// DB client.
+	DB model.DB
+	// Model (kind) prototype.
+	Model model.Model
+	// Route (collection) path.
+	Path string
+}
+
+//
+// Add routes.
+func (h *WriteHandler) AddRoutes(r *gin.Engine) {
+	r.POST(h.Path, h.Create)
+	r.PUT(h.Path+"/:"+PkParam, h.Update)
+	r.DELETE(h.Path+"/:"+PkParam, h.Delete)
+}
+
+//
+// Models (kinds) served by the handler.
+func (h *WriteHandler) Models() []model.Model {
+	return []model.Model{h.Model}
+}
+
+//
+// Create (POST) a model.
+func (h *WriteHandler) Create(ctx *gin.Context) {
+	m := h.new()
+	err := ctx.BindJSON(m)
+	if err != nil {
+		return
+	}
+	err = h.validate(m)
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+	err = h.DB.With(func(tx *model.Tx) (err error) {
+		err = tx.Get(model.Clone(m))
+		if err == nil {
+			err = liberr.Wrap(ConflictErr)
+			return
+		}
+		if !errors.Is(err, model.NotFound) {
+			return
+		}
+		err = tx.Insert(m)
+		return
+	})
+	if err != nil {
+		Fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, m)
+}
+
+//
+// Update (PUT) a model.
+func (h *WriteHandler) Update(ctx *gin.Context) {
+	m := h.new()
+	err := ctx.BindJSON(m)
+	if err != nil {
+		return
+	}
+	err = h.setPk(m, ctx.Param(PkParam))
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+	err = h.validate(m)
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+	revision, hasRevision, err := h.revision(ctx, m)
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+	err = h.DB.With(func(tx *model.Tx) (err error) {
+		stored := model.Clone(m)
+		err = tx.Get(stored)
+		if err != nil {
+			return
+		}
+		if hasRevision {
+			err = h.match(stored, revision)
+			if err != nil {
+				return
+			}
+		}
+		h.carryRevision(stored, m)
+		err = tx.Update(m)
+		return
+	})
+	if err != nil {
+		Fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, m)
+}
+
+//
+// Delete (DELETE) a model.
+func (h *WriteHandler) Delete(ctx *gin.Context) {
+	m := h.new()
+	err := h.setPk(m, ctx.Param(PkParam))
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+	revision, hasRevision, err := h.revision(ctx, nil)
+	if err != nil {
+		Fail(ctx, &BadRequest{Err: err})
+		return
+	}
+	err = h.DB.With(func(tx *model.Tx) (err error) {
+		err = tx.Get(m)
+		if err != nil {
+			return
+		}
+		if hasRevision {
+			err = h.match(m, revision)
+			if err != nil {
+				return
+			}
+		}
+		err = tx.Delete(m)
+		return
+	})
+	if err != nil {
+		Fail(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//
+// New model.
+func (h *WriteHandler) new() model.Model {
+	mt := reflect.TypeOf(h.Model)
+	if mt.Kind() == reflect.Ptr {
+		mt = mt.Elem()
+	}
+
+	return reflect.New(mt).Interface().(model.Model)
+}
+
+//
+// Validate the model.
+func (h *WriteHandler) validate(m model.Model) (err error) {
+	if validator, cast := m.(model.Validator); cast {
+		err = validator.Validate()
+	}
+
+	return
+}
+
+//
+// Set the model PK field.
+func (h *WriteHandler) setPk(m model.Model, pk string) (err error) {
+	md, err := model.Inspect(m)
+	if err != nil {
+		return
+	}
+	f := md.PkField()
+	switch f.Value.Kind() {
+	case reflect.String:
+		f.Value.SetString(pk)
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		n, pErr := strconv.ParseInt(pk, 0, f.Value.Type().Bits())
+		if pErr != nil {
+			err = liberr.Wrap(pErr)
+			return
+		}
+		f.Value.SetInt(n)
+	case reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64:
+		n, pErr := strconv.ParseUint(pk, 0, f.Value.Type().Bits())
+		if pErr != nil {
+			err = liberr.Wrap(pErr)
+			return
+		}
+		f.Value.SetUint(n)
+	default:
+		err = liberr.New(
+			"pk type not supported.",
+			"kind",
+			md.Kind,
+			"type",
+			f.Value.Type().String())
+	}
+
+	return
+}
+
+//
+// Get the expected revision.
+// The `If-Match` header has precedence over the
+// revision in the (optional) model.
+func (h *WriteHandler) revision(ctx *gin.Context, m model.Model) (revision int64, found bool, err error) {
+	header := strings.Trim(ctx.GetHeader(IfMatchHeader), `"`)
+	if header != "" {
+		revision, err = strconv.ParseInt(header, 0, 64)
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+		found = true
+		return
+	}
+	if m == nil {
+		return
+	}
+	md, err := model.Inspect(m)
+	if err != nil {
+		return
+	}
+	f := md.IncrementedField()
+	if f == nil {
+		return
+	}
+	revision = f.Value.Int()
+	found = revision > 0
+
+	return
+}
+
+//
+// Match the stored revision.
+func (h *WriteHandler) match(stored model.Model, revision int64) (err error) {
+	md, err := model.Inspect(stored)
+	if err != nil {
+		return
+	}
+	f := md.IncrementedField()
+	if f == nil {
+		return
+	}
+	if f.Value.Int() != revision {
+		err = liberr.Wrap(
+			ConflictErr,
+			"kind",
+			md.Kind,
+			"stored",
+			f.Value.Int(),
+			"expected",
+			revision)
+	}
+
+	return
+}
+
+//
+// Carry the stored revision forward to the updated
+// model. The revision is incremented on update.
+func (h *WriteHandler) carryRevision(stored, updated model.Model) {
+	mdA, err := model.Inspect(stored)
+	if err != nil {
+		return
+	}
+	mdB, err := model.Inspect(updated)
+	if err != nil {
+		return
+	}
+	fA := mdA.IncrementedField()
+	fB := mdB.IncrementedField()
+	if fA != nil && fB != nil {
+		fB.Value.SetInt(fA.Value.Int())
+	}
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sync"
+	"time"
+)
+
+//
+// Build an error fingerprint.
+// Based on the (root) error type and the wrapped stack.
+// The message is used when the error is not wrapped.
+func Fingerprint(err error) string {
+	h := sha1.New()
+	root := err
+	if le, wrapped := err.(*liberr.Error); wrapped {
+		root = le.Unwrap()
+		_, _ = h.Write([]byte(le.Stack()))
+	} else {
+		_, _ = h.Write([]byte(err.Error()))
+	}
+	_, _ = h.Write([]byte(fmt.Sprintf("%T", root)))
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+//
+// Deduplicating logger.
+// Errors (by fingerprint) are logged once per window.
+// Duplicates within the window are suppressed and a
+// `repeated N times` summary is logged when the
+// window has elapsed.
+type DedupLogger struct {
+	*Logger
+	// The dedup window.
+	Window time.Duration
+	// Suppressed errors by fingerprint.
+	suppressed map[string]*suppressed
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// Suppressed error.
+type suppressed struct {
+	// The (last) error.
+	err error
+	// Number of duplicates suppressed.
+	count int
+}
+
+//
+// Get a deduplicating logger.
+func (l *Logger) Dedup(window time.Duration) *DedupLogger {
+	return &DedupLogger{
+		Logger:     l,
+		Window:     window,
+		suppressed: map[string]*suppressed{},
+	}
+}
+
+//
+// Logs an error.
+// Duplicates within the window are suppressed.
+func (l *DedupLogger) Error(err error, message string, kvpair ...interface{}) {
+	if err == nil {
+		return
+	}
+	fingerprint := Fingerprint(err)
+	l.mutex.Lock()
+	entry, found := l.suppressed[fingerprint]
+	if found {
+		entry.err = err
+		entry.count++
+		l.mutex.Unlock()
+		return
+	}
+	l.suppressed[fingerprint] = &suppressed{err: err}
+	l.mutex.Unlock()
+	time.AfterFunc(
+		l.Window,
+		func() {
+			l.flush(fingerprint)
+		})
+
+	l.Logger.Error(err, message, kvpair...)
+}
+
+//
+// Logs an error without a description.
+func (l *DedupLogger) Trace(err error, kvpair ...interface{}) {
+	l.Error(err, None, kvpair...)
+}
+
+//
+// Window elapsed.
+// Log the summary of suppressed duplicates.
+func (l *DedupLogger) flush(fingerprint string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	entry, found := l.suppressed[fingerprint]
+	delete(l.suppressed, fingerprint)
+	if !found || entry.count == 0 {
+		return
+	}
+
+	l.Logger.Info(
+		fmt.Sprintf("error repeated %d times.", entry.count),
+		"fingerprint",
+		fingerprint,
+		"repeated",
+		entry.count,
+		Error,
+		entry.err.Error())
+}
@@ -8,6 +8,21 @@ import (
 	"os"
 )
 
+//
+// Backends.
+const (
+	ZapBackend     = "zap"
+	DiscardBackend = "discard"
+)
+
+//
+// Builders by backend.
+// Selected at init by Settings.Backend.
+var Backends = map[string]Builder{
+	ZapBackend:     &ZapBuilder{},
+	DiscardBackend: &LogrBuilder{Logger: logr.Discard()},
+}
+
 //
 // Builder.
 type Builder interface {
@@ -31,7 +46,7 @@ func (b *ZapBuilder) New() (logger logr.Logger) {
 		zap.ErrorOutput(sinker),
 		zap.AddCallerSkip(1),
 	}
-	if Settings.Development {
+	if current().Development {
 		cfg := zap.NewDevelopmentEncoderConfig()
 		encoder = zapcore.NewConsoleEncoder(cfg)
 		options = append(options, zap.Development())
@@ -52,7 +67,33 @@ func (b *ZapBuilder) New() (logger logr.Logger) {
 //
 // Debug logger.
 func (b *ZapBuilder) V(level int, in logr.Logger) (l logr.Logger) {
-	if Settings.atDebug(level) {
+	if current().atDebug(level) {
+		l = in.V(1)
+	} else {
+		l = in.V(0)
+	}
+
+	return
+}
+
+//
+// Logr builder.
+// Delegates to an existing logr logger (backend).
+type LogrBuilder struct {
+	// The real logger.
+	Logger logr.Logger
+}
+
+//
+// Build new logger.
+func (b *LogrBuilder) New() logr.Logger {
+	return b.Logger
+}
+
+//
+// Debug logger.
+func (b *LogrBuilder) V(level int, in logr.Logger) (l logr.Logger) {
+	if current().atDebug(level) {
 		l = in.V(1)
 	} else {
 		l = in.V(0)
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+//
+// Structured (standard) fields.
+const (
+	// The controller name.
+	Controller = "controller"
+	// The resource (CR) kind.
+	Kind = "kind"
+	// The (model) primary key.
+	PK = "pk"
+	// The reconcile ID.
+	// Correlates entries logged by a reconcile.
+	ReconcileID = "reconcileID"
+)
+
+//
+// Get a logger for a reconcile.
+// The standard fields are included along with a
+// generated reconcile ID.
+func (l *Logger) ForReconcile(controller, kind, name string, kvpair ...interface{}) *Logger {
+	kvpair = append(
+		[]interface{}{
+			Controller,
+			controller,
+			Kind,
+			kind,
+			"name",
+			name,
+			ReconcileID,
+			NewReconcileID(),
+		},
+		kvpair...)
+
+	return l.WithValues(kvpair...).(*Logger)
+}
+
+//
+// Build a (random) reconcile ID.
+func NewReconcileID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+//
+// Reconcile ID (context) key.
+type reconcileIDKey struct{}
+
+//
+// Build a context carrying the reconcile ID.
+// The ID is propagated to DB (tracing) spans, watch
+// events and logs so that a change may be correlated
+// across subsystems.
+func WithReconcileID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, reconcileIDKey{}, id)
+}
+
+//
+// Get the reconcile ID carried by the context.
+// Returns "" when not found.
+func ReconcileIDOf(ctx context.Context) (id string) {
+	if ctx == nil {
+		return
+	}
+	id, _ = ctx.Value(reconcileIDKey{}).(string)
+	return
+}
+
+//
+// Get a logger for the context.
+// The reconcile ID is included when carried by the context.
+func (l *Logger) ForContext(ctx context.Context) *Logger {
+	id := ReconcileIDOf(ctx)
+	if id == "" {
+		return l
+	}
+
+	return l.WithValues(ReconcileID, id).(*Logger)
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//
+// Subsystems.
+const (
+	Model      = "model"
+	Web        = "web"
+	Container  = "container"
+	FileBacked = "filebacked"
+)
+
+//
+// Levels by subsystem.
+// Adjustable at runtime.
+var levels = levelMap{}
+
+//
+// Set the level for a subsystem (logger name).
+func SetLevel(name string, level int) {
+	levels.set(name, level)
+}
+
+//
+// Reset (remove) the level for a subsystem.
+// The Settings.Level is used.
+func ResetLevel(name string) {
+	levels.reset(name)
+}
+
+//
+// Get the effective level for a subsystem (logger name).
+func GetLevel(name string) int {
+	return levels.get(name)
+}
+
+//
+// Get the levels set by subsystem.
+func Levels() map[string]int {
+	return levels.list()
+}
+
+//
+// Levels by subsystem.
+// Read (on every log call) without locking. The map
+// is replaced (copy on write) when changed.
+type levelMap struct {
+	// Levels by name (map[string]int).
+	content atomic.Value
+	// Serialize writers.
+	mutex sync.Mutex
+}
+
+//
+// Set the level.
+func (r *levelMap) set(name string, level int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	content := r.list()
+	content[name] = level
+	r.content.Store(content)
+}
+
+//
+// Reset the level.
+func (r *levelMap) reset(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	content := r.list()
+	delete(content, name)
+	r.content.Store(content)
+}
+
+//
+// Get the effective level.
+// Matched by name, then by subsystem. The subsystem
+// is the name prefix delimited by `|`. Otherwise, the
+// Settings.Level is returned.
+func (r *levelMap) get(name string) int {
+	content, _ := r.content.Load().(map[string]int)
+	if level, found := content[name]; found {
+		return level
+	}
+	subsystem := strings.SplitN(name, "|", 2)[0]
+	if level, found := content[subsystem]; found {
+		return level
+	}
+
+	return current().Level
+}
+
+//
+// List (copy) the levels.
+func (r *levelMap) list() (list map[string]int) {
+	content, _ := r.content.Load().(map[string]int)
+	list = map[string]int{}
+	for name, level := range content {
+		list[name] = level
+	}
+
+	return
+}
@@ -3,8 +3,9 @@ package logging
 import (
 	"github.com/go-logr/logr"
 	liberr "github.com/konveyor/controller/pkg/error"
-	"os"
-	"strconv"
+	"github.com/konveyor/controller/pkg/settings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -12,33 +13,89 @@ const (
 	Error = "error"
 	None  = ""
 )
+
+//
+// Settings keys.
 const (
-	EnvDevelopment = "LOG_DEVELOPMENT"
-	EnvLevel       = "LOG_LEVEL"
+	KeyDevelopment      = "log.development"
+	KeyLevel            = "log.level"
+	KeyBackend          = "log.backend"
+	KeySampleFirst      = "log.sample.first"
+	KeySampleThereafter = "log.sample.thereafter"
+)
+
+//
+// Environment variables (for the settings keys).
+const (
+	EnvDevelopment      = "LOG_DEVELOPMENT"
+	EnvLevel            = "LOG_LEVEL"
+	EnvBackend          = "LOG_BACKEND"
+	EnvSampleFirst      = "LOG_SAMPLE_FIRST"
+	EnvSampleThereafter = "LOG_SAMPLE_THEREAFTER"
 )
 
 //
 // Settings.
+// Loaded at init. May be modified before logging starts.
+// Settings reloaded (changed) at runtime are published
+// (atomically) and used instead. See: Current().
 var Settings _Settings
 
+//
+// Settings published when reloaded.
+var published atomic.Value
+
 func init() {
 	Settings.Load()
+	settings.Global.Subscribe(
+		func(_ *settings.Settings, _ []string) {
+			reloaded := &_Settings{}
+			reloaded.Load()
+			published.Store(reloaded)
+		},
+		"log.")
+}
+
+//
+// The current (effective) settings.
+func Current() _Settings {
+	return *current()
+}
+
+//
+// The current (effective) settings.
+// The published settings or the Settings.
+func current() *_Settings {
+	if reloaded, cast := published.Load().(*_Settings); cast {
+		return reloaded
+	}
+
+	return &Settings
 }
 
 //
 // Logger factory.
+// Selected by backend.
 var Factory Builder
 
 func init() {
-	Factory = &ZapBuilder{}
+	Factory = Backends[Settings.Backend]
+	if Factory == nil {
+		Factory = &ZapBuilder{}
+	}
 }
 
+//
+// Error sampler.
+var errSampler sampler
+
 //
 // Logger
 // Delegates functionality to the wrapped `Real` logger.
 // Provides:
 //   - Provides a `Trace()` method for convenience and brevity.
 //   - Handles wrapped errors.
+//   - Forwards (tees) records to sinks.
 type Logger struct {
 	// Real (wrapped) logger.
 	Real logr.Logger
@@ -46,14 +103,17 @@ type Logger struct {
 	name string
 	// Level.
 	level int
+	// Values (forwarded to sinks).
+	values []interface{}
 }
 
 //
 // Get a named logger.
 func WithName(name string, kvpair ...interface{}) *Logger {
 	l := &Logger{
-		Real: Factory.New(),
-		name: name,
+		Real:   Factory.New(),
+		name:   name,
+		values: kvpair,
 	}
 	l.Real = l.Real.WithValues(kvpair...)
 	l.Real = l.Real.WithName(name)
@@ -64,8 +124,9 @@ func WithName(name string, kvpair ...interface{}) *Logger {
 //
 // Logs at info.
 func (l *Logger) Info(message string, kvpair ...interface{}) {
-	if Settings.allowed(l.level) {
+	if l.allowed() {
 		l.Real.Info(message, kvpair...)
+		l.forward(message, nil, kvpair)
 	}
 }
 
@@ -75,7 +136,10 @@ func (l *Logger) Error(err error, message string, kvpair ...interface{}) {
 	if err == nil {
 		return
 	}
-	if !Settings.allowed(l.level) {
+	if !l.allowed() {
+		return
+	}
+	if !errSampler.allowed(current().Sampling, l.name+message+err.Error()) {
 		return
 	}
 	le, wrapped := err.(*liberr.Error)
@@ -95,6 +159,7 @@ func (l *Logger) Error(err error, message string, kvpair ...interface{}) {
 			le.Stack())
 
 		l.Real.Info(message, kvpair...)
+		l.forward(message, le, kvpair)
 		return
 	}
 	if wErr, wrapped := err.(interface {
@@ -107,6 +172,7 @@ func (l *Logger) Error(err error, message string, kvpair ...interface{}) {
 	}
 
 	l.Real.Error(err, message, kvpair...)
+	l.forward(message, err, kvpair)
 }
 
 //
@@ -125,9 +191,10 @@ func (l *Logger) Enabled() bool {
 // Get logger with verbosity level.
 func (l *Logger) V(level int) logr.InfoLogger {
 	return &Logger{
-		Real:  Factory.V(level, l.Real),
-		name:  l.name,
-		level: level,
+		Real:   Factory.V(level, l.Real),
+		name:   l.name,
+		level:  level,
+		values: l.values,
 	}
 }
 
@@ -135,22 +202,45 @@ func (l *Logger) V(level int) logr.InfoLogger {
 // Get logger with name.
 func (l *Logger) WithName(name string) logr.Logger {
 	return &Logger{
-		Real:  l.Real.WithName(name),
-		name:  name,
-		level: l.level,
+		Real:   l.Real.WithName(name),
+		name:   name,
+		level:  l.level,
+		values: l.values,
 	}
 }
 
 //
 // Get logger with values.
 func (l *Logger) WithValues(kvpair ...interface{}) logr.Logger {
+	values := make([]interface{}, 0, len(l.values)+len(kvpair))
+	values = append(values, l.values...)
+	values = append(values, kvpair...)
 	return &Logger{
-		Real:  l.Real.WithValues(kvpair...),
-		name:  l.name,
-		level: l.level,
+		Real:   l.Real.WithValues(kvpair...),
+		name:   l.name,
+		level:  l.level,
+		values: values,
 	}
 }
 
+//
+// Forward (tee) the record to the sinks.
+func (l *Logger) forward(message string, err error, kvpair []interface{}) {
+	sinks.forward(func() *Record {
+		fields := make([]interface{}, 0, len(l.values)+len(kvpair))
+		fields = append(fields, l.values...)
+		fields = append(fields, kvpair...)
+		return newRecord(l.name, l.level, message, err, fields)
+	})
+}
+
+//
+// The level is at (or above) the level
+// set for the subsystem.
+func (l *Logger) allowed() bool {
+	return GetLevel(l.name) >= l.level
+}
+
 //
 // Package settings.
 type _Settings struct {
@@ -163,30 +253,32 @@ type _Settings struct {
 	// Info level threshold.
 	// Higher level increases verbosity.
 	Level int
+	// Backend (name).
+	Backend string
+	// Error sampling.
+	Sampling Sampling
 }
 
 //
 // Determine development logger.
 func (r *_Settings) Load() {
 	r.DebugThreshold = 4
-	if s, found := os.LookupEnv(EnvDevelopment); found {
-		bv, err := strconv.ParseBool(s)
-		if err == nil {
-			r.Development = bv
-		}
+	r.Sampling.Tick = time.Second
+	s := settings.Global
+	s.Default(KeyBackend, ZapBackend)
+	r.Backend = s.String(KeyBackend)
+	if b, err := s.Bool(KeyDevelopment); err == nil {
+		r.Development = b
 	}
-	if s, found := os.LookupEnv(EnvLevel); found {
-		n, err := strconv.ParseInt(s, 10, 8)
-		if err == nil {
-			r.Level = int(n)
-		}
+	if n, err := s.Int(KeyLevel); err == nil {
+		r.Level = n
+	}
+	if n, err := s.Int(KeySampleFirst); err == nil {
+		r.Sampling.First = n
+	}
+	if n, err := s.Int(KeySampleThereafter); err == nil {
+		r.Sampling.Thereafter = n
 	}
-}
-
-//
-// The level is at (or above) the level setting.
-func (r *_Settings) allowed(level int) bool {
-	return r.Level >= level
 }
 
 //
@@ -1,11 +1,16 @@
 package logging
 
 import (
+	"context"
 	"errors"
 	"github.com/go-logr/logr"
 	liberr "github.com/konveyor/controller/pkg/error"
 	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net"
+	"sync"
 	"testing"
+	"time"
 )
 
 type entry struct {
@@ -35,9 +40,13 @@ type fake struct {
 	entry  []entry
 	values []interface{}
 	name   string
+	// Entries may be written by timer goroutines.
+	mutex sync.Mutex
 }
 
 func (l *fake) Info(message string, kvpair ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	l.entry = append(
 		l.entry,
 		entry{
@@ -47,6 +56,8 @@ func (l *fake) Info(message string, kvpair ...interface{}) {
 }
 
 func (l *fake) Error(err error, message string, kvpair ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	l.entry = append(
 		l.entry,
 		entry{
@@ -56,6 +67,14 @@ func (l *fake) Error(err error, message string, kvpair ...interface{}) {
 		})
 }
 
+//
+// Logged entries (copy).
+func (l *fake) entries() []entry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]entry{}, l.entry...)
+}
+
 func (l *fake) Enabled() bool {
 	return true
 }
@@ -204,3 +223,216 @@ func TestFake(t *testing.T) {
 	g.Expect(log3.(*Logger).name).To(gomega.Equal("another"))
 	g.Expect(log3.(*Logger).level).To(gomega.Equal(log3.(*Logger).level))
 }
+
+func TestSampling(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	Factory = &fakeBuilder{}
+	Settings.Level = 0
+	Settings.Sampling = Sampling{
+		Tick:       time.Hour,
+		First:      2,
+		Thereafter: 3,
+	}
+	defer func() {
+		Settings.Sampling = Sampling{}
+	}()
+
+	log := WithName("sampling")
+	f := log.Real.(*fake)
+	for i := 0; i < 8; i++ {
+		log.Trace(errors.New("E"))
+	}
+	// 1, 2, 5, 8.
+	g.Expect(len(f.entry)).To(gomega.Equal(4))
+	log.Trace(errors.New("F"))
+	g.Expect(len(f.entry)).To(gomega.Equal(5))
+}
+
+func TestForReconcile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	Factory = &fakeBuilder{}
+
+	log := WithName("reconcile").ForReconcile("plan", "Plan", "test", PK, "1")
+	f := log.Real.(*fake)
+	g.Expect(len(f.values)).To(gomega.Equal(10))
+	g.Expect(f.values[0]).To(gomega.Equal(Controller))
+	g.Expect(f.values[6]).To(gomega.Equal(ReconcileID))
+	g.Expect(f.values[7]).ToNot(gomega.BeEmpty())
+	g.Expect(f.values[8]).To(gomega.Equal(PK))
+}
+
+func TestReconcileContext(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	Factory = &fakeBuilder{}
+
+	g.Expect(ReconcileIDOf(context.TODO())).To(gomega.BeEmpty())
+	ctx := WithReconcileID(context.TODO(), "1234")
+	g.Expect(ReconcileIDOf(ctx)).To(gomega.Equal("1234"))
+	log := WithName("reconcile")
+	g.Expect(log.ForContext(context.TODO())).To(gomega.BeIdenticalTo(log))
+	f := log.ForContext(ctx).Real.(*fake)
+	g.Expect(f.values).To(gomega.Equal([]interface{}{ReconcileID, "1234"}))
+}
+
+func TestLevels(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	Factory = &fakeBuilder{}
+	Settings.Level = 0
+	defer ResetLevel(Model)
+
+	log := WithName(Model + "|db")
+	f := log.V(3).(*Logger).Real.(*fake)
+	log.V(3).Info("hidden")
+	g.Expect(len(f.entry)).To(gomega.Equal(0))
+	// Subsystem.
+	SetLevel(Model, 3)
+	g.Expect(GetLevel(Model + "|db")).To(gomega.Equal(3))
+	g.Expect(GetLevel(Web)).To(gomega.Equal(0))
+	debug := log.V(3)
+	debug.Info("shown")
+	g.Expect(len(debug.(*Logger).Real.(*fake).entry)).To(gomega.Equal(1))
+	g.Expect(Levels()).To(gomega.Equal(map[string]int{Model: 3}))
+	// Reset.
+	ResetLevel(Model)
+	g.Expect(GetLevel(Model)).To(gomega.Equal(0))
+}
+
+func TestDedup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	Factory = &fakeBuilder{}
+	Settings.Level = 0
+
+	log := WithName("dedup").Dedup(50 * time.Millisecond)
+	f := log.Real.(*fake)
+	for i := 0; i < 5; i++ {
+		log.Trace(liberr.New("A"))
+	}
+	log.Trace(errors.New("B"))
+	g.Expect(len(f.entries())).To(gomega.Equal(2))
+	g.Expect(Fingerprint(errors.New("B"))).ToNot(
+		gomega.Equal(Fingerprint(errors.New("C"))))
+	// Summary.
+	g.Eventually(func() int {
+		return len(f.entries())
+	}).Should(gomega.Equal(3))
+	g.Expect(f.entries()[2].message).To(gomega.Equal("error repeated 4 times."))
+	// New window.
+	log.Trace(liberr.New("A"))
+	g.Expect(len(f.entries())).To(gomega.Equal(4))
+}
+
+type blockedSink struct {
+	released chan struct{}
+}
+
+func (r *blockedSink) Write(*Record) error {
+	<-r.released
+	return nil
+}
+
+func (r *blockedSink) Close() error {
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	Factory = &fakeBuilder{}
+	Settings.Level = 0
+	defer CloseSinks()
+
+	memory := &MemorySink{}
+	AddSink("memory", memory)
+	g.Expect(SinkNames()).To(gomega.Equal([]string{"memory"}))
+	log := WithName("sink", "a", 1).WithValues("b", 2).(*Logger)
+	log.Info("hello", "c", 3)
+	log.Trace(liberr.New("failed", "d", 4))
+	log.V(5).Info("hidden")
+	RemoveSink("memory")
+	records := memory.Records()
+	g.Expect(len(records)).To(gomega.Equal(2))
+	g.Expect(records[0].Logger).To(gomega.Equal("sink"))
+	g.Expect(records[0].Message).To(gomega.Equal("hello"))
+	g.Expect(records[0].Fields).To(gomega.Equal(
+		map[string]interface{}{"a": 1, "b": 2, "c": 3}))
+	g.Expect(records[1].Error).To(gomega.Equal("failed"))
+	g.Expect(records[1].Fields["d"]).To(gomega.Equal(4))
+	// Dropped.
+	SinkBuffer = 1
+	defer func() {
+		SinkBuffer = 1000
+	}()
+	blocked := &blockedSink{released: make(chan struct{})}
+	AddSink("blocked", blocked)
+	for i := 0; i < 5; i++ {
+		log.Info("hello")
+	}
+	close(blocked.released)
+	RemoveSink("blocked")
+	dropped := testutil.ToFloat64(DroppedCounter.WithLabelValues("blocked", DropFull))
+	g.Expect(dropped >= 3).To(gomega.BeTrue())
+	g.Expect(SinkNames()).To(gomega.BeEmpty())
+}
+
+func TestSinkDrain(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	Factory = &fakeBuilder{}
+	Settings.Level = 0
+	SinkDrain = 50 * time.Millisecond
+	defer func() {
+		SinkDrain = 5 * time.Second
+	}()
+	counter := DroppedCounter.WithLabelValues("drained", DropDrain)
+	prior := testutil.ToFloat64(counter)
+	blocked := &blockedSink{released: make(chan struct{})}
+	AddSink("drained", blocked)
+	log := WithName("sink")
+	for i := 0; i < 5; i++ {
+		log.Info("hello")
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(blocked.released)
+	}()
+	mark := time.Now()
+	RemoveSink("drained")
+	g.Expect(time.Since(mark) < time.Second).To(gomega.BeTrue())
+	dropped := testutil.ToFloat64(counter) - prior
+	g.Expect(dropped).To(gomega.Equal(float64(4)))
+}
+
+func TestSocketBackoff(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(gomega.BeNil())
+	address := listener.Addr().String()
+	_ = listener.Close()
+	sink := &SocketSink{Network: "tcp", Address: address}
+	defer func() {
+		_ = sink.Close()
+	}()
+	err = sink.Write(&Record{Message: "hello"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(sink.conn.failed).To(gomega.Equal(1))
+	// Not dialed until the retry delay has elapsed.
+	err = sink.Write(&Record{Message: "hello"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(sink.conn.failed).To(gomega.Equal(1))
+	// Reconnected.
+	listener, err = net.Listen("tcp", address)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = listener.Close()
+	}()
+	sink.conn.next = time.Time{}
+	err = sink.Write(&Record{Message: "hello"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(sink.conn.failed).To(gomega.Equal(0))
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"sync"
+	"time"
+)
+
+//
+// Error sampling.
+// Within each tick, the first N occurrences of an error
+// are logged and thereafter, every Mth occurrence.
+type Sampling struct {
+	// The sampling interval.
+	Tick time.Duration
+	// The number of (identical) errors logged per tick.
+	// Zero=disabled.
+	First int
+	// Then log every Mth error. Zero=none.
+	Thereafter int
+}
+
+//
+// Error sampler.
+type sampler struct {
+	// Counts by key.
+	counts map[string]int
+	// Tick started.
+	started time.Time
+	// Protect the map.
+	mutex sync.Mutex
+}
+
+//
+// The error (key) should be logged.
+func (r *sampler) allowed(sampling Sampling, key string) bool {
+	if sampling.First < 1 {
+		return true
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := time.Now()
+	if r.counts == nil || now.Sub(r.started) > sampling.Tick {
+		r.counts = map[string]int{}
+		r.started = now
+	}
+	r.counts[key]++
+	n := r.counts[key]
+	if n <= sampling.First {
+		return true
+	}
+	if sampling.Thereafter > 0 {
+		return (n-sampling.First)%sampling.Thereafter == 0
+	}
+
+	return false
+}
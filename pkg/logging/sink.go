@@ -0,0 +1,610 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/prometheus/client_golang/prometheus"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// Sink defaults.
+var (
+	// Number of records buffered (by sink).
+	// Records are dropped when the buffer is full.
+	SinkBuffer = 1000
+	// Socket (dial and write) timeout.
+	SinkTimeout = time.Second * 5
+	// Delay before the socket is reconnected after a failed
+	// connect. Doubled for each failed attempt.
+	SinkRetry = time.Second
+	// Max delay before the socket is reconnected.
+	SinkMaxRetry = time.Minute
+	// Time allowed to deliver buffered records when a
+	// sink is removed (or replaced) or the sinks closed.
+	SinkDrain = time.Second * 5
+)
+
+//
+// Drop reasons.
+const (
+	// The buffer is full.
+	DropFull = "full"
+	// The sink failed to write the record.
+	DropFailed = "failed"
+	// Not delivered before the drain deadline.
+	DropDrain = "drain"
+)
+
+//
+// Sink metrics.
+// Registered with the (web) metrics registry.
+var (
+	// Dropped records by sink and reason.
+	DroppedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_sink_dropped_records_total",
+			Help: "Number of log records dropped by sinks.",
+		},
+		[]string{"sink", "reason"})
+)
+
+//
+// Log record.
+// Forwarded to sinks.
+type Record struct {
+	// Logged.
+	Time time.Time `json:"time"`
+	// Logger name.
+	Logger string `json:"logger,omitempty"`
+	// Verbosity level.
+	Level int `json:"level"`
+	// Message.
+	Message string `json:"message"`
+	// Error description.
+	Error string `json:"error,omitempty"`
+	// Fields (key/value pairs).
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+//
+// Log (forwarding) sink.
+// Records are delivered (in order) by a goroutine for each
+// sink so that a slow or failed sink does not block logging.
+type Sink interface {
+	// Write the record.
+	Write(record *Record) error
+	// Close the sink.
+	Close() error
+}
+
+//
+// Add a (named) sink.
+// Replaces (and closes) the sink with the same name.
+func AddSink(name string, sink Sink) {
+	sinks.add(name, sink)
+}
+
+//
+// Remove (and close) a sink.
+// Buffered records are delivered until the drain
+// deadline. See: SinkDrain.
+func RemoveSink(name string) {
+	sinks.remove(name)
+}
+
+//
+// Remove (and close) all sinks.
+// Buffered records are delivered until the drain
+// deadline. See: SinkDrain.
+func CloseSinks() {
+	sinks.close()
+}
+
+//
+// Sink names.
+func SinkNames() []string {
+	return sinks.names()
+}
+
+//
+// Sinks.
+var sinks = sinkSet{}
+
+//
+// Sinks by name.
+type sinkSet struct {
+	// Workers by name.
+	content map[string]*sinkWorker
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Add a sink.
+func (r *sinkSet) add(name string, sink Sink) {
+	r.mutex.Lock()
+	if r.content == nil {
+		r.content = map[string]*sinkWorker{}
+	}
+	replaced := r.content[name]
+	w := &sinkWorker{
+		name:  name,
+		sink:  sink,
+		queue: make(chan *Record, SinkBuffer),
+	}
+	r.content[name] = w
+	w.start()
+	r.mutex.Unlock()
+	if replaced != nil {
+		replaced.stop(time.Now().Add(SinkDrain))
+	}
+}
+
+//
+// Remove a sink.
+func (r *sinkSet) remove(name string) {
+	r.mutex.Lock()
+	w := r.content[name]
+	delete(r.content, name)
+	r.mutex.Unlock()
+	if w != nil {
+		w.stop(time.Now().Add(SinkDrain))
+	}
+}
+
+//
+// Remove all sinks.
+// The sinks are drained concurrently (same deadline).
+func (r *sinkSet) close() {
+	r.mutex.Lock()
+	removed := r.content
+	r.content = nil
+	r.mutex.Unlock()
+	deadline := time.Now().Add(SinkDrain)
+	stopped := sync.WaitGroup{}
+	for _, w := range removed {
+		stopped.Add(1)
+		go func(w *sinkWorker) {
+			defer stopped.Done()
+			w.stop(deadline)
+		}(w)
+	}
+	stopped.Wait()
+}
+
+//
+// Sink names (sorted).
+func (r *sinkSet) names() (list []string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	list = []string{}
+	for name := range r.content {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+
+	return
+}
+
+//
+// Forward (tee) the record to the sinks.
+// The record is built only when sinks have been added.
+func (r *sinkSet) forward(build func() *Record) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if len(r.content) == 0 {
+		return
+	}
+	record := build()
+	for _, w := range r.content {
+		w.put(record)
+	}
+}
+
+//
+// Sink (delivery) worker.
+type sinkWorker struct {
+	// Sink name.
+	name string
+	// Sink.
+	sink Sink
+	// Buffered records.
+	queue chan *Record
+	// Closed when the drain deadline has passed.
+	expired chan struct{}
+	// Closed when delivery (goroutine) has ended.
+	ended chan struct{}
+}
+
+//
+// Start delivery.
+func (w *sinkWorker) start() {
+	w.expired = make(chan struct{})
+	w.ended = make(chan struct{})
+	go w.run()
+}
+
+//
+// Stop delivery.
+// Buffered records are delivered until the deadline; records
+// not delivered by then are dropped. The sink is closed.
+func (w *sinkWorker) stop(deadline time.Time) {
+	close(w.queue)
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-w.ended:
+	case <-timer.C:
+		close(w.expired)
+		<-w.ended
+	}
+	_ = w.sink.Close()
+}
+
+//
+// Queue the record.
+// Dropped when the buffer is full.
+func (w *sinkWorker) put(record *Record) {
+	select {
+	case w.queue <- record:
+	default:
+		DroppedCounter.WithLabelValues(w.name, DropFull).Inc()
+	}
+}
+
+//
+// Delivery (main) loop.
+func (w *sinkWorker) run() {
+	defer close(w.ended)
+	for record := range w.queue {
+		select {
+		case <-w.expired:
+			DroppedCounter.WithLabelValues(w.name, DropDrain).Inc()
+			continue
+		default:
+		}
+		err := w.sink.Write(record)
+		if err != nil {
+			DroppedCounter.WithLabelValues(w.name, DropFailed).Inc()
+		}
+	}
+}
+
+//
+// Build a record.
+func newRecord(name string, level int, message string, err error, kvpair []interface{}) (record *Record) {
+	record = &Record{
+		Time:    time.Now(),
+		Logger:  name,
+		Level:   level,
+		Message: message,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if len(kvpair) > 1 {
+		record.Fields = map[string]interface{}{}
+		for i := 0; i+1 < len(kvpair); i += 2 {
+			value := kvpair[i+1]
+			switch v := value.(type) {
+			case error:
+				value = v.Error()
+			case fmt.Stringer:
+				value = v.String()
+			}
+			record.Fields[fmt.Sprint(kvpair[i])] = value
+		}
+	}
+
+	return
+}
+
+//
+// In-memory sink.
+// Intended for testing.
+type MemorySink struct {
+	// Max number of records retained.
+	// The oldest are discarded. 0 = not limited.
+	Max int
+	// Records.
+	records []Record
+	// Protect the records.
+	mutex sync.Mutex
+}
+
+//
+// Write the record.
+func (r *MemorySink) Write(record *Record) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.records = append(r.records, *record)
+	if r.Max > 0 && len(r.records) > r.Max {
+		r.records = r.records[len(r.records)-r.Max:]
+	}
+
+	return
+}
+
+//
+// Close the sink.
+func (r *MemorySink) Close() (err error) {
+	return
+}
+
+//
+// Get the (retained) records.
+func (r *MemorySink) Records() (list []Record) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list = make([]Record, len(r.records))
+	copy(list, r.records)
+	return
+}
+
+//
+// Writer sink.
+// Records are written as JSON (lines).
+type WriterSink struct {
+	// Writer.
+	Writer io.Writer
+}
+
+//
+// Write the record.
+func (r *WriterSink) Write(record *Record) (err error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	_, err = r.Writer.Write(append(b, '\n'))
+	if err != nil {
+		err = liberr.Wrap(err)
+	}
+
+	return
+}
+
+//
+// Close the sink.
+// The writer is closed when it is an io.Closer.
+func (r *WriterSink) Close() (err error) {
+	if closer, cast := r.Writer.(io.Closer); cast {
+		err = closer.Close()
+	}
+
+	return
+}
+
+//
+// Socket sink.
+// Records are written as JSON (lines) to a (stream or
+// datagram) socket. Compatible with the fluentd tcp, udp
+// and unix (json) inputs. The socket is (re)connected on
+// demand with backoff (see: SinkRetry); records written while
+// disconnected are dropped.
+// Example:
+//   logging.AddSink(
+//      "fluentd",
+//      &logging.SocketSink{
+//         Network: "tcp",
+//         Address: "fluentd:5170",
+//      })
+type SocketSink struct {
+	// Network: tcp|udp|unix|unixgram.
+	Network string
+	// Address.
+	Address string
+	// Connection.
+	conn socket
+}
+
+//
+// Write the record.
+func (r *SocketSink) Write(record *Record) (err error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	err = r.conn.write(r.Network, r.Address, append(b, '\n'))
+	return
+}
+
+//
+// Close the sink.
+func (r *SocketSink) Close() error {
+	return r.conn.close()
+}
+
+//
+// Syslog sink.
+// Records are written (RFC 5424) to the syslog daemon.
+// Errors are written with severity ERR; debug (level at or
+// above the debug threshold) with DEBUG; else INFO.
+// Example:
+//   logging.AddSink(
+//      "syslog",
+//      &logging.SyslogSink{
+//         Network: "udp",
+//         Address: "syslog:514",
+//         Tag:     "inventory",
+//      })
+type SyslogSink struct {
+	// Network: tcp|udp|unix|unixgram.
+	// Default: unixgram.
+	Network string
+	// Address.
+	// Default: /dev/log.
+	Address string
+	// Tag (app name).
+	// Default: the program name.
+	Tag string
+	// Facility.
+	// Default: 1 (user).
+	Facility int
+	// Connection.
+	conn socket
+}
+
+//
+// Syslog severities.
+const (
+	syslogErr   = 3
+	syslogInfo  = 6
+	syslogDebug = 7
+)
+
+//
+// Write the record.
+func (r *SyslogSink) Write(record *Record) (err error) {
+	severity := syslogInfo
+	switch {
+	case record.Error != "":
+		severity = syslogErr
+	case current().atDebug(record.Level):
+		severity = syslogDebug
+	}
+	facility := r.Facility
+	if facility == 0 {
+		facility = 1
+	}
+	tag := r.Tag
+	if tag == "" && len(os.Args) > 0 {
+		tag = os.Args[0]
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	msg := bytes.Buffer{}
+	msg.WriteString(record.Message)
+	if record.Error != "" {
+		msg.WriteString(" error=")
+		msg.WriteString(record.Error)
+	}
+	if len(record.Fields) > 0 {
+		b, jErr := json.Marshal(record.Fields)
+		if jErr == nil {
+			msg.WriteByte(' ')
+			msg.Write(b)
+		}
+	}
+	line := fmt.Sprintf(
+		"<%d>1 %s %s %s %d - - %s\n",
+		facility*8+severity,
+		record.Time.Format(time.RFC3339Nano),
+		hostname,
+		tag,
+		os.Getpid(),
+		msg.String())
+	network := r.Network
+	address := r.Address
+	if network == "" {
+		network = "unixgram"
+		if address == "" {
+			address = "/dev/log"
+		}
+	}
+	err = r.conn.write(network, address, []byte(line))
+	return
+}
+
+//
+// Close the sink.
+func (r *SyslogSink) Close() error {
+	return r.conn.close()
+}
+
+//
+// Socket connection.
+// (Re)connected on demand.
+type socket struct {
+	// Connection.
+	conn net.Conn
+	// Consecutive failed connects.
+	failed int
+	// Time of the next connect (attempt).
+	next time.Time
+	// Protect the connection.
+	mutex sync.Mutex
+}
+
+//
+// Write to the socket.
+// Connected as needed. Closed on error so that the next
+// write will reconnect. After a failed connect, writes fail
+// (without dialing) until the retry (backoff) delay has
+// elapsed so that an unreachable peer does not delay
+// delivery of each record by the (dial) timeout.
+func (r *socket) write(network, address string, b []byte) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.conn == nil {
+		if time.Now().Before(r.next) {
+			err = liberr.New(
+				"socket not connected.",
+				"network",
+				network,
+				"address",
+				address)
+			return
+		}
+		r.conn, err = net.DialTimeout(network, address, SinkTimeout)
+		if err != nil {
+			r.backoff()
+			err = liberr.Wrap(
+				err,
+				"network",
+				network,
+				"address",
+				address)
+			return
+		}
+		r.failed = 0
+	}
+	_ = r.conn.SetWriteDeadline(time.Now().Add(SinkTimeout))
+	_, err = r.conn.Write(b)
+	if err != nil {
+		err = liberr.Wrap(err)
+		_ = r.conn.Close()
+		r.conn = nil
+	}
+
+	return
+}
+
+//
+// Schedule the next connect (attempt).
+func (r *socket) backoff() {
+	delay := SinkRetry
+	for i := 0; i < r.failed && delay < SinkMaxRetry; i++ {
+		delay *= 2
+	}
+	if delay > SinkMaxRetry {
+		delay = SinkMaxRetry
+	}
+	r.failed++
+	r.next = time.Now().Add(delay)
+}
+
+//
+// Close the socket.
+func (r *socket) close() (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.conn != nil {
+		err = r.conn.Close()
+		r.conn = nil
+	}
+
+	return
+}
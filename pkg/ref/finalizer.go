@@ -0,0 +1,95 @@
+package ref
+
+import (
+	"fmt"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//
+// The object has the finalizer.
+func HasFinalizer(object meta.Object, name string) bool {
+	for _, f := range object.GetFinalizers() {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Ensure the object has the finalizer.
+// Returns: true when added (the object must be updated).
+func EnsureFinalizer(object meta.Object, name string) (added bool) {
+	if HasFinalizer(object, name) {
+		return
+	}
+	object.SetFinalizers(append(object.GetFinalizers(), name))
+	added = true
+	return
+}
+
+//
+// Remove the finalizer from the object.
+// Returns: true when removed (the object must be updated).
+func RemoveFinalizer(object meta.Object, name string) (removed bool) {
+	kept := []string{}
+	for _, f := range object.GetFinalizers() {
+		if f == name {
+			removed = true
+			continue
+		}
+		kept = append(kept, f)
+	}
+	if removed {
+		object.SetFinalizers(kept)
+	}
+
+	return
+}
+
+//
+// Remove the finalizer from a (referenced) target object.
+// Guarded: the finalizer is not removed while (live) owners
+// still reference the target (in the Map). A ReferencedBy
+// error is returned instead.
+// Returns: true when removed (the object must be updated).
+func ReleaseFinalizer(object meta.Object, name string) (removed bool, err error) {
+	target := Target{
+		Kind:      ToKind(object),
+		Namespace: object.GetNamespace(),
+		Name:      object.GetName(),
+	}
+	owners := Map.GetOwners(target, "")
+	if len(owners) > 0 {
+		err = &ReferencedBy{
+			Target: target,
+			Owners: owners,
+		}
+		return
+	}
+
+	removed = RemoveFinalizer(object, name)
+
+	return
+}
+
+//
+// Target still referenced by owners.
+type ReferencedBy struct {
+	// The referenced target.
+	Target Target
+	// The referencing owners.
+	Owners []Owner
+}
+
+//
+// Error description.
+func (e *ReferencedBy) Error() string {
+	return fmt.Sprintf(
+		"%s/%s (%s) referenced by %d owner(s).",
+		e.Target.Namespace,
+		e.Target.Name,
+		e.Target.Kind,
+		len(e.Owners))
+}
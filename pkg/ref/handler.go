@@ -49,10 +49,7 @@ func GetRequests(kind string, a handler.MapObject) []reconcile.Request {
 		Namespace: a.Meta.GetNamespace(),
 	}
 	list := []reconcile.Request{}
-	for _, owner := range Map.Find(target) {
-		if owner.Kind != kind {
-			continue
-		}
+	for _, owner := range Map.GetOwners(target, kind) {
 		list = append(
 			list,
 			reconcile.Request{
@@ -2,6 +2,7 @@ package ref
 
 import (
 	"k8s.io/api/core/v1"
+	"sort"
 	"sync"
 )
 
@@ -13,9 +14,12 @@ type Target v1.ObjectReference
 
 //
 // A 1-n mapping of Target => [Owner, ...].
+// The reverse (Owner => [Target, ...]) is indexed.
 type RefMap struct {
 	Content map[Target]map[Owner]bool
-	mutex   sync.RWMutex
+	// Index of Owner => [Target, ...].
+	index map[Owner]map[Target]bool
+	mutex sync.RWMutex
 }
 
 //
@@ -30,6 +34,16 @@ func (r *RefMap) Add(owner Owner, target Target) {
 	}
 
 	r.Content[target][owner] = true
+	if r.index == nil {
+		r.index = map[Owner]map[Target]bool{}
+	}
+	targets, found := r.index[owner]
+	if !found {
+		targets = map[Target]bool{}
+		r.index[owner] = targets
+	}
+
+	targets[target] = true
 
 	log.V(3).Info(
 		"map: added.",
@@ -52,6 +66,9 @@ func (r *RefMap) Delete(owner Owner, target Target) {
 			"owner",
 			owner)
 	}
+	if targets, found := r.index[owner]; found {
+		delete(targets, target)
+	}
 	r.Prune()
 }
 
@@ -60,13 +77,16 @@ func (r *RefMap) Delete(owner Owner, target Target) {
 func (r *RefMap) DeleteOwner(owner Owner) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	for _, owners := range r.Content {
-		delete(owners, owner)
-		log.V(3).Info(
-			"map: owner deleted.",
-			"owner",
-			owner)
+	for target := range r.index[owner] {
+		if owners, found := r.Content[target]; found {
+			delete(owners, owner)
+		}
 	}
+	delete(r.index, owner)
+	log.V(3).Info(
+		"map: owner deleted.",
+		"owner",
+		owner)
 	r.Prune()
 }
 
@@ -104,6 +124,44 @@ func (r *RefMap) Find(target Target) []Owner {
 	return list
 }
 
+//
+// Get the owners (of kind) mapped to the target.
+// Owners are sorted by namespace and name.
+// Kind optional; Empty=any.
+func (r *RefMap) GetOwners(target Target, kind string) []Owner {
+	list := []Owner{}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for owner := range r.Content[target] {
+		if kind == "" || owner.Kind == kind {
+			list = append(list, owner)
+		}
+	}
+	sort.Slice(
+		list,
+		func(i, j int) bool {
+			if list[i].Namespace != list[j].Namespace {
+				return list[i].Namespace < list[j].Namespace
+			}
+			return list[i].Name < list[j].Name
+		})
+
+	return list
+}
+
+//
+// Get the targets mapped to the owner.
+func (r *RefMap) GetTargets(owner Owner) []Target {
+	list := []Target{}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for target := range r.index[owner] {
+		list = append(list, target)
+	}
+
+	return list
+}
+
 //
 // Prune empty mappings.
 func (r *RefMap) Prune() {
@@ -112,4 +170,9 @@ func (r *RefMap) Prune() {
 			delete(r.Content, key)
 		}
 	}
+	for key, targets := range r.index {
+		if len(targets) == 0 {
+			delete(r.index, key)
+		}
+	}
 }
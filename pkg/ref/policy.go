@@ -0,0 +1,141 @@
+package ref
+
+import (
+	"context"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//
+// Cross-namespace reference policy.
+// Applied when resolving references. The zero value
+// permits all references.
+type Policy struct {
+	// Only references to the owner namespace are permitted.
+	SameNamespace bool
+	// Target namespaces permitted. Empty=any.
+	Allow []string
+	// Target namespaces denied.
+	Deny []string
+}
+
+//
+// The (global) reference policy.
+var DefaultPolicy = &Policy{}
+
+//
+// Reference denied by policy.
+type PolicyDenied struct {
+	// The owner.
+	Owner Owner
+	// The referenced target.
+	Target Target
+	// The reason denied.
+	Reason string
+}
+
+//
+// Error description.
+func (e *PolicyDenied) Error() string {
+	return fmt.Sprintf(
+		"reference to %s/%s (%s) by %s/%s denied: %s",
+		e.Target.Namespace,
+		e.Target.Name,
+		e.Target.Kind,
+		e.Owner.Namespace,
+		e.Owner.Name,
+		e.Reason)
+}
+
+//
+// Check the policy.
+// Returns a PolicyDenied error when not permitted.
+func (r *Policy) Check(owner Owner, target Target) (err error) {
+	denied := func(reason string) {
+		err = &PolicyDenied{
+			Owner:  owner,
+			Target: target,
+			Reason: reason,
+		}
+	}
+	if target.Namespace == owner.Namespace {
+		return
+	}
+	if r.SameNamespace {
+		denied("cross-namespace references not permitted.")
+		return
+	}
+	for _, ns := range r.Deny {
+		if ns == target.Namespace {
+			denied("namespace denied.")
+			return
+		}
+	}
+	if len(r.Allow) == 0 {
+		return
+	}
+	for _, ns := range r.Allow {
+		if ns == target.Namespace {
+			return
+		}
+	}
+
+	denied("namespace not allowed.")
+
+	return
+}
+
+//
+// Resolve (get) a referenced object.
+// The DefaultPolicy is applied.
+// Returns a (wrapped) PolicyDenied error when not permitted.
+func Resolve(
+	ctx context.Context,
+	client client.Client,
+	owner meta.Object,
+	ref *v1.ObjectReference,
+	object runtime.Object) (err error) {
+	//
+	if !RefSet(ref) {
+		err = liberr.New("reference not set.")
+		return
+	}
+	err = DefaultPolicy.Check(
+		Owner{
+			Kind:      ToKind(owner),
+			Namespace: owner.GetNamespace(),
+			Name:      owner.GetName(),
+		},
+		Target{
+			Kind:      ToKind(object),
+			Namespace: ref.Namespace,
+			Name:      ref.Name,
+		})
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	err = client.Get(
+		ctx,
+		clientKey(ref),
+		object)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+
+	return
+}
+
+//
+// Build the client key for a reference.
+func clientKey(ref *v1.ObjectReference) client.ObjectKey {
+	return client.ObjectKey{
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
+	}
+}
@@ -1,14 +1,20 @@
 package ref
 
 import (
+	"context"
+	"errors"
 	"github.com/onsi/gomega"
 	"k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"testing"
+	"time"
 )
 
 type _ThingSpec struct {
@@ -315,3 +321,265 @@ func TestHandler(t *testing.T) {
 
 	g.Expect(len(list)).To(gomega.Equal(1))
 }
+
+func TestGetOwners(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Setup
+	m := &RefMap{
+		Content: map[Target]map[Owner]bool{},
+	}
+	target := Target{
+		Kind:      "Secret",
+		Namespace: "ns0",
+		Name:      "secret",
+	}
+	other := Target{
+		Kind:      "Provider",
+		Namespace: "ns0",
+		Name:      "provider",
+	}
+	ownerA := Owner{Kind: "Plan", Namespace: "ns1", Name: "b"}
+	ownerB := Owner{Kind: "Plan", Namespace: "ns1", Name: "a"}
+	ownerC := Owner{Kind: "Migration", Namespace: "ns1", Name: "c"}
+	m.Add(ownerA, target)
+	m.Add(ownerA, other)
+	m.Add(ownerB, target)
+	m.Add(ownerC, target)
+
+	// Test
+	owners := m.GetOwners(target, "Plan")
+	g.Expect(owners).To(gomega.Equal([]Owner{ownerB, ownerA}))
+	g.Expect(len(m.GetOwners(target, ""))).To(gomega.Equal(3))
+	g.Expect(len(m.GetTargets(ownerA))).To(gomega.Equal(2))
+
+	// Delete.
+	m.DeleteOwner(ownerA)
+	g.Expect(m.GetOwners(target, "Plan")).To(gomega.Equal([]Owner{ownerB}))
+	g.Expect(m.GetTargets(ownerA)).To(gomega.BeEmpty())
+	g.Expect(len(m.Content)).To(gomega.Equal(1))
+	m.Delete(ownerB, target)
+	m.Delete(ownerC, target)
+	g.Expect(len(m.Content)).To(gomega.Equal(0))
+}
+
+func TestPolicy(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Setup
+	owner := &_Thing{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: "ns0",
+			Name:      "joe",
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "secret",
+		},
+	}
+	ref := &v1.ObjectReference{
+		Namespace: "ns1",
+		Name:      "secret",
+	}
+	client := fake.NewFakeClient(secret)
+	defer func() {
+		DefaultPolicy = &Policy{}
+	}()
+
+	// Permitted.
+	err := Resolve(context.TODO(), client, owner, ref, &v1.Secret{})
+	g.Expect(err).To(gomega.BeNil())
+
+	// Same namespace.
+	DefaultPolicy = &Policy{SameNamespace: true}
+	err = Resolve(context.TODO(), client, owner, ref, &v1.Secret{})
+	denied := &PolicyDenied{}
+	g.Expect(errors.As(err, &denied)).To(gomega.BeTrue())
+	g.Expect(denied.Target.Kind).To(gomega.Equal("Secret"))
+
+	// Deny.
+	DefaultPolicy = &Policy{Deny: []string{"ns1"}}
+	err = Resolve(context.TODO(), client, owner, ref, &v1.Secret{})
+	g.Expect(errors.As(err, &denied)).To(gomega.BeTrue())
+
+	// Allow.
+	DefaultPolicy = &Policy{Allow: []string{"ns2"}}
+	err = Resolve(context.TODO(), client, owner, ref, &v1.Secret{})
+	g.Expect(errors.As(err, &denied)).To(gomega.BeTrue())
+	DefaultPolicy = &Policy{Allow: []string{"ns1"}}
+	err = Resolve(context.TODO(), client, owner, ref, &v1.Secret{})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestFinalizer(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Setup
+	Map.Content = map[Target]map[Owner]bool{}
+	secret := &v1.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "secret",
+		},
+	}
+	owner := Owner{Kind: "Plan", Namespace: "ns0", Name: "joe"}
+	target := Target{Kind: "Secret", Namespace: "ns1", Name: "secret"}
+	name := "konveyor.io/ref"
+
+	// Ensure.
+	g.Expect(EnsureFinalizer(secret, name)).To(gomega.BeTrue())
+	g.Expect(EnsureFinalizer(secret, name)).To(gomega.BeFalse())
+	g.Expect(HasFinalizer(secret, name)).To(gomega.BeTrue())
+
+	// Guarded.
+	Map.Add(owner, target)
+	removed, err := ReleaseFinalizer(secret, name)
+	g.Expect(removed).To(gomega.BeFalse())
+	referenced := &ReferencedBy{}
+	g.Expect(errors.As(err, &referenced)).To(gomega.BeTrue())
+	g.Expect(referenced.Owners).To(gomega.Equal([]Owner{owner}))
+	g.Expect(HasFinalizer(secret, name)).To(gomega.BeTrue())
+
+	// Released.
+	Map.DeleteOwner(owner)
+	removed, err = ReleaseFinalizer(secret, name)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(removed).To(gomega.BeTrue())
+	g.Expect(HasFinalizer(secret, name)).To(gomega.BeFalse())
+	g.Expect(RemoveFinalizer(secret, name)).To(gomega.BeFalse())
+}
+
+type countingClient struct {
+	client.Client
+	// Number of gets.
+	gets int
+	// Get error.
+	err error
+}
+
+func (r *countingClient) Get(ctx context.Context, key client.ObjectKey, object runtime.Object) error {
+	r.gets++
+	if r.err != nil {
+		return r.err
+	}
+	return r.Client.Get(ctx, key, object)
+}
+
+func TestResolver(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Setup
+	owner := &_Thing{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: "ns0",
+			Name:      "joe",
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "secret",
+		},
+		Data: map[string][]byte{"user": []byte("joe")},
+	}
+	ref := &v1.ObjectReference{
+		Namespace: "ns1",
+		Name:      "secret",
+	}
+	kube := &countingClient{Client: fake.NewFakeClient(secret)}
+	resolver := Resolver{Client: kube}
+
+	// Resolved and cached.
+	found := &v1.Secret{}
+	err := resolver.Resolve(context.TODO(), owner, ref, found)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(found.Data["user"])).To(gomega.Equal("joe"))
+	found = &v1.Secret{}
+	err = resolver.Resolve(context.TODO(), owner, ref, found)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(found.Data["user"])).To(gomega.Equal("joe"))
+	g.Expect(kube.gets).To(gomega.Equal(1))
+	// cached copy not shared.
+	found.Data["user"] = []byte("larry")
+
+	// Invalidated (watch).
+	updated := secret.DeepCopy()
+	updated.Data["user"] = []byte("mary")
+	g.Expect(kube.Update(context.TODO(), updated)).To(gomega.BeNil())
+	resolver.Update(
+		event.UpdateEvent{
+			ObjectOld: secret,
+			MetaOld:   secret,
+			ObjectNew: updated,
+			MetaNew:   updated,
+		})
+	found = &v1.Secret{}
+	err = resolver.Resolve(context.TODO(), owner, ref, found)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(found.Data["user"])).To(gomega.Equal("mary"))
+	g.Expect(kube.gets).To(gomega.Equal(2))
+
+	// Not found.
+	g.Expect(kube.Delete(context.TODO(), updated)).To(gomega.BeNil())
+	resolver.Delete(event.DeleteEvent{Object: updated, Meta: updated})
+	err = resolver.Resolve(context.TODO(), owner, ref, &v1.Secret{})
+	notFound := &NotFound{}
+	g.Expect(errors.As(err, &notFound)).To(gomega.BeTrue())
+	g.Expect(notFound.Target.Kind).To(gomega.Equal("Secret"))
+	g.Expect(ReasonOf(err)).To(gomega.Equal(NotFoundReason))
+
+	// Forbidden.
+	kube.err = k8serr.NewForbidden(schema.GroupResource{}, "secret", errors.New("denied"))
+	err = resolver.Resolve(context.TODO(), owner, ref, &v1.Secret{})
+	g.Expect(ReasonOf(err)).To(gomega.Equal(ForbiddenReason))
+
+	// Policy denied.
+	resolver.Policy = &Policy{SameNamespace: true}
+	err = resolver.Resolve(context.TODO(), owner, ref, &v1.Secret{})
+	g.Expect(ReasonOf(err)).To(gomega.Equal(PolicyDeniedReason))
+	g.Expect(ReasonOf(errors.New("other"))).To(gomega.BeEmpty())
+
+	// Bounded (oldest evicted).
+	kube.err = nil
+	resolver.Policy = nil
+	resolver.Size = 2
+	for _, name := range []string{"a", "b", "c"} {
+		g.Expect(kube.Create(
+			context.TODO(),
+			&v1.Secret{
+				ObjectMeta: meta.ObjectMeta{
+					Namespace: "ns1",
+					Name:      name,
+				},
+			})).To(gomega.BeNil())
+		err = resolver.Resolve(
+			context.TODO(),
+			owner,
+			&v1.ObjectReference{Namespace: "ns1", Name: name},
+			&v1.Secret{})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	g.Expect(len(resolver.cache)).To(gomega.Equal(2))
+	_, cached := resolver.cache[Target{Kind: "Secret", Namespace: "ns1", Name: "a"}]
+	g.Expect(cached).To(gomega.BeFalse())
+
+	// Expired (evicted).
+	resolver.TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	gets := kube.gets
+	resolver.Invalidate(Target{Kind: "Secret", Namespace: "ns1", Name: "b"})
+	cached = resolver.cached(
+		Target{Kind: "Secret", Namespace: "ns1", Name: "c"},
+		&v1.Secret{})
+	g.Expect(cached).To(gomega.BeFalse())
+	g.Expect(len(resolver.cache)).To(gomega.Equal(0))
+	err = resolver.Resolve(
+		context.TODO(),
+		owner,
+		&v1.ObjectReference{Namespace: "ns1", Name: "c"},
+		&v1.Secret{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(kube.gets).To(gomega.Equal(gets + 1))
+}
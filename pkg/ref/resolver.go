@@ -0,0 +1,313 @@
+package ref
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sync"
+	"time"
+)
+
+//
+// Resolve (condition) reasons.
+const (
+	NotFoundReason     = "NotFound"
+	ForbiddenReason    = "Forbidden"
+	PolicyDeniedReason = "PolicyDenied"
+)
+
+//
+// Resolver (default) max cached objects.
+var ResolverSize = 1000
+
+//
+// Referenced object not found.
+type NotFound struct {
+	// The referenced target.
+	Target Target
+}
+
+//
+// Error description.
+func (e *NotFound) Error() string {
+	return fmt.Sprintf(
+		"%s/%s (%s) not found.",
+		e.Target.Namespace,
+		e.Target.Name,
+		e.Target.Kind)
+}
+
+//
+// Access to the referenced object forbidden.
+type Forbidden struct {
+	// The referenced target.
+	Target Target
+}
+
+//
+// Error description.
+func (e *Forbidden) Error() string {
+	return fmt.Sprintf(
+		"%s/%s (%s) access forbidden.",
+		e.Target.Namespace,
+		e.Target.Name,
+		e.Target.Kind)
+}
+
+//
+// The (condition) reason for a resolve error.
+// Returns "" when not a resolve error.
+func ReasonOf(err error) (reason string) {
+	var notFound *NotFound
+	var forbidden *Forbidden
+	var denied *PolicyDenied
+	switch {
+	case errors.As(err, &notFound):
+		reason = NotFoundReason
+	case errors.As(err, &forbidden):
+		reason = ForbiddenReason
+	case errors.As(err, &denied):
+		reason = PolicyDeniedReason
+	}
+
+	return
+}
+
+//
+// Caching reference resolver.
+// Resolves references to (live) objects using the client and
+// caches the objects by target. Cached objects are invalidated
+// by watch (predicate) events and (optionally) expire. Expired
+// objects are evicted when read and when the cache is full; the
+// oldest cached object is evicted when none have expired.
+// Example (usage):
+//     func (p Predicate) Update(e event.UpdateEvent) bool {
+//         ...
+//         resolver.Update(e)
+//     }
+//
+//     err := resolver.Resolve(ctx, owner, plan.Spec.ThingRef, thing)
+//     if err != nil {
+//         reason := ref.ReasonOf(err)
+//         ...
+//     }
+type Resolver struct {
+	// k8s client.
+	Client client.Client
+	// Reference policy.
+	// Default: DefaultPolicy.
+	Policy *Policy
+	// Cached objects expire after the TTL.
+	// 0 = until invalidated.
+	TTL time.Duration
+	// Max cached objects.
+	// Default: ResolverSize.
+	Size int
+	// Cached objects by target.
+	cache map[Target]cached
+	// Protect the map.
+	mutex sync.RWMutex
+}
+
+//
+// Cached object.
+type cached struct {
+	// The object.
+	object runtime.Object
+	// When cached.
+	cached time.Time
+}
+
+//
+// Resolve (get) a referenced object.
+// The object is copied from the cache when cached. Returns a
+// (wrapped) NotFound, Forbidden or PolicyDenied error when not
+// resolved. See: ReasonOf().
+func (r *Resolver) Resolve(
+	ctx context.Context,
+	owner meta.Object,
+	ref *v1.ObjectReference,
+	object runtime.Object) (err error) {
+	//
+	if !RefSet(ref) {
+		err = liberr.New("reference not set.")
+		return
+	}
+	target := Target{
+		Kind:      ToKind(object),
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
+	}
+	policy := r.Policy
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+	err = policy.Check(
+		Owner{
+			Kind:      ToKind(owner),
+			Namespace: owner.GetNamespace(),
+			Name:      owner.GetName(),
+		},
+		target)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	if r.cached(target, object) {
+		return
+	}
+	err = r.Client.Get(ctx, clientKey(ref), object)
+	if err != nil {
+		switch {
+		case k8serr.IsNotFound(err):
+			err = &NotFound{Target: target}
+		case k8serr.IsForbidden(err):
+			err = &Forbidden{Target: target}
+		}
+		err = liberr.Wrap(err)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.cache == nil {
+		r.cache = map[Target]cached{}
+	}
+	if _, found := r.cache[target]; !found {
+		r.evict()
+	}
+	r.cache[target] = cached{
+		object: object.DeepCopyObject(),
+		cached: time.Now(),
+	}
+
+	return
+}
+
+//
+// Invalidate (remove) the cached object.
+func (r *Resolver) Invalidate(target Target) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.cache, target)
+	log.V(4).Info(
+		"resolver: invalidated.",
+		"target",
+		target)
+}
+
+//
+// Invalidate all cached objects.
+func (r *Resolver) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cache = nil
+}
+
+//
+// Create event.
+func (r *Resolver) Create(event event.CreateEvent) {
+	r.invalidate(event.Object, event.Meta)
+}
+
+//
+// Update event.
+func (r *Resolver) Update(event event.UpdateEvent) {
+	r.invalidate(event.ObjectNew, event.MetaNew)
+}
+
+//
+// Delete event.
+func (r *Resolver) Delete(event event.DeleteEvent) {
+	r.invalidate(event.Object, event.Meta)
+}
+
+//
+// Invalidate the cached object (from an event).
+func (r *Resolver) invalidate(object runtime.Object, md meta.Object) {
+	if object == nil || md == nil {
+		return
+	}
+	r.Invalidate(
+		Target{
+			Kind:      ToKind(object),
+			Namespace: md.GetNamespace(),
+			Name:      md.GetName(),
+		})
+}
+
+//
+// Copy the cached object.
+// Returns false when not cached (or expired).
+// The expired object is evicted.
+func (r *Resolver) cached(target Target, object runtime.Object) (found bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry, found := r.cache[target]
+	if !found {
+		return
+	}
+	if r.expired(entry) {
+		delete(r.cache, target)
+		found = false
+		return
+	}
+	ov := reflect.ValueOf(object)
+	cv := reflect.ValueOf(entry.object.DeepCopyObject())
+	if ov.Kind() != reflect.Ptr || ov.Type() != cv.Type() {
+		found = false
+		return
+	}
+
+	ov.Elem().Set(cv.Elem())
+
+	return
+}
+
+//
+// Evict cached objects as needed to make room for one.
+// Expired objects are evicted first, then the oldest.
+// Must be called with the mutex held.
+func (r *Resolver) evict() {
+	size := r.Size
+	if size < 1 {
+		size = ResolverSize
+	}
+	if len(r.cache) < size {
+		return
+	}
+	for target, entry := range r.cache {
+		if r.expired(entry) {
+			delete(r.cache, target)
+		}
+	}
+	for len(r.cache) >= size {
+		var oldest Target
+		var mark time.Time
+		for target, entry := range r.cache {
+			if mark.IsZero() || entry.cached.Before(mark) {
+				oldest = target
+				mark = entry.cached
+			}
+		}
+		delete(r.cache, oldest)
+		log.V(4).Info(
+			"resolver: evicted.",
+			"target",
+			oldest)
+	}
+}
+
+//
+// The cached object has expired.
+func (r *Resolver) expired(entry cached) bool {
+	return r.TTL > 0 && time.Since(entry.cached) > r.TTL
+}
@@ -0,0 +1,13 @@
+/*
+Provides layered settings.
+Values are resolved (highest precedence first) from:
+  - Environment variables.
+  - The settings (YAML, JSON or TOML) file.
+  - Defaults.
+Keys are dot-delimited (e.g. log.level) and nested file
+sections are flattened. The file is parsed as TOML when
+the extension is `.toml`. The environment variable for a
+key is the upper-cased key with `.` and `-` replaced
+by `_` (e.g. LOG_LEVEL).
+*/
+package settings
@@ -0,0 +1,326 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Environment variables.
+const (
+	// The settings file path.
+	EnvPath = "SETTINGS_PATH"
+)
+
+//
+// Global settings.
+// Loaded at init using the file specified by SETTINGS_PATH.
+var Global *Settings
+
+func init() {
+	Global = New(os.Getenv(EnvPath))
+	_ = Global.Load()
+}
+
+//
+// Validates a (raw) value.
+type Validator func(value string) error
+
+//
+// Layered settings.
+type Settings struct {
+	// The settings file path. Optional.
+	Path string
+	// Default values by key.
+	defaults map[string]string
+	// File values by key.
+	file map[string]string
+	// Validators by key.
+	validators map[string][]Validator
+	// Change subscriptions.
+	subscriptions []subscription
+	// Closed to stop watching.
+	done chan struct{}
+	// Last reload error.
+	err error
+	// Protect the maps.
+	mutex sync.RWMutex
+}
+
+//
+// New settings.
+func New(path string) *Settings {
+	return &Settings{
+		Path:       path,
+		defaults:   map[string]string{},
+		file:       map[string]string{},
+		validators: map[string][]Validator{},
+	}
+}
+
+//
+// Set the default value for a key.
+func (r *Settings) Default(key string, value interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.defaults[key] = fmt.Sprint(value)
+}
+
+//
+// Register a validator for a key.
+func (r *Settings) Validate(key string, validator Validator) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.validators[key] = append(r.validators[key], validator)
+}
+
+//
+// Load (read) the file and validate.
+// A file that does not exist is ignored.
+func (r *Settings) Load() (err error) {
+	file := map[string]string{}
+	if r.Path != "" {
+		file, err = r.read()
+		if err != nil {
+			return
+		}
+	}
+	r.mutex.Lock()
+	r.file = file
+	r.mutex.Unlock()
+	err = r.validate()
+	return
+}
+
+//
+// Lookup a value.
+// Resolved using: environment, file, defaults.
+func (r *Settings) Lookup(key string) (value string, found bool) {
+	value, found = os.LookupEnv(EnvName(key))
+	if found {
+		return
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	value, found = r.file[key]
+	if found {
+		return
+	}
+
+	value, found = r.defaults[key]
+
+	return
+}
+
+//
+// Get a string value.
+func (r *Settings) String(key string) (value string) {
+	value, _ = r.Lookup(key)
+	return
+}
+
+//
+// Get an integer value.
+// Returns zero when not found.
+func (r *Settings) Int(key string) (n int, err error) {
+	value, found := r.Lookup(key)
+	if !found {
+		return
+	}
+	n, err = strconv.Atoi(value)
+	if err != nil {
+		err = liberr.Wrap(err, "key", key)
+	}
+
+	return
+}
+
+//
+// Get a boolean value.
+// Returns false when not found.
+func (r *Settings) Bool(key string) (b bool, err error) {
+	value, found := r.Lookup(key)
+	if !found {
+		return
+	}
+	b, err = strconv.ParseBool(value)
+	if err != nil {
+		err = liberr.Wrap(err, "key", key)
+	}
+
+	return
+}
+
+//
+// Get a duration value.
+// Returns zero when not found.
+func (r *Settings) Duration(key string) (d time.Duration, err error) {
+	value, found := r.Lookup(key)
+	if !found {
+		return
+	}
+	d, err = time.ParseDuration(value)
+	if err != nil {
+		err = liberr.Wrap(err, "key", key)
+	}
+
+	return
+}
+
+//
+// Keys (sorted) with a file or default value.
+func (r *Settings) Keys() (keys []string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	set := map[string]bool{}
+	for key := range r.defaults {
+		set[key] = true
+	}
+	for key := range r.file {
+		set[key] = true
+	}
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return
+}
+
+//
+// Read and flatten the file.
+// Parsed as TOML when the extension is `.toml`; otherwise, YAML.
+func (r *Settings) read() (values map[string]string, err error) {
+	values = map[string]string{}
+	b, err := ioutil.ReadFile(r.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			err = liberr.Wrap(err, "path", r.Path)
+		}
+		return
+	}
+	content := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(r.Path)) {
+	case ".toml":
+		err = toml.Unmarshal(b, &content)
+	default:
+		err = yaml.Unmarshal(b, &content)
+	}
+	if err != nil {
+		err = liberr.Wrap(err, "path", r.Path)
+		return
+	}
+
+	flatten("", content, values)
+
+	return
+}
+
+//
+// Run the validators.
+func (r *Settings) validate() (err error) {
+	r.mutex.RLock()
+	validators := map[string][]Validator{}
+	for key, list := range r.validators {
+		validators[key] = list
+	}
+	r.mutex.RUnlock()
+	for key, list := range validators {
+		value, found := r.Lookup(key)
+		if !found {
+			continue
+		}
+		for _, validator := range list {
+			vErr := validator(value)
+			if vErr != nil {
+				err = liberr.Wrap(
+					vErr,
+					"setting not valid.",
+					"key",
+					key,
+					"value",
+					value)
+				return
+			}
+		}
+	}
+
+	return
+}
+
+//
+// Flatten nested sections into dot-delimited keys.
+func flatten(prefix string, in map[string]interface{}, out map[string]string) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, cast := v.(map[string]interface{}); cast {
+			flatten(key, nested, out)
+			continue
+		}
+		if f, cast := v.(float64); cast {
+			out[key] = strconv.FormatFloat(f, 'f', -1, 64)
+			continue
+		}
+		out[key] = fmt.Sprint(v)
+	}
+}
+
+//
+// Environment variable name for a key.
+func EnvName(key string) string {
+	return strings.ToUpper(
+		strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+//
+// Validate the value is an integer within the range.
+func IntRange(min, max int) Validator {
+	return func(value string) (err error) {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return
+		}
+		if n < min || n > max {
+			err = liberr.New(
+				"value out of range.",
+				"min",
+				min,
+				"max",
+				max)
+		}
+		return
+	}
+}
+
+//
+// Validate the value is one of the specified values.
+func OneOf(values ...string) Validator {
+	return func(value string) (err error) {
+		for _, v := range values {
+			if v == value {
+				return
+			}
+		}
+		err = liberr.New(
+			"value not valid.",
+			"expected",
+			values)
+		return
+	}
+}
@@ -0,0 +1,147 @@
+package settings
+
+import (
+	"errors"
+	"github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSettings(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "settings")
+	g.Expect(err).To(gomega.BeNil())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "settings.yaml")
+	err = ioutil.WriteFile(
+		path,
+		[]byte("log:\n  level: 3\nweb:\n  port: 8080\n  timeout: 10s\n"),
+		0644)
+	g.Expect(err).To(gomega.BeNil())
+	s := New(path)
+	s.Default("log.level", 0)
+	s.Default("log.development", true)
+	s.Default("web.port", 80)
+	err = s.Load()
+	g.Expect(err).To(gomega.BeNil())
+	// File.
+	n, err := s.Int("log.level")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(3))
+	d, err := s.Duration("web.timeout")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(d).To(gomega.Equal(10 * time.Second))
+	// Default.
+	b, err := s.Bool("log.development")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(b).To(gomega.BeTrue())
+	// Environment.
+	g.Expect(EnvName("web.port")).To(gomega.Equal("WEB_PORT"))
+	os.Setenv("WEB_PORT", "9090")
+	defer os.Unsetenv("WEB_PORT")
+	n, err = s.Int("web.port")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(9090))
+	g.Expect(s.Keys()).To(gomega.Equal(
+		[]string{
+			"log.development",
+			"log.level",
+			"web.port",
+			"web.timeout",
+		}))
+	// Not valid.
+	os.Setenv("WEB_PORT", "http")
+	_, err = s.Int("web.port")
+	g.Expect(err).ToNot(gomega.BeNil())
+	s.Validate("log.level", IntRange(0, 2))
+	err = s.Load()
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Not found.
+	err = New(filepath.Join(dir, "none")).Load()
+	g.Expect(err).To(gomega.BeNil())
+	// TOML.
+	path = filepath.Join(dir, "settings.toml")
+	err = ioutil.WriteFile(
+		path,
+		[]byte("[log]\nlevel = 1\n[web]\ntimeout = \"5s\"\n"),
+		0644)
+	g.Expect(err).To(gomega.BeNil())
+	s = New(path)
+	err = s.Load()
+	g.Expect(err).To(gomega.BeNil())
+	n, err = s.Int("log.level")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(1))
+	d, err = s.Duration("web.timeout")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(d).To(gomega.Equal(5 * time.Second))
+}
+
+func TestWatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "settings")
+	g.Expect(err).To(gomega.BeNil())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "settings.yaml")
+	write := func(content string) {
+		tmp := path + ".tmp"
+		err := ioutil.WriteFile(tmp, []byte(content), 0644)
+		g.Expect(err).To(gomega.BeNil())
+		err = os.Rename(tmp, path)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	write("log:\n  level: 1\nweb:\n  port: 8080\n")
+	s := New(path)
+	s.Validate("log.level", IntRange(0, 5))
+	err = s.Load()
+	g.Expect(err).To(gomega.BeNil())
+	notified := make(chan []string, 10)
+	s.Subscribe(func(s *Settings, changed []string) {
+		notified <- changed
+	})
+	WatchInterval = 10 * time.Millisecond
+	s.Watch()
+	defer s.Stop()
+	// Changed.
+	write("log:\n  level: 4\n")
+	var changed []string
+	g.Eventually(notified).Should(gomega.Receive(&changed))
+	g.Expect(changed).To(gomega.Equal([]string{"log.level", "web.port"}))
+	g.Expect(s.String("log.level")).To(gomega.Equal("4"))
+	// Not valid.
+	write("log:\n  level: 9\n")
+	g.Eventually(s.Err).ShouldNot(gomega.BeNil())
+	g.Expect(s.String("log.level")).To(gomega.Equal("4"))
+	g.Consistently(notified, "50ms").ShouldNot(gomega.Receive())
+}
+
+func TestWatchRejected(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "settings")
+	g.Expect(err).To(gomega.BeNil())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "settings.yaml")
+	err = ioutil.WriteFile(path, []byte("log:\n  level: 1\nweb:\n  port: 8080\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	s := New(path)
+	err = s.Load()
+	g.Expect(err).To(gomega.BeNil())
+	notified := []string{}
+	s.Subscribe(
+		func(s *Settings, changed []string) {
+			notified = append(notified, changed...)
+		},
+		"log.")
+	err = ioutil.WriteFile(path, []byte("log:\n  level: 2\nweb:\n  port: 9090\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	changed, err := s.Reload()
+	g.Expect(errors.Is(err, RestartErr)).To(gomega.BeTrue())
+	g.Expect(s.Err()).ToNot(gomega.BeNil())
+	g.Expect(changed).To(gomega.Equal([]string{"log.level"}))
+	g.Expect(notified).To(gomega.Equal([]string{"log.level"}))
+	g.Expect(s.String("log.level")).To(gomega.Equal("2"))
+	g.Expect(s.String("web.port")).To(gomega.Equal("8080"))
+}
@@ -0,0 +1,208 @@
+package settings
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sort"
+	"strings"
+	"time"
+)
+
+//
+// Interval used to poll the settings file for changes.
+// Polling (rather than inotify) handles ConfigMap mounts
+// which are updated by swapping symlinks.
+var WatchInterval = time.Second * 10
+
+//
+// Errors.
+var (
+	// Changed settings not applied (not subscribed).
+	RestartErr = errors.New("restart required")
+)
+
+//
+// Notified of changed settings.
+// The changed keys are sorted.
+type Subscriber func(settings *Settings, changed []string)
+
+//
+// Subscription.
+type subscription struct {
+	// Key prefixes. Empty = all keys.
+	prefix []string
+	// Subscriber.
+	subscriber Subscriber
+}
+
+//
+// The key is matched by the subscription.
+func (s *subscription) match(key string) bool {
+	if len(s.prefix) == 0 {
+		return true
+	}
+	for _, prefix := range s.prefix {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Register a subscriber.
+// The subscriber is notified of changed keys matching the
+// (optional) prefixes. Keys changed in the file that are
+// not matched by a subscriber are not applied (reload) and
+// are reported by Err() as RestartErr.
+func (r *Settings) Subscribe(subscriber Subscriber, prefix ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.subscriptions = append(
+		r.subscriptions,
+		subscription{
+			prefix:     prefix,
+			subscriber: subscriber,
+		})
+}
+
+//
+// Watch the file for changes.
+// Changed files are re-read, validated and subscribers
+// notified. Files that are not valid are ignored (the
+// error is reported by Err()) and the prior values retained.
+func (r *Settings) Watch() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.done != nil {
+		return
+	}
+	r.done = make(chan struct{})
+	go r.watch(r.done)
+}
+
+//
+// Stop watching the file.
+func (r *Settings) Stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.done == nil {
+		return
+	}
+	close(r.done)
+	r.done = nil
+}
+
+//
+// The last reload error.
+func (r *Settings) Err() error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.err
+}
+
+//
+// Reload the file.
+// Subscribers are notified of changed keys. Changed keys
+// without a subscriber retain the prior value and the
+// error (RestartErr) is reported by Err().
+func (r *Settings) Reload() (changed []string, err error) {
+	if r.Path == "" {
+		return
+	}
+	var rejected []string
+	file, err := r.read()
+	if err == nil {
+		r.mutex.Lock()
+		prior := r.file
+		for _, key := range diff(prior, file) {
+			if r.subscribed(key) {
+				changed = append(changed, key)
+				continue
+			}
+			rejected = append(rejected, key)
+			if v, found := prior[key]; found {
+				file[key] = v
+			} else {
+				delete(file, key)
+			}
+		}
+		r.file = file
+		r.mutex.Unlock()
+		err = r.validate()
+		if err != nil {
+			changed = nil
+			r.mutex.Lock()
+			r.file = prior
+			r.mutex.Unlock()
+		} else if len(rejected) > 0 {
+			err = liberr.Wrap(RestartErr, "keys", rejected)
+		}
+	}
+	r.mutex.Lock()
+	r.err = err
+	subscriptions := r.subscriptions
+	r.mutex.Unlock()
+	if len(changed) == 0 {
+		return
+	}
+	for i := range subscriptions {
+		matched := []string{}
+		for _, key := range changed {
+			if subscriptions[i].match(key) {
+				matched = append(matched, key)
+			}
+		}
+		if len(matched) > 0 {
+			subscriptions[i].subscriber(r, matched)
+		}
+	}
+
+	return
+}
+
+//
+// The key is matched by a subscription.
+// The mutex must be held.
+func (r *Settings) subscribed(key string) bool {
+	for i := range r.subscriptions {
+		if r.subscriptions[i].match(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Poll the file.
+func (r *Settings) watch(done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(WatchInterval):
+			_, _ = r.Reload()
+		}
+	}
+}
+
+//
+// Keys changed (added, updated, deleted).
+func diff(prior, current map[string]string) (changed []string) {
+	for key, value := range current {
+		if v, found := prior[key]; !found || v != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range prior {
+		if _, found := current[key]; !found {
+			changed = append(changed, key)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return
+}
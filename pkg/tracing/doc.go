@@ -0,0 +1,18 @@
+/*
+Provides (optional) tracing.
+Spans are started using the installed Tracer and are linked
+through the context. The default tracer is a no-op so that
+instrumentation costs (close to) nothing unless enabled.
+
+The Exporter is a tracer that buffers ended spans and passes
+them (in batches) to a pluggable SpanExporter which delivers
+them to a tracing backend:
+
+	exporter := &tracing.Exporter{SpanExporter: backend}
+	tracing.Use(exporter)
+	go exporter.Run(ctx)
+
+The trace context is propagated across HTTP using the (W3C)
+traceparent header. See: Inject() and Extract().
+*/
+package tracing
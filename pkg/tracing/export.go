@@ -0,0 +1,278 @@
+package tracing
+
+import (
+	"context"
+	"github.com/konveyor/controller/pkg/logging"
+	"sync"
+	"time"
+)
+
+//
+// Logger.
+var log = logging.WithName("tracing")
+
+//
+// Exporter defaults.
+var (
+	// Spans per (exported) batch.
+	ExportBatch = 512
+	// Buffered spans.
+	ExportBuffer = 2048
+	// Export interval.
+	ExportInterval = time.Second * 5
+	// Export (call) timeout.
+	ExportTimeout = time.Second * 10
+)
+
+//
+// Exported (ended) span.
+type Exported struct {
+	// Span context (trace and span IDs).
+	Context SpanContext
+	// Parent span ID. Zero=root.
+	Parent [8]byte
+	// Name.
+	Name string
+	// Attributes.
+	Attributes map[string]interface{}
+	// Recorded error.
+	Err error
+	// Started timestamp.
+	Started time.Time
+	// Ended timestamp.
+	Ended time.Time
+}
+
+//
+// Span exporter.
+// Delivers batches of ended spans to a tracing backend.
+// The encoding and transport are owned by the implementation.
+type SpanExporter interface {
+	// Export a batch of spans.
+	// The context is done when the export times out.
+	Export(ctx context.Context, spans []Exported) error
+}
+
+//
+// Function adapter.
+type SpanExporterFunc func(ctx context.Context, spans []Exported) error
+
+//
+// Export a batch of spans.
+func (f SpanExporterFunc) Export(ctx context.Context, spans []Exported) error {
+	return f(ctx, spans)
+}
+
+//
+// Exporting tracer.
+// A tracer for which ended spans are buffered and passed
+// (in batches) to the (pluggable) span exporter. The span
+// context carried by the context (see: Extract()) is used
+// as the parent so traces propagated by clients are
+// continued. Spans are dropped when the buffer is full.
+type Exporter struct {
+	// Span exporter (backend).
+	SpanExporter SpanExporter
+	// Spans per batch.
+	// Default: ExportBatch.
+	Batch int
+	// Buffered spans.
+	// Default: ExportBuffer.
+	Buffer int
+	// Export interval.
+	// Default: ExportInterval.
+	Interval time.Duration
+	// Export (call) timeout.
+	// Default: ExportTimeout.
+	Timeout time.Duration
+	// Ended spans.
+	spans []*exportedSpan
+	// Dropped spans.
+	dropped int
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Start a span.
+func (r *Exporter) Start(ctx context.Context, name string, kv ...interface{}) (context.Context, Span) {
+	parent, _ := SpanContextOf(ctx)
+	span := &exportedSpan{
+		exporter: r,
+		Exported: Exported{
+			Context:    parent.Child(),
+			Parent:     parent.SpanID,
+			Name:       name,
+			Attributes: map[string]interface{}{},
+			Started:    time.Now(),
+		},
+	}
+	span.Set(kv...)
+	ctx = WithSpanContext(ctx, span.Context)
+	return ctx, span
+}
+
+//
+// Run the exporter.
+// Buffered spans are exported at the interval until
+// the context is done and then (a final time) on return.
+func (r *Exporter) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = ExportInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.export()
+		case <-ctx.Done():
+			r.export()
+			return
+		}
+	}
+}
+
+//
+// Export buffered spans.
+// A batch that fails to be exported is dropped.
+func (r *Exporter) Flush() (err error) {
+	for {
+		batch, dropped := r.next()
+		if dropped > 0 {
+			log.Info(
+				"spans dropped.",
+				"count",
+				dropped)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		err = r.send(batch)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Export and log errors.
+func (r *Exporter) export() {
+	err := r.Flush()
+	if err != nil {
+		log.Error(err, "export failed.")
+	}
+}
+
+//
+// Buffer an ended span.
+func (r *Exporter) add(span *exportedSpan) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	limit := r.Buffer
+	if limit < 1 {
+		limit = ExportBuffer
+	}
+	if len(r.spans) < limit {
+		r.spans = append(r.spans, span)
+	} else {
+		r.dropped++
+	}
+}
+
+//
+// Next batch of spans and the number dropped since
+// the last batch.
+func (r *Exporter) next() (batch []*exportedSpan, dropped int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	n := r.Batch
+	if n < 1 {
+		n = ExportBatch
+	}
+	if n > len(r.spans) {
+		n = len(r.spans)
+	}
+	batch = r.spans[:n]
+	r.spans = r.spans[n:]
+	dropped = r.dropped
+	r.dropped = 0
+	return
+}
+
+//
+// Pass the batch to the span exporter.
+// The batch is discarded when no span exporter is set.
+func (r *Exporter) send(batch []*exportedSpan) (err error) {
+	if r.SpanExporter == nil {
+		return
+	}
+	timeout := r.Timeout
+	if timeout < 1 {
+		timeout = ExportTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	spans := []Exported{}
+	for _, span := range batch {
+		spans = append(spans, span.exported())
+	}
+	err = r.SpanExporter.Export(ctx, spans)
+	return
+}
+
+//
+// Exported span.
+type exportedSpan struct {
+	Exported
+	// Exporter.
+	exporter *Exporter
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Set attributes.
+func (s *exportedSpan) Set(kv ...interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		if key, cast := kv[i].(string); cast {
+			s.Attributes[key] = kv[i+1]
+		}
+	}
+}
+
+//
+// Record an error.
+func (s *exportedSpan) Error(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Err = err
+}
+
+//
+// End the span.
+func (s *exportedSpan) End() {
+	s.mutex.Lock()
+	s.Ended = time.Now()
+	s.mutex.Unlock()
+	s.exporter.add(s)
+}
+
+//
+// Exported (copy).
+func (s *exportedSpan) exported() (span Exported) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	span = s.Exported
+	span.Attributes = map[string]interface{}{}
+	for key, value := range s.Attributes {
+		span.Attributes[key] = value
+	}
+
+	return
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"net/http"
+	"strings"
+)
+
+//
+// W3C trace context header.
+const TraceParent = "traceparent"
+
+//
+// Context key for the span context.
+type contextKey struct{}
+
+//
+// Span context.
+// Identifies the (remote or local) span used as
+// the parent of spans started with the context.
+type SpanContext struct {
+	// Trace ID.
+	TraceID [16]byte
+	// Span ID.
+	SpanID [8]byte
+	// Sampled flag.
+	Sampled bool
+}
+
+//
+// Valid (non-zero IDs).
+func (r SpanContext) Valid() bool {
+	return r.TraceID != [16]byte{} && r.SpanID != [8]byte{}
+}
+
+//
+// Child span context.
+// Same trace with a new (random) span ID. A new trace
+// is started when the span context is not valid.
+func (r SpanContext) Child() (child SpanContext) {
+	child = r
+	if r.TraceID == [16]byte{} {
+		_, _ = rand.Read(child.TraceID[:])
+		child.Sampled = true
+	}
+	_, _ = rand.Read(child.SpanID[:])
+	return
+}
+
+//
+// Rendered as the `traceparent` header value.
+func (r SpanContext) String() string {
+	flags := 0
+	if r.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf(
+		"00-%s-%s-%02x",
+		hex.EncodeToString(r.TraceID[:]),
+		hex.EncodeToString(r.SpanID[:]),
+		flags)
+}
+
+//
+// Parse the `traceparent` header value.
+func ParseTraceParent(s string) (sc SpanContext, err error) {
+	part := strings.Split(strings.TrimSpace(s), "-")
+	if len(part) < 4 || part[0] == "ff" || len(part[0]) != 2 {
+		err = liberr.New("traceparent not valid.", "value", s)
+		return
+	}
+	traceID, tErr := hex.DecodeString(part[1])
+	spanID, sErr := hex.DecodeString(part[2])
+	flags, fErr := hex.DecodeString(part[3])
+	if tErr != nil || sErr != nil || fErr != nil ||
+		len(traceID) != len(sc.TraceID) ||
+		len(spanID) != len(sc.SpanID) ||
+		len(flags) != 1 {
+		err = liberr.New("traceparent not valid.", "value", s)
+		return
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.Valid() {
+		err = liberr.New("traceparent not valid.", "value", s)
+	}
+
+	return
+}
+
+//
+// Context with the span context.
+func WithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, sc)
+}
+
+//
+// The span context carried by the context.
+func SpanContextOf(ctx context.Context) (sc SpanContext, found bool) {
+	if ctx == nil {
+		return
+	}
+	sc, found = ctx.Value(contextKey{}).(SpanContext)
+	return
+}
+
+//
+// Inject the span context carried by the context
+// into the (request) header.
+func Inject(ctx context.Context, header http.Header) {
+	if sc, found := SpanContextOf(ctx); found && sc.Valid() {
+		header.Set(TraceParent, sc.String())
+	}
+}
+
+//
+// Extract the span context from the (request) header.
+// Returns the context unchanged when the header is
+// not found or not valid.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	value := header.Get(TraceParent)
+	if value == "" {
+		return ctx
+	}
+	sc, err := ParseTraceParent(value)
+	if err != nil {
+		return ctx
+	}
+
+	return WithSpanContext(ctx, sc)
+}
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//
+// Context key for the recorded span.
+type spanKey struct{}
+
+//
+// Recorded span.
+type Recorded struct {
+	// Span ID.
+	ID int
+	// Parent span ID. Zero=root.
+	Parent int
+	// Name.
+	Name string
+	// Attributes.
+	Attributes map[string]interface{}
+	// Recorded error.
+	Err error
+	// Started timestamp.
+	Started time.Time
+	// Ended timestamp.
+	Ended time.Time
+}
+
+//
+// Duration.
+func (r *Recorded) Duration() time.Duration {
+	return r.Ended.Sub(r.Started)
+}
+
+//
+// Recording tracer.
+// Keeps ended spans in memory. Intended for tests
+// and debugging.
+type Recorder struct {
+	// Ended spans.
+	spans []*Recorded
+	// Last span ID.
+	lastID int
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Start a span.
+func (r *Recorder) Start(ctx context.Context, name string, kv ...interface{}) (context.Context, Span) {
+	r.mutex.Lock()
+	r.lastID++
+	span := &recordedSpan{
+		recorder: r,
+		Recorded: Recorded{
+			ID:         r.lastID,
+			Name:       name,
+			Attributes: map[string]interface{}{},
+			Started:    time.Now(),
+		},
+	}
+	r.mutex.Unlock()
+	if parent, cast := ctx.Value(spanKey{}).(*recordedSpan); cast {
+		span.Parent = parent.ID
+	}
+	span.Set(kv...)
+	ctx = context.WithValue(ctx, spanKey{}, span)
+	return ctx, span
+}
+
+//
+// Ended spans.
+func (r *Recorder) Spans() (list []Recorded) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, span := range r.spans {
+		list = append(list, *span)
+	}
+
+	return
+}
+
+//
+// Find ended spans by name.
+func (r *Recorder) Find(name string) (list []Recorded) {
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			list = append(list, span)
+		}
+	}
+
+	return
+}
+
+//
+// Reset.
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = nil
+}
+
+//
+// Recorded span.
+type recordedSpan struct {
+	Recorded
+	// Recorder.
+	recorder *Recorder
+	// Protect fields.
+	mutex sync.Mutex
+}
+
+//
+// Set attributes.
+func (s *recordedSpan) Set(kv ...interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		if key, cast := kv[i].(string); cast {
+			s.Attributes[key] = kv[i+1]
+		}
+	}
+}
+
+//
+// Record an error.
+func (s *recordedSpan) Error(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Err = err
+}
+
+//
+// End the span.
+func (s *recordedSpan) End() {
+	s.mutex.Lock()
+	s.Ended = time.Now()
+	s.mutex.Unlock()
+	s.recorder.mutex.Lock()
+	defer s.recorder.mutex.Unlock()
+	s.recorder.spans = append(s.recorder.spans, &s.Recorded)
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+//
+// Span attribute keys.
+const (
+	Kind      = "kind"
+	Phase     = "phase"
+	Statement = "db.statement"
+	Method    = "http.method"
+	Route     = "http.route"
+	Status    = "http.status_code"
+	Count     = "count"
+	Reconcile = "reconcile.id"
+)
+
+//
+// Span.
+type Span interface {
+	// Set attributes as key/value pairs.
+	Set(kv ...interface{})
+	// Record an error.
+	Error(err error)
+	// End the span.
+	End()
+}
+
+//
+// Tracer.
+type Tracer interface {
+	// Start a span.
+	// The returned context carries the span and is used
+	// to link (child) spans started with it.
+	Start(ctx context.Context, name string, kv ...interface{}) (context.Context, Span)
+}
+
+//
+// No-op tracer.
+type NopTracer struct{}
+
+//
+// Start a (no-op) span.
+func (t *NopTracer) Start(ctx context.Context, name string, kv ...interface{}) (context.Context, Span) {
+	return ctx, &nopSpan{}
+}
+
+//
+// No-op span.
+type nopSpan struct{}
+
+func (s *nopSpan) Set(kv ...interface{}) {}
+func (s *nopSpan) Error(err error)       {}
+func (s *nopSpan) End()                  {}
+
+//
+// Installed tracer.
+var tracer = struct {
+	Tracer
+	enabled bool
+	mutex   sync.RWMutex
+}{
+	Tracer: &NopTracer{},
+}
+
+//
+// Install a tracer.
+// Passing nil restores the no-op tracer.
+func Use(t Tracer) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	if t == nil {
+		tracer.Tracer = &NopTracer{}
+		tracer.enabled = false
+	} else {
+		tracer.Tracer = t
+		tracer.enabled = true
+	}
+}
+
+//
+// Tracing enabled.
+// A tracer (other than no-op) is installed.
+func Enabled() bool {
+	tracer.mutex.RLock()
+	defer tracer.mutex.RUnlock()
+	return tracer.enabled
+}
+
+//
+// Start a span using the installed tracer.
+// A nil context is treated as context.Background().
+func Start(ctx context.Context, name string, kv ...interface{}) (context.Context, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tracer.mutex.RLock()
+	t := tracer.Tracer
+	tracer.mutex.RUnlock()
+	return t.Start(ctx, name, kv...)
+}
+
+//
+// End the span and record the error (when not nil).
+// Intended to be deferred with a named error return.
+func End(span Span, err *error) {
+	if err != nil && *err != nil {
+		span.Error(*err)
+	}
+	span.End()
+}
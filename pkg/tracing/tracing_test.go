@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/onsi/gomega"
+	"net/http"
+	"testing"
+)
+
+func TestNop(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(Enabled()).To(gomega.BeFalse())
+	ctx := context.TODO()
+	ctx2, span := Start(ctx, "test", Kind, "A")
+	g.Expect(ctx2).To(gomega.Equal(ctx))
+	span.Set(Count, 1)
+	span.Error(errors.New("failed"))
+	span.End()
+}
+
+func TestRecorder(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	recorder := &Recorder{}
+	Use(recorder)
+	defer Use(nil)
+	g.Expect(Enabled()).To(gomega.BeTrue())
+	ctx, root := Start(nil, "root", Kind, "A")
+	func() (err error) {
+		_, child := Start(ctx, "child")
+		defer End(child, &err)
+		err = errors.New("failed")
+		return
+	}()
+	root.Set(Count, 2)
+	root.End()
+	spans := recorder.Spans()
+	g.Expect(len(spans)).To(gomega.Equal(2))
+	child := recorder.Find("child")[0]
+	g.Expect(child.Parent).To(gomega.Equal(spans[1].ID))
+	g.Expect(child.Err).ToNot(gomega.BeNil())
+	parent := recorder.Find("root")[0]
+	g.Expect(parent.Parent).To(gomega.Equal(0))
+	g.Expect(parent.Attributes[Kind]).To(gomega.Equal("A"))
+	g.Expect(parent.Attributes[Count]).To(gomega.Equal(2))
+	g.Expect(parent.Duration() >= 0).To(gomega.BeTrue())
+	recorder.Reset()
+	g.Expect(recorder.Spans()).To(gomega.BeEmpty())
+	Use(nil)
+	g.Expect(Enabled()).To(gomega.BeFalse())
+}
+
+func TestPropagation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	header := http.Header{}
+	Inject(context.TODO(), header)
+	g.Expect(header.Get(TraceParent)).To(gomega.BeEmpty())
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	header.Set(TraceParent, value)
+	ctx := Extract(context.TODO(), header)
+	sc, found := SpanContextOf(ctx)
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(sc.Sampled).To(gomega.BeTrue())
+	g.Expect(sc.String()).To(gomega.Equal(value))
+	header = http.Header{}
+	Inject(ctx, header)
+	g.Expect(header.Get(TraceParent)).To(gomega.Equal(value))
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-xyz-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceParent(bad)
+		g.Expect(err).ToNot(gomega.BeNil())
+		header.Set(TraceParent, bad)
+		_, found = SpanContextOf(Extract(context.TODO(), header))
+		g.Expect(found).To(gomega.BeFalse())
+	}
+}
+
+func TestExporter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	received := [][]Exported{}
+	var failed error
+	exporter := &Exporter{
+		SpanExporter: SpanExporterFunc(
+			func(ctx context.Context, spans []Exported) error {
+				_, hasDeadline := ctx.Deadline()
+				g.Expect(hasDeadline).To(gomega.BeTrue())
+				received = append(received, spans)
+				return failed
+			}),
+		Batch:  2,
+		Buffer: 3,
+	}
+	Use(exporter)
+	defer Use(nil)
+	header := http.Header{}
+	header.Set(TraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := Start(Extract(context.TODO(), header), "root", Kind, "A", Count, 2)
+	_, child := Start(ctx, "child")
+	child.Error(errors.New("failed"))
+	child.End()
+	root.End()
+	_, other := Start(context.TODO(), "other")
+	other.End()
+	_, dropped := Start(context.TODO(), "dropped")
+	dropped.End()
+	err := exporter.Flush()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(received)).To(gomega.Equal(2))
+	spans := []Exported{}
+	for _, batch := range received {
+		spans = append(spans, batch...)
+	}
+	g.Expect(len(spans)).To(gomega.Equal(3))
+	childSpan, rootSpan, otherSpan := spans[0], spans[1], spans[2]
+	g.Expect(rootSpan.Context.String()).To(
+		gomega.HavePrefix("00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+	g.Expect(rootSpan.Parent[:]).To(
+		gomega.Equal([]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}))
+	g.Expect(childSpan.Context.TraceID).To(gomega.Equal(rootSpan.Context.TraceID))
+	g.Expect(childSpan.Parent).To(gomega.Equal(rootSpan.Context.SpanID))
+	g.Expect(childSpan.Err).ToNot(gomega.BeNil())
+	g.Expect(len(rootSpan.Attributes)).To(gomega.Equal(2))
+	g.Expect(rootSpan.Ended.Before(rootSpan.Started)).To(gomega.BeFalse())
+	g.Expect(otherSpan.Context.TraceID).ToNot(gomega.Equal(rootSpan.Context.TraceID))
+	g.Expect(otherSpan.Parent).To(gomega.Equal([8]byte{}))
+	// Failed.
+	failed = errors.New("rejected")
+	_, span := Start(context.TODO(), "rejected")
+	span.End()
+	err = exporter.Flush()
+	g.Expect(err).ToNot(gomega.BeNil())
+	// No span exporter.
+	exporter.SpanExporter = nil
+	_, span = Start(context.TODO(), "discarded")
+	span.End()
+	err = exporter.Flush()
+	g.Expect(err).To(gomega.BeNil())
+}